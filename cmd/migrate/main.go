@@ -0,0 +1,85 @@
+// Command migrate applies, reverts, or lists the versioned schema migrations
+// embedded in this release (internal/db/migrations).
+//
+// Usage:
+//
+//	go run ./cmd/migrate up           # apply every pending migration (and partition accounts if PARTITIONED_SCHEMA=true)
+//	go run ./cmd/migrate down [N]     # revert the last N migrations (default 1)
+//	go run ./cmd/migrate status       # list migrations and whether they are applied
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/secrets"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Load environment variables from .env file (if it exists)
+	_ = godotenv.Load()
+
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		log.Fatal("Failed to initialize primary database:", err)
+	}
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		if err := db.MigrateUp(ctx); err != nil {
+			db.CloseDB()
+			log.Fatal("Migration failed:", err)
+		}
+		if db.PartitionedSchema() {
+			if err := db.EnsurePartitionedAccounts(ctx); err != nil {
+				db.CloseDB()
+				log.Fatal("Failed to partition accounts:", err)
+			}
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			n, err := strconv.Atoi(os.Args[2])
+			if err != nil || n < 1 {
+				usage()
+			}
+			steps = n
+		}
+		if err := db.MigrateDown(ctx, steps); err != nil {
+			db.CloseDB()
+			log.Fatal("Migration failed:", err)
+		}
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx)
+		if err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, status := range statuses {
+			fmt.Println(status)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: migrate up | down [N] | status")
+	os.Exit(2)
+}
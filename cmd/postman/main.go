@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"saas-go-app/docs"
+	"saas-go-app/internal/postman"
+)
+
+func main() {
+	baseURL := flag.String("base-url", "", "API base URL including /api (default: http://localhost:8080/api)")
+	output := flag.String("o", "", "Output file (default: stdout)")
+	flag.Parse()
+
+	collection, err := postman.FromSwagger([]byte(docs.SwaggerInfo.ReadDoc()), *baseURL)
+	if err != nil {
+		log.Fatal("Failed to build Postman collection:", err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(collection); err != nil {
+		log.Fatal("Failed to write Postman collection:", err)
+	}
+
+	if *output != "" {
+		log.Printf("Postman collection written to %s", *output)
+	}
+}
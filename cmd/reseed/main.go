@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"saas-go-app/internal/auth"
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/secrets"
 
 	"github.com/joho/godotenv"
 )
@@ -13,6 +18,11 @@ func main() {
 	// Load environment variables from .env file (if it exists)
 	_ = godotenv.Load()
 
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
 	// Initialize JWT (needed for password hashing)
 	if err := auth.InitJWT(); err != nil {
 		log.Fatal("Failed to initialize JWT:", err)
@@ -28,13 +38,16 @@ func main() {
 		log.Printf("Warning: Failed to initialize analytics database: %v", err)
 	}
 
-	// Create database tables (in case they don't exist)
-	if err := db.CreateTables(); err != nil {
-		log.Fatal("Failed to create database tables:", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Apply pending migrations (in case the tables don't exist)
+	if err := db.MigrateUp(ctx); err != nil {
+		log.Fatal("Failed to migrate database:", err)
 	}
 
 	// Clear and reseed
-	if err := db.ClearAndReseed(); err != nil {
+	if err := db.ClearAndReseed(ctx, nil); err != nil {
 		log.Fatal("Failed to clear and reseed database:", err)
 	}
 
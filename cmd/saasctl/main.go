@@ -0,0 +1,124 @@
+// Command saasctl runs the NGPG showcase as repeatable, scriptable demos
+// against a running app (local or on Heroku) through its API.
+//
+// Usage:
+//
+//	saasctl scenario list
+//	saasctl scenario run read-scaling --seed --duration 30s --concurrency 20
+//	saasctl scenario run failover --base-url https://your-app.herokuapp.com/api
+//	saasctl scenario run bulk-import --rows 200000
+//
+// Scenarios log in as SAASCTL_USERNAME / SAASCTL_PASSWORD (default admin /
+// admin123) unless SAASCTL_TOKEN is set; the user must be an admin.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"saas-go-app/internal/scenario"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage:
+  saasctl scenario list
+  saasctl scenario run <scenario> [flags]
+`
+
+func main() {
+	// Load environment variables from .env file (if it exists)
+	_ = godotenv.Load()
+
+	args := os.Args[1:]
+	if len(args) < 2 || args[0] != "scenario" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch args[1] {
+	case "list":
+		list()
+	case "run":
+		run(args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func list() {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range scenario.Scenarios {
+		fmt.Fprintf(table, "%s\t%s\n", s.Name, s.Description)
+	}
+	table.Flush()
+}
+
+func run(args []string) {
+	flags := flag.NewFlagSet("saasctl scenario run", flag.ExitOnError)
+	baseURL := flags.String("base-url", envOr("SAASCTL_BASE_URL", "http://localhost:8080/api"), "API base URL including /api")
+	username := flags.String("username", envOr("SAASCTL_USERNAME", "admin"), "admin user to log in as")
+	password := flags.String("password", envOr("SAASCTL_PASSWORD", "admin123"), "password of the admin user")
+	token := flags.String("token", os.Getenv("SAASCTL_TOKEN"), "bearer token to use instead of logging in")
+	var opts scenario.Options
+	flags.DurationVar(&opts.Duration, "duration", 0, "length of each load phase (default 30s)")
+	flags.IntVar(&opts.Concurrency, "concurrency", 0, "concurrent load workers (default 10)")
+	paths := flags.String("paths", "", "comma-separated endpoints to load, relative to the base URL (default: "+strings.Join(scenario.DefaultReadPaths, ",")+")")
+	flags.BoolVar(&opts.Seed, "seed", false, "read-scaling: clear and reseed the database first")
+	flags.IntVar(&opts.Rows, "rows", 0, "bulk-import: customers to upload (default 100000)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: saasctl scenario run <%s> [flags]\n", strings.Join(scenario.Names(), "|"))
+		flags.PrintDefaults()
+	}
+
+	// The scenario name may come before or after the flags
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	_ = flags.Parse(args)
+	if name == "" && flags.NArg() > 0 {
+		name = flags.Arg(0)
+	}
+	if name == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *paths != "" {
+		opts.Paths = strings.Split(*paths, ",")
+	}
+
+	// Ctrl-C stops the scenario but still restores the runtime settings it changed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := scenario.NewClient(*baseURL, *token)
+	if client.Token == "" {
+		if err := client.Login(ctx, *username, *password); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	report, err := scenario.Run(ctx, name, client, opts)
+	if err != nil {
+		log.Fatalf("Scenario %s failed: %v", name, err)
+	}
+	fmt.Println()
+	if err := report.Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
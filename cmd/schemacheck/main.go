@@ -0,0 +1,98 @@
+// Command schemacheck compares the live database schema with the schema this
+// release creates and exits non-zero if the change would break the release
+// that is still serving traffic. It runs in the Heroku release phase, so a
+// breaking change fails the deploy or pipeline promotion before it goes live.
+//
+// Usage:
+//
+//	go run ./cmd/schemacheck [-json]
+//
+// Set SCHEMA_CHECK_ALLOW_BREAKING=true to report breaking changes without
+// failing, e.g. for a deliberate contract step during a maintenance window.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/schemacheck"
+	"saas-go-app/internal/secrets"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "Print findings as JSON")
+	flag.Parse()
+
+	// Load environment variables from .env file (if it exists)
+	_ = godotenv.Load()
+
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		log.Fatal("Failed to initialize primary database:", err)
+	}
+	defer db.CloseDB()
+
+	findings, err := check(context.Background())
+	if err != nil {
+		log.Fatal("Schema check failed:", err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(findings)
+	} else if len(findings) == 0 {
+		fmt.Println("No schema changes")
+	} else {
+		for _, finding := range findings {
+			fmt.Println(finding)
+		}
+	}
+
+	if schemacheck.HasBreaking(findings) {
+		if os.Getenv("SCHEMA_CHECK_ALLOW_BREAKING") == "true" {
+			log.Println("Breaking schema changes found, continuing because SCHEMA_CHECK_ALLOW_BREAKING=true")
+			return
+		}
+		db.CloseDB()
+		log.Fatal("Breaking schema changes found; split the change into expand and contract releases or set SCHEMA_CHECK_ALLOW_BREAKING=true")
+	}
+}
+
+// check builds this release's schema in a scratch schema and compares it with
+// the live public schema
+func check(ctx context.Context) ([]schemacheck.Finding, error) {
+	live, err := schemacheck.Load(ctx, db.PrimaryDB, "public")
+	if err != nil {
+		return nil, err
+	}
+
+	scratch := fmt.Sprintf("schemacheck_%d", time.Now().UnixNano())
+	defer func() {
+		if _, err := db.PrimaryDB.Exec("DROP SCHEMA IF EXISTS " + pgx.Identifier{scratch}.Sanitize() + " CASCADE"); err != nil {
+			log.Printf("Warning: Failed to drop scratch schema %s: %v", scratch, err)
+		}
+	}()
+	if err := db.MigrateInSchema(ctx, scratch); err != nil {
+		return nil, fmt.Errorf("failed to create release schema: %w", err)
+	}
+
+	release, err := schemacheck.Load(ctx, db.PrimaryDB, scratch)
+	if err != nil {
+		return nil, err
+	}
+	return schemacheck.Compare(live, release, schemacheck.References(ctx, db.PrimaryDB, "public"))
+}
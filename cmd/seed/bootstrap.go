@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"saas-go-app/internal/httpclient"
+)
+
+// herokuAPIURL is the Heroku Platform API
+const herokuAPIURL = "https://api.heroku.com"
+
+// publishCredential stores the bootstrap admin credential in the app's
+// BOOTSTRAP_ADMIN_USERNAME and BOOTSTRAP_ADMIN_PASSWORD config vars, where
+// it can be read from the dashboard or with `heroku config`. It needs
+// HEROKU_API_KEY and HEROKU_APP_NAME, and returns false without them.
+// Setting config vars restarts the app's dynos.
+func publishCredential(ctx context.Context, username, password string) (bool, error) {
+	apiKey, app := os.Getenv("HEROKU_API_KEY"), os.Getenv("HEROKU_APP_NAME")
+	if apiKey == "" || app == "" {
+		return false, nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"BOOTSTRAP_ADMIN_USERNAME": username,
+		"BOOTSTRAP_ADMIN_PASSWORD": password,
+	})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, herokuAPIURL+"/apps/"+url.PathEscape(app)+"/config-vars", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.heroku+json; version=3")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := httpclient.New("heroku", httpclient.Options{}).Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("heroku API returned %s: %s", resp.Status, message)
+	}
+	return true, nil
+}
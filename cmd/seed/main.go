@@ -0,0 +1,186 @@
+// Command seed populates the database with demo data on demand, instead of
+// only at web startup when SEED_DATA=true. It applies pending migrations first
+// and leaves a database that already has customers alone unless --clear or
+// --fill is set.
+//
+// Usage:
+//
+//	go run ./cmd/seed                                  # demo profile, if the database is empty
+//	go run ./cmd/seed --performance --customers 100000 --accounts-per-customer 10
+//	go run ./cmd/seed --performance --customers 1000000 --workers 8 --force
+//	go run ./cmd/seed --clear --performance            # replace existing data
+//	go run ./cmd/seed --fill                           # add demo records missing after a partial run
+//	seed --release                                     # release phase: seed only if SEED_DATA=true
+//	seed --bootstrap                                   # review app postdeploy: demo profile and a random admin password
+//
+// Flags default to the SEED_* environment variables. Interrupting a seed stops
+// it; performance data keeps the chunks committed so far, so rerun with --clear.
+// Demo records that fail are counted and skipped, and the command exits
+// non-zero after seeding the rest.
+//
+// --bootstrap sets up a fresh app, such as a Heroku review app (app.json runs
+// it as the postdeploy script): it migrates, creates the admin user with a
+// random password instead of admin123, and seeds the demo profile. The
+// credential is logged, and also stored in the BOOTSTRAP_ADMIN_USERNAME and
+// BOOTSTRAP_ADMIN_PASSWORD config vars when HEROKU_API_KEY and
+// HEROKU_APP_NAME are set. An app that already has users keeps them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/secrets"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (if it exists)
+	_ = godotenv.Load()
+
+	defaults := db.PerformanceSeedOptionsFromEnv()
+	opts := defaults
+	performance := flag.Bool("performance", os.Getenv("SEED_PERFORMANCE_DATA") == "true", "generate performance demo data instead of the demo profile")
+	flag.IntVar(&opts.Customers, "customers", defaults.Customers, "customers to generate with --performance")
+	flag.IntVar(&opts.AccountsPerCustomer, "accounts-per-customer", defaults.AccountsPerCustomer, "average accounts per customer with --performance")
+	flag.IntVar(&opts.BatchSize, "batch-size", defaults.BatchSize, "rows per COPY with --performance")
+	flag.IntVar(&opts.Workers, "workers", defaults.Workers, "chunks loaded concurrently with --performance")
+	flag.Int64Var(&opts.RandomSeed, "random-seed", defaults.RandomSeed, "seed for reproducible data with --performance (0 picks one at random)")
+	flag.BoolVar(&opts.Force, "force", defaults.Force, "seed with --performance beyond SEED_MAX_ROWS and SEED_MAX_DATABASE_MB")
+	clearData := flag.Bool("clear", false, "delete existing customers and accounts first")
+	fill := flag.Bool("fill", false, "add the demo records that are missing, even if the database has data")
+	release := flag.Bool("release", false, "run as a release-phase step: do nothing unless SEED_DATA=true, and never clear")
+	bootstrap := flag.Bool("bootstrap", false, "set up a fresh app: create the admin user with a random password and seed the demo profile")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: seed [--performance [--customers N] [--accounts-per-customer N] [--batch-size N] [--workers N] [--random-seed N] [--force]] [--clear] [--fill] [--release] [--bootstrap]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	sized := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "customers", "accounts-per-customer", "batch-size", "workers", "random-seed", "force":
+			sized = true
+		}
+	})
+	if sized && !*performance {
+		log.Fatal("--customers, --accounts-per-customer, --batch-size, --workers, --random-seed, and --force require --performance")
+	}
+	if *fill && (*performance || *clearData) {
+		log.Fatal("--fill cannot be used with --performance or --clear")
+	}
+	if *bootstrap && (*performance || *clearData || *fill || *release) {
+		log.Fatal("--bootstrap cannot be used with --performance, --clear, --fill, or --release")
+	}
+	if opts.Customers < 0 || opts.AccountsPerCustomer < 0 {
+		log.Fatal("--customers and --accounts-per-customer must not be negative")
+	}
+	if *release {
+		if os.Getenv("SEED_DATA") != "true" {
+			log.Println("SEED_DATA is not true, skipping seed")
+			return
+		}
+		if *clearData {
+			log.Fatal("--clear cannot be used with --release")
+		}
+	}
+
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
+	// Initialize JWT (needed for password hashing)
+	if err := auth.InitJWT(); err != nil {
+		log.Fatal("Failed to initialize JWT:", err)
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		log.Fatal("Failed to initialize primary database:", err)
+	}
+	defer db.CloseDB()
+
+	// Ctrl-C stops seeding; performance chunks already committed are kept
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := db.MigrateUp(ctx); err != nil {
+		db.CloseDB()
+		log.Fatal("Failed to migrate database:", err)
+	}
+	if db.PartitionedSchema() {
+		if err := db.EnsurePartitionedAccounts(ctx); err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to partition accounts:", err)
+		}
+	}
+
+	if *bootstrap {
+		password, err := db.CreateBootstrapAdmin(ctx)
+		if err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to create admin user:", err)
+		}
+		if password == "" {
+			log.Println("Database already has users, not creating a bootstrap admin")
+		} else {
+			log.Printf("Bootstrap admin credential: username=%s password=%s", db.BootstrapAdminUsername, password)
+			published, err := publishCredential(ctx, db.BootstrapAdminUsername, password)
+			if err != nil {
+				log.Printf("Warning: Failed to store the admin credential in config vars: %v", err)
+			} else if published {
+				log.Println("Stored the admin credential in BOOTSTRAP_ADMIN_USERNAME and BOOTSTRAP_ADMIN_PASSWORD")
+			}
+		}
+	}
+
+	if *clearData {
+		if err := db.ClearData(ctx); err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to clear database:", err)
+		}
+	} else if !*fill {
+		var hasData bool
+		if err := db.Primary(ctx).QueryRow("SELECT EXISTS (SELECT 1 FROM customers)").Scan(&hasData); err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to check for existing data:", err)
+		}
+		if hasData {
+			log.Println("Database already contains data, skipping seed (use --clear to replace it)")
+			return
+		}
+	}
+
+	if *performance {
+		if err := db.SeedPerformance(ctx, opts, nil); err != nil {
+			db.CloseDB()
+			log.Fatal("Failed to seed database:", err)
+		}
+		log.Println("Database seeded successfully")
+		return
+	}
+
+	summary, err := db.SeedData(ctx, nil)
+	if err != nil {
+		db.CloseDB()
+		log.Fatal("Failed to seed database:", err)
+	}
+	if summary.Failed() > 0 {
+		db.CloseDB()
+		log.Fatalf("Seeded database with %d failed records (%s)", summary.Failed(), summary)
+	}
+	log.Printf("Database seeded successfully (%s)", summary)
+}
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"saas-go-app/internal/api"
 	"saas-go-app/internal/auth"
+	"saas-go-app/internal/changefeed"
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/cursor"
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/devdb"
+	"saas-go-app/internal/events"
 	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/kpi"
+	"saas-go-app/internal/mock"
+	"saas-go-app/internal/posture"
+	"saas-go-app/internal/progress"
+	"saas-go-app/internal/secrets"
+	"saas-go-app/internal/tracing"
+	"saas-go-app/internal/usage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
@@ -19,10 +32,39 @@ func main() {
 	// Load environment variables from .env file (if it exists)
 	_ = godotenv.Load()
 
+	// Runtime settings can be reloaded later via SIGHUP or the admin API
+	config.Load()
+
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
 	// Initialize JWT
 	if err := auth.InitJWT(); err != nil {
 		log.Fatal("Failed to initialize JWT:", err)
 	}
+	if err := cursor.Init(); err != nil {
+		log.Fatal("Failed to initialize cursors:", err)
+	}
+	if err := auth.InitTOTPKey(); err != nil {
+		log.Fatal("Failed to initialize TOTP key:", err)
+	}
+
+	// Mock mode serves the API from memory, without Postgres or Redis
+	if os.Getenv("APP_MODE") == "mock" {
+		runMockServer()
+		return
+	}
+
+	// Start a throwaway local Postgres when no database is configured
+	if devdb.Wanted() {
+		stopDevDB, err := devdb.Start()
+		if err != nil {
+			log.Fatal("Failed to start embedded development database:", err)
+		}
+		defer stopDevDB()
+	}
 
 	// Initialize database connections
 	if err := db.InitPrimaryDB(); err != nil {
@@ -30,27 +72,73 @@ func main() {
 	}
 	defer db.CloseDB()
 
+	// Reject JWTs revoked by logging out, and accept API keys, publishing an
+	// event when one is used from outside its allowed ranges
+	auth.CheckRevocations(db.TokenRevoked)
+	auth.AcceptAPIKeys(db.AuthenticateAPIKey)
+	auth.OnAPIKeyIPDenied(api.PublishAPIKeyIPDenied)
+
 	if err := db.InitAnalyticsDB(); err != nil {
 		log.Printf("Warning: Failed to initialize analytics database: %v", err)
 	}
 
-	// Create database tables
-	if err := db.CreateTables(); err != nil {
-		log.Fatal("Failed to create database tables:", err)
+	// Apply pending migrations, or fail fast if DB_AUTO_MIGRATE=false and the schema is behind
+	if err := db.EnsureSchema(context.Background()); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	// Partition accounts by month, and create the coming months' partitions,
+	// when PARTITIONED_SCHEMA=true
+	if db.PartitionedSchema() {
+		if err := db.EnsurePartitionedAccounts(context.Background()); err != nil {
+			log.Fatal("Failed to partition accounts:", err)
+		}
+	}
+
+	// Warn about missing indexes and list queries planned as full table scans
+	db.LogIndexReport(context.Background())
+
+	// Warn about insecure defaults, such as the admin123 seed password
+	posture.LogReport(context.Background())
+
+	// Flush API usage rollups in batches
+	usage.Default.Start(context.Background(), db.PrimaryDB, usage.FlushInterval())
+
+	// Deliver queued lifecycle events to webhook subscribers
+	events.NewDispatcher().Start(context.Background(), events.DispatchInterval())
+
+	// Fan out committed customer and account changes to in-process subscribers
+	if changefeed.Enabled() {
+		changefeed.Default.Start(context.Background())
 	}
 
-	// Seed database with sample data if SEED_DATA is set
+	// Export business KPIs on /metrics
+	kpi.Start(context.Background(), kpi.RefreshInterval())
+
+	// Audit runtime config changes and reload on SIGHUP
+	config.OnChange(db.RecordConfigChanges)
+	config.WatchSignals()
+
+	// Seed database with sample data if SEED_DATA is set. Seeding runs in the
+	// background so its progress can be streamed from /api/admin/jobs.
 	if os.Getenv("SEED_DATA") == "true" {
-		// Check if we should force reseed (clears existing data first)
-		if os.Getenv("FORCE_RESEED") == "true" {
-			if err := db.ClearAndReseed(); err != nil {
-				log.Printf("Warning: Failed to clear and reseed database: %v", err)
-			}
-		} else {
-			if err := db.SeedDataIfEmpty(); err != nil {
-				log.Printf("Warning: Failed to seed database: %v", err)
+		job := progress.Start("seed")
+		log.Printf("Seeding database in the background (job %s)", job.Snapshot().JobID)
+		ctx := job.Context(context.Background())
+		go func() {
+			var err error
+			// Check if we should force reseed (clears existing data first)
+			if os.Getenv("FORCE_RESEED") == "true" {
+				if err = db.ClearAndReseed(ctx, job.Update); err != nil {
+					log.Printf("Warning: Failed to clear and reseed database: %v", err)
+				}
+			} else {
+				if err = db.SeedDataIfEmpty(ctx, job.Update); err != nil {
+					log.Printf("Warning: Failed to seed database: %v", err)
+				}
 			}
-		}
+			job.Finish(err)
+		}()
 	}
 
 	// Initialize background job processor
@@ -69,7 +157,8 @@ func main() {
 		)
 
 		mux := asynq.NewServeMux()
-		mux.HandleFunc(jobs.TypeAggregateData, jobs.HandleAggregationTask)
+		mux.Use(jobs.TracingMiddleware)
+		jobs.RegisterTasks(mux)
 
 		go func() {
 			log.Println("Starting background job processor...")
@@ -77,55 +166,245 @@ func main() {
 				log.Fatalf("Failed to start background job processor: %v", err)
 			}
 		}()
+
+		scheduler, err := jobs.NewScheduler(redisURL)
+		if err != nil {
+			log.Printf("Warning: Failed to create job scheduler: %v", err)
+		} else {
+			go func() {
+				if err := scheduler.Run(); err != nil {
+					log.Printf("Warning: Job scheduler stopped: %v", err)
+				}
+			}()
+		}
+
+		// Report queue depth and worker heartbeats on /health/ready and /metrics
+		jobs.StartMonitor(context.Background(), redisURL, jobs.MonitorInterval())
 	} else {
 		log.Println("REDIS_URL not set, background jobs will not be processed")
 	}
 
-	// Set up Gin router
-	router := gin.Default()
+	// Set up Gin router; access logs are suppressed when LOG_LEVEL is warn or error
+	router := gin.New()
+	if err := api.TrustProxies(router); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	router.Use(tracing.Middleware(), gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: tracing.LogFormatter,
+		Skip:      func(c *gin.Context) bool { return !config.LogEnabled("info") },
+	}), gin.Recovery())
+
+	// Shed load with 503s once the dyno is saturated; health and auth are exempt
+	router.Use(api.LoadShed(api.LoadShedOptionsFromEnv()))
+
+	// Count each request's queries; the db_stats_headers flag shows them in headers
+	router.Use(api.DBStats())
 
 	// Prometheus metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Health check endpoint
 	router.GET("/health", api.HealthCheck)
+	router.GET("/health/ready", api.ReadinessCheck)
+	router.GET("/health/db", api.DatabaseHealth)
+
+	// Public keys for other services verifying the app's JWTs
+	router.GET("/.well-known/jwks.json", api.GetJWKS)
+
+	// Postman collection generated from the Swagger spec
+	router.GET("/docs/postman.json", api.GetPostmanCollection)
+
+	// Feed of API changes and upcoming deprecations
+	router.GET("/changelog", api.GetChangelog)
 
 	// Public routes
 	apiRoutes := router.Group("/api")
+	apiRoutes.Use(api.ResponseStyle(), api.MaintenanceMode(), api.RateLimit(), api.DBRoute(), api.ReplicaLagFallback())
 	{
 		apiRoutes.POST("/auth/login", api.Login)
+		apiRoutes.POST("/auth/refresh", api.RefreshToken)
+		apiRoutes.POST("/auth/revoke", api.RevokeRefreshToken)
+		apiRoutes.POST("/auth/logout", auth.AuthMiddleware(), api.Logout)
 		apiRoutes.POST("/auth/register", api.Register)
+		apiRoutes.POST("/auth/signup", api.Signup)
+		apiRoutes.POST("/auth/invitations/accept", api.AcceptInvitation)
+		apiRoutes.GET("/auth/oidc/login", api.OIDCLogin)
+		apiRoutes.GET("/auth/oidc/callback", api.OIDCCallback)
+	}
+
+	// Customer self-service routes, authenticated with customer API tokens
+	// instead of user logins
+	myRoutes := apiRoutes.Group("/my")
+	myRoutes.Use(api.RequireCustomerToken(), api.TrackUsage())
+	{
+		myRoutes.GET("/accounts", api.RequireTokenScope(auth.ScopeAccountsRead), api.GetMyAccounts)
+		myRoutes.GET("/usage", api.RequireTokenScope(auth.ScopeUsageRead), api.GetMyUsage)
+		myRoutes.GET("/statements", api.RequireTokenScope(auth.ScopeStatementsRead), api.GetMyStatements)
+		myRoutes.GET("/statements/:period", api.RequireTokenScope(auth.ScopeStatementsRead), api.GetMyStatement)
 	}
 
 	// Protected routes
 	protectedRoutes := apiRoutes.Group("")
-	protectedRoutes.Use(auth.AuthMiddleware())
+	protectedRoutes.Use(auth.AuthMiddleware(), api.TrackUsage(), api.RequireConsent())
 	{
-		// Customer routes
+		// API keys for scripts and CI, used in place of a JWT
+		protectedRoutes.POST("/auth/api-keys", api.CreateAPIKey)
+		protectedRoutes.GET("/auth/api-keys", api.GetAPIKeys)
+		protectedRoutes.PUT("/auth/api-keys/:id/allowed-cidrs", api.UpdateAPIKeyCIDRs)
+		protectedRoutes.DELETE("/auth/api-keys/:id", api.RevokeAPIKey)
+
+		// Customer routes; organization members only see their organization's
 		customers := protectedRoutes.Group("/customers")
+		customers.Use(api.ScopeToOrganization())
 		{
 			customers.GET("", api.GetCustomers)
 			customers.GET("/:id", api.GetCustomer)
+			customers.GET("/:id/diff", api.GetCustomerDiff)
 			customers.POST("", api.CreateCustomer)
 			customers.PUT("/:id", api.UpdateCustomer)
 			customers.DELETE("/:id", api.DeleteCustomer)
+			customers.POST("/:id/restore", api.RestoreCustomer)
 		}
 
-		// Account routes
+		// Account routes, scoped the same way
 		accounts := protectedRoutes.Group("/accounts")
+		accounts.Use(api.ScopeToOrganization())
 		{
 			accounts.GET("", api.GetAccounts)
 			accounts.GET("/:id", api.GetAccount)
+			// References are handed out on create and looked up right after, so
+			// this lookup must see the write even if the follower is behind
+			accounts.GET("/by-reference/:reference", api.ReadFromPrimary(), api.GetAccountByReference)
 			accounts.POST("", api.CreateAccount)
 			accounts.PUT("/:id", api.UpdateAccount)
 			accounts.DELETE("/:id", api.DeleteAccount)
+			accounts.POST("/:id/restore", api.RestoreAccount)
+			accounts.GET("/:id/notes", api.GetAccountNotes)
+			accounts.POST("/:id/notes", api.CreateAccountNote)
+			accounts.GET("/:id/settings", api.GetAccountSettings)
+			accounts.PATCH("/:id/settings", api.PatchAccountSettings)
+		}
+
+		// Account settings schemas
+		protectedRoutes.GET("/account-types", api.GetAccountTypes)
+		protectedRoutes.GET("/account-types/:type/schema", api.GetAccountTypeSchema)
+
+		// Search routes
+		protectedRoutes.GET("/search/notes", api.RequireStaff(), api.SearchNotes)
+
+		// Notification routes
+		protectedRoutes.GET("/notifications", api.GetNotifications)
+
+		// The caller's own account
+		protectedRoutes.GET("/me", api.GetProfile)
+		protectedRoutes.PUT("/me", api.UpdateProfile)
+		protectedRoutes.POST("/me/password", api.ChangePassword)
+		protectedRoutes.POST("/me/2fa/setup", api.SetupTwoFactor)
+		protectedRoutes.POST("/me/2fa/verify", api.VerifyTwoFactor)
+		protectedRoutes.DELETE("/me/2fa", api.DisableTwoFactor)
+
+		// The caller's defaults for the list endpoints
+		protectedRoutes.GET("/me/preferences", api.GetPreferences)
+		protectedRoutes.PUT("/me/preferences", api.UpdatePreferences)
+
+		// The caller's recent logins
+		protectedRoutes.GET("/me/security", api.GetMySecurity)
+
+		// Policy consent routes (exempt from RequireConsent)
+		protectedRoutes.GET("/consents", api.GetConsents)
+		protectedRoutes.POST("/consents", api.AcceptConsent)
+
+		// Organization routes; members can look, owners manage invitations and members
+		protectedRoutes.GET("/organization", api.GetMyOrganization)
+		orgs := protectedRoutes.Group("/orgs/:id")
+		{
+			orgs.GET("", api.RequireOrganizationMember(), api.GetOrganization)
+			orgs.GET("/members", api.RequireOrganizationMember(), api.GetMembers)
+			orgs.GET("/usage", api.RequireOrganizationMember(), api.GetOrganizationAPIUsage)
+			orgs.DELETE("/members/:username", api.RequireOrganizationOwner(), api.RemoveMember)
+			orgs.GET("/invitations", api.RequireOrganizationOwner(), api.GetInvitations)
+			orgs.POST("/invitations", api.RequireOrganizationOwner(), api.CreateInvitation)
+			orgs.DELETE("/invitations/:invitation_id", api.RequireOrganizationOwner(), api.RevokeInvitation)
 		}
 
-		// Analytics routes
+		// Responses of slow requests continued in the background by AsyncAfter
+		protectedRoutes.GET("/jobs/:id", api.GetAsyncResult)
+
+		// Analytics routes, across every organization
 		analytics := protectedRoutes.Group("/analytics")
+		analytics.Use(api.RequireStaff())
+		{
+			analytics.GET("", api.AsyncAfter(api.WithMeta(api.GetAnalytics)))
+			analytics.GET("/customers/:customer_id", api.AsyncAfter(api.WithMeta(api.GetCustomerAnalytics)))
+			analytics.GET("/anomalies", api.WithMeta(api.GetAnomalies))
+			analytics.GET("/api-usage", api.WithMeta(api.GetAPIUsage))
+			analytics.GET("/duplicates", api.AsyncAfter(api.WithMeta(api.GetDuplicateAccounts)))
+			analytics.GET("/heatmap", api.AsyncAfter(api.WithMeta(api.GetUsageHeatmap)))
+			analytics.GET("/forecast", api.AsyncAfter(api.WithMeta(api.GetForecast)))
+			analytics.GET("/data-quality", api.WithMeta(api.GetDataQuality))
+			analytics.GET("/sla", api.WithMeta(api.GetSLACompliance))
+		}
+
+		// Admin routes
+		admin := protectedRoutes.Group("/admin")
+		admin.Use(api.RequireAdmin())
+		{
+			admin.GET("/config", api.GetConfig)
+			admin.PUT("/config", api.UpdateConfig)
+			admin.POST("/config/reload", api.ReloadConfig)
+			admin.POST("/integrity/check", api.CheckIntegrity)
+			admin.GET("/db/maintenance", api.GetDBMaintenance)
+			admin.GET("/db/slow-queries", api.GetSlowQueries)
+			admin.GET("/schema", api.GetSchema)
+			admin.GET("/jobs", api.GetJobs)
+			admin.GET("/jobs/:id", api.GetJob)
+			admin.GET("/jobs/:id/events", api.StreamJobProgress)
+			admin.POST("/jobs/:id/cancel", api.CancelJob)
+			admin.POST("/seed", api.ReseedDatabase)
+			admin.POST("/contacts/normalize", api.NormalizeContacts)
+			admin.GET("/contacts/issues", api.GetContactIssues)
+			admin.POST("/analytics/heatmap/refresh", api.RefreshUsageHeatmap)
+			admin.POST("/data-quality/refresh", api.RefreshDataQuality)
+			admin.GET("/history/diff", api.AsyncAfter(api.GetSnapshotDiff))
+			admin.POST("/imports", api.CreateImportUpload)
+			admin.POST("/imports/stream", api.StreamImport)
+			admin.GET("/imports/:id", api.GetImportUpload)
+			admin.PUT("/imports/:id/parts/:part", api.PutImportUploadPart)
+			admin.POST("/imports/:id/complete", api.CompleteImportUpload)
+			admin.DELETE("/imports/:id", api.AbortImportUpload)
+			admin.POST("/webhooks", api.CreateWebhookEndpoint)
+			admin.GET("/webhooks", api.GetWebhookEndpoints)
+			admin.DELETE("/webhooks/:id", api.DeleteWebhookEndpoint)
+			admin.GET("/webhooks/:id/deliveries", api.GetWebhookDeliveries)
+			admin.POST("/customers/:id/tokens", api.CreateCustomerToken)
+			admin.GET("/customers/:id/tokens", api.GetCustomerTokens)
+			admin.DELETE("/customers/:id/tokens/:token_id", api.RevokeCustomerToken)
+			admin.GET("/customers/:id/statements", api.GetCustomerStatements)
+			admin.GET("/customers/:id/statements/:period", api.GetCustomerStatement)
+			admin.GET("/pii/access-log", api.GetPIIAccessLog)
+			admin.GET("/audit", api.GetAuditLog)
+			admin.GET("/queue/jobs", api.ListQueuedJobs)
+			admin.POST("/queue/jobs", api.EnqueueJob)
+			admin.POST("/queue/jobs/:id/retry", api.RetryQueuedJob)
+			admin.GET("/consents", api.GetPolicyConsents)
+			admin.GET("/registrations", api.GetRegistrations)
+			admin.POST("/registrations/:id/approve", api.ApproveRegistration)
+			admin.POST("/registrations/:id/reject", api.RejectRegistration)
+			admin.POST("/sla-rules", api.CreateSLARule)
+			admin.GET("/sla-rules", api.GetSLARules)
+			admin.DELETE("/sla-rules/:id", api.DeleteSLARule)
+			admin.POST("/sla-rules/evaluate", api.EvaluateSLAs)
+			admin.GET("/security-posture", api.GetSecurityPosture)
+		}
+
+		// Chaos routes are exempt from the faults they inject, so they can
+		// always be switched off again
+		chaosRoutes := protectedRoutes.Group("/admin/chaos")
+		chaosRoutes.Use(api.ExemptFromChaos(), api.RequireAdmin())
 		{
-			analytics.GET("", api.GetAnalytics)
-			analytics.GET("/customers/:customer_id", api.GetCustomerAnalytics)
+			chaosRoutes.GET("", api.GetChaos)
+			chaosRoutes.PUT("", api.UpdateChaos)
+			chaosRoutes.DELETE("", api.ResetChaos)
 		}
 	}
 
@@ -141,3 +420,28 @@ func main() {
 	}
 }
 
+// runMockServer serves the API surface from an in-memory store seeded with the
+// demo profile, so the frontend can be developed without any backing services
+func runMockServer() {
+	log.Println("APP_MODE=mock: serving API from in-memory store (data is lost on restart)")
+
+	router := gin.Default()
+	if err := api.TrustProxies(router); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	router.Use(tracing.Middleware())
+	router.GET("/docs/postman.json", api.GetPostmanCollection)
+	router.GET("/changelog", api.GetChangelog)
+	router.GET("/.well-known/jwks.json", api.GetJWKS)
+	mock.RegisterRoutes(router)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Mock server starting on port %s", port)
+	if err := router.Run(":" + port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Command worker runs jobs from the Postgres job queue (queued_jobs), as a
+// Heroku worker dyno:
+//
+//	worker: worker
+//
+// It handles the background task types (see jobs.RegisterQueueHandlers) and
+// is configured with JOB_QUEUES, JOB_WORKER_CONCURRENCY, JOB_POLL_INTERVAL,
+// JOB_SHUTDOWN_TIMEOUT, and JOB_RETENTION. Scale it with
+// `heroku ps:scale worker=N`; workers share the queue. On SIGTERM it stops
+// taking jobs and lets running ones finish before exiting.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/secrets"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables from .env file (if it exists)
+	_ = godotenv.Load()
+
+	// Resolve secrets from env, mounted files, or Vault (SECRETS_PROVIDER)
+	if err := secrets.Init(); err != nil {
+		log.Fatal("Failed to initialize secrets provider:", err)
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		log.Fatal("Failed to initialize primary database:", err)
+	}
+	defer db.CloseDB()
+
+	if err := db.InitAnalyticsDB(); err != nil {
+		log.Printf("Warning: Failed to initialize analytics database: %v", err)
+	}
+
+	// Heroku sends SIGTERM to stop a dyno, and kills it 30s later
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := db.EnsureSchema(ctx); err != nil {
+		db.CloseDB()
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	worker := jobs.NewWorker(jobs.WorkerOptionsFromEnv())
+	jobs.RegisterQueueHandlers(worker)
+	if err := worker.Run(ctx); err != nil {
+		db.CloseDB()
+		log.Fatal("Job worker failed:", err)
+	}
+	log.Println("Job worker stopped")
+}
@@ -0,0 +1,94 @@
+// Package abuse screens self-registrations for spam. A registration that
+// fills in the honeypot field, uses an address at a disposable email domain,
+// or comes from an IP address that registered too often recently is held for
+// an admin to review instead of creating the user.
+package abuse
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reasons a registration is flagged
+const (
+	// ReasonHoneypot means the hidden form field people never see was filled in
+	ReasonHoneypot = "honeypot"
+	// ReasonDisposableEmail means the email (or an email used as the
+	// username) is at a disposable domain
+	ReasonDisposableEmail = "disposable_email"
+	// ReasonIPVelocity means the IP address registered REGISTRATION_IP_LIMIT
+	// times within REGISTRATION_IP_WINDOW already
+	ReasonIPVelocity = "ip_velocity"
+)
+
+// disposableDomains are common throwaway email providers.
+// REGISTRATION_DISPOSABLE_DOMAINS adds to them.
+var disposableDomains = []string{
+	"10minutemail.com", "discard.email", "dispostable.com", "fakeinbox.com",
+	"getnada.com", "guerrillamail.com", "mailinator.com", "maildrop.cc",
+	"mintemail.com", "mohmal.com", "sharklasers.com", "temp-mail.org",
+	"tempmail.com", "throwawaymail.com", "trashmail.com", "yopmail.com",
+}
+
+// Registration is what a registration is screened on
+type Registration struct {
+	Username string
+	Email    string
+	Honeypot string
+	// RecentFromIP is how many registrations the client IP address made
+	// within the velocity window
+	RecentFromIP int
+}
+
+// Screen returns the reasons to hold a registration for review, or none if
+// it can be created right away
+func Screen(r Registration) []string {
+	var reasons []string
+	if strings.TrimSpace(r.Honeypot) != "" {
+		reasons = append(reasons, ReasonHoneypot)
+	}
+	if DisposableEmail(r.Email) || DisposableEmail(r.Username) {
+		reasons = append(reasons, ReasonDisposableEmail)
+	}
+	if limit, _ := IPVelocity(); limit > 0 && r.RecentFromIP >= limit {
+		reasons = append(reasons, ReasonIPVelocity)
+	}
+	return reasons
+}
+
+// DisposableEmail reports whether email is at a disposable domain or one of
+// its subdomains. Values that aren't email addresses never are.
+func DisposableEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+	extra := strings.Split(os.Getenv("REGISTRATION_DISPOSABLE_DOMAINS"), ",")
+	for _, list := range [][]string{disposableDomains, extra} {
+		for _, disposable := range list {
+			disposable = strings.ToLower(strings.TrimSpace(disposable))
+			if disposable != "" && (domain == disposable || strings.HasSuffix(domain, "."+disposable)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IPVelocity returns how many registrations an IP address may make
+// (REGISTRATION_IP_LIMIT, default 3, 0 disables the check) within how long
+// (REGISTRATION_IP_WINDOW, default 1h) before the next one is flagged
+func IPVelocity() (int, time.Duration) {
+	limit := 3
+	if value, err := strconv.Atoi(os.Getenv("REGISTRATION_IP_LIMIT")); err == nil && value >= 0 {
+		limit = value
+	}
+	window := time.Hour
+	if value, err := time.ParseDuration(os.Getenv("REGISTRATION_IP_WINDOW")); err == nil && value > 0 {
+		window = value
+	}
+	return limit, window
+}
@@ -0,0 +1,58 @@
+package abuse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDisposableEmail(t *testing.T) {
+	t.Setenv("REGISTRATION_DISPOSABLE_DOMAINS", " spam.example , ")
+
+	tests := []struct {
+		email      string
+		disposable bool
+	}{
+		{"bot@mailinator.com", true},
+		{"Bot@Mail.YOPMAIL.com", true},
+		{"bot@spam.example", true},
+		{"jane@example.com", false},
+		{"jane@notmailinator.com", false},
+		{"jane", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := DisposableEmail(tt.email); got != tt.disposable {
+			t.Errorf("DisposableEmail(%q) = %v, expected %v", tt.email, got, tt.disposable)
+		}
+	}
+}
+
+func TestScreen(t *testing.T) {
+	t.Setenv("REGISTRATION_IP_LIMIT", "2")
+	t.Setenv("REGISTRATION_IP_WINDOW", "30m")
+	if limit, window := IPVelocity(); limit != 2 || window != 30*time.Minute {
+		t.Fatalf("Expected 2 registrations per 30m, got %d per %v", limit, window)
+	}
+
+	tests := []struct {
+		registration Registration
+		reasons      []string
+	}{
+		{Registration{Username: "jane", Email: "jane@example.com", RecentFromIP: 1}, nil},
+		{Registration{Username: "jane", Honeypot: "https://spam.example"}, []string{ReasonHoneypot}},
+		{Registration{Username: "bot@guerrillamail.com"}, []string{ReasonDisposableEmail}},
+		{Registration{Username: "bot", Email: "bot@trashmail.com", RecentFromIP: 2}, []string{ReasonDisposableEmail, ReasonIPVelocity}},
+	}
+	for _, tt := range tests {
+		if got := Screen(tt.registration); fmt.Sprint(got) != fmt.Sprint(tt.reasons) {
+			t.Errorf("Screen(%+v) = %v, expected %v", tt.registration, got, tt.reasons)
+		}
+	}
+
+	t.Setenv("REGISTRATION_IP_LIMIT", "0")
+	if got := Screen(Registration{Username: "jane", RecentFromIP: 100}); len(got) != 0 {
+		t.Errorf("Expected the velocity check to be disabled, got %v", got)
+	}
+}
@@ -0,0 +1,170 @@
+// Package accountsettings defines the JSON Schema that governs the settings
+// document of each account type. Settings are stored as JSONB on the account
+// and validated against the schema on every write, so new settings only need
+// a schema change, not a migration.
+package accountsettings
+
+import (
+	"encoding/json"
+	"sort"
+
+	"saas-go-app/internal/jsonschema"
+)
+
+// DefaultType is the type of accounts created without one
+const DefaultType = "standard"
+
+const standardSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Standard account settings",
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"timezone": {
+			"type": "string",
+			"minLength": 1,
+			"maxLength": 64,
+			"description": "IANA time zone used for statements and reports, e.g. Europe/Berlin"
+		},
+		"currency": {
+			"type": "string",
+			"enum": ["USD", "EUR", "GBP", "JPY"],
+			"description": "Billing currency"
+		},
+		"invoice_day": {
+			"type": "integer",
+			"minimum": 1,
+			"maximum": 28,
+			"description": "Day of the month invoices are issued"
+		},
+		"notifications": {
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {
+				"email": {"type": "boolean", "description": "Send account notifications by email"},
+				"digest": {"type": "string", "enum": ["off", "daily", "weekly"], "description": "How often to send an activity digest"}
+			}
+		}
+	}
+}`
+
+const enterpriseSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Enterprise account settings",
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"timezone": {
+			"type": "string",
+			"minLength": 1,
+			"maxLength": 64,
+			"description": "IANA time zone used for statements and reports, e.g. Europe/Berlin"
+		},
+		"currency": {
+			"type": "string",
+			"enum": ["USD", "EUR", "GBP", "JPY"],
+			"description": "Billing currency"
+		},
+		"invoice_day": {
+			"type": "integer",
+			"minimum": 1,
+			"maximum": 28,
+			"description": "Day of the month invoices are issued"
+		},
+		"notifications": {
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {
+				"email": {"type": "boolean", "description": "Send account notifications by email"},
+				"digest": {"type": "string", "enum": ["off", "daily", "weekly"], "description": "How often to send an activity digest"}
+			}
+		},
+		"seats": {
+			"type": "integer",
+			"minimum": 1,
+			"maximum": 100000,
+			"description": "Licensed seats"
+		},
+		"sso_domain": {
+			"type": "string",
+			"pattern": "^[a-z0-9-]+(\\.[a-z0-9-]+)+$",
+			"description": "Email domain that signs in with SSO"
+		},
+		"ip_allowlist": {
+			"type": "array",
+			"maxItems": 50,
+			"items": {"type": "string", "minLength": 1, "maxLength": 64},
+			"description": "CIDR ranges allowed to sign in"
+		}
+	}
+}`
+
+var documents = map[string]string{
+	"standard":   standardSchema,
+	"enterprise": enterpriseSchema,
+}
+
+var schemas = make(map[string]*jsonschema.Schema)
+
+func init() {
+	for accountType, document := range documents {
+		schema, err := jsonschema.Parse([]byte(document))
+		if err != nil {
+			panic("accountsettings: invalid schema for " + accountType + ": " + err.Error())
+		}
+		schemas[accountType] = schema
+	}
+}
+
+// Types returns the account types, sorted
+func Types() []string {
+	types := make([]string, 0, len(documents))
+	for accountType := range documents {
+		types = append(types, accountType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidType reports whether accountType has a settings schema
+func ValidType(accountType string) bool {
+	_, ok := documents[accountType]
+	return ok
+}
+
+// Schema returns the JSON Schema document for an account type
+func Schema(accountType string) (json.RawMessage, bool) {
+	document, ok := documents[accountType]
+	return json.RawMessage(document), ok
+}
+
+// Validate checks settings against the schema for an account type
+func Validate(accountType string, settings map[string]interface{}) []jsonschema.Error {
+	schema, ok := schemas[accountType]
+	if !ok {
+		return []jsonschema.Error{{Message: "unknown account type " + accountType}}
+	}
+	return schema.Validate(settings)
+}
+
+// Merge applies a JSON merge patch (RFC 7396) to settings and returns the
+// result: objects are merged recursively and null removes a setting
+func Merge(settings, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(settings)+len(patch))
+	for name, value := range settings {
+		merged[name] = value
+	}
+	for name, value := range patch {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		if patchObject, ok := value.(map[string]interface{}); ok {
+			current, _ := merged[name].(map[string]interface{})
+			merged[name] = Merge(current, patchObject)
+			continue
+		}
+		merged[name] = value
+	}
+	return merged
+}
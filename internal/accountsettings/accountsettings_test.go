@@ -0,0 +1,56 @@
+package accountsettings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeAppliesMergePatch(t *testing.T) {
+	current := map[string]interface{}{
+		"timezone":      "UTC",
+		"currency":      "USD",
+		"notifications": map[string]interface{}{"email": true, "digest": "daily"},
+	}
+	patch := map[string]interface{}{
+		"currency":      nil,
+		"invoice_day":   float64(15),
+		"notifications": map[string]interface{}{"digest": "weekly"},
+	}
+
+	merged := Merge(current, patch)
+	expected := map[string]interface{}{
+		"timezone":      "UTC",
+		"invoice_day":   float64(15),
+		"notifications": map[string]interface{}{"email": true, "digest": "weekly"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+	if current["currency"] != "USD" {
+		t.Error("Expected Merge not to modify the current settings")
+	}
+}
+
+func TestValidateUsesSchemaForType(t *testing.T) {
+	enterpriseOnly := map[string]interface{}{"seats": float64(25), "sso_domain": "example.com"}
+	if errs := Validate("enterprise", enterpriseOnly); len(errs) != 0 {
+		t.Errorf("Expected enterprise settings to be valid, got %v", errs)
+	}
+	if errs := Validate("standard", enterpriseOnly); len(errs) != 2 {
+		t.Errorf("Expected seats and sso_domain to be rejected for standard accounts, got %v", errs)
+	}
+	if errs := Validate("standard", map[string]interface{}{"invoice_day": float64(31)}); len(errs) != 1 {
+		t.Errorf("Expected invoice_day above 28 to be rejected, got %v", errs)
+	}
+	if errs := Validate("unknown", map[string]interface{}{}); len(errs) != 1 {
+		t.Errorf("Expected unknown type to be rejected, got %v", errs)
+	}
+}
+
+func TestEveryTypeAcceptsEmptySettings(t *testing.T) {
+	for _, accountType := range Types() {
+		if errs := Validate(accountType, map[string]interface{}{}); len(errs) != 0 {
+			t.Errorf("Expected empty %s settings to be valid, got %v", accountType, errs)
+		}
+	}
+}
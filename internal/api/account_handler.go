@@ -1,75 +1,229 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
+	"saas-go-app/internal/accountsettings"
 	"saas-go-app/internal/db"
 	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
+// accountRepo backs the account handlers; tests replace it with a fake
+var accountRepo repository.AccountRepository = repository.PostgresAccounts{}
+
 // GetAccounts retrieves all accounts
 // @Summary      List all accounts
-// @Description  Get a list of all accounts
+// @Description  Get a list of all accounts, or the accounts that existed at as_of, newest first, optionally filtered by status, type, and customer_id. Deleted accounts are left out unless an admin passes include_deleted=true. With limit, pages are returned with an opaque X-Next-Cursor header to pass back as cursor for the next page; the header is absent on the last page. With facets, the response is a models.AccountList instead of an array: the page plus, for each facet, the number of accounts matching the filter per value (across all pages), e.g. to render filter chips. With group_by=customer, the response is an array of models.CustomerAccounts instead: the customers with matching accounts, newest first, each with those accounts nested; limit and cursor then page through customers, and facets and fields aren't supported. limit, sort, fields, and tz default to the caller's preferences (see /me/preferences).
 // @Tags         accounts
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   models.Account
-// @Failure      500  {object}  map[string]string
+// @Param        as_of        query     string  false  "RFC 3339 timestamp to read historical data at"
+// @Param        limit        query     int     false  "Page size (1-1000, default: all accounts)"
+// @Param        cursor       query     string  false  "X-Next-Cursor from the previous page"
+// @Param        status       query     string  false  "Only accounts with this status"
+// @Param        type         query     string  false  "Only accounts of this type"
+// @Param        customer_id  query     int     false  "Only accounts of this customer"
+// @Param        facets       query     string  false  "Comma-separated facets to count: status, type, customer_id"
+// @Param        group_by     query     string  false  "Nest accounts under their customers"  Enums(customer)
+// @Param        sort         query     string  false  "Order by creation, newest first by default"  Enums(-created_at, created_at)
+// @Param        fields       query     string  false  "Comma-separated fields to return; id is always returned"
+// @Param        tz           query     string  false  "IANA time zone to return timestamps in"
+// @Param        include_deleted  query  bool  false  "Include soft-deleted accounts, and with group_by soft-deleted customers (admins only)"
+// @Success      200          {array}   models.Account
+// @Header       200          {string}  X-Next-Cursor  "Cursor for the next page, if any"
+// @Failure      400          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
 // @Router       /accounts [get]
 // @Security     BearerAuth
 func GetAccounts(c *gin.Context) {
-	rows, err := db.PrimaryDB.Query(
-		"SELECT id, customer_id, name, status, created_at, updated_at FROM accounts ORDER BY created_at DESC",
-	)
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "customer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by, expected customer"})
+		return
+	}
+	resource := "accounts"
+	if groupBy != "" {
+		// Grouped pages resume after a customer, not an account
+		resource = "accounts-by-customer"
+	}
+	p, ok := parsePage(c, resource)
+	if !ok {
+		return
+	}
+	filter, ok := parseAccountFilter(c)
+	if !ok {
+		return
+	}
+	facets, ok := parseFacets(c, repository.AccountFacets)
+	if !ok {
+		return
+	}
+	if groupBy != "" {
+		if facets != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "facets can't be combined with group_by"})
+			return
+		}
+		if _, ok := c.GetQuery("fields"); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fields can't be combined with group_by"})
+			return
+		}
+	}
+	view, ok := parseListView(c, "accounts")
+	if !ok {
+		return
+	}
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+
+	opts := p.options(asOf)
+	opts.Filter = filter
+	opts.IncludeDeleted = includeDeleted
+	if groupBy != "" {
+		// Grouped rows aren't accounts, so only the time zone applies
+		getAccountsByCustomer(c, p, opts, listView{location: view.location})
+		return
+	}
+
+	accounts, err := accountRepo.List(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
 		return
 	}
-	defer rows.Close()
+	if p.more(len(accounts)) {
+		accounts = accounts[:p.Limit]
+		last := accounts[p.Limit-1]
+		p.next(c, last.CreatedAt, last.ID)
+	}
 
-	var accounts []models.Account
-	for rows.Next() {
-		var account models.Account
-		if err := rows.Scan(&account.ID, &account.CustomerID, &account.Name, &account.Status, &account.CreatedAt, &account.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan account"})
-			return
+	if facets == nil {
+		c.JSON(http.StatusOK, view.render(accounts))
+		return
+	}
+	counts, err := accountRepo.Facets(c.Request.Context(), repository.ListOptions{AsOf: asOf, Filter: filter, IncludeDeleted: includeDeleted}, facets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count account facets"})
+		return
+	}
+	if accounts == nil {
+		accounts = []models.Account{}
+	}
+	if len(view.fields) > 0 {
+		c.JSON(http.StatusOK, gin.H{"accounts": view.render(accounts), "facets": counts})
+		return
+	}
+	c.JSON(http.StatusOK, models.AccountList{Accounts: view.render(accounts).([]models.Account), Facets: counts})
+}
+
+// getAccountsByCustomer responds with the customers that have accounts
+// matching opts, the options for page p, each with those accounts nested,
+// rendered with view
+func getAccountsByCustomer(c *gin.Context, p page, opts repository.ListOptions, view listView) {
+	customers, err := accountRepo.ListByCustomer(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+	if p.more(len(customers)) {
+		customers = customers[:p.Limit]
+		last := customers[p.Limit-1]
+		p.next(c, last.CreatedAt, last.ID)
+	}
+	if customers == nil {
+		customers = []models.CustomerAccounts{}
+	}
+	c.JSON(http.StatusOK, view.render(customers))
+}
+
+// parseAccountFilter reads the status, type, and customer_id list filters. It
+// writes a 400 response and returns false if customer_id isn't a number.
+func parseAccountFilter(c *gin.Context) (map[string]interface{}, bool) {
+	filter := map[string]interface{}{}
+	for _, field := range []string{"status", "type"} {
+		if value := c.Query(field); value != "" {
+			filter[field] = value
 		}
-		accounts = append(accounts, account)
+	}
+	if value := c.Query("customer_id"); value != "" {
+		customerID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return nil, false
+		}
+		filter["customer_id"] = customerID
+	}
+	return filter, true
+}
+
+// parseFacets reads the comma-separated facets parameter, returning nil when
+// it is absent. It writes a 400 response and returns false for facets not in allowed.
+func parseFacets(c *gin.Context, allowed map[string]string) ([]string, bool) {
+	value, ok := c.GetQuery("facets")
+	if !ok {
+		return nil, true
 	}
 
-	c.JSON(http.StatusOK, accounts)
+	facets := []string{}
+	seen := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			names := make([]string, 0, len(allowed))
+			for allowedName := range allowed {
+				names = append(names, allowedName)
+			}
+			sort.Strings(names)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown facet " + name + ", expected one of: " + strings.Join(names, ", ")})
+			return nil, false
+		}
+		seen[name] = true
+		facets = append(facets, name)
+	}
+	return facets, true
 }
 
 // GetAccount retrieves a single account by ID
 // @Summary      Get account by ID
-// @Description  Get a specific account by its ID
+// @Description  Get a specific account by its ID, optionally as it was at as_of. The ETag header is the account's version, to send back in If-Match when updating it.
 // @Tags         accounts
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Account ID"
-// @Success      200  {object}  models.Account
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Param        id     path      string  true   "Account ID or UUID"
+// @Param        as_of  query     string  false  "RFC 3339 timestamp to read historical data at"
+// @Success      200    {object}  models.Account
+// @Header       200    {string}  ETag  "The account's version"
+// @Failure      400    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
 // @Router       /accounts/{id} [get]
 // @Security     BearerAuth
 func GetAccount(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+	id, ok := parseAccountID(c, "id")
+	if !ok {
+		return
+	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
 		return
 	}
 
-	var account models.Account
-	err = db.PrimaryDB.QueryRow(
-		"SELECT id, customer_id, name, status, created_at, updated_at FROM accounts WHERE id = $1",
-		id,
-	).Scan(&account.ID, &account.CustomerID, &account.Name, &account.Status, &account.CreatedAt, &account.UpdatedAt)
-
-	if err == sql.ErrNoRows {
+	account, err := accountRepo.Get(c.Request.Context(), id, asOf)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 		return
 	}
@@ -78,6 +232,7 @@ func GetAccount(c *gin.Context) {
 		return
 	}
 
+	setETag(c, account.Version)
 	c.JSON(http.StatusOK, account)
 }
 
@@ -98,38 +253,87 @@ func CreateAccount(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Type == "" {
+		req.Type = accountsettings.DefaultType
+	}
+	if !accountsettings.ValidType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account type, expected one of: " + strings.Join(accountsettings.Types(), ", ")})
+		return
+	}
 
-	var account models.Account
-	err := db.PrimaryDB.QueryRow(
-		"INSERT INTO accounts (customer_id, name, status) VALUES ($1, $2, $3) RETURNING id, customer_id, name, status, created_at, updated_at",
-		req.CustomerID, req.Name, req.Status,
-	).Scan(&account.ID, &account.CustomerID, &account.Name, &account.Status, &account.CreatedAt, &account.UpdatedAt)
-
+	account, err := accountRepo.Create(c.Request.Context(), req)
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
 		return
 	}
 
+	setETag(c, account.Version)
 	c.JSON(http.StatusCreated, account)
 }
 
+// GetAccountByReference retrieves a single account by its human-friendly reference
+// @Summary      Get account by reference
+// @Description  Look up an account by its reference (e.g. ACC-000042-0003-6), optionally as it was at as_of. The ETag header is the account's version.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        reference  path      string  true   "Account reference"
+// @Param        as_of      query     string  false  "RFC 3339 timestamp to read historical data at"
+// @Success      200        {object}  models.Account
+// @Header       200        {string}  ETag  "The account's version"
+// @Failure      400        {object}  map[string]string
+// @Failure      404        {object}  map[string]string
+// @Router       /accounts/by-reference/{reference} [get]
+// @Security     BearerAuth
+func GetAccountByReference(c *gin.Context) {
+	reference := strings.ToUpper(c.Param("reference"))
+	if !db.ValidAccountReference(reference) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account reference"})
+		return
+	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	account, err := accountRepo.GetByReference(c.Request.Context(), reference, asOf)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account"})
+		return
+	}
+
+	setETag(c, account.Version)
+	c.JSON(http.StatusOK, account)
+}
+
 // UpdateAccount updates an existing account
 // @Summary      Update account
-// @Description  Update an existing account record
+// @Description  Update an existing account record. Send the version the update is based on, in If-Match (the ETag of the account) or the version field; If-Match: * skips the check. If the account has changed since, the update is refused with 409 and the current version.
 // @Tags         accounts
 // @Accept       json
 // @Produce      json
-// @Param        id       path      int                         true  "Account ID"
-// @Param        account  body      models.UpdateAccountRequest true  "Updated account data"
-// @Success      200      {object}  models.Account
-// @Failure      400      {object}  map[string]string
-// @Failure      404      {object}  map[string]string
+// @Param        id        path      string                       true   "Account ID or UUID"
+// @Param        If-Match  header    string                       false  "ETag of the version the update is based on"
+// @Param        account   body      models.UpdateAccountRequest  true   "Updated account data"
+// @Success      200       {object}  models.Account
+// @Header       200       {string}  ETag  "The account's new version"
+// @Failure      400       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      409       {object}  map[string]interface{}
+// @Failure      428       {object}  map[string]string
 // @Router       /accounts/{id} [put]
 // @Security     BearerAuth
 func UpdateAccount(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+	id, ok := parseAccountID(c, "id")
+	if !ok {
 		return
 	}
 
@@ -139,55 +343,110 @@ func UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	var account models.Account
-	err = db.PrimaryDB.QueryRow(
-		"UPDATE accounts SET name = $1, status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING id, customer_id, name, status, created_at, updated_at",
-		req.Name, req.Status, id,
-	).Scan(&account.ID, &account.CustomerID, &account.Name, &account.Status, &account.CreatedAt, &account.UpdatedAt)
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	req.Version = version
 
-	if err == sql.ErrNoRows {
+	account, err := accountRepo.Update(c.Request.Context(), id, req)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 		return
 	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		setETag(c, account.Version)
+		c.JSON(http.StatusConflict, gin.H{"error": "Account was changed by someone else, reload it and try again", "version": account.Version})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
 		return
 	}
 
+	setETag(c, account.Version)
 	c.JSON(http.StatusOK, account)
 }
 
 // DeleteAccount deletes an account
 // @Summary      Delete account
-// @Description  Delete an account by ID
+// @Description  Soft-delete an account by ID; POST /accounts/{id}/restore brings it back. With hard=true, admins remove the account permanently instead, whether or not it was soft-deleted.
 // @Tags         accounts
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Account ID"
-// @Success      200  {object}  map[string]string
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Param        id    path      string  true   "Account ID or UUID"
+// @Param        hard  query     bool    false  "Delete permanently (admins only)"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
 // @Router       /accounts/{id} [delete]
 // @Security     BearerAuth
 func DeleteAccount(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+	id, ok := parseAccountID(c, "id")
+	if !ok {
+		return
+	}
+	hard, ok := parseHardDelete(c)
+	if !ok {
 		return
 	}
 
-	result, err := db.PrimaryDB.Exec("DELETE FROM accounts WHERE id = $1", id)
+	var err error
+	message := "Account deleted successfully"
+	if hard {
+		err = accountRepo.Purge(c.Request.Context(), id)
+		message = "Account permanently deleted"
+	} else {
+		err = accountRepo.Delete(c.Request.Context(), id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// RestoreAccount undoes a soft delete
+// @Summary      Restore account
+// @Description  Restore a soft-deleted account. An account whose customer is deleted can't be restored on its own; restore the customer instead.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Account ID or UUID"
+// @Success      200  {object}  models.Account
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /accounts/{id}/restore [post]
+// @Security     BearerAuth
+func RestoreAccount(c *gin.Context) {
+	id, ok := parseAccountID(c, "id")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+	account, err := accountRepo.Restore(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted account not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The account's customer is deleted, restore the customer instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore account"})
+		return
+	}
+
+	setETag(c, account.Version)
+	c.JSON(http.StatusOK, account)
 }
 
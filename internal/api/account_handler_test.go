@@ -0,0 +1,269 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAccounts is an in-memory AccountRepository
+type fakeAccounts struct {
+	customerIDs map[int]bool
+	accounts    []models.Account
+}
+
+func (f *fakeAccounts) List(ctx context.Context, opts repository.ListOptions) ([]models.Account, error) {
+	var accounts []models.Account
+	for _, account := range f.accounts {
+		if status, ok := opts.Filter["status"]; ok && account.Status != status {
+			continue
+		}
+		if customerID, ok := opts.Filter["customer_id"]; ok && account.CustomerID != customerID {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (f *fakeAccounts) Facets(ctx context.Context, opts repository.ListOptions, names []string) (models.Facets, error) {
+	accounts, _ := f.List(ctx, opts)
+	facets := models.Facets{}
+	for _, name := range names {
+		facets[name] = map[string]int64{}
+		for _, account := range accounts {
+			switch name {
+			case "status":
+				facets[name][account.Status]++
+			case "type":
+				facets[name][account.Type]++
+			}
+		}
+	}
+	return facets, nil
+}
+
+func (f *fakeAccounts) ListByCustomer(ctx context.Context, opts repository.ListOptions) ([]models.CustomerAccounts, error) {
+	accounts, _ := f.List(ctx, opts)
+	var customers []models.CustomerAccounts
+	for _, account := range accounts {
+		if n := len(customers); n > 0 && customers[n-1].ID == account.CustomerID {
+			customers[n-1].Accounts = append(customers[n-1].Accounts, account)
+			continue
+		}
+		customers = append(customers, models.CustomerAccounts{
+			Customer: models.Customer{ID: account.CustomerID},
+			Accounts: []models.Account{account},
+		})
+	}
+	if opts.Limit > 0 && len(customers) > opts.Limit {
+		customers = customers[:opts.Limit]
+	}
+	return customers, nil
+}
+
+func (f *fakeAccounts) Get(ctx context.Context, id int, asOf *time.Time) (models.Account, error) {
+	for _, account := range f.accounts {
+		if account.ID == id {
+			return account, nil
+		}
+	}
+	return models.Account{}, repository.ErrNotFound
+}
+
+func (f *fakeAccounts) GetByReference(ctx context.Context, reference string, asOf *time.Time) (models.Account, error) {
+	for _, account := range f.accounts {
+		if account.Reference == reference {
+			return account, nil
+		}
+	}
+	return models.Account{}, repository.ErrNotFound
+}
+
+func (f *fakeAccounts) Create(ctx context.Context, req models.CreateAccountRequest) (models.Account, error) {
+	if !f.customerIDs[req.CustomerID] {
+		return models.Account{}, repository.ErrCustomerNotFound
+	}
+	account := models.Account{ID: len(f.accounts) + 1, CustomerID: req.CustomerID, Type: req.Type, Name: req.Name, Status: req.Status}
+	f.accounts = append(f.accounts, account)
+	return account, nil
+}
+
+func (f *fakeAccounts) Update(ctx context.Context, id int, req models.UpdateAccountRequest) (models.Account, error) {
+	return models.Account{}, repository.ErrNotFound
+}
+
+func (f *fakeAccounts) Delete(ctx context.Context, id int) error {
+	return repository.ErrNotFound
+}
+
+func (f *fakeAccounts) Restore(ctx context.Context, id int) (models.Account, error) {
+	return models.Account{}, repository.ErrNotFound
+}
+
+func (f *fakeAccounts) Purge(ctx context.Context, id int) error {
+	return repository.ErrNotFound
+}
+
+func (f *fakeAccounts) IDByUUID(ctx context.Context, uuid string) (int, error) {
+	for _, account := range f.accounts {
+		if account.UUID == uuid {
+			return account.ID, nil
+		}
+	}
+	return 0, repository.ErrNotFound
+}
+
+// useFakeAccounts swaps accountRepo for a fake for the duration of the test
+func useFakeAccounts(t *testing.T, fake *fakeAccounts) {
+	previous := accountRepo
+	accountRepo = fake
+	t.Cleanup(func() { accountRepo = previous })
+}
+
+func TestCreateAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fake := &fakeAccounts{customerIDs: map[int]bool{1: true}}
+	useFakeAccounts(t, fake)
+
+	router := gin.New()
+	router.POST("/api/accounts", CreateAccount)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"customer_id": 1, "name": "Main", "status": "active"}`, http.StatusCreated},
+		{`{"customer_id": 2, "name": "Main", "status": "active"}`, http.StatusBadRequest},
+		{`{"customer_id": 1, "name": "Main", "status": "active", "type": "bogus"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/api/accounts", bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	if len(fake.accounts) != 1 || fake.accounts[0].Type != "standard" {
+		t.Errorf("Expected one standard account to be created, got %+v", fake.accounts)
+	}
+}
+
+func TestGetAccountByReferenceNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeAccounts(t, &fakeAccounts{})
+
+	router := gin.New()
+	router.GET("/api/accounts/by-reference/:reference", GetAccountByReference)
+
+	tests := map[string]int{
+		"ACC-000042-0003-6": http.StatusNotFound,
+		"ACC-000042-0003-7": http.StatusBadRequest,
+	}
+	for reference, want := range tests {
+		req, _ := http.NewRequest("GET", "/api/accounts/by-reference/"+reference, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("Expected status %d for %s, got %d", want, reference, w.Code)
+		}
+	}
+}
+
+func TestGetAccountsFacets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeAccounts(t, &fakeAccounts{accounts: []models.Account{
+		{ID: 1, Type: "standard", Status: "active"},
+		{ID: 2, Type: "enterprise", Status: "active"},
+		{ID: 3, Type: "standard", Status: "inactive"},
+	}})
+
+	router := gin.New()
+	router.GET("/api/accounts", GetAccounts)
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/accounts"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?status=active&facets=type,status")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list models.AccountList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode account list: %v", err)
+	}
+	if len(list.Accounts) != 2 {
+		t.Errorf("Expected the 2 active accounts, got %d", len(list.Accounts))
+	}
+	if list.Facets["type"]["standard"] != 1 || list.Facets["type"]["enterprise"] != 1 || list.Facets["status"]["active"] != 2 {
+		t.Errorf("Expected facets counted over the filtered accounts, got %v", list.Facets)
+	}
+
+	var accounts []models.Account
+	if err := json.Unmarshal(get("").Body.Bytes(), &accounts); err != nil || len(accounts) != 3 {
+		t.Errorf("Expected a plain array without facets, got %d accounts (%v)", len(accounts), err)
+	}
+
+	for _, query := range []string{"?facets=name", "?customer_id=abc"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestGetAccountsByCustomer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeAccounts(t, &fakeAccounts{accounts: []models.Account{
+		{ID: 1, CustomerID: 1, Status: "active"},
+		{ID: 2, CustomerID: 1, Status: "inactive"},
+		{ID: 3, CustomerID: 2, Status: "active"},
+		{ID: 4, CustomerID: 3, Status: "active"},
+	}})
+
+	router := gin.New()
+	router.GET("/api/accounts", GetAccounts)
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/accounts"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?group_by=customer&status=active&limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var customers []models.CustomerAccounts
+	if err := json.Unmarshal(w.Body.Bytes(), &customers); err != nil {
+		t.Fatalf("Failed to decode customers: %v", err)
+	}
+	if len(customers) != 2 || customers[0].ID != 1 || len(customers[0].Accounts) != 1 {
+		t.Errorf("Expected a page of 2 customers with their active accounts nested, got %+v", customers)
+	}
+	if w.Header().Get("X-Next-Cursor") == "" {
+		t.Error("Expected a cursor to the next page of customers")
+	}
+
+	for _, query := range []string{"?group_by=status", "?group_by=customer&facets=status"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
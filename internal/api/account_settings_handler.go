@@ -0,0 +1,173 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"saas-go-app/internal/accountsettings"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccountSettings returns an account's settings document
+// @Summary      Get account settings
+// @Description  Get the settings document of an account. Its shape is described by the JSON Schema for the account's type (see /account-types).
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Account ID or UUID"
+// @Success      200  {object}  models.AccountSettings
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /accounts/{id}/settings [get]
+// @Security     BearerAuth
+func GetAccountSettings(c *gin.Context) {
+	id, ok := parseAccountID(c, "id")
+	if !ok || !requireInOrganizationScope(c, "account", id, "Account not found") {
+		return
+	}
+
+	settings := models.AccountSettings{AccountID: id}
+	var document []byte
+	err := db.Primary(c.Request.Context()).QueryRow(
+		"SELECT type, settings, updated_at FROM accounts WHERE id = $1 AND deleted_at IS NULL", id,
+	).Scan(&settings.Type, &document, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account settings"})
+		return
+	}
+	if err := json.Unmarshal(document, &settings.Settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode account settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// PatchAccountSettings merges changes into an account's settings document
+// @Summary      Update account settings
+// @Description  Apply a JSON merge patch (RFC 7396) to an account's settings: nested objects are merged and null removes a setting. The result must match the JSON Schema for the account's type, otherwise nothing is saved and every violation is listed in details.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                  true  "Account ID or UUID"
+// @Param        patch  body      map[string]interface{}  true  "Settings to change"
+// @Success      200    {object}  models.AccountSettings
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /accounts/{id}/settings [patch]
+// @Security     BearerAuth
+func PatchAccountSettings(c *gin.Context) {
+	id, ok := parseAccountID(c, "id")
+	if !ok || !requireInOrganizationScope(c, "account", id, "Account not found") {
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Settings patch must be a JSON object"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := db.Primary(ctx).Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account settings"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the row so concurrent patches are applied one after the other
+	settings := models.AccountSettings{AccountID: id}
+	var document []byte
+	err = tx.QueryRowContext(ctx, "SELECT type, settings FROM accounts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id).Scan(&settings.Type, &document)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account settings"})
+		return
+	}
+	var current map[string]interface{}
+	if err := json.Unmarshal(document, &current); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode account settings"})
+		return
+	}
+
+	settings.Settings = accountsettings.Merge(current, patch)
+	if errs := accountsettings.Validate(settings.Type, settings.Settings); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Settings do not match the schema for " + settings.Type + " accounts",
+			"details": errs,
+		})
+		return
+	}
+
+	document, err = json.Marshal(settings.Settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode account settings"})
+		return
+	}
+	err = tx.QueryRowContext(ctx,
+		"UPDATE accounts SET settings = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING updated_at",
+		document, id,
+	).Scan(&settings.UpdatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account settings"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetAccountTypes lists the account types and their settings schemas
+// @Summary      List account types
+// @Description  Get every account type with the JSON Schema its settings document must match, so clients can build settings forms and validate before saving
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.AccountType
+// @Router       /account-types [get]
+// @Security     BearerAuth
+func GetAccountTypes(c *gin.Context) {
+	types := []models.AccountType{}
+	for _, accountType := range accountsettings.Types() {
+		schema, _ := accountsettings.Schema(accountType)
+		types = append(types, models.AccountType{Type: accountType, Schema: schema})
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// GetAccountTypeSchema returns the settings schema of one account type
+// @Summary      Get settings schema
+// @Description  Get the JSON Schema document that settings of the given account type must match
+// @Tags         accounts
+// @Produce      json
+// @Param        type  path      string  true  "Account type"
+// @Success      200   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]string
+// @Router       /account-types/{type}/schema [get]
+// @Security     BearerAuth
+func GetAccountTypeSchema(c *gin.Context) {
+	schema, ok := accountsettings.Schema(c.Param("type"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account type not found"})
+		return
+	}
+	c.Data(http.StatusOK, "application/schema+json", schema)
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/forecast"
+	"saas-go-app/internal/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,52 +29,54 @@ type AnalyticsResponse struct {
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  AnalyticsResponse
+// @Failure      403  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /analytics [get]
 // @Security     BearerAuth
 func GetAnalytics(c *gin.Context) {
-	// Use analytics DB (follower pool) for read-only analytics queries
-	analyticsDB := db.AnalyticsDB
-	if analyticsDB == nil {
-		analyticsDB = db.PrimaryDB
-	}
+	// The counts are independent, so they run concurrently on the analytics
+	// DB (follower pool)
+	fanOut := db.NewFanOut(c.Request.Context())
 
 	var totalCustomers int
-	err := analyticsDB.QueryRow("SELECT COUNT(*) FROM customers").Scan(&totalCustomers)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer count"})
-		return
-	}
+	fanOut.Go("customer count", func(analyticsDB db.Handle) error {
+		return analyticsDB.QueryRow("SELECT COUNT(*) FROM customers WHERE deleted_at IS NULL").Scan(&totalCustomers)
+	})
 
 	var totalAccounts int
-	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&totalAccounts)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account count"})
-		return
-	}
+	fanOut.Go("account count", func(analyticsDB db.Handle) error {
+		return analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE deleted_at IS NULL").Scan(&totalAccounts)
+	})
 
 	var activeAccounts int
-	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'active'").Scan(&activeAccounts)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch active account count"})
-		return
-	}
+	fanOut.Go("active account count", func(analyticsDB db.Handle) error {
+		return analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'active' AND deleted_at IS NULL").Scan(&activeAccounts)
+	})
 
 	var inactiveAccounts int
-	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'inactive'").Scan(&inactiveAccounts)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inactive account count"})
-		return
-	}
+	fanOut.Go("inactive account count", func(analyticsDB db.Handle) error {
+		return analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'inactive' AND deleted_at IS NULL").Scan(&inactiveAccounts)
+	})
 
 	var avgAccountsPerCustomer float64
-	if totalCustomers > 0 {
-		err = analyticsDB.QueryRow(
-			"SELECT COALESCE(AVG(account_count), 0) FROM (SELECT customer_id, COUNT(*) as account_count FROM accounts GROUP BY customer_id) AS subquery",
+	fanOut.Go("average accounts per customer", func(analyticsDB db.Handle) error {
+		err := analyticsDB.QueryRow(
+			"SELECT COALESCE(AVG(account_count), 0) FROM (SELECT customer_id, COUNT(*) as account_count FROM accounts WHERE deleted_at IS NULL GROUP BY customer_id) AS subquery",
 		).Scan(&avgAccountsPerCustomer)
 		if err != nil {
 			avgAccountsPerCustomer = 0
 		}
+		return nil
+	})
+
+	if err := fanOut.Wait(); err != nil {
+		var queryErr *db.QueryError
+		if errors.As(err, &queryErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + queryErr.Query})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch analytics"})
+		return
 	}
 
 	response := AnalyticsResponse{
@@ -85,27 +92,30 @@ func GetAnalytics(c *gin.Context) {
 
 // GetCustomerAnalytics retrieves analytics for a specific customer
 // @Summary      Get customer analytics
-// @Description  Get analytics for a specific customer including account counts
+// @Description  Get analytics for a specific customer including account counts. customer_id is the customer's ID or UUID, and is returned as given.
 // @Tags         analytics
 // @Accept       json
 // @Produce      json
-// @Param        customer_id  path      string  true  "Customer ID"
+// @Param        customer_id  path      string  true  "Customer ID or UUID"
 // @Success      200          {object}  map[string]interface{}
+// @Failure      400          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      404          {object}  map[string]string
 // @Failure      500          {object}  map[string]string
 // @Router       /analytics/customers/{customer_id} [get]
 // @Security     BearerAuth
 func GetCustomerAnalytics(c *gin.Context) {
-	customerID := c.Param("customer_id")
-
-	analyticsDB := db.AnalyticsDB
-	if analyticsDB == nil {
-		analyticsDB = db.PrimaryDB
+	customerID, ok := parseCustomerID(c, "customer_id")
+	if !ok {
+		return
 	}
 
+	analyticsDB := db.Analytics(c.Request.Context())
+
 	var accountCount int
 	var activeCount int
 	err := analyticsDB.QueryRow(
-		"SELECT COUNT(*), COUNT(CASE WHEN status = 'active' THEN 1 END) FROM accounts WHERE customer_id = $1",
+		"SELECT COUNT(*), COUNT(CASE WHEN status = 'active' THEN 1 END) FROM accounts WHERE customer_id = $1 AND deleted_at IS NULL",
 		customerID,
 	).Scan(&accountCount, &activeCount)
 	if err != nil {
@@ -114,10 +124,240 @@ func GetCustomerAnalytics(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"customer_id":   customerID,
+		"customer_id":   c.Param("customer_id"),
 		"total_accounts": accountCount,
 		"active_accounts": activeCount,
 		"inactive_accounts": accountCount - activeCount,
 	})
 }
 
+
+// GetAnomalies retrieves recent anomaly events from the follower pool
+// @Summary      List anomaly events
+// @Description  Get the most recent write-volume and login-failure anomalies detected by the background job. With format=columnar the response is an object with one array per field instead of an array of objects.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        format  query     string  false  "Response layout: rows (default) or columnar"
+// @Success      200     {array}   models.AnomalyEvent
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /analytics/anomalies [get]
+// @Security     BearerAuth
+func GetAnomalies(c *gin.Context) {
+	if !validRowFormat(c) {
+		return
+	}
+
+	analyticsDB := db.Analytics(c.Request.Context())
+
+	rows, err := analyticsDB.Query(
+		"SELECT id, metric, observed, baseline_mean, baseline_stddev, threshold, detected_at FROM anomaly_events ORDER BY detected_at DESC LIMIT 100",
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch anomaly events"})
+		return
+	}
+	defer rows.Close()
+
+	events := []models.AnomalyEvent{}
+	for rows.Next() {
+		var event models.AnomalyEvent
+		if err := rows.Scan(&event.ID, &event.Metric, &event.Observed, &event.BaselineMean, &event.BaselineStdDev, &event.Threshold, &event.DetectedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan anomaly event"})
+			return
+		}
+		events = append(events, event)
+	}
+
+	RespondRows(c, events)
+}
+
+// GetDuplicateAccounts retrieves the latest duplicate account report from the follower pool
+// @Summary      List likely duplicate accounts
+// @Description  Get pairs of accounts within the same customer that are likely duplicates (similar normalized names created close together), with a suggestion of which account to keep. The report is refreshed by a background job. With format=columnar the response is an object with one array per field instead of an array of objects.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        customer_id  query     int     false  "Only show duplicates for this customer"
+// @Param        min_score    query     number  false  "Minimum score between 0 and 1"
+// @Param        format       query     string  false  "Response layout: rows (default) or columnar"
+// @Success      200          {array}   models.DuplicateCandidate
+// @Failure      400          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /analytics/duplicates [get]
+// @Security     BearerAuth
+func GetDuplicateAccounts(c *gin.Context) {
+	if !validRowFormat(c) {
+		return
+	}
+	minScore, err := strconv.ParseFloat(c.DefaultQuery("min_score", "0"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_score"})
+		return
+	}
+
+	var customerID *int
+	if value := c.Query("customer_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+			return
+		}
+		customerID = &id
+	}
+
+	analyticsDB := db.Analytics(c.Request.Context())
+
+	rows, err := analyticsDB.Query(`
+		SELECT d.customer_id, d.keep_account_id, k.name, d.merge_account_id, m.name,
+			d.score, d.name_similarity, d.created_apart_seconds, d.detected_at
+		FROM account_duplicate_candidates d
+		JOIN accounts k ON k.id = d.keep_account_id
+		JOIN accounts m ON m.id = d.merge_account_id
+		WHERE d.score >= $1 AND ($2::int IS NULL OR d.customer_id = $2)
+		ORDER BY d.score DESC, d.customer_id
+		LIMIT 500`,
+		minScore, customerID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duplicate accounts"})
+		return
+	}
+	defer rows.Close()
+
+	candidates := []models.DuplicateCandidate{}
+	var detectedAt *time.Time
+	for rows.Next() {
+		var candidate models.DuplicateCandidate
+		if err := rows.Scan(&candidate.CustomerID, &candidate.KeepAccountID, &candidate.KeepName, &candidate.MergeAccountID, &candidate.MergeName,
+			&candidate.Score, &candidate.NameSimilarity, &candidate.CreatedApartSeconds, &candidate.DetectedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan duplicate account"})
+			return
+		}
+		candidates = append(candidates, candidate)
+		if detectedAt == nil || candidate.DetectedAt.After(*detectedAt) {
+			detectedAt = &candidate.DetectedAt
+		}
+	}
+
+	SetDataAsOf(c, detectedAt)
+	RespondRows(c, candidates)
+}
+
+// forecastSources maps forecast metrics to the table whose rows are counted
+var forecastSources = map[string]string{"accounts": "accounts", "customers": "customers"}
+
+// forecastZ is the z-score of the 95% prediction interval
+const forecastZ = 1.96
+
+// ForecastResponse represents daily signups and their projection
+type ForecastResponse struct {
+	Metric string `json:"metric"`
+	// Confidence is the coverage of the lower and upper bounds
+	Confidence float64 `json:"confidence"`
+	// SlopePerDay is how much daily signups grow (or shrink) per day
+	SlopePerDay float64 `json:"slope_per_day"`
+	// ProjectedTotal is the sum of the forecast values
+	ProjectedTotal float64               `json:"projected_total"`
+	History        []forecast.Point      `json:"history"`
+	Forecast       []forecast.Projection `json:"forecast"`
+}
+
+// BuildForecast fits daily history and projects it days ahead. The mock
+// server uses it too, so both return the same shape.
+func BuildForecast(metric string, history []forecast.Point, days int) (ForecastResponse, error) {
+	model, err := forecast.Fit(history)
+	if err != nil {
+		return ForecastResponse{}, err
+	}
+	response := ForecastResponse{
+		Metric:      metric,
+		Confidence:  0.95,
+		SlopePerDay: model.Slope,
+		History:     history,
+		Forecast:    model.Project(days, forecastZ),
+	}
+	for _, projection := range response.Forecast {
+		response.ProjectedTotal += projection.Value
+	}
+	return response, nil
+}
+
+// GetForecast projects daily signups from the follower pool
+// @Summary      Forecast signups
+// @Description  Fit a linear trend with day-of-week seasonality to daily new accounts (or customers) over the last history days, excluding today, and project it days ahead with 95% prediction bounds
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        metric   query     string  false  "What to forecast: accounts (default) or customers"
+// @Param        history  query     int     false  "Days of history to fit (14-365, default 90)"
+// @Param        days     query     int     false  "Days to project (1-90, default 30)"
+// @Success      200      {object}  ForecastResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /analytics/forecast [get]
+// @Security     BearerAuth
+func GetForecast(c *gin.Context) {
+	metric, historyDays, days, ok := ParseForecastParams(c)
+	if !ok {
+		return
+	}
+
+	// generate_series fills days without signups with zeros
+	rows, err := db.Analytics(c.Request.Context()).Query(`
+		SELECT d::date, COUNT(t.id)
+		FROM generate_series(CURRENT_DATE - $1 * INTERVAL '1 day', CURRENT_DATE - INTERVAL '1 day', INTERVAL '1 day') d
+		LEFT JOIN `+forecastSources[metric]+` t ON t.created_at >= d AND t.created_at < d + INTERVAL '1 day'
+		GROUP BY d
+		ORDER BY d`,
+		historyDays,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch signups"})
+		return
+	}
+	defer rows.Close()
+
+	history := make([]forecast.Point, 0, historyDays)
+	for rows.Next() {
+		var point forecast.Point
+		if err := rows.Scan(&point.Date, &point.Value); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan signups"})
+			return
+		}
+		history = append(history, point)
+	}
+
+	response, err := BuildForecast(metric, history, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fit forecast"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ParseForecastParams reads and validates the metric, history, and days query
+// parameters. It writes a 400 response and returns false if any is invalid.
+func ParseForecastParams(c *gin.Context) (string, int, int, bool) {
+	metric := c.DefaultQuery("metric", "accounts")
+	if _, ok := forecastSources[metric]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metric, expected accounts or customers"})
+		return "", 0, 0, false
+	}
+	historyDays, err := strconv.Atoi(c.DefaultQuery("history", "90"))
+	if err != nil || historyDays < forecast.MinHistory || historyDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history, expected 14-365 days"})
+		return "", 0, 0, false
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days, expected 1-90"})
+		return "", 0, 0, false
+	}
+	return metric, historyDays, days, true
+}
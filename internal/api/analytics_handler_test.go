@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondRowsColumnar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	detected := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	events := []models.AnomalyEvent{
+		{ID: 1, Metric: "writes", Observed: 412, DetectedAt: detected},
+		{ID: 2, Metric: "login_failures", Observed: 37, DetectedAt: detected.Add(time.Hour)},
+	}
+	router := gin.New()
+	router.GET("/anomalies", func(c *gin.Context) { RespondRows(c, events) })
+
+	req, _ := http.NewRequest("GET", "/anomalies?format=columnar", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var columns map[string][]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &columns); err != nil {
+		t.Fatalf("Expected a columnar object, got %s", w.Body.String())
+	}
+	if len(columns) != 7 {
+		t.Errorf("Expected one column per field, got %v", columns)
+	}
+	if metrics := columns["metric"]; len(metrics) != 2 || metrics[0] != "writes" || metrics[1] != "login_failures" {
+		t.Errorf("Expected metrics in row order, got %v", metrics)
+	}
+	if observed := columns["observed"]; len(observed) != 2 || observed[0] != float64(412) {
+		t.Errorf("Expected observed values in row order, got %v", observed)
+	}
+
+	req, _ = http.NewRequest("GET", "/anomalies", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var rows []models.AnomalyEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &rows); err != nil || len(rows) != 2 {
+		t.Errorf("Expected rows by default, got %s", w.Body.String())
+	}
+}
+
+func TestGetAnomaliesInvalidFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/analytics/anomalies", GetAnomalies)
+
+	req, _ := http.NewRequest("GET", "/api/analytics/anomalies?format=csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
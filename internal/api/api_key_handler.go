@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var apiKeyIPDenials = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "api_key_ip_denials_total",
+	Help: "Requests rejected because their API key was used from outside its allowed ranges.",
+})
+
+const apiKeyColumns = "id, name, prefix, scopes, allowed_cidrs, created_at, last_used_at, last_used_ip, expires_at, revoked_at"
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (models.APIKey, error) {
+	var key models.APIKey
+	var lastUsedAt, expiresAt, revokedAt sql.NullTime
+	var lastUsedIP sql.NullString
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, db.Array(&key.Scopes), db.Array(&key.AllowedCIDRs), &key.CreatedAt, &lastUsedAt, &lastUsedIP, &expiresAt, &revokedAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	if lastUsedIP.Valid {
+		key.LastUsedIP = &lastUsedIP.String
+	}
+	key.ExpiresAt = nullTime(expiresAt)
+	key.RevokedAt = nullTime(revokedAt)
+	return key, err
+}
+
+// CreateAPIKey mints an API key for the caller's scripts and CI jobs
+// @Summary      Create API key
+// @Description  Mint an API key that authenticates as the caller in the X-API-Key header, in place of a JWT. Scopes default to read: read allows GET requests, write allows every method, and admin is needed on top for the admin routes. allowed_cidrs restricts where the key can be used from (CIDR ranges or IP addresses; empty allows any address). The key is only returned in this response. Keys can't be minted with another key.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        key  body      models.CreateAPIKeyRequest  true  "Key name, scopes, allowed ranges, and expiry"
+// @Success      201  {object}  models.APIKey
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/api-keys [post]
+// @Security     BearerAuth
+func CreateAPIKey(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't create API keys, log in to create one"})
+		return
+	}
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{auth.ScopeRead}
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidAPIKeyScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	cidrs, err := auth.NormalizeCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.AllowedCIDRs = cidrs
+
+	secret, err := auth.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+	key, err := insertAPIKey(c.Request.Context(), c.GetString("username"), req, secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	key.Key = secret
+	c.JSON(http.StatusCreated, key)
+}
+
+// insertAPIKey stores the hash of secret for username; tests replace it
+var insertAPIKey = func(ctx context.Context, username string, req models.CreateAPIKeyRequest, secret string) (models.APIKey, error) {
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		// expires_at is a TIMESTAMP, compared against UTC timestamps
+		utc := req.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	return scanAPIKey(db.Primary(ctx).QueryRow(
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, allowed_cidrs, expires_at)
+		 SELECT id, $2, $3, $4, $5, $6, $7 FROM users WHERE username = $1
+		 RETURNING `+apiKeyColumns,
+		username, req.Name, auth.APIKeyDisplay(secret), auth.HashAPIKey(secret), req.Scopes, req.AllowedCIDRs, expiresAt,
+	))
+}
+
+// GetAPIKeys lists the caller's API keys
+// @Summary      List API keys
+// @Description  List the caller's API keys, including revoked and expired ones, with the ranges each can be used from and when and from which IP address each was last used, without the keys themselves
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.APIKey
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/api-keys [get]
+// @Security     BearerAuth
+func GetAPIKeys(c *gin.Context) {
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = (SELECT id FROM users WHERE username = $1) ORDER BY id",
+		c.GetString("username"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan API key"})
+			return
+		}
+		keys = append(keys, key)
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// UpdateAPIKeyCIDRs changes where one of the caller's API keys can be used from
+// @Summary      Restrict API key
+// @Description  Replace the CIDR ranges or IP addresses one of the caller's API keys can be used from; empty allows any address. Requests from elsewhere get a 403 and publish an api_key.ip_denied event. Keys can't change their own or other keys' ranges.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int                              true  "API key ID"
+// @Param        cidrs body      models.UpdateAPIKeyCIDRsRequest  true  "Allowed ranges"
+// @Success      200   {object}  models.APIKey
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /auth/api-keys/{id}/allowed-cidrs [put]
+// @Security     BearerAuth
+func UpdateAPIKeyCIDRs(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't change API keys, log in to change one"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+	var req models.UpdateAPIKeyCIDRsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cidrs, err := auth.NormalizeCIDRs(req.AllowedCIDRs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := scanAPIKey(db.Primary(c.Request.Context()).QueryRow(
+		`UPDATE api_keys SET allowed_cidrs = $1
+		 WHERE id = $2 AND user_id = (SELECT id FROM users WHERE username = $3)
+		 RETURNING `+apiKeyColumns,
+		cidrs, id, c.GetString("username"),
+	))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// PublishAPIKeyIPDenied publishes api_key.ip_denied for auth.OnAPIKeyIPDenied
+// when an API key is used from outside its allowed ranges
+func PublishAPIKeyIPDenied(ctx context.Context, principal auth.APIKeyPrincipal, clientIP string) {
+	apiKeyIPDenials.Inc()
+	events.Publish(ctx, events.APIKeyIPDenied, gin.H{
+		"api_key_id":    principal.ID,
+		"username":      principal.Username,
+		"ip_address":    clientIP,
+		"allowed_cidrs": principal.AllowedCIDRs,
+	})
+}
+
+// RevokeAPIKey stops one of the caller's API keys from working
+// @Summary      Revoke API key
+// @Description  Revoke one of the caller's API keys; it is kept, with revoked_at set, so its last use stays on record
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "API key ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /auth/api-keys/{id} [delete]
+// @Security     BearerAuth
+func RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	result, err := db.Primary(c.Request.Context()).Exec(
+		"UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = (SELECT id FROM users WHERE username = $2)",
+		id, c.GetString("username"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
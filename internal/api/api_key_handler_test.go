@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := insertAPIKey
+	t.Cleanup(func() { insertAPIKey = previous })
+	var gotUser, gotSecret string
+	var gotScopes, gotCIDRs []string
+	insertAPIKey = func(ctx context.Context, username string, req models.CreateAPIKeyRequest, secret string) (models.APIKey, error) {
+		gotUser, gotSecret, gotScopes, gotCIDRs = username, secret, req.Scopes, req.AllowedCIDRs
+		return models.APIKey{ID: 3, Name: req.Name, Prefix: auth.APIKeyDisplay(secret), Scopes: req.Scopes}, nil
+	}
+
+	router := gin.New()
+	var principal *auth.APIKeyPrincipal
+	router.POST("/api/auth/api-keys", func(c *gin.Context) {
+		c.Set("username", "alice")
+		if principal != nil {
+			c.Set("api_key", *principal)
+		}
+	}, CreateAPIKey)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/api-keys", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"name": "CI"}`)
+	var key models.APIKey
+	if err := json.Unmarshal(w.Body.Bytes(), &key); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d with the key, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if key.Key != gotSecret || !strings.HasPrefix(key.Key, auth.APIKeyPrefix) || gotUser != "alice" {
+		t.Errorf("Expected alice's new key to be returned once, got %+v", key)
+	}
+	if len(gotScopes) != 1 || gotScopes[0] != auth.ScopeRead {
+		t.Errorf("Expected the read scope by default, got %v", gotScopes)
+	}
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"name": "CI", "scopes": ["accounts:read"]}`, http.StatusBadRequest},
+		{`{"name": "CI", "expires_at": "2020-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{`{"name": "CI", "allowed_cidrs": ["10.0.0.0/33"]}`, http.StatusBadRequest},
+		{`{"name": "CI", "scopes": ["read", "write"], "expires_at": "2999-01-01T00:00:00Z"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if w := post(tt.body); w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	if w := post(`{"name": "CI", "allowed_cidrs": ["203.0.113.9/24", "2001:db8::1"]}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d with allowed ranges, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if fmt.Sprint(gotCIDRs) != "[203.0.113.0/24 2001:db8::1/128]" {
+		t.Errorf("Expected the allowed ranges in canonical form, got %v", gotCIDRs)
+	}
+
+	principal = &auth.APIKeyPrincipal{ID: 3, Username: "alice", Scopes: []string{auth.ScopeWrite}}
+	if w := post(`{"name": "CI"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d when minting with a key, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestRequireAdminChecksAPIKeyScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/config", func(c *gin.Context) {
+		c.Set("username", "admin")
+		c.Set("api_key", auth.APIKeyPrincipal{ID: 1, Username: "admin", Scopes: []string{auth.ScopeRead, auth.ScopeWrite}})
+	}, RequireAdmin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/config", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a key without the admin scope, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultAsyncAfter leaves headroom below the Heroku router's 30s timeout, after
+// which the client gets an H12 error however the request ends
+const DefaultAsyncAfter = 25 * time.Second
+
+// asyncRetention is how long a deferred response can be collected once ready
+const asyncRetention = time.Hour
+
+// AsyncAccepted is returned with 202 when a request outlives the deadline
+type AsyncAccepted struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+// AsyncAfterFromEnv reads ASYNC_AFTER (default 25s, 0 disables)
+func AsyncAfterFromEnv() time.Duration {
+	value := os.Getenv("ASYNC_AFTER")
+	if value == "" {
+		return DefaultAsyncAfter
+	}
+	after, err := time.ParseDuration(value)
+	if err != nil || after < 0 {
+		log.Printf("Warning: Invalid value for ASYNC_AFTER (%s), using default %v", value, DefaultAsyncAfter)
+		return DefaultAsyncAfter
+	}
+	return after
+}
+
+// AsyncAfter wraps a slow synchronous handler (exports, big analytics) so it
+// never hits the router timeout. The handler runs as usual; if it hasn't
+// finished after ASYNC_AFTER, the client gets 202 with a status URL instead,
+// and the handler keeps running in the background. The status URL
+// (GET /api/jobs/:id) answers 202 until the handler finishes and then serves
+// its response exactly as it would have been sent.
+func AsyncAfter(handler gin.HandlerFunc) gin.HandlerFunc {
+	after := AsyncAfterFromEnv()
+	if after <= 0 {
+		return handler
+	}
+
+	return func(c *gin.Context) {
+		// The gin context is recycled once this function returns, so the
+		// handler gets its own copy. Its request must outlive the client's.
+		response := newBufferedResponse()
+		cp := c.Copy()
+		cp.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+		cp.Writer = response
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Panic in %s: %v", c.FullPath(), r)
+					response.reset()
+					cp.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				}
+			}()
+			handler(cp)
+		}()
+
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+		select {
+		case <-done:
+			response.replay(c)
+			return
+		case <-timer.C:
+		}
+
+		job := progress.Start("request")
+		id := job.Snapshot().JobID
+		deferred := &deferredResponse{owner: c.GetString("username"), job: job}
+		storeDeferred(id, deferred)
+		log.Printf("%s %s still running after %v, continuing as job %s", c.Request.Method, c.Request.URL.Path, after, id)
+
+		go func() {
+			<-done
+			deferred.finish(response)
+		}()
+
+		statusURL := "/api/jobs/" + id
+		c.Header("Location", statusURL)
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusAccepted, AsyncAccepted{JobID: id, Status: progress.StatusRunning, StatusURL: statusURL})
+	}
+}
+
+// GetAsyncResult returns the response of a request that was continued in the background
+// @Summary      Get a deferred response
+// @Description  Poll a request that took too long to answer synchronously. Returns 202 with the job's progress until it finishes, then the original response (status, headers, and body). With wait (e.g. 20s or 20), the request blocks until the job finishes or the wait elapses, whichever comes first; waits are capped at 25s. Only the user who made the request can read it; responses are kept for an hour.
+// @Tags         jobs
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true   "Job ID from the 202 response"
+// @Param        wait  query     string  false  "Longest to wait for the job to finish, as a duration or seconds (max 25s)"
+// @Success      200   {object}  map[string]interface{}  "The original response"
+// @Success      202   {object}  progress.Snapshot
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Router       /jobs/{id} [get]
+// @Security     BearerAuth
+func GetAsyncResult(c *gin.Context) {
+	wait, ok := longPollWait(c)
+	if !ok {
+		return
+	}
+	deferred, ok := loadDeferred(c.Param("id"))
+	if !ok || deferred.owner != c.GetString("username") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if wait > 0 && deferred.result() == nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		deferred.job.Wait(ctx)
+		cancel()
+	}
+	response := deferred.result()
+	if response == nil {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusAccepted, deferred.job.Snapshot())
+		return
+	}
+	response.replay(c)
+}
+
+// maxLongPollWait caps the wait parameter of job status endpoints, below the
+// Heroku router timeout like DefaultAsyncAfter
+const maxLongPollWait = DefaultAsyncAfter
+
+// longPollWait reads the wait query parameter, a duration (20s) or a number
+// of seconds, capped at maxLongPollWait. It answers 400 and returns false if
+// the value is invalid.
+func longPollWait(c *gin.Context) (time.Duration, bool) {
+	value := c.Query("wait")
+	if value == "" {
+		return 0, true
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		wait, err = time.Duration(seconds)*time.Second, convErr
+	}
+	if err != nil || wait < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wait, expected a duration such as 20s"})
+		return 0, false
+	}
+	if wait > maxLongPollWait {
+		wait = maxLongPollWait
+	}
+	return wait, true
+}
+
+// deferredResponse is a request that outlived its deadline
+type deferredResponse struct {
+	owner string
+	job   *progress.Job
+
+	mu       sync.Mutex
+	response *bufferedResponse
+	finished time.Time
+}
+
+func (d *deferredResponse) finish(response *bufferedResponse) {
+	d.mu.Lock()
+	d.response = response
+	d.finished = time.Now()
+	d.mu.Unlock()
+
+	var err error
+	if response.Status() >= http.StatusInternalServerError {
+		err = fmt.Errorf("request failed with status %d", response.Status())
+	}
+	d.job.Finish(err)
+}
+
+// result returns the response once the handler has finished, or nil
+func (d *deferredResponse) result() *bufferedResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.response
+}
+
+func (d *deferredResponse) expired(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.response != nil && now.Sub(d.finished) > asyncRetention
+}
+
+var (
+	deferredMu sync.Mutex
+	deferred   = make(map[string]*deferredResponse)
+)
+
+func storeDeferred(id string, response *deferredResponse) {
+	deferredMu.Lock()
+	defer deferredMu.Unlock()
+	now := time.Now()
+	for id, d := range deferred {
+		if d.expired(now) {
+			delete(deferred, id)
+		}
+	}
+	deferred[id] = response
+}
+
+func loadDeferred(id string) (*deferredResponse, bool) {
+	deferredMu.Lock()
+	defer deferredMu.Unlock()
+	d, ok := deferred[id]
+	return d, ok
+}
+
+// bufferedResponse is a gin.ResponseWriter that holds the response in memory
+// until it is replayed to a client
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+// replay writes the buffered response to c
+func (b *bufferedResponse) replay(c *gin.Context) {
+	for key, values := range b.header {
+		c.Writer.Header()[key] = values
+	}
+	c.Writer.Header().Set("Content-Length", strconv.Itoa(b.body.Len()))
+	c.Writer.WriteHeader(b.status)
+	_, _ = c.Writer.Write(b.body.Bytes())
+}
+
+func (b *bufferedResponse) reset() {
+	b.header = make(http.Header)
+	b.status = http.StatusOK
+	b.body.Reset()
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(data []byte) (int, error) { return b.body.Write(data) }
+
+func (b *bufferedResponse) WriteString(s string) (int, error) { return b.body.WriteString(s) }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if status > 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) WriteHeaderNow() {}
+
+func (b *bufferedResponse) Status() int { return b.status }
+
+func (b *bufferedResponse) Size() int { return b.body.Len() }
+
+func (b *bufferedResponse) Written() bool { return b.body.Len() > 0 }
+
+func (b *bufferedResponse) Flush() {}
+
+func (b *bufferedResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("buffered response cannot be hijacked")
+}
+
+func (b *bufferedResponse) CloseNotify() <-chan bool { return make(chan bool) }
+
+func (b *bufferedResponse) Pusher() http.Pusher { return nil }
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAsyncAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ASYNC_AFTER", "20ms")
+
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", c.GetHeader("X-User"))
+		c.Next()
+	})
+	router.GET("/api/fast", AsyncAfter(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"total": 1})
+	}))
+	router.GET("/api/slow", AsyncAfter(func(c *gin.Context) {
+		<-release
+		if c.Request.Context().Err() != nil {
+			t.Error("Expected the request context to outlive the client")
+		}
+		c.Header("X-Report", "slow")
+		c.JSON(http.StatusOK, gin.H{"total": c.Query("n")})
+	}))
+	router.GET("/api/jobs/:id", GetAsyncResult)
+
+	get := func(path, user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/fast", "alice"); w.Code != http.StatusOK || w.Body.String() != `{"total":1}` {
+		t.Errorf("Expected the fast response unchanged, got %d %s", w.Code, w.Body.String())
+	}
+
+	w := get("/api/slow?n=42", "alice")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	var accepted AsyncAccepted
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil || accepted.StatusURL == "" {
+		t.Fatalf("Expected a status URL, got %s", w.Body.String())
+	}
+	if w.Header().Get("Location") != accepted.StatusURL {
+		t.Errorf("Expected Location %s, got %s", accepted.StatusURL, w.Header().Get("Location"))
+	}
+
+	if w := get(accepted.StatusURL, "alice"); w.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d while running, got %d", http.StatusAccepted, w.Code)
+	}
+	if w := get(accepted.StatusURL, "bob"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another user's job to be hidden, got %d", w.Code)
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		w = get(accepted.StatusURL, "alice")
+		if w.Code != http.StatusAccepted || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != `{"total":"42"}` {
+		t.Errorf("Expected the original response, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Report") != "slow" {
+		t.Error("Expected the original headers to be replayed")
+	}
+}
+
+func TestGetAsyncResultWait(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ASYNC_AFTER", "20ms")
+
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Next()
+	})
+	router.GET("/api/slow", AsyncAfter(func(c *gin.Context) {
+		<-release
+		c.JSON(http.StatusOK, gin.H{"total": 1})
+	}))
+	router.GET("/api/jobs/:id", GetAsyncResult)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	var accepted AsyncAccepted
+	if err := json.Unmarshal(get("/api/slow").Body.Bytes(), &accepted); err != nil || accepted.StatusURL == "" {
+		t.Fatalf("Expected a status URL, got %v", err)
+	}
+	if w := get(accepted.StatusURL + "?wait=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid wait, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	started := time.Now()
+	if w := get(accepted.StatusURL + "?wait=20ms"); w.Code != http.StatusAccepted || time.Since(started) < 20*time.Millisecond {
+		t.Errorf("Expected 202 after waiting 20ms, got %d after %v", w.Code, time.Since(started))
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if w := get(accepted.StatusURL + "?wait=5"); w.Code != http.StatusOK || w.Body.String() != `{"total":1}` {
+		t.Errorf("Expected the original response once the job finished, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLongPollWait(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, true},
+		{"10s", 10 * time.Second, true},
+		{"15", 15 * time.Second, true},
+		{"90s", maxLongPollWait, true},
+		{"-1s", 0, false},
+		{"later", 0, false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/jobs/1?wait="+tt.value, nil)
+		if got, ok := longPollWait(c); got != tt.want || ok != tt.ok {
+			t.Errorf("longPollWait(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/sqlbuilder"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditEntries caps the limit parameter of GET /admin/audit
+const maxAuditEntries = 1000
+
+// auditActions are the actions audit_log records
+var auditActions = []string{"insert", "update", "delete"}
+
+// auditFilter selects audit log entries; zero fields don't filter
+type auditFilter struct {
+	entity   string
+	entityID int
+	actor    string
+	action   string
+	// before only selects entries older than this id, to page back
+	before int64
+	limit  int
+}
+
+// GetAuditLog returns recent changes to customers and accounts
+// @Summary      List audited changes
+// @Description  Get the latest inserts, updates, and deletes of customers and accounts, newest first, with the row before and after each change and the user who made it (system for changes made outside a request). Filter by entity (customers or accounts) and entity_id, actor, and action; pass the last id as before to page back. Customer emails follow the PII policy (admin only).
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        entity     query     string  false  "Only changes to this table: customers or accounts"
+// @Param        entity_id  query     int     false  "Only changes to this row (requires entity)"
+// @Param        actor      query     string  false  "Only changes by this user, or system"
+// @Param        action     query     string  false  "Only this action: insert, update, or delete"
+// @Param        before     query     int     false  "Only entries older than this id"
+// @Param        limit      query     int     false  "Entries to return (default 100, max 1000)"
+// @Success      200        {array}   models.AuditEntry
+// @Failure      400        {object}  map[string]string
+// @Failure      403        {object}  map[string]string
+// @Failure      500        {object}  map[string]string
+// @Router       /admin/audit [get]
+// @Security     BearerAuth
+func GetAuditLog(c *gin.Context) {
+	filter := auditFilter{entity: c.Query("entity"), actor: c.Query("actor"), action: c.Query("action"), limit: 100}
+	if filter.entity != "" && !contains(db.AuditedTables, filter.entity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown entity %s, expected %s", filter.entity, strings.Join(db.AuditedTables, " or "))})
+		return
+	}
+	if value := c.Query("entity_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity_id"})
+			return
+		}
+		if filter.entity == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "entity_id requires entity"})
+			return
+		}
+		filter.entityID = id
+	}
+	if filter.action != "" && !contains(auditActions, filter.action) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action, expected insert, update, or delete"})
+		return
+	}
+	if value := c.Query("before"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+			return
+		}
+		filter.before = id
+	}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxAuditEntries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit, expected 1-%d", maxAuditEntries)})
+			return
+		}
+		filter.limit = n
+	}
+
+	entries, err := auditEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, auditEntryDTOs(c, entries))
+}
+
+// auditEntries reads the audit log entries filter selects, newest first;
+// tests replace it
+var auditEntries = func(ctx context.Context, filter auditFilter) ([]models.AuditEntry, error) {
+	args := sqlbuilder.NewArgs()
+	where := args.Where()
+	if filter.entity != "" {
+		where.Equal("entity", filter.entity)
+	}
+	if filter.entityID != 0 {
+		where.Equal("entity_id", filter.entityID)
+	}
+	if filter.actor != "" {
+		where.Equal("actor", filter.actor)
+	}
+	if filter.action != "" {
+		where.Equal("action", filter.action)
+	}
+	if filter.before != 0 {
+		where.And("id < " + args.Add(filter.before))
+	}
+	rows, err := db.Primary(ctx).Query(
+		"SELECT id, entity, entity_id, action, actor, before, after, created_at FROM audit_log"+where.String()+" ORDER BY id DESC"+args.Limit(filter.limit),
+		args.Values()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.Entity, &entry.EntityID, &entry.Action, &entry.Actor, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		for _, row := range []struct {
+			data []byte
+			dest *map[string]interface{}
+		}{{before, &entry.Before}, {after, &entry.After}} {
+			if row.data == nil {
+				continue
+			}
+			if err := json.Unmarshal(row.data, row.dest); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetAuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := auditEntries
+	t.Cleanup(func() { auditEntries = previous })
+	var got auditFilter
+	auditEntries = func(ctx context.Context, filter auditFilter) ([]models.AuditEntry, error) {
+		got = filter
+		return []models.AuditEntry{{
+			ID: 7, Entity: "customers", EntityID: 42, Action: "update", Actor: "alice",
+			Before: map[string]interface{}{"id": 42.0, "email": "jane.doe@example.com"},
+			After:  map[string]interface{}{"id": 42.0, "email": "jane@example.com"},
+		}}, nil
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "bob")
+		c.Set("role", "user")
+	})
+	router.GET("/api/admin/audit", GetAuditLog)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/admin/audit?entity=customers&entity_id=42&actor=alice&action=update&before=100&limit=5")
+	var entries []models.AuditEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	want := auditFilter{entity: "customers", entityID: 42, actor: "alice", action: "update", before: 100, limit: 5}
+	if got != want {
+		t.Errorf("Expected filter %+v, got %+v", want, got)
+	}
+	if len(entries) != 1 || entries[0].Before["email"] != "j***@example.com" || entries[0].After["email"] != "j***@example.com" {
+		t.Errorf("Expected masked emails before and after, got %+v", entries)
+	}
+
+	if w := get("/api/admin/audit"); w.Code != http.StatusOK || got != (auditFilter{limit: 100}) {
+		t.Errorf("Expected the default filter, got %+v with status %d", got, w.Code)
+	}
+	for _, query := range []string{"entity=users", "entity_id=42", "entity=accounts&entity_id=x", "action=truncate", "before=-1", "limit=0", "limit=1001"} {
+		if w := get("/api/admin/audit?" + query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
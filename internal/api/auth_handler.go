@@ -1,11 +1,17 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"io"
 	"net/http"
 
 	"saas-go-app/internal/auth"
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,16 +20,23 @@ import (
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// TwoFactorCode is a TOTP code or unused backup code, required for users
+	// with two-factor authentication enabled
+	TwoFactorCode string `json:"two_factor_code"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
 	Token string `json:"token"`
+	// RefreshToken exchanges for a new token at POST /auth/refresh
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// ExpiresIn is the number of seconds Token is valid for
+	ExpiresIn int `json:"expires_in,omitempty"`
 }
 
 // Login handles user authentication
 // @Summary      Login user
-// @Description  Authenticate a user and return a JWT token
+// @Description  Authenticate a user and return a JWT token, valid for JWT_TTL (default 15m), with a refresh token that exchanges for a new one at POST /auth/refresh. After LOGIN_LOCKOUT_THRESHOLD failed attempts within LOGIN_LOCKOUT_WINDOW the account is locked until the window passes. Every attempt records the client's IP address, user agent, and device fingerprint (from X-Device-ID, or the User-Agent and Accept-Language headers); a successful login from a new device or country notifies the user. Users with two-factor authentication enabled must also send two_factor_code, a code from their authenticator app or an unused backup code; without one the response is 401 with two_factor_required set, and a wrong code counts as a failed login.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -31,6 +44,7 @@ type LoginResponse struct {
 // @Success      200          {object}  LoginResponse
 // @Failure      400          {object}  map[string]string
 // @Failure      401          {object}  map[string]string
+// @Failure      423          {object}  map[string]string
 // @Router       /auth/login [post]
 func Login(c *gin.Context) {
 	var req LoginRequest
@@ -40,13 +54,21 @@ func Login(c *gin.Context) {
 	}
 
 	// Query user from database
+	var userID int
 	var passwordHash string
-	err := db.PrimaryDB.QueryRow(
-		"SELECT password_hash FROM users WHERE username = $1",
+	var twoFactor bool
+	err := db.Primary(c.Request.Context()).QueryRow(
+		"SELECT id, password_hash, totp_enabled_at IS NOT NULL FROM users WHERE username = $1",
 		req.Username,
-	).Scan(&passwordHash)
+	).Scan(&userID, &passwordHash, &twoFactor)
 
+	client := newLoginClient(c)
+	if err == nil && loginLocked(c.Request.Context(), req.Username) {
+		c.JSON(http.StatusLocked, gin.H{"error": "Account temporarily locked after too many failed logins"})
+		return
+	}
 	if err == sql.ErrNoRows {
+		recordLoginAttempt(c.Request.Context(), req.Username, false, client)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -57,34 +79,161 @@ func Login(c *gin.Context) {
 
 	// Verify password
 	if !auth.CheckPasswordHash(req.Password, passwordHash) {
+		recordFailedLogin(c, req.Username, client)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
+	if twoFactor {
+		err := checkTwoFactor(c.Request.Context(), userID, req.TwoFactorCode)
+		switch {
+		case errors.Is(err, errTwoFactorRequired):
+			// Not a failed login: clients ask for the code once they see this
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "two_factor_required": true})
+			return
+		case errors.Is(err, errInvalidTwoFactorCode):
+			recordFailedLogin(c, req.Username, client)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "two_factor_required": true})
+			return
+		case err != nil:
+			tracing.Printf(c.Request.Context(), "Error checking two-factor code for %s: %v", req.Username, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor code"})
+			return
+		}
+	}
+	noticeNewLogin(c.Request.Context(), req.Username, client)
+	recordLoginAttempt(c.Request.Context(), req.Username, true, client)
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(req.Username)
+	// Generate JWT and refresh tokens
+	tokens, err := issueTokens(c.Request.Context(), req.Username, client)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// LogoutRequest optionally carries the refresh token to revoke with the JWT
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// revokeToken adds a JWT to the denylist; tests replace it
+var revokeToken = db.RevokeToken
+
+// Logout revokes the caller's JWT
+// @Summary      Logout
+// @Description  Revoke the JWT the request is made with, so it is rejected before it expires, and the refresh token in the body, if any, with every refresh token descended from the same login. Other JWTs of the user stay valid. Tokens issued before JWTs had an ID (the jti claim) can't be revoked and expire on their own. Requests made with an API key get 400.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        logout  body      LogoutRequest  false  "Refresh token to revoke too"
+// @Success      200     {object}  map[string]string
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /auth/logout [post]
+// @Security     BearerAuth
+func Logout(c *gin.Context) {
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	claims, ok := c.Get("token_claims")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logout revokes a JWT; revoke API keys with DELETE /api/auth/api-keys/{id}"})
+		return
+	}
+	if claims := claims.(*auth.Claims); claims.ID != "" {
+		if err := revokeToken(ctx, claims.ID, claims.Username, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+			return
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := revokeRefreshFamily(ctx, req.RefreshToken); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// recordLoginAttempt stores the outcome of a login and the client it came
+// from, for anomaly detection and the user's login activity. Failures to
+// record are logged but never block the login itself.
+func recordLoginAttempt(ctx context.Context, username string, success bool, client loginClient) {
+	_, err := db.Primary(ctx).Exec(
+		"INSERT INTO login_attempts (username, success, ip_address, user_agent, device_fingerprint, country) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))",
+		username, success, client.ip, client.userAgent, client.fingerprint, client.country,
+	)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to record login attempt for %s: %v", username, err)
+	}
+}
+
+// recordFailedLogin records a failed login, and publishes user.locked_out if
+// it locked the account
+func recordFailedLogin(c *gin.Context, username string, client loginClient) {
+	recordLoginAttempt(c.Request.Context(), username, false, client)
+	if loginLocked(c.Request.Context(), username) {
+		events.Publish(c.Request.Context(), events.UserLockedOut, gin.H{
+			"username":   username,
+			"ip_address": c.ClientIP(),
+			"window":     auth.LoginLockoutWindow().String(),
+		})
+	}
+}
+
+// loginLocked reports whether username has reached LOGIN_LOCKOUT_THRESHOLD
+// (default 5) failed logins within LOGIN_LOCKOUT_WINDOW (default 15m) since its
+// last successful login. Lookup errors fail open so a database hiccup never
+// locks everyone out.
+func loginLocked(ctx context.Context, username string) bool {
+	threshold := auth.LoginLockoutThreshold()
+	if threshold <= 0 {
+		return false
+	}
+
+	var failures int
+	err := db.Primary(ctx).QueryRow(`
+		SELECT COUNT(*) FROM login_attempts
+		WHERE username = $1 AND NOT success AND created_at > NOW() - $2 * INTERVAL '1 second'
+			AND created_at > COALESCE((SELECT MAX(created_at) FROM login_attempts WHERE username = $1 AND success), '-infinity')`,
+		username, auth.LoginLockoutWindow().Seconds(),
+	).Scan(&failures)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to check login lockout for %s: %v", username, err)
+		return false
+	}
+	return failures >= threshold
 }
 
 // RegisterRequest represents the registration request payload
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
+	// Email is an optional contact address, screened like the username
+	// against disposable email domains
+	Email string `json:"email" binding:"omitempty,email"`
+	// Website is a honeypot: registration forms hide it, so only bots fill
+	// it in
+	Website string `json:"website"`
 }
 
 // Register handles user registration
 // @Summary      Register new user
-// @Description  Create a new user account
+// @Description  Create a new user account. Registrations are screened for spam: one that fills in the hidden website field, uses an email (or an email as the username) at a disposable domain, or comes from an IP address that already registered REGISTRATION_IP_LIMIT times within REGISTRATION_IP_WINDOW is held for an admin to review instead, and gets 202. Its user is created once an admin approves it.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        user  body      RegisterRequest  true  "User registration data"
 // @Success      201   {object}  map[string]string
+// @Success      202   {object}  map[string]string
 // @Failure      400   {object}  map[string]string
 // @Failure      409   {object}  map[string]string
 // @Router       /auth/register [post]
@@ -95,6 +244,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	registration := models.Registration{Username: req.Username, IPAddress: optionalString(c.ClientIP()), UserAgent: optionalString(c.Request.UserAgent())}
+	if req.Email != "" {
+		registration.Email = &req.Email
+	}
+
+	registration.Reasons = screenRegistration(c, req.Username, req.Email, req.Website)
+
 	// Hash password
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
@@ -102,15 +259,41 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Flagged registrations get the same response whatever the reason, so
+	// bots can't tell which check caught them
+	if len(registration.Reasons) > 0 {
+		held, err := holdRegistration(ctx, registration, passwordHash)
+		if errors.Is(err, errUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+			return
+		}
+		reportFlaggedRegistration(c, held)
+		c.JSON(http.StatusAccepted, gin.H{"message": "Registration received and pending review"})
+		return
+	}
+
 	// Insert user into database
-	_, err = db.PrimaryDB.Exec(
-		"INSERT INTO users (username, password_hash) VALUES ($1, $2)",
-		req.Username, passwordHash,
-	)
+	var userID int
+	err = db.Primary(ctx).QueryRow(
+		"INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4) RETURNING id",
+		req.Username, passwordHash, registration.Email, models.RoleTenant,
+	).Scan(&userID)
 	if err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
 		return
 	}
+	registration.UserID = &userID
+	recordRegistration(ctx, registration)
+
+	events.Publish(ctx, events.UserRegistered, gin.H{
+		"user_id":    userID,
+		"username":   req.Username,
+		"ip_address": c.ClientIP(),
+	})
 
 	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 }
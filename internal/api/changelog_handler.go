@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/changelog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChangelog returns the feed of API changes
+// @Summary      API changelog
+// @Description  Get the API's changes release by release, newest first: new endpoints, changes, deprecations, removals, and schema changes, each marked breaking or not. upcoming lists the deprecations that haven't reached their sunset yet, so SDKs and the admin UI can warn before a breaking change lands. Pass since to get only newer releases, and breaking=true to get only breaking changes.
+// @Tags         docs
+// @Produce      json
+// @Param        since     query     string  false  "Only releases after this version (MAJOR.MINOR.PATCH)"
+// @Param        breaking  query     bool    false  "Only breaking changes"
+// @Success      200       {object}  changelog.Feed
+// @Failure      400       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /changelog [get]
+func GetChangelog(c *gin.Context) {
+	feed, err := changelog.BuildFeed(changelog.FeedOptions{
+		Since:        c.Query("since"),
+		BreakingOnly: c.Query("breaking") == "true",
+	}, time.Now())
+	if errors.Is(err, changelog.ErrInvalidVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected a version such as 1.0.0"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read changelog"})
+		return
+	}
+
+	// The feed only changes with a deploy, or when a sunset passes
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, feed)
+}
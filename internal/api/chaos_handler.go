@@ -0,0 +1,80 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"saas-go-app/internal/chaos"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChaosResponse represents the active faults
+type ChaosResponse struct {
+	Enabled  bool           `json:"enabled"`
+	Settings chaos.Settings `json:"settings"`
+}
+
+// GetChaos returns the active fault injection settings
+// @Summary      Get chaos settings
+// @Description  Get the faults currently injected into database calls and outbound circuit breakers (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ChaosResponse
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/chaos [get]
+// @Security     BearerAuth
+func GetChaos(c *gin.Context) {
+	c.JSON(http.StatusOK, ChaosResponse{Enabled: chaos.Enabled(), Settings: chaos.Current()})
+}
+
+// UpdateChaos turns fault injection on
+// @Summary      Inject faults
+// @Description  Add latency or errors to a percentage of database calls and force outbound circuit breakers open by destination (e.g. "webhooks"), for resilience demos (admin only). Replaces any active faults. Faults switch off after duration (default 5m, max 1h). Requires CHAOS_ENABLED=true.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        settings  body      chaos.Settings  true  "Faults to inject"
+// @Success      200       {object}  ChaosResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Router       /admin/chaos [put]
+// @Security     BearerAuth
+func UpdateChaos(c *gin.Context) {
+	if !chaos.Enabled() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Chaos endpoints are disabled, set CHAOS_ENABLED=true to use them"})
+		return
+	}
+
+	var req chaos.Settings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := chaos.Set(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Warning: Chaos faults enabled by %s until %s: %+v", c.GetString("username"), settings.ExpiresAt.Format("15:04:05"), settings)
+
+	c.JSON(http.StatusOK, ChaosResponse{Enabled: true, Settings: settings})
+}
+
+// ResetChaos turns every fault off
+// @Summary      Stop injecting faults
+// @Description  Switch off every injected fault immediately (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ChaosResponse
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/chaos [delete]
+// @Security     BearerAuth
+func ResetChaos(c *gin.Context) {
+	chaos.Reset()
+	log.Printf("Chaos faults cleared by %s", c.GetString("username"))
+	c.JSON(http.StatusOK, ChaosResponse{Enabled: chaos.Enabled(), Settings: chaos.Current()})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Large analytics responses can be requested with format=columnar. Instead of
+// an array of objects that repeats every field name per row, the response is
+// one object with an array per field, e.g.
+//
+//	{"detected_at": ["2024-01-02T15:04:05Z", ...], "observed": [412, ...]}
+//
+// The arrays are aligned by index. For wide result sets this roughly halves
+// the payload.
+
+// validRowFormat checks the optional format query parameter (rows, the
+// default, or columnar). It writes a 400 response and returns false if it is
+// anything else, so handlers can reject the request before querying.
+func validRowFormat(c *gin.Context) bool {
+	switch c.DefaultQuery("format", "rows") {
+	case "rows", "columnar":
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected rows or columnar"})
+	return false
+}
+
+// RespondRows writes rows, a slice of structs, with 200 in the layout chosen
+// by the format query parameter. The mock server uses it too, so both honor
+// format the same way.
+func RespondRows(c *gin.Context, rows interface{}) {
+	if !validRowFormat(c) {
+		return
+	}
+	if c.Query("format") == "columnar" {
+		c.JSON(http.StatusOK, columnar(rows))
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// columnar turns a slice of structs into a map of JSON field name to the
+// values of that field in row order. Fields without a JSON name are skipped.
+func columnar(rows interface{}) map[string][]interface{} {
+	slice := reflect.ValueOf(rows)
+	elemType := slice.Type().Elem()
+
+	type column struct {
+		index int
+		name  string
+	}
+	var fields []column
+	for i := 0; i < elemType.NumField(); i++ {
+		field := elemType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, column{index: i, name: name})
+	}
+
+	columns := make(map[string][]interface{}, len(fields))
+	for _, field := range fields {
+		values := make([]interface{}, slice.Len())
+		for row := 0; row < slice.Len(); row++ {
+			values[row] = slice.Index(row).Field(field.index).Interface()
+		}
+		columns[field.name] = values
+	}
+	return columns
+}
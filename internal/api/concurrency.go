@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Customers and accounts carry a version that every update increments (see
+// migrations/0017_row_versions.up.sql). Reads return it in the body and as an
+// ETag, and PUT requests must send it back, in If-Match or as the version
+// field, so an update based on a stale read gets 409 instead of silently
+// overwriting someone else's change. If-Match: * updates whatever the current
+// version is.
+
+// setETag sets the ETag header to a record's version
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// expectedVersion returns the version an update is based on: the If-Match
+// header, or else body, the version field of the request. It returns 0 for
+// If-Match: *. It writes a 400 response for a malformed If-Match, or a 428
+// response if neither gives a version, and returns false.
+func expectedVersion(c *gin.Context, body int) (int, bool) {
+	match := strings.TrimSpace(c.GetHeader("If-Match"))
+	if match == "" {
+		if body <= 0 {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Send the version you are updating, in If-Match or the version field"})
+			return 0, false
+		}
+		return body, true
+	}
+	if match == "*" {
+		return 0, true
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(match, "W/"), `"`))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match, expected the ETag of the record"})
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigAuditEntry represents a recorded configuration change
+type ConfigAuditEntry struct {
+	ID        int             `json:"id"`
+	Source    string          `json:"source"`
+	Actor     string          `json:"actor"`
+	Changes   []config.Change `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ConfigResponse represents the active settings and their recent change history
+type ConfigResponse struct {
+	Settings config.Settings    `json:"settings"`
+	Audit    []ConfigAuditEntry `json:"audit"`
+}
+
+// ConfigChangeResponse represents the result of a configuration change
+type ConfigChangeResponse struct {
+	Settings config.Settings `json:"settings"`
+	Changes  []config.Change `json:"changes"`
+}
+
+// GetConfig returns the active runtime settings
+// @Summary      Get runtime config
+// @Description  Get the active hot-reloadable settings and the 50 most recent changes (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ConfigResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/config [get]
+// @Security     BearerAuth
+func GetConfig(c *gin.Context) {
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT id, source, actor, changes, created_at FROM config_audit ORDER BY created_at DESC LIMIT 50",
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch config audit"})
+		return
+	}
+	defer rows.Close()
+
+	response := ConfigResponse{Settings: config.Current(), Audit: []ConfigAuditEntry{}}
+	for rows.Next() {
+		var entry ConfigAuditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.Source, &entry.Actor, &changes, &entry.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan config audit"})
+			return
+		}
+		_ = json.Unmarshal(changes, &entry.Changes)
+		response.Audit = append(response.Audit, entry)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateConfig replaces the active runtime settings
+// @Summary      Update runtime config
+// @Description  Replace the hot-reloadable settings without restarting (admin only). Changes are audited.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        settings  body      config.Settings  true  "New settings"
+// @Success      200       {object}  ConfigChangeResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Router       /admin/config [put]
+// @Security     BearerAuth
+func UpdateConfig(c *gin.Context) {
+	var settings config.Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := config.Apply(settings, "api", c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ConfigChangeResponse{Settings: config.Current(), Changes: changes})
+}
+
+// ReloadConfig re-reads runtime settings from the environment, .env, and CONFIG_FILE
+// @Summary      Reload runtime config
+// @Description  Re-read hot-reloadable settings from the environment, .env, and CONFIG_FILE, same as sending SIGHUP (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ConfigChangeResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/config/reload [post]
+// @Security     BearerAuth
+func ReloadConfig(c *gin.Context) {
+	changes, err := config.Reload("api", c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ConfigChangeResponse{Settings: config.Current(), Changes: changes})
+}
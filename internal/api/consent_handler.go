@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+
+	"saas-go-app/internal/consent"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// acceptedConsents caches username/policy/version keys known to be accepted.
+// Consents are never withdrawn, so RequireConsent only hits the database
+// until a user has accepted the current versions.
+var acceptedConsents sync.Map
+
+func consentKey(username string, policy consent.Policy) string {
+	return username + "\x00" + policy.Policy + "\x00" + policy.Version
+}
+
+// pendingConsents returns the required policy versions username has not accepted
+func pendingConsents(ctx context.Context, username string) ([]consent.Policy, error) {
+	required := consent.Required()
+	cached := true
+	for _, policy := range required {
+		if _, ok := acceptedConsents.Load(consentKey(username, policy)); !ok {
+			cached = false
+			break
+		}
+	}
+	if cached {
+		return []consent.Policy{}, nil
+	}
+
+	rows, err := db.Primary(ctx).Query("SELECT policy, version FROM consents WHERE username = $1", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accepted []consent.Policy
+	for rows.Next() {
+		var policy consent.Policy
+		if err := rows.Scan(&policy.Policy, &policy.Version); err != nil {
+			return nil, err
+		}
+		accepted = append(accepted, policy)
+		acceptedConsents.Store(consentKey(username, policy), true)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return consent.Pending(accepted), nil
+}
+
+func policyVersions(policies []consent.Policy) []models.PolicyVersion {
+	versions := make([]models.PolicyVersion, 0, len(policies))
+	for _, policy := range policies {
+		versions = append(versions, models.PolicyVersion{Policy: policy.Policy, Version: policy.Version})
+	}
+	return versions
+}
+
+func scanConsents(rows *sql.Rows) ([]models.Consent, error) {
+	consents := []models.Consent{}
+	for rows.Next() {
+		var record models.Consent
+		if err := rows.Scan(&record.ID, &record.Username, &record.Policy, &record.Version,
+			&record.IPAddress, &record.UserAgent, &record.AcceptedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, record)
+	}
+	return consents, rows.Err()
+}
+
+const consentColumns = "id, username, policy, version, COALESCE(ip_address, ''), COALESCE(user_agent, ''), accepted_at"
+
+// GetConsents returns the caller's consents and the policies still to accept
+// @Summary      Get my consents
+// @Description  Get the current version of every policy the API requires, the ones the caller has not accepted yet, and every consent the caller has given. While pending is not empty, other API calls return 403.
+// @Tags         consents
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.ConsentStatus
+// @Failure      500  {object}  map[string]string
+// @Router       /consents [get]
+// @Security     BearerAuth
+func GetConsents(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString("username")
+
+	pending, err := pendingConsents(ctx, username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+		return
+	}
+
+	rows, err := db.Primary(ctx).Query(
+		"SELECT "+consentColumns+" FROM consents WHERE username = $1 ORDER BY accepted_at DESC", username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+		return
+	}
+	defer rows.Close()
+	consents, err := scanConsents(rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan consents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConsentStatus{
+		Pending:  policyVersions(pending),
+		Required: policyVersions(consent.Required()),
+		Consents: consents,
+	})
+}
+
+// AcceptConsent records that the caller accepted a policy
+// @Summary      Accept a policy
+// @Description  Accept the current version of a policy. Only the current version can be accepted; accepting it again returns the existing consent.
+// @Tags         consents
+// @Accept       json
+// @Produce      json
+// @Param        consent  body      models.AcceptConsentRequest  true  "Policy and version"
+// @Success      201      {object}  models.Consent
+// @Success      200      {object}  models.Consent
+// @Failure      400      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      409      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /consents [post]
+// @Security     BearerAuth
+func AcceptConsent(c *gin.Context) {
+	var req models.AcceptConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, ok := consent.Current(req.Policy)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+	if current.Version != req.Version {
+		c.JSON(http.StatusConflict, gin.H{"error": "Version " + req.Version + " is not the current version of " + req.Policy + " (" + current.Version + ")"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString("username")
+	status := http.StatusCreated
+	var record models.Consent
+	err := db.Primary(ctx).QueryRow(
+		`INSERT INTO consents (username, policy, version, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username, policy, version) DO NOTHING
+		RETURNING `+consentColumns,
+		username, current.Policy, current.Version, c.ClientIP(), c.Request.UserAgent(),
+	).Scan(&record.ID, &record.Username, &record.Policy, &record.Version, &record.IPAddress, &record.UserAgent, &record.AcceptedAt)
+	if err == sql.ErrNoRows {
+		status = http.StatusOK
+		err = db.Primary(ctx).QueryRow(
+			"SELECT "+consentColumns+" FROM consents WHERE username = $1 AND policy = $2 AND version = $3",
+			username, current.Policy, current.Version,
+		).Scan(&record.ID, &record.Username, &record.Policy, &record.Version, &record.IPAddress, &record.UserAgent, &record.AcceptedAt)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record consent"})
+		return
+	}
+
+	acceptedConsents.Store(consentKey(username, current), true)
+	c.JSON(status, record)
+}
+
+// GetPolicyConsents lists consents for admins
+// @Summary      List consents
+// @Description  Get the 500 most recent consents, newest first, optionally for one user, policy, or policy version (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        username  query     string  false  "Only show consents by this user"
+// @Param        policy    query     string  false  "Only show consents to this policy"
+// @Param        version   query     string  false  "Only show consents to this version"
+// @Success      200       {array}   models.Consent
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/consents [get]
+// @Security     BearerAuth
+func GetPolicyConsents(c *gin.Context) {
+	optional := func(name string) *string {
+		if value := c.Query(name); value != "" {
+			return &value
+		}
+		return nil
+	}
+
+	rows, err := db.Primary(c.Request.Context()).Query(`
+		SELECT `+consentColumns+`
+		FROM consents
+		WHERE ($1::text IS NULL OR username = $1)
+			AND ($2::text IS NULL OR policy = $2)
+			AND ($3::text IS NULL OR version = $3)
+		ORDER BY accepted_at DESC
+		LIMIT 500`,
+		optional("username"), optional("policy"), optional("version"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+		return
+	}
+	defer rows.Close()
+
+	consents, err := scanConsents(rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan consents"})
+		return
+	}
+	c.JSON(http.StatusOK, consents)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContactIssuesResponse represents the contact issue report with counts per issue
+type ContactIssuesResponse struct {
+	Counts map[string]int        `json:"counts"`
+	Issues []models.ContactIssue `json:"issues"`
+}
+
+// NormalizeContacts runs contact normalization immediately
+// @Summary      Normalize customer emails
+// @Description  Lowercase and trim customer emails, flag addresses with an undeliverable format or that collide once normalized, and refresh the contact issue report (admin only). Run this after large imports; it also runs daily when Redis is configured.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  jobs.ContactNormalizationResult
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/contacts/normalize [post]
+// @Security     BearerAuth
+func NormalizeContacts(c *gin.Context) {
+	result, err := jobs.RunContactNormalization(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to normalize contacts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetContactIssues returns the contact issue report from the last normalization run
+// @Summary      List contact issues
+// @Description  Get customer emails flagged by the last normalization run (invalid_format or duplicate_after_normalization), with counts per issue (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        issue  query     string  false  "Only show this issue"
+// @Success      200    {object}  ContactIssuesResponse
+// @Failure      403    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /admin/contacts/issues [get]
+// @Security     BearerAuth
+func GetContactIssues(c *gin.Context) {
+	ctx := c.Request.Context()
+	analyticsDB := db.Analytics(ctx)
+
+	response := ContactIssuesResponse{Counts: map[string]int{}, Issues: []models.ContactIssue{}}
+	countRows, err := analyticsDB.Query("SELECT issue, COUNT(*) FROM contact_issues GROUP BY issue")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact issues"})
+		return
+	}
+	defer countRows.Close()
+	for countRows.Next() {
+		var issue string
+		var count int
+		if err := countRows.Scan(&issue, &count); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan contact issues"})
+			return
+		}
+		response.Counts[issue] = count
+	}
+
+	var issue *string
+	if value := c.Query("issue"); value != "" {
+		issue = &value
+	}
+	rows, err := analyticsDB.Query(`
+		SELECT customer_id, field, value, issue, detected_at
+		FROM contact_issues
+		WHERE $1::text IS NULL OR issue = $1
+		ORDER BY issue, value, customer_id
+		LIMIT 1000`,
+		issue,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact issues"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contactIssue models.ContactIssue
+		if err := rows.Scan(&contactIssue.CustomerID, &contactIssue.Field, &contactIssue.Value, &contactIssue.Issue, &contactIssue.DetectedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan contact issue"})
+			return
+		}
+		response.Issues = append(response.Issues, contactIssue)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -1,75 +1,98 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
 
-	"saas-go-app/internal/db"
 	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
+// customerRepo backs the customer handlers; tests replace it with a fake
+var customerRepo repository.CustomerRepository = repository.PostgresCustomers{}
+
 // GetCustomers retrieves all customers
 // @Summary      List all customers
-// @Description  Get a list of all customers
+// @Description  Get a list of all customers, or the customers that existed at as_of, newest first. Deleted customers are left out unless an admin passes include_deleted=true. Emails are partially masked unless the caller's role is in PII_UNMASKED_ROLES; unmasked access is audited. With limit, pages are returned with an opaque X-Next-Cursor header to pass back as cursor for the next page; the header is absent on the last page. limit, sort, fields, and tz default to the caller's preferences (see /me/preferences).
 // @Tags         customers
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   models.Customer
-// @Failure      500  {object}  map[string]string
+// @Param        as_of   query     string  false  "RFC 3339 timestamp to read historical data at"
+// @Param        limit   query     int     false  "Page size (1-1000, default: all customers)"
+// @Param        cursor  query     string  false  "X-Next-Cursor from the previous page"
+// @Param        sort    query     string  false  "Order by creation, newest first by default"  Enums(-created_at, created_at)
+// @Param        fields  query     string  false  "Comma-separated fields to return; id is always returned"
+// @Param        tz      query     string  false  "IANA time zone to return timestamps in"
+// @Param        include_deleted  query  bool  false  "Include soft-deleted customers (admins only)"
+// @Success      200     {array}   models.Customer
+// @Header       200     {string}  X-Next-Cursor  "Cursor for the next page, if any"
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
 // @Router       /customers [get]
 // @Security     BearerAuth
 func GetCustomers(c *gin.Context) {
-	rows, err := db.PrimaryDB.Query(
-		"SELECT id, name, email, created_at, updated_at FROM customers ORDER BY created_at DESC",
-	)
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	p, ok := parsePage(c, "customers")
+	if !ok {
+		return
+	}
+	view, ok := parseListView(c, "customers")
+	if !ok {
+		return
+	}
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+
+	opts := p.options(asOf)
+	opts.IncludeDeleted = includeDeleted
+	customers, err := customerRepo.List(c.Request.Context(), opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
 		return
 	}
-	defer rows.Close()
-
-	var customers []models.Customer
-	for rows.Next() {
-		var customer models.Customer
-		if err := rows.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.CreatedAt, &customer.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan customer"})
-			return
-		}
-		customers = append(customers, customer)
+	if p.more(len(customers)) {
+		customers = customers[:p.Limit]
+		last := customers[p.Limit-1]
+		p.next(c, last.CreatedAt, last.ID)
 	}
 
-	c.JSON(http.StatusOK, customers)
+	c.JSON(http.StatusOK, view.render(customerDTOs(c, customers)))
 }
 
 // GetCustomer retrieves a single customer by ID
 // @Summary      Get customer by ID
-// @Description  Get a specific customer by their ID
+// @Description  Get a specific customer by their ID, optionally as it was at as_of. Deleted customers aren't found. The ETag header is the customer's version, to send back in If-Match when updating it. Emails are partially masked unless the caller's role is in PII_UNMASKED_ROLES; unmasked access is audited.
 // @Tags         customers
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Customer ID"
-// @Success      200  {object}  models.Customer
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Param        id     path      string  true   "Customer ID or UUID"
+// @Param        as_of  query     string  false  "RFC 3339 timestamp to read historical data at"
+// @Success      200    {object}  models.Customer
+// @Header       200    {string}  ETag  "The customer's version"
+// @Failure      400    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
 // @Router       /customers/{id} [get]
 // @Security     BearerAuth
 func GetCustomer(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+	id, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
 		return
 	}
 
-	var customer models.Customer
-	err = db.PrimaryDB.QueryRow(
-		"SELECT id, name, email, created_at, updated_at FROM customers WHERE id = $1",
-		id,
-	).Scan(&customer.ID, &customer.Name, &customer.Email, &customer.CreatedAt, &customer.UpdatedAt)
-
-	if err == sql.ErrNoRows {
+	customer, err := customerRepo.Get(c.Request.Context(), id, asOf)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
 		return
 	}
@@ -78,12 +101,13 @@ func GetCustomer(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, customer)
+	setETag(c, customer.Version)
+	c.JSON(http.StatusOK, customerDTOs(c, []models.Customer{customer})[0])
 }
 
 // CreateCustomer creates a new customer
 // @Summary      Create new customer
-// @Description  Create a new customer record
+// @Description  Create a new customer record. The email in the response is masked like GET /customers/{id}.
 // @Tags         customers
 // @Accept       json
 // @Produce      json
@@ -99,37 +123,36 @@ func CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	var customer models.Customer
-	err := db.PrimaryDB.QueryRow(
-		"INSERT INTO customers (name, email) VALUES ($1, $2) RETURNING id, name, email, created_at, updated_at",
-		req.Name, req.Email,
-	).Scan(&customer.ID, &customer.Name, &customer.Email, &customer.CreatedAt, &customer.UpdatedAt)
-
+	customer, err := customerRepo.Create(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create customer"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, customer)
+	setETag(c, customer.Version)
+	c.JSON(http.StatusCreated, customerDTOs(c, []models.Customer{customer})[0])
 }
 
 // UpdateCustomer updates an existing customer
 // @Summary      Update customer
-// @Description  Update an existing customer record
+// @Description  Update an existing customer record. Send the version the update is based on, in If-Match (the ETag of the customer) or the version field; If-Match: * skips the check. If the customer has changed since, the update is refused with 409 and the current version. The email in the response is masked like GET /customers/{id}.
 // @Tags         customers
 // @Accept       json
 // @Produce      json
-// @Param        id         path      int                           true  "Customer ID"
-// @Param        customer   body      models.UpdateCustomerRequest  true  "Updated customer data"
-// @Success      200        {object}  models.Customer
-// @Failure      400        {object}  map[string]string
-// @Failure      404        {object}  map[string]string
+// @Param        id        path      string                        true   "Customer ID or UUID"
+// @Param        If-Match  header    string                        false  "ETag of the version the update is based on"
+// @Param        customer  body      models.UpdateCustomerRequest  true   "Updated customer data"
+// @Success      200       {object}  models.Customer
+// @Header       200        {string}  ETag  "The customer's new version"
+// @Failure      400       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      409       {object}  map[string]interface{}
+// @Failure      428       {object}  map[string]string
 // @Router       /customers/{id} [put]
 // @Security     BearerAuth
 func UpdateCustomer(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+	id, ok := parseCustomerID(c, "id")
+	if !ok {
 		return
 	}
 
@@ -139,55 +162,105 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
-	var customer models.Customer
-	err = db.PrimaryDB.QueryRow(
-		"UPDATE customers SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING id, name, email, created_at, updated_at",
-		req.Name, req.Email, id,
-	).Scan(&customer.ID, &customer.Name, &customer.Email, &customer.CreatedAt, &customer.UpdatedAt)
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	req.Version = version
 
-	if err == sql.ErrNoRows {
+	customer, err := customerRepo.Update(c.Request.Context(), id, req)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
 		return
 	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		setETag(c, customer.Version)
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer was changed by someone else, reload it and try again", "version": customer.Version})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer"})
 		return
 	}
 
-	c.JSON(http.StatusOK, customer)
+	setETag(c, customer.Version)
+	c.JSON(http.StatusOK, customerDTOs(c, []models.Customer{customer})[0])
 }
 
 // DeleteCustomer deletes a customer
 // @Summary      Delete customer
-// @Description  Delete a customer by ID
+// @Description  Soft-delete a customer by ID, along with its accounts; POST /customers/{id}/restore brings them back. With hard=true, admins remove the customer and its accounts permanently instead, whether or not it was soft-deleted.
 // @Tags         customers
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int  true  "Customer ID"
-// @Success      200  {object}  map[string]string
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Param        id    path      string  true   "Customer ID or UUID"
+// @Param        hard  query     bool    false  "Delete permanently (admins only)"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
 // @Router       /customers/{id} [delete]
 // @Security     BearerAuth
 func DeleteCustomer(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+	id, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	hard, ok := parseHardDelete(c)
+	if !ok {
 		return
 	}
 
-	result, err := db.PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", id)
+	var err error
+	message := "Customer deleted successfully"
+	if hard {
+		err = customerRepo.Purge(c.Request.Context(), id)
+		message = "Customer permanently deleted"
+	} else {
+		err = customerRepo.Delete(c.Request.Context(), id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete customer"})
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// RestoreCustomer undoes a soft delete
+// @Summary      Restore customer
+// @Description  Restore a soft-deleted customer along with the accounts that were deleted with it. Accounts deleted on their own before the customer stay deleted.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Customer ID or UUID"
+// @Success      200  {object}  models.Customer
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /customers/{id}/restore [post]
+// @Security     BearerAuth
+func RestoreCustomer(c *gin.Context) {
+	id, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+
+	customer, err := customerRepo.Restore(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted customer not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore customer"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
+	setETag(c, customer.Version)
+	c.JSON(http.StatusOK, customerDTOs(c, []models.Customer{customer})[0])
 }
 
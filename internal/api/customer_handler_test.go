@@ -2,13 +2,17 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"saas-go-app/internal/auth"
 	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -47,3 +51,450 @@ func TestCreateCustomer(t *testing.T) {
 	}
 }
 
+
+func TestGetCustomerInvalidAsOf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/customers/:id", GetCustomer)
+
+	req, _ := http.NewRequest("GET", "/api/customers/1?as_of=yesterday", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetCustomersInvalidPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/customers", GetCustomers)
+
+	for _, query := range []string{"limit=0", "limit=5000", "limit=ten", "cursor=forged"} {
+		req, _ := http.NewRequest("GET", "/api/customers?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestPageCursorScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var token string
+	router := gin.New()
+	router.GET("/:user/:resource", func(c *gin.Context) {
+		c.Set("username", c.Param("user"))
+		c.Set("preferences", models.Preferences{})
+		p, ok := parsePage(c, c.Param("resource"))
+		if !ok {
+			return
+		}
+		if p.After == nil {
+			p.next(c, time.Now(), 42)
+			token = c.Writer.Header().Get("X-Next-Cursor")
+		}
+		c.JSON(http.StatusOK, p.After)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	serve("/alice/customers?limit=10")
+	if token == "" {
+		t.Fatal("Expected an X-Next-Cursor header")
+	}
+
+	w := serve("/alice/customers?cursor=" + token)
+	var after pageCursor
+	if err := json.Unmarshal(w.Body.Bytes(), &after); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the cursor to decode, got %d %s", w.Code, w.Body.String())
+	}
+	if after.ID != 42 || after.Limit != 10 {
+		t.Errorf("Expected id 42 and limit 10, got %+v", after)
+	}
+
+	for _, path := range []string{"/bob/customers?", "/alice/accounts?", "/alice/customers?as_of=2024-01-01T00:00:00Z&"} {
+		if w := serve(path + "cursor=" + token); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, path, w.Code)
+		}
+	}
+}
+
+// fakeCustomers is an in-memory CustomerRepository. Customers are kept newest
+// first, as List returns them.
+type fakeCustomers struct {
+	customers []models.Customer
+}
+
+func (f *fakeCustomers) List(ctx context.Context, opts repository.ListOptions) ([]models.Customer, error) {
+	var customers []models.Customer
+	for _, customer := range f.customers {
+		if customer.DeletedAt != nil && !opts.IncludeDeleted {
+			continue
+		}
+		if after := opts.After; after != nil && !customer.CreatedAt.Before(after.CreatedAt) &&
+			!(customer.CreatedAt.Equal(after.CreatedAt) && customer.ID < after.ID) {
+			continue
+		}
+		if opts.Limit > 0 && len(customers) == opts.Limit {
+			break
+		}
+		customers = append(customers, customer)
+	}
+	return customers, nil
+}
+
+func (f *fakeCustomers) Get(ctx context.Context, id int, asOf *time.Time) (models.Customer, error) {
+	for _, customer := range f.customers {
+		if customer.ID == id && customer.DeletedAt == nil {
+			return customer, nil
+		}
+	}
+	return models.Customer{}, repository.ErrNotFound
+}
+
+func (f *fakeCustomers) Create(ctx context.Context, req models.CreateCustomerRequest) (models.Customer, error) {
+	customer := models.Customer{ID: len(f.customers) + 1, Name: req.Name, Email: req.Email, CreatedAt: time.Now()}
+	f.customers = append([]models.Customer{customer}, f.customers...)
+	return customer, nil
+}
+
+func (f *fakeCustomers) Update(ctx context.Context, id int, req models.UpdateCustomerRequest) (models.Customer, error) {
+	for i, customer := range f.customers {
+		if customer.ID == id && customer.DeletedAt == nil {
+			if req.Version != 0 && req.Version != customer.Version {
+				return customer, repository.ErrVersionConflict
+			}
+			f.customers[i].Name, f.customers[i].Email = req.Name, req.Email
+			f.customers[i].Version++
+			return f.customers[i], nil
+		}
+	}
+	return models.Customer{}, repository.ErrNotFound
+}
+
+func (f *fakeCustomers) Delete(ctx context.Context, id int) error {
+	for i, customer := range f.customers {
+		if customer.ID == id && customer.DeletedAt == nil {
+			now := time.Now()
+			f.customers[i].DeletedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeCustomers) Restore(ctx context.Context, id int) (models.Customer, error) {
+	for i, customer := range f.customers {
+		if customer.ID == id && customer.DeletedAt != nil {
+			f.customers[i].DeletedAt = nil
+			return f.customers[i], nil
+		}
+	}
+	return models.Customer{}, repository.ErrNotFound
+}
+
+func (f *fakeCustomers) Purge(ctx context.Context, id int) error {
+	for i, customer := range f.customers {
+		if customer.ID == id {
+			f.customers = append(f.customers[:i], f.customers[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (f *fakeCustomers) IDByUUID(ctx context.Context, uuid string) (int, error) {
+	for _, customer := range f.customers {
+		if customer.UUID == uuid {
+			return customer.ID, nil
+		}
+	}
+	return 0, repository.ErrNotFound
+}
+
+// useFakeCustomers swaps customerRepo for a fake for the duration of the test
+func useFakeCustomers(t *testing.T, customers ...models.Customer) *fakeCustomers {
+	fake := &fakeCustomers{customers: customers}
+	previous := customerRepo
+	customerRepo = fake
+	t.Cleanup(func() { customerRepo = previous })
+	return fake
+}
+
+// customerRouter serves the customer handlers as a user whose role masks PII
+// and who has no preferences
+func customerRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Set("role", "user")
+		c.Set("preferences", models.Preferences{})
+	})
+	router.GET("/api/customers", GetCustomers)
+	router.GET("/api/customers/:id", GetCustomer)
+	router.DELETE("/api/customers/:id", DeleteCustomer)
+	router.PUT("/api/customers/:id", UpdateCustomer)
+	router.POST("/api/customers", CreateCustomer)
+	router.POST("/api/customers/:id/restore", RestoreCustomer)
+	return router
+}
+
+func TestGetCustomersPaginates(t *testing.T) {
+	now := time.Now()
+	useFakeCustomers(t,
+		models.Customer{ID: 3, Name: "Carol", Email: "carol@example.com", CreatedAt: now},
+		models.Customer{ID: 2, Name: "Bob", Email: "bob@example.com", CreatedAt: now.Add(-time.Minute)},
+		models.Customer{ID: 1, Name: "Alice", Email: "alice@example.com", CreatedAt: now.Add(-2 * time.Minute)},
+	)
+	router := customerRouter()
+
+	var names []string
+	path := "/api/customers?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 2 {
+			t.Fatal("Expected pagination to end after two pages")
+		}
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var customers []models.Customer
+		json.Unmarshal(w.Body.Bytes(), &customers)
+		for _, customer := range customers {
+			names = append(names, customer.Name)
+			if customer.Email == "carol@example.com" {
+				t.Errorf("Expected emails to be masked, got %s", customer.Email)
+			}
+		}
+
+		path = ""
+		if next := w.Header().Get("X-Next-Cursor"); next != "" {
+			path = "/api/customers?cursor=" + next
+		}
+	}
+
+	if len(names) != 3 || names[0] != "Carol" || names[2] != "Alice" {
+		t.Errorf("Expected Carol, Bob, Alice, got %v", names)
+	}
+}
+
+func TestGetCustomerNotFound(t *testing.T) {
+	useFakeCustomers(t)
+	router := customerRouter()
+
+	for _, method := range []string{"GET", "DELETE"} {
+		req, _ := http.NewRequest(method, "/api/customers/42", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusNotFound, method, w.Code)
+		}
+	}
+}
+
+func TestDeleteAndRestoreCustomer(t *testing.T) {
+	fake := useFakeCustomers(t, models.Customer{ID: 1, Name: "Alice", Email: "alice@example.com", CreatedAt: time.Now()})
+	router := customerRouter()
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("DELETE", "/api/customers/1"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(fake.customers) != 1 || fake.customers[0].DeletedAt == nil {
+		t.Fatalf("Expected the customer soft-deleted, got %+v", fake.customers)
+	}
+	if w := serve("GET", "/api/customers/1"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a deleted customer, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := serve("GET", "/api/customers"); w.Body.String() != "null" && w.Body.String() != "[]" {
+		t.Errorf("Expected deleted customers left out of the list, got %s", w.Body.String())
+	}
+
+	// Only admins may list deleted customers or delete permanently
+	if w := serve("GET", "/api/customers?include_deleted=true"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d listing deleted customers, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := serve("DELETE", "/api/customers/1?hard=true"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d deleting permanently, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := serve("GET", "/api/customers?include_deleted=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid include_deleted, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w := serve("POST", "/api/customers/1/restore"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := serve("POST", "/api/customers/1/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d restoring a live customer, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := serve("GET", "/api/customers/1"); w.Code != http.StatusOK {
+		t.Errorf("Expected the restored customer found, got %d", w.Code)
+	}
+}
+
+func TestIncludeDeletedAsAdmin(t *testing.T) {
+	deletedAt := time.Now()
+	fake := useFakeCustomers(t,
+		models.Customer{ID: 2, Name: "Bob", Email: "bob@example.com", CreatedAt: time.Now()},
+		models.Customer{ID: 1, Name: "Alice", Email: "alice@example.com", CreatedAt: time.Now(), DeletedAt: &deletedAt},
+	)
+	// Keep emails masked, so listing them isn't audited to the database
+	t.Setenv("PII_UNMASKED_ROLES", "auditor")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "admin")
+		c.Set("role", "admin")
+		c.Set("preferences", models.Preferences{})
+	})
+	router.GET("/api/customers", GetCustomers)
+	router.DELETE("/api/customers/:id", DeleteCustomer)
+
+	req, _ := http.NewRequest("GET", "/api/customers?include_deleted=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var customers []models.Customer
+	json.Unmarshal(w.Body.Bytes(), &customers)
+	if w.Code != http.StatusOK || len(customers) != 2 || customers[1].DeletedAt == nil {
+		t.Errorf("Expected both customers, the deleted one with deleted_at, got %d %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/customers/1?hard=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || len(fake.customers) != 1 || fake.customers[0].ID != 2 {
+		t.Errorf("Expected the deleted customer purged, got %d %+v", w.Code, fake.customers)
+	}
+}
+
+func TestUpdateCustomerVersion(t *testing.T) {
+	fake := useFakeCustomers(t, models.Customer{ID: 1, Name: "Alice", Email: "alice@example.com", Version: 3})
+	router := customerRouter()
+
+	update := func(ifMatch, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/api/customers/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		ifMatch string
+		body    string
+		want    int
+		etag    string
+	}{
+		{"", `{"name": "Alice", "email": "alice@example.com"}`, http.StatusPreconditionRequired, ""},
+		{"soon", `{"name": "Alice", "email": "alice@example.com"}`, http.StatusBadRequest, ""},
+		{`"2"`, `{"name": "Alice B", "email": "alice@example.com"}`, http.StatusConflict, `"3"`},
+		{`"3"`, `{"name": "Alice B", "email": "alice@example.com"}`, http.StatusOK, `"4"`},
+		// The version in the body is stale now
+		{"", `{"name": "Alice C", "email": "alice@example.com", "version": 3}`, http.StatusConflict, `"4"`},
+		{"", `{"name": "Alice C", "email": "alice@example.com", "version": 4}`, http.StatusOK, `"5"`},
+		{"*", `{"name": "Alice D", "email": "alice@example.com"}`, http.StatusOK, `"6"`},
+	}
+	for _, tt := range tests {
+		w := update(tt.ifMatch, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("If-Match %q, %s: expected status %d, got %d: %s", tt.ifMatch, tt.body, tt.want, w.Code, w.Body.String())
+		}
+		if etag := w.Header().Get("ETag"); etag != tt.etag {
+			t.Errorf("If-Match %q, %s: expected ETag %s, got %s", tt.ifMatch, tt.body, tt.etag, etag)
+		}
+	}
+	if fake.customers[0].Name != "Alice D" {
+		t.Errorf("Expected only current updates applied, got %+v", fake.customers[0])
+	}
+}
+
+func TestCreateAndUpdateCustomerMaskEmail(t *testing.T) {
+	useFakeCustomers(t)
+	router := customerRouter()
+
+	for _, method := range []string{"POST", "PUT"} {
+		path := "/api/customers"
+		if method == "PUT" {
+			path = "/api/customers/1"
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(`{"name": "Alice", "email": "alice@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+			t.Fatalf("%s: expected success, got %d: %s", method, w.Code, w.Body.String())
+		}
+
+		var customer models.Customer
+		json.Unmarshal(w.Body.Bytes(), &customer)
+		if customer.Name != "Alice" || customer.Email == "" || customer.Email == "alice@example.com" {
+			t.Errorf("%s: expected the email to be masked, got %+v", method, customer)
+		}
+	}
+}
+
+func TestGetCustomerByUUID(t *testing.T) {
+	const key = "0190a6c8-9f3e-7b4a-8c1d-2e5f6a7b8c9d"
+	useFakeCustomers(t, models.Customer{ID: 7, UUID: key, Name: "Alice", Email: "alice@example.com", CreatedAt: time.Now()})
+	router := customerRouter()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/api/customers/7", "/api/customers/" + key, "/api/customers/" + strings.ToUpper(key)} {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d for %s, got %d: %s", http.StatusOK, path, w.Code, w.Body.String())
+		}
+		var customer models.Customer
+		if err := json.Unmarshal(w.Body.Bytes(), &customer); err != nil || customer.ID != 7 || customer.UUID != key {
+			t.Errorf("Expected customer 7 with its UUID for %s, got %+v (%v)", path, customer, err)
+		}
+	}
+	if w := get("/api/customers/0190a6c8-9f3e-7b4a-8c1d-000000000000"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown UUID, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := get("/api/customers/alice"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid ID, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// Once clients use UUIDs, integer ids can be turned off
+	t.Setenv("API_INTEGER_IDS", "false")
+	if w := get("/api/customers/7"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an integer id, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := get("/api/customers/" + key); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for the UUID, got %d", http.StatusOK, w.Code)
+	}
+}
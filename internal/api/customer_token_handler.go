@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const customerTokenColumns = "id, customer_id, account_id, name, prefix, scopes, created_by, created_at, last_used_at, expires_at, revoked_at"
+
+func scanCustomerToken(row interface{ Scan(...interface{}) error }) (models.CustomerToken, error) {
+	var token models.CustomerToken
+	var accountID sql.NullInt64
+	var lastUsedAt, expiresAt, revokedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.CustomerID, &accountID, &token.Name, &token.Prefix, db.Array(&token.Scopes),
+		&token.CreatedBy, &token.CreatedAt, &lastUsedAt, &expiresAt, &revokedAt)
+	if accountID.Valid {
+		id := int(accountID.Int64)
+		token.AccountID = &id
+	}
+	token.LastUsedAt = nullTime(lastUsedAt)
+	token.ExpiresAt = nullTime(expiresAt)
+	token.RevokedAt = nullTime(revokedAt)
+	return token, err
+}
+
+// CreateCustomerToken issues an API token a customer can use on the /my routes
+// @Summary      Create customer API token
+// @Description  Issue a token a customer uses to read its own data through the /my routes (admin only). Set account_id to limit it to one of the customer's accounts. Scopes default to every scope: accounts:read, usage:read, and statements:read. The token is only returned in this response; publishes an api_key.created event.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                             true  "Customer ID or UUID"
+// @Param        token  body      models.CreateCustomerTokenRequest  true  "Token name, account, scopes, and expiry"
+// @Success      201    {object}  models.CustomerToken
+// @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /admin/customers/{id}/tokens [post]
+// @Security     BearerAuth
+func CreateCustomerToken(c *gin.Context) {
+	customerID, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.CreateCustomerTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = auth.CustomerTokenScopes
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidCustomerTokenScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := customerRepo.Get(ctx, customerID, nil); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if req.AccountID != nil {
+		account, err := accountRepo.Get(ctx, *req.AccountID, nil)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && account.CustomerID != customerID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Account does not belong to the customer"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+	}
+
+	secret, err := auth.NewCustomerToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	token, err := insertCustomerToken(ctx, customerID, req, secret, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create customer token"})
+		return
+	}
+	token.Token = secret
+
+	events.Publish(ctx, events.APIKeyCreated, gin.H{
+		"id":          token.ID,
+		"customer_id": token.CustomerID,
+		"account_id":  token.AccountID,
+		"name":        token.Name,
+		"prefix":      token.Prefix,
+		"scopes":      token.Scopes,
+		"created_by":  token.CreatedBy,
+	})
+	c.JSON(http.StatusCreated, token)
+}
+
+// insertCustomerToken stores the hash of secret; tests replace it
+var insertCustomerToken = func(ctx context.Context, customerID int, req models.CreateCustomerTokenRequest, secret, createdBy string) (models.CustomerToken, error) {
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		// expires_at is a TIMESTAMP, compared against UTC timestamps
+		utc := req.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	return scanCustomerToken(db.Primary(ctx).QueryRow(
+		`INSERT INTO customer_api_tokens (customer_id, account_id, name, prefix, token_hash, scopes, created_by, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING `+customerTokenColumns,
+		customerID, req.AccountID, req.Name, auth.CustomerTokenDisplay(secret), auth.HashCustomerToken(secret), req.Scopes, createdBy, expiresAt,
+	))
+}
+
+// GetCustomerTokens lists a customer's API tokens
+// @Summary      List customer API tokens
+// @Description  List a customer's API tokens, including revoked and expired ones, without the tokens themselves (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Customer ID or UUID"
+// @Success      200  {array}   models.CustomerToken
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/customers/{id}/tokens [get]
+// @Security     BearerAuth
+func GetCustomerTokens(c *gin.Context) {
+	customerID, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT "+customerTokenColumns+" FROM customer_api_tokens WHERE customer_id = $1 ORDER BY id",
+		customerID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer tokens"})
+		return
+	}
+	defer rows.Close()
+
+	tokens := []models.CustomerToken{}
+	for rows.Next() {
+		token, err := scanCustomerToken(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan customer token"})
+			return
+		}
+		tokens = append(tokens, token)
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeCustomerToken stops a customer API token from working
+// @Summary      Revoke customer API token
+// @Description  Revoke a customer's API token; it is kept, with revoked_at set, so its usage stays attributable (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "Customer ID or UUID"
+// @Param        token_id  path      int     true  "Token ID"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/customers/{id}/tokens/{token_id} [delete]
+// @Security     BearerAuth
+func RevokeCustomerToken(c *gin.Context) {
+	customerID, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	tokenID, err := strconv.Atoi(c.Param("token_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	result, err := db.Primary(c.Request.Context()).Exec(
+		"UPDATE customer_api_tokens SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1 AND customer_id = $2",
+		tokenID, customerID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke customer token"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer token revoked successfully"})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateCustomerTokenValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeCustomers(t, models.Customer{ID: 7}, models.Customer{ID: 8})
+	useFakeAccounts(t, &fakeAccounts{accounts: []models.Account{{ID: 2, CustomerID: 8}}})
+
+	router := gin.New()
+	router.POST("/api/admin/customers/:id/tokens", CreateCustomerToken)
+
+	tests := []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/admin/customers/x/tokens", `{"name": "Billing"}`, http.StatusBadRequest},
+		{"/api/admin/customers/7/tokens", `{}`, http.StatusBadRequest},
+		{"/api/admin/customers/7/tokens", `{"name": "Billing", "scopes": ["accounts:write"]}`, http.StatusBadRequest},
+		{"/api/admin/customers/7/tokens", `{"name": "Billing", "expires_at": "2020-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"/api/admin/customers/9/tokens", `{"name": "Billing"}`, http.StatusNotFound},
+		{"/api/admin/customers/7/tokens", `{"name": "Billing", "account_id": 2}`, http.StatusBadRequest},
+		{"/api/admin/customers/7/tokens", `{"name": "Billing", "account_id": 5}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s %s, got %d: %s", tt.want, tt.path, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"log"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/pii"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Responses that carry personal data go through the functions in this file,
+// which apply the caller's PII policy (see internal/pii) and audit access to
+// unmasked fields.
+
+// piiPolicy returns the caller's masking policy. If the role can't be read the
+// caller is treated as masked.
+func piiPolicy(c *gin.Context) pii.Policy {
+	return pii.PolicyFor(callerRole(c))
+}
+
+// callerRole returns the caller's role, looking it up at most once per
+// request, or "" if it can't be read
+func callerRole(c *gin.Context) string {
+	role, ok := c.Get("role")
+	if !ok {
+		var err error
+		role, err = userRole(c.Request.Context(), c.GetString("username"))
+		if err != nil {
+			role = ""
+		}
+		c.Set("role", role)
+	}
+	roleName, _ := role.(string)
+	return roleName
+}
+
+// customerDTO applies policy to a customer before it is returned
+func customerDTO(policy pii.Policy, customer models.Customer) models.Customer {
+	customer.Email = policy.Apply("email", customer.Email)
+	return customer
+}
+
+// customerDTOs prepares customers for the caller and audits unmasked access
+func customerDTOs(c *gin.Context, customers []models.Customer) []models.Customer {
+	policy := piiPolicy(c)
+	ids := make([]int, 0, len(customers))
+	for i := range customers {
+		customers[i] = customerDTO(policy, customers[i])
+		ids = append(ids, customers[i].ID)
+	}
+	auditPIIAccess(c, policy, "customers", ids, "email")
+	return customers
+}
+
+// customerDiffDTO masks PII field changes in a customer diff
+func customerDiffDTO(c *gin.Context, diff models.CustomerDiff) models.CustomerDiff {
+	policy := piiPolicy(c)
+	revealed := false
+	for i, change := range diff.Changes {
+		if change.Field != "email" {
+			continue
+		}
+		if from, ok := change.From.(string); ok {
+			diff.Changes[i].From = policy.Apply("email", from)
+		}
+		if to, ok := change.To.(string); ok {
+			diff.Changes[i].To = policy.Apply("email", to)
+		}
+		revealed = true
+	}
+	if revealed {
+		auditPIIAccess(c, policy, "customers", []int{diff.CustomerID}, "email")
+	}
+	return diff
+}
+
+// auditEntryDTOs masks PII in the customer rows of audit entries and audits
+// unmasked access
+func auditEntryDTOs(c *gin.Context, entries []models.AuditEntry) []models.AuditEntry {
+	policy := piiPolicy(c)
+	var ids []int
+	for _, entry := range entries {
+		if entry.Entity != "customers" {
+			continue
+		}
+		for _, row := range []map[string]interface{}{entry.Before, entry.After} {
+			if email, ok := row["email"].(string); ok {
+				row["email"] = policy.Apply("email", email)
+			}
+		}
+		ids = append(ids, entry.EntityID)
+	}
+	auditPIIAccess(c, policy, "customers", ids, "email")
+	return entries
+}
+
+// auditPIIAccess records that the caller saw fields of the given records
+// unmasked. Failures are logged rather than failing the request.
+func auditPIIAccess(c *gin.Context, policy pii.Policy, resource string, ids []int, fields ...string) {
+	var revealed []string
+	for _, field := range fields {
+		if policy.Reveals(field) {
+			revealed = append(revealed, field)
+		}
+	}
+	if len(revealed) == 0 || len(ids) == 0 {
+		return
+	}
+
+	ctx := c.Request.Context()
+	_, err := db.Primary(ctx).Exec(
+		`INSERT INTO pii_access_log (username, role, resource, record_ids, fields, route, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		c.GetString("username"), c.GetString("role"), resource, ids, revealed, c.FullPath(), tracing.FromContext(ctx).RequestID,
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to audit PII access by %s: %v", c.GetString("username"), err)
+	}
+}
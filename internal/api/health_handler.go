@@ -2,8 +2,10 @@ package api
 
 import (
 	"net/http"
+	"os"
 
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,7 +32,7 @@ func HealthCheck(c *gin.Context) {
 	}
 
 	// Check primary database
-	if err := db.PrimaryDB.Ping(); err != nil {
+	if err := db.Primary(c.Request.Context()).Ping(); err != nil {
 		response.Status = "unhealthy"
 		response.Database = "disconnected"
 		c.JSON(http.StatusServiceUnavailable, response)
@@ -40,7 +42,7 @@ func HealthCheck(c *gin.Context) {
 
 	// Check analytics database
 	if db.AnalyticsDB != nil && db.AnalyticsDB != db.PrimaryDB {
-		if err := db.AnalyticsDB.Ping(); err != nil {
+		if err := db.AnalyticsDB.PingContext(c.Request.Context()); err != nil {
 			response.AnalyticsDB = "disconnected"
 		} else {
 			response.AnalyticsDB = "connected"
@@ -56,3 +58,104 @@ func HealthCheck(c *gin.Context) {
 	}
 }
 
+// ReadinessResponse represents the readiness check response
+type ReadinessResponse struct {
+	Status   string            `json:"status"` // ready, degraded, or unready
+	Database string            `json:"database"`
+	Jobs     string            `json:"jobs"` // ok, degraded, unknown, or disabled without REDIS_URL
+	Queues   *jobs.QueueHealth `json:"queues,omitempty"`
+}
+
+// ReadinessCheck reports whether the service can do its work: the database is
+// reachable and the background job backlog is under control
+// @Summary      Readiness check
+// @Description  Check the primary database, job processor heartbeats, queue depth, and the age of the oldest waiting job. Readiness is degraded when the backlog exceeds QUEUE_BACKLOG_THRESHOLD, the oldest job has waited longer than QUEUE_MAX_JOB_AGE, or jobs are waiting with no worker alive. Degraded returns 200 unless READINESS_FAIL_ON_DEGRADED=true.
+// @Tags         health
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  ReadinessResponse
+// @Failure      503  {object}  ReadinessResponse
+// @Router       /health/ready [get]
+func ReadinessCheck(c *gin.Context) {
+	response := ReadinessResponse{Status: "ready", Database: "connected", Jobs: "disabled"}
+
+	if err := db.Primary(c.Request.Context()).Ping(); err != nil {
+		response.Status = "unready"
+		response.Database = "disconnected"
+	}
+
+	if health, ok := jobs.CurrentQueueHealth(); ok {
+		response.Jobs = health.Status
+		response.Queues = &health
+		if health.Status == "degraded" && response.Status == "ready" {
+			response.Status = "degraded"
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status == "unready" || (response.Status == "degraded" && os.Getenv("READINESS_FAIL_ON_DEGRADED") == "true") {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// DatabaseHealthResponse represents the database health response
+type DatabaseHealthResponse struct {
+	Status string `json:"status"` // healthy, degraded when the follower is unreachable, or unhealthy
+	// AnalyticsRouting is where analytics reads go: follower or primary
+	AnalyticsRouting string            `json:"analytics_routing"`
+	Pools            []db.PoolStat     `json:"pools"`
+	Databases        []db.DatabaseStat `json:"databases"`
+}
+
+// DatabaseHealth reports the connection pools and databases side by side
+// @Summary      Database health
+// @Description  Report each connection pool's connections in use (acquired_conns), idle, and acquires that had to wait (empty_acquire_count), with the round-trip latency and pg_stat_database counters of the primary and the analytics follower. Comparing the two shows the reads routed to the follower. Returns 503 when the primary is unreachable; an unreachable follower only degrades the status.
+// @Tags         health
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  DatabaseHealthResponse
+// @Failure      503  {object}  DatabaseHealthResponse
+// @Router       /health/db [get]
+func DatabaseHealth(c *gin.Context) {
+	response := DatabaseHealthResponse{
+		AnalyticsRouting: "follower",
+		Pools:            db.PoolStats(),
+		Databases:        db.DatabaseStats(c.Request.Context()),
+	}
+	if db.AnalyticsPool() == db.PrimaryDB {
+		response.AnalyticsRouting = "primary"
+	}
+	if response.Pools == nil {
+		response.Pools = []db.PoolStat{}
+	}
+	if response.Databases == nil {
+		response.Databases = []db.DatabaseStat{}
+	}
+	response.Status = databaseHealthStatus(response.Databases)
+
+	status := http.StatusOK
+	if response.Status == "unhealthy" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// databaseHealthStatus is unhealthy unless the primary answered, and degraded
+// if any other database didn't
+func databaseHealthStatus(stats []db.DatabaseStat) string {
+	status := "unhealthy"
+	for _, stat := range stats {
+		if stat.Pool == "primary" && stat.Error == "" {
+			status = "healthy"
+		}
+	}
+	if status == "healthy" {
+		for _, stat := range stats {
+			if stat.Error != "" {
+				return "degraded"
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"saas-go-app/internal/db"
+)
+
+func TestDatabaseHealthStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    []db.DatabaseStat
+		expected string
+	}{
+		{"no databases", nil, "unhealthy"},
+		{"primary only", []db.DatabaseStat{{Pool: "primary"}}, "healthy"},
+		{"both up", []db.DatabaseStat{{Pool: "primary"}, {Pool: "analytics"}}, "healthy"},
+		{"follower down", []db.DatabaseStat{{Pool: "primary"}, {Pool: "analytics", Error: "timeout"}}, "degraded"},
+		{"primary down", []db.DatabaseStat{{Pool: "primary", Error: "refused"}, {Pool: "analytics"}}, "unhealthy"},
+	}
+	for _, tt := range tests {
+		if got := databaseHealthStatus(tt.stats); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
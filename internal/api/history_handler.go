@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseAsOf reads the optional as_of query parameter (RFC 3339). It writes a
+// 400 response and returns false if the parameter is present but invalid.
+func parseAsOf(c *gin.Context) (*time.Time, bool) {
+	value := c.Query("as_of")
+	if value == "" {
+		return nil, true
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of, expected an RFC 3339 timestamp such as 2024-01-02T15:04:05Z"})
+		return nil, false
+	}
+	return &asOf, true
+}
+
+// versionedSource returns what to select from for table: the live table, or
+// its rows as of asOf with the timestamp appended to args
+func versionedSource(table string, asOf *time.Time, args []interface{}) (string, []interface{}) {
+	if asOf == nil {
+		return table, args
+	}
+	args = append(args, *asOf)
+	return db.AsOf(table, len(args)), args
+}
+
+// parseWindow reads the from (required) and to (default: now) query
+// parameters as RFC 3339 timestamps. It writes a 400 response and returns
+// false if either is invalid or from isn't before to.
+func parseWindow(c *gin.Context) (time.Time, time.Time, bool) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from, expected an RFC 3339 timestamp"})
+		return time.Time{}, time.Time{}, false
+	}
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to, expected an RFC 3339 timestamp"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// GetCustomerDiff returns what changed for a customer and its accounts between two points in time
+// @Summary      Diff customer history
+// @Description  Get a field-level diff of a customer between from and to, plus the accounts added, removed, or changed in that window. updated_at is left out of field changes.
+// @Tags         customers
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true   "Customer ID or UUID"
+// @Param        from  query     string  true   "Start of the window (RFC 3339)"
+// @Param        to    query     string  false  "End of the window (RFC 3339, default: now)"
+// @Success      200   {object}  models.CustomerDiff
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /customers/{id}/diff [get]
+// @Security     BearerAuth
+func GetCustomerDiff(c *gin.Context) {
+	id, ok := parseCustomerID(c, "id")
+	if !ok || !requireInOrganizationScope(c, "customer", id, "Customer not found in this window") {
+		return
+	}
+
+	from, to, ok := parseWindow(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	before, err := customerVersion(ctx, id, from)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer history"})
+		return
+	}
+	after, err := customerVersion(ctx, id, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer history"})
+		return
+	}
+	if before == nil && after == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found in this window"})
+		return
+	}
+
+	accountsBefore, err := accountVersions(ctx, id, from)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account history"})
+		return
+	}
+	accountsAfter, err := accountVersions(ctx, id, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account history"})
+		return
+	}
+
+	diff := models.CustomerDiff{
+		CustomerID: id,
+		From:       from,
+		To:         to,
+		Changes:    diffFields(before, after),
+		Accounts:   models.AccountsDiff{Added: []models.Account{}, Removed: []models.Account{}, Changed: []models.AccountChange{}},
+	}
+	for accountID, version := range accountsAfter {
+		previous, existed := accountsBefore[accountID]
+		if !existed {
+			diff.Accounts.Added = append(diff.Accounts.Added, version.account)
+			continue
+		}
+		if changes := diffFields(previous.data, version.data); len(changes) > 0 {
+			diff.Accounts.Changed = append(diff.Accounts.Changed, models.AccountChange{AccountID: accountID, Changes: changes})
+		}
+	}
+	for accountID, version := range accountsBefore {
+		if _, exists := accountsAfter[accountID]; !exists {
+			diff.Accounts.Removed = append(diff.Accounts.Removed, version.account)
+		}
+	}
+	sort.Slice(diff.Accounts.Added, func(i, j int) bool { return diff.Accounts.Added[i].ID < diff.Accounts.Added[j].ID })
+	sort.Slice(diff.Accounts.Removed, func(i, j int) bool { return diff.Accounts.Removed[i].ID < diff.Accounts.Removed[j].ID })
+	sort.Slice(diff.Accounts.Changed, func(i, j int) bool { return diff.Accounts.Changed[i].AccountID < diff.Accounts.Changed[j].AccountID })
+
+	c.JSON(http.StatusOK, customerDiffDTO(c, diff))
+}
+
+// customerVersion returns the customer row as it was at asOf, or nil if it did not exist
+func customerVersion(ctx context.Context, id int, asOf time.Time) (map[string]interface{}, error) {
+	var data []byte
+	err := db.Primary(ctx).QueryRow(
+		"SELECT to_jsonb(customers) FROM "+db.AsOf("customers", 2)+" WHERE id = $1",
+		id, asOf,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+type accountVersion struct {
+	account models.Account
+	data    map[string]interface{}
+}
+
+// accountVersions returns the customer's accounts as they were at asOf, keyed by account ID
+func accountVersions(ctx context.Context, customerID int, asOf time.Time) (map[int]accountVersion, error) {
+	rows, err := db.Primary(ctx).Query(
+		"SELECT id, COALESCE(uuid::text, ''), customer_id, COALESCE(reference, ''), COALESCE(type, 'standard'), name, status, created_at, updated_at, to_jsonb(accounts) FROM "+db.AsOf("accounts", 2)+" WHERE customer_id = $1",
+		customerID, asOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]accountVersion)
+	for rows.Next() {
+		var version accountVersion
+		var data []byte
+		account := &version.account
+		if err := rows.Scan(&account.ID, &account.UUID, &account.CustomerID, &account.Reference, &account.Type, &account.Name, &account.Status, &account.CreatedAt, &account.UpdatedAt, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &version.data); err != nil {
+			return nil, err
+		}
+		versions[account.ID] = version
+	}
+	return versions, rows.Err()
+}
+
+// diffFields compares two versions of a row field by field. A nil version
+// (the row did not exist) diffs as if every field was null. updated_at is
+// skipped since it changes on every write.
+func diffFields(before, after map[string]interface{}) []models.FieldChange {
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+	delete(fields, "updated_at")
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	changes := []models.FieldChange{}
+	for _, field := range names {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, models.FieldChange{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	return changes
+}
+
+// GetSnapshotDiff returns the rows added, removed, or changed in each versioned table between two points in time
+// @Summary      Diff snapshots
+// @Description  Compare every versioned table (customers, accounts) as it was at from with how it was at to, and report per table how many rows were added, removed, or changed, how many changed rows touched each field, and a sample of the IDs (admin only). updated_at is ignored. Use it to verify a demo scenario or a migration backfill did what it should.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        from    query     string  true   "First snapshot (RFC 3339)"
+// @Param        to      query     string  false  "Second snapshot (RFC 3339, default: now)"
+// @Param        table   query     string  false  "Only diff this table"
+// @Param        sample  query     int     false  "IDs to list per kind of change (0-1000, default: 20)"
+// @Success      200     {object}  models.SnapshotDiff
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/history/diff [get]
+// @Security     BearerAuth
+func GetSnapshotDiff(c *gin.Context) {
+	from, to, ok := parseWindow(c)
+	if !ok {
+		return
+	}
+	sample, err := strconv.Atoi(c.DefaultQuery("sample", "20"))
+	if err != nil || sample < 0 || sample > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sample, expected 0 to 1000"})
+		return
+	}
+	tables := db.VersionedTables
+	if table := c.Query("table"); table != "" {
+		tables = nil
+		for _, versioned := range db.VersionedTables {
+			if versioned == table {
+				tables = []string{table}
+			}
+		}
+		if tables == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown table %q, expected one of %v", table, db.VersionedTables)})
+			return
+		}
+	}
+
+	diff := models.SnapshotDiff{From: from, To: to, Tables: []models.TableDiff{}}
+	for _, table := range tables {
+		tableDiff, err := snapshotTableDiff(c.Request.Context(), table, from, to, sample)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff " + table})
+			return
+		}
+		diff.Tables = append(diff.Tables, tableDiff)
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// snapshotTableDiff compares table as it was at from and at to. Postgres
+// joins the two versions and returns only rows that differ; the field-level
+// comparison is done here with diffFields.
+func snapshotTableDiff(ctx context.Context, table string, from, to time.Time, sample int) (models.TableDiff, error) {
+	diff := models.TableDiff{Table: table, Fields: map[string]int{}, AddedIDs: []int{}, RemovedIDs: []int{}, ChangedIDs: []int{}}
+	rows, err := db.Analytics(ctx).Query(fmt.Sprintf(`
+		SELECT COALESCE(a.id, b.id), b.data, a.data
+		FROM (SELECT id, to_jsonb(%[1]s) - 'updated_at' AS data FROM %[2]s) b
+		FULL JOIN (SELECT id, to_jsonb(%[1]s) - 'updated_at' AS data FROM %[3]s) a ON a.id = b.id
+		WHERE b.data IS DISTINCT FROM a.data
+		ORDER BY 1`,
+		table, db.AsOf(table, 1), db.AsOf(table, 2)),
+		from, to,
+	)
+	if err != nil {
+		return diff, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var beforeData, afterData []byte
+		if err := rows.Scan(&id, &beforeData, &afterData); err != nil {
+			return diff, err
+		}
+		switch {
+		case beforeData == nil:
+			diff.Added++
+			if len(diff.AddedIDs) < sample {
+				diff.AddedIDs = append(diff.AddedIDs, id)
+			}
+		case afterData == nil:
+			diff.Removed++
+			if len(diff.RemovedIDs) < sample {
+				diff.RemovedIDs = append(diff.RemovedIDs, id)
+			}
+		default:
+			var before, after map[string]interface{}
+			if err := json.Unmarshal(beforeData, &before); err != nil {
+				return diff, err
+			}
+			if err := json.Unmarshal(afterData, &after); err != nil {
+				return diff, err
+			}
+			diff.Changed++
+			if len(diff.ChangedIDs) < sample {
+				diff.ChangedIDs = append(diff.ChangedIDs, id)
+			}
+			for _, change := range diffFields(before, after) {
+				diff.Fields[change.Field]++
+			}
+		}
+	}
+	return diff, rows.Err()
+}
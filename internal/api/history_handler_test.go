@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiffFields(t *testing.T) {
+	before := map[string]interface{}{"id": 1.0, "name": "Acme", "email": "ops@acme.com", "updated_at": "2024-01-01T00:00:00"}
+	after := map[string]interface{}{"id": 1.0, "name": "Acme Corp", "email": "ops@acme.com", "updated_at": "2024-02-01T00:00:00"}
+
+	changes := diffFields(before, after)
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "name" || changes[0].From != "Acme" || changes[0].To != "Acme Corp" {
+		t.Errorf("Unexpected change %+v", changes[0])
+	}
+}
+
+func TestDiffFieldsCreatedInWindow(t *testing.T) {
+	after := map[string]interface{}{"id": 1.0, "name": "Acme"}
+
+	changes := diffFields(nil, after)
+	if len(changes) != 2 {
+		t.Fatalf("Expected every field to change, got %+v", changes)
+	}
+	for _, change := range changes {
+		if change.From != nil {
+			t.Errorf("Expected %s to change from null, got %v", change.Field, change.From)
+		}
+	}
+}
+
+func TestGetSnapshotDiffValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/history/diff", GetSnapshotDiff)
+
+	for _, query := range []string{
+		"",
+		"from=yesterday",
+		"from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"from=2024-03-01T00:00:00Z&sample=-1",
+		"from=2024-03-01T00:00:00Z&table=users",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/history/diff?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
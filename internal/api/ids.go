@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Customers and accounts have a serial id and a UUIDv7 (see
+// migrations/0018_uuidv7_ids.up.sql). Paths take either, e.g.
+//
+//	GET /api/customers/42
+//	GET /api/customers/0190a6c8-9f3e-7b4a-8c1d-2e5f6a7b8c9d
+//
+// Integer ids can be guessed and give away how many rows there are, so once
+// clients use UUIDs, API_INTEGER_IDS=false makes paths with an integer id 404.
+
+// IntegerIDsEnabled reads API_INTEGER_IDS, whether paths still take the
+// integer ids of customers and accounts (default true)
+func IntegerIDsEnabled() bool {
+	value := os.Getenv("API_INTEGER_IDS")
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for API_INTEGER_IDS (%s), using default true", value)
+		return true
+	}
+	return enabled
+}
+
+// parseCustomerID reads the customer in the path parameter name, an id or a
+// UUID. It writes a 400, 404, or 500 response and returns false if there is
+// no such customer to look up.
+func parseCustomerID(c *gin.Context, name string) (int, bool) {
+	return parsePathID(c, name, "customer", "Customer not found", func(ctx context.Context, key string) (int, error) {
+		return customerRepo.IDByUUID(ctx, key)
+	})
+}
+
+// parseAccountID is parseCustomerID for accounts
+func parseAccountID(c *gin.Context, name string) (int, bool) {
+	return parsePathID(c, name, "account", "Account not found", func(ctx context.Context, key string) (int, error) {
+		return accountRepo.IDByUUID(ctx, key)
+	})
+}
+
+// parsePathID reads the path parameter name as the id or UUID of a kind of
+// record, resolving a UUID to its id with byUUID. Unknown records get 404
+// with notFound.
+func parsePathID(c *gin.Context, name, kind, notFound string, byUUID func(ctx context.Context, key string) (int, error)) (int, bool) {
+	value := c.Param(name)
+	if id, err := strconv.Atoi(value); err == nil {
+		if !IntegerIDsEnabled() {
+			c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+			return 0, false
+		}
+		return id, true
+	}
+
+	key, err := uuid.Parse(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + kind + " ID"})
+		return 0, false
+	}
+	id, err := byUUID(c.Request.Context(), key.String())
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + kind})
+		return 0, false
+	}
+	return id, true
+}
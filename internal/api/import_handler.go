@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"saas-go-app/internal/imports"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChunkChecksumHeader carries the hex SHA-256 of an uploaded chunk
+const ChunkChecksumHeader = "X-Chunk-SHA256"
+
+// importError maps an imports error to a response
+func importError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, imports.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Imports are not configured: set IMPORT_S3_BUCKET or attach Bucketeer"})
+	case errors.Is(err, imports.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+	case errors.Is(err, imports.ErrNotUploading), errors.Is(err, imports.ErrIncomplete):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, imports.ErrInvalidChunkSize), errors.Is(err, imports.ErrTooLarge),
+		errors.Is(err, imports.ErrInvalidPart), errors.Is(err, imports.ErrInvalidChecksum),
+		errors.Is(err, imports.ErrChecksumMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Failed to %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action})
+	}
+}
+
+// CreateImportUpload starts a resumable CSV upload
+// @Summary      Start import upload
+// @Description  Start a resumable, chunked upload of a customers CSV (header with name and email columns) to S3 (admin only). The returned id is the resume token. Split the file into parts of chunk_size bytes (5-64 MiB, default 8 MiB; only the last part may be smaller) and PUT each one; parts can be sent in any order and in parallel.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        upload  body      models.CreateImportUploadRequest  true  "File name, total size in bytes, and optional chunk size"
+// @Success      201     {object}  models.ImportUpload
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      503     {object}  map[string]string
+// @Router       /admin/imports [post]
+// @Security     BearerAuth
+func CreateImportUpload(c *gin.Context) {
+	var req models.CreateImportUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := imports.Create(c.Request.Context(), req, c.GetString("username"))
+	if err != nil {
+		importError(c, err, "start upload")
+		return
+	}
+	c.JSON(http.StatusCreated, upload)
+}
+
+// GetImportUpload returns an upload's state, for resuming it
+// @Summary      Get import upload
+// @Description  Get an upload with the parts received so far and the part numbers still missing, so an interrupted upload can be resumed; after completion, the import's status and counts (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Upload ID (resume token)"
+// @Success      200  {object}  models.ImportUpload
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/imports/{id} [get]
+// @Security     BearerAuth
+func GetImportUpload(c *gin.Context) {
+	upload, err := imports.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		importError(c, err, "fetch upload")
+		return
+	}
+	c.JSON(http.StatusOK, upload)
+}
+
+// PutImportUploadPart stores one chunk of an upload
+// @Summary      Upload import part
+// @Description  Upload one chunk as the raw request body, with its hex SHA-256 in the X-Chunk-SHA256 header. Chunks that don't match their checksum or expected size are rejected. Re-uploading a part replaces it (admin only).
+// @Tags         admin
+// @Accept       application/octet-stream
+// @Produce      json
+// @Param        id              path      string  true  "Upload ID (resume token)"
+// @Param        part            path      int     true  "Part number, starting at 1"
+// @Param        X-Chunk-SHA256  header    string  true  "Hex SHA-256 of the chunk"
+// @Success      200             {object}  models.ImportUploadPart
+// @Failure      400             {object}  map[string]string
+// @Failure      403             {object}  map[string]string
+// @Failure      404             {object}  map[string]string
+// @Failure      409             {object}  map[string]string
+// @Router       /admin/imports/{id}/parts/{part} [put]
+// @Security     BearerAuth
+func PutImportUploadPart(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("part"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid part number"})
+		return
+	}
+	checksum := c.GetHeader(ChunkChecksumHeader)
+	if checksum == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ChunkChecksumHeader + " header is required"})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, imports.MaxChunkSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read chunk: " + err.Error()})
+		return
+	}
+
+	part, err := imports.PutPart(c.Request.Context(), c.Param("id"), number, data, checksum)
+	if err != nil {
+		importError(c, err, "store part")
+		return
+	}
+	c.JSON(http.StatusOK, part)
+}
+
+// CompleteImportUpload assembles an upload and starts its import
+// @Summary      Complete import upload
+// @Description  Assemble an upload once every part is in and start the import job. Returns 202 with the upload's job_id; follow it via /admin/jobs/{id}/events or poll the upload for the final imported and skipped counts. Rows whose email already exists are skipped (admin only).
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Upload ID (resume token)"
+// @Success      202  {object}  models.ImportUpload
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/imports/{id}/complete [post]
+// @Security     BearerAuth
+func CompleteImportUpload(c *gin.Context) {
+	upload, err := imports.Complete(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		importError(c, err, "complete upload")
+		return
+	}
+	c.JSON(http.StatusAccepted, upload)
+}
+
+// AbortImportUpload discards an unfinished upload
+// @Summary      Abort import upload
+// @Description  Discard an unfinished upload and the parts stored so far (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Upload ID (resume token)"
+// @Success      204
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/imports/{id} [delete]
+// @Security     BearerAuth
+func AbortImportUpload(c *gin.Context) {
+	if err := imports.Abort(c.Request.Context(), c.Param("id")); err != nil {
+		importError(c, err, "abort upload")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// streamImport loads a JSON Lines import; tests replace it
+var streamImport = imports.Stream
+
+// jsonLinesTypes are the content types accepted for JSON Lines imports
+var jsonLinesTypes = []string{"application/x-ndjson", "application/jsonl", "application/json-lines"}
+
+// StreamImport imports customers and accounts from a JSON Lines request body
+// @Summary      Stream an import
+// @Description  Import customers and accounts from a JSON Lines (NDJSON) body, one record per line, loading them in batches as they arrive, so large imports can be streamed with chunked transfer encoding instead of staged first (admin only). Customers are {"type": "customer", "name", "email"} and skipped if the email exists; accounts are {"type": "account", "name", "customer_email", "status"} (status defaults to active) and skipped if the customer doesn't exist, and get the next reference of their customer. Invalid lines are counted and skipped. The response is JSON Lines too: a progress line after every batch and a final completed or failed line with the first 100 rejected lines. Batches are committed as they go, so a failed import keeps what was loaded. Lines may be at most 1 MiB.
+// @Tags         admin
+// @Accept       application/x-ndjson
+// @Produce      application/x-ndjson
+// @Param        records  body      models.StreamImportRecord  true  "One record per line"
+// @Success      200      {object}  models.StreamImportProgress  "One per line"
+// @Failure      403      {object}  map[string]string
+// @Failure      415      {object}  map[string]string
+// @Router       /admin/imports/stream [post]
+// @Security     BearerAuth
+func StreamImport(c *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if !contains(jsonLinesTypes, mediaType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/x-ndjson"})
+		return
+	}
+
+	job := progress.Start("import")
+	// Answer while the body is still arriving, so the client sees progress and
+	// the router sees a response long before the import ends
+	_ = http.NewResponseController(c.Writer).EnableFullDuplex()
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	encoder := json.NewEncoder(c.Writer)
+	send := func(line models.StreamImportProgress) {
+		line.JobID = job.Snapshot().JobID
+		_ = encoder.Encode(line)
+		c.Writer.Flush()
+	}
+	result, err := streamImport(progress.WithJob(c.Request.Context(), job), c.Request.Body, func(update models.StreamImportProgress) {
+		job.Update("rows", update.Lines, 0)
+		update.Errors = nil
+		send(update)
+	})
+	job.Update("rows", result.Lines, 0)
+	job.Finish(err)
+
+	result.Event = progress.StatusCompleted
+	if err != nil {
+		log.Printf("Streamed import %s failed: %v", job.Snapshot().JobID, err)
+		result.Event = progress.StatusFailed
+		result.Error = err.Error()
+	}
+	send(result)
+}
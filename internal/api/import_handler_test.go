@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := streamImport
+	t.Cleanup(func() { streamImport = previous })
+	var body string
+	var failure error
+	streamImport = func(ctx context.Context, r io.Reader, report func(models.StreamImportProgress)) (models.StreamImportProgress, error) {
+		if progress.FromContext(ctx) == nil {
+			t.Error("Expected the import to run with its job in the context")
+		}
+		data, _ := io.ReadAll(r)
+		body = string(data)
+		update := models.StreamImportProgress{Event: "progress", Lines: 2, Customers: models.StreamImportCounts{Imported: 2}}
+		report(update)
+		update.Invalid = 1
+		update.Errors = []models.StreamImportLineError{{Line: 3, Error: "invalid JSON"}}
+		return update, failure
+	}
+
+	router := gin.New()
+	router.POST("/api/admin/imports/stream", StreamImport)
+	router.POST("/api/admin/imports/:id/complete", CompleteImportUpload)
+	post := func(contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/imports/stream", strings.NewReader(`{"type": "customer"}`+"\n"))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	lines := func(w *httptest.ResponseRecorder) []models.StreamImportProgress {
+		var lines []models.StreamImportProgress
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var line models.StreamImportProgress
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("Expected JSON lines, got %q", scanner.Text())
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	if w := post("application/json"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d for JSON, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+
+	w := post("application/x-ndjson; charset=utf-8")
+	got := lines(w)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" || len(got) != 2 {
+		t.Fatalf("Expected a progress and a final line, got %d %v", w.Code, got)
+	}
+	if body != `{"type": "customer"}`+"\n" {
+		t.Errorf("Expected the request body to be streamed to the import, got %q", body)
+	}
+	if got[0].Event != "progress" || got[0].JobID == "" || got[0].Customers.Imported != 2 {
+		t.Errorf("Expected progress with the job ID, got %+v", got[0])
+	}
+	if got[1].Event != "completed" || got[1].JobID != got[0].JobID || got[1].Invalid != 1 || len(got[1].Errors) != 1 {
+		t.Errorf("Expected the completed import with its errors, got %+v", got[1])
+	}
+	if job, ok := progress.Get(got[1].JobID); !ok || job.Snapshot().Status != progress.StatusCompleted {
+		t.Errorf("Expected a completed import job")
+	}
+
+	failure = errors.New("failed to import customers: connection reset")
+	got = lines(post("application/jsonl"))
+	if len(got) != 2 || got[1].Event != "failed" || got[1].Error != failure.Error() {
+		t.Errorf("Expected the import to fail with its error, got %+v", got)
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrityResponse represents the results of an integrity check run
+type IntegrityResponse struct {
+	Repair  bool                 `json:"repair"`
+	Results []db.IntegrityResult `json:"results"`
+}
+
+// CheckIntegrity runs the data integrity checks immediately
+// @Summary      Run integrity checks
+// @Description  Verify data invariants (orphaned rows, denormalized counters) and optionally repair violations (admin only). The same checks run on a schedule when Redis is configured.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        repair  query     bool  false  "Repair violations that have a safe fix"
+// @Success      200     {object}  IntegrityResponse
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/integrity/check [post]
+// @Security     BearerAuth
+func CheckIntegrity(c *gin.Context) {
+	repair := c.Query("repair") == "true"
+
+	results, err := jobs.RunIntegrityChecks(c.Request.Context(), repair)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run integrity checks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, IntegrityResponse{Repair: repair, Results: results})
+}
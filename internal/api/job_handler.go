@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Heroku's router closes streams that send nothing for 55 seconds
+const sseHeartbeat = 15 * time.Second
+
+// GetJobs lists tracked seed and import jobs
+// @Summary      List jobs
+// @Description  List running seed and import jobs and those that finished in the last hour, most recent first (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   progress.Snapshot
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/jobs [get]
+// @Security     BearerAuth
+func GetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, progress.List())
+}
+
+// GetJob returns the current progress of a job
+// @Summary      Get job progress
+// @Description  Get the current progress of a seed or import job (admin only). With wait (e.g. 20s or 20), the request blocks until the job finishes or the wait elapses, whichever comes first; waits are capped at 25s.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true   "Job ID"
+// @Param        wait  query     string  false  "Longest to wait for the job to finish, as a duration or seconds (max 25s)"
+// @Success      200   {object}  progress.Snapshot
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Router       /admin/jobs/{id} [get]
+// @Security     BearerAuth
+func GetJob(c *gin.Context) {
+	wait, ok := longPollWait(c)
+	if !ok {
+		return
+	}
+	job, ok := progress.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		c.JSON(http.StatusOK, job.Wait(ctx))
+		return
+	}
+	c.JSON(http.StatusOK, job.Snapshot())
+}
+
+// CancelJob asks a running job to stop
+// @Summary      Cancel job
+// @Description  Ask a running seed job to stop (admin only). The job keeps running until the rows in flight are rolled back, then finishes as cancelled; poll or stream it to see when. Chunks of performance data committed before that are kept, so clear the data before seeding again. Returns 409 for jobs that have finished or can't be cancelled.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Job ID"
+// @Success      202  {object}  progress.Snapshot
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/jobs/{id}/cancel [post]
+// @Security     BearerAuth
+func CancelJob(c *gin.Context) {
+	job, ok := progress.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if err := job.Cancel(); err != nil {
+		if errors.Is(err, progress.ErrFinished) || errors.Is(err, progress.ErrNotCancellable) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		return
+	}
+
+	snapshot := job.Snapshot()
+	log.Printf("Cancelling %s job %s, requested by %s", snapshot.Kind, snapshot.JobID, c.GetString("username"))
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// StreamJobProgress streams a job's progress as server-sent events
+// @Summary      Stream job progress
+// @Description  Stream progress events (phase, rows done, rate, ETA) for a seed or import job until it finishes (admin only). Each update is a "progress" event with a progress.Snapshot as data; the stream ends with a "completed", "failed", or "cancelled" event. Browsers' EventSource cannot send the Authorization header, so read the stream with fetch.
+// @Tags         admin
+// @Accept       json
+// @Produce      text/event-stream
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  progress.Snapshot
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/jobs/{id}/events [get]
+// @Security     BearerAuth
+func StreamJobProgress(c *gin.Context) {
+	job, ok := progress.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	updates, cancel := job.Subscribe()
+	defer cancel()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Stop proxies from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case snapshot, open := <-updates:
+			if !open {
+				return
+			}
+			event := "progress"
+			if snapshot.Finished() {
+				event = snapshot.Status
+			}
+			c.SSEvent(event, snapshot)
+		case <-heartbeat.C:
+			// SSE comment lines keep the connection open without emitting an event
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
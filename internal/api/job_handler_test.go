@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamJobProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/admin/jobs/:id/events", StreamJobProgress)
+
+	job := progress.Start("seed")
+	job.Update("customers", 5, 5)
+	job.Finish(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/jobs/"+job.Snapshot().JobID+"/events", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Errorf("Expected an event stream, got %s", contentType)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "event:completed") {
+		t.Errorf("Expected a completed event, got %q", body)
+	}
+}
+
+func TestStreamJobProgressUnknownJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/api/admin/jobs/:id/events", StreamJobProgress)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/jobs/seed-missing/events", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
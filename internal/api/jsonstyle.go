@@ -0,0 +1,325 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Clients pick how /api JSON is serialized, so consumers written against
+// other conventions don't each need an adapter. Handlers keep encoding the
+// snake_case models; ResponseStyle rewrites the encoded body on its way out:
+//
+//	API-Version: 2                  camelCase keys, successes wrapped in {"data": ...}
+//	X-JSON-Naming: camelCase        override the version's naming (snake_case or camelCase)
+//	X-JSON-Envelope: true           override the version's envelope (true or false)
+//
+// Version 1, the default, is the API as documented.
+
+// Field naming styles
+const (
+	SnakeCase = "snake_case"
+	CamelCase = "camelCase"
+)
+
+// DefaultAPIVersion is the version requests without API-Version get
+const DefaultAPIVersion = "1"
+
+// jsonStyleKey holds the request's JSONStyle in the gin context
+const jsonStyleKey = "json_style"
+
+// JSONStyle is how response bodies are serialized
+type JSONStyle struct {
+	// Naming is SnakeCase or CamelCase
+	Naming string
+	// Envelope wraps successful responses as {"data": ...}, with the
+	// response_meta block, if any, next to it as "meta"
+	Envelope bool
+}
+
+// APIVersions maps each API-Version a client can send to the style it gets
+var APIVersions = map[string]JSONStyle{
+	"1": {Naming: SnakeCase},
+	"2": {Naming: CamelCase, Envelope: true},
+}
+
+// requestedStyle returns the style and API version asked for by a request's
+// headers, or an error naming the invalid one
+func requestedStyle(header http.Header) (JSONStyle, string, error) {
+	version := header.Get("API-Version")
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+	style, ok := APIVersions[version]
+	if !ok {
+		return style, version, errors.New("Invalid API-Version, expected 1 or 2")
+	}
+
+	switch naming := header.Get("X-JSON-Naming"); naming {
+	case "":
+	case SnakeCase, CamelCase:
+		style.Naming = naming
+	default:
+		return style, version, errors.New("Invalid X-JSON-Naming, expected snake_case or camelCase")
+	}
+	if envelope := header.Get("X-JSON-Envelope"); envelope != "" {
+		value, err := strconv.ParseBool(envelope)
+		if err != nil {
+			return style, version, errors.New("Invalid X-JSON-Envelope, expected true or false")
+		}
+		style.Envelope = value
+	}
+	return style, version, nil
+}
+
+// responseStyle returns the style ResponseStyle chose for the request, or
+// version 1's outside of it
+func responseStyle(c *gin.Context) JSONStyle {
+	if style, ok := c.Get(jsonStyleKey); ok {
+		return style.(JSONStyle)
+	}
+	return APIVersions[DefaultAPIVersion]
+}
+
+// ResponseStyle serializes JSON responses in the style the request asks for
+// with API-Version, X-JSON-Naming, and X-JSON-Envelope, and answers invalid
+// values with 400. Responses carry the API-Version they were served as.
+//
+// With camelCase, every object key in the response is renamed, and JSON
+// request bodies are renamed back to snake_case before handlers bind them.
+// That includes the keys of free-form maps, such as feature_flags. Other
+// content types, such as CSV exports and event streams, pass through as is,
+// and error responses are never wrapped in an envelope.
+func ResponseStyle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		style, version, err := requestedStyle(c.Request.Header)
+		c.Writer.Header().Add("Vary", "API-Version, X-JSON-Naming, X-JSON-Envelope")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Header("API-Version", version)
+		c.Set(jsonStyleKey, style)
+		if style == APIVersions[DefaultAPIVersion] {
+			c.Next()
+			return
+		}
+
+		if style.Naming == CamelCase && c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			// Invalid JSON is left for the handler to reject
+			if renamed, err := renameKeys(body, snakeCase); err == nil {
+				body = renamed
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.ContentLength = int64(len(body))
+		}
+
+		writer := &styledWriter{ResponseWriter: c.Writer, style: style}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		writer.finish(c.GetBool(envelopedKey))
+	}
+}
+
+// envelopedKey is set once WithMeta has wrapped the response in an envelope
+const envelopedKey = "json_enveloped"
+
+// styledWriter holds back JSON bodies so finish can restyle them, and passes
+// anything else straight through
+type styledWriter struct {
+	gin.ResponseWriter
+	style     JSONStyle
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+// decide buffers the response if it is JSON, judging by the Content-Type set
+// before its first write
+func (w *styledWriter) decide() {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+}
+
+func (w *styledWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *styledWriter) WriteString(s string) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *styledWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *styledWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *styledWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *styledWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// finish writes the buffered body in the chosen style. enveloped reports
+// that WithMeta already wrapped it.
+func (w *styledWriter) finish(enveloped bool) {
+	if !w.buffering {
+		return
+	}
+	body := w.body.Bytes()
+	status := w.ResponseWriter.Status()
+	if w.style.Envelope && !enveloped && status >= 200 && status < 300 && json.Valid(body) {
+		body = append(append([]byte(`{"data":`), bytes.TrimSpace(body)...), '}')
+	}
+	if w.style.Naming == CamelCase {
+		if renamed, err := renameKeys(body, camelCase); err == nil {
+			body = renamed
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// renameKeys rewrites every object key in a JSON document with rename,
+// keeping the order of keys and the exact form of values
+func renameKeys(body []byte, rename func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	// Each open object or array, with the tokens read in it so far
+	type container struct {
+		object bool
+		tokens int
+	}
+	var stack []container
+	var out bytes.Buffer
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		key := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			key = top.object && top.tokens%2 == 0
+			switch {
+			case top.tokens == 0:
+			case top.object && !key:
+				out.WriteByte(':')
+			default:
+				out.WriteByte(',')
+			}
+			top.tokens++
+		}
+
+		switch value := token.(type) {
+		case json.Delim:
+			out.WriteByte(byte(value))
+			stack = append(stack, container{object: value == '{'})
+		case string:
+			if key {
+				value = rename(value)
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded)
+		case json.Number:
+			out.WriteString(value.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(value))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// camelCase turns snake_case into camelCase, e.g. created_at into createdAt.
+// Underscores not followed by a lowercase letter, as in _id or p_95, are kept.
+func camelCase(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+	var b strings.Builder
+	runes := []rune(key)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '_' && i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			i++
+			b.WriteRune(unicode.ToUpper(runes[i]))
+			continue
+		}
+		b.WriteRune(runes[i])
+	}
+	return b.String()
+}
+
+// snakeCase turns camelCase into snake_case, e.g. createdAt into created_at
+// and otpauthURL into otpauth_url. snake_case keys are left as they are.
+func snakeCase(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if i > 0 {
+			previous := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
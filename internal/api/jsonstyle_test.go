@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRenameKeys(t *testing.T) {
+	body := `{"customer_id":1,"created_at":"2024-05-01","items":[{"mrr_cents":12.50,"notes":null,"active":true}],"_id":"x_y","p_95":[]}`
+	got, err := renameKeys([]byte(body), camelCase)
+	if err != nil {
+		t.Fatalf("Failed to rename keys: %v", err)
+	}
+	expected := `{"customerId":1,"createdAt":"2024-05-01","items":[{"mrrCents":12.50,"notes":null,"active":true}],"_id":"x_y","p_95":[]}`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	back, err := renameKeys(got, snakeCase)
+	if err != nil {
+		t.Fatalf("Failed to rename keys back: %v", err)
+	}
+	if string(back) != body {
+		t.Errorf("Expected %s back, got %s", body, back)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for key, expected := range map[string]string{
+		"createdAt":   "created_at",
+		"otpauthURL":  "otpauth_url",
+		"HTTPServer":  "http_server",
+		"already_set": "already_set",
+	} {
+		if got := snakeCase(key); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, key, got)
+		}
+	}
+}
+
+func TestResponseStyle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ResponseStyle())
+	router.GET("/accounts", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{{"mrr_cents": 100}})
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	})
+	router.GET("/export", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/csv", []byte("mrr_cents\n100\n"))
+	})
+	router.POST("/echo", func(c *gin.Context) {
+		var body struct {
+			CustomerID int `json:"customer_id"`
+		}
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusCreated, gin.H{"customer_id": body.CustomerID})
+	})
+
+	tests := []struct {
+		method, path, body string
+		headers            map[string]string
+		status             int
+		expected           string
+	}{
+		{"GET", "/accounts", "", nil, http.StatusOK, `[{"mrr_cents":100}]`},
+		{"GET", "/accounts", "", map[string]string{"API-Version": "2"}, http.StatusOK, `{"data":[{"mrrCents":100}]}`},
+		{"GET", "/accounts", "", map[string]string{"X-JSON-Naming": "camelCase"}, http.StatusOK, `[{"mrrCents":100}]`},
+		{"GET", "/accounts", "", map[string]string{"API-Version": "2", "X-JSON-Naming": "snake_case"}, http.StatusOK, `{"data":[{"mrr_cents":100}]}`},
+		{"GET", "/accounts", "", map[string]string{"X-JSON-Envelope": "true"}, http.StatusOK, `{"data":[{"mrr_cents":100}]}`},
+		{"GET", "/missing", "", map[string]string{"API-Version": "2"}, http.StatusNotFound, `{"error":"Account not found"}`},
+		{"GET", "/export", "", map[string]string{"API-Version": "2"}, http.StatusOK, "mrr_cents\n100\n"},
+		{"POST", "/echo", `{"customerId": 7}`, map[string]string{"X-JSON-Naming": "camelCase", "Content-Type": "application/json"}, http.StatusCreated, `{"customerId":7}`},
+		{"GET", "/accounts", "", map[string]string{"API-Version": "3"}, http.StatusBadRequest, `{"error":"Invalid API-Version, expected 1 or 2"}`},
+		{"GET", "/accounts", "", map[string]string{"X-JSON-Envelope": "maybe"}, http.StatusBadRequest, `{"error":"Invalid X-JSON-Envelope, expected true or false"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Body.String() != tt.expected {
+			t.Errorf("%s %s with %v: expected %d %s, got %d %s", tt.method, tt.path, tt.headers, tt.status, tt.expected, w.Code, w.Body.String())
+		}
+		if w.Code != http.StatusBadRequest && w.Header().Get("Content-Length") != "" && w.Header().Get("Content-Length") != strconv.Itoa(w.Body.Len()) {
+			t.Errorf("%s %s with %v: Content-Length %s doesn't match the body", tt.method, tt.path, tt.headers, w.Header().Get("Content-Length"))
+		}
+	}
+}
+
+func TestResponseStyleWithMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+	settings := previous
+	settings.FeatureFlags = map[string]bool{ResponseMetaFlag: true}
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(ResponseStyle())
+	router.GET("/summary", WithMeta(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"total_mrr": 5})
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/summary", nil)
+	req.Header.Set("API-Version", "2")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.HasPrefix(body, `{"data":{"totalMrr":5},"meta":{`) || !strings.Contains(body, `"queryDurationMs"`) {
+		t.Errorf("Expected the meta block beside the data, got %s", body)
+	}
+}
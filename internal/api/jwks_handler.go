@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"saas-go-app/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys that verify the app's JWTs
+// @Summary      JSON Web Key Set
+// @Description  The RSA public keys that verify the JWTs this app issues, as a JWKS (RFC 7517), so other services can verify tokens without sharing a secret. Tokens are signed with RS256 and name their key in the kid header. The signing key comes first; keys still active for verification during a rotation follow. Responses may be cached for 5 minutes, so publish a new key here before signing with it.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  auth.JSONWebKeySet
+// @Router       /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.JWKS())
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	// Time zones are embedded, since the runtime image ships without tzdata
+	_ "time/tzdata"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Customer and account lists can be trimmed to some fields and have their
+// timestamps returned in a time zone, e.g.
+//
+//	GET /api/customers?fields=name,email&tz=Europe/Berlin
+//
+// Without the parameters, the caller's preferences apply (see
+// preference_handler.go). id is always returned, so rows can be told apart.
+
+// listRows maps the lists that take fields to their row type, whose JSON
+// field names are the fields that can be picked
+var listRows = map[string]reflect.Type{
+	"customers": reflect.TypeOf(models.Customer{}),
+	"accounts":  reflect.TypeOf(models.Account{}),
+}
+
+// listView is how the caller wants a list rendered
+type listView struct {
+	fields   []string
+	location *time.Location
+}
+
+// parseListView reads the fields and tz query parameters for list, falling
+// back to the caller's preferences. It writes a 400 response and returns
+// false if either parameter is invalid. Preferences that no longer apply,
+// e.g. a field that was removed, are skipped.
+func parseListView(c *gin.Context, list string) (listView, bool) {
+	var view listView
+	prefs := preferences(c)
+
+	if value, ok := c.GetQuery("fields"); ok {
+		fields := splitFields(value)
+		if unknown := unknownField(list, fields); unknown != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown field %s, expected one of %s", unknown, strings.Join(jsonFields(listRows[list]), ", "))})
+			return listView{}, false
+		}
+		view.fields = fields
+	} else {
+		for _, field := range prefs.Fields[list] {
+			if unknownField(list, []string{field}) == "" {
+				view.fields = append(view.fields, field)
+			}
+		}
+	}
+
+	if value, ok := c.GetQuery("tz"); ok {
+		location, err := loadTimezone(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tz, expected an IANA time zone such as Europe/Berlin"})
+			return listView{}, false
+		}
+		view.location = location
+	} else if prefs.Timezone != "" {
+		location, err := loadTimezone(prefs.Timezone)
+		if err != nil {
+			log.Printf("Warning: Ignoring time zone preference %q of %s: %v", prefs.Timezone, c.GetString("username"), err)
+		}
+		view.location = location
+	}
+	return view, true
+}
+
+// splitFields splits a comma-separated fields parameter, dropping blanks
+func splitFields(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// unknownField returns the first of fields that list's rows don't have, or ""
+// if they have them all
+func unknownField(list string, fields []string) string {
+	known := jsonFields(listRows[list])
+	for _, field := range fields {
+		found := false
+		for _, name := range known {
+			if field == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return field
+		}
+	}
+	return ""
+}
+
+// jsonFields returns the JSON field names of a struct type, in field order
+func jsonFields(rowType reflect.Type) []string {
+	var names []string
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// loadTimezone loads an IANA time zone. The server's Local zone isn't one a
+// client can rely on, so it is rejected.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// render applies the view to rows, a slice of structs. Timestamps are moved
+// to the view's time zone and, with fields, each row becomes an object of
+// just id and those fields.
+func (v listView) render(rows interface{}) interface{} {
+	slice := reflect.ValueOf(rows)
+	if v.location != nil {
+		inLocation(slice, v.location)
+	}
+	if len(v.fields) == 0 {
+		return rows
+	}
+
+	rowType := slice.Type().Elem()
+	index := make(map[string]int, rowType.NumField())
+	for i := 0; i < rowType.NumField(); i++ {
+		index[strings.Split(rowType.Field(i).Tag.Get("json"), ",")[0]] = i
+	}
+	picked := make([]map[string]interface{}, slice.Len())
+	for row := range picked {
+		values := map[string]interface{}{"id": slice.Index(row).Field(index["id"]).Interface()}
+		for _, field := range v.fields {
+			values[field] = slice.Index(row).Field(index[field]).Interface()
+		}
+		picked[row] = values
+	}
+	return picked
+}
+
+// inLocation moves every time.Time in value to loc, through struct fields,
+// pointers, and slices
+func inLocation(value reflect.Value, loc *time.Location) {
+	switch value.Kind() {
+	case reflect.Struct:
+		if t, ok := value.Addr().Interface().(*time.Time); ok {
+			*t = t.In(loc)
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				inLocation(value.Field(i), loc)
+			}
+		}
+	case reflect.Ptr:
+		if !value.IsNil() {
+			inLocation(value.Elem(), loc)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			inLocation(value.Index(i), loc)
+		}
+	}
+}
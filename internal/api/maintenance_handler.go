@@ -0,0 +1,184 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Tables with fewer dead tuples are skipped for warnings, matching autovacuum_vacuum_threshold
+	minDeadTuples = 50
+	// Indexes smaller than this are skipped for bloat warnings; the estimate is too rough for tiny indexes
+	minIndexBloatBytes = 1 << 20
+)
+
+// TableMaintenance represents vacuum statistics and estimated bloat for a table
+type TableMaintenance struct {
+	Table               string     `json:"table"`
+	LiveTuples          int64      `json:"live_tuples"`
+	DeadTuples          int64      `json:"dead_tuples"`
+	DeadTupleRatio      float64    `json:"dead_tuple_ratio"`
+	SizeBytes           int64      `json:"size_bytes"`
+	EstimatedBloatBytes int64      `json:"estimated_bloat_bytes"`
+	LastVacuum          *time.Time `json:"last_vacuum"`
+	LastAutovacuum      *time.Time `json:"last_autovacuum"`
+	LastAnalyze         *time.Time `json:"last_analyze"`
+	LastAutoanalyze     *time.Time `json:"last_autoanalyze"`
+	AutovacuumCount     int64      `json:"autovacuum_count"`
+}
+
+// IndexMaintenance represents estimated bloat for an index
+type IndexMaintenance struct {
+	Table               string  `json:"table"`
+	Index               string  `json:"index"`
+	SizeBytes           int64   `json:"size_bytes"`
+	EstimatedBloatBytes int64   `json:"estimated_bloat_bytes"`
+	BloatRatio          float64 `json:"bloat_ratio"`
+	Scans               int64   `json:"scans"`
+}
+
+// MaintenanceResponse represents table and index health with warnings for anything over the threshold
+type MaintenanceResponse struct {
+	Threshold float64            `json:"threshold"`
+	Tables    []TableMaintenance `json:"tables"`
+	Indexes   []IndexMaintenance `json:"indexes"`
+	Warnings  []string           `json:"warnings"`
+}
+
+// GetDBMaintenance reports dead tuples, vacuum times, and estimated bloat
+// @Summary      Database maintenance status
+// @Description  Report dead tuple counts, last (auto)vacuum and analyze times, and estimated table and index bloat, with warnings for ratios above the threshold (admin only). The threshold defaults to DB_BLOAT_WARN_RATIO or 0.2.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        threshold  query     number  false  "Warning threshold as a ratio between 0 and 1"
+// @Success      200        {object}  MaintenanceResponse
+// @Failure      400        {object}  map[string]string
+// @Failure      403        {object}  map[string]string
+// @Failure      500        {object}  map[string]string
+// @Router       /admin/db/maintenance [get]
+// @Security     BearerAuth
+func GetDBMaintenance(c *gin.Context) {
+	threshold := 0.2
+	if value := os.Getenv("DB_BLOAT_WARN_RATIO"); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			threshold = parsed
+		}
+	}
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold"})
+			return
+		}
+		threshold = parsed
+	}
+
+	ctx := c.Request.Context()
+	response := MaintenanceResponse{Threshold: threshold, Tables: []TableMaintenance{}, Indexes: []IndexMaintenance{}, Warnings: []string{}}
+
+	// Table bloat is estimated as the dead tuple share of the table's heap
+	rows, err := db.Primary(ctx).Query(`
+		SELECT relname, n_live_tup, n_dead_tup, pg_table_size(relid),
+			last_vacuum, last_autovacuum, last_analyze, last_autoanalyze, autovacuum_count
+		FROM pg_stat_user_tables
+		ORDER BY n_dead_tup DESC, relname`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch table statistics"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table TableMaintenance
+		var lastVacuum, lastAutovacuum, lastAnalyze, lastAutoanalyze sql.NullTime
+		if err := rows.Scan(&table.Table, &table.LiveTuples, &table.DeadTuples, &table.SizeBytes,
+			&lastVacuum, &lastAutovacuum, &lastAnalyze, &lastAutoanalyze, &table.AutovacuumCount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan table statistics"})
+			return
+		}
+		table.LastVacuum = nullTime(lastVacuum)
+		table.LastAutovacuum = nullTime(lastAutovacuum)
+		table.LastAnalyze = nullTime(lastAnalyze)
+		table.LastAutoanalyze = nullTime(lastAutoanalyze)
+
+		if total := table.LiveTuples + table.DeadTuples; total > 0 {
+			table.DeadTupleRatio = float64(table.DeadTuples) / float64(total)
+			table.EstimatedBloatBytes = int64(table.DeadTupleRatio * float64(table.SizeBytes))
+		}
+		if table.DeadTuples >= minDeadTuples && table.DeadTupleRatio > threshold {
+			response.Warnings = append(response.Warnings, fmt.Sprintf(
+				"table %s: %.0f%% dead tuples (%d), last autovacuum %s",
+				table.Table, table.DeadTupleRatio*100, table.DeadTuples, formatLastRun(table.LastAutovacuum)))
+		}
+
+		response.Tables = append(response.Tables, table)
+	}
+
+	// Index bloat compares the actual size with the size expected from row count and
+	// average key width (12 bytes of tuple header and item pointer, 90% fill factor)
+	indexRows, err := db.Primary(ctx).Query(`
+		SELECT s.relname, s.indexrelname, pg_relation_size(s.indexrelid), s.idx_scan,
+			(GREATEST(c.reltuples, 0) * (12 + COALESCE((
+				SELECT SUM(st.avg_width)
+				FROM pg_attribute a
+				JOIN pg_stats st ON st.schemaname = s.schemaname AND st.tablename = s.relname AND st.attname = a.attname
+				WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+			), 8)) / 0.9)::bigint
+		FROM pg_stat_user_indexes s
+		JOIN pg_index i ON i.indexrelid = s.indexrelid
+		JOIN pg_class c ON c.oid = s.indexrelid
+		ORDER BY pg_relation_size(s.indexrelid) DESC, s.indexrelname`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch index statistics"})
+		return
+	}
+	defer indexRows.Close()
+
+	for indexRows.Next() {
+		var index IndexMaintenance
+		var expectedBytes int64
+		if err := indexRows.Scan(&index.Table, &index.Index, &index.SizeBytes, &index.Scans, &expectedBytes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan index statistics"})
+			return
+		}
+
+		if index.SizeBytes > expectedBytes {
+			index.EstimatedBloatBytes = index.SizeBytes - expectedBytes
+			index.BloatRatio = float64(index.EstimatedBloatBytes) / float64(index.SizeBytes)
+		}
+		if index.SizeBytes >= minIndexBloatBytes && index.BloatRatio > threshold {
+			response.Warnings = append(response.Warnings, fmt.Sprintf(
+				"index %s on %s: ~%.0f%% bloat (%d bytes), consider REINDEX CONCURRENTLY",
+				index.Index, index.Table, index.BloatRatio*100, index.EstimatedBloatBytes))
+		}
+
+		response.Indexes = append(response.Indexes, index)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func formatLastRun(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ResponseMetaFlag is the feature flag that adds a meta block to analytics responses
+const ResponseMetaFlag = "response_meta"
+
+// DBStatsFlag is the feature flag that adds X-DB-Queries and X-DB-Duration-ms
+// headers to every response
+const DBStatsFlag = "db_stats_headers"
+
+// dataAsOfKey holds when precomputed data served by a handler was computed
+const dataAsOfKey = "meta_data_as_of"
+
+// ResponseMeta describes what the infrastructure did to answer a request
+type ResponseMeta struct {
+	// ServedBy is the pool that ran the queries: follower, primary, or mixed
+	ServedBy string `json:"served_by,omitempty"`
+	// Cache is "live" when the data was queried for this request, or
+	// "precomputed" when it comes from a report refreshed in the background
+	Cache           string  `json:"cache"`
+	Queries         int     `json:"queries"`
+	QueryDurationMS float64 `json:"query_duration_ms"`
+	// DataAsOf is when the data was current: the report's refresh time for
+	// precomputed data, and for live reads from the follower, the time less
+	// the replication lag
+	DataAsOf *time.Time `json:"data_as_of"`
+}
+
+// WithMeta wraps a handler so that, while the response_meta feature flag is
+// on, successful JSON responses carry a meta block (see ResponseMeta). Objects
+// get a "meta" field; arrays are wrapped as {"data": [...], "meta": {...}}, as
+// are objects for clients that asked for an envelope.
+// Wrap the handler before AsyncAfter so deferred responses get it too.
+func WithMeta(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.FeatureEnabled(ResponseMetaFlag) {
+			handler(c)
+			return
+		}
+
+		ctx, stats := db.WithQueryStats(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		writer := c.Writer
+		response := newBufferedResponse()
+		c.Writer = response
+		handler(c)
+		c.Writer = writer
+
+		if response.Status() == http.StatusOK {
+			body := response.body.Bytes()
+			// Clients that asked for an envelope get the meta block beside the
+			// data rather than in it (see ResponseStyle)
+			envelope := responseStyle(c).Envelope && json.Valid(body)
+			if envelope {
+				body = append(append([]byte(`{"data":`), bytes.TrimSpace(body)...), '}')
+			}
+			if body, ok := addMeta(body, buildMeta(c, stats)); ok {
+				response.body.Reset()
+				response.body.Write(body)
+				c.Set(envelopedKey, envelope)
+			}
+		}
+		response.replay(c)
+	}
+}
+
+// SetDataAsOf marks the response as precomputed data computed at asOf, for
+// handlers that serve reports refreshed in the background
+func SetDataAsOf(c *gin.Context, asOf *time.Time) {
+	if asOf != nil {
+		c.Set(dataAsOfKey, *asOf)
+	}
+}
+
+// buildMeta describes the queries recorded in stats
+func buildMeta(c *gin.Context, stats *db.QueryStats) ResponseMeta {
+	meta := ResponseMeta{
+		ServedBy:        stats.ServedBy(),
+		Cache:           "live",
+		Queries:         stats.Queries(),
+		QueryDurationMS: float64(stats.Duration().Microseconds()) / 1000,
+	}
+	if asOf, ok := c.Get(dataAsOfKey); ok {
+		t := asOf.(time.Time)
+		meta.Cache = "precomputed"
+		meta.DataAsOf = &t
+		return meta
+	}
+
+	switch meta.ServedBy {
+	case "primary":
+		now := time.Now().UTC()
+		meta.DataAsOf = &now
+	case "follower", "mixed":
+		// Reads from the follower are only as fresh as its replay
+		if asOf, err := db.FollowerDataAsOf(c.Request.Context()); err == nil {
+			asOf = asOf.UTC()
+			meta.DataAsOf = &asOf
+		}
+	}
+	return meta
+}
+
+// addMeta adds meta to a JSON object or array body. It reports false for
+// bodies that are neither, which are left alone.
+func addMeta(body []byte, meta ResponseMeta) ([]byte, bool) {
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		return nil, false
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) < 2 || !json.Valid(body) {
+		return nil, false
+	}
+
+	var out bytes.Buffer
+	switch body[0] {
+	case '{':
+		// Append the field so the rest of the object keeps its order
+		out.Write(body[:len(body)-1])
+		if len(bytes.TrimSpace(body[1:len(body)-1])) > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(`"meta":`)
+		out.Write(encoded)
+		out.WriteByte('}')
+	case '[':
+		out.WriteString(`{"data":`)
+		out.Write(body)
+		out.WriteString(`,"meta":`)
+		out.Write(encoded)
+		out.WriteByte('}')
+	default:
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+var requestQueries = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "http_request_db_queries",
+	Help:    "Database statements run per request, by route.",
+	Buckets: []float64{0, 1, 2, 5, 10, 20, 50, 100},
+}, []string{"route"})
+
+// DBStats counts the statements each request runs, on either pool, and the
+// time spent running them, in http_request_db_queries. While the
+// db_stats_headers feature flag is on, responses also carry them as
+// X-DB-Queries and X-DB-Duration-ms, which makes N+1 queries and the effect
+// of routing visible from curl. Headers count the statements run before the
+// response started; streamed responses may run more afterwards.
+func DBStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, stats := db.WithQueryStats(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		if config.FeatureEnabled(DBStatsFlag) {
+			c.Writer = &dbStatsWriter{ResponseWriter: c.Writer, stats: stats}
+		}
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestQueries.WithLabelValues(route).Observe(float64(stats.Queries()))
+	}
+}
+
+// dbStatsWriter adds the X-DB-* headers just before the response headers are
+// sent
+type dbStatsWriter struct {
+	gin.ResponseWriter
+	stats *db.QueryStats
+}
+
+func (w *dbStatsWriter) setHeaders() {
+	if w.Written() {
+		return
+	}
+	w.Header().Set("X-DB-Queries", strconv.Itoa(w.stats.Queries()))
+	w.Header().Set("X-DB-Duration-ms", strconv.FormatFloat(float64(w.stats.Duration().Microseconds())/1000, 'f', 3, 64))
+}
+
+func (w *dbStatsWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *dbStatsWriter) Write(data []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *dbStatsWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *dbStatsWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *dbStatsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
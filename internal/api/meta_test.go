@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAddMeta(t *testing.T) {
+	meta := ResponseMeta{ServedBy: "follower", Cache: "live", Queries: 2}
+	encoded, _ := json.Marshal(meta)
+	tests := []struct {
+		body string
+		want string
+		ok   bool
+	}{
+		{`{"total":5}`, `{"total":5,"meta":` + string(encoded) + `}`, true},
+		{`{}`, `{"meta":` + string(encoded) + `}`, true},
+		{`[{"id":1}]`, `{"data":[{"id":1}],"meta":` + string(encoded) + `}`, true},
+		{`"text"`, "", false},
+		{`{"broken"`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := addMeta([]byte(tt.body), meta)
+		if ok != tt.ok || string(got) != tt.want {
+			t.Errorf("addMeta(%s) = %s, %v; want %s, %v", tt.body, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithMeta(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+
+	refreshedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.GET("/report", WithMeta(func(c *gin.Context) {
+		SetDataAsOf(c, &refreshedAt)
+		c.JSON(http.StatusOK, []gin.H{{"id": 1}})
+	}))
+	router.GET("/missing", WithMeta(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	}))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	settings := previous
+	settings.FeatureFlags = map[string]bool{}
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	if body := get("/report").Body.String(); body != `[{"id":1}]` {
+		t.Errorf("Expected the response unchanged with the flag off, got %s", body)
+	}
+
+	settings.FeatureFlags = map[string]bool{ResponseMetaFlag: true}
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	var response struct {
+		Data []map[string]int `json:"data"`
+		Meta ResponseMeta     `json:"meta"`
+	}
+	if err := json.Unmarshal(get("/report").Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Meta.Cache != "precomputed" || response.Meta.DataAsOf == nil || !response.Meta.DataAsOf.Equal(refreshedAt) {
+		t.Errorf("Expected the rows with precomputed meta, got %+v", response)
+	}
+
+	if w := get("/missing"); w.Code != http.StatusNotFound || w.Body.String() != `{"error":"Not found"}` {
+		t.Errorf("Expected errors to pass through without meta, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDBStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+
+	router := gin.New()
+	router.Use(DBStats())
+	router.GET("/customers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{})
+	})
+	router.GET("/report", WithMeta(func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	}))
+	router.GET("/stream", func(c *gin.Context) {
+		c.Writer.Flush()
+		c.String(http.StatusOK, "data: {}\n\n")
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	settings := previous
+	settings.FeatureFlags = map[string]bool{}
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	if w := get("/customers"); w.Header().Get("X-DB-Queries") != "" || w.Header().Get("X-DB-Duration-ms") != "" {
+		t.Errorf("Expected no headers with the flag off, got %v", w.Header())
+	}
+
+	settings.FeatureFlags = map[string]bool{DBStatsFlag: true, ResponseMetaFlag: true}
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	for _, path := range []string{"/customers", "/report", "/stream"} {
+		w := get(path)
+		if w.Code != http.StatusOK || w.Header().Get("X-DB-Queries") != "0" || w.Header().Get("X-DB-Duration-ms") != "0.000" {
+			t.Errorf("Expected %s to report no queries, got %d %v", path, w.Code, w.Header())
+		}
+	}
+}
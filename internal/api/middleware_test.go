@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoadShedRejectsWhenSaturated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	router := gin.New()
+	router.Use(LoadShed(LoadShedOptions{MaxInFlight: 1, MaxQueue: 0, MaxWait: 10 * time.Millisecond}))
+	router.GET("/api/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/health/db", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/auth/api-keys", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d while saturated, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header on shed requests")
+	}
+
+	// Health checks are never shed
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected health check to bypass shedding, got %d", w.Code)
+	}
+
+	// Only the exact priority paths are, not the routes under them
+	for _, path := range []string{"/health/db", "/api/auth/api-keys"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected %s to be shed while saturated, got %d", path, w.Code)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+}
+
+func TestRequireConsentSkipsWithoutDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("CONSENT_POLICIES", "terms=2")
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("username", "consent-test") }, RequireConsent())
+	router.GET("/api/consents", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/customers", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// The consent endpoints stay reachable so pending policies can be accepted
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/consents", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected consent endpoints to be exempt, got %d", w.Code)
+	}
+
+	// Accepted versions are cached, so no query is needed
+	acceptedConsents.Store("consent-test\x00terms\x002", true)
+	defer acceptedConsents.Delete("consent-test\x00terms\x002")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/customers", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected accepted caller to pass, got %d", w.Code)
+	}
+}
+
+func TestReadFromPrimary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(DBRoute())
+	pinned := func(c *gin.Context) {
+		c.String(http.StatusOK, "%t", db.PinnedToPrimary(c.Request.Context()))
+	}
+	router.GET("/routed", pinned)
+	router.GET("/primary", ReadFromPrimary(), pinned)
+
+	for _, tt := range []struct {
+		path, header, expected string
+	}{
+		{"/routed", "", "false"},
+		{"/routed", "primary", "true"},
+		{"/primary", "", "true"},
+		{"/primary", "follower", "true"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("X-DB-Route", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Body.String() != tt.expected {
+			t.Errorf("Expected %s with X-DB-Route %q to be pinned=%s, got %s", tt.path, tt.header, tt.expected, w.Body.String())
+		}
+	}
+}
+
+func TestRequireCustomerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	past := time.Now().UTC().Add(-time.Hour)
+	recent := time.Now().UTC().Add(-time.Second)
+	tokens := map[string]models.CustomerToken{
+		"sgc_valid":   {ID: 1, CustomerID: 7, Scopes: []string{auth.ScopeAccountsRead}},
+		"sgc_recent":  {ID: 2, CustomerID: 7, Scopes: []string{auth.ScopeAccountsRead}, LastUsedAt: &recent},
+		"sgc_revoked": {ID: 3, CustomerID: 7, RevokedAt: &past},
+		"sgc_expired": {ID: 4, CustomerID: 7, ExpiresAt: &past},
+	}
+	previousFind, previousTouch := findCustomerToken, touchCustomerToken
+	t.Cleanup(func() { findCustomerToken, touchCustomerToken = previousFind, previousTouch })
+	findCustomerToken = func(ctx context.Context, hash string) (models.CustomerToken, error) {
+		for secret, token := range tokens {
+			if auth.HashCustomerToken(secret) == hash {
+				return token, nil
+			}
+		}
+		return models.CustomerToken{}, sql.ErrNoRows
+	}
+	var touched []int
+	touchCustomerToken = func(ctx context.Context, id int) error {
+		touched = append(touched, id)
+		return nil
+	}
+
+	router := gin.New()
+	router.Use(RequireCustomerToken())
+	router.GET("/api/my/accounts", RequireTokenScope(auth.ScopeAccountsRead), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("username"))
+	})
+	router.GET("/api/my/usage", RequireTokenScope(auth.ScopeUsageRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path, header string
+		want         int
+	}{
+		{"/api/my/accounts", "", http.StatusUnauthorized},
+		{"/api/my/accounts", "Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig", http.StatusUnauthorized},
+		{"/api/my/accounts", "Bearer sgc_unknown", http.StatusUnauthorized},
+		{"/api/my/accounts", "Bearer sgc_revoked", http.StatusUnauthorized},
+		{"/api/my/accounts", "Bearer sgc_expired", http.StatusUnauthorized},
+		{"/api/my/usage", "Bearer sgc_valid", http.StatusForbidden},
+		{"/api/my/accounts", "Bearer sgc_recent", http.StatusOK},
+		{"/api/my/accounts", "Bearer sgc_valid", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s with %q, got %d: %s", tt.want, tt.path, tt.header, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusOK && w.Body.String() != "customer:7" {
+			t.Errorf("Expected calls attributed to customer:7, got %s", w.Body.String())
+		}
+	}
+
+	// sgc_recent was used within customerTokenTouchInterval, so it isn't written again
+	if len(touched) != 2 || touched[0] != 1 || touched[1] != 1 {
+		t.Errorf("Expected only sgc_valid's last use to be recorded, got %v", touched)
+	}
+}
+
+func TestMaintenanceModeRejectsWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+	t.Setenv("MAINTENANCE_RETRY_AFTER", "2m")
+
+	router := gin.New()
+	router.Use(MaintenanceMode())
+	var pinned bool
+	ok := func(c *gin.Context) {
+		pinned = db.PinnedToPrimary(c.Request.Context())
+		c.Status(http.StatusOK)
+	}
+	router.GET("/api/customers", ok)
+	router.GET("/api/accounts/by-reference/:reference", ReadFromPrimary(), ok)
+	router.POST("/api/customers", ok)
+	router.POST("/api/auth/login", ok)
+	router.POST("/api/auth/refresh", ok)
+	router.PUT("/api/admin/config", ok)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/api/customers"); w.Code != http.StatusOK {
+		t.Errorf("Expected writes outside maintenance mode, got %d", w.Code)
+	}
+
+	settings := config.Current()
+	settings.MaintenanceMode = config.ReadOnlyMaintenance
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	w := serve(http.MethodPost, "/api/customers")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected status %d with Retry-After 120, got %d and %q", http.StatusServiceUnavailable, w.Code, w.Header().Get("Retry-After"))
+	}
+	for _, request := range [][2]string{{http.MethodGet, "/api/customers"}, {http.MethodPost, "/api/auth/login"}, {http.MethodPost, "/api/auth/refresh"}, {http.MethodPut, "/api/admin/config"}} {
+		if w := serve(request[0], request[1]); w.Code != http.StatusOK || w.Header().Get("X-Maintenance-Mode") != "readonly" {
+			t.Errorf("Expected %s %s to be allowed in maintenance mode, got %d", request[0], request[1], w.Code)
+		}
+	}
+	if serve(http.MethodGet, "/api/accounts/by-reference/ACC-1"); pinned {
+		t.Error("Expected reads to stay on the follower in maintenance mode")
+	}
+}
+
+func TestTrustProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		trusted    string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		// The Heroku router connects from a private address and appends the
+		// address it saw, so a forged first hop is ignored
+		{"", "10.1.2.3:5000", "1.2.3.4, 203.0.113.9", "203.0.113.9"},
+		{"", "10.1.2.3:5000", "", "10.1.2.3"},
+		// A client connecting directly can't pick its own address
+		{"", "203.0.113.9:5000", "1.2.3.4", "203.0.113.9"},
+		{"none", "10.1.2.3:5000", "203.0.113.9", "10.1.2.3"},
+		{"192.0.2.0/24", "192.0.2.1:5000", "1.2.3.4, 203.0.113.9", "203.0.113.9"},
+		{"192.0.2.0/24", "10.1.2.3:5000", "203.0.113.9", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Setenv("TRUSTED_PROXIES", tt.trusted)
+		router := gin.New()
+		if err := TrustProxies(router); err != nil {
+			t.Fatalf("Expected TRUSTED_PROXIES=%q to be accepted, got %v", tt.trusted, err)
+		}
+		router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Body.String() != tt.want {
+			t.Errorf("Expected client IP %s from %s forwarding %q, got %s", tt.want, tt.remoteAddr, tt.forwarded, w.Body.String())
+		}
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "not-a-cidr")
+	if err := TrustProxies(gin.New()); err == nil {
+		t.Error("Expected an invalid TRUSTED_PROXIES to be rejected")
+	}
+}
+
+func TestScopeToOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := userTenancy
+	t.Cleanup(func() { userTenancy = previous })
+	userTenancy = func(ctx context.Context, username string) (int, bool, error) {
+		switch username {
+		case "owner":
+			return 7, true, nil
+		case "removed":
+			return 0, true, nil
+		}
+		return 0, false, nil
+	}
+
+	var scope int
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("username", c.GetHeader("X-User")) })
+	router.GET("/api/customers", ScopeToOrganization(), func(c *gin.Context) {
+		scope = repository.OrganizationFromContext(c.Request.Context())
+	})
+	router.GET("/api/analytics", RequireStaff(), func(c *gin.Context) {})
+	request := func(path, username string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User", username)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if request("/api/customers", "owner"); scope != 7 {
+		t.Errorf("Expected an organization member to be scoped to it, got %d", scope)
+	}
+	if request("/api/customers", "staff"); scope != 0 {
+		t.Errorf("Expected staff to be unscoped, got %d", scope)
+	}
+	if code := request("/api/customers", "removed"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a tenant who left their organization, got %d", http.StatusForbidden, code)
+	}
+	if code := request("/api/analytics", "owner"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d for an organization member, got %d", http.StatusForbidden, code)
+	}
+	if code := request("/api/analytics", "staff"); code != http.StatusOK {
+		t.Errorf("Expected status %d for staff, got %d", http.StatusOK, code)
+	}
+}
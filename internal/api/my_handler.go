@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyAccounts lists the accounts a customer token can see
+// @Summary      List my accounts
+// @Description  Get the token's customer's accounts newest first, or only its account for account-scoped tokens. Requires a customer API token with the accounts:read scope. With limit, pages are returned with an opaque X-Next-Cursor header to pass back as cursor for the next page; the header is absent on the last page.
+// @Tags         my
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status"
+// @Param        limit   query     int     false  "Page size (1-1000, default: all accounts)"
+// @Param        cursor  query     string  false  "X-Next-Cursor from the previous page"
+// @Param        sort    query     string  false  "Order by creation, newest first by default"  Enums(-created_at, created_at)
+// @Success      200     {array}   models.Account
+// @Header       200     {string}  X-Next-Cursor  "Cursor for the next page, if any"
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /my/accounts [get]
+// @Security     BearerAuth
+func GetMyAccounts(c *gin.Context) {
+	token := customerToken(c)
+	ctx := c.Request.Context()
+
+	if token.AccountID != nil {
+		account, err := accountRepo.Get(ctx, *token.AccountID, nil)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+			return
+		}
+		accounts := []models.Account{}
+		status := c.Query("status")
+		if err == nil && account.CustomerID == token.CustomerID && (status == "" || account.Status == status) {
+			accounts = append(accounts, account)
+		}
+		c.JSON(http.StatusOK, accounts)
+		return
+	}
+
+	p, ok := parsePage(c, "my-accounts")
+	if !ok {
+		return
+	}
+	opts := p.options(nil)
+	opts.Filter = map[string]interface{}{"customer_id": token.CustomerID}
+	if status := c.Query("status"); status != "" {
+		opts.Filter["status"] = status
+	}
+	accounts, err := accountRepo.List(ctx, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+	if p.more(len(accounts)) {
+		accounts = accounts[:p.Limit]
+		last := accounts[p.Limit-1]
+		p.next(c, last.CreatedAt, last.ID)
+	}
+	if accounts == nil {
+		accounts = []models.Account{}
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// GetMyUsage returns the API usage of a customer's tokens
+// @Summary      Get my API usage
+// @Description  Get API calls, errors, and latency per endpoint made with the customer's tokens. Requires a customer API token with the usage:read scope. Usage is flushed in batches, so the last few seconds may be missing.
+// @Tags         my
+// @Accept       json
+// @Produce      json
+// @Param        hours  query     int  false  "Window in hours (1-720, default 24)"
+// @Success      200    {object}  APIUsageResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /my/usage [get]
+// @Security     BearerAuth
+func GetMyUsage(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 720 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hours"})
+		return
+	}
+
+	username := CustomerPrincipal(customerToken(c).CustomerID)
+	since := time.Now().UTC().Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour)
+	endpoints, err := apiUsageEndpoints(db.Analytics(c.Request.Context()), username, 0, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIUsageResponse{Username: username, Since: since, Endpoints: endpoints})
+}
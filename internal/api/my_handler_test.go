@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetMyAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeAccounts(t, &fakeAccounts{accounts: []models.Account{
+		{ID: 1, CustomerID: 7, Status: "active"},
+		{ID: 2, CustomerID: 8, Status: "active"},
+		{ID: 3, CustomerID: 7, Status: "inactive"},
+	}})
+
+	accountID := func(id int) *int { return &id }
+	get := func(token models.CustomerToken, query string) []models.Account {
+		t.Helper()
+		router := gin.New()
+		router.GET("/api/my/accounts", func(c *gin.Context) {
+			c.Set("customer_token", token)
+			c.Set("username", CustomerPrincipal(token.CustomerID))
+		}, GetMyAccounts)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/my/accounts"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var accounts []models.Account
+		if err := json.Unmarshal(w.Body.Bytes(), &accounts); err != nil {
+			t.Fatalf("Failed to decode accounts: %v", err)
+		}
+		return accounts
+	}
+
+	if accounts := get(models.CustomerToken{CustomerID: 7}, ""); len(accounts) != 2 || accounts[0].ID != 1 || accounts[1].ID != 3 {
+		t.Errorf("Expected only customer 7's accounts, got %+v", accounts)
+	}
+	if accounts := get(models.CustomerToken{CustomerID: 7}, "?status=inactive"); len(accounts) != 1 || accounts[0].ID != 3 {
+		t.Errorf("Expected customer 7's inactive account, got %+v", accounts)
+	}
+	if accounts := get(models.CustomerToken{CustomerID: 7, AccountID: accountID(3)}, ""); len(accounts) != 1 || accounts[0].ID != 3 {
+		t.Errorf("Expected only the token's account, got %+v", accounts)
+	}
+	// An account moved to another customer is no longer visible to the old one's tokens
+	if accounts := get(models.CustomerToken{CustomerID: 7, AccountID: accountID(2)}, ""); len(accounts) != 0 {
+		t.Errorf("Expected no accounts, got %+v", accounts)
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mentionPattern matches @username mentions that are not part of an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// ParseMentions extracts the unique usernames mentioned in a note body
+func ParseMentions(body string) []string {
+	mentions := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		mentions = append(mentions, username)
+	}
+	return mentions
+}
+
+// CreateAccountNote adds a note to an account
+// @Summary      Create account note
+// @Description  Add a note to an account. @username mentions notify the mentioned users.
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                    true  "Account ID or UUID"
+// @Param        note  body      models.CreateNoteRequest  true  "Note data"
+// @Success      201   {object}  models.Note
+// @Failure      400   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Router       /accounts/{id}/notes [post]
+// @Security     BearerAuth
+func CreateAccountNote(c *gin.Context) {
+	accountID, ok := parseAccountID(c, "id")
+	if !ok || !requireInOrganizationScope(c, "account", accountID, "Account not found") {
+		return
+	}
+
+	var req models.CreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := db.Primary(c.Request.Context()).QueryRow("SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND deleted_at IS NULL)", accountID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	author := c.GetString("username")
+	mentions := ParseMentions(req.Body)
+
+	note := models.Note{Mentions: mentions}
+	err := db.Primary(c.Request.Context()).QueryRow(
+		"INSERT INTO account_notes (account_id, author, body, mentions) VALUES ($1, $2, $3, $4) RETURNING id, account_id, author, body, created_at",
+		accountID, author, req.Body, mentions,
+	).Scan(&note.ID, &note.AccountID, &note.Author, &note.Body, &note.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
+
+	if len(mentions) > 0 {
+		message := fmt.Sprintf("%s mentioned you in a note on account %d", author, accountID)
+		if err := db.CreateNotifications(c.Request.Context(), "mention", message, mentions...); err != nil {
+			tracing.Printf(c.Request.Context(), "Warning: Failed to notify mentioned users for note %d: %v", note.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// GetAccountNotes retrieves all notes for an account
+// @Summary      List account notes
+// @Description  Get all notes attached to an account, newest first
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Account ID or UUID"
+// @Success      200  {array}   models.Note
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /accounts/{id}/notes [get]
+// @Security     BearerAuth
+func GetAccountNotes(c *gin.Context) {
+	accountID, ok := parseAccountID(c, "id")
+	if !ok || !requireInOrganizationScope(c, "account", accountID, "Account not found") {
+		return
+	}
+
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT id, account_id, author, body, mentions, created_at FROM account_notes WHERE account_id = $1 ORDER BY created_at DESC",
+		accountID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
+		return
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.AccountID, &note.Author, &note.Body, db.Array(&note.Mentions), &note.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan note"})
+			return
+		}
+		notes = append(notes, note)
+	}
+
+	c.JSON(http.StatusOK, notes)
+}
+
+// SearchNotes performs a full-text search over account notes on the follower pool
+// @Summary      Search notes
+// @Description  Full-text search across all account notes, ranked by relevance
+// @Tags         notes
+// @Accept       json
+// @Produce      json
+// @Param        q      query     string  true   "Search query (web search syntax)"
+// @Param        limit  query     int     false  "Maximum number of results (default 20, max 100)"
+// @Success      200    {array}   models.NoteSearchResult
+// @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /search/notes [get]
+// @Security     BearerAuth
+func SearchNotes(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Text search is read-only, so it runs against the follower pool
+	analyticsDB := db.Analytics(c.Request.Context())
+
+	rows, err := analyticsDB.Query(`
+		SELECT id, account_id, author, body, mentions, created_at,
+			ts_rank(search_vector, query) AS rank,
+			ts_headline('english', body, query) AS headline
+		FROM account_notes, websearch_to_tsquery('english', $1) AS query
+		WHERE search_vector @@ query
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $2`,
+		query, limit,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+		return
+	}
+	defer rows.Close()
+
+	results := []models.NoteSearchResult{}
+	for rows.Next() {
+		var result models.NoteSearchResult
+		if err := rows.Scan(&result.ID, &result.AccountID, &result.Author, &result.Body, db.Array(&result.Mentions), &result.CreatedAt, &result.Rank, &result.Headline); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan search result"})
+			return
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
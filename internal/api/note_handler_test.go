@@ -0,0 +1,27 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected []string
+	}{
+		{"no mentions here", []string{}},
+		{"@alice please review", []string{"alice"}},
+		{"cc @alice and @bob.", []string{"alice", "bob"}},
+		{"@alice @alice duplicate", []string{"alice"}},
+		{"email contact@acme.com is not a mention", []string{}},
+		{"(@carol) follow up", []string{"carol"}},
+	}
+
+	for _, tt := range tests {
+		got := ParseMentions(tt.body)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseMentions(%q) = %v, expected %v", tt.body, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications retrieves the notifications for the authenticated user
+// @Summary      List notifications
+// @Description  Get the most recent notifications for the authenticated user
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.Notification
+// @Failure      500  {object}  map[string]string
+// @Router       /notifications [get]
+// @Security     BearerAuth
+func GetNotifications(c *gin.Context) {
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT id, username, kind, message, created_at FROM notifications WHERE username = $1 ORDER BY created_at DESC LIMIT 100",
+		c.GetString("username"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := rows.Scan(&notification.ID, &notification.Username, &notification.Kind, &notification.Message, &notification.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan notification"})
+			return
+		}
+		notifications = append(notifications, notification)
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
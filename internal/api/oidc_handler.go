@@ -0,0 +1,394 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/cursor"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/oidc"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDC login signs users in with an OpenID Connect provider instead of a
+// local password. GET /auth/oidc/login sends the browser to the provider,
+// which sends it back to GET /auth/oidc/callback. The state, nonce, and PKCE
+// verifier of the login travel in a short-lived sealed cookie, so no server
+// side session is needed. A user is provisioned on their first login and
+// found by their identity (the provider's issuer and subject) after that.
+
+const (
+	// oidcCookie carries the pending login between the two endpoints
+	oidcCookie = "oidc_login"
+	// oidcCookiePath scopes the cookie to the OIDC endpoints
+	oidcCookiePath = "/api/auth/oidc"
+	// oidcLoginTTL is how long a user has to sign in at the provider
+	oidcLoginTTL = 10 * time.Minute
+)
+
+var errOIDCDomainNotAllowed = errors.New("Your email domain is not allowed to sign in")
+
+// oidcLogin is a pending login, sealed into oidcCookie
+type oidcLogin struct {
+	State       string `json:"state"`
+	Nonce       string `json:"nonce"`
+	Verifier    string `json:"verifier"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// oidcIdentity is who the provider says signed in
+type oidcIdentity struct {
+	Issuer  string
+	Subject string
+	// Email is empty unless the provider verified it
+	Email string
+	// Username is the username to provision the user with, if they are new
+	Username string
+	// Role is the role the claims map to, or "" to leave it as it is
+	Role string
+}
+
+// loadOIDCProvider returns the configured provider; tests replace it
+var loadOIDCProvider = oidc.Default
+
+// provisionOIDCUser returns the username of the user with identity, creating
+// the user if this is their first login, and syncing their email and mapped
+// role; tests replace it
+var provisionOIDCUser = func(ctx context.Context, identity oidcIdentity) (username string, created bool, err error) {
+	err = db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRowContext(ctx, `
+			SELECT u.id, u.username FROM user_identities i JOIN users u ON u.id = i.user_id
+			WHERE i.issuer = $1 AND i.subject = $2 FOR UPDATE OF i`,
+			identity.Issuer, identity.Subject,
+		).Scan(&userID, &username)
+		switch {
+		case err == sql.ErrNoRows:
+			userID, username, err = createOIDCUser(ctx, tx, identity)
+			if err != nil {
+				return err
+			}
+			created = true
+		case err != nil:
+			return err
+		default:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE user_identities SET email = $3, last_login_at = CURRENT_TIMESTAMP WHERE issuer = $1 AND subject = $2",
+				identity.Issuer, identity.Subject, optionalString(identity.Email),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		if identity.Role != "" {
+			// Only admin is synced: staff granted by hand are promoted and
+			// admins demoted to tenants, but tenants are never promoted
+			from := models.RoleAdmin
+			if identity.Role == models.RoleAdmin {
+				from = models.RoleStaff
+			}
+			_, err = tx.ExecContext(ctx, "UPDATE users SET role = $2 WHERE id = $1 AND role = $3", userID, identity.Role, from)
+		}
+		return err
+	})
+	return username, created, err
+}
+
+// createOIDCUser inserts a user for identity, suffixing the username if it
+// is taken. OIDC users get a random password nobody knows, so they can only
+// sign in through the provider.
+func createOIDCUser(ctx context.Context, tx *sql.Tx, identity oidcIdentity) (int, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return 0, "", err
+	}
+	passwordHash, err := auth.HashPassword(hex.EncodeToString(random))
+	if err != nil {
+		return 0, "", err
+	}
+	role := identity.Role
+	if role == "" {
+		role = models.RoleTenant
+	}
+
+	var userID int
+	username := identity.Username
+	for attempt := 1; ; attempt++ {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (username) DO NOTHING RETURNING id`,
+			username, passwordHash, optionalString(identity.Email), role,
+		).Scan(&userID)
+		if err == nil {
+			break
+		}
+		if err != sql.ErrNoRows {
+			return 0, "", err
+		}
+		if attempt >= 10 {
+			return 0, "", fmt.Errorf("no free username for %s", identity.Username)
+		}
+		username = identity.Username + "-" + strconv.Itoa(attempt+1)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4)",
+		identity.Issuer, identity.Subject, userID, optionalString(identity.Email),
+	)
+	return userID, username, err
+}
+
+// OIDCLogin starts a login with the OpenID Connect provider
+// @Summary      Start OIDC login
+// @Description  Redirect the browser to the OpenID Connect provider configured with OIDC_ISSUER, OIDC_CLIENT_ID, and OIDC_CLIENT_SECRET to sign in. The provider sends it back to GET /auth/oidc/callback, which must be registered with the provider as a redirect URI (OIDC_REDIRECT_URL overrides it). The login must complete within 10 minutes, in the same browser. Returns 503 when OIDC is not configured.
+// @Tags         auth
+// @Success      302
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/oidc/login [get]
+func OIDCLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	provider, err := loadOIDCProvider(ctx)
+	if errors.Is(err, oidc.ErrNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		tracing.Printf(ctx, "Error loading OIDC provider: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OIDC provider unavailable"})
+		return
+	}
+
+	login := oidcLogin{RedirectURL: provider.Config().RedirectURL}
+	if login.RedirectURL == "" {
+		login.RedirectURL = requestBaseURL(c) + oidcCookiePath + "/callback"
+	}
+	var challenge string
+	if login.State, err = oidc.RandomString(); err == nil {
+		if login.Nonce, err = oidc.RandomString(); err == nil {
+			login.Verifier, challenge, err = oidc.NewPKCE()
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	sealed, err := cursor.Encode("oidc-login", login, oidcLoginTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	setOIDCCookie(c, sealed, int(oidcLoginTTL.Seconds()))
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(login.RedirectURL, login.State, login.Nonce, challenge))
+}
+
+// OIDCCallback completes a login with the OpenID Connect provider
+// @Summary      Complete OIDC login
+// @Description  The provider redirects here after the user signs in. The authorization code is exchanged for an ID token, whose signature, issuer, audience, expiry, and nonce are verified. The user is found by the provider's issuer and subject, or provisioned on their first login with a username from the preferred_username claim, the email claim's local part, or the subject, suffixed with -2, -3, ... if taken; existing local users are never linked by email. OIDC_ALLOWED_DOMAINS, if set, limits logins to users with a verified email at one of those domains. Roles are mapped from the claim named by OIDC_ROLE_CLAIM (default groups, dotted for nested claims such as realm_access.roles): if OIDC_ADMIN_VALUES is set, users with one of its values get the admin role and everyone else the user role, on every login. With OIDC_POST_LOGIN_REDIRECT set the browser is redirected there with token, refresh_token, and expires_in in the URL fragment; otherwise the tokens are returned as JSON. Local two-factor authentication does not apply; the provider is responsible for it.
+// @Tags         auth
+// @Produce      json
+// @Param        code   query     string  false  "Authorization code"
+// @Param        state  query     string  true   "State from GET /auth/oidc/login"
+// @Param        error  query     string  false  "Error reported by the provider"
+// @Success      200    {object}  LoginResponse
+// @Success      302
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Failure      503    {object}  map[string]string
+// @Router       /auth/oidc/callback [get]
+func OIDCCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+	sealed, _ := c.Cookie(oidcCookie)
+	// The login is single use, whatever happens next
+	setOIDCCookie(c, "", -1)
+
+	var login oidcLogin
+	if sealed == "" || cursor.Decode("oidc-login", sealed, &login) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired or was started in another browser, start again at GET /auth/oidc/login"})
+		return
+	}
+	if c.Query("state") != login.State {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state"})
+		return
+	}
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login failed at the provider: " + providerError, "description": c.Query("error_description")})
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing code"})
+		return
+	}
+
+	provider, err := loadOIDCProvider(ctx)
+	if err != nil {
+		tracing.Printf(ctx, "Error loading OIDC provider: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OIDC provider unavailable"})
+		return
+	}
+	rawIDToken, err := provider.Exchange(ctx, code, login.Verifier, login.RedirectURL)
+	if err != nil {
+		tracing.Printf(ctx, "Error exchanging OIDC code: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to complete login with the provider"})
+		return
+	}
+	claims, err := provider.Verify(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		tracing.Printf(ctx, "Rejected OIDC ID token: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+
+	identity, err := identityFromClaims(provider.Config().Issuer, claims)
+	if errors.Is(err, errOIDCDomainNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	username, created, err := provisionOIDCUser(ctx, identity)
+	if err != nil {
+		tracing.Printf(ctx, "Error provisioning OIDC user %s: %v", identity.Subject, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if created {
+		events.Publish(ctx, events.UserRegistered, gin.H{
+			"username":   username,
+			"ip_address": c.ClientIP(),
+			"issuer":     identity.Issuer,
+		})
+	}
+
+	client := newLoginClient(c)
+	noticeNewLogin(ctx, username, client)
+	recordLoginAttempt(ctx, username, true, client)
+	tokens, err := issueTokens(ctx, username, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	if target := os.Getenv("OIDC_POST_LOGIN_REDIRECT"); target != "" {
+		// In the fragment, so the tokens never reach a server's logs
+		fragment := url.Values{}
+		fragment.Set("token", tokens.Token)
+		fragment.Set("refresh_token", tokens.RefreshToken)
+		fragment.Set("expires_in", strconv.Itoa(tokens.ExpiresIn))
+		c.Redirect(http.StatusFound, target+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// identityFromClaims maps a verified ID token's claims to an identity,
+// enforcing OIDC_ALLOWED_DOMAINS and mapping OIDC_ROLE_CLAIM to a role
+func identityFromClaims(issuer string, claims oidc.Claims) (oidcIdentity, error) {
+	identity := oidcIdentity{Issuer: issuer, Subject: claims.String("sub")}
+	if identity.Subject == "" {
+		return identity, errors.New("ID token has no subject")
+	}
+	if claims.EmailVerified() {
+		identity.Email = strings.ToLower(claims.String("email"))
+	}
+
+	if domains := listEnvValues("OIDC_ALLOWED_DOMAINS"); len(domains) > 0 {
+		_, domain, _ := strings.Cut(identity.Email, "@")
+		if !domains[domain] {
+			return identity, errOIDCDomainNotAllowed
+		}
+	}
+
+	identity.Username = oidcUsername(claims)
+	if admins := listEnvValues("OIDC_ADMIN_VALUES"); len(admins) > 0 {
+		claim := os.Getenv("OIDC_ROLE_CLAIM")
+		if claim == "" {
+			claim = "groups"
+		}
+		identity.Role = models.RoleTenant
+		for _, value := range claims.Strings(claim) {
+			if admins[strings.ToLower(value)] {
+				identity.Role = models.RoleAdmin
+				break
+			}
+		}
+	}
+	return identity, nil
+}
+
+// usernameUnsafe matches characters left out of provisioned usernames
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// oidcUsername picks a username for a new user from preferred_username, the
+// local part of email, or the subject
+func oidcUsername(claims oidc.Claims) string {
+	candidates := []string{claims.String("preferred_username")}
+	if local, _, ok := strings.Cut(claims.String("email"), "@"); ok {
+		candidates = append(candidates, local)
+	}
+	for _, candidate := range candidates {
+		// preferred_username is often an email too
+		candidate, _, _ = strings.Cut(candidate, "@")
+		candidate = strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(candidate), "-"), "-.")
+		if candidate != "" {
+			if len(candidate) > 64 {
+				candidate = candidate[:64]
+			}
+			return candidate
+		}
+	}
+	subject := usernameUnsafe.ReplaceAllString(strings.ToLower(claims.String("sub")), "-")
+	if len(subject) > 64 {
+		subject = subject[:64]
+	}
+	return "oidc-" + subject
+}
+
+// listEnvValues reads a comma-separated, case-insensitive list from the
+// environment
+func listEnvValues(name string) map[string]bool {
+	values := map[string]bool{}
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			values[value] = true
+		}
+	}
+	return values
+}
+
+// setOIDCCookie sets the pending login cookie, or deletes it with maxAge -1
+func setOIDCCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, value, maxAge, oidcCookiePath, "", strings.HasPrefix(requestBaseURL(c), "https:"), true)
+}
+
+// requestBaseURL is the scheme and host the client reached the app at,
+// honoring X-Forwarded-Proto from the Heroku router
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
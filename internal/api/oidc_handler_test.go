@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/oidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newOIDCDiscoveryServer serves a discovery document for itself
+func newOIDCDiscoveryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOIDCLoginNotConfigured(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("OIDC_ISSUER", "")
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", OIDCLogin)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestOIDCLoginRedirectsToProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	issuer := newOIDCDiscoveryServer(t)
+	t.Setenv("OIDC_ISSUER", issuer.URL)
+	t.Setenv("OIDC_CLIENT_ID", "client-1")
+	t.Setenv("OIDC_REDIRECT_URL", "")
+	router := gin.New()
+	router.GET("/api/auth/oidc/login", OIDCLogin)
+	router.GET("/api/auth/oidc/callback", OIDCCallback)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil)
+	req.Host = "app.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusFound, w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), issuer.URL+"/authorize?") {
+		t.Errorf("Expected a redirect to the provider, got %s", location)
+	}
+	if got := location.Query().Get("redirect_uri"); got != "https://app.example.com/api/auth/oidc/callback" {
+		t.Errorf("Expected the callback as redirect_uri, got %q", got)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oidcCookie || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].Path != oidcCookiePath {
+		t.Fatalf("Expected a secure, HTTP-only login cookie, got %v", cookies)
+	}
+
+	// A callback whose state doesn't match the cookie's is rejected before
+	// the code is exchanged
+	callback := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=good-code&state=forged", nil)
+	callback.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, callback)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a forged state, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// The provider reports the user declined
+	state := location.Query().Get("state")
+	callback = httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?error=access_denied&state="+state, nil)
+	callback.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, callback)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a provider error, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestOIDCCallbackRequiresLoginCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/auth/oidc/callback", OIDCCallback)
+
+	for _, cookie := range []string{"", "tampered"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=good-code&state=state-1", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: oidcCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for cookie %q, got %d", http.StatusBadRequest, cookie, w.Code)
+		}
+	}
+}
+
+func TestIdentityFromClaims(t *testing.T) {
+	t.Setenv("OIDC_ALLOWED_DOMAINS", "")
+	t.Setenv("OIDC_ROLE_CLAIM", "realm_access.roles")
+	t.Setenv("OIDC_ADMIN_VALUES", "Platform-Admin")
+
+	identity, err := identityFromClaims("https://idp.example.com", oidc.Claims{
+		"sub":                "1234",
+		"email":              "Alice@Example.com",
+		"email_verified":     true,
+		"preferred_username": "alice@example.com",
+		"realm_access":       map[string]interface{}{"roles": []interface{}{"platform-admin"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "alice@example.com" || identity.Username != "alice" || identity.Role != "admin" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	// Unverified emails are ignored, and without an admin value the role is tenant
+	identity, err = identityFromClaims("https://idp.example.com", oidc.Claims{"sub": "1234", "email": "bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "" || identity.Username != "bob" || identity.Role != models.RoleTenant {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	// Without OIDC_ADMIN_VALUES roles are left alone
+	t.Setenv("OIDC_ADMIN_VALUES", "")
+	if identity, _ := identityFromClaims("https://idp.example.com", oidc.Claims{"sub": "1234"}); identity.Role != "" {
+		t.Errorf("Expected no role mapping, got %q", identity.Role)
+	}
+
+	if _, err := identityFromClaims("https://idp.example.com", oidc.Claims{"email": "alice@example.com"}); err == nil {
+		t.Error("Expected an error without a subject")
+	}
+}
+
+func TestIdentityFromClaimsAllowedDomains(t *testing.T) {
+	t.Setenv("OIDC_ALLOWED_DOMAINS", "example.com, example.org")
+	t.Setenv("OIDC_ADMIN_VALUES", "")
+
+	tests := []struct {
+		claims  oidc.Claims
+		allowed bool
+	}{
+		{oidc.Claims{"sub": "1", "email": "alice@example.com", "email_verified": true}, true},
+		{oidc.Claims{"sub": "2", "email": "bob@EXAMPLE.org", "email_verified": true}, true},
+		{oidc.Claims{"sub": "3", "email": "carol@example.com"}, false},
+		{oidc.Claims{"sub": "4", "email": "dave@evil.com", "email_verified": true}, false},
+		{oidc.Claims{"sub": "5"}, false},
+	}
+	for _, tt := range tests {
+		_, err := identityFromClaims("https://idp.example.com", tt.claims)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("Expected allowed %v for %v, got error %v", tt.allowed, tt.claims, err)
+		}
+	}
+}
+
+func TestOIDCUsername(t *testing.T) {
+	tests := []struct {
+		claims oidc.Claims
+		want   string
+	}{
+		{oidc.Claims{"preferred_username": "Alice Smith", "sub": "1"}, "alice-smith"},
+		{oidc.Claims{"email": "bob.jones+sso@example.com", "sub": "2"}, "bob.jones-sso"},
+		{oidc.Claims{"preferred_username": "???", "email": "carol@example.com", "sub": "3"}, "carol"},
+		{oidc.Claims{"sub": "auth0|5f3e"}, "oidc-auth0-5f3e"},
+	}
+	for _, tt := range tests {
+		if got := oidcUsername(tt.claims); got != tt.want {
+			t.Errorf("oidcUsername(%v) = %q, expected %q", tt.claims, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,753 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trialPlan is the plan every signup starts on, with status trialing
+const trialPlan = "trial"
+
+var (
+	errUsernameTaken         = errors.New("Username already exists")
+	errEmailTaken            = errors.New("Email already registered")
+	errInvitationInvalid     = errors.New("Invalid, revoked, or expired invitation")
+	errInvalidCredentials    = errors.New("Invalid credentials")
+	errAlreadyInOrganization = errors.New("User already belongs to an organization")
+	errMemberNotFound        = errors.New("Member not found")
+	errLastOwner             = errors.New("An organization needs at least one owner")
+)
+
+// SignupResponse is returned when a user signs up or joins an organization
+type SignupResponse struct {
+	Token string `json:"token"`
+	// RefreshToken exchanges for a new token at POST /auth/refresh
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the number of seconds Token is valid for
+	ExpiresIn    int                 `json:"expires_in"`
+	Organization models.Organization `json:"organization"`
+}
+
+// trialDays reads SIGNUP_TRIAL_DAYS, the length of a new organization's trial
+// (default 14)
+func trialDays() int {
+	days := getEnvInt("SIGNUP_TRIAL_DAYS", 14)
+	if days < 1 {
+		return 14
+	}
+	return days
+}
+
+// Signup creates an organization with its owner
+// @Summary      Sign up
+// @Description  Create an organization (workspace), its owner user, a customer record billed to email, and a trial subscription of SIGNUP_TRIAL_DAYS (default 14), in one transaction. Returns a JWT and a refresh token for the owner; publishes user.registered and organization.created events. Signups are screened like POST /auth/register and count toward its per-IP velocity limit; a flagged signup is recorded as a rejected registration, publishes registration.flagged, and gets a 403. The owner is a tenant, so they only see their organization's customers and accounts.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        signup  body      models.SignupRequest  true  "Organization, billing email, and owner credentials"
+// @Success      201     {object}  SignupResponse
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      409     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /auth/signup [post]
+func Signup(c *gin.Context) {
+	var req models.SignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.OrganizationName = strings.TrimSpace(req.OrganizationName)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.OrganizationName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_name must not be blank"})
+		return
+	}
+
+	// Signups are screened like registrations, but a flagged signup can't be
+	// held, since approval only creates a user, so it is rejected outright
+	registration := models.Registration{Username: req.Username, Email: &req.Email, IPAddress: optionalString(c.ClientIP()), UserAgent: optionalString(c.Request.UserAgent())}
+	registration.Reasons = screenRegistration(c, req.Username, req.Email, req.Website)
+	if len(registration.Reasons) > 0 {
+		rejected, err := rejectRegistration(c.Request.Context(), registration)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+			return
+		}
+		reportFlaggedRegistration(c, rejected)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Signup could not be completed"})
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// The billing customer is audited as created by the new owner
+	ctx := auth.WithActor(c.Request.Context(), req.Username)
+	trialEndsAt := time.Now().UTC().AddDate(0, 0, trialDays()).Truncate(time.Second)
+	org, userID, err := provisionOrganization(ctx, req, passwordHash, trialEndsAt)
+	if errors.Is(err, errUsernameTaken) || errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+	registration.UserID = &userID
+	recordRegistration(ctx, registration)
+
+	tokens, err := issueTokens(ctx, req.Username, newLoginClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	events.Publish(ctx, events.UserRegistered, gin.H{
+		"user_id":         userID,
+		"username":        req.Username,
+		"ip_address":      c.ClientIP(),
+		"organization_id": org.ID,
+	})
+	events.Publish(ctx, events.OrganizationCreated, gin.H{
+		"id":            org.ID,
+		"name":          org.Name,
+		"customer_id":   org.CustomerID,
+		"owner":         req.Username,
+		"email":         req.Email,
+		"plan":          org.Subscription.Plan,
+		"trial_ends_at": org.Subscription.TrialEndsAt,
+	})
+	c.JSON(http.StatusCreated, SignupResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn, Organization: org})
+}
+
+// provisionOrganization creates the organization, its customer record, its
+// owner, and its trial subscription in one transaction; tests replace it
+var provisionOrganization = func(ctx context.Context, req models.SignupRequest, passwordHash string, trialEndsAt time.Time) (models.Organization, int, error) {
+	var org models.Organization
+	var userID int
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		org = models.Organization{Name: req.OrganizationName}
+		err := tx.QueryRowContext(ctx,
+			"INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at", req.OrganizationName,
+		).Scan(&org.ID, &org.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		var customerID int
+		err = tx.QueryRowContext(ctx,
+			"INSERT INTO customers (name, email, organization_id) VALUES ($1, $2, $3) RETURNING id",
+			req.OrganizationName, req.Email, org.ID,
+		).Scan(&customerID)
+		if db.UniqueViolation(err) != "" {
+			return errEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		org.CustomerID = &customerID
+
+		owner := models.OrganizationMember{Username: req.Username, Role: models.OrganizationRoleOwner}
+		err = tx.QueryRowContext(ctx,
+			"INSERT INTO users (username, password_hash, organization_id, organization_role, role) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+			req.Username, passwordHash, org.ID, owner.Role, models.RoleTenant,
+		).Scan(&userID, &owner.CreatedAt)
+		if db.UniqueViolation(err) != "" {
+			return errUsernameTaken
+		}
+		if err != nil {
+			return err
+		}
+		org.Members = []models.OrganizationMember{owner}
+
+		subscription := models.Subscription{Plan: trialPlan, Status: "trialing", TrialEndsAt: &trialEndsAt}
+		err = tx.QueryRowContext(ctx,
+			"INSERT INTO subscriptions (organization_id, plan, status, trial_ends_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+			org.ID, subscription.Plan, subscription.Status, trialEndsAt,
+		).Scan(&subscription.ID, &subscription.CreatedAt)
+		if err != nil {
+			return err
+		}
+		org.Subscription = &subscription
+		return nil
+	})
+	return org, userID, err
+}
+
+// invitationTTL reads INVITATION_TTL, how long an invitation can be accepted
+// (default 168h, a week)
+func invitationTTL() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("INVITATION_TTL")); err == nil && value > 0 {
+		return value
+	}
+	return 7 * 24 * time.Hour
+}
+
+// GetMyOrganization returns the caller's organization
+// @Summary      Get my organization
+// @Description  Get the caller's organization with its members and current subscription
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Organization
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /organization [get]
+// @Security     BearerAuth
+func GetMyOrganization(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID, _, err := userOrganization(ctx, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if orgID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not a member of an organization"})
+		return
+	}
+
+	org, err := loadOrganization(ctx, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization"})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// GetOrganization returns an organization the caller belongs to
+// @Summary      Get organization
+// @Description  Get an organization with its members and current subscription (members only)
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Organization ID"
+// @Success      200  {object}  models.Organization
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /orgs/{id} [get]
+// @Security     BearerAuth
+func GetOrganization(c *gin.Context) {
+	org, err := loadOrganization(c.Request.Context(), c.GetInt("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization"})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// userOrganization returns the organization a user belongs to and their role
+// in it, or 0 if they belong to none; tests replace it
+var userOrganization = func(ctx context.Context, username string) (int, string, error) {
+	var orgID sql.NullInt64
+	var role sql.NullString
+	err := db.Primary(ctx).QueryRow(
+		"SELECT organization_id, organization_role FROM users WHERE username = $1", username,
+	).Scan(&orgID, &role)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	return int(orgID.Int64), role.String, err
+}
+
+// userTenancy returns the organization a user belongs to, or 0 if none, and
+// whether they are a tenant (see models.RoleTenant) rather than staff. Only
+// users outside any organization with a staff role (see models.IsStaffRole)
+// are staff; everyone else, unknown users included, is a tenant. Tests
+// replace it
+var userTenancy = func(ctx context.Context, username string) (int, bool, error) {
+	var orgID sql.NullInt64
+	var role string
+	err := db.Primary(ctx).QueryRow("SELECT organization_id, role FROM users WHERE username = $1", username).Scan(&orgID, &role)
+	if err == sql.ErrNoRows {
+		return 0, true, nil
+	}
+	return int(orgID.Int64), orgID.Valid || !models.IsStaffRole(role), err
+}
+
+// loadOrganization reads an organization with its members and latest subscription
+func loadOrganization(ctx context.Context, orgID int) (models.Organization, error) {
+	h := db.Primary(ctx)
+	var org models.Organization
+	var customerID sql.NullInt64
+	err := h.QueryRow(
+		"SELECT o.id, o.name, (SELECT MIN(id) FROM customers WHERE organization_id = o.id), o.created_at FROM organizations o WHERE o.id = $1",
+		orgID,
+	).Scan(&org.ID, &org.Name, &customerID, &org.CreatedAt)
+	if err != nil {
+		return org, err
+	}
+	if customerID.Valid {
+		id := int(customerID.Int64)
+		org.CustomerID = &id
+	}
+
+	var subscription models.Subscription
+	var trialEndsAt sql.NullTime
+	err = h.QueryRow(
+		"SELECT id, plan, status, trial_ends_at, created_at FROM subscriptions WHERE organization_id = $1 ORDER BY id DESC LIMIT 1",
+		orgID,
+	).Scan(&subscription.ID, &subscription.Plan, &subscription.Status, &trialEndsAt, &subscription.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return org, err
+	}
+	if err == nil {
+		subscription.TrialEndsAt = nullTime(trialEndsAt)
+		org.Subscription = &subscription
+	}
+
+	org.Members, err = organizationMembers(ctx, orgID)
+	return org, err
+}
+
+// organizationMembers lists an organization's users, earliest first; tests
+// replace it
+var organizationMembers = func(ctx context.Context, orgID int) ([]models.OrganizationMember, error) {
+	rows, err := db.Primary(ctx).Query(
+		"SELECT username, organization_role, created_at FROM users WHERE organization_id = $1 ORDER BY created_at, id",
+		orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrganizationMember{}
+	for rows.Next() {
+		var member models.OrganizationMember
+		if err := rows.Scan(&member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// GetMembers lists an organization's members
+// @Summary      List members
+// @Description  List an organization's users and their roles, earliest first (members only)
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Organization ID"
+// @Success      200  {array}   models.OrganizationMember
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /orgs/{id}/members [get]
+// @Security     BearerAuth
+func GetMembers(c *gin.Context) {
+	members, err := organizationMembers(c.Request.Context(), c.GetInt("organization_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// RemoveMember removes a user from an organization
+// @Summary      Remove member
+// @Description  Remove a user from an organization (owners only). The user is kept, without an organization, and can be invited again. The last owner can't be removed.
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int     true  "Organization ID"
+// @Param        username  path      string  true  "Username"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      409       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /orgs/{id}/members/{username} [delete]
+// @Security     BearerAuth
+func RemoveMember(c *gin.Context) {
+	err := removeMember(c.Request.Context(), c.GetInt("organization_id"), c.Param("username"))
+	if errors.Is(err, errMemberNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// removeMember detaches username from the organization, keeping at least one
+// owner; tests replace it
+var removeMember = func(ctx context.Context, orgID int, username string) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Locking the organization serializes removals, so two owners can't
+		// remove each other at the same time
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM organizations WHERE id = $1 FOR UPDATE", orgID); err != nil {
+			return err
+		}
+		var role string
+		err := tx.QueryRowContext(ctx,
+			"SELECT organization_role FROM users WHERE username = $1 AND organization_id = $2", username, orgID,
+		).Scan(&role)
+		if err == sql.ErrNoRows {
+			return errMemberNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if role == models.OrganizationRoleOwner {
+			var owners int
+			err := tx.QueryRowContext(ctx,
+				"SELECT COUNT(*) FROM users WHERE organization_id = $1 AND organization_role = $2", orgID, models.OrganizationRoleOwner,
+			).Scan(&owners)
+			if err != nil {
+				return err
+			}
+			if owners <= 1 {
+				return errLastOwner
+			}
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET organization_id = NULL, organization_role = NULL WHERE username = $1", username,
+		)
+		return err
+	})
+}
+
+const invitationColumns = "id, email, role, invited_by, created_at, expires_at, accepted_at, accepted_by, revoked_at"
+
+func scanInvitation(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Invitation, error) {
+	var invitation models.Invitation
+	var acceptedAt, revokedAt sql.NullTime
+	var acceptedBy sql.NullString
+	dest := append([]interface{}{&invitation.ID, &invitation.Email, &invitation.Role, &invitation.InvitedBy,
+		&invitation.CreatedAt, &invitation.ExpiresAt, &acceptedAt, &acceptedBy, &revokedAt}, extra...)
+	err := row.Scan(dest...)
+	invitation.AcceptedAt = nullTime(acceptedAt)
+	invitation.RevokedAt = nullTime(revokedAt)
+	if acceptedBy.Valid {
+		invitation.AcceptedBy = &acceptedBy.String
+	}
+	return invitation, err
+}
+
+// CreateInvitation invites a teammate to an organization
+// @Summary      Invite a teammate
+// @Description  Invite an email address to join an organization as a member, or with role owner (owners only). Inviting an address again revokes its pending invitation. The token is only returned in this response and in the user.invited event, which an email webhook delivers; the invitee joins with POST /auth/invitations/accept. Invitations expire after INVITATION_TTL (default 168h).
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                             true  "Organization ID"
+// @Param        invitation  body      models.CreateInvitationRequest  true  "Email to invite and the role to grant"
+// @Success      201         {object}  models.Invitation
+// @Failure      400         {object}  map[string]string
+// @Failure      403         {object}  map[string]string
+// @Failure      500         {object}  map[string]string
+// @Router       /orgs/{id}/invitations [post]
+// @Security     BearerAuth
+func CreateInvitation(c *gin.Context) {
+	var req models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Role == "" {
+		req.Role = models.OrganizationRoleMember
+	}
+	if !models.ValidOrganizationRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role, expected owner or member"})
+		return
+	}
+
+	secret, err := auth.NewInviteToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation"})
+		return
+	}
+	ctx := c.Request.Context()
+	orgID := c.GetInt("organization_id")
+	expiresAt := time.Now().UTC().Add(invitationTTL()).Truncate(time.Second)
+	invitation, orgName, err := insertInvitation(ctx, orgID, req, secret, c.GetString("username"), expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+	invitation.Token = secret
+
+	events.Publish(ctx, events.UserInvited, gin.H{
+		"id":                invitation.ID,
+		"organization_id":   orgID,
+		"organization_name": orgName,
+		"email":             invitation.Email,
+		"role":              invitation.Role,
+		"invited_by":        invitation.InvitedBy,
+		"token":             secret,
+		"expires_at":        invitation.ExpiresAt,
+	})
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// insertInvitation stores the hash of secret, revoking the email's pending
+// invitations, and returns the organization's name for the invitation email;
+// tests replace it
+var insertInvitation = func(ctx context.Context, orgID int, req models.CreateInvitationRequest, secret, invitedBy string, expiresAt time.Time) (models.Invitation, string, error) {
+	var invitation models.Invitation
+	var orgName string
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE organization_invites SET revoked_at = CURRENT_TIMESTAMP WHERE organization_id = $1 AND email = $2 AND accepted_at IS NULL AND revoked_at IS NULL",
+			orgID, req.Email,
+		)
+		if err != nil {
+			return err
+		}
+		invitation, err = scanInvitation(tx.QueryRowContext(ctx,
+			`INSERT INTO organization_invites (organization_id, email, role, token_hash, invited_by, expires_at)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING `+invitationColumns+`, (SELECT name FROM organizations WHERE id = $1)`,
+			orgID, req.Email, req.Role, auth.HashInviteToken(secret), invitedBy, expiresAt,
+		), &orgName)
+		return err
+	})
+	return invitation, orgName, err
+}
+
+// GetInvitations lists an organization's invitations
+// @Summary      List invitations
+// @Description  List an organization's invitations, including accepted, revoked, and expired ones, without their tokens (owners only)
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Organization ID"
+// @Success      200  {array}   models.Invitation
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /orgs/{id}/invitations [get]
+// @Security     BearerAuth
+func GetInvitations(c *gin.Context) {
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT "+invitationColumns+" FROM organization_invites WHERE organization_id = $1 ORDER BY id",
+		c.GetInt("organization_id"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan invitation"})
+			return
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation stops a pending invitation from being accepted
+// @Summary      Revoke invitation
+// @Description  Revoke a pending invitation of an organization (owners only)
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int  true  "Organization ID"
+// @Param        invitation_id  path      int  true  "Invitation ID"
+// @Success      200            {object}  map[string]string
+// @Failure      400            {object}  map[string]string
+// @Failure      403            {object}  map[string]string
+// @Failure      404            {object}  map[string]string
+// @Failure      500            {object}  map[string]string
+// @Router       /orgs/{id}/invitations/{invitation_id} [delete]
+// @Security     BearerAuth
+func RevokeInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	result, err := db.Primary(c.Request.Context()).Exec(
+		"UPDATE organization_invites SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL",
+		id, c.GetInt("organization_id"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation adds a user to the organization that invited them
+// @Summary      Accept invitation
+// @Description  Join an organization with an invitation token, with the role the invitation grants. If username belongs to an existing user without an organization, password must be theirs, with two_factor_code if they enabled two-factor authentication, and that user joins (200); otherwise a user is created (201) and a user.registered event is published. Returns a JWT and a refresh token for the user. Invitations skip registration screening, since an organization admin vetted the invitee.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        invitation  body      models.AcceptInvitationRequest  true  "Invitation token and the user's credentials"
+// @Success      200         {object}  SignupResponse
+// @Success      201         {object}  SignupResponse
+// @Failure      400         {object}  map[string]string
+// @Failure      401         {object}  map[string]string
+// @Failure      409         {object}  map[string]string
+// @Failure      500         {object}  map[string]string
+// @Router       /auth/invitations/accept [post]
+func AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	accepted, err := acceptInvitation(ctx, req)
+	switch {
+	case errors.Is(err, errInvitationInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTwoFactorRequired), errors.Is(err, errInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "two_factor_required": true})
+		return
+	case errors.Is(err, errAlreadyInOrganization), errors.Is(err, errUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	tokens, err := issueTokens(ctx, req.Username, newLoginClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	status := http.StatusOK
+	if accepted.Created {
+		status = http.StatusCreated
+		events.Publish(ctx, events.UserRegistered, gin.H{
+			"user_id":         accepted.UserID,
+			"username":        req.Username,
+			"ip_address":      c.ClientIP(),
+			"organization_id": accepted.OrganizationID,
+		})
+	}
+
+	org, err := loadOrganization(ctx, accepted.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organization"})
+		return
+	}
+	c.JSON(status, SignupResponse{Token: tokens.Token, RefreshToken: tokens.RefreshToken, ExpiresIn: tokens.ExpiresIn, Organization: org})
+}
+
+// acceptedInvitation is the outcome of acceptInvitation
+type acceptedInvitation struct {
+	OrganizationID int
+	UserID         int
+	// Created is set when a user was created rather than an existing one linked
+	Created bool
+}
+
+// acceptInvitation adds the user named in req to the invitation's
+// organization with its role, creating the user if there is none, and marks
+// the invitation accepted; tests replace it
+var acceptInvitation = func(ctx context.Context, req models.AcceptInvitationRequest) (acceptedInvitation, error) {
+	var accepted acceptedInvitation
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		accepted = acceptedInvitation{}
+		invitation, err := scanInvitation(tx.QueryRowContext(ctx,
+			"SELECT "+invitationColumns+", organization_id FROM organization_invites WHERE token_hash = $1 FOR UPDATE",
+			auth.HashInviteToken(req.Token),
+		), &accepted.OrganizationID)
+		if err == sql.ErrNoRows || (err == nil && !invitation.Pending(time.Now())) {
+			return errInvitationInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		var passwordHash string
+		var orgID sql.NullInt64
+		var twoFactor bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT id, password_hash, organization_id, totp_enabled_at IS NOT NULL FROM users WHERE username = $1 FOR UPDATE", req.Username,
+		).Scan(&accepted.UserID, &passwordHash, &orgID, &twoFactor)
+		switch {
+		case err == sql.ErrNoRows:
+			if passwordHash, err = auth.HashPassword(req.Password); err != nil {
+				return err
+			}
+			err = tx.QueryRowContext(ctx,
+				"INSERT INTO users (username, password_hash, organization_id, organization_role, role) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+				req.Username, passwordHash, accepted.OrganizationID, invitation.Role, models.RoleTenant,
+			).Scan(&accepted.UserID)
+			if db.UniqueViolation(err) != "" {
+				// Someone took the username since it was looked up
+				return errUsernameTaken
+			}
+			if err != nil {
+				return err
+			}
+			accepted.Created = true
+		case err != nil:
+			return err
+		case !auth.CheckPasswordHash(req.Password, passwordHash):
+			return errInvalidCredentials
+		case orgID.Valid:
+			return errAlreadyInOrganization
+		default:
+			if twoFactor {
+				if err := consumeTwoFactorCode(ctx, tx, accepted.UserID, req.TwoFactorCode); err != nil {
+					return err
+				}
+			}
+			_, err = tx.ExecContext(ctx,
+				"UPDATE users SET organization_id = $1, organization_role = $2 WHERE id = $3",
+				accepted.OrganizationID, invitation.Role, accepted.UserID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE organization_invites SET accepted_at = CURRENT_TIMESTAMP, accepted_by = $1 WHERE id = $2",
+			req.Username, invitation.ID,
+		)
+		return err
+	})
+	return accepted, err
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/abuse"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSignupErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous, previousCount := provisionOrganization, countRegistrations
+	t.Cleanup(func() { provisionOrganization, countRegistrations = previous, previousCount })
+	countRegistrations = func(ctx context.Context, ip string, since time.Time) (int, error) { return 0, nil }
+	var provisioned models.SignupRequest
+	provisionOrganization = func(ctx context.Context, req models.SignupRequest, passwordHash string, trialEndsAt time.Time) (models.Organization, int, error) {
+		provisioned = req
+		if days := time.Until(trialEndsAt).Hours() / 24; days < 13 || days > 14 {
+			t.Errorf("Expected a 14-day trial, got %.1f days", days)
+		}
+		switch req.Username {
+		case "taken":
+			return models.Organization{}, 0, errUsernameTaken
+		case "broken":
+			return models.Organization{}, 0, errors.New("connection reset")
+		}
+		return models.Organization{}, 0, errEmailTaken
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/signup", Signup)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"organization_name": "Acme", "email": "owner@acme.test", "username": "owner"}`, http.StatusBadRequest},
+		{`{"organization_name": "Acme", "email": "not-an-email", "username": "owner", "password": "secret1"}`, http.StatusBadRequest},
+		{`{"organization_name": "  ", "email": "owner@acme.test", "username": "owner", "password": "secret1"}`, http.StatusBadRequest},
+		{`{"organization_name": "Acme", "email": "owner@acme.test", "username": "taken", "password": "secret1"}`, http.StatusConflict},
+		{`{"organization_name": " Acme ", "email": "Owner@Acme.test", "username": "owner", "password": "secret1"}`, http.StatusConflict},
+		{`{"organization_name": "Acme", "email": "owner@acme.test", "username": "broken", "password": "secret1"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/signup", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+	if provisioned.OrganizationName != "Acme" || provisioned.Email != "owner@acme.test" {
+		t.Errorf("Expected the name trimmed and the email normalized, got %+v", provisioned)
+	}
+}
+
+func TestSignupRejectsFlaggedSignups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("REGISTRATION_IP_LIMIT", "3")
+	previousCount, previousReject, previousProvision := countRegistrations, rejectRegistration, provisionOrganization
+	t.Cleanup(func() {
+		countRegistrations, rejectRegistration, provisionOrganization = previousCount, previousReject, previousProvision
+	})
+
+	recent := 0
+	var countedIP string
+	countRegistrations = func(ctx context.Context, ip string, since time.Time) (int, error) {
+		countedIP = ip
+		return recent, nil
+	}
+	var rejected models.Registration
+	rejectRegistration = func(ctx context.Context, registration models.Registration) (models.Registration, error) {
+		rejected = registration
+		// Stop before the registration.flagged event, which needs a database
+		return registration, errors.New("connection reset")
+	}
+	provisionOrganization = func(ctx context.Context, req models.SignupRequest, passwordHash string, trialEndsAt time.Time) (models.Organization, int, error) {
+		t.Errorf("Expected flagged signup %s not to be provisioned", req.Username)
+		return models.Organization{}, 0, errUsernameTaken
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/signup", Signup)
+
+	tests := []struct {
+		body    string
+		recent  int
+		reasons []string
+	}{
+		{`{"organization_name": "Spam", "email": "bot@spam.test", "username": "bot", "password": "secret1", "website": "https://spam.example"}`, 0, []string{abuse.ReasonHoneypot}},
+		{`{"organization_name": "Spam", "email": "bot@mailinator.com", "username": "bot", "password": "secret1"}`, 0, []string{abuse.ReasonDisposableEmail}},
+		{`{"organization_name": "Spam", "email": "bot@spam.test", "username": "bot", "password": "secret1"}`, 3, []string{abuse.ReasonIPVelocity}},
+	}
+	for _, tt := range tests {
+		recent, rejected = tt.recent, models.Registration{}
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/signup", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.9:4000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected the failed rejection's status %d for %s, got %d: %s", http.StatusInternalServerError, tt.body, w.Code, w.Body.String())
+		}
+		if fmt.Sprint(rejected.Reasons) != fmt.Sprint(tt.reasons) {
+			t.Errorf("Expected %s to be rejected for %v, got %v", tt.body, tt.reasons, rejected.Reasons)
+		}
+		if countedIP != "203.0.113.9" {
+			t.Errorf("Expected registrations counted from 203.0.113.9, got %q", countedIP)
+		}
+	}
+}
+
+func TestAcceptInvitationErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := acceptInvitation
+	t.Cleanup(func() { acceptInvitation = previous })
+	acceptInvitation = func(ctx context.Context, req models.AcceptInvitationRequest) (acceptedInvitation, error) {
+		if req.Token != "sgi_valid" {
+			return acceptedInvitation{}, errInvitationInvalid
+		}
+		switch req.Username {
+		case "jane":
+			return acceptedInvitation{}, errInvalidCredentials
+		case "joined":
+			return acceptedInvitation{}, errAlreadyInOrganization
+		case "taken":
+			return acceptedInvitation{}, errUsernameTaken
+		}
+		return acceptedInvitation{}, errors.New("connection reset")
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/invitations/accept", AcceptInvitation)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"token": "sgi_valid", "username": "teammate", "password": "short"}`, http.StatusBadRequest},
+		{`{"token": "sgi_expired", "username": "teammate", "password": "secret1"}`, http.StatusBadRequest},
+		{`{"token": "sgi_valid", "username": "jane", "password": "wrong-password"}`, http.StatusUnauthorized},
+		{`{"token": "sgi_valid", "username": "joined", "password": "secret1"}`, http.StatusConflict},
+		{`{"token": "sgi_valid", "username": "taken", "password": "secret1"}`, http.StatusConflict},
+		{`{"token": "sgi_valid", "username": "teammate", "password": "secret1"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/invitations/accept", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequireOrganization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useFakeOrganizations(t, map[string]int{"owner": 3, "member": 3, "outsider": 4})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", c.GetHeader("X-User"))
+	})
+	router.GET("/api/orgs/:id/members", RequireOrganizationMember(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"organization_id": c.GetInt("organization_id")})
+	})
+	router.GET("/api/orgs/:id/invitations", RequireOrganizationOwner(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"organization_id": c.GetInt("organization_id")})
+	})
+
+	tests := []struct {
+		path, username string
+		want           int
+	}{
+		{"/api/orgs/3/members", "owner", http.StatusOK},
+		{"/api/orgs/3/members", "member", http.StatusOK},
+		{"/api/orgs/3/members", "outsider", http.StatusForbidden},
+		{"/api/orgs/3/members", "admin", http.StatusForbidden},
+		{"/api/orgs/x/members", "owner", http.StatusBadRequest},
+		{"/api/orgs/3/invitations", "owner", http.StatusOK},
+		{"/api/orgs/3/invitations", "member", http.StatusForbidden},
+		{"/api/orgs/4/invitations", "owner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("X-User", tt.username)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s as %s, got %d", tt.want, tt.path, tt.username, w.Code)
+		}
+		if tt.want == http.StatusOK && !strings.Contains(w.Body.String(), `"organization_id":3`) {
+			t.Errorf("Expected organization_id to be set, got %s", w.Body.String())
+		}
+	}
+}
+
+func TestCreateInvitationValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := insertInvitation
+	t.Cleanup(func() { insertInvitation = previous })
+	insertInvitation = func(ctx context.Context, orgID int, req models.CreateInvitationRequest, secret, invitedBy string, expiresAt time.Time) (models.Invitation, string, error) {
+		t.Fatalf("Expected invalid invitations not to be stored, got %+v", req)
+		return models.Invitation{}, "", nil
+	}
+
+	router := gin.New()
+	router.POST("/api/orgs/:id/invitations", CreateInvitation)
+
+	for _, body := range []string{`{}`, `{"email": "not-an-email"}`, `{"email": "teammate@acme.test", "role": "admin"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/orgs/3/invitations", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := removeMember
+	t.Cleanup(func() { removeMember = previous })
+	removeMember = func(ctx context.Context, orgID int, username string) error {
+		switch username {
+		case "owner":
+			return errLastOwner
+		case "member":
+			return nil
+		}
+		return errMemberNotFound
+	}
+
+	router := gin.New()
+	router.DELETE("/api/orgs/:id/members/:username", func(c *gin.Context) {
+		c.Set("organization_id", 3)
+	}, RemoveMember)
+
+	for username, want := range map[string]int{"member": http.StatusOK, "owner": http.StatusConflict, "stranger": http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/orgs/3/members/"+username, nil))
+
+		if w.Code != want {
+			t.Errorf("Expected status %d removing %s, got %d", want, username, w.Code)
+		}
+	}
+}
+
+// useFakeOrganizations swaps userOrganization for a lookup in orgs, by
+// username, for the duration of the test. Users named owner are owners.
+func useFakeOrganizations(t *testing.T, orgs map[string]int) {
+	previous := userOrganization
+	t.Cleanup(func() { userOrganization = previous })
+	userOrganization = func(ctx context.Context, username string) (int, string, error) {
+		if username == "owner" {
+			return orgs[username], models.OrganizationRoleOwner, nil
+		}
+		if orgs[username] == 0 {
+			return 0, "", nil
+		}
+		return orgs[username], models.OrganizationRoleMember, nil
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/cursor"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPageLimit caps the limit parameter on paginated list endpoints
+const maxPageLimit = 1000
+
+// The orders the sort parameter of paginated list endpoints accepts
+const (
+	sortNewest = "-created_at"
+	sortOldest = "created_at"
+)
+
+// validSort reports whether sort is an order lists can be returned in
+func validSort(sort string) bool {
+	return sort == sortNewest || sort == sortOldest
+}
+
+// pageCursor is the keyset position a page resumes after. It only ever
+// leaves the server sealed by internal/cursor, so clients can't forge offsets.
+type pageCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        int       `json:"i"`
+	Limit     int       `json:"l"`
+}
+
+// page is the pagination requested for a list endpoint. A zero Limit means
+// the endpoint returns every row, as it did before pagination.
+type page struct {
+	Limit     int
+	After     *pageCursor
+	Ascending bool
+	scope     string
+}
+
+// parsePage reads the optional limit, cursor, and sort query parameters for
+// resource, defaulting limit and sort to the caller's page_size and sort
+// preferences. Cursors are bound to the resource, the caller, as_of,
+// include_deleted, and the sort, so they can't be replayed on another
+// endpoint, by another user, against another snapshot, or in another order. It writes a 400 response and
+// returns false if any parameter is invalid.
+func parsePage(c *gin.Context, resource string) (page, bool) {
+	prefs := preferences(c)
+	sort := c.DefaultQuery("sort", prefs.Sort)
+	if sort == "" {
+		sort = sortNewest
+	}
+	if !validSort(sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, expected -created_at or created_at"})
+		return page{}, false
+	}
+	p := page{
+		Ascending: sort == sortOldest,
+		scope:     fmt.Sprintf("%s|%s|%s|%s|%s", resource, c.GetString("username"), c.Query("as_of"), c.Query("include_deleted"), sort),
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		var after pageCursor
+		if err := cursor.Decode(p.scope, token, &after); err != nil {
+			message := "Invalid cursor"
+			if errors.Is(err, cursor.ErrExpired) {
+				message = "Cursor expired, start again from the first page"
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return page{}, false
+		}
+		p.After = &after
+		p.Limit = after.Limit
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit, expected 1-%d", maxPageLimit)})
+			return page{}, false
+		}
+		p.Limit = limit
+	}
+	if p.Limit == 0 && p.After == nil {
+		p.Limit = prefs.PageSize
+	}
+	return p, true
+}
+
+// options returns the repository list options for the page at asOf. With a
+// limit it asks for one extra row so more can tell whether another page follows.
+func (p page) options(asOf *time.Time) repository.ListOptions {
+	opts := repository.ListOptions{AsOf: asOf, Ascending: p.Ascending}
+	if p.After != nil {
+		opts.After = &repository.Position{CreatedAt: p.After.CreatedAt, ID: p.After.ID}
+	}
+	if p.Limit > 0 {
+		opts.Limit = p.Limit + 1
+	}
+	return opts
+}
+
+// more reports whether n fetched rows include the extra row from options, in
+// which case the caller drops the extra row and calls next
+func (p page) more(n int) bool {
+	return p.Limit > 0 && n > p.Limit
+}
+
+// next sets the X-Next-Cursor header to resume after the row with createdAt and id
+func (p page) next(c *gin.Context, createdAt time.Time, id int) {
+	token, err := cursor.Encode(p.scope, pageCursor{CreatedAt: createdAt, ID: id, Limit: p.Limit}, 24*time.Hour)
+	if err != nil {
+		log.Printf("Warning: Failed to encode cursor: %v", err)
+		return
+	}
+	c.Header("X-Next-Cursor", token)
+}
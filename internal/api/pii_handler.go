@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPIIAccessLog returns recent unmasked PII access
+// @Summary      List unmasked PII access
+// @Description  Get the 500 most recent responses that showed PII fields unmasked, newest first, optionally for one user or customer (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        username     query     string  false  "Only show access by this user"
+// @Param        customer_id  query     int     false  "Only show access to this customer"
+// @Success      200          {array}   models.PIIAccess
+// @Failure      400          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /admin/pii/access-log [get]
+// @Security     BearerAuth
+func GetPIIAccessLog(c *gin.Context) {
+	var username *string
+	if value := c.Query("username"); value != "" {
+		username = &value
+	}
+	var customerID *int
+	if value := c.Query("customer_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return
+		}
+		customerID = &id
+	}
+
+	rows, err := db.Primary(c.Request.Context()).Query(`
+		SELECT id, username, role, resource, record_ids, fields, route, COALESCE(request_id, ''), created_at
+		FROM pii_access_log
+		WHERE ($1::text IS NULL OR username = $1)
+			AND ($2::int IS NULL OR (resource = 'customers' AND record_ids @> ARRAY[$2::int]))
+		ORDER BY created_at DESC
+		LIMIT 500`,
+		username, customerID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch PII access log"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.PIIAccess{}
+	for rows.Next() {
+		var entry models.PIIAccess
+		if err := rows.Scan(&entry.ID, &entry.Username, &entry.Role, &entry.Resource, db.Array(&entry.RecordIDs),
+			db.Array(&entry.Fields), &entry.Route, &entry.RequestID, &entry.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan PII access log"})
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"saas-go-app/internal/posture"
+
+	"github.com/gin-gonic/gin"
+)
+
+// assessPosture runs the security posture checks; tests replace it
+var assessPosture = posture.Assess
+
+// GetSecurityPosture reports how safely the app is deployed
+// @Summary      Security posture
+// @Description  Assess the deployment's security (admin only): whether the admin user still has the deprecated seed password admin123, whether JWT_SECRET is set, long enough, and not a known placeholder, whether the API allows cross-origin requests, and whether rate limiting and login lockouts are enabled. Each finding has a status (pass, fail, or unknown when it couldn't be checked) and a severity (critical, high, medium, or low) saying how exposed the deployment is while it fails, with a remediation for failures. risk is the severity of the most serious failure, or none. The same assessment is logged at startup.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  posture.Report
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/security-posture [get]
+// @Security     BearerAuth
+func GetSecurityPosture(c *gin.Context) {
+	c.JSON(http.StatusOK, assessPosture(c.Request.Context()))
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// preferences returns the caller's preferences, looking them up at most once
+// per request. Customer token callers, and users whose preferences can't be
+// read, get none.
+func preferences(c *gin.Context) models.Preferences {
+	if prefs, ok := c.Get("preferences"); ok {
+		return prefs.(models.Preferences)
+	}
+	var prefs models.Preferences
+	_, customer := c.Get("customer_token")
+	if username := c.GetString("username"); username != "" && !customer {
+		var err error
+		prefs, err = userPreferences(c.Request.Context(), username)
+		if err != nil {
+			log.Printf("Warning: Failed to load preferences of %s: %v", username, err)
+			prefs = models.Preferences{}
+		}
+	}
+	c.Set("preferences", prefs)
+	return prefs
+}
+
+// userPreferences reads a user's preferences, which are empty if they never
+// saved any; tests replace it
+var userPreferences = func(ctx context.Context, username string) (models.Preferences, error) {
+	prefs, err := scanPreferences(db.Primary(ctx).QueryRow(
+		"SELECT page_size, sort, fields, timezone, updated_at FROM user_preferences WHERE username = $1", username,
+	))
+	if err == sql.ErrNoRows {
+		return models.Preferences{}, nil
+	}
+	return prefs, err
+}
+
+// savePreferences replaces a user's preferences; tests replace it
+var savePreferences = func(ctx context.Context, username string, req models.UpdatePreferencesRequest) (models.Preferences, error) {
+	if req.Fields == nil {
+		req.Fields = map[string][]string{}
+	}
+	fields, err := json.Marshal(req.Fields)
+	if err != nil {
+		return models.Preferences{}, err
+	}
+	return scanPreferences(db.Primary(ctx).QueryRow(
+		`INSERT INTO user_preferences (username, page_size, sort, fields, timezone)
+		 VALUES ($1, NULLIF($2, 0), NULLIF($3, ''), $4, NULLIF($5, ''))
+		 ON CONFLICT (username) DO UPDATE SET page_size = EXCLUDED.page_size, sort = EXCLUDED.sort,
+			fields = EXCLUDED.fields, timezone = EXCLUDED.timezone, updated_at = CURRENT_TIMESTAMP
+		 RETURNING page_size, sort, fields, timezone, updated_at`,
+		username, req.PageSize, req.Sort, fields, req.Timezone,
+	))
+}
+
+func scanPreferences(row interface{ Scan(...interface{}) error }) (models.Preferences, error) {
+	var prefs models.Preferences
+	var pageSize sql.NullInt64
+	var sort, timezone sql.NullString
+	var fields []byte
+	var updatedAt sql.NullTime
+	if err := row.Scan(&pageSize, &sort, &fields, &timezone, &updatedAt); err != nil {
+		return prefs, err
+	}
+	prefs.PageSize = int(pageSize.Int64)
+	prefs.Sort = sort.String
+	prefs.Timezone = timezone.String
+	prefs.UpdatedAt = nullTime(updatedAt)
+	if err := json.Unmarshal(fields, &prefs.Fields); err != nil {
+		return prefs, err
+	}
+	if len(prefs.Fields) == 0 {
+		prefs.Fields = nil
+	}
+	return prefs, nil
+}
+
+// GetPreferences returns the caller's list defaults
+// @Summary      Get my preferences
+// @Description  Get the caller's defaults for the customer and account lists: page_size for limit, sort, fields per list, and timezone for tz. Each applies when a request leaves out that query parameter. Preferences that were never set are omitted.
+// @Tags         preferences
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Preferences
+// @Failure      500  {object}  map[string]string
+// @Router       /me/preferences [get]
+// @Security     BearerAuth
+func GetPreferences(c *gin.Context) {
+	prefs, err := userPreferences(c.Request.Context(), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences replaces the caller's list defaults
+// @Summary      Update my preferences
+// @Description  Replace the caller's defaults for the customer and account lists; omitted preferences are cleared. page_size is 1-1000, sort is -created_at (newest first) or created_at, fields maps customers or accounts to the fields to return, and timezone is an IANA time zone such as Europe/Berlin.
+// @Tags         preferences
+// @Accept       json
+// @Produce      json
+// @Param        preferences  body      models.UpdatePreferencesRequest  true  "List defaults"
+// @Success      200          {object}  models.Preferences
+// @Failure      400          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /me/preferences [put]
+// @Security     BearerAuth
+func UpdatePreferences(c *gin.Context) {
+	var req models.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if message := validatePreferences(req); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	prefs, err := savePreferences(c.Request.Context(), c.GetString("username"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// validatePreferences returns why req can't be saved, or "" if it can
+func validatePreferences(req models.UpdatePreferencesRequest) string {
+	if req.PageSize < 0 || req.PageSize > maxPageLimit {
+		return fmt.Sprintf("Invalid page_size, expected 1-%d", maxPageLimit)
+	}
+	if req.Sort != "" && !validSort(req.Sort) {
+		return "Invalid sort, expected -created_at or created_at"
+	}
+	for list, fields := range req.Fields {
+		if _, ok := listRows[list]; !ok {
+			return fmt.Sprintf("Unknown list %s in fields, expected customers or accounts", list)
+		}
+		if unknown := unknownField(list, fields); unknown != "" {
+			return fmt.Sprintf("Unknown %s field %s", list, unknown)
+		}
+	}
+	if req.Timezone != "" {
+		if _, err := loadTimezone(req.Timezone); err != nil {
+			return "Invalid timezone, expected an IANA time zone such as Europe/Berlin"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetCustomersAppliesPreferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	useFakeCustomers(t,
+		models.Customer{ID: 3, Name: "Carol", Email: "carol@example.com", CreatedAt: now},
+		models.Customer{ID: 2, Name: "Bob", Email: "bob@example.com", CreatedAt: now.Add(-time.Minute)},
+		models.Customer{ID: 1, Name: "Alice", Email: "alice@example.com", CreatedAt: now.Add(-2 * time.Minute)},
+	)
+	previous := userPreferences
+	t.Cleanup(func() { userPreferences = previous })
+	userPreferences = func(ctx context.Context, username string) (models.Preferences, error) {
+		return models.Preferences{
+			PageSize: 2,
+			Fields:   map[string][]string{"customers": {"name", "created_at"}},
+			Timezone: "Asia/Tokyo",
+		}, nil
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Set("role", "user")
+	})
+	router.GET("/api/customers", GetCustomers)
+
+	get := func(path string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var rows []map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &rows)
+		return w, rows
+	}
+
+	w, rows := get("/api/customers")
+	if w.Code != http.StatusOK || len(rows) != 2 || w.Header().Get("X-Next-Cursor") == "" {
+		t.Fatalf("Expected a page of 2 customers from page_size, got %d %s", w.Code, w.Body.String())
+	}
+	if len(rows[0]) != 3 || rows[0]["name"] != "Carol" || rows[0]["id"] != float64(3) {
+		t.Errorf("Expected id, name, and created_at only, got %v", rows[0])
+	}
+	if rows[0]["created_at"] != "2024-03-01T21:00:00+09:00" {
+		t.Errorf("Expected created_at in Asia/Tokyo, got %v", rows[0]["created_at"])
+	}
+
+	w, rows = get("/api/customers?limit=3&fields=email&tz=UTC")
+	if w.Code != http.StatusOK || len(rows) != 3 {
+		t.Fatalf("Expected query parameters to override preferences, got %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := rows[0]["email"]; !ok || len(rows[0]) != 2 {
+		t.Errorf("Expected id and email only, got %v", rows[0])
+	}
+
+	for _, query := range []string{"fields=password", "tz=Mars/Olympus", "sort=name"} {
+		if w, _ := get("/api/customers?" + query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestUpdatePreferencesValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := savePreferences
+	t.Cleanup(func() { savePreferences = previous })
+	var saved []models.UpdatePreferencesRequest
+	savePreferences = func(ctx context.Context, username string, req models.UpdatePreferencesRequest) (models.Preferences, error) {
+		saved = append(saved, req)
+		return models.Preferences{PageSize: req.PageSize, Sort: req.Sort, Fields: req.Fields, Timezone: req.Timezone}, nil
+	}
+
+	router := gin.New()
+	router.PUT("/api/me/preferences", UpdatePreferences)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"page_size": 50, "sort": "created_at", "fields": {"accounts": ["name", "status"]}, "timezone": "Europe/Berlin"}`, http.StatusOK},
+		{`{}`, http.StatusOK},
+		{`{"page_size": 5000}`, http.StatusBadRequest},
+		{`{"page_size": -1}`, http.StatusBadRequest},
+		{`{"sort": "name"}`, http.StatusBadRequest},
+		{`{"fields": {"users": ["username"]}}`, http.StatusBadRequest},
+		{`{"fields": {"customers": ["password"]}}`, http.StatusBadRequest},
+		{`{"timezone": "Local"}`, http.StatusBadRequest},
+		{`{"timezone": "Mars/Olympus"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/me/preferences", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+	if len(saved) != 2 {
+		t.Errorf("Expected only the valid preferences to be saved, got %d", len(saved))
+	}
+}
+
+func TestListViewInLocation(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rows := []models.CustomerAccounts{{
+		Customer: models.Customer{ID: 1, CreatedAt: created},
+		Accounts: []models.Account{{ID: 2, CreatedAt: created}},
+	}}
+	location, _ := loadTimezone("America/New_York")
+	listView{location: location}.render(rows)
+
+	if rows[0].CreatedAt.Location() != location || rows[0].Accounts[0].CreatedAt.Location() != location {
+		t.Errorf("Expected nested timestamps in America/New_York, got %v and %v", rows[0].CreatedAt, rows[0].Accounts[0].CreatedAt)
+	}
+	if !rows[0].CreatedAt.Equal(created) {
+		t.Errorf("Expected the same instant, got %v", rows[0].CreatedAt)
+	}
+}
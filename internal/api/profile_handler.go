@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errUserNotFound = errors.New("User not found")
+
+const profileColumns = "id, username, display_name, email, role, organization_id, organization_role, created_at, updated_at, password_changed_at, totp_enabled_at"
+
+func scanProfile(row interface{ Scan(...interface{}) error }) (models.Profile, error) {
+	var profile models.Profile
+	var displayName, email, organizationRole sql.NullString
+	var organizationID sql.NullInt64
+	var createdAt, updatedAt, passwordChangedAt, twoFactorEnabledAt sql.NullTime
+	err := row.Scan(&profile.ID, &profile.Username, &displayName, &email, &profile.Role,
+		&organizationID, &organizationRole, &createdAt, &updatedAt, &passwordChangedAt, &twoFactorEnabledAt)
+	if err == sql.ErrNoRows {
+		return profile, errUserNotFound
+	}
+	profile.DisplayName = nullString(displayName)
+	profile.Email = nullString(email)
+	if organizationID.Valid {
+		id := int(organizationID.Int64)
+		profile.OrganizationID = &id
+	}
+	profile.OrganizationRole = nullString(organizationRole)
+	profile.CreatedAt = createdAt.Time
+	profile.UpdatedAt = nullTime(updatedAt)
+	profile.PasswordChangedAt = nullTime(passwordChangedAt)
+	profile.TwoFactorEnabledAt = nullTime(twoFactorEnabledAt)
+	return profile, err
+}
+
+// userProfile reads a user's profile; tests replace it
+var userProfile = func(ctx context.Context, username string) (models.Profile, error) {
+	return scanProfile(db.Primary(ctx).QueryRow("SELECT "+profileColumns+" FROM users WHERE username = $1", username))
+}
+
+// saveProfile replaces a user's profile fields; tests replace it
+var saveProfile = func(ctx context.Context, username string, req models.UpdateProfileRequest) (models.Profile, error) {
+	return scanProfile(db.Primary(ctx).QueryRow(
+		`UPDATE users SET display_name = NULLIF($2, ''), email = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+		 WHERE username = $1 RETURNING `+profileColumns,
+		username, req.DisplayName, req.Email,
+	))
+}
+
+// userPasswordHash reads a user's password hash; tests replace it
+var userPasswordHash = func(ctx context.Context, username string) (string, error) {
+	var passwordHash string
+	err := db.Primary(ctx).QueryRow("SELECT password_hash FROM users WHERE username = $1", username).Scan(&passwordHash)
+	if err == sql.ErrNoRows {
+		return "", errUserNotFound
+	}
+	return passwordHash, err
+}
+
+// setPassword replaces a user's password hash and revokes every refresh
+// token they hold, so no other session outlives its access token; tests
+// replace it
+var setPassword = func(ctx context.Context, username, passwordHash string) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRowContext(ctx,
+			`UPDATE users SET password_hash = $2, password_changed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			 WHERE username = $1 RETURNING id`,
+			username, passwordHash,
+		).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return errUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userID)
+		return err
+	})
+}
+
+// GetProfile returns the caller's account
+// @Summary      Get my profile
+// @Description  Get the caller's username, display name, email, role, organization, and when they registered, last changed their password, and enabled two-factor authentication
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.Profile
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /me [get]
+// @Security     BearerAuth
+func GetProfile(c *gin.Context) {
+	profile, err := userProfile(c.Request.Context(), c.GetString("username"))
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateProfile replaces the caller's display name and email
+// @Summary      Update my profile
+// @Description  Replace the caller's display name and email; omitted fields are cleared. The username, role, and organization can't be changed here.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        profile  body      models.UpdateProfileRequest  true  "Display name and email"
+// @Success      200      {object}  models.Profile
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /me [put]
+// @Security     BearerAuth
+func UpdateProfile(c *gin.Context) {
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := saveProfile(c.Request.Context(), c.GetString("username"), req)
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save profile"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// ChangePassword replaces the caller's password
+// @Summary      Change my password
+// @Description  Change the caller's password, given the current one. Every refresh token of the caller is revoked, so other sessions end when their access token expires; log in again for a new refresh token. Publishes user.password_changed. Passwords can't be changed with an API key.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        password  body      models.ChangePasswordRequest  true  "Current and new password"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /me/password [post]
+// @Security     BearerAuth
+func ChangePassword(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't change passwords, log in to change yours"})
+		return
+	}
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString("username")
+	currentHash, err := userPasswordHash(ctx, username)
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	// 403 rather than 401, which clients take to mean the token is invalid
+	if !auth.CheckPasswordHash(req.CurrentPassword, currentHash) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	err = setPassword(ctx, username, passwordHash)
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	events.Publish(ctx, events.UserPasswordChanged, gin.H{"username": username, "ip_address": c.ClientIP()})
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousHash, previousSet := userPasswordHash, setPassword
+	t.Cleanup(func() { userPasswordHash, setPassword = previousHash, previousSet })
+
+	currentHash, err := auth.HashPassword("secret1")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	userPasswordHash = func(ctx context.Context, username string) (string, error) {
+		return currentHash, nil
+	}
+	var setHash string
+	setPassword = func(ctx context.Context, username, passwordHash string) error {
+		setHash = passwordHash
+		// Stop before the user.password_changed event, which needs a database
+		return errUserNotFound
+	}
+
+	post := func(body string, apiKey bool) int {
+		router := gin.New()
+		router.POST("/api/me/password", func(c *gin.Context) {
+			c.Set("username", "alice")
+			if apiKey {
+				c.Set("api_key", auth.APIKeyPrincipal{ID: 1, Username: "alice", Scopes: []string{auth.ScopeWrite}})
+			}
+			c.Next()
+		}, ChangePassword)
+		req := httptest.NewRequest(http.MethodPost, "/api/me/password", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		body   string
+		apiKey bool
+		status int
+	}{
+		{`{"current_password": "secret1", "new_password": "secret2"}`, true, http.StatusForbidden},
+		{`{"current_password": "secret1", "new_password": "short"}`, false, http.StatusBadRequest},
+		{`{"current_password": "secret1", "new_password": "secret1"}`, false, http.StatusBadRequest},
+		{`{"current_password": "wrong", "new_password": "secret2"}`, false, http.StatusForbidden},
+		{`{"current_password": "secret1", "new_password": "secret2"}`, false, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		setHash = ""
+		if code := post(tt.body, tt.apiKey); code != tt.status {
+			t.Errorf("Expected status %d for %s, got %d", tt.status, tt.body, code)
+		}
+		if tt.status != http.StatusNotFound && setHash != "" {
+			t.Errorf("Expected the password to be kept for %s", tt.body)
+		}
+	}
+
+	if !auth.CheckPasswordHash("secret2", setHash) {
+		t.Errorf("Expected the new password to be hashed, got %q", setHash)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataQualityTable represents the data quality checks for one table
+type DataQualityTable struct {
+	Table   string                     `json:"table"`
+	Rows    int64                      `json:"rows"`
+	Score   float64                    `json:"score"`
+	Metrics []models.DataQualityMetric `json:"metrics"`
+}
+
+// DataQualityResponse represents the data quality report. Scores run from 0
+// to 100: the share of rows passing each check, averaged over the checks.
+type DataQualityResponse struct {
+	Score      float64            `json:"score"`
+	MeasuredAt *time.Time         `json:"measured_at"`
+	Tables     []DataQualityTable `json:"tables"`
+}
+
+// BuildDataQuality groups metrics by table, in the order given, and scores them
+func BuildDataQuality(metrics []models.DataQualityMetric) DataQualityResponse {
+	response := DataQualityResponse{Score: 100, Tables: []DataQualityTable{}}
+	index := map[string]int{}
+	var passing float64
+	for _, metric := range metrics {
+		i, ok := index[metric.Table]
+		if !ok {
+			i = len(response.Tables)
+			index[metric.Table] = i
+			response.Tables = append(response.Tables, DataQualityTable{Table: metric.Table})
+		}
+		table := &response.Tables[i]
+		table.Metrics = append(table.Metrics, metric)
+		if metric.Total > table.Rows {
+			table.Rows = metric.Total
+		}
+		passing += 1 - metric.Ratio()
+		if response.MeasuredAt == nil || metric.MeasuredAt.After(*response.MeasuredAt) {
+			measuredAt := metric.MeasuredAt
+			response.MeasuredAt = &measuredAt
+		}
+	}
+
+	for i := range response.Tables {
+		table := &response.Tables[i]
+		var tablePassing float64
+		for _, metric := range table.Metrics {
+			tablePassing += 1 - metric.Ratio()
+		}
+		table.Score = roundScore(100 * tablePassing / float64(len(table.Metrics)))
+	}
+	if len(metrics) > 0 {
+		response.Score = roundScore(100 * passing / float64(len(metrics)))
+	}
+	return response
+}
+
+func roundScore(score float64) float64 {
+	return float64(int64(score*10+0.5)) / 10
+}
+
+// GetDataQuality returns the data quality report from the last scoring run
+// @Summary      Get data quality report
+// @Description  Get per-table completeness metrics from the last data quality run: customers missing or with invalid emails, customers without accounts, accounts still named like a placeholder or missing a reference, and rows not updated within DATA_QUALITY_STALE_AFTER. Each table and the report overall get a 0-100 score. Metrics are refreshed every 6 hours when Redis is configured; measured_at is null until the first run.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  DataQualityResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /analytics/data-quality [get]
+// @Security     BearerAuth
+func GetDataQuality(c *gin.Context) {
+	rows, err := db.Analytics(c.Request.Context()).Query(`
+		SELECT table_name, metric, description, failing, total, measured_at
+		FROM data_quality_metrics
+		ORDER BY table_name, metric`,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data quality metrics"})
+		return
+	}
+	defer rows.Close()
+
+	var metrics []models.DataQualityMetric
+	for rows.Next() {
+		var metric models.DataQualityMetric
+		if err := rows.Scan(&metric.Table, &metric.Metric, &metric.Description, &metric.Failing, &metric.Total, &metric.MeasuredAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan data quality metric"})
+			return
+		}
+		metrics = append(metrics, metric)
+	}
+
+	response := BuildDataQuality(metrics)
+	SetDataAsOf(c, response.MeasuredAt)
+	c.JSON(http.StatusOK, response)
+}
+
+// RefreshDataQuality runs the data quality checks immediately
+// @Summary      Refresh data quality report
+// @Description  Run the data quality checks now and return the new report (admin only). Use this after imports or cleanups instead of waiting for the scheduled run.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  DataQualityResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/data-quality/refresh [post]
+// @Security     BearerAuth
+func RefreshDataQuality(c *gin.Context) {
+	metrics, err := jobs.RunDataQuality(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to measure data quality"})
+		return
+	}
+
+	c.JSON(http.StatusOK, BuildDataQuality(metrics))
+}
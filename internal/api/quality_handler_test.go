@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"saas-go-app/internal/models"
+)
+
+func TestBuildDataQuality(t *testing.T) {
+	measuredAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	response := BuildDataQuality([]models.DataQualityMetric{
+		{Table: "accounts", Metric: "default_name", Failing: 5, Total: 10, MeasuredAt: measuredAt},
+		{Table: "accounts", Metric: "stale", Failing: 0, Total: 10, MeasuredAt: measuredAt},
+		{Table: "customers", Metric: "missing_email", Failing: 1, Total: 4, MeasuredAt: measuredAt},
+		{Table: "customers", Metric: "without_accounts", Failing: 0, Total: 0, MeasuredAt: measuredAt},
+	})
+
+	if len(response.Tables) != 2 || response.Tables[0].Table != "accounts" || response.Tables[1].Table != "customers" {
+		t.Fatalf("Expected accounts and customers tables, got %+v", response.Tables)
+	}
+	if response.Tables[0].Score != 75 || response.Tables[0].Rows != 10 {
+		t.Errorf("Expected accounts score 75 over 10 rows, got %.1f over %d", response.Tables[0].Score, response.Tables[0].Rows)
+	}
+	if response.Tables[1].Score != 87.5 {
+		t.Errorf("Expected customers score 87.5, got %.1f", response.Tables[1].Score)
+	}
+	if response.Score != 81.3 {
+		t.Errorf("Expected overall score 81.3, got %.1f", response.Score)
+	}
+	if response.MeasuredAt == nil || !response.MeasuredAt.Equal(measuredAt) {
+		t.Errorf("Expected measured_at %v, got %v", measuredAt, response.MeasuredAt)
+	}
+
+	if empty := BuildDataQuality(nil); empty.Score != 100 || empty.MeasuredAt != nil || len(empty.Tables) != 0 {
+		t.Errorf("Expected a perfect score with no measurements, got %+v", empty)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxQueuedJobs caps the limit parameter of GET /admin/queue/jobs
+const maxQueuedJobs = 1000
+
+// queuedJobStatuses are the statuses a queued job can have
+var queuedJobStatuses = []string{jobs.JobPending, jobs.JobRunning, jobs.JobDone, jobs.JobDead}
+
+// EnqueueJobRequest is a job to add to the Postgres job queue
+type EnqueueJobRequest struct {
+	Type string `json:"type" binding:"required"`
+	// Payload is passed to the job's handler as JSON
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	Queue       string          `json:"queue"`
+	MaxAttempts int             `json:"max_attempts"`
+	// RunAt delays the job, it runs now if omitted
+	RunAt *time.Time `json:"run_at"`
+}
+
+// Seams over the jobs package; tests replace them
+var (
+	listQueuedJobs = jobs.ListQueuedJobs
+	enqueueJob     = func(ctx context.Context, jobType string, payload json.RawMessage, opts jobs.EnqueueOptions) (int64, error) {
+		return jobs.Enqueue(ctx, jobType, payload, opts)
+	}
+	retryQueuedJob = jobs.RetryJob
+	workerTypes    = jobs.WorkerTypes
+)
+
+// ListQueuedJobs returns recent jobs of the Postgres job queue
+// @Summary      List queued jobs
+// @Description  Get the latest jobs of the Postgres job queue, newest first, with their status (pending, running, done, or dead), attempts, and last error (admin only). Done jobs are kept for JOB_RETENTION.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Only jobs with this status: pending, running, done, or dead"
+// @Param        type    query     string  false  "Only jobs of this type"
+// @Param        limit   query     int     false  "Jobs to return (default 100, max 1000)"
+// @Success      200     {array}   jobs.QueuedJob
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/queue/jobs [get]
+// @Security     BearerAuth
+func ListQueuedJobs(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !contains(queuedJobStatuses, status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, expected one of: " + strings.Join(queuedJobStatuses, ", ")})
+		return
+	}
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxQueuedJobs {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit, expected 1-%d", maxQueuedJobs)})
+			return
+		}
+		limit = n
+	}
+
+	queued, err := listQueuedJobs(c.Request.Context(), status, c.Query("type"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queued jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, queued)
+}
+
+// EnqueueJob adds a job to the Postgres job queue
+// @Summary      Queue a job
+// @Description  Add a job to the Postgres job queue for a worker dyno to run (admin only). The type must be handled by a running worker. Failed jobs are retried with backoff up to max_attempts (default 5) and then kept as dead.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        job  body      EnqueueJobRequest  true  "Job to queue"
+// @Success      201  {object}  map[string]int64
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/queue/jobs [post]
+// @Security     BearerAuth
+func EnqueueJob(c *gin.Context) {
+	var req EnqueueJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MaxAttempts < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_attempts"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	types, err := workerTypes(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workers"})
+		return
+	}
+	if !contains(types, req.Type) {
+		if len(types) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No worker is running, start one with heroku ps:scale worker=1"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown job type, expected one of: " + strings.Join(types, ", ")})
+		return
+	}
+
+	if len(req.Payload) == 0 {
+		req.Payload = json.RawMessage("{}")
+	}
+	opts := jobs.EnqueueOptions{Queue: req.Queue, MaxAttempts: req.MaxAttempts}
+	if req.RunAt != nil {
+		opts.RunAt = *req.RunAt
+	}
+	id, err := enqueueJob(ctx, req.Type, req.Payload, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue job"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": id})
+}
+
+// RetryQueuedJob queues a dead job again
+// @Summary      Retry a dead job
+// @Description  Queue a job that ran out of attempts again, to run now with its attempts reset (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  jobs.QueuedJob
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/queue/jobs/{id}/retry [post]
+// @Security     BearerAuth
+func RetryQueuedJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := retryQueuedJob(c.Request.Context(), id)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No dead job with this ID"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
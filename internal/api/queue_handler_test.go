@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEnqueueJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousTypes, previousEnqueue := workerTypes, enqueueJob
+	t.Cleanup(func() { workerTypes, enqueueJob = previousTypes, previousEnqueue })
+	types := []string{"customer:sync"}
+	workerTypes = func(ctx context.Context) ([]string, error) { return types, nil }
+	var gotType, gotPayload string
+	var gotOpts jobs.EnqueueOptions
+	enqueueJob = func(ctx context.Context, jobType string, payload json.RawMessage, opts jobs.EnqueueOptions) (int64, error) {
+		gotType, gotPayload, gotOpts = jobType, string(payload), opts
+		return 12, nil
+	}
+
+	router := gin.New()
+	router.POST("/api/admin/queue/jobs", EnqueueJob)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/queue/jobs", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"type": "customer:sync", "payload": {"customer_id": 42}, "max_attempts": 3}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"id":12`) {
+		t.Fatalf("Expected status %d with the job id, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if gotType != "customer:sync" || gotPayload != `{"customer_id": 42}` || gotOpts.MaxAttempts != 3 {
+		t.Errorf("Expected the job as posted, got %s %s %+v", gotType, gotPayload, gotOpts)
+	}
+
+	if w := post(`{"type": "customer:sync"}`); w.Code != http.StatusCreated || gotPayload != "{}" {
+		t.Errorf("Expected an empty object payload by default, got %q with status %d", gotPayload, w.Code)
+	}
+	for _, body := range []string{`{}`, `{"type": "export:csv"}`, `{"type": "customer:sync", "max_attempts": -1}`} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+
+	types = nil
+	if w := post(`{"type": "customer:sync"}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "No worker") {
+		t.Errorf("Expected a missing worker error, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRetryQueuedJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := retryQueuedJob
+	t.Cleanup(func() { retryQueuedJob = previous })
+	retryQueuedJob = func(ctx context.Context, id int64) (jobs.QueuedJob, error) {
+		if id != 5 {
+			return jobs.QueuedJob{}, jobs.ErrJobNotFound
+		}
+		return jobs.QueuedJob{ID: id, Status: jobs.JobPending}, nil
+	}
+
+	router := gin.New()
+	router.POST("/api/admin/queue/jobs/:id/retry", RetryQueuedJob)
+	for path, want := range map[string]int{
+		"/api/admin/queue/jobs/5/retry": http.StatusOK,
+		"/api/admin/queue/jobs/6/retry": http.StatusNotFound,
+		"/api/admin/queue/jobs/x/retry": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != want {
+			t.Errorf("Expected status %d for %s, got %d", want, path, w.Code)
+		}
+	}
+}
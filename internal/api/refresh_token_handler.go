@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logins return a short-lived JWT (JWT_TTL, default 15m) with a
+// refresh token (REFRESH_TOKEN_TTL, default 720h). POST /auth/refresh
+// exchanges a refresh token for a new JWT and a new refresh token, and the
+// one presented stops working (see migrations/0023_refresh_tokens.up.sql).
+// Presenting it again revokes every token descended from the same login and
+// notifies the user, since only a copy of the token can be behind it.
+
+var (
+	errRefreshTokenInvalid = errors.New("Invalid, revoked, or expired refresh token")
+	errRefreshTokenReused  = errors.New("Refresh token already used, the session has been revoked")
+)
+
+// RefreshRequest carries a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// refreshTokenTTL reads REFRESH_TOKEN_TTL, how long a refresh token can be
+// exchanged (default 720h, 30 days)
+func refreshTokenTTL() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL")); err == nil && value > 0 {
+		return value
+	}
+	return 30 * 24 * time.Hour
+}
+
+// lookupTokenUser reads the ID and role a user's JWTs carry; tests replace it
+var lookupTokenUser = func(ctx context.Context, username string) (int, string, error) {
+	var id int
+	var role string
+	err := db.Primary(ctx).QueryRow("SELECT id, role FROM users WHERE username = $1", username).Scan(&id, &role)
+	return id, role, err
+}
+
+// generateToken returns a JWT for username with their ID and role
+func generateToken(ctx context.Context, username string) (string, error) {
+	id, role, err := lookupTokenUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	return auth.GenerateToken(id, username, role)
+}
+
+// issueTokens returns a JWT for username with a refresh token that starts a
+// new family, for a login from client. When the refresh token can't be
+// stored because the primary is read-only, e.g. during maintenance, the JWT
+// is returned alone, so the login still works and the client signs in again
+// once it expires. Any other failure to store it is an error.
+func issueTokens(ctx context.Context, username string, client loginClient) (LoginResponse, error) {
+	token, err := generateToken(ctx, username)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	response := LoginResponse{Token: token, ExpiresIn: int(auth.AccessTokenTTL().Seconds())}
+	refresh, err := auth.NewRefreshToken()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if err := storeRefreshToken(ctx, username, refresh, client); db.ReadOnlyViolation(err) {
+		tracing.Printf(ctx, "Warning: Database is read-only, issuing the JWT for %s without a refresh token: %v", username, err)
+		return response, nil
+	} else if err != nil {
+		tracing.Printf(ctx, "Error: Failed to store a refresh token for %s: %v", username, err)
+		return LoginResponse{}, err
+	}
+	response.RefreshToken = refresh
+	return response, nil
+}
+
+// storeRefreshToken stores the hash of a refresh token starting a new family
+// for username, and deletes the user's expired ones; tests replace it
+var storeRefreshToken = func(ctx context.Context, username, token string, client loginClient) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1", username).Scan(&userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP", userID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO refresh_tokens (user_id, token_hash, family_id, ip_address, user_agent, expires_at)
+			VALUES ($1, $2, gen_random_uuid(), $3, $4, CURRENT_TIMESTAMP + $5 * INTERVAL '1 second')`,
+			userID, auth.HashRefreshToken(token), client.ip, client.userAgent, refreshTokenTTL().Seconds(),
+		)
+		return err
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new JWT
+// @Summary      Refresh access token
+// @Description  Exchange a refresh token for a new JWT, valid for JWT_TTL (default 15m), and a new refresh token, valid for REFRESH_TOKEN_TTL (default 720h). The refresh token presented is used up. Presenting a used refresh token again revokes every refresh token descended from the same login, returns 401, and sends the user a security notification; clients must therefore not refresh concurrently with the same token.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      RefreshRequest  true  "Refresh token from the login or the previous refresh"
+// @Success      200    {object}  LoginResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	next, err := auth.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	username, err := rotateRefreshToken(c.Request.Context(), req.RefreshToken, next, newLoginClient(c))
+	if errors.Is(err, errRefreshTokenInvalid) || errors.Is(err, errRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	token, err := generateToken(c.Request.Context(), username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{Token: token, RefreshToken: next, ExpiresIn: int(auth.AccessTokenTTL().Seconds())})
+}
+
+// rotateRefreshToken uses up token and stores next in its family, returning
+// the user it belongs to. A token that was used before revokes its family,
+// notifies the user, and returns errRefreshTokenReused. Tests replace it.
+var rotateRefreshToken = func(ctx context.Context, token, next string, client loginClient) (string, error) {
+	var username string
+	var reused bool
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		var id int64
+		var userID int
+		var familyID string
+		var expired bool
+		var usedAt, revokedAt sql.NullTime
+		err := tx.QueryRowContext(ctx, `
+			SELECT rt.id, rt.user_id, u.username, rt.family_id, rt.expires_at < CURRENT_TIMESTAMP, rt.used_at, rt.revoked_at
+			FROM refresh_tokens rt JOIN users u ON u.id = rt.user_id
+			WHERE rt.token_hash = $1
+			FOR UPDATE OF rt`,
+			auth.HashRefreshToken(token),
+		).Scan(&id, &userID, &username, &familyID, &expired, &usedAt, &revokedAt)
+		if err == sql.ErrNoRows || (err == nil && (expired || revokedAt.Valid)) {
+			return errRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if usedAt.Valid {
+			reused = true
+			_, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO refresh_tokens (user_id, token_hash, family_id, parent_id, ip_address, user_agent, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP + $7 * INTERVAL '1 second')`,
+			userID, auth.HashRefreshToken(next), familyID, id, client.ip, client.userAgent, refreshTokenTTL().Seconds(),
+		)
+		return err
+	})
+	if err != nil || !reused {
+		return username, err
+	}
+
+	tracing.Printf(ctx, "Warning: Refresh token of %s reused from %s, revoking its session", username, client.ip)
+	message := "A refresh token was used twice, so that session has been signed out. If you didn't sign out, change your password."
+	if err := db.CreateNotifications(ctx, "security", message, username); err != nil {
+		tracing.Printf(ctx, "Warning: Failed to notify %s of a reused refresh token: %v", username, err)
+	}
+	return username, errRefreshTokenReused
+}
+
+// RevokeRefreshToken signs out the session a refresh token belongs to
+// @Summary      Revoke refresh token
+// @Description  Revoke a refresh token and every refresh token descended from the same login, for signing out. Access tokens already issued stay valid until they expire. Unknown tokens are accepted too, so the response doesn't reveal whether a token exists.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body      RefreshRequest  true  "Refresh token to revoke"
+// @Success      200    {object}  map[string]string
+// @Failure      400    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /auth/revoke [post]
+func RevokeRefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := revokeRefreshFamily(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh token revoked"})
+}
+
+// revokeRefreshFamily revokes the family of token; tests replace it
+var revokeRefreshFamily = func(ctx context.Context, token string) error {
+	_, err := db.Primary(ctx).Exec(
+		"UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE revoked_at IS NULL AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)",
+		auth.HashRefreshToken(token),
+	)
+	return err
+}
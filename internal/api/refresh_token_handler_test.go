@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+	previous, previousLookup := rotateRefreshToken, lookupTokenUser
+	t.Cleanup(func() { rotateRefreshToken, lookupTokenUser = previous, previousLookup })
+	lookupTokenUser = func(ctx context.Context, username string) (int, string, error) { return 7, "admin", nil }
+	var gotNext, gotIP string
+	rotateRefreshToken = func(ctx context.Context, token, next string, client loginClient) (string, error) {
+		gotNext, gotIP = next, client.ip
+		switch token {
+		case "sgr_valid":
+			return "alice", nil
+		case "sgr_used":
+			return "alice", errRefreshTokenReused
+		case "sgr_broken":
+			return "", errors.New("connection reset")
+		}
+		return "", errRefreshTokenInvalid
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshToken)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"refresh_token": "sgr_valid"}`)
+	var response LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d with tokens, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if response.RefreshToken != gotNext || !strings.HasPrefix(gotNext, auth.RefreshTokenPrefix) || gotIP != "203.0.113.7" {
+		t.Errorf("Expected the rotated refresh token %q from the client's IP, got %q from %s", gotNext, response.RefreshToken, gotIP)
+	}
+	if claims, err := auth.ValidateToken(response.Token); err != nil || claims.Username != "alice" || claims.UserID != 7 || claims.Role != "admin" {
+		t.Errorf("Expected a JWT for alice with her ID and role, got %+v, %v", claims, err)
+	}
+	if response.ExpiresIn != 900 {
+		t.Errorf("Expected the token to expire in 900 seconds, got %d", response.ExpiresIn)
+	}
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"refresh_token": "sgr_unknown"}`, http.StatusUnauthorized},
+		{`{"refresh_token": "sgr_used"}`, http.StatusUnauthorized},
+		{`{"refresh_token": "sgr_broken"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if w := post(tt.body); w.Code != tt.want {
+			t.Errorf("Expected status %d for %s, got %d: %s", tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestIssueTokensWithoutRefreshToken(t *testing.T) {
+	_ = auth.InitJWT()
+	previousStore, previousLookup := storeRefreshToken, lookupTokenUser
+	t.Cleanup(func() { storeRefreshToken, lookupTokenUser = previousStore, previousLookup })
+	lookupTokenUser = func(ctx context.Context, username string) (int, string, error) { return 7, "user", nil }
+	storeErr := error(&pgconn.PgError{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"})
+	storeRefreshToken = func(ctx context.Context, username, token string, client loginClient) error {
+		return storeErr
+	}
+
+	// The primary is read-only, so the login gets a JWT without a refresh token
+	response, err := issueTokens(context.Background(), "alice", loginClient{})
+	if err != nil {
+		t.Fatalf("Expected the login to succeed, got %v", err)
+	}
+	if response.Token == "" || response.RefreshToken != "" || response.ExpiresIn != 900 {
+		t.Errorf("Expected a JWT alone, got %+v", response)
+	}
+
+	// Other failures aren't hidden
+	storeErr = errors.New("connection reset by peer")
+	if response, err := issueTokens(context.Background(), "alice", loginClient{}); err == nil {
+		t.Errorf("Expected an error when the refresh token can't be stored, got %+v", response)
+	}
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := revokeRefreshFamily
+	t.Cleanup(func() { revokeRefreshFamily = previous })
+	var revoked string
+	revokeRefreshFamily = func(ctx context.Context, token string) error {
+		revoked = token
+		return nil
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/revoke", RevokeRefreshToken)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/revoke", strings.NewReader(`{"refresh_token": "sgr_valid"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || revoked != "sgr_valid" {
+		t.Errorf("Expected the token revoked with status %d, got %d for %q", http.StatusOK, w.Code, revoked)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+	previousToken, previousFamily := revokeToken, revokeRefreshFamily
+	t.Cleanup(func() { revokeToken, revokeRefreshFamily = previousToken, previousFamily })
+	var revokedJTI, revokedUser, revokedFamily string
+	var revokedUntil time.Time
+	revokeToken = func(ctx context.Context, jti, username string, expiresAt time.Time) error {
+		revokedJTI, revokedUser, revokedUntil = jti, username, expiresAt
+		return nil
+	}
+	revokeRefreshFamily = func(ctx context.Context, token string) error {
+		revokedFamily = token
+		return nil
+	}
+
+	token, err := auth.GenerateToken(1, "alice", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, _ := auth.ValidateToken(token)
+
+	router := gin.New()
+	router.POST("/api/auth/logout", auth.AuthMiddleware(), Logout)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(""); w.Code != http.StatusOK || revokedFamily != "" {
+		t.Errorf("Expected status %d without a refresh token, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if revokedJTI != claims.ID || revokedUser != "alice" || !revokedUntil.Equal(claims.ExpiresAt.Time) {
+		t.Errorf("Expected alice's token %s revoked until it expires, got %s of %s until %v", claims.ID, revokedJTI, revokedUser, revokedUntil)
+	}
+
+	if w := post(`{"refresh_token": "sgr_valid"}`); w.Code != http.StatusOK || revokedFamily != "sgr_valid" {
+		t.Errorf("Expected the refresh token revoked with status %d, got %d for %q", http.StatusOK, w.Code, revokedFamily)
+	}
+
+	revokeToken = func(ctx context.Context, jti, username string, expiresAt time.Time) error {
+		return errors.New("connection reset")
+	}
+	if w := post(""); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d when the token can't be revoked, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/abuse"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var registrationsFlagged = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "registrations_flagged_total",
+	Help: "Registrations held for review, by reason; a registration flagged for several reasons counts once for each.",
+}, []string{"reason"})
+
+var errRegistrationNotPending = errors.New("Pending registration not found")
+
+const registrationColumns = "id, username, email, ip_address, user_agent, status, reasons, user_id, created_at, reviewed_by, reviewed_at"
+
+func scanRegistration(row interface{ Scan(...interface{}) error }) (models.Registration, error) {
+	var registration models.Registration
+	var email, ipAddress, userAgent, reviewedBy sql.NullString
+	var userID sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(&registration.ID, &registration.Username, &email, &ipAddress, &userAgent, &registration.Status,
+		db.Array(&registration.Reasons), &userID, &registration.CreatedAt, &reviewedBy, &reviewedAt)
+	registration.Email = nullString(email)
+	registration.IPAddress = nullString(ipAddress)
+	registration.UserAgent = nullString(userAgent)
+	if userID.Valid {
+		id := int(userID.Int64)
+		registration.UserID = &id
+	}
+	registration.ReviewedBy = nullString(reviewedBy)
+	registration.ReviewedAt = nullTime(reviewedAt)
+	return registration, err
+}
+
+func nullString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+// optionalString returns value, or nil if it is empty
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// countRegistrations returns how many registrations came from ip since a
+// time; tests replace it
+var countRegistrations = func(ctx context.Context, ip string, since time.Time) (int, error) {
+	var count int
+	err := db.Primary(ctx).QueryRow(
+		"SELECT COUNT(*) FROM registrations WHERE ip_address = $1 AND created_at > $2",
+		ip, since.UTC(),
+	).Scan(&count)
+	return count, err
+}
+
+// screenRegistration returns the reasons a registration from c looks
+// automated. Velocity lookups fail open, so a database hiccup never blocks
+// signups.
+func screenRegistration(c *gin.Context, username, email, honeypot string) []string {
+	ctx := c.Request.Context()
+	limit, window := abuse.IPVelocity()
+	recent := 0
+	if limit > 0 {
+		count, err := countRegistrations(ctx, c.ClientIP(), time.Now().Add(-window))
+		if err != nil {
+			tracing.Printf(ctx, "Warning: Failed to count registrations from %s: %v", c.ClientIP(), err)
+		} else {
+			recent = count
+		}
+	}
+	return abuse.Screen(abuse.Registration{Username: username, Email: email, Honeypot: honeypot, RecentFromIP: recent})
+}
+
+// reportFlaggedRegistration counts a flagged registration by reason and
+// publishes registration.flagged
+func reportFlaggedRegistration(c *gin.Context, registration models.Registration) {
+	for _, reason := range registration.Reasons {
+		registrationsFlagged.WithLabelValues(reason).Inc()
+	}
+	events.Publish(c.Request.Context(), events.RegistrationFlagged, gin.H{
+		"registration_id": registration.ID,
+		"username":        registration.Username,
+		"reasons":         registration.Reasons,
+		"ip_address":      c.ClientIP(),
+	})
+}
+
+// recordRegistration logs a registration that created its user. Failures
+// are logged but never fail the registration.
+func recordRegistration(ctx context.Context, registration models.Registration) {
+	_, err := db.Primary(ctx).Exec(
+		`INSERT INTO registrations (username, email, ip_address, user_agent, status, user_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		registration.Username, registration.Email, registration.IPAddress, registration.UserAgent, models.RegistrationCreated, registration.UserID,
+	)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to record registration of %s: %v", registration.Username, err)
+	}
+}
+
+// holdRegistration stores a flagged registration for review with the hash of
+// the password its user gets on approval, returning errUsernameTaken if the
+// username is in use; tests replace it
+var holdRegistration = func(ctx context.Context, registration models.Registration, passwordHash string) (models.Registration, error) {
+	var taken bool
+	err := db.Primary(ctx).QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)", registration.Username).Scan(&taken)
+	if err != nil {
+		return registration, err
+	}
+	if taken {
+		return registration, errUsernameTaken
+	}
+	return scanRegistration(db.Primary(ctx).QueryRow(
+		`INSERT INTO registrations (username, email, ip_address, user_agent, status, reasons, password_hash)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+registrationColumns,
+		registration.Username, registration.Email, registration.IPAddress, registration.UserAgent,
+		models.RegistrationPending, registration.Reasons, passwordHash,
+	))
+}
+
+// rejectRegistration stores a flagged signup as rejected, with the reasons it
+// was flagged; tests replace it
+var rejectRegistration = func(ctx context.Context, registration models.Registration) (models.Registration, error) {
+	return scanRegistration(db.Primary(ctx).QueryRow(
+		`INSERT INTO registrations (username, email, ip_address, user_agent, status, reasons)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+registrationColumns,
+		registration.Username, registration.Email, registration.IPAddress, registration.UserAgent,
+		models.RegistrationRejected, registration.Reasons,
+	))
+}
+
+// GetRegistrations lists self-registrations for review
+// @Summary      List registrations
+// @Description  List registrations made through POST /auth/register, newest first (admin only). Defaults to the pending ones, flagged as likely spam and held until approved or rejected, with the reasons they were flagged.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status  query     string  false  "Filter by status (default pending)"  Enums(pending, created, approved, rejected, all)
+// @Param        limit   query     int     false  "Maximum registrations (1-500, default 100)"
+// @Success      200     {array}   models.Registration
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/registrations [get]
+// @Security     BearerAuth
+func GetRegistrations(c *gin.Context) {
+	status := c.DefaultQuery("status", models.RegistrationPending)
+	switch status {
+	case models.RegistrationPending, models.RegistrationCreated, models.RegistrationApproved, models.RegistrationRejected, "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT "+registrationColumns+" FROM registrations WHERE $1 = 'all' OR status = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		status, limit,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch registrations"})
+		return
+	}
+	defer rows.Close()
+
+	registrations := []models.Registration{}
+	for rows.Next() {
+		registration, err := scanRegistration(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan registration"})
+			return
+		}
+		registrations = append(registrations, registration)
+	}
+
+	c.JSON(http.StatusOK, registrations)
+}
+
+// ApproveRegistration creates the user of a registration held for review
+// @Summary      Approve registration
+// @Description  Create the user of a pending registration with the password it was made with, and publish user.registered (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Registration ID"
+// @Success      200  {object}  models.Registration
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/registrations/{id}/approve [post]
+// @Security     BearerAuth
+func ApproveRegistration(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	registration, err := approveRegistration(ctx, id, c.GetString("username"))
+	switch {
+	case errors.Is(err, errRegistrationNotPending):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve registration"})
+		return
+	}
+
+	data := gin.H{"user_id": registration.UserID, "username": registration.Username, "registration_id": registration.ID}
+	if registration.IPAddress != nil {
+		data["ip_address"] = *registration.IPAddress
+	}
+	events.Publish(ctx, events.UserRegistered, data)
+	c.JSON(http.StatusOK, registration)
+}
+
+// approveRegistration creates the user of pending registration id and marks
+// it approved by reviewer; tests replace it
+var approveRegistration = func(ctx context.Context, id int, reviewer string) (models.Registration, error) {
+	var registration models.Registration
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		var passwordHash string
+		var email sql.NullString
+		err := tx.QueryRowContext(ctx,
+			"SELECT username, email, password_hash FROM registrations WHERE id = $1 AND status = $2 FOR UPDATE",
+			id, models.RegistrationPending,
+		).Scan(&registration.Username, &email, &passwordHash)
+		if err == sql.ErrNoRows {
+			return errRegistrationNotPending
+		}
+		if err != nil {
+			return err
+		}
+
+		var userID int
+		err = tx.QueryRowContext(ctx,
+			"INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4) RETURNING id",
+			registration.Username, passwordHash, email, models.RoleTenant,
+		).Scan(&userID)
+		if db.UniqueViolation(err) != "" {
+			return errUsernameTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		registration, err = scanRegistration(tx.QueryRowContext(ctx,
+			`UPDATE registrations SET status = $2, user_id = $3, password_hash = NULL, reviewed_by = $4, reviewed_at = CURRENT_TIMESTAMP
+			 WHERE id = $1 RETURNING `+registrationColumns,
+			id, models.RegistrationApproved, userID, reviewer,
+		))
+		return err
+	})
+	return registration, err
+}
+
+// RejectRegistration discards a registration held for review
+// @Summary      Reject registration
+// @Description  Reject a pending registration without creating its user (admin only). The registration is kept, without its password hash, so it still counts towards its IP address's registrations.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Registration ID"
+// @Success      200  {object}  models.Registration
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/registrations/{id}/reject [post]
+// @Security     BearerAuth
+func RejectRegistration(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration ID"})
+		return
+	}
+
+	registration, err := scanRegistration(db.Primary(c.Request.Context()).QueryRow(
+		`UPDATE registrations SET status = $2, password_hash = NULL, reviewed_by = $3, reviewed_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND status = $4 RETURNING `+registrationColumns,
+		id, models.RegistrationRejected, c.GetString("username"), models.RegistrationPending,
+	))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": errRegistrationNotPending.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject registration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, registration)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/abuse"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterHoldsFlaggedRegistrations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("REGISTRATION_IP_LIMIT", "3")
+	previousCount, previousHold := countRegistrations, holdRegistration
+	t.Cleanup(func() { countRegistrations, holdRegistration = previousCount, previousHold })
+
+	recent := 0
+	var countErr error
+	countRegistrations = func(ctx context.Context, ip string, since time.Time) (int, error) {
+		return recent, countErr
+	}
+	var held models.Registration
+	holdRegistration = func(ctx context.Context, registration models.Registration, passwordHash string) (models.Registration, error) {
+		held = registration
+		if passwordHash == "" || passwordHash == "secret1" {
+			t.Errorf("Expected a password hash, got %q", passwordHash)
+		}
+		// Stop before the user.registered event, which needs a database
+		return registration, errUsernameTaken
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/register", Register)
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "spambot/1.0")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		body    string
+		recent  int
+		reasons []string
+	}{
+		{`{"username": "bot", "password": "secret1", "website": "https://spam.example"}`, 0, []string{abuse.ReasonHoneypot}},
+		{`{"username": "bot", "password": "secret1", "email": "bot@mailinator.com"}`, 0, []string{abuse.ReasonDisposableEmail}},
+		{`{"username": "bot", "password": "secret1"}`, 3, []string{abuse.ReasonIPVelocity}},
+	}
+	for _, tt := range tests {
+		recent, held = tt.recent, models.Registration{}
+		if code := post(tt.body); code != http.StatusConflict {
+			t.Errorf("Expected the held registration's status %d, got %d", http.StatusConflict, code)
+		}
+		if fmt.Sprint(held.Reasons) != fmt.Sprint(tt.reasons) {
+			t.Errorf("Expected %s to be held for %v, got %v", tt.body, tt.reasons, held.Reasons)
+		}
+		if held.UserAgent == nil || *held.UserAgent != "spambot/1.0" {
+			t.Errorf("Expected the user agent to be kept, got %v", held.UserAgent)
+		}
+	}
+
+	if code := post(`{"username": "bot", "password": "secret1", "email": "not-an-email"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid email, got %d", http.StatusBadRequest, code)
+	}
+
+	// A failed velocity lookup lets the registration through to the other checks
+	recent, countErr, held = 10, errors.New("connection reset"), models.Registration{}
+	post(`{"username": "bot", "password": "secret1", "website": "x"}`)
+	if fmt.Sprint(held.Reasons) != fmt.Sprint([]string{abuse.ReasonHoneypot}) {
+		t.Errorf("Expected only the honeypot to flag the registration, got %v", held.Reasons)
+	}
+}
+
+func TestGetRegistrationsValidatesStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/registrations", GetRegistrations)
+
+	for _, query := range []string{"?status=spam", "?limit=0", "?limit=501"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/registrations"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetSchema(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := describeSchema
+	t.Cleanup(func() { describeSchema = previous })
+	var failure error
+	describeSchema = func(ctx context.Context) ([]db.TableSchema, error) {
+		rows := int64(1200)
+		return []db.TableSchema{{
+			Name:        "accounts",
+			RowEstimate: &rows,
+			Columns:     []db.ColumnSchema{{Name: "id", Type: "integer", Default: "nextval('accounts_id_seq'::regclass)"}},
+			Indexes:     []db.IndexSchema{{Name: "accounts_pkey", Columns: []string{"id"}, Unique: true, Primary: true, Valid: true}},
+		}}, failure
+	}
+
+	router := gin.New()
+	router.GET("/api/admin/schema", GetSchema)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/schema", nil))
+		return w
+	}
+
+	w := get()
+	var response SchemaResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d with tables, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if len(response.Tables) != 1 || *response.Tables[0].RowEstimate != 1200 || response.Tables[0].Indexes[0].Columns[0] != "id" {
+		t.Errorf("Expected the accounts table, got %+v", response.Tables)
+	}
+
+	failure = errors.New("connection reset")
+	if w := get(); w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
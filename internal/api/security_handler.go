@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Every login attempt records the client it came from (see
+// migrations/0019_login_audit.up.sql). A successful login from a device, or
+// a country, the user hasn't logged in from before gets them an in-app
+// notification and publishes user.new_login, for an email webhook to deliver.
+// The first recorded login only sets the baseline.
+
+// maxRecentLogins caps the limit parameter of GET /me/security
+const maxRecentLogins = 100
+
+// loginClient is where a login attempt came from
+type loginClient struct {
+	ip          string
+	userAgent   string
+	fingerprint string
+	country     string
+}
+
+// newLoginClient describes the client of the request c
+func newLoginClient(c *gin.Context) loginClient {
+	userAgent := c.Request.UserAgent()
+	return loginClient{
+		ip:          c.ClientIP(),
+		userAgent:   userAgent,
+		fingerprint: auth.DeviceFingerprint(c.GetHeader("X-Device-ID"), userAgent, c.GetHeader("Accept-Language")),
+		country:     loginCountry(c),
+	}
+}
+
+// countryCode matches ISO 3166-1 alpha-2 codes
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// loginCountry reads the client's country from the header named by
+// LOGIN_COUNTRY_HEADER, such as CF-IPCountry behind Cloudflare. The app has no
+// IP geolocation of its own, so without the header countries aren't recorded.
+// Only a proxy that overwrites the header should be trusted with it.
+func loginCountry(c *gin.Context) string {
+	header := os.Getenv("LOGIN_COUNTRY_HEADER")
+	if header == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(c.GetHeader(header)))
+	// XX is Cloudflare's unknown country
+	if !countryCode.MatchString(country) || country == "XX" {
+		return ""
+	}
+	return country
+}
+
+// noticeNewLogin notifies username of a successful login from client if it
+// comes from a new device or country. Failures are logged and never block
+// the login.
+func noticeNewLogin(ctx context.Context, username string, client loginClient) {
+	var known, sameDevice, knownCountries, sameCountry int
+	err := db.Primary(ctx).QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE device_fingerprint = $2), COUNT(country), COUNT(*) FILTER (WHERE country = $3)
+		FROM login_attempts WHERE username = $1 AND success AND device_fingerprint IS NOT NULL`,
+		username, client.fingerprint, client.country,
+	).Scan(&known, &sameDevice, &knownCountries, &sameCountry)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to check the login history of %s: %v", username, err)
+		return
+	}
+	newDevice := known > 0 && sameDevice == 0
+	newCountry := client.country != "" && knownCountries > 0 && sameCountry == 0
+	if !newDevice && !newCountry {
+		return
+	}
+
+	if err := db.CreateNotifications(ctx, "security", newLoginMessage(client, newDevice, newCountry), username); err != nil {
+		tracing.Printf(ctx, "Warning: Failed to notify %s of a new login: %v", username, err)
+	}
+	events.Publish(ctx, events.UserNewLogin, gin.H{
+		"username":           username,
+		"ip_address":         client.ip,
+		"user_agent":         client.userAgent,
+		"device_fingerprint": client.fingerprint,
+		"country":            client.country,
+		"new_device":         newDevice,
+		"new_country":        newCountry,
+	})
+}
+
+// newLoginMessage is the notification of a login from client
+func newLoginMessage(client loginClient, newDevice, newCountry bool) string {
+	var from []string
+	if newDevice {
+		from = append(from, "a new device")
+	}
+	if newCountry {
+		from = append(from, "a new country ("+client.country+")")
+	}
+	agent := client.userAgent
+	if agent == "" {
+		agent = "unknown client"
+	}
+	return fmt.Sprintf("New login from %s: %s at %s. If this wasn't you, change your password.",
+		strings.Join(from, " and "), agent, client.ip)
+}
+
+// GetMySecurity returns the caller's recent logins
+// @Summary      Get my login activity
+// @Description  Get the caller's latest successful logins, newest first, with the IP address, user agent, device fingerprint, and country (when LOGIN_COUNTRY_HEADER is set) of each, plus the number of failed logins in the past 30 days. A login from a new device or country also sends a security notification.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        limit  query     int  false  "Logins to return (default 20, max 100)"
+// @Success      200    {object}  models.SecurityOverview
+// @Failure      400    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /me/security [get]
+// @Security     BearerAuth
+func GetMySecurity(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxRecentLogins {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit, expected 1-%d", maxRecentLogins)})
+			return
+		}
+		limit = n
+	}
+
+	overview, err := loginActivity(c.Request.Context(), c.GetString("username"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch login activity"})
+		return
+	}
+	c.JSON(http.StatusOK, overview)
+}
+
+// loginActivity reads a user's latest limit successful logins and counts
+// their failed logins of the past 30 days; tests replace it
+var loginActivity = func(ctx context.Context, username string, limit int) (models.SecurityOverview, error) {
+	overview := models.SecurityOverview{RecentLogins: []models.LoginRecord{}}
+	rows, err := db.Primary(ctx).Query(`
+		SELECT COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(device_fingerprint, ''), COALESCE(country, ''), created_at
+		FROM login_attempts WHERE username = $1 AND success
+		ORDER BY created_at DESC LIMIT $2`,
+		username, limit,
+	)
+	if err != nil {
+		return overview, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var login models.LoginRecord
+		if err := rows.Scan(&login.IPAddress, &login.UserAgent, &login.DeviceFingerprint, &login.Country, &login.CreatedAt); err != nil {
+			return overview, err
+		}
+		overview.RecentLogins = append(overview.RecentLogins, login)
+	}
+	if err := rows.Err(); err != nil {
+		return overview, err
+	}
+
+	err = db.Primary(ctx).QueryRow(
+		"SELECT COUNT(*) FROM login_attempts WHERE username = $1 AND NOT success AND created_at > NOW() - INTERVAL '30 days'",
+		username,
+	).Scan(&overview.FailedLogins)
+	return overview, err
+}
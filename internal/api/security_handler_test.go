@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetMySecurity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := loginActivity
+	t.Cleanup(func() { loginActivity = previous })
+	var gotUsername string
+	var gotLimit int
+	loginActivity = func(ctx context.Context, username string, limit int) (models.SecurityOverview, error) {
+		gotUsername, gotLimit = username, limit
+		return models.SecurityOverview{
+			RecentLogins: []models.LoginRecord{{IPAddress: "203.0.113.7", UserAgent: "curl/8.4.0", DeviceFingerprint: "abc", Country: "DE", CreatedAt: time.Now()}},
+			FailedLogins: 2,
+		}, nil
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("username", "alice") })
+	router.GET("/api/me/security", GetMySecurity)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/me/security")
+	var overview models.SecurityOverview
+	if err := json.Unmarshal(w.Body.Bytes(), &overview); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gotUsername != "alice" || gotLimit != 20 || len(overview.RecentLogins) != 1 || overview.FailedLogins != 2 {
+		t.Errorf("Unexpected overview %+v for %s, limit %d", overview, gotUsername, gotLimit)
+	}
+
+	if w := get("/api/me/security?limit=5"); w.Code != http.StatusOK || gotLimit != 5 {
+		t.Errorf("Expected limit 5, got %d with status %d", gotLimit, w.Code)
+	}
+	for _, limit := range []string{"0", "101", "all"} {
+		if w := get("/api/me/security?limit=" + limit); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for limit %s, got %d", http.StatusBadRequest, limit, w.Code)
+		}
+	}
+}
+
+func TestLoginClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := func(headers map[string]string) loginClient {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		for name, value := range headers {
+			c.Request.Header.Set(name, value)
+		}
+		return newLoginClient(c)
+	}
+
+	headers := map[string]string{"User-Agent": "curl/8.4.0", "CF-IPCountry": "de"}
+	if got := client(headers); got.country != "" || got.userAgent != "curl/8.4.0" || got.fingerprint == "" {
+		t.Errorf("Expected no country without LOGIN_COUNTRY_HEADER, got %+v", got)
+	}
+	t.Setenv("LOGIN_COUNTRY_HEADER", "CF-IPCountry")
+	if got := client(headers); got.country != "DE" {
+		t.Errorf("Expected country DE, got %q", got.country)
+	}
+	for _, value := range []string{"XX", "T1", "Germany"} {
+		if got := client(map[string]string{"CF-IPCountry": value}); got.country != "" {
+			t.Errorf("Expected no country for %s, got %q", value, got.country)
+		}
+	}
+}
+
+func TestNewLoginMessage(t *testing.T) {
+	client := loginClient{ip: "203.0.113.7", userAgent: "curl/8.4.0", country: "DE"}
+	message := newLoginMessage(client, true, true)
+	if !strings.Contains(message, "a new device and a new country (DE)") || !strings.Contains(message, "curl/8.4.0 at 203.0.113.7") {
+		t.Errorf("Unexpected message %q", message)
+	}
+	if message := newLoginMessage(loginClient{ip: "203.0.113.7"}, true, false); !strings.Contains(message, "new device: unknown client") {
+		t.Errorf("Unexpected message %q", message)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// reseed is db.ClearAndReseed, replaceable in tests
+	reseed = db.ClearAndReseed
+
+	// seedMu makes checking for a running seed and starting one atomic
+	seedMu sync.Mutex
+)
+
+// ReseedDatabase clears all customers and accounts and seeds them again in the background
+// @Summary      Reseed database
+// @Description  Delete all customers and accounts, with their history, and seed them again in the background, like make reseed: the demo profile, or performance data sized by the SEED_* config vars when SEED_PERFORMANCE_DATA=true. Returns 202 with the job ID; poll status_url or stream /admin/jobs/{id}/events for progress, and stop it with /admin/jobs/{id}/cancel. Returns 409 while another seed job is running (admin only).
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      202  {object}  AsyncAccepted
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/seed [post]
+// @Security     BearerAuth
+func ReseedDatabase(c *gin.Context) {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+
+	for _, snapshot := range progress.List() {
+		if snapshot.Kind == "seed" && !snapshot.Finished() {
+			c.JSON(http.StatusConflict, gin.H{"error": "A seed job is already running", "job_id": snapshot.JobID})
+			return
+		}
+	}
+
+	job := progress.Start("seed")
+	id := job.Snapshot().JobID
+	log.Printf("Reseeding database in the background (job %s), requested by %s", id, c.GetString("username"))
+	ctx := job.Context(context.Background())
+	go func() {
+		err := reseed(ctx, job.Update)
+		if err != nil {
+			log.Printf("Warning: Failed to clear and reseed database: %v", err)
+		}
+		job.Finish(err)
+	}()
+
+	statusURL := "/api/admin/jobs/" + id
+	c.Header("Location", statusURL)
+	c.JSON(http.StatusAccepted, AsyncAccepted{JobID: id, Status: progress.StatusRunning, StatusURL: statusURL})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/progress"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReseedDatabase(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	reseed = func(ctx context.Context, report db.ProgressFunc) error {
+		report("customers", 5, 5)
+		<-release
+		return nil
+	}
+	t.Cleanup(func() { reseed = db.ClearAndReseed })
+
+	router := gin.New()
+	router.POST("/api/admin/seed", ReseedDatabase)
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/seed", nil))
+		return w
+	}
+
+	w := post()
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var accepted AsyncAccepted
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	job, ok := progress.Get(accepted.JobID)
+	if !ok || accepted.StatusURL != "/api/admin/jobs/"+accepted.JobID {
+		t.Fatalf("Expected a tracked job with its status URL, got %+v", accepted)
+	}
+
+	if w := post(); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d while the seed runs, got %d", http.StatusConflict, w.Code)
+	}
+
+	updates, cancel := job.Subscribe()
+	defer cancel()
+	close(release)
+	var last progress.Snapshot
+	for snapshot := range updates {
+		last = snapshot
+	}
+	if last.Status != progress.StatusCompleted {
+		t.Errorf("Expected the job to complete, got %+v", last)
+	}
+}
+
+func TestCancelReseed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reseed = func(ctx context.Context, report db.ProgressFunc) error {
+		<-ctx.Done()
+		return fmt.Errorf("seed cancelled: %w", ctx.Err())
+	}
+	t.Cleanup(func() { reseed = db.ClearAndReseed })
+
+	router := gin.New()
+	router.POST("/api/admin/seed", ReseedDatabase)
+	router.POST("/api/admin/jobs/:id/cancel", CancelJob)
+	post := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w
+	}
+
+	var accepted AsyncAccepted
+	if err := json.Unmarshal(post("/api/admin/seed").Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	job, _ := progress.Get(accepted.JobID)
+	updates, cancel := job.Subscribe()
+	defer cancel()
+
+	if w := post("/api/admin/jobs/" + accepted.JobID + "/cancel"); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var last progress.Snapshot
+	for snapshot := range updates {
+		last = snapshot
+	}
+	if last.Status != progress.StatusCancelled {
+		t.Errorf("Expected the job to be cancelled, got %+v", last)
+	}
+
+	if w := post("/api/admin/jobs/" + accepted.JobID + "/cancel"); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a finished job, got %d", http.StatusConflict, w.Code)
+	}
+	if w := post("/api/admin/jobs/seed-missing/cancel"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown job, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SLAResponse represents the compliance of accounts with each SLA rule
+type SLAResponse struct {
+	// Days is the reporting window for completed transitions
+	Days  int                    `json:"days"`
+	Since time.Time              `json:"since"`
+	Rules []models.SLACompliance `json:"rules"`
+}
+
+// GetSLACompliance summarizes compliance with the account lifecycle SLAs
+// @Summary      Get SLA compliance
+// @Description  Get each account lifecycle SLA rule with the accounts in its from_status that are within the deadline and past it, the accounts that moved on to its to_status within the last days and how many did in time, the compliance percentage (on-time completions over completions and breaches, null when there are none), and the 20 oldest open breaches recorded by the scheduler
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        days  query     int  false  "Reporting window in days for completed transitions (1-365, default 30)"
+// @Success      200   {object}  SLAResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /analytics/sla [get]
+// @Security     BearerAuth
+func GetSLACompliance(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days, expected 1-365"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rules, err := jobs.SLARules(db.Analytics(ctx))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SLA rules"})
+		return
+	}
+
+	response := SLAResponse{Days: days, Since: time.Now().UTC().AddDate(0, 0, -days), Rules: []models.SLACompliance{}}
+	for _, rule := range rules {
+		compliance, err := jobs.SLACompliance(ctx, rule, response.Since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to measure SLA compliance"})
+			return
+		}
+		response.Rules = append(response.Rules, compliance)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSLARule defines an account lifecycle SLA
+// @Summary      Create SLA rule
+// @Description  Define an account lifecycle SLA: accounts in from_status must move to to_status within within_days (1-365), e.g. pending accounts activate within 7 days (admin only). The scheduler records accounts past the deadline as breaches and notifies SLA_NOTIFY_USERS.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        rule  body      models.CreateSLARuleRequest  true  "SLA rule"
+// @Success      201   {object}  models.SLARule
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /admin/sla-rules [post]
+// @Security     BearerAuth
+func CreateSLARule(c *gin.Context) {
+	var req models.CreateSLARuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.FromStatus, req.ToStatus = strings.TrimSpace(req.FromStatus), strings.TrimSpace(req.ToStatus)
+	if req.FromStatus == "" || req.ToStatus == "" || req.FromStatus == req.ToStatus {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from_status and to_status must be two different statuses"})
+		return
+	}
+
+	rule := models.SLARule{Name: req.Name, FromStatus: req.FromStatus, ToStatus: req.ToStatus, WithinDays: req.WithinDays, CreatedBy: c.GetString("username")}
+	err := db.Primary(c.Request.Context()).QueryRow(
+		"INSERT INTO sla_rules (name, from_status, to_status, within_days, created_by) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		rule.Name, rule.FromStatus, rule.ToStatus, rule.WithinDays, rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create SLA rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetSLARules lists the account lifecycle SLAs
+// @Summary      List SLA rules
+// @Description  List the account lifecycle SLA rules (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.SLARule
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/sla-rules [get]
+// @Security     BearerAuth
+func GetSLARules(c *gin.Context) {
+	rules, err := jobs.SLARules(db.Primary(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch SLA rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// DeleteSLARule removes an account lifecycle SLA and its breaches
+// @Summary      Delete SLA rule
+// @Description  Delete an SLA rule with the breaches recorded for it (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "SLA rule ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/sla-rules/{id} [delete]
+// @Security     BearerAuth
+func DeleteSLARule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid SLA rule ID"})
+		return
+	}
+
+	result, err := db.Primary(c.Request.Context()).Exec("DELETE FROM sla_rules WHERE id = $1", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete SLA rule"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "SLA rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "SLA rule deleted successfully"})
+}
+
+// EvaluateSLAs checks the SLA rules immediately
+// @Summary      Evaluate SLA rules
+// @Description  Check the SLA rules now instead of waiting for the scheduled run (admin only): accounts past a deadline are recorded as breaches, new ones notify SLA_NOTIFY_USERS, and breaches of accounts that left the status are resolved. Returns how many breaches are new.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]int
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/sla-rules/evaluate [post]
+// @Security     BearerAuth
+func EvaluateSLAs(c *gin.Context) {
+	added, err := jobs.EvaluateSLAs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate SLA rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"new_breaches": added})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateSLARuleValidates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/admin/sla-rules", CreateSLARule)
+
+	for _, body := range []string{
+		`{"name": "Activation", "from_status": "pending", "to_status": "pending", "within_days": 7}`,
+		`{"name": "Activation", "from_status": " ", "to_status": "active", "within_days": 7}`,
+		`{"name": "Activation", "from_status": "pending", "to_status": "active", "within_days": 0}`,
+		`{"name": "Activation", "from_status": "pending", "to_status": "active", "within_days": 366}`,
+		`{"from_status": "pending", "to_status": "active", "within_days": 7}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/sla-rules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+}
+
+func TestGetSLAComplianceValidatesDays(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/analytics/sla", GetSLACompliance)
+
+	for _, query := range []string{"?days=0", "?days=366", "?days=week"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/analytics/sla"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
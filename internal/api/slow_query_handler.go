@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSlowQueries caps the limit parameter of GET /admin/db/slow-queries
+const maxSlowQueries = 100
+
+// topStatements reads pg_stat_statements; tests replace it
+var topStatements = db.TopStatements
+
+// SlowQueriesResponse lists the statements that took the most time
+type SlowQueriesResponse struct {
+	// OrderBy is total or mean execution time
+	OrderBy    string             `json:"order_by"`
+	Statements []db.StatementStat `json:"statements"`
+}
+
+// GetSlowQueries returns the statements that took the most time from pg_stat_statements
+// @Summary      Slowest queries
+// @Description  List the statements of the primary's database that took the most total or mean execution time since the statistics were last reset, from pg_stat_statements (admin only). Query text is normalized, with constants replaced by $1, $2, and so on. Returns 404 when the extension isn't installed.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        order_by  query     string  false  "total (default) or mean execution time"
+// @Param        limit     query     int     false  "Number of statements, 1-100 (default 10)"
+// @Success      200       {object}  SlowQueriesResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/db/slow-queries [get]
+// @Security     BearerAuth
+func GetSlowQueries(c *gin.Context) {
+	orderBy := c.DefaultQuery("order_by", db.OrderByTotalTime)
+	if orderBy != db.OrderByTotalTime && orderBy != db.OrderByMeanTime {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order_by, expected total or mean"})
+		return
+	}
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSlowQueries {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit, expected 1-%d", maxSlowQueries)})
+			return
+		}
+		limit = n
+	}
+
+	statements, err := topStatements(c.Request.Context(), orderBy, limit)
+	if errors.Is(err, db.ErrStatementsUnavailable) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pg_stat_statements is not installed, run CREATE EXTENSION pg_stat_statements"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch query statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, SlowQueriesResponse{OrderBy: orderBy, Statements: statements})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetSlowQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := topStatements
+	t.Cleanup(func() { topStatements = previous })
+	var gotOrder string
+	var gotLimit int
+	installed := true
+	topStatements = func(ctx context.Context, order string, limit int) ([]db.StatementStat, error) {
+		if !installed {
+			return nil, db.ErrStatementsUnavailable
+		}
+		gotOrder, gotLimit = order, limit
+		return []db.StatementStat{{QueryID: 7, Query: "SELECT * FROM accounts WHERE customer_id = $1", Calls: 1200, MeanTimeMs: 4.2}}, nil
+	}
+
+	router := gin.New()
+	router.GET("/api/admin/db/slow-queries", GetSlowQueries)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/db/slow-queries"+query, nil))
+		return w
+	}
+
+	w := get("")
+	var response SlowQueriesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d with statements, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if gotOrder != db.OrderByTotalTime || gotLimit != 10 || response.OrderBy != "total" || len(response.Statements) != 1 || response.Statements[0].Calls != 1200 {
+		t.Errorf("Expected the top 10 by total time, got %s %d and %+v", gotOrder, gotLimit, response)
+	}
+
+	if w := get("?order_by=mean&limit=3"); w.Code != http.StatusOK || gotOrder != db.OrderByMeanTime || gotLimit != 3 {
+		t.Errorf("Expected the top 3 by mean time, got %d with %s %d", w.Code, gotOrder, gotLimit)
+	}
+	for _, query := range []string{"?order_by=calls", "?limit=0", "?limit=101", "?limit=many"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+
+	installed = false
+	if w := get(""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d without the extension, got %d", http.StatusNotFound, w.Code)
+	}
+}
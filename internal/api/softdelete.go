@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deleting a customer or account only sets its deleted_at (see
+// migrations/0016_soft_delete.up.sql), so it can be restored with
+// POST /customers/{id}/restore or /accounts/{id}/restore. Deleted rows are
+// left out of lists and lookups; admins can list them with include_deleted
+// and remove them for good with hard.
+
+// parseIncludeDeleted reads the include_deleted list parameter. It writes a
+// 400 or 403 response and returns false if the value isn't a boolean or the
+// caller isn't an admin.
+func parseIncludeDeleted(c *gin.Context) (bool, bool) {
+	return parseAdminFlag(c, "include_deleted", "Only admins can list deleted records")
+}
+
+// parseHardDelete reads the hard parameter of delete endpoints, like
+// parseIncludeDeleted
+func parseHardDelete(c *gin.Context) (bool, bool) {
+	return parseAdminFlag(c, "hard", "Only admins can delete records permanently")
+}
+
+// parseAdminFlag reads the boolean query parameter name, which only admins may
+// set to true. It writes a 400 response, or a 403 response with forbidden,
+// and returns false if the caller can't have it.
+func parseAdminFlag(c *gin.Context, name, forbidden string) (bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return false, true
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ", expected true or false"})
+		return false, false
+	}
+	if flag && callerRole(c) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": forbidden})
+		return false, false
+	}
+	return flag, true
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listStatements returns a customer's statements, newest first; tests
+// replace it
+var listStatements = func(ctx context.Context, customerID int) ([]models.Statement, error) {
+	rows, err := db.Primary(ctx).Query(
+		`SELECT id, customer_id, period, account_changes, api_calls, invoice_totals, generated_at
+		 FROM customer_statements WHERE customer_id = $1 ORDER BY period DESC`,
+		customerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := []models.Statement{}
+	for rows.Next() {
+		var statement models.Statement
+		var period time.Time
+		var totals []byte
+		if err := rows.Scan(&statement.ID, &statement.CustomerID, &period, &statement.AccountChanges, &statement.APICalls, &totals, &statement.GeneratedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(totals, &statement.InvoiceTotals); err != nil {
+			return nil, err
+		}
+		statement.Period = period.Format(jobs.StatementPeriodLayout)
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
+
+// statementDocument returns a stored statement document; tests replace it
+var statementDocument = jobs.StatementDocument
+
+// GetCustomerStatements lists a customer's monthly statements
+// @Summary      List customer statements
+// @Description  List a customer's monthly statements, newest first, with their totals but not their documents (admin only). Statements for the previous month are generated daily by the statements:generate job.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Customer ID or UUID"
+// @Success      200  {array}   models.Statement
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/customers/{id}/statements [get]
+// @Security     BearerAuth
+func GetCustomerStatements(c *gin.Context) {
+	customerID, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	respondStatements(c, customerID)
+}
+
+// GetCustomerStatement returns one of a customer's monthly statements
+// @Summary      Get customer statement
+// @Description  Get the document of a customer's statement for a month: the changes to its accounts, the API calls made with its tokens, and an invoice per active account (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Customer ID or UUID"
+// @Param        period  path      string  true  "Month, e.g. 2026-09"
+// @Success      200     {object}  models.StatementDocument
+// @Failure      400     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      404     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /admin/customers/{id}/statements/{period} [get]
+// @Security     BearerAuth
+func GetCustomerStatement(c *gin.Context) {
+	customerID, ok := parseCustomerID(c, "id")
+	if !ok {
+		return
+	}
+	respondStatement(c, customerID)
+}
+
+// GetMyStatements lists the monthly statements of a customer token's customer
+// @Summary      List my statements
+// @Description  List the customer's monthly statements, newest first, with their totals. Requires a customer API token with the statements:read scope that isn't limited to one account.
+// @Tags         my
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.Statement
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /my/statements [get]
+// @Security     BearerAuth
+func GetMyStatements(c *gin.Context) {
+	if customerID, ok := statementCustomer(c); ok {
+		respondStatements(c, customerID)
+	}
+}
+
+// GetMyStatement returns one of the monthly statements of a customer token's customer
+// @Summary      Get my statement
+// @Description  Get the customer's statement for a month. Requires a customer API token with the statements:read scope that isn't limited to one account.
+// @Tags         my
+// @Accept       json
+// @Produce      json
+// @Param        period  path      string  true  "Month, e.g. 2026-09"
+// @Success      200     {object}  models.StatementDocument
+// @Failure      400     {object}  map[string]string
+// @Failure      401     {object}  map[string]string
+// @Failure      403     {object}  map[string]string
+// @Failure      404     {object}  map[string]string
+// @Failure      500     {object}  map[string]string
+// @Router       /my/statements/{period} [get]
+// @Security     BearerAuth
+func GetMyStatement(c *gin.Context) {
+	if customerID, ok := statementCustomer(c); ok {
+		respondStatement(c, customerID)
+	}
+}
+
+// statementCustomer returns the customer of the request's token. Statements
+// cover every account of a customer, so tokens limited to one account get a
+// 403.
+func statementCustomer(c *gin.Context) (int, bool) {
+	token := customerToken(c)
+	if token.AccountID != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Statements cover every account, and the token is limited to one"})
+		return 0, false
+	}
+	return token.CustomerID, true
+}
+
+func respondStatements(c *gin.Context, customerID int) {
+	statements, err := listStatements(c.Request.Context(), customerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statements"})
+		return
+	}
+	c.JSON(http.StatusOK, statements)
+}
+
+func respondStatement(c *gin.Context, customerID int) {
+	start, err := time.Parse(jobs.StatementPeriodLayout, c.Param("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period, use YYYY-MM"})
+		return
+	}
+
+	document, err := statementDocument(c.Request.Context(), customerID, start)
+	if errors.Is(err, jobs.ErrStatementNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Statement not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch statement"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", document)
+}
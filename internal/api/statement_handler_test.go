@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetMyStatement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := statementDocument
+	t.Cleanup(func() { statementDocument = previous })
+	var gotCustomer int
+	var gotStart time.Time
+	statementDocument = func(ctx context.Context, customerID int, start time.Time) ([]byte, error) {
+		gotCustomer, gotStart = customerID, start
+		if start.Month() != time.September {
+			return nil, jobs.ErrStatementNotFound
+		}
+		return []byte(`{"period":"2026-09"}`), nil
+	}
+
+	get := func(token models.CustomerToken, period string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/api/my/statements/:period", func(c *gin.Context) {
+			c.Set("customer_token", token)
+			c.Next()
+		}, GetMyStatement)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/my/statements/"+period, nil))
+		return w
+	}
+	token := models.CustomerToken{CustomerID: 42}
+
+	w := get(token, "2026-09")
+	if w.Code != http.StatusOK || w.Body.String() != `{"period":"2026-09"}` {
+		t.Fatalf("Expected the stored document, got %d: %s", w.Code, w.Body.String())
+	}
+	if gotCustomer != 42 || !gotStart.Equal(time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected customer 42's September statement, got customer %d, %v", gotCustomer, gotStart)
+	}
+
+	if w := get(token, "2026-08"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing statement, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := get(token, "september"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid period, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	accountID := 7
+	token.AccountID = &accountID
+	if w := get(token, "2026-09"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for an account-scoped token, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errTwoFactorRequired    = errors.New("Two-factor code required")
+	errInvalidTwoFactorCode = errors.New("Invalid two-factor code")
+	errTwoFactorNotStarted  = errors.New("Start two-factor setup with POST /me/2fa/setup first")
+	errTwoFactorEnabled     = errors.New("Two-factor authentication is already enabled")
+	errTwoFactorDisabled    = errors.New("Two-factor authentication is not enabled")
+)
+
+// startTwoFactor stores a new TOTP secret for a user, pending until
+// enableTwoFactor confirms it; tests replace it
+var startTwoFactor = func(ctx context.Context, username string) (string, error) {
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	err = db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		var enabled bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT id, totp_enabled_at IS NOT NULL FROM users WHERE username = $1 FOR UPDATE", username,
+		).Scan(&userID, &enabled)
+		if err == sql.ErrNoRows {
+			return errUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if enabled {
+			return errTwoFactorEnabled
+		}
+
+		sealed, err := auth.SealTOTPSecret(userID, secret)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE users SET totp_secret = $2, totp_last_step = NULL WHERE id = $1", userID, sealed)
+		return err
+	})
+	return secret, err
+}
+
+// enableTwoFactor turns on two-factor authentication for a user once code
+// matches their pending secret, replacing their backup codes with new ones,
+// which it returns; tests replace it
+var enableTwoFactor = func(ctx context.Context, username, code string) ([]string, error) {
+	backupCodes, err := auth.NewBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		var sealed sql.NullString
+		var enabled bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT id, totp_secret, totp_enabled_at IS NOT NULL FROM users WHERE username = $1 FOR UPDATE", username,
+		).Scan(&userID, &sealed, &enabled)
+		switch {
+		case err == sql.ErrNoRows:
+			return errUserNotFound
+		case err != nil:
+			return err
+		case enabled:
+			return errTwoFactorEnabled
+		case !sealed.Valid:
+			return errTwoFactorNotStarted
+		}
+
+		secret, _, err := auth.OpenTOTPSecret(userID, sealed.String)
+		if err != nil {
+			return err
+		}
+		step, ok := auth.ValidateTOTP(secret, code, time.Now(), 0)
+		if !ok {
+			return errInvalidTwoFactorCode
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $2 WHERE id = $1", userID, step,
+		)
+		if err != nil {
+			return err
+		}
+		return replaceBackupCodes(ctx, tx, userID, backupCodes)
+	})
+	return backupCodes, err
+}
+
+// replaceBackupCodes stores the hashes of codes as a user's only backup codes
+func replaceBackupCodes(ctx context.Context, tx *sql.Tx, userID int, codes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_backup_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashBackupCode(code)
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO user_backup_codes (user_id, code_hash) SELECT $1, unnest($2::text[])", userID, hashes,
+	)
+	return err
+}
+
+// consumeTwoFactorCode checks code, a TOTP code or an unused backup code, for
+// a user with two-factor authentication enabled, and uses it up so it can't
+// be presented again. A secret sealed under a rotated key is sealed again
+// under the current one.
+func consumeTwoFactorCode(ctx context.Context, tx *sql.Tx, userID int, code string) error {
+	if code == "" {
+		return errTwoFactorRequired
+	}
+	if !auth.IsTOTPCode(code) {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE user_backup_codes SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+			userID, auth.HashBackupCode(code),
+		)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return errInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	var sealed string
+	var lastStep sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT totp_secret, totp_last_step FROM users WHERE id = $1 FOR UPDATE", userID,
+	).Scan(&sealed, &lastStep)
+	if err != nil {
+		return err
+	}
+	secret, stale, err := auth.OpenTOTPSecret(userID, sealed)
+	if err != nil {
+		return err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now(), lastStep.Int64)
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+	if stale {
+		if sealed, err = auth.SealTOTPSecret(userID, secret); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_last_step = $2, totp_secret = $3 WHERE id = $1", userID, step, sealed)
+	return err
+}
+
+// checkTwoFactor consumes a two-factor code of a user at login; tests
+// replace it
+var checkTwoFactor = func(ctx context.Context, userID int, code string) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		return consumeTwoFactorCode(ctx, tx, userID, code)
+	})
+}
+
+// disableTwoFactor turns off two-factor authentication for a user, given a
+// code, and deletes their secret and backup codes; tests replace it
+var disableTwoFactor = func(ctx context.Context, username, code string) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		var enabled bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT id, totp_enabled_at IS NOT NULL FROM users WHERE username = $1 FOR UPDATE", username,
+		).Scan(&userID, &enabled)
+		switch {
+		case err == sql.ErrNoRows:
+			return errUserNotFound
+		case err != nil:
+			return err
+		case !enabled:
+			return errTwoFactorDisabled
+		}
+		if err := consumeTwoFactorCode(ctx, tx, userID, code); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1", userID,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM user_backup_codes WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+// SetupTwoFactor starts enrolling the caller in two-factor authentication
+// @Summary      Set up two-factor authentication
+// @Description  Generate a TOTP secret for the caller and return it with an otpauth:// URL to scan into an authenticator app. Two-factor authentication is only enabled, and required at login, once POST /me/2fa/verify confirms a code from the app; calling this again before then replaces the secret. The secret is stored encrypted under TOTP_ENCRYPTION_KEY. Returns 409 if two-factor authentication is already enabled, and 503 if no encryption key is configured. Not available with an API key.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  models.TwoFactorSetup
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /me/2fa/setup [post]
+// @Security     BearerAuth
+func SetupTwoFactor(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't manage two-factor authentication, log in to manage yours"})
+		return
+	}
+
+	username := c.GetString("username")
+	secret, err := startTwoFactor(c.Request.Context(), username)
+	switch {
+	case errors.Is(err, errUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrTwoFactorUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Two-factor authentication is not configured"})
+		return
+	case err != nil:
+		tracing.Printf(c.Request.Context(), "Error starting two-factor setup for %s: %v", username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set up two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorSetup{Secret: secret, OTPAuthURL: auth.TOTPURL(username, secret)})
+}
+
+// VerifyTwoFactor enables two-factor authentication for the caller
+// @Summary      Enable two-factor authentication
+// @Description  Confirm the secret from POST /me/2fa/setup with a current code from the authenticator app, enabling two-factor authentication: logins then need a code as well as the password. Returns 10 single-use backup codes for logging in without the app; only their hashes are stored, so they are shown just once. A wrong code gets 403. Not available with an API key.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        code  body      models.TwoFactorCodeRequest  true  "TOTP code"
+// @Success      200   {object}  models.TwoFactorBackupCodes
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      404   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      500   {object}  map[string]string
+// @Router       /me/2fa/verify [post]
+// @Security     BearerAuth
+func VerifyTwoFactor(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't manage two-factor authentication, log in to manage yours"})
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	backupCodes, err := enableTwoFactor(c.Request.Context(), c.GetString("username"), req.Code)
+	switch {
+	case errors.Is(err, errUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTwoFactorNotStarted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	// 403 rather than 401, which clients take to mean the token is invalid
+	case errors.Is(err, errInvalidTwoFactorCode):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorBackupCodes{BackupCodes: backupCodes})
+}
+
+// DisableTwoFactor turns off two-factor authentication for the caller
+// @Summary      Disable two-factor authentication
+// @Description  Turn off two-factor authentication, given the caller's password and a current TOTP code or unused backup code. The secret and backup codes are deleted; set it up again with POST /me/2fa/setup. A wrong password or code gets 403. Not available with an API key.
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body      models.DisableTwoFactorRequest  true  "Password and two-factor code"
+// @Success      200          {object}  map[string]string
+// @Failure      400          {object}  map[string]string
+// @Failure      401          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      404          {object}  map[string]string
+// @Failure      409          {object}  map[string]string
+// @Failure      500          {object}  map[string]string
+// @Router       /me/2fa [delete]
+// @Security     BearerAuth
+func DisableTwoFactor(c *gin.Context) {
+	if _, ok := auth.APIKeyFromContext(c); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't manage two-factor authentication, log in to manage yours"})
+		return
+	}
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString("username")
+	passwordHash, err := userPasswordHash(ctx, username)
+	if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !auth.CheckPasswordHash(req.Password, passwordHash) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	err = disableTwoFactor(ctx, username, req.Code)
+	switch {
+	case errors.Is(err, errUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errTwoFactorDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errInvalidTwoFactorCode):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetupTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := startTwoFactor
+	t.Cleanup(func() { startTwoFactor = previous })
+
+	startErr := error(nil)
+	startTwoFactor = func(ctx context.Context, username string) (string, error) {
+		return "JBSWY3DPEHPK3PXP", startErr
+	}
+
+	post := func(apiKey bool) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/api/me/2fa/setup", func(c *gin.Context) {
+			c.Set("username", "alice")
+			if apiKey {
+				c.Set("api_key", auth.APIKeyPrincipal{ID: 1, Username: "alice", Scopes: []string{auth.ScopeWrite}})
+			}
+			c.Next()
+		}, SetupTwoFactor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/me/2fa/setup", nil))
+		return w
+	}
+
+	if w := post(true); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d with an API key, got %d", http.StatusForbidden, w.Code)
+	}
+
+	w := post(false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var setup models.TwoFactorSetup
+	if err := json.Unmarshal(w.Body.Bytes(), &setup); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if setup.Secret != "JBSWY3DPEHPK3PXP" || !strings.HasPrefix(setup.OTPAuthURL, "otpauth://totp/") {
+		t.Errorf("Unexpected setup %+v", setup)
+	}
+
+	for err, status := range map[error]int{
+		errTwoFactorEnabled:          http.StatusConflict,
+		auth.ErrTwoFactorUnavailable: http.StatusServiceUnavailable,
+	} {
+		startErr = err
+		if w := post(false); w.Code != status {
+			t.Errorf("Expected status %d for %v, got %d", status, err, w.Code)
+		}
+	}
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := enableTwoFactor
+	t.Cleanup(func() { enableTwoFactor = previous })
+
+	enableTwoFactor = func(ctx context.Context, username, code string) ([]string, error) {
+		switch code {
+		case "123456":
+			return []string{"k7f2m-q9xa4"}, nil
+		case "000000":
+			return nil, errTwoFactorNotStarted
+		}
+		return nil, errInvalidTwoFactorCode
+	}
+
+	router := gin.New()
+	router.POST("/api/me/2fa/verify", func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Next()
+	}, VerifyTwoFactor)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"code": "000000"}`, http.StatusBadRequest},
+		{`{"code": "654321"}`, http.StatusForbidden},
+		{`{"code": "123456"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/me/2fa/verify", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("Expected status %d for %s, got %d", tt.status, tt.body, w.Code)
+		}
+		if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), `"backup_codes":["k7f2m-q9xa4"]`) {
+			t.Errorf("Expected the backup codes in the response, got %s", w.Body.String())
+		}
+	}
+}
+
+func TestDisableTwoFactorChecksPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previousHash, previousDisable := userPasswordHash, disableTwoFactor
+	t.Cleanup(func() { userPasswordHash, disableTwoFactor = previousHash, previousDisable })
+
+	currentHash, err := auth.HashPassword("secret1")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	userPasswordHash = func(ctx context.Context, username string) (string, error) {
+		return currentHash, nil
+	}
+	disabled := false
+	disableTwoFactor = func(ctx context.Context, username, code string) error {
+		if code != "123456" {
+			return errInvalidTwoFactorCode
+		}
+		disabled = true
+		return nil
+	}
+
+	router := gin.New()
+	router.DELETE("/api/me/2fa", func(c *gin.Context) {
+		c.Set("username", "alice")
+		c.Next()
+	}, DisableTwoFactor)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"password": "wrong", "code": "123456"}`, http.StatusForbidden},
+		{`{"password": "secret1", "code": "654321"}`, http.StatusForbidden},
+		{`{"password": "secret1", "code": "123456"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		disabled = false
+		req := httptest.NewRequest(http.MethodDelete, "/api/me/2fa", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("Expected status %d for %s, got %d", tt.status, tt.body, w.Code)
+		}
+		if disabled != (tt.status == http.StatusOK) {
+			t.Errorf("Expected two-factor authentication to be disabled only on success, for %s", tt.body)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIUsageEndpoint represents call volume and latency for one endpoint
+type APIUsageEndpoint struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	Calls        int64   `json:"calls"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// APIUsageConsumer represents one caller's share of API traffic
+type APIUsageConsumer struct {
+	Username string `json:"username"`
+	// TokenID is the API key or customer token the calls were made with,
+	// when broken down by token; 0 for JWTs
+	TokenID      int     `json:"token_id,omitempty"`
+	Calls        int64   `json:"calls"`
+	Errors       int64   `json:"errors"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// APIUsageOrganization represents one organization's share of API traffic
+type APIUsageOrganization struct {
+	OrganizationID int     `json:"organization_id"`
+	Name           string  `json:"name"`
+	Calls          int64   `json:"calls"`
+	Errors         int64   `json:"errors"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+}
+
+// APIUsageResponse represents API usage over a time window
+type APIUsageResponse struct {
+	Username         string                 `json:"username"`
+	Since            time.Time              `json:"since"`
+	Endpoints        []APIUsageEndpoint     `json:"endpoints"`
+	TopConsumers     []APIUsageConsumer     `json:"top_consumers,omitempty"`
+	TopOrganizations []APIUsageOrganization `json:"top_organizations,omitempty"`
+}
+
+// OrganizationAPIUsageResponse represents an organization's API usage over a
+// time window
+type OrganizationAPIUsageResponse struct {
+	OrganizationID int                `json:"organization_id"`
+	Since          time.Time          `json:"since"`
+	Calls          int64              `json:"calls"`
+	Errors         int64              `json:"errors"`
+	Endpoints      []APIUsageEndpoint `json:"endpoints"`
+	Consumers      []APIUsageConsumer `json:"consumers"`
+}
+
+// GetAPIUsage returns API usage rollups from the follower pool
+// @Summary      Get API usage
+// @Description  Get API calls, errors, and latency per endpoint for the current user (staff only; organization members use GET /orgs/{id}/usage). Admins can view any user with the username parameter and also get the top consumers and organizations across all users. Usage is flushed in batches, so the last few seconds may be missing.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        hours     query     int     false  "Window in hours (1-720, default 24)"
+// @Param        username  query     string  false  "User to report on (admins only, default: current user)"
+// @Success      200       {object}  APIUsageResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /analytics/api-usage [get]
+// @Security     BearerAuth
+func GetAPIUsage(c *gin.Context) {
+	since, ok := parseUsageWindow(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	caller := c.GetString("username")
+	role, err := userRole(ctx, caller)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	isAdmin := role == "admin"
+
+	username := c.DefaultQuery("username", caller)
+	if username != caller && !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view other users' usage"})
+		return
+	}
+
+	response := APIUsageResponse{Username: username, Since: since}
+
+	// The caller's usage and the top consumers are independent, so they run
+	// concurrently on the analytics DB
+	fanOut := db.NewFanOut(ctx)
+	fanOut.Go("API usage", func(analyticsDB db.Handle) error {
+		endpoints, err := apiUsageEndpoints(analyticsDB, username, 0, since)
+		response.Endpoints = endpoints
+		return err
+	})
+
+	// Admins also see who generates the most traffic, to spot noisy neighbors
+	if isAdmin {
+		fanOut.Go("top consumers", func(analyticsDB db.Handle) error {
+			consumerRows, err := analyticsDB.Query(`
+				SELECT username, SUM(calls),
+					COALESCE(SUM(calls) FILTER (WHERE status >= 500), 0),
+					COALESCE(SUM(total_latency_ms) / NULLIF(SUM(calls), 0), 0)
+				FROM api_usage_rollups
+				WHERE bucket >= $1
+				GROUP BY username
+				ORDER BY SUM(calls) DESC
+				LIMIT 10`,
+				since,
+			)
+			if err != nil {
+				return err
+			}
+			defer consumerRows.Close()
+
+			response.TopConsumers = []APIUsageConsumer{}
+			for consumerRows.Next() {
+				var consumer APIUsageConsumer
+				if err := consumerRows.Scan(&consumer.Username, &consumer.Calls, &consumer.Errors, &consumer.AvgLatencyMs); err != nil {
+					return err
+				}
+				response.TopConsumers = append(response.TopConsumers, consumer)
+			}
+			return consumerRows.Err()
+		})
+		fanOut.Go("top organizations", func(analyticsDB db.Handle) error {
+			orgRows, err := analyticsDB.Query(`
+				SELECT r.organization_id, COALESCE(o.name, ''), SUM(r.calls),
+					COALESCE(SUM(r.calls) FILTER (WHERE r.status >= 500), 0),
+					COALESCE(SUM(r.total_latency_ms) / NULLIF(SUM(r.calls), 0), 0)
+				FROM api_usage_rollups r
+				LEFT JOIN organizations o ON o.id = r.organization_id
+				WHERE r.bucket >= $1 AND r.organization_id <> 0
+				GROUP BY r.organization_id, o.name
+				ORDER BY SUM(r.calls) DESC
+				LIMIT 10`,
+				since,
+			)
+			if err != nil {
+				return err
+			}
+			defer orgRows.Close()
+
+			response.TopOrganizations = []APIUsageOrganization{}
+			for orgRows.Next() {
+				var org APIUsageOrganization
+				if err := orgRows.Scan(&org.OrganizationID, &org.Name, &org.Calls, &org.Errors, &org.AvgLatencyMs); err != nil {
+					return err
+				}
+				response.TopOrganizations = append(response.TopOrganizations, org)
+			}
+			return orgRows.Err()
+		})
+	}
+
+	if err := fanOut.Wait(); err != nil {
+		var queryErr *db.QueryError
+		if errors.As(err, &queryErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + queryErr.Query})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetOrganizationAPIUsage returns an organization's API usage rollups from the follower pool
+// @Summary      Get organization API usage
+// @Description  Get the API calls, errors, and latency of an organization's members and of its customers' tokens, in total, per endpoint, and per caller and token (members only). Calls count toward the organization the caller belonged to when they were flushed. Usage is flushed in batches, so the last few seconds may be missing.
+// @Tags         organization
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int  true   "Organization ID"
+// @Param        hours  query     int  false  "Window in hours (1-720, default 24)"
+// @Success      200    {object}  OrganizationAPIUsageResponse
+// @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /orgs/{id}/usage [get]
+// @Security     BearerAuth
+func GetOrganizationAPIUsage(c *gin.Context) {
+	since, ok := parseUsageWindow(c)
+	if !ok {
+		return
+	}
+
+	orgID := c.GetInt("organization_id")
+	response := OrganizationAPIUsageResponse{OrganizationID: orgID, Since: since}
+
+	fanOut := db.NewFanOut(c.Request.Context())
+	fanOut.Go("API usage", func(analyticsDB db.Handle) error {
+		endpoints, err := apiUsageEndpoints(analyticsDB, "", orgID, since)
+		response.Endpoints = endpoints
+		return err
+	})
+	fanOut.Go("consumers", func(analyticsDB db.Handle) error {
+		rows, err := analyticsDB.Query(`
+			SELECT username, token_id, SUM(calls),
+				COALESCE(SUM(calls) FILTER (WHERE status >= 500), 0),
+				COALESCE(SUM(total_latency_ms) / NULLIF(SUM(calls), 0), 0)
+			FROM api_usage_rollups
+			WHERE organization_id = $1 AND bucket >= $2
+			GROUP BY username, token_id
+			ORDER BY SUM(calls) DESC`,
+			orgID, since,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		response.Consumers = []APIUsageConsumer{}
+		for rows.Next() {
+			var consumer APIUsageConsumer
+			if err := rows.Scan(&consumer.Username, &consumer.TokenID, &consumer.Calls, &consumer.Errors, &consumer.AvgLatencyMs); err != nil {
+				return err
+			}
+			response.Consumers = append(response.Consumers, consumer)
+		}
+		return rows.Err()
+	})
+
+	if err := fanOut.Wait(); err != nil {
+		var queryErr *db.QueryError
+		if errors.As(err, &queryErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + queryErr.Query})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API usage"})
+		return
+	}
+
+	for _, endpoint := range response.Endpoints {
+		response.Calls += endpoint.Calls
+		response.Errors += endpoint.Errors
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseUsageWindow reads the hours parameter of the API usage endpoints and
+// returns the start of the window, at the top of the hour. It writes a 400
+// response and returns false if hours is invalid.
+func parseUsageWindow(c *gin.Context) (time.Time, bool) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 720 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hours"})
+		return time.Time{}, false
+	}
+	return time.Now().UTC().Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour), true
+}
+
+// apiUsageEndpoints sums the API usage rollups of username, or of the
+// organization with organizationID if username is empty, since a time per
+// endpoint, busiest first
+func apiUsageEndpoints(analyticsDB db.Handle, username string, organizationID int, since time.Time) ([]APIUsageEndpoint, error) {
+	rows, err := analyticsDB.Query(`
+		SELECT method, route, SUM(calls),
+			COALESCE(SUM(calls) FILTER (WHERE status >= 500), 0),
+			COALESCE(SUM(total_latency_ms) / NULLIF(SUM(calls), 0), 0),
+			MAX(max_latency_ms)
+		FROM api_usage_rollups
+		WHERE ($1 = '' OR username = $1) AND ($2 = 0 OR organization_id = $2) AND bucket >= $3
+		GROUP BY method, route
+		ORDER BY SUM(calls) DESC`,
+		username, organizationID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []APIUsageEndpoint{}
+	for rows.Next() {
+		var endpoint APIUsageEndpoint
+		if err := rows.Scan(&endpoint.Method, &endpoint.Route, &endpoint.Calls, &endpoint.Errors, &endpoint.AvgLatencyMs, &endpoint.MaxLatencyMs); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// UsageHeatmapResponse represents API calls by weekday and hour of day (UTC)
+// over the last 28 days. Calls[d][h] and Errors[d][h] are the counts for ISO
+// weekday d+1 (Monday first) at hour h.
+type UsageHeatmapResponse struct {
+	Username    string     `json:"username,omitempty"`
+	Calls       [][]int64  `json:"calls"`
+	Errors      [][]int64  `json:"errors"`
+	Total       int64      `json:"total"`
+	Max         int64      `json:"max"`
+	RefreshedAt *time.Time `json:"refreshed_at"`
+}
+
+// GetUsageHeatmap returns API activity by weekday and hour from the follower pool
+// @Summary      Get usage heatmap
+// @Description  Get API calls and errors by weekday and hour (UTC) over the last 28 days for the current user, as 7x24 grids with Monday first. Admins can view any user with the username parameter, or everyone with all=true. Counts come from a rollup refreshed hourly, so the latest hour may be missing; refreshed_at tells when it last ran.
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        username  query     string  false  "User to report on (admins only, default: current user)"
+// @Param        all       query     bool    false  "Combine all users (admins only)"
+// @Success      200       {object}  UsageHeatmapResponse
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /analytics/heatmap [get]
+// @Security     BearerAuth
+func GetUsageHeatmap(c *gin.Context) {
+	ctx := c.Request.Context()
+	caller := c.GetString("username")
+	username := c.DefaultQuery("username", caller)
+	all := c.Query("all") == "true"
+	if username != caller || all {
+		role, err := userRole(ctx, caller)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view other users' usage"})
+			return
+		}
+	}
+
+	var filter *string
+	if !all {
+		filter = &username
+	}
+	rows, err := db.Analytics(ctx).Query(`
+		SELECT weekday, hour, SUM(calls), SUM(errors), MAX(refreshed_at)
+		FROM usage_heatmap
+		WHERE $1::text IS NULL OR username = $1
+		GROUP BY weekday, hour`,
+		filter,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage heatmap"})
+		return
+	}
+	defer rows.Close()
+
+	response := UsageHeatmapResponse{Calls: heatmapGrid(), Errors: heatmapGrid()}
+	if !all {
+		response.Username = username
+	}
+	for rows.Next() {
+		var weekday, hour int
+		var calls, errorCount int64
+		var refreshedAt time.Time
+		if err := rows.Scan(&weekday, &hour, &calls, &errorCount, &refreshedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan usage heatmap"})
+			return
+		}
+		if weekday < 1 || weekday > 7 || hour < 0 || hour > 23 {
+			continue
+		}
+		response.Calls[weekday-1][hour] = calls
+		response.Errors[weekday-1][hour] = errorCount
+		response.Total += calls
+		if calls > response.Max {
+			response.Max = calls
+		}
+		if response.RefreshedAt == nil || refreshedAt.After(*response.RefreshedAt) {
+			response.RefreshedAt = &refreshedAt
+		}
+	}
+
+	SetDataAsOf(c, response.RefreshedAt)
+	c.JSON(http.StatusOK, response)
+}
+
+// RefreshUsageHeatmap refreshes the usage heatmap rollup immediately
+// @Summary      Refresh usage heatmap
+// @Description  Recompute the usage heatmap rollup from the API usage rollups (admin only). It also runs hourly when Redis is configured.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/analytics/heatmap/refresh [post]
+// @Security     BearerAuth
+func RefreshUsageHeatmap(c *gin.Context) {
+	if err := jobs.RefreshHeatmap(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh usage heatmap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Usage heatmap refreshed"})
+}
+
+// heatmapGrid returns an empty 7x24 grid
+func heatmapGrid() [][]int64 {
+	grid := make([][]int64, 7)
+	for i := range grid {
+		grid[i] = make([]int64, 24)
+	}
+	return grid
+}
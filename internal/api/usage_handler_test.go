@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetOrganizationAPIUsageRejectsInvalidHours(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/orgs/:id/usage", func(c *gin.Context) {
+		c.Set("organization_id", 7)
+		GetOrganizationAPIUsage(c)
+	})
+
+	for _, hours := range []string{"0", "721", "day"} {
+		req, _ := http.NewRequest("GET", "/api/orgs/7/usage?hours="+hours, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for hours=%s, got %d", http.StatusBadRequest, hours, w.Code)
+		}
+	}
+}
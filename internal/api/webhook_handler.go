@@ -0,0 +1,193 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/events"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateWebhookEndpoint subscribes a URL to lifecycle events
+// @Summary      Create webhook endpoint
+// @Description  Subscribe a URL to user lifecycle events (admin only). Leave events empty to receive every type: user.registered, user.locked_out, user.password_changed, api_key.created, organization.created, user.invited, user.new_login, registration.flagged, api_key.ip_denied. The signing secret is only returned in this response; each delivery carries an X-Webhook-Signature header of sha256=HMAC(secret, body).
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        endpoint  body      models.CreateWebhookEndpointRequest  true  "Endpoint URL and event types"
+// @Success      201       {object}  models.WebhookEndpoint
+// @Failure      400       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      500       {object}  map[string]string
+// @Router       /admin/webhooks [post]
+// @Security     BearerAuth
+func CreateWebhookEndpoint(c *gin.Context) {
+	var req models.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+	for _, eventType := range req.Events {
+		if !events.ValidType(eventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + eventType})
+			return
+		}
+	}
+
+	secret, err := events.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{URL: req.URL, Events: req.Events, Active: true, CreatedBy: c.GetString("username"), Secret: secret}
+	err = db.Primary(c.Request.Context()).QueryRow(
+		"INSERT INTO webhook_endpoints (url, secret, events, created_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		endpoint.URL, secret, endpoint.Events, endpoint.CreatedBy,
+	).Scan(&endpoint.ID, &endpoint.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetWebhookEndpoints lists webhook subscriptions
+// @Summary      List webhook endpoints
+// @Description  List subscribed webhook endpoints without their secrets (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   models.WebhookEndpoint
+// @Failure      403  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/webhooks [get]
+// @Security     BearerAuth
+func GetWebhookEndpoints(c *gin.Context) {
+	rows, err := db.Primary(c.Request.Context()).Query(
+		"SELECT id, url, events, active, created_by, created_at FROM webhook_endpoints ORDER BY id",
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook endpoints"})
+		return
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var endpoint models.WebhookEndpoint
+		if err := rows.Scan(&endpoint.ID, &endpoint.URL, db.Array(&endpoint.Events), &endpoint.Active, &endpoint.CreatedBy, &endpoint.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan webhook endpoint"})
+			return
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	c.JSON(http.StatusOK, endpoints)
+}
+
+// DeleteWebhookEndpoint removes a webhook subscription and its pending deliveries
+// @Summary      Delete webhook endpoint
+// @Description  Unsubscribe a webhook endpoint; pending deliveries are dropped (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Endpoint ID"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/webhooks/{id} [delete]
+// @Security     BearerAuth
+func DeleteWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	result, err := db.Primary(c.Request.Context()).Exec("DELETE FROM webhook_endpoints WHERE id = $1", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook endpoint"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook endpoint deleted successfully"})
+}
+
+// GetWebhookDeliveries lists recent deliveries for an endpoint
+// @Summary      List webhook deliveries
+// @Description  Get the 100 most recent deliveries for an endpoint with their status, attempts, and last error (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Endpoint ID"
+// @Success      200  {array}   models.WebhookDelivery
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /admin/webhooks/{id}/deliveries [get]
+// @Security     BearerAuth
+func GetWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var exists int
+	err = db.Primary(ctx).QueryRow("SELECT 1 FROM webhook_endpoints WHERE id = $1", id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := db.Primary(ctx).Query(
+		`SELECT id, endpoint_id, event_type, status, attempts, last_error, next_attempt_at, delivered_at, created_at
+		 FROM webhook_deliveries WHERE endpoint_id = $1 ORDER BY created_at DESC LIMIT 100`,
+		id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook deliveries"})
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+		if err := rows.Scan(&delivery.ID, &delivery.EndpointID, &delivery.EventType, &delivery.Status, &delivery.Attempts,
+			&lastError, &delivery.NextAttempt, &deliveredAt, &delivery.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan webhook delivery"})
+			return
+		}
+		if lastError.Valid {
+			delivery.LastError = &lastError.String
+		}
+		delivery.DeliveredAt = nullTime(deliveredAt)
+		deliveries = append(deliveries, delivery)
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,21 @@
+package auth
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the username of the caller, which
+// the database records as the actor of changes made with ctx (see audit_log)
+func WithActor(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, actorKey{}, username)
+}
+
+// ActorFromContext returns the username carried by ctx, or "" for work that
+// no user started, such as scheduled jobs
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
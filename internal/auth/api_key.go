@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyPrefix starts every API key, so keys can't be mistaken for JWTs or
+// customer tokens and leaked ones are easy to search for
+const APIKeyPrefix = "sgk_"
+
+// APIKeyHeader carries an API key in place of a JWT
+const APIKeyHeader = "X-API-Key"
+
+// API key scopes. Read allows GET, HEAD, and OPTIONS, write allows every
+// method, and admin is needed on top for the admin routes, which also
+// require the key's user to be an admin.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// APIKeyScopes lists every scope an API key can be granted
+var APIKeyScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// ErrInvalidAPIKey is returned by an APIKeyFunc for unknown, revoked, and
+// expired keys
+var ErrInvalidAPIKey = errors.New("invalid, revoked, or expired API key")
+
+// ValidAPIKeyScope reports whether scope can be granted to an API key
+func ValidAPIKeyScope(scope string) bool {
+	for _, known := range APIKeyScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeCIDRs parses the ranges an API key is restricted to, accepting a
+// bare IP address as a range of one, and returns them in canonical form
+func NormalizeCIDRs(cidrs []string) ([]string, error) {
+	normalized := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid CIDR range: %s", cidr)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		normalized = append(normalized, prefix.Masked().String())
+	}
+	return normalized, nil
+}
+
+// NewAPIKey generates an API key. Only its hash is stored (see HashAPIKey),
+// so the key itself can be shown just once.
+func NewAPIKey() (string, error) {
+	return randomToken(APIKeyPrefix)
+}
+
+// HashAPIKey returns the hex SHA-256 an API key is stored and looked up by
+func HashAPIKey(key string) string {
+	return hashToken(key)
+}
+
+// APIKeyDisplay returns the start of a key shown in lists
+func APIKeyDisplay(key string) string {
+	return CustomerTokenDisplay(key)
+}
+
+// APIKeyPrincipal is the user and scopes an API key authenticates as
+type APIKeyPrincipal struct {
+	ID       int
+	Username string
+	Scopes   []string
+	// AllowedCIDRs restrict where the key can be used from; empty allows
+	// any address
+	AllowedCIDRs []string
+}
+
+// AllowsIP reports whether the key can be used from clientIP
+func (p APIKeyPrincipal) AllowsIP(clientIP string) bool {
+	if len(p.AllowedCIDRs) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, cidr := range p.AllowedCIDRs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the key was granted scope
+func (p APIKeyPrincipal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns the scope a request with method needs, and whether the key
+// has it
+func (p APIKeyPrincipal) allows(method string) (string, bool) {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ScopeRead, p.HasScope(ScopeRead) || p.HasScope(ScopeWrite)
+	}
+	return ScopeWrite, p.HasScope(ScopeWrite)
+}
+
+// APIKeyFunc looks up the active API key key and records its use from
+// clientIP, returning ErrInvalidAPIKey if there is none
+type APIKeyFunc func(ctx context.Context, key, clientIP string) (APIKeyPrincipal, error)
+
+// APIKeyIPDeniedFunc is told about a valid API key used from outside its
+// allowed ranges
+type APIKeyIPDeniedFunc func(ctx context.Context, principal APIKeyPrincipal, clientIP string)
+
+var (
+	apiKeyMu       sync.RWMutex
+	lookupAPIKey   APIKeyFunc
+	apiKeyIPDenied APIKeyIPDeniedFunc
+)
+
+// AcceptAPIKeys makes AuthMiddleware accept API keys in the X-API-Key
+// header, looked up with lookup. Without it, only JWTs are accepted.
+func AcceptAPIKeys(lookup APIKeyFunc) {
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+	lookupAPIKey = lookup
+}
+
+// OnAPIKeyIPDenied calls denied whenever AuthMiddleware rejects an API key
+// used from outside its allowed ranges, e.g. to publish a security event
+func OnAPIKeyIPDenied(denied APIKeyIPDeniedFunc) {
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+	apiKeyIPDenied = denied
+}
+
+// APIKeyFromContext returns the API key AuthMiddleware authenticated the
+// request with, if it wasn't a JWT
+func APIKeyFromContext(c *gin.Context) (APIKeyPrincipal, bool) {
+	value, ok := c.Get("api_key")
+	if !ok {
+		return APIKeyPrincipal{}, false
+	}
+	principal, ok := value.(APIKeyPrincipal)
+	return principal, ok
+}
+
+// authenticateAPIKey handles a request carrying key for AuthMiddleware,
+// aborting it unless the key is valid and its scopes allow the method
+func authenticateAPIKey(c *gin.Context, key string) {
+	apiKeyMu.RLock()
+	lookup, denied := lookupAPIKey, apiKeyIPDenied
+	apiKeyMu.RUnlock()
+	if lookup == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not accepted"})
+		c.Abort()
+		return
+	}
+
+	principal, err := lookup(c.Request.Context(), key, c.ClientIP())
+	if errors.Is(err, ErrInvalidAPIKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, revoked, or expired API key"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		c.Abort()
+		return
+	}
+	if !principal.AllowsIP(c.ClientIP()) {
+		if denied != nil {
+			denied(c.Request.Context(), principal, c.ClientIP())
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is not allowed from this IP address"})
+		c.Abort()
+		return
+	}
+	if scope, ok := principal.allows(c.Request.Method); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set("username", principal.Username)
+	c.Set("api_key", principal)
+	c.Request = c.Request.WithContext(WithActor(c.Request.Context(), principal.Username))
+	c.Next()
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	if key[:len(APIKeyPrefix)] != APIKeyPrefix || HashAPIKey(key) != HashAPIKey(key) || len(HashAPIKey(key)) != 64 {
+		t.Errorf("Expected a prefixed key with a stable hash, got %s", key)
+	}
+	if !ValidAPIKeyScope(ScopeWrite) || ValidAPIKeyScope("accounts:read") {
+		t.Error("Expected only known scopes to be valid")
+	}
+}
+
+func TestAuthMiddlewareAcceptsAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { AcceptAPIKeys(nil) })
+
+	router := gin.New()
+	router.Use(AuthMiddleware())
+	handler := func(c *gin.Context) {
+		key, _ := APIKeyFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"username": c.GetString("username"), "key": key.ID, "actor": ActorFromContext(c.Request.Context())})
+	}
+	router.GET("/api/customers", handler)
+	router.POST("/api/customers", handler)
+	var body string
+	request := func(method, key string) int {
+		req := httptest.NewRequest(method, "/api/customers", nil)
+		req.Header.Set(APIKeyHeader, key)
+		req.RemoteAddr = "203.0.113.7:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		body = w.Body.String()
+		return w.Code
+	}
+
+	if code := request(http.MethodGet, "sgk_read"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d before API keys are accepted, got %d", http.StatusUnauthorized, code)
+	}
+
+	var gotIP string
+	AcceptAPIKeys(func(ctx context.Context, key, clientIP string) (APIKeyPrincipal, error) {
+		gotIP = clientIP
+		switch key {
+		case "sgk_read":
+			return APIKeyPrincipal{ID: 1, Username: "ci", Scopes: []string{ScopeRead}}, nil
+		case "sgk_write":
+			return APIKeyPrincipal{ID: 2, Username: "ci", Scopes: []string{ScopeWrite}}, nil
+		case "sgk_broken":
+			return APIKeyPrincipal{}, errors.New("connection reset")
+		}
+		return APIKeyPrincipal{}, ErrInvalidAPIKey
+	})
+	tests := []struct {
+		method, key string
+		want        int
+	}{
+		{http.MethodGet, "sgk_read", http.StatusOK},
+		{http.MethodPost, "sgk_read", http.StatusForbidden},
+		{http.MethodGet, "sgk_write", http.StatusOK},
+		{http.MethodPost, "sgk_write", http.StatusOK},
+		{http.MethodGet, "sgk_revoked", http.StatusUnauthorized},
+		{http.MethodGet, "sgk_broken", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if code := request(tt.method, tt.key); code != tt.want {
+			t.Errorf("Expected status %d for %s with %s, got %d", tt.want, tt.method, tt.key, code)
+		}
+	}
+	if request(http.MethodGet, "sgk_read"); body != `{"actor":"ci","key":1,"username":"ci"}` {
+		t.Errorf("Expected the request to run as the key's user, got %s", body)
+	}
+	if gotIP != "203.0.113.7" {
+		t.Errorf("Expected the client IP to be recorded, got %q", gotIP)
+	}
+}
+
+func TestAPIKeyAllowedCIDRs(t *testing.T) {
+	if _, err := NormalizeCIDRs([]string{"10.0.0.0/8", "office"}); err == nil {
+		t.Error("Expected an invalid range to be rejected")
+	}
+	cidrs, err := NormalizeCIDRs([]string{"10.1.2.3/8", "192.0.2.7", "2001:db8::/32"})
+	if err != nil || fmt.Sprint(cidrs) != "[10.0.0.0/8 192.0.2.7/32 2001:db8::/32]" {
+		t.Fatalf("Expected canonical ranges, got %v: %v", cidrs, err)
+	}
+
+	principal := APIKeyPrincipal{AllowedCIDRs: cidrs}
+	for ip, want := range map[string]bool{
+		"10.200.0.1":      true,
+		"::ffff:10.0.0.1": true,
+		"192.0.2.7":       true,
+		"192.0.2.8":       false,
+		"2001:db8::1":     true,
+		"2001:db9::1":     false,
+		"not-an-ip":       false,
+	} {
+		if got := principal.AllowsIP(ip); got != want {
+			t.Errorf("Expected AllowsIP(%s) to be %v, got %v", ip, want, got)
+		}
+	}
+	if !(APIKeyPrincipal{}).AllowsIP("203.0.113.7") {
+		t.Error("Expected a key without ranges to be allowed from anywhere")
+	}
+}
+
+func TestAuthMiddlewareDeniesAPIKeysOutsideAllowedCIDRs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() {
+		AcceptAPIKeys(nil)
+		OnAPIKeyIPDenied(nil)
+	})
+	AcceptAPIKeys(func(ctx context.Context, key, clientIP string) (APIKeyPrincipal, error) {
+		return APIKeyPrincipal{ID: 4, Username: "ci", Scopes: []string{ScopeRead}, AllowedCIDRs: []string{"198.51.100.0/24"}}, nil
+	})
+	var deniedKey int
+	var deniedIP string
+	OnAPIKeyIPDenied(func(ctx context.Context, principal APIKeyPrincipal, clientIP string) {
+		deniedKey, deniedIP = principal.ID, clientIP
+	})
+
+	router := gin.New()
+	router.Use(AuthMiddleware())
+	router.GET("/api/customers", func(c *gin.Context) { c.Status(http.StatusOK) })
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/customers", nil)
+		req.Header.Set(APIKeyHeader, "sgk_office")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("198.51.100.20:1234"); code != http.StatusOK || deniedKey != 0 {
+		t.Errorf("Expected status %d from an allowed address, got %d", http.StatusOK, code)
+	}
+	if code := request("203.0.113.7:1234"); code != http.StatusForbidden {
+		t.Errorf("Expected status %d from outside the allowed ranges, got %d", http.StatusForbidden, code)
+	}
+	if deniedKey != 4 || deniedIP != "203.0.113.7" {
+		t.Errorf("Expected the denial to be reported, got key %d from %q", deniedKey, deniedIP)
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// CustomerTokenPrefix starts every customer API token, so they can't be
+// mistaken for JWTs and leaked ones are easy to search for
+const CustomerTokenPrefix = "sgc_"
+
+// customerTokenDisplayLength is how much of a token is kept in the clear to
+// tell tokens apart in lists
+const customerTokenDisplayLength = 12
+
+// Customer API token scopes
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeUsageRead      = "usage:read"
+	ScopeStatementsRead = "statements:read"
+)
+
+// CustomerTokenScopes lists every scope a customer token can be granted
+var CustomerTokenScopes = []string{ScopeAccountsRead, ScopeUsageRead, ScopeStatementsRead}
+
+// ValidCustomerTokenScope reports whether scope can be granted to a customer token
+func ValidCustomerTokenScope(scope string) bool {
+	for _, known := range CustomerTokenScopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCustomerToken generates a customer API token. Only its hash is stored
+// (see HashCustomerToken), so the token itself can be shown just once.
+func NewCustomerToken() (string, error) {
+	return randomToken(CustomerTokenPrefix)
+}
+
+// randomToken returns prefix followed by 32 random bytes in hex
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// IsCustomerToken reports whether a bearer token is a customer API token
+func IsCustomerToken(token string) bool {
+	return strings.HasPrefix(token, CustomerTokenPrefix)
+}
+
+// HashCustomerToken returns the hex SHA-256 a customer token is stored and
+// looked up by. Tokens are random, so a fast unsalted hash is enough.
+func HashCustomerToken(token string) string {
+	return hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CustomerTokenDisplay returns the start of a token shown in lists
+func CustomerTokenDisplay(token string) string {
+	if len(token) <= customerTokenDisplayLength {
+		return token
+	}
+	return token[:customerTokenDisplayLength]
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestCustomerToken(t *testing.T) {
+	token, err := NewCustomerToken()
+	if err != nil {
+		t.Fatalf("Failed to generate customer token: %v", err)
+	}
+	if !IsCustomerToken(token) {
+		t.Errorf("Expected %s to be recognized as a customer token", token)
+	}
+	if other, _ := NewCustomerToken(); other == token {
+		t.Error("Expected a new token each time")
+	}
+
+	hash := HashCustomerToken(token)
+	if len(hash) != 64 || hash != HashCustomerToken(token) {
+		t.Errorf("Expected a stable hex SHA-256, got %s", hash)
+	}
+	if display := CustomerTokenDisplay(token); display != token[:12] || !IsCustomerToken(display) {
+		t.Errorf("Expected the first 12 characters, got %s", display)
+	}
+
+	if !ValidCustomerTokenScope(ScopeAccountsRead) || ValidCustomerTokenScope("accounts:write") {
+		t.Error("Expected only known scopes to be valid")
+	}
+}
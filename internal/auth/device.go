@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// versionNumbers matches the version numbers in a user agent, e.g. 17.1 in
+// Version/17.1 or 10_15_7 in Mac OS X 10_15_7
+var versionNumbers = regexp.MustCompile(`[0-9][0-9._]*`)
+
+// DeviceFingerprint derives an identifier for the device a request came from.
+// Apps that send a stable X-Device-ID get it hashed as is. Otherwise it hashes
+// the user agent without its version numbers, so browser and OS updates keep
+// the fingerprint, together with the preferred languages. It is a heuristic
+// for noticing logins from somewhere new, not proof of identity: two
+// identical browsers share a fingerprint, and clients can send anything.
+func DeviceFingerprint(deviceID, userAgent, acceptLanguage string) string {
+	source := "device:" + strings.TrimSpace(deviceID)
+	if strings.TrimSpace(deviceID) == "" {
+		agent := strings.Join(strings.Fields(versionNumbers.ReplaceAllString(userAgent, "")), " ")
+		source = "agent:" + strings.ToLower(agent) + "\n" + strings.ToLower(strings.TrimSpace(acceptLanguage))
+	}
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,28 @@
+package auth
+
+import "testing"
+
+func TestDeviceFingerprint(t *testing.T) {
+	safari := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15"
+	updated := "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"
+	firefox := "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+
+	fingerprint := DeviceFingerprint("", safari, "en-US,en;q=0.9")
+	if len(fingerprint) != 32 {
+		t.Fatalf("Expected 32 hex characters, got %q", fingerprint)
+	}
+	if got := DeviceFingerprint("", updated, "en-US,en;q=0.9"); got != fingerprint {
+		t.Errorf("Expected a browser update to keep the fingerprint, got %s and %s", fingerprint, got)
+	}
+	if got := DeviceFingerprint("", firefox, "en-US,en;q=0.9"); got == fingerprint {
+		t.Error("Expected another browser to get another fingerprint")
+	}
+	if got := DeviceFingerprint("", safari, "de-DE"); got == fingerprint {
+		t.Error("Expected other languages to get another fingerprint")
+	}
+
+	app := DeviceFingerprint("install-42", safari, "")
+	if app == fingerprint || app != DeviceFingerprint("install-42", firefox, "de-DE") {
+		t.Errorf("Expected a device ID to decide the fingerprint on its own, got %s", app)
+	}
+}
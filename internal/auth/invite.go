@@ -0,0 +1,15 @@
+package auth
+
+// InviteTokenPrefix starts every organization invite token
+const InviteTokenPrefix = "sgi_"
+
+// NewInviteToken generates a token that lets its holder join an organization.
+// Like customer tokens, only its hash is stored.
+func NewInviteToken() (string, error) {
+	return randomToken(InviteTokenPrefix)
+}
+
+// HashInviteToken returns the hex SHA-256 an invite is stored and looked up by
+func HashInviteToken(token string) string {
+	return hashToken(token)
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInviteToken(t *testing.T) {
+	token, err := NewInviteToken()
+	if err != nil {
+		t.Fatalf("Failed to generate invite token: %v", err)
+	}
+	if !strings.HasPrefix(token, InviteTokenPrefix) || IsCustomerToken(token) {
+		t.Errorf("Expected an invite token, got %s", token)
+	}
+	if hash := HashInviteToken(token); len(hash) != 64 || hash != HashInviteToken(token) {
+		t.Errorf("Expected a stable hex SHA-256, got %s", hash)
+	}
+}
@@ -1,54 +1,114 @@
 package auth
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"saas-go-app/internal/secrets"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret []byte
+var watchRotation sync.Once
 
-// Claims represents JWT claims
+// Claims represents JWT claims. The subject is the user ID, so services
+// verifying tokens with the JWKS know the user without a lookup.
 type Claims struct {
+	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	// Role is the user's role when the token was issued. The API itself
+	// reads the current role from the database, so a demotion applies at once.
+	Role string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// InitJWT initializes JWT secret from environment or generates one
+// InitJWT loads the RS256 signing keys (see signing_keys.go), generating one
+// if none is configured. When JWT_PRIVATE_KEY or JWT_PUBLIC_KEYS rotates, new
+// tokens use the new signing key while tokens signed with the old keys stay
+// valid until they expire.
 func InitJWT() error {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// Generate a random secret for development
-		secretBytes := make([]byte, 32)
-		if _, err := rand.Read(secretBytes); err != nil {
-			return err
-		}
-		secret = base64.URLEncoding.EncodeToString(secretBytes)
-		log.Println("WARNING: JWT_SECRET not set, using generated secret (not secure for production)")
+	set, err := loadKeySet()
+	if err != nil {
+		return err
+	}
+	if set.generated {
+		log.Println("WARNING: JWT_PRIVATE_KEY not set, using generated signing key (not secure for production)")
 	}
-	jwtSecret = []byte(secret)
+	keysMu.Lock()
+	keys = set
+	retiredKeys = nil
+	keysMu.Unlock()
+
+	watchRotation.Do(func() {
+		for _, name := range []string{"JWT_PRIVATE_KEY", "JWT_PUBLIC_KEYS"} {
+			secrets.OnRotate(name, func(name, value string) { rotateKeys() })
+		}
+	})
 	return nil
 }
 
-// GenerateToken generates a JWT token for a user
-func GenerateToken(username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// AccessTokenTTL reads JWT_TTL, how long a JWT is valid (default 15m), or
+// ACCESS_TOKEN_TTL, its former name. Clients get a new one with their refresh
+// token when it expires.
+func AccessTokenTTL() time.Duration {
+	for _, name := range []string{"JWT_TTL", "ACCESS_TOKEN_TTL"} {
+		if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value > 0 {
+			return value
+		}
+	}
+	return 15 * time.Minute
+}
+
+// tokenIssuer reads JWT_ISSUER, the iss claim of every JWT. When set, tokens
+// from another issuer are rejected.
+func tokenIssuer() string {
+	return os.Getenv("JWT_ISSUER")
+}
+
+// tokenAudience reads JWT_AUDIENCE, the aud claim of every JWT. When set,
+// tokens meant for another audience are rejected.
+func tokenAudience() string {
+	return os.Getenv("JWT_AUDIENCE")
+}
+
+// GenerateToken generates a JWT token for a user, valid for AccessTokenTTL,
+// with their ID and role. Its random ID (the jti claim) lets it be revoked
+// before it expires.
+func GenerateToken(userID int, username, role string) (string, error) {
+	id, err := randomToken("")
+	if err != nil {
+		return "", err
+	}
+	expirationTime := time.Now().Add(AccessTokenTTL())
 	claims := &Claims{
+		UserID:   userID,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   strconv.Itoa(userID),
+			Issuer:    tokenIssuer(),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if audience := tokenAudience(); audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	current, _ := verificationKeys()
+	if current == nil {
+		return "", errors.New("JWT signing keys not initialized")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = current.signing.id
+	tokenString, err := token.SignedString(current.signing.key)
 	if err != nil {
 		return "", err
 	}
@@ -56,13 +116,26 @@ func GenerateToken(username string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns the claims. Only RS256
+// tokens signed by one of the keys in the JWKS are accepted, and only for
+// JWT_ISSUER and JWT_AUDIENCE when they are set.
 func ValidateToken(tokenString string) (*Claims, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired()}
+	if issuer := tokenIssuer(); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience := tokenAudience(); audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
-
+		kid, _ := token.Header["kid"].(string)
+		key, ok := publicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,18 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
 	"testing"
 	"time"
+
+	"saas-go-app/internal/secrets"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestInitJWT(t *testing.T) {
@@ -11,8 +21,8 @@ func TestInitJWT(t *testing.T) {
 		t.Fatalf("Failed to initialize JWT: %v", err)
 	}
 
-	if len(jwtSecret) == 0 {
-		t.Fatal("JWT secret is empty")
+	if current, _ := verificationKeys(); current == nil || current.signing.key == nil {
+		t.Fatal("JWT signing key is not set")
 	}
 }
 
@@ -20,7 +30,7 @@ func TestGenerateAndValidateToken(t *testing.T) {
 	_ = InitJWT()
 
 	username := "testuser"
-	token, err := GenerateToken(username)
+	token, err := GenerateToken(42, username, "admin")
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -37,6 +47,9 @@ func TestGenerateAndValidateToken(t *testing.T) {
 	if claims.Username != username {
 		t.Errorf("Expected username %s, got %s", username, claims.Username)
 	}
+	if claims.UserID != 42 || claims.Subject != "42" || claims.Role != "admin" {
+		t.Errorf("Expected user 42 with role admin, got %d (sub %q) with role %q", claims.UserID, claims.Subject, claims.Role)
+	}
 }
 
 func TestHashPassword(t *testing.T) {
@@ -77,7 +90,7 @@ func TestTokenExpiration(t *testing.T) {
 	// This test would require mocking time or using a very short expiration
 	// For now, we'll just verify the token structure
 	username := "testuser"
-	token, err := GenerateToken(username)
+	token, err := GenerateToken(1, username, "user")
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -97,3 +110,299 @@ func TestTokenExpiration(t *testing.T) {
 	}
 }
 
+// newPEMKey returns a new RSA private key, PEM-encoded as PKCS #8
+func newPEMKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// resetKeys forgets the cached key secrets and reloads the keys once the
+// test's environment is restored. Call it before t.Setenv, so its cleanup
+// runs after theirs.
+func resetKeys(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		secrets.Default().Forget("JWT_PRIVATE_KEY", "JWT_PUBLIC_KEYS")
+		_ = InitJWT()
+	})
+}
+
+func TestTokensSurviveKeyRotation(t *testing.T) {
+	resetKeys(t)
+	first, firstPEM := newPEMKey(t)
+	t.Setenv("JWT_PRIVATE_KEY", firstPEM)
+	if err := InitJWT(); err != nil {
+		t.Fatal(err)
+	}
+	if SigningKeyGenerated() {
+		t.Error("Expected the configured key to sign, not a generated one")
+	}
+
+	oldToken, err := GenerateToken(1, "rotator", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, secondPEM := newPEMKey(t)
+	t.Setenv("JWT_PRIVATE_KEY", secondPEM)
+	secrets.Default().Refresh(context.Background())
+
+	if _, err := ValidateToken(oldToken); err != nil {
+		t.Errorf("token signed before rotation rejected: %v", err)
+	}
+	newToken, err := GenerateToken(1, "rotator", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != keyID(&second.PublicKey) || parsed.Method.Alg() != "RS256" {
+		t.Errorf("Expected an RS256 token signed by the new key, got %v", parsed.Header)
+	}
+
+	// Both keys are published until tokens signed by the old one expire
+	jwks := JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != keyID(&second.PublicKey) || jwks.Keys[1].Kid != keyID(&first.PublicKey) {
+		t.Errorf("Expected the new key then the old one in the JWKS, got %+v", jwks.Keys)
+	}
+}
+
+func TestRotatingBothKeySecretsKeepsOldKey(t *testing.T) {
+	resetKeys(t)
+	_, firstPEM := newPEMKey(t)
+	retired, _ := newPEMKey(t)
+	retiredDER, err := x509.MarshalPKIXPublicKey(&retired.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_PRIVATE_KEY", firstPEM)
+	t.Setenv("JWT_PUBLIC_KEYS", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: retiredDER})))
+	if err := InitJWT(); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := GenerateToken(1, "rotator", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One refresh rotates both secrets, firing a hook for each
+	_, secondPEM := newPEMKey(t)
+	otherRetired, _ := newPEMKey(t)
+	otherDER, err := x509.MarshalPKIXPublicKey(&otherRetired.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_PRIVATE_KEY", secondPEM)
+	t.Setenv("JWT_PUBLIC_KEYS", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherDER})))
+	secrets.Default().Refresh(context.Background())
+
+	if _, err := ValidateToken(oldToken); err != nil {
+		t.Errorf("Expected the token signed before both rotations to verify, got %v", err)
+	}
+	// The two new keys, then the two retired ones
+	if keys := JWKS().Keys; len(keys) != 4 {
+		t.Errorf("Expected 4 keys in the JWKS, got %d", len(keys))
+	}
+}
+
+func TestRetiredKeysExpire(t *testing.T) {
+	resetKeys(t)
+	_, firstPEM := newPEMKey(t)
+	t.Setenv("JWT_PRIVATE_KEY", firstPEM)
+	if err := InitJWT(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens signed by the old key last JWT_TTL, so it is dropped after that
+	t.Setenv("JWT_TTL", "1ms")
+	_, secondPEM := newPEMKey(t)
+	t.Setenv("JWT_PRIVATE_KEY", secondPEM)
+	secrets.Default().Refresh(context.Background())
+	if keys := JWKS().Keys; len(keys) != 2 {
+		t.Fatalf("Expected the retired key in the JWKS, got %d keys", len(keys))
+	}
+	time.Sleep(5 * time.Millisecond)
+	if keys := JWKS().Keys; len(keys) != 1 {
+		t.Errorf("Expected the retired key to expire, got %d keys", len(keys))
+	}
+}
+
+func TestMultipleActiveKeys(t *testing.T) {
+	first, firstPEM := newPEMKey(t)
+	second, secondPEM := newPEMKey(t)
+	retired, _ := newPEMKey(t)
+	retiredDER, err := x509.MarshalPKIXPublicKey(&retired.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retiredPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: retiredDER}))
+
+	// A config var set on one line, with escaped newlines
+	resetKeys(t)
+	t.Setenv("JWT_PRIVATE_KEY", strings.ReplaceAll(firstPEM+secondPEM, "\n", `\n`))
+	t.Setenv("JWT_PUBLIC_KEYS", retiredPEM)
+	secrets.Default().Refresh(context.Background())
+	if err := InitJWT(); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := JWKS()
+	want := []string{keyID(&first.PublicKey), keyID(&second.PublicKey), keyID(&retired.PublicKey)}
+	if len(jwks.Keys) != len(want) {
+		t.Fatalf("Expected %d keys in the JWKS, got %d", len(want), len(jwks.Keys))
+	}
+	for i, jwk := range jwks.Keys {
+		if jwk.Kid != want[i] || jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.Use != "sig" || jwk.E != "AQAB" {
+			t.Errorf("Unexpected key %d: %+v", i, jwk)
+		}
+	}
+
+	// Tokens from the other active key and the retired one still verify
+	for _, key := range []*rsa.PrivateKey{second, retired} {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, &Claims{
+			Username:         "alice",
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		token.Header["kid"] = keyID(&key.PublicKey)
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateToken(signed); err != nil {
+			t.Errorf("Expected a token from key %s to verify, got %v", keyID(&key.PublicKey), err)
+		}
+	}
+}
+
+func TestValidateTokenRejectsOtherKeys(t *testing.T) {
+	_ = InitJWT()
+	claims := &Claims{
+		Username:         "mallory",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}
+
+	// HS256 tokens, including ones signed with a public key as the secret
+	current, _ := verificationKeys()
+	publicDER, err := x509.MarshalPKIXPublicKey(&current.signing.key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range [][]byte{[]byte("legacy-secret"), publicDER} {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["kid"] = current.signing.id
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateToken(signed); err == nil {
+			t.Error("Expected an HS256 token to be rejected")
+		}
+	}
+
+	// RS256 tokens from a key that isn't configured
+	other, _ := newPEMKey(t)
+	for _, kid := range []string{current.signing.id, keyID(&other.PublicKey), ""} {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateToken(signed); err == nil {
+			t.Errorf("Expected a token from an unknown key with kid %q to be rejected", kid)
+		}
+	}
+}
+
+func TestParseRSAKeysRejectsWeakKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(small)}))
+	for _, data := range []string{smallPEM, "not a key", "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"} {
+		if _, _, err := parseRSAKeys(data); err == nil {
+			t.Errorf("Expected %.30q to be rejected", data)
+		}
+	}
+}
+
+func TestAccessTokenTTL(t *testing.T) {
+	_ = InitJWT()
+
+	t.Setenv("JWT_TTL", "")
+	if got := AccessTokenTTL(); got != 15*time.Minute {
+		t.Errorf("Expected a 15m default, got %v", got)
+	}
+	t.Setenv("ACCESS_TOKEN_TTL", "1h")
+	token, err := GenerateToken(1, "testuser", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if remaining := time.Until(claims.ExpiresAt.Time); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected the token to expire in an hour, got %v", remaining)
+	}
+	t.Setenv("JWT_TTL", "30m")
+	if got := AccessTokenTTL(); got != 30*time.Minute {
+		t.Errorf("Expected JWT_TTL to take precedence, got %v", got)
+	}
+	t.Setenv("JWT_TTL", "")
+	t.Setenv("ACCESS_TOKEN_TTL", "-5m")
+	if got := AccessTokenTTL(); got != 15*time.Minute {
+		t.Errorf("Expected the default for a negative TTL, got %v", got)
+	}
+}
+
+func TestIssuerAndAudience(t *testing.T) {
+	_ = InitJWT()
+
+	t.Setenv("JWT_ISSUER", "")
+	t.Setenv("JWT_AUDIENCE", "")
+	unscoped, err := GenerateToken(1, "testuser", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	t.Setenv("JWT_ISSUER", "https://app.example.com")
+	t.Setenv("JWT_AUDIENCE", "saas-api")
+	token, err := GenerateToken(1, "testuser", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.Issuer != "https://app.example.com" || len(claims.Audience) != 1 || claims.Audience[0] != "saas-api" {
+		t.Errorf("Expected iss and aud from the environment, got %q and %v", claims.Issuer, claims.Audience)
+	}
+	if _, err := ValidateToken(unscoped); err == nil {
+		t.Error("Expected a token without iss and aud to be rejected once they are configured")
+	}
+
+	t.Setenv("JWT_AUDIENCE", "other-api")
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("Expected a token for another audience to be rejected")
+	}
+	t.Setenv("JWT_AUDIENCE", "saas-api")
+	t.Setenv("JWT_ISSUER", "https://other.example.com")
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("Expected a token from another issuer to be rejected")
+	}
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LoginLockoutThreshold reads LOGIN_LOCKOUT_THRESHOLD, how many failed logins
+// within LoginLockoutWindow lock an account (default 5, 0 disables lockout)
+func LoginLockoutThreshold() int {
+	value := os.Getenv("LOGIN_LOCKOUT_THRESHOLD")
+	if value == "" {
+		return 5
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for LOGIN_LOCKOUT_THRESHOLD (%s), using default 5", value)
+		return 5
+	}
+	return threshold
+}
+
+// LoginLockoutWindow reads LOGIN_LOCKOUT_WINDOW, how far back failed logins
+// count towards the lockout (default 15m)
+func LoginLockoutWindow() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_WINDOW")); err == nil && value > 0 {
+		return value
+	}
+	return 15 * time.Minute
+}
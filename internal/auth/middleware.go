@@ -7,9 +7,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens for protected routes
+// AuthMiddleware validates JWT tokens for protected routes, or API keys in
+// the X-API-Key header once AcceptAPIKeys is set up
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -32,9 +38,17 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if tokenRevoked(c.Request.Context(), claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
 
-		// Store username in context for use in handlers
+		// Store username in context for use in handlers, and the claims for
+		// POST /auth/logout
 		c.Set("username", claims.Username)
+		c.Set("token_claims", claims)
+		c.Request = c.Request.WithContext(WithActor(c.Request.Context(), claims.Username))
 		c.Next()
 	}
 }
@@ -0,0 +1,16 @@
+package auth
+
+// RefreshTokenPrefix starts every refresh token
+const RefreshTokenPrefix = "sgr_"
+
+// NewRefreshToken generates a token that exchanges for a new access token
+// at POST /auth/refresh. Like customer tokens, only its hash is stored.
+func NewRefreshToken() (string, error) {
+	return randomToken(RefreshTokenPrefix)
+}
+
+// HashRefreshToken returns the hex SHA-256 a refresh token is stored and
+// looked up by
+func HashRefreshToken(token string) string {
+	return hashToken(token)
+}
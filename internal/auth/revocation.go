@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// RevokedFunc reports whether the token with ID jti has been revoked
+type RevokedFunc func(ctx context.Context, jti string) (bool, error)
+
+var (
+	revocationMu sync.RWMutex
+	isRevoked    RevokedFunc
+)
+
+// CheckRevocations makes AuthMiddleware reject tokens that revoked reports as
+// revoked, such as those of users who logged out. Without it, tokens are
+// valid until they expire.
+func CheckRevocations(revoked RevokedFunc) {
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	isRevoked = revoked
+}
+
+// tokenRevoked reports whether the token with claims was revoked. Tokens
+// without an ID can't be revoked. Lookup errors fail open, like the login
+// lockout, so a database hiccup doesn't sign everyone out.
+func tokenRevoked(ctx context.Context, claims *Claims) bool {
+	revocationMu.RLock()
+	revoked := isRevoked
+	revocationMu.RUnlock()
+	if revoked == nil || claims.ID == "" {
+		return false
+	}
+
+	result, err := revoked(ctx, claims.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to check whether the token of %s was revoked: %v", claims.Username, err)
+		return false
+	}
+	return result
+}
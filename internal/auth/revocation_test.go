@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareRejectsRevokedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = InitJWT()
+	t.Cleanup(func() { CheckRevocations(nil) })
+
+	token, err := GenerateToken(1, "alice", "user")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, _ := ValidateToken(token)
+	if claims.ID == "" {
+		t.Fatal("Expected the token to have an ID")
+	}
+
+	router := gin.New()
+	router.Use(AuthMiddleware())
+	router.GET("/api/customers", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/customers", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected status %d without a revocation check, got %d", http.StatusOK, code)
+	}
+
+	revoked := map[string]bool{}
+	var lookupErr error
+	CheckRevocations(func(ctx context.Context, jti string) (bool, error) {
+		return revoked[jti], lookupErr
+	})
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected status %d before the token is revoked, got %d", http.StatusOK, code)
+	}
+	revoked[claims.ID] = true
+	if code := get(); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a revoked token, got %d", http.StatusUnauthorized, code)
+	}
+
+	revoked, lookupErr = map[string]bool{}, errors.New("connection reset")
+	if code := get(); code != http.StatusOK {
+		t.Errorf("Expected a failed lookup to let the token through, got %d", code)
+	}
+}
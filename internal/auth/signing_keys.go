@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/secrets"
+)
+
+// JWTs are signed with RS256, so other services can verify them with the
+// public keys at GET /.well-known/jwks.json without sharing a secret.
+//
+// JWT_PRIVATE_KEY (read through the secrets provider) or the file named by
+// JWT_PRIVATE_KEY_FILE holds one or more PEM-encoded RSA private keys. The
+// first signs new tokens; every key verifies them and is published, so a new
+// key can be put first while tokens signed by the old one are still live.
+// JWT_PUBLIC_KEYS holds PEM public keys of retired keys whose private half is
+// gone, which still verify until their tokens expire. Each key's ID (the kid
+// header) is its RFC 7638 thumbprint, so no IDs need configuring. A key
+// removed by a live rotation keeps verifying for AccessTokenTTL, until every
+// token it signed has expired.
+
+// minRSAKeyBits is the smallest RSA key accepted
+const minRSAKeyBits = 2048
+
+// signingKey is the private key that signs new tokens
+type signingKey struct {
+	id  string
+	key *rsa.PrivateKey
+}
+
+// keySet is the configured signing key and every key that verifies tokens,
+// in the order they were configured
+type keySet struct {
+	signing signingKey
+	ids     []string
+	public  map[string]*rsa.PublicKey
+	// generated is set when no key was configured
+	generated bool
+}
+
+// retiredKey is a key removed by a live rotation, which verifies tokens
+// until those it signed have expired
+type retiredKey struct {
+	id    string
+	key   *rsa.PublicKey
+	until time.Time
+}
+
+var (
+	keysMu sync.RWMutex
+	keys   *keySet
+
+	// retiredKeys still verify tokens after the keys are rotated live, in
+	// the order they were retired
+	retiredKeys []retiredKey
+
+	// generatedKey is reused across InitJWT calls, so a process without a
+	// configured key keeps one key
+	generatedKey     *rsa.PrivateKey
+	generatedKeyOnce sync.Once
+	generatedKeyErr  error
+)
+
+// loadKeySet reads the signing and verification keys
+func loadKeySet() (*keySet, error) {
+	privatePEM, err := secrets.Get("JWT_PRIVATE_KEY")
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return nil, err
+	}
+	if privatePEM == "" {
+		if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read JWT_PRIVATE_KEY_FILE: %w", err)
+			}
+			privatePEM = string(data)
+		}
+	}
+	publicPEM, err := secrets.Get("JWT_PUBLIC_KEYS")
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return nil, err
+	}
+
+	privateKeys, _, err := parseRSAKeys(privatePEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY: %w", err)
+	}
+	_, publicKeys, err := parseRSAKeys(publicPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_PUBLIC_KEYS: %w", err)
+	}
+
+	set := &keySet{public: map[string]*rsa.PublicKey{}}
+	if len(privateKeys) == 0 {
+		generatedKeyOnce.Do(func() {
+			generatedKey, generatedKeyErr = rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+		})
+		if generatedKeyErr != nil {
+			return nil, generatedKeyErr
+		}
+		privateKeys = []*rsa.PrivateKey{generatedKey}
+		set.generated = true
+	}
+	set.signing = signingKey{id: keyID(&privateKeys[0].PublicKey), key: privateKeys[0]}
+	verifying := make([]*rsa.PublicKey, 0, len(privateKeys)+len(publicKeys))
+	for _, key := range privateKeys {
+		verifying = append(verifying, &key.PublicKey)
+	}
+	for _, key := range append(verifying, publicKeys...) {
+		id := keyID(key)
+		if _, ok := set.public[id]; !ok {
+			set.ids = append(set.ids, id)
+			set.public[id] = key
+		}
+	}
+	return set, nil
+}
+
+// parseRSAKeys reads the PEM blocks of data: RSA private keys (PKCS #1 or
+// PKCS #8) and public keys (PKIX or PKCS #1)
+func parseRSAKeys(data string) ([]*rsa.PrivateKey, []*rsa.PublicKey, error) {
+	var privateKeys []*rsa.PrivateKey
+	var publicKeys []*rsa.PublicKey
+	// Config vars set on one line carry escaped newlines
+	rest := []byte(strings.TrimSpace(strings.ReplaceAll(data, `\n`, "\n")))
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, nil, errors.New("expected PEM-encoded keys")
+		}
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		default:
+			return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			if key.N.BitLen() < minRSAKeyBits {
+				return nil, nil, fmt.Errorf("RSA keys must be at least %d bits, got %d", minRSAKeyBits, key.N.BitLen())
+			}
+			privateKeys = append(privateKeys, key)
+		case *rsa.PublicKey:
+			if key.N.BitLen() < minRSAKeyBits {
+				return nil, nil, fmt.Errorf("RSA keys must be at least %d bits, got %d", minRSAKeyBits, key.N.BitLen())
+			}
+			publicKeys = append(publicKeys, key)
+		default:
+			return nil, nil, fmt.Errorf("expected an RSA key, got %T", key)
+		}
+	}
+	return privateKeys, publicKeys, nil
+}
+
+// keyID is the RFC 7638 thumbprint of a public key
+func keyID(key *rsa.PublicKey) string {
+	jwk := publicJWK(key)
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JSONWebKey is a public key in a JWKS (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JSONWebKeySet is the JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func publicJWK(key *rsa.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// JWKS returns the public keys that verify tokens, the signing key first,
+// followed by those retired by a live rotation
+func JWKS() JSONWebKeySet {
+	current, retired := verificationKeys()
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if current != nil {
+		for _, id := range current.ids {
+			jwk := publicJWK(current.public[id])
+			jwk.Kid = id
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	for _, r := range retired {
+		jwk := publicJWK(r.key)
+		jwk.Kid = r.id
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// SigningKeyGenerated reports whether tokens are signed with a key generated
+// at startup because none is configured
+func SigningKeyGenerated() bool {
+	current, _ := verificationKeys()
+	return current == nil || current.generated
+}
+
+// verificationKeys returns the current key set and the retired keys that
+// haven't expired
+func verificationKeys() (current *keySet, retired []retiredKey) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	now := time.Now()
+	for _, r := range retiredKeys {
+		if now.Before(r.until) {
+			retired = append(retired, r)
+		}
+	}
+	return keys, retired
+}
+
+// rotateKeys reloads the key set after JWT_PRIVATE_KEY or JWT_PUBLIC_KEYS
+// rotated. Keys that are no longer configured are retired, so they verify
+// the tokens they signed until those expire. A refresh that rotates both
+// secrets calls it twice, so the keys retired by the first call are kept.
+func rotateKeys() {
+	set, err := loadKeySet()
+	if err != nil {
+		log.Printf("Warning: Ignoring rotated JWT keys: %v", err)
+		return
+	}
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	now := time.Now()
+	var retired []retiredKey
+	for _, r := range retiredKeys {
+		if _, configured := set.public[r.id]; !configured && now.Before(r.until) {
+			retired = append(retired, r)
+		}
+	}
+	if keys != nil {
+		until := now.Add(AccessTokenTTL())
+		for _, id := range keys.ids {
+			if _, configured := set.public[id]; !configured {
+				retired = append(retired, retiredKey{id: id, key: keys.public[id], until: until})
+			}
+		}
+	}
+	retiredKeys = retired
+	keys = set
+	log.Printf("JWT signing key rotated to %s", set.signing.id)
+}
+
+// publicKey finds the key with ID kid among the current and retired keys
+func publicKey(kid string) (*rsa.PublicKey, bool) {
+	current, retired := verificationKeys()
+	if current != nil {
+		if key, ok := current.public[kid]; ok {
+			return key, true
+		}
+	}
+	for _, r := range retired {
+		if r.id == kid {
+			return r.key, true
+		}
+	}
+	return nil, false
+}
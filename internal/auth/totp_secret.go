@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+
+	"saas-go-app/internal/secrets"
+)
+
+// ErrTwoFactorUnavailable is returned when no TOTP encryption key is
+// configured, so TOTP secrets can't be stored
+var ErrTwoFactorUnavailable = errors.New("two-factor authentication is not configured")
+
+var (
+	totpMu          sync.RWMutex
+	totpKey         cipher.AEAD
+	previousTOTPKey cipher.AEAD
+
+	watchTOTPRotation sync.Once
+)
+
+// InitTOTPKey derives the key TOTP secrets are stored under from
+// TOTP_ENCRYPTION_KEY, or from JWT_SECRET when it is not set. When the key
+// rotates, secrets sealed under the previous one still open, and are sealed
+// again under the new key at the user's next login. Without either secret,
+// two-factor authentication can't be enabled.
+func InitTOTPKey() error {
+	name := "TOTP_ENCRYPTION_KEY"
+	secret, err := secrets.Get(name)
+	if errors.Is(err, secrets.ErrNotFound) || (err == nil && secret == "") {
+		name = "JWT_SECRET"
+		secret, err = secrets.Get(name)
+	}
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return err
+	}
+	if secret == "" {
+		log.Println("WARNING: TOTP_ENCRYPTION_KEY and JWT_SECRET not set, two-factor authentication is unavailable")
+		return nil
+	}
+	if name == "JWT_SECRET" {
+		log.Println("WARNING: TOTP_ENCRYPTION_KEY not set, deriving the TOTP key from JWT_SECRET")
+	}
+	if err := setTOTPKey([]byte(secret)); err != nil {
+		return err
+	}
+
+	watchTOTPRotation.Do(func() {
+		secrets.OnRotate(name, func(_, value string) {
+			if err := setTOTPKey([]byte(value)); err != nil {
+				log.Printf("Warning: Failed to rotate TOTP key: %v", err)
+			}
+		})
+	})
+	return nil
+}
+
+// setTOTPKey makes secret the current key and keeps the old one for opening
+func setTOTPKey(secret []byte) error {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("saas-go-app totp v1"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	totpMu.Lock()
+	previousTOTPKey = totpKey
+	totpKey = aead
+	totpMu.Unlock()
+	return nil
+}
+
+func totpKeys() (current, previous cipher.AEAD) {
+	totpMu.RLock()
+	defer totpMu.RUnlock()
+	return totpKey, previousTOTPKey
+}
+
+// SealTOTPSecret encrypts a user's TOTP secret for storage. The ciphertext is
+// bound to the user, so it can't be copied to another user's row.
+func SealTOTPSecret(userID int, secret string) (string, error) {
+	aead, _ := totpKeys()
+	if aead == nil {
+		return "", ErrTwoFactorUnavailable
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(strconv.Itoa(userID)))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenTOTPSecret decrypts a secret sealed by SealTOTPSecret for the same
+// user. stale reports that it was sealed under the previous key and should be
+// sealed again.
+func OpenTOTPSecret(userID int, sealed string) (secret string, stale bool, err error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", false, errors.New("invalid sealed TOTP secret")
+	}
+	current, previous := totpKeys()
+	if current == nil {
+		return "", false, ErrTwoFactorUnavailable
+	}
+	plaintext, err := openTOTPSecret(current, raw, userID)
+	if err != nil && previous != nil {
+		plaintext, err = openTOTPSecret(previous, raw, userID)
+		stale = err == nil
+	}
+	if err != nil {
+		return "", false, errors.New("failed to open TOTP secret")
+	}
+	return string(plaintext), stale, nil
+}
+
+func openTOTPSecret(aead cipher.AEAD, raw []byte, userID int) ([]byte, error) {
+	if len(raw) < aead.NonceSize() {
+		return nil, errors.New("sealed TOTP secret too short")
+	}
+	nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(strconv.Itoa(userID)))
+}
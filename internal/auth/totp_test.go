@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for at, expected := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(at, 0)))
+		if err != nil {
+			t.Fatalf("Failed to compute code: %v", err)
+		}
+		if code != expected {
+			t.Errorf("Expected %s at %d, got %s", expected, at, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	now := time.Now()
+	step := TOTPStep(now)
+	previous, _ := TOTPCode(secret, step-1)
+	stale, _ := TOTPCode(secret, step-2)
+
+	if matched, ok := ValidateTOTP(secret, previous, now, 0); !ok || matched != step-1 {
+		t.Errorf("Expected the previous step's code to be accepted, got %d %v", matched, ok)
+	}
+	// Unless the two codes happen to be the same
+	if _, ok := ValidateTOTP(secret, stale, now, 0); ok && stale != previous {
+		t.Error("Expected a code two steps old to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, previous, now, step-1); ok {
+		t.Error("Expected a used code to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Error("Expected a short code to be rejected")
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	t.Setenv("TOTP_ISSUER", "Acme")
+	url := TOTPURL("alice", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(url, "otpauth://totp/Acme:alice?") || !strings.Contains(url, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(url, "issuer=Acme") {
+		t.Errorf("Unexpected otpauth URL %s", url)
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, err := NewBackupCodes()
+	if err != nil {
+		t.Fatalf("Failed to generate backup codes: %v", err)
+	}
+	if len(codes) != backupCodeCount || len(codes[0]) != 11 || IsTOTPCode(codes[0]) {
+		t.Errorf("Unexpected backup codes %v", codes)
+	}
+	if HashBackupCode(codes[0]) != HashBackupCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))) {
+		t.Error("Expected backup codes to match regardless of case and separators")
+	}
+}
+
+func TestSealTOTPSecret(t *testing.T) {
+	current, previous := totpKeys()
+	t.Cleanup(func() { totpKey, previousTOTPKey = current, previous })
+
+	if err := setTOTPKey([]byte("first")); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+	sealed, err := SealTOTPSecret(1, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Failed to seal secret: %v", err)
+	}
+	if _, _, err := OpenTOTPSecret(2, sealed); err == nil {
+		t.Error("Expected a secret sealed for another user not to open")
+	}
+
+	if err := setTOTPKey([]byte("second")); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	secret, stale, err := OpenTOTPSecret(1, sealed)
+	if err != nil || secret != "JBSWY3DPEHPK3PXP" || !stale {
+		t.Errorf("Expected the secret to open under the previous key, got %q %v %v", secret, stale, err)
+	}
+}
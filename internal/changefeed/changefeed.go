@@ -0,0 +1,248 @@
+// Package changefeed fans out committed changes to customers and accounts to
+// in-process subscribers. Triggers send each change as a notification on the
+// saas_changes channel (see migrations/0022_change_notify.up.sql), and each
+// process LISTENs on one dedicated connection to the primary, so features
+// such as SSE streams and webhooks don't have to poll for changes.
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Channel is the notification channel the triggers send changes on
+const Channel = "saas_changes"
+
+// Actions of a change
+const (
+	Insert = "insert"
+	Update = "update"
+	Delete = "delete"
+	// Gap tells a subscriber it missed changes, because the listener lost its
+	// connection or the subscriber fell behind; reload what it tracks
+	Gap = "gap"
+)
+
+// subscriberBuffer is how far a subscriber can fall behind before it misses
+// changes
+const subscriberBuffer = 256
+
+// maxReconnectBackoff caps the wait between attempts to listen again
+const maxReconnectBackoff = 30 * time.Second
+
+var (
+	listening = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "changefeed_listening",
+		Help: "1 while the change feed is listening for notifications.",
+	})
+	notificationsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "changefeed_notifications_total",
+		Help: "Change notifications received, by entity.",
+	}, []string{"entity"})
+	changesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "changefeed_dropped_total",
+		Help: "Changes not delivered to a subscriber that had fallen behind.",
+	})
+	subscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "changefeed_subscribers",
+		Help: "Current change feed subscriptions.",
+	})
+)
+
+// Change is a committed insert, update, or delete of a customer or account.
+// It identifies the row; subscribers read the row if they need it.
+type Change struct {
+	// Entity is customers or accounts, empty for a Gap
+	Entity  string `json:"entity,omitempty"`
+	ID      int    `json:"id,omitempty"`
+	UUID    string `json:"uuid,omitempty"`
+	Action  string `json:"action"`
+	Version int    `json:"version,omitempty"`
+	// Actor is the user whose request made the change, or system
+	Actor string `json:"actor,omitempty"`
+}
+
+// Feed delivers changes to its subscribers
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Default is the feed the process listens with
+var Default = NewFeed()
+
+// NewFeed returns a feed without subscribers
+func NewFeed() *Feed {
+	return &Feed{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives changes on C until it is closed
+type Subscription struct {
+	C <-chan Change
+
+	c        chan Change
+	entities map[string]bool
+	feed     *Feed
+	// missed is set while changes were dropped and the Gap telling the
+	// subscriber so hasn't been delivered yet
+	missed bool
+}
+
+// Subscribe returns a subscription to changes of entities, or of all
+// entities if none are given. A subscriber that falls behind by more than
+// 256 changes misses changes and then receives a Gap.
+func (f *Feed) Subscribe(entities ...string) *Subscription {
+	c := make(chan Change, subscriberBuffer)
+	s := &Subscription{C: c, c: c, feed: f}
+	if len(entities) > 0 {
+		s.entities = make(map[string]bool, len(entities))
+		for _, entity := range entities {
+			s.entities[entity] = true
+		}
+	}
+	f.mu.Lock()
+	f.subscribers[s] = struct{}{}
+	f.mu.Unlock()
+	subscribers.Inc()
+	return s
+}
+
+// Close stops the subscription and closes C
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if _, ok := s.feed.subscribers[s]; !ok {
+		return
+	}
+	delete(s.feed.subscribers, s)
+	close(s.c)
+	subscribers.Dec()
+}
+
+// Publish delivers change to the subscribers of its entity without waiting;
+// a Gap goes to every subscriber
+func (f *Feed) Publish(change Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s := range f.subscribers {
+		if change.Action != Gap && s.entities != nil && !s.entities[change.Entity] {
+			continue
+		}
+		if s.missed {
+			select {
+			case s.c <- Change{Action: Gap}:
+				s.missed = false
+			default:
+				changesDropped.Inc()
+				continue
+			}
+			if change.Action == Gap {
+				continue
+			}
+		}
+		select {
+		case s.c <- change:
+		default:
+			s.missed = true
+			changesDropped.Inc()
+		}
+	}
+}
+
+// Enabled reads CHANGEFEED_ENABLED (default true). Listening holds one
+// connection to the primary per process.
+func Enabled() bool {
+	value := os.Getenv("CHANGEFEED_ENABLED")
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for CHANGEFEED_ENABLED (%s), using default true", value)
+		return true
+	}
+	return enabled
+}
+
+// connect opens the connection the feed listens on; tests replace it
+var connect = func(ctx context.Context) (*pgx.Conn, error) {
+	// A connection of its own, since a pooled one would be handed back and
+	// stop listening
+	return pgx.ConnectConfig(ctx, db.PrimaryPgx.Config().ConnConfig.Copy())
+}
+
+// Start listens for changes in the background until ctx is done. If the
+// connection is lost it listens again, with backoff, and sends subscribers a
+// Gap, since changes committed in between were missed.
+func (f *Feed) Start(ctx context.Context) {
+	go func() {
+		backoff := time.Second
+		listened := false
+		for {
+			started := time.Now()
+			err := f.listen(ctx, func() {
+				if listened {
+					f.Publish(Change{Action: Gap})
+				}
+				listened = true
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) > maxReconnectBackoff {
+				backoff = time.Second
+			}
+			log.Printf("Warning: Change feed stopped listening, retrying in %v: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxReconnectBackoff {
+				backoff = maxReconnectBackoff
+			}
+		}
+	}()
+}
+
+// listen LISTENs on Channel, calls onListening, and publishes notifications
+// until the connection fails or ctx is done
+func (f *Feed) listen(ctx context.Context, onListening func()) error {
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	listening.Set(1)
+	defer listening.Set(0)
+	onListening()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var change Change
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			log.Printf("Warning: Ignoring change notification %q: %v", notification.Payload, err)
+			continue
+		}
+		notificationsReceived.WithLabelValues(change.Entity).Inc()
+		f.Publish(change)
+	}
+}
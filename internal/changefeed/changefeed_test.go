@@ -0,0 +1,115 @@
+package changefeed
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/db"
+)
+
+func TestPublishFiltersByEntity(t *testing.T) {
+	feed := NewFeed()
+	all := feed.Subscribe()
+	defer all.Close()
+	accounts := feed.Subscribe("accounts")
+	defer accounts.Close()
+
+	feed.Publish(Change{Entity: "customers", ID: 1, Action: Insert})
+	feed.Publish(Change{Entity: "accounts", ID: 2, Action: Update})
+
+	if got := len(all.C); got != 2 {
+		t.Errorf("Expected 2 changes for the unfiltered subscriber, got %d", got)
+	}
+	if got := <-accounts.C; got.Entity != "accounts" || got.ID != 2 || len(accounts.C) != 0 {
+		t.Errorf("Expected only the account change, got %+v and %d more", got, len(accounts.C))
+	}
+}
+
+func TestPublishSendsGapAfterFallingBehind(t *testing.T) {
+	feed := NewFeed()
+	s := feed.Subscribe()
+	defer s.Close()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		feed.Publish(Change{Entity: "customers", ID: i + 1, Action: Update})
+	}
+	for i := 0; i < subscriberBuffer; i++ {
+		<-s.C
+	}
+	feed.Publish(Change{Entity: "customers", ID: 1000, Action: Update})
+	if got := <-s.C; got.Action != Gap {
+		t.Fatalf("Expected a gap after missing a change, got %+v", got)
+	}
+	if got := <-s.C; got.ID != 1000 {
+		t.Errorf("Expected the next change after the gap, got %+v", got)
+	}
+}
+
+func TestCloseStopsDelivery(t *testing.T) {
+	feed := NewFeed()
+	s := feed.Subscribe()
+	s.Close()
+	s.Close()
+	feed.Publish(Change{Entity: "customers", ID: 1, Action: Delete})
+	if _, ok := <-s.C; ok {
+		t.Error("Expected a closed channel")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("CHANGEFEED_ENABLED", "false")
+	if Enabled() {
+		t.Error("Expected the feed to be disabled")
+	}
+	t.Setenv("CHANGEFEED_ENABLED", "maybe")
+	if !Enabled() {
+		t.Error("Expected the default for an invalid value")
+	}
+}
+
+func TestFeedReceivesCommittedChanges(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer db.CloseDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	feed := NewFeed()
+	s := feed.Subscribe("customers")
+	defer s.Close()
+	ready := make(chan struct{})
+	go feed.listen(ctx, func() { close(ready) })
+	select {
+	case <-ready:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting to listen")
+	}
+
+	var id int
+	email := "changefeed-" + time.Now().Format("20060102150405.000000") + "@example.com"
+	if err := db.PrimaryDB.QueryRow("INSERT INTO customers (name, email) VALUES ('Change Feed', $1) RETURNING id", email).Scan(&id); err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	defer db.PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", id)
+
+	select {
+	case change := <-s.C:
+		if change.Entity != "customers" || change.ID != id || change.Action != Insert || change.UUID == "" || change.Actor != "system" {
+			t.Errorf("Expected the insert of customer %d by system, got %+v", id, change)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the change")
+	}
+}
@@ -0,0 +1,227 @@
+// Package changelog describes the API's changes (new endpoints, deprecations,
+// schema changes) release by release, from releases.json embedded at build
+// time. Add an entry to the newest release, or start a new one, with every
+// change to the API.
+package changelog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed releases.json
+var releasesJSON []byte
+
+// Types of change
+const (
+	Added      = "added"
+	Changed    = "changed"
+	Deprecated = "deprecated"
+	Removed    = "removed"
+	Schema     = "schema"
+)
+
+var types = []string{Added, Changed, Deprecated, Removed, Schema}
+
+// dateLayout is the format of release dates and sunsets
+const dateLayout = "2006-01-02"
+
+// ErrInvalidVersion is returned for a version that isn't MAJOR.MINOR.PATCH
+var ErrInvalidVersion = errors.New("invalid version, expected MAJOR.MINOR.PATCH")
+
+// Change is a change to the API
+type Change struct {
+	// Type is added, changed, deprecated, removed, or schema
+	Type   string `json:"type"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// Schema is the model a schema change is to
+	Schema      string `json:"schema,omitempty"`
+	Description string `json:"description"`
+	// Breaking changes need clients to change, now or, for deprecations, by
+	// the sunset
+	Breaking bool `json:"breaking"`
+	// Sunset is the date (YYYY-MM-DD) a deprecated feature goes away, if set
+	Sunset string `json:"sunset,omitempty"`
+}
+
+// Release is the changes of one API version, newest first
+type Release struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date"`
+	Changes []Change `json:"changes"`
+}
+
+// UpcomingChange is a deprecation that still works, and the version that
+// announced it
+type UpcomingChange struct {
+	Change
+	Version string `json:"version"`
+}
+
+// Feed is the changelog as served by GET /changelog
+type Feed struct {
+	Latest   string    `json:"latest"`
+	Releases []Release `json:"releases"`
+	// Upcoming lists the deprecations that haven't reached their sunset, so
+	// clients can move off them in time
+	Upcoming []UpcomingChange `json:"upcoming"`
+}
+
+var (
+	loadOnce sync.Once
+	releases []Release
+	loadErr  error
+)
+
+// Releases returns the embedded releases, newest first
+func Releases() ([]Release, error) {
+	loadOnce.Do(func() {
+		releases, loadErr = parse(releasesJSON)
+	})
+	return releases, loadErr
+}
+
+// Latest returns the newest API version, or "" if the changelog is invalid
+func Latest() string {
+	all, err := Releases()
+	if err != nil || len(all) == 0 {
+		return ""
+	}
+	return all[0].Version
+}
+
+// parse reads and checks releases: known change types, valid dates, and
+// versions going down
+func parse(data []byte) ([]Release, error) {
+	var all []Release
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	var previous [3]int
+	for i, release := range all {
+		version, err := parseVersion(release.Version)
+		if err != nil {
+			return nil, fmt.Errorf("release %d: %w", i, err)
+		}
+		if i > 0 && compareVersions(version, previous) >= 0 {
+			return nil, fmt.Errorf("release %s is listed after %s, expected newest first", release.Version, all[i-1].Version)
+		}
+		previous = version
+		if _, err := time.Parse(dateLayout, release.Date); err != nil {
+			return nil, fmt.Errorf("release %s: invalid date %q", release.Version, release.Date)
+		}
+		for _, change := range release.Changes {
+			if !validType(change.Type) {
+				return nil, fmt.Errorf("release %s: unknown change type %q, expected one of: %s", release.Version, change.Type, strings.Join(types, ", "))
+			}
+			if change.Description == "" {
+				return nil, fmt.Errorf("release %s: %s change without a description", release.Version, change.Type)
+			}
+			if change.Sunset != "" {
+				if change.Type != Deprecated {
+					return nil, fmt.Errorf("release %s: only deprecations have a sunset", release.Version)
+				}
+				if _, err := time.Parse(dateLayout, change.Sunset); err != nil {
+					return nil, fmt.Errorf("release %s: invalid sunset %q", release.Version, change.Sunset)
+				}
+			}
+		}
+	}
+	return all, nil
+}
+
+func validType(changeType string) bool {
+	for _, t := range types {
+		if t == changeType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVersion parses MAJOR.MINOR.PATCH
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, ErrInvalidVersion
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, ErrInvalidVersion
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// FeedOptions select the part of the changelog a client needs
+type FeedOptions struct {
+	// Since leaves out releases up to and including this version
+	Since string
+	// BreakingOnly leaves out changes that aren't breaking
+	BreakingOnly bool
+}
+
+// BuildFeed returns the changelog selected by opts, with the deprecations
+// still before their sunset on now. It returns ErrInvalidVersion for an
+// invalid Since.
+func BuildFeed(opts FeedOptions, now time.Time) (Feed, error) {
+	var since [3]int
+	if opts.Since != "" {
+		var err error
+		if since, err = parseVersion(opts.Since); err != nil {
+			return Feed{}, err
+		}
+	}
+	all, err := Releases()
+	if err != nil {
+		return Feed{}, err
+	}
+
+	feed := Feed{Latest: Latest(), Releases: []Release{}, Upcoming: []UpcomingChange{}}
+	today := now.UTC().Format(dateLayout)
+	for _, release := range all {
+		for _, change := range release.Changes {
+			if change.Type == Deprecated && (change.Sunset == "" || change.Sunset > today) {
+				feed.Upcoming = append(feed.Upcoming, UpcomingChange{Change: change, Version: release.Version})
+			}
+		}
+		version, _ := parseVersion(release.Version)
+		if opts.Since != "" && compareVersions(version, since) <= 0 {
+			continue
+		}
+		changes := []Change{}
+		for _, change := range release.Changes {
+			if !opts.BreakingOnly || change.Breaking {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) == 0 && opts.BreakingOnly {
+			continue
+		}
+		release.Changes = changes
+		feed.Releases = append(feed.Releases, release)
+	}
+	return feed, nil
+}
@@ -0,0 +1,78 @@
+package changelog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEmbeddedReleasesAreValid(t *testing.T) {
+	all, err := Releases()
+	if err != nil {
+		t.Fatalf("Invalid releases.json: %v", err)
+	}
+	if len(all) == 0 || Latest() != all[0].Version {
+		t.Errorf("Expected the latest version to be the first release, got %q", Latest())
+	}
+}
+
+func TestParseRejectsInvalidReleases(t *testing.T) {
+	for name, data := range map[string]string{
+		"bad version":  `[{"version": "1.2", "date": "2026-01-01", "changes": []}]`,
+		"oldest first": `[{"version": "1.0.0", "date": "2026-01-01", "changes": []}, {"version": "1.1.0", "date": "2026-02-01", "changes": []}]`,
+		"bad date":     `[{"version": "1.0.0", "date": "January", "changes": []}]`,
+		"bad type":     `[{"version": "1.0.0", "date": "2026-01-01", "changes": [{"type": "fixed", "description": "x"}]}]`,
+		"bad sunset":   `[{"version": "1.0.0", "date": "2026-01-01", "changes": [{"type": "deprecated", "description": "x", "sunset": "soon"}]}]`,
+		"added sunset": `[{"version": "1.0.0", "date": "2026-01-01", "changes": [{"type": "added", "description": "x", "sunset": "2027-01-01"}]}]`,
+	} {
+		if _, err := parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBuildFeed(t *testing.T) {
+	// Load the embedded releases first, so they don't replace the test's
+	Releases()
+	previous := releases
+	t.Cleanup(func() { releases = previous })
+	var err error
+	releases, err = parse([]byte(`[
+		{"version": "1.10.0", "date": "2026-03-01", "changes": [
+			{"type": "deprecated", "path": "/old", "description": "Old endpoint", "breaking": true, "sunset": "2026-06-01"},
+			{"type": "added", "path": "/new", "description": "New endpoint"}
+		]},
+		{"version": "1.9.0", "date": "2026-02-01", "changes": [
+			{"type": "deprecated", "path": "/older", "description": "Older endpoint", "breaking": true, "sunset": "2026-03-01"},
+			{"type": "added", "path": "/older/v2", "description": "Replacement"}
+		]}
+	]`))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	feed, err := BuildFeed(FeedOptions{}, now)
+	if err != nil {
+		t.Fatalf("Failed to build feed: %v", err)
+	}
+	if feed.Latest != "1.10.0" || len(feed.Releases) != 2 {
+		t.Errorf("Expected both releases with latest 1.10.0, got %+v", feed)
+	}
+	if len(feed.Upcoming) != 1 || feed.Upcoming[0].Path != "/old" || feed.Upcoming[0].Version != "1.10.0" {
+		t.Errorf("Expected only the deprecation before its sunset to be upcoming, got %+v", feed.Upcoming)
+	}
+
+	// 1.10.0 is newer than 1.9.0, not older as strings would have it
+	feed, _ = BuildFeed(FeedOptions{Since: "1.9.0", BreakingOnly: true}, now)
+	if len(feed.Releases) != 1 || feed.Releases[0].Version != "1.10.0" || len(feed.Releases[0].Changes) != 1 || feed.Releases[0].Changes[0].Path != "/old" {
+		t.Errorf("Expected the breaking change of 1.10.0, got %+v", feed.Releases)
+	}
+	if len(feed.Upcoming) != 1 {
+		t.Errorf("Expected upcoming changes regardless of since, got %+v", feed.Upcoming)
+	}
+
+	if _, err := BuildFeed(FeedOptions{Since: "v1"}, now); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("Expected ErrInvalidVersion, got %v", err)
+	}
+}
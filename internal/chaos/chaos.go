@@ -0,0 +1,173 @@
+// Package chaos injects faults for resilience demos: extra latency or errors on
+// a percentage of database calls, and circuit breakers forced open for named
+// outbound destinations. Faults are off until an admin turns them on, only when
+// CHAOS_ENABLED=true, and they always switch themselves off after a duration.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrInjected is returned by database calls failed on purpose
+var ErrInjected = errors.New("chaos: injected database error")
+
+const (
+	// DefaultDuration is how long faults stay on when no duration is given
+	DefaultDuration = 5 * time.Minute
+	// MaxDuration caps how long faults can stay on, so a forgotten demo
+	// cannot degrade the app indefinitely
+	MaxDuration = time.Hour
+)
+
+var injections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "chaos_injections_total",
+	Help: "Faults injected by the chaos settings, by kind.",
+}, []string{"kind"})
+
+// Settings are the active faults. Percentages are 0-100.
+type Settings struct {
+	DBLatencyMS      int      `json:"db_latency_ms"`
+	DBLatencyPercent float64  `json:"db_latency_percent"`
+	DBErrorPercent   float64  `json:"db_error_percent"`
+	OpenCircuits     []string `json:"open_circuits"`
+	// Duration is how long the faults stay on, e.g. "10m" (default 5m, max 1h)
+	Duration  string     `json:"duration,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Active reports whether any fault is configured
+func (s Settings) Active() bool {
+	return (s.DBLatencyMS > 0 && s.DBLatencyPercent > 0) || s.DBErrorPercent > 0 || len(s.OpenCircuits) > 0
+}
+
+var (
+	mu      sync.RWMutex
+	current Settings
+)
+
+// Enabled reports whether chaos endpoints may turn faults on (CHAOS_ENABLED=true)
+func Enabled() bool {
+	return os.Getenv("CHAOS_ENABLED") == "true"
+}
+
+// Current returns the active faults, or zero settings once they have expired
+func Current() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active(time.Now())
+}
+
+// active must be called with mu held
+func active(now time.Time) Settings {
+	if current.ExpiresAt == nil || !now.Before(*current.ExpiresAt) {
+		return Settings{OpenCircuits: []string{}}
+	}
+	settings := current
+	settings.OpenCircuits = append([]string{}, current.OpenCircuits...)
+	return settings
+}
+
+// Set validates and applies new faults, replacing any already active, and
+// returns them with their expiry filled in
+func Set(settings Settings) (Settings, error) {
+	if settings.DBLatencyMS < 0 || settings.DBLatencyMS > 30000 {
+		return Settings{}, errors.New("db_latency_ms must be between 0 and 30000")
+	}
+	for _, percent := range []float64{settings.DBLatencyPercent, settings.DBErrorPercent} {
+		if percent < 0 || percent > 100 {
+			return Settings{}, errors.New("percentages must be between 0 and 100")
+		}
+	}
+
+	duration := DefaultDuration
+	if settings.Duration != "" {
+		parsed, err := time.ParseDuration(settings.Duration)
+		if err != nil || parsed <= 0 {
+			return Settings{}, errors.New("duration must be a positive Go duration such as 10m")
+		}
+		if parsed > MaxDuration {
+			return Settings{}, errors.New("duration must be at most 1h")
+		}
+		duration = parsed
+	}
+	if settings.OpenCircuits == nil {
+		settings.OpenCircuits = []string{}
+	}
+
+	expiresAt := time.Now().Add(duration).UTC()
+	settings.Duration = duration.String()
+	settings.ExpiresAt = &expiresAt
+
+	mu.Lock()
+	current = settings
+	mu.Unlock()
+	return Current(), nil
+}
+
+// Reset turns every fault off
+func Reset() {
+	mu.Lock()
+	current = Settings{}
+	mu.Unlock()
+}
+
+type exemptKey struct{}
+
+// Exempt marks ctx so database calls made with it are never faulted. The chaos
+// endpoints use it so faults can always be switched off again.
+func Exempt(ctx context.Context) context.Context {
+	return context.WithValue(ctx, exemptKey{}, true)
+}
+
+// DB applies the database faults to one call: it may sleep, and it returns
+// ErrInjected for the share of calls that should fail
+func DB(ctx context.Context) error {
+	mu.RLock()
+	settings := active(time.Now())
+	mu.RUnlock()
+	if !settings.Active() || ctx.Value(exemptKey{}) != nil {
+		return nil
+	}
+
+	if settings.DBLatencyMS > 0 && roll(settings.DBLatencyPercent) {
+		injections.WithLabelValues("db_latency").Inc()
+		timer := time.NewTimer(time.Duration(settings.DBLatencyMS) * time.Millisecond)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if roll(settings.DBErrorPercent) {
+		injections.WithLabelValues("db_error").Inc()
+		return ErrInjected
+	}
+	return nil
+}
+
+// CircuitOpen reports whether the breaker for destination is forced open
+func CircuitOpen(destination string) bool {
+	mu.RLock()
+	settings := active(time.Now())
+	mu.RUnlock()
+	for _, name := range settings.OpenCircuits {
+		if name == destination {
+			injections.WithLabelValues("circuit_open").Inc()
+			return true
+		}
+	}
+	return false
+}
+
+func roll(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
@@ -0,0 +1,89 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetValidates(t *testing.T) {
+	defer Reset()
+
+	invalid := []Settings{
+		{DBLatencyMS: -1},
+		{DBErrorPercent: 101},
+		{DBLatencyPercent: -5},
+		{Duration: "soon"},
+		{Duration: "2h"},
+	}
+	for _, settings := range invalid {
+		if _, err := Set(settings); err == nil {
+			t.Errorf("Expected %+v to be rejected", settings)
+		}
+	}
+	if Current().Active() {
+		t.Error("Expected rejected settings not to be applied")
+	}
+}
+
+func TestDBInjectsErrorsUntilReset(t *testing.T) {
+	defer Reset()
+
+	settings, err := Set(Settings{DBErrorPercent: 100})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if settings.Duration != DefaultDuration.String() || settings.ExpiresAt == nil {
+		t.Errorf("Expected default duration and an expiry, got %+v", settings)
+	}
+
+	if err := DB(context.Background()); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected injected error, got %v", err)
+	}
+	if err := DB(Exempt(context.Background())); err != nil {
+		t.Errorf("Expected exempt context to pass, got %v", err)
+	}
+
+	Reset()
+	if err := DB(context.Background()); err != nil {
+		t.Errorf("Expected no error after reset, got %v", err)
+	}
+}
+
+func TestFaultsExpire(t *testing.T) {
+	defer Reset()
+
+	if _, err := Set(Settings{OpenCircuits: []string{"webhooks"}, Duration: "20ms"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if !CircuitOpen("webhooks") {
+		t.Error("Expected webhooks circuit to be forced open")
+	}
+	if CircuitOpen("stripe") {
+		t.Error("Expected other destinations to be unaffected")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if CircuitOpen("webhooks") || Current().Active() {
+		t.Error("Expected faults to switch off after their duration")
+	}
+}
+
+func TestDBLatencyRespectsContext(t *testing.T) {
+	defer Reset()
+
+	if _, err := Set(Settings{DBLatencyMS: 5000, DBLatencyPercent: 100}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := DB(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected injected latency to stop at the context deadline")
+	}
+}
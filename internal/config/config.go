@@ -0,0 +1,273 @@
+// Package config holds runtime settings that can be reloaded without a restart,
+// either on SIGHUP or through the admin API. Settings are read from the
+// environment, then .env, then an optional JSON file named by CONFIG_FILE.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// Log levels in increasing order of severity
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// Settings are the hot-reloadable application settings
+type Settings struct {
+	LogLevel           string          `json:"log_level"`
+	RateLimitPerMinute int             `json:"rate_limit_per_minute"`
+	FeatureFlags       map[string]bool `json:"feature_flags"`
+	AnalyticsRouting   string          `json:"analytics_routing"`
+	// MaintenanceMode is "readonly" to reject writes, or "" for none
+	MaintenanceMode string `json:"maintenance_mode"`
+	// ShadowReadPercent is the share of analytics reads, 0-100, also run on
+	// the follower and compared while analytics_routing is primary
+	ShadowReadPercent float64 `json:"shadow_read_percent"`
+}
+
+// ReadOnlyMaintenance is the maintenance mode that rejects writes and serves
+// reads from the follower
+const ReadOnlyMaintenance = "readonly"
+
+// Change describes a single setting that changed during a reload
+type Change struct {
+	Setting string      `json:"setting"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+}
+
+// ChangeHook is called after settings change, e.g. to audit the change
+type ChangeHook func(changes []Change, source, actor string)
+
+var (
+	current atomic.Pointer[Settings]
+
+	hooksMu sync.Mutex
+	hooks   []ChangeHook
+)
+
+func init() {
+	Load()
+}
+
+// Load reads settings from the environment without notifying change hooks.
+// Call it after loading .env at startup.
+func Load() {
+	settings := fromEnv()
+	if err := settings.Validate(); err != nil {
+		log.Printf("Warning: %v, using defaults", err)
+		settings = Settings{LogLevel: "info", FeatureFlags: settings.FeatureFlags, AnalyticsRouting: "follower"}
+	}
+	current.Store(&settings)
+}
+
+// WatchSignals reloads settings whenever the process receives SIGHUP
+func WatchSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if _, err := Reload("sighup", "system"); err != nil {
+				log.Printf("Warning: Failed to reload config: %v", err)
+			}
+		}
+	}()
+}
+
+// Current returns the active settings
+func Current() Settings {
+	return *current.Load()
+}
+
+// FeatureEnabled reports whether a feature flag is turned on
+func FeatureEnabled(flag string) bool {
+	return Current().FeatureFlags[flag]
+}
+
+// ReadOnly reports whether MAINTENANCE_MODE=readonly is rejecting writes
+func ReadOnly() bool {
+	return Current().MaintenanceMode == ReadOnlyMaintenance
+}
+
+// LogEnabled reports whether messages at the given level should be logged
+func LogEnabled(level string) bool {
+	return logLevels[level] >= logLevels[Current().LogLevel]
+}
+
+// Debugf logs a message only when LOG_LEVEL is debug
+func Debugf(format string, args ...interface{}) {
+	if LogEnabled("debug") {
+		log.Printf(format, args...)
+	}
+}
+
+// OnChange registers a hook called whenever settings change
+func OnChange(hook ChangeHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// Reload re-reads settings from the environment, .env, and CONFIG_FILE
+func Reload(source, actor string) ([]Change, error) {
+	// Values in .env override the process environment so they can be edited in place
+	if _, err := os.Stat(".env"); err == nil {
+		if err := godotenv.Overload(); err != nil {
+			return nil, fmt.Errorf("failed to read .env: %w", err)
+		}
+	}
+
+	settings := fromEnv()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	return Apply(settings, source, actor)
+}
+
+// Apply validates and activates new settings, notifying hooks of any changes
+func Apply(settings Settings, source, actor string) ([]Change, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	old := current.Swap(&settings)
+	changes := diff(*old, settings)
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		log.Printf("Config changed by %s via %s: %s %v -> %v", actor, source, change.Setting, change.Old, change.New)
+	}
+
+	hooksMu.Lock()
+	registered := append([]ChangeHook(nil), hooks...)
+	hooksMu.Unlock()
+	for _, hook := range registered {
+		hook(changes, source, actor)
+	}
+
+	return changes, nil
+}
+
+// Validate checks that settings hold supported values
+func (s Settings) Validate() error {
+	if _, ok := logLevels[s.LogLevel]; !ok {
+		return fmt.Errorf("invalid log_level %q (expected debug, info, warn, or error)", s.LogLevel)
+	}
+	if s.RateLimitPerMinute < 0 {
+		return fmt.Errorf("rate_limit_per_minute must not be negative")
+	}
+	if s.AnalyticsRouting != "follower" && s.AnalyticsRouting != "primary" {
+		return fmt.Errorf("invalid analytics_routing %q (expected follower or primary)", s.AnalyticsRouting)
+	}
+	if s.MaintenanceMode != "" && s.MaintenanceMode != ReadOnlyMaintenance {
+		return fmt.Errorf("invalid maintenance_mode %q (expected readonly or empty)", s.MaintenanceMode)
+	}
+	if s.ShadowReadPercent < 0 || s.ShadowReadPercent > 100 {
+		return fmt.Errorf("shadow_read_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+func fromEnv() Settings {
+	settings := Settings{
+		LogLevel:         strings.ToLower(os.Getenv("LOG_LEVEL")),
+		FeatureFlags:     parseFeatureFlags(os.Getenv("FEATURE_FLAGS")),
+		AnalyticsRouting: strings.ToLower(os.Getenv("ANALYTICS_ROUTING")),
+		MaintenanceMode:  strings.ToLower(os.Getenv("MAINTENANCE_MODE")),
+	}
+	if settings.LogLevel == "" {
+		settings.LogLevel = "info"
+	}
+	if settings.AnalyticsRouting == "" {
+		settings.AnalyticsRouting = "follower"
+	}
+	if value := os.Getenv("RATE_LIMIT_PER_MINUTE"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("Warning: Invalid value for RATE_LIMIT_PER_MINUTE (%s), rate limiting disabled", value)
+		}
+		settings.RateLimitPerMinute = limit
+	}
+	if value := os.Getenv("SHADOW_READ_PERCENT"); value != "" {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("Warning: Invalid value for SHADOW_READ_PERCENT (%s), shadow reads disabled", value)
+		}
+		settings.ShadowReadPercent = percent
+	}
+	return settings
+}
+
+// parseFeatureFlags parses "flag_a,flag_b=false,!flag_c" into a flag map
+func parseFeatureFlags(value string) map[string]bool {
+	flags := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "!") {
+			flags[entry[1:]] = false
+			continue
+		}
+		name, enabled, found := strings.Cut(entry, "=")
+		flags[name] = !found || enabled == "true" || enabled == "1"
+	}
+	return flags
+}
+
+func diff(old, new Settings) []Change {
+	changes := []Change{}
+	if old.LogLevel != new.LogLevel {
+		changes = append(changes, Change{"log_level", old.LogLevel, new.LogLevel})
+	}
+	if old.RateLimitPerMinute != new.RateLimitPerMinute {
+		changes = append(changes, Change{"rate_limit_per_minute", old.RateLimitPerMinute, new.RateLimitPerMinute})
+	}
+	if old.AnalyticsRouting != new.AnalyticsRouting {
+		changes = append(changes, Change{"analytics_routing", old.AnalyticsRouting, new.AnalyticsRouting})
+	}
+	if old.ShadowReadPercent != new.ShadowReadPercent {
+		changes = append(changes, Change{"shadow_read_percent", old.ShadowReadPercent, new.ShadowReadPercent})
+	}
+	if !reflect.DeepEqual(old.FeatureFlags, new.FeatureFlags) {
+		names := make(map[string]bool)
+		for name := range old.FeatureFlags {
+			names[name] = true
+		}
+		for name := range new.FeatureFlags {
+			names[name] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			if old.FeatureFlags[name] != new.FeatureFlags[name] {
+				changes = append(changes, Change{"feature_flags." + name, old.FeatureFlags[name], new.FeatureFlags[name]})
+			}
+		}
+	}
+	return changes
+}
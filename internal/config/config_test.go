@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFeatureFlags(t *testing.T) {
+	flags := parseFeatureFlags("beta_ui, heatmap=false,!legacy,export=true")
+	expected := map[string]bool{"beta_ui": true, "heatmap": false, "legacy": false, "export": true}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("Expected %v, got %v", expected, flags)
+	}
+}
+
+func TestApplyReportsChanges(t *testing.T) {
+	Load()
+	original := Current()
+	defer Apply(original, "test", "test")
+
+	var hooked []Change
+	OnChange(func(changes []Change, source, actor string) {
+		hooked = changes
+	})
+
+	updated := original
+	updated.LogLevel = "debug"
+	updated.AnalyticsRouting = "primary"
+	changes, err := Apply(updated, "test", "tester")
+	if err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+
+	if len(changes) != 2 || len(hooked) != 2 {
+		t.Errorf("Expected 2 changes reported to caller and hook, got %d and %d", len(changes), len(hooked))
+	}
+	if !LogEnabled("debug") {
+		t.Error("Expected debug logging to be enabled")
+	}
+}
+
+func TestApplyRejectsInvalidSettings(t *testing.T) {
+	Load()
+	invalid := Current()
+	invalid.AnalyticsRouting = "somewhere"
+	if _, err := Apply(invalid, "test", "tester"); err == nil {
+		t.Error("Expected invalid analytics routing to be rejected")
+	}
+	if Current().AnalyticsRouting == "somewhere" {
+		t.Error("Invalid settings must not be activated")
+	}
+
+	invalid = Current()
+	invalid.MaintenanceMode = "on"
+	if _, err := Apply(invalid, "test", "tester"); err == nil {
+		t.Error("Expected an unknown maintenance mode to be rejected")
+	}
+}
+
+func TestValidateShadowReadPercent(t *testing.T) {
+	settings := Current()
+	for _, percent := range []float64{-1, 100.5} {
+		settings.ShadowReadPercent = percent
+		if settings.Validate() == nil {
+			t.Errorf("Expected shadow read percent %v to be rejected", percent)
+		}
+	}
+	settings.ShadowReadPercent = 2.5
+	if err := settings.Validate(); err != nil {
+		t.Errorf("Expected shadow read percent 2.5 to be accepted, got %v", err)
+	}
+}
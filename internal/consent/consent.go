@@ -0,0 +1,70 @@
+// Package consent defines the policies users must accept before using the
+// API, such as the terms of service. The current version of each policy is
+// read from CONSENT_POLICIES, e.g. "terms=2025-01-15,privacy=3". Publishing a
+// new version means changing it there: every user is asked to accept again.
+package consent
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Policy is one version of a policy document
+type Policy struct {
+	Policy  string `json:"policy"`
+	Version string `json:"version"`
+}
+
+// Required returns the current version of every policy, sorted by name. It
+// is empty, and nothing is gated, when CONSENT_POLICIES is not set.
+func Required() []Policy {
+	var policies []Policy
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(os.Getenv("CONSENT_POLICIES"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, version, ok := strings.Cut(item, "=")
+		name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+		if !ok || name == "" || version == "" {
+			log.Printf("Warning: Invalid entry in CONSENT_POLICIES (%s), expected policy=version", item)
+			continue
+		}
+		if seen[name] {
+			log.Printf("Warning: Policy %s is listed twice in CONSENT_POLICIES, using the first version", name)
+			continue
+		}
+		seen[name] = true
+		policies = append(policies, Policy{Policy: name, Version: version})
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Policy < policies[j].Policy })
+	return policies
+}
+
+// Current returns the current version of a policy
+func Current(name string) (Policy, bool) {
+	for _, policy := range Required() {
+		if policy.Policy == name {
+			return policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// Pending returns the required policies not in accepted
+func Pending(accepted []Policy) []Policy {
+	have := make(map[Policy]bool, len(accepted))
+	for _, policy := range accepted {
+		have[policy] = true
+	}
+	pending := []Policy{}
+	for _, policy := range Required() {
+		if !have[policy] {
+			pending = append(pending, policy)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,44 @@
+package consent
+
+import "testing"
+
+func TestRequired(t *testing.T) {
+	t.Setenv("CONSENT_POLICIES", " terms=2025-01-15, privacy=3,broken,terms=old,=1")
+
+	policies := Required()
+	if len(policies) != 2 {
+		t.Fatalf("Expected 2 policies, got %v", policies)
+	}
+	if policies[0] != (Policy{Policy: "privacy", Version: "3"}) {
+		t.Errorf("Expected privacy version 3 first, got %v", policies[0])
+	}
+	if policies[1] != (Policy{Policy: "terms", Version: "2025-01-15"}) {
+		t.Errorf("Expected the first terms version to win, got %v", policies[1])
+	}
+}
+
+func TestRequiredUnset(t *testing.T) {
+	t.Setenv("CONSENT_POLICIES", "")
+	if policies := Required(); len(policies) != 0 {
+		t.Errorf("Expected no policies, got %v", policies)
+	}
+	if pending := Pending(nil); len(pending) != 0 {
+		t.Errorf("Expected nothing pending, got %v", pending)
+	}
+}
+
+func TestPending(t *testing.T) {
+	t.Setenv("CONSENT_POLICIES", "terms=2,privacy=3")
+
+	pending := Pending([]Policy{{Policy: "terms", Version: "1"}, {Policy: "privacy", Version: "3"}})
+	if len(pending) != 1 || pending[0] != (Policy{Policy: "terms", Version: "2"}) {
+		t.Errorf("Expected terms version 2 to be pending, got %v", pending)
+	}
+
+	if policy, ok := Current("privacy"); !ok || policy.Version != "3" {
+		t.Errorf("Expected current privacy version 3, got %v, %v", policy, ok)
+	}
+	if _, ok := Current("cookies"); ok {
+		t.Error("Expected no current version for an unknown policy")
+	}
+}
@@ -0,0 +1,186 @@
+// Package cursor turns pagination positions and export tokens into opaque,
+// tamper-proof strings. Values are encrypted and authenticated with
+// AES-256-GCM under a key derived from CURSOR_SECRET (or JWT_SECRET), and
+// bound to a scope such as the endpoint and caller, so a client can neither
+// read nor forge a cursor, nor replay one on another endpoint or as another
+// user.
+package cursor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/secrets"
+)
+
+var (
+	// ErrInvalid is returned for cursors that were tampered with, issued for
+	// another scope, or signed with an unknown key
+	ErrInvalid = errors.New("invalid cursor")
+	// ErrExpired is returned for cursors past their expiry
+	ErrExpired = errors.New("cursor expired")
+)
+
+var (
+	mu       sync.RWMutex
+	current  cipher.AEAD
+	previous cipher.AEAD
+
+	watchRotation sync.Once
+	generateOnce  sync.Once
+)
+
+type envelope struct {
+	Expires int64           `json:"exp,omitempty"`
+	Value   json.RawMessage `json:"v"`
+}
+
+// Init derives the cursor key from CURSOR_SECRET, or from JWT_SECRET when it
+// is not set. When the secret rotates, cursors issued under the previous key
+// stay valid until the next rotation.
+func Init() error {
+	name := "CURSOR_SECRET"
+	secret, err := secrets.Get(name)
+	if errors.Is(err, secrets.ErrNotFound) || (err == nil && secret == "") {
+		name = "JWT_SECRET"
+		secret, err = secrets.Get(name)
+	}
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return err
+	}
+	if secret == "" {
+		log.Println("WARNING: CURSOR_SECRET and JWT_SECRET not set, using generated cursor key (cursors won't survive restarts)")
+		return setKey(nil)
+	}
+	if err := setKey([]byte(secret)); err != nil {
+		return err
+	}
+
+	watchRotation.Do(func() {
+		secrets.OnRotate(name, func(_, value string) {
+			if err := setKey([]byte(value)); err != nil {
+				log.Printf("Warning: Failed to rotate cursor key: %v", err)
+			}
+		})
+	})
+	return nil
+}
+
+// setKey makes secret the current key and keeps the old one for decoding. A
+// nil secret generates a random key.
+func setKey(secret []byte) error {
+	if secret == nil {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("saas-go-app cursor v1"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	previous = current
+	current = aead
+	mu.Unlock()
+	return nil
+}
+
+func keys() (cipher.AEAD, cipher.AEAD) {
+	mu.RLock()
+	cur, prev := current, previous
+	mu.RUnlock()
+	if cur != nil {
+		return cur, prev
+	}
+
+	// Not initialized, e.g. in tests or mock mode
+	generateOnce.Do(func() {
+		if err := setKey(nil); err != nil {
+			panic("cursor: failed to generate key: " + err.Error())
+		}
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	return current, previous
+}
+
+// Encode seals value, which must marshal to JSON, into a URL-safe token that
+// only decodes for the same scope. A ttl of 0 never expires.
+func Encode(scope string, value interface{}, ttl time.Duration) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	env := envelope{Value: raw}
+	if ttl > 0 {
+		env.Expires = time.Now().Add(ttl).Unix()
+	}
+	plaintext, err := json.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	aead, _ := keys()
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(scope))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode opens a token produced by Encode for the same scope into value
+func Decode(scope, token string, value interface{}) error {
+	// Strict, so a token differing only in its padding bits is rejected
+	sealed, err := base64.RawURLEncoding.Strict().DecodeString(token)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	cur, prev := keys()
+	plaintext, err := open(cur, sealed, scope)
+	if err != nil && prev != nil {
+		plaintext, err = open(prev, sealed, scope)
+	}
+	if err != nil {
+		return ErrInvalid
+	}
+
+	var env envelope
+	if err := json.Unmarshal(plaintext, &env); err != nil {
+		return ErrInvalid
+	}
+	if env.Expires > 0 && time.Now().Unix() > env.Expires {
+		return ErrExpired
+	}
+	if err := json.Unmarshal(env.Value, value); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func open(aead cipher.AEAD, sealed []byte, scope string) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalid
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(scope))
+}
@@ -0,0 +1,111 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type position struct {
+	ID int `json:"id"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	if err := setKey([]byte("test-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+
+	token, err := Encode("customers:alice", position{ID: 42}, time.Hour)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if sealed, _ := base64.RawURLEncoding.DecodeString(token); strings.Contains(string(sealed), `"id":42`) {
+		t.Errorf("Expected an opaque token, got %s", token)
+	}
+
+	var got position
+	if err := Decode("customers:alice", token, &got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.ID != 42 {
+		t.Errorf("Expected id 42, got %d", got.ID)
+	}
+}
+
+func TestDecodeRejectsOtherScope(t *testing.T) {
+	if err := setKey([]byte("test-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+
+	token, _ := Encode("customers:alice", position{ID: 42}, 0)
+	var got position
+	if err := Decode("customers:bob", token, &got); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for another scope, got %v", err)
+	}
+	if err := Decode("accounts:alice", token, &got); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for another endpoint, got %v", err)
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	if err := setKey([]byte("test-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+
+	token, _ := Encode("customers:alice", position{ID: 42}, 0)
+	// Flip a character in the middle, which always changes the decoded bytes
+	middle := len(token) / 2
+	flipped := byte('A')
+	if token[middle] == 'A' {
+		flipped = 'B'
+	}
+	var got position
+	for _, forged := range []string{token[:middle] + string(flipped) + token[middle+1:], "", "not base64!", "AAAA"} {
+		if err := Decode("customers:alice", forged, &got); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected ErrInvalid for %q, got %v", forged, err)
+		}
+	}
+}
+
+func TestDecodeExpired(t *testing.T) {
+	if err := setKey([]byte("test-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+
+	token, _ := Encode("export:alice", position{ID: 1}, -time.Minute)
+	var got position
+	// A negative ttl never expires, like 0
+	if err := Decode("export:alice", token, &got); err != nil {
+		t.Errorf("Expected a non-positive ttl to never expire, got %v", err)
+	}
+
+	token, _ = Encode("export:alice", position{ID: 1}, time.Second)
+	time.Sleep(2100 * time.Millisecond)
+	if err := Decode("export:alice", token, &got); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+}
+
+func TestRotationKeepsPreviousKey(t *testing.T) {
+	if err := setKey([]byte("old-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+	token, _ := Encode("customers:alice", position{ID: 7}, 0)
+
+	if err := setKey([]byte("new-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+	var got position
+	if err := Decode("customers:alice", token, &got); err != nil || got.ID != 7 {
+		t.Errorf("Expected cursor from the previous key to decode, got %v, %v", got, err)
+	}
+
+	if err := setKey([]byte("newer-secret")); err != nil {
+		t.Fatalf("setKey failed: %v", err)
+	}
+	if err := Decode("customers:alice", token, &got); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid after two rotations, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"saas-go-app/internal/auth"
+)
+
+// apiKeyTouchInterval limits how often a key's last use is written, so busy
+// keys don't turn every read into a write
+const apiKeyTouchInterval = time.Minute
+
+// AuthenticateAPIKey looks up an active API key for auth.AcceptAPIKeys and
+// records when and from where it was last used. Uses from outside the key's
+// allowed ranges aren't recorded, since AuthMiddleware rejects them.
+func AuthenticateAPIKey(ctx context.Context, key, clientIP string) (auth.APIKeyPrincipal, error) {
+	var principal auth.APIKeyPrincipal
+	var lastUsedAt, expiresAt, revokedAt sql.NullTime
+	var lastUsedIP sql.NullString
+	err := Primary(ctx).QueryRow(`
+		SELECT k.id, u.username, k.scopes, k.allowed_cidrs, k.last_used_at, k.last_used_ip, k.expires_at, k.revoked_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1`,
+		auth.HashAPIKey(key),
+	).Scan(&principal.ID, &principal.Username, Array(&principal.Scopes), Array(&principal.AllowedCIDRs), &lastUsedAt, &lastUsedIP, &expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return principal, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return principal, err
+	}
+	// expires_at is a TIMESTAMP, compared against UTC timestamps
+	now := time.Now().UTC()
+	if revokedAt.Valid || (expiresAt.Valid && !now.Before(expiresAt.Time)) {
+		return principal, auth.ErrInvalidAPIKey
+	}
+
+	if !principal.AllowsIP(clientIP) {
+		return principal, nil
+	}
+
+	if !lastUsedAt.Valid || now.Sub(lastUsedAt.Time) >= apiKeyTouchInterval || lastUsedIP.String != clientIP {
+		_, err := Primary(ctx).Exec("UPDATE api_keys SET last_used_at = $1, last_used_ip = $2 WHERE id = $3", now, clientIP, principal.ID)
+		if err != nil {
+			log.Printf("Warning: Failed to record use of API key %d: %v", principal.ID, err)
+		}
+	}
+	return principal, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/auth"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+	ctx := context.Background()
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	username := "apikey_" + time.Now().Format("150405.000000")
+	var userID int
+	if err := PrimaryDB.QueryRow("INSERT INTO users (username, password_hash) VALUES ($1, 'x') RETURNING id", username).Scan(&userID); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM users WHERE id = $1", userID)
+
+	key, _ := auth.NewAPIKey()
+	var keyID int
+	err := PrimaryDB.QueryRow(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes) VALUES ($1, 'CI', $2, $3, $4) RETURNING id",
+		userID, auth.APIKeyDisplay(key), auth.HashAPIKey(key), []string{auth.ScopeRead},
+	).Scan(&keyID)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	principal, err := AuthenticateAPIKey(ctx, key, "203.0.113.7")
+	if err != nil || principal.Username != username || !principal.HasScope(auth.ScopeRead) {
+		t.Fatalf("Expected the key to authenticate as %s, got %+v: %v", username, principal, err)
+	}
+	var lastUsedIP string
+	if err := PrimaryDB.QueryRow("SELECT last_used_ip FROM api_keys WHERE id = $1", keyID).Scan(&lastUsedIP); err != nil || lastUsedIP != "203.0.113.7" {
+		t.Errorf("Expected the use to be recorded, got %q: %v", lastUsedIP, err)
+	}
+
+	// Uses from outside the allowed ranges return the ranges for the auth
+	// middleware to reject, without being recorded
+	if _, err := PrimaryDB.Exec("UPDATE api_keys SET allowed_cidrs = '{198.51.100.0/24}' WHERE id = $1", keyID); err != nil {
+		t.Fatalf("Failed to restrict API key: %v", err)
+	}
+	principal, err = AuthenticateAPIKey(ctx, key, "203.0.113.8")
+	if err != nil || principal.AllowsIP("203.0.113.8") || !principal.AllowsIP("198.51.100.9") {
+		t.Errorf("Expected the key's allowed ranges, got %+v: %v", principal, err)
+	}
+	if err := PrimaryDB.QueryRow("SELECT last_used_ip FROM api_keys WHERE id = $1", keyID).Scan(&lastUsedIP); err != nil || lastUsedIP != "203.0.113.7" {
+		t.Errorf("Expected the denied use not to be recorded, got %q: %v", lastUsedIP, err)
+	}
+
+	if _, err := PrimaryDB.Exec("UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1", keyID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if _, err := AuthenticateAPIKey(ctx, key, "203.0.113.7"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Expected a revoked key to be rejected, got %v", err)
+	}
+	if _, err := AuthenticateAPIKey(ctx, "sgk_unknown", "203.0.113.7"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Expected an unknown key to be rejected, got %v", err)
+	}
+}
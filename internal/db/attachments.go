@@ -0,0 +1,122 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrAmbiguousAttachment is returned on a Heroku dyno when several Heroku
+// Postgres attachments could be the primary database and PRIMARY_DB_ATTACHMENT
+// doesn't pick one
+var ErrAmbiguousAttachment = errors.New("several Heroku Postgres attachments found; set PRIMARY_DB_ATTACHMENT to pick one")
+
+// Attachment is a Heroku Postgres add-on attachment, found by its
+// HEROKU_POSTGRESQL_<COLOR>_URL config var
+type Attachment struct {
+	// Name is the attachment name, e.g. HEROKU_POSTGRESQL_PURPLE
+	Name string
+	URL  string
+}
+
+// ConfigVar is the config var holding the attachment's URL
+func (a Attachment) ConfigVar() string {
+	return a.Name + "_URL"
+}
+
+// DetectAttachments returns the Heroku Postgres attachments in environ (in
+// os.Environ form), sorted by name. Config vars without a postgres:// URL,
+// such as HEROKU_POSTGRESQL_PURPLE holding a color name, are ignored.
+func DetectAttachments(environ []string) []Attachment {
+	var attachments []Attachment
+	for _, envVar := range environ {
+		name, value, ok := strings.Cut(envVar, "=")
+		if !ok || !strings.HasPrefix(name, "HEROKU_POSTGRESQL_") || !strings.HasSuffix(name, "_URL") {
+			continue
+		}
+		if !strings.HasPrefix(value, "postgres://") && !strings.HasPrefix(value, "postgresql://") {
+			continue
+		}
+		attachments = append(attachments, Attachment{Name: strings.TrimSuffix(name, "_URL"), URL: value})
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	return attachments
+}
+
+// pickAttachment chooses the attachment whose URL is the primary database:
+//   - the one named by choice (PRIMARY_DB_ATTACHMENT), given as
+//     HEROKU_POSTGRESQL_PURPLE, HEROKU_POSTGRESQL_PURPLE_URL, or PURPLE;
+//     DATABASE picks none, so DATABASE_URL is used
+//   - the only one, if there is one
+//   - the one DATABASE_URL points at (databaseURL), if several
+//
+// It returns false when there are none, so DATABASE_URL is used. Several
+// attachments that none of the above settles are an ErrAmbiguousAttachment
+// when strict; otherwise the first by name is used.
+func pickAttachment(attachments []Attachment, choice, databaseURL string, strict bool) (Attachment, bool, error) {
+	if choice != "" {
+		name := strings.TrimSuffix(strings.ToUpper(choice), "_URL")
+		if name == "DATABASE" {
+			return Attachment{}, false, nil
+		}
+		if !strings.HasPrefix(name, "HEROKU_POSTGRESQL_") {
+			name = "HEROKU_POSTGRESQL_" + name
+		}
+		for _, attachment := range attachments {
+			if attachment.Name == name {
+				return attachment, true, nil
+			}
+		}
+		return Attachment{}, false, fmt.Errorf("PRIMARY_DB_ATTACHMENT is %s, but %s_URL is not set", choice, name)
+	}
+
+	switch len(attachments) {
+	case 0:
+		return Attachment{}, false, nil
+	case 1:
+		return attachments[0], true, nil
+	}
+	for _, attachment := range attachments {
+		if databaseURL != "" && attachment.URL == databaseURL {
+			return attachment, true, nil
+		}
+	}
+	if strict {
+		return Attachment{}, false, ErrAmbiguousAttachment
+	}
+	log.Printf("Warning: %v; using %s", ErrAmbiguousAttachment, attachments[0].Name)
+	return attachments[0], true, nil
+}
+
+// primaryDatabaseURL returns the URL of the Heroku Postgres attachment picked
+// by pickAttachment, otherwise DATABASE_URL. Ambiguous attachments are an
+// error on a Heroku dyno, where guessing could point the app at the wrong
+// database.
+func primaryDatabaseURL() (string, error) {
+	attachments := DetectAttachments(os.Environ())
+	if len(attachments) > 0 {
+		names := make([]string, len(attachments))
+		for i, attachment := range attachments {
+			names[i] = attachment.Name
+		}
+		log.Printf("Detected Heroku Postgres attachments: %s", strings.Join(names, ", "))
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	attachment, ok, err := pickAttachment(attachments, os.Getenv("PRIMARY_DB_ATTACHMENT"), databaseURL, os.Getenv("DYNO") != "")
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		log.Printf("Using Next Gen Postgres Advanced connection: %s", attachment.ConfigVar())
+		return attachment.URL, nil
+	}
+
+	if databaseURL == "" {
+		return "", fmt.Errorf("DATABASE_URL environment variable is not set")
+	}
+	return databaseURL, nil
+}
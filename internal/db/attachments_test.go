@@ -0,0 +1,61 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectAttachments(t *testing.T) {
+	attachments := DetectAttachments([]string{
+		"HEROKU_POSTGRESQL_PURPLE_URL=postgres://purple",
+		"HEROKU_POSTGRESQL_PURPLE=PURPLE",
+		"HEROKU_POSTGRESQL_GREEN_URL=postgresql://green",
+		"HEROKU_POSTGRESQL_RED_URL=not-a-url",
+		"DATABASE_URL=postgres://purple",
+	})
+	if len(attachments) != 2 || attachments[0].Name != "HEROKU_POSTGRESQL_GREEN" || attachments[1].ConfigVar() != "HEROKU_POSTGRESQL_PURPLE_URL" {
+		t.Errorf("Expected the green and purple attachments sorted by name, got %+v", attachments)
+	}
+}
+
+func TestPickAttachment(t *testing.T) {
+	green := Attachment{Name: "HEROKU_POSTGRESQL_GREEN", URL: "postgres://green"}
+	purple := Attachment{Name: "HEROKU_POSTGRESQL_PURPLE", URL: "postgres://purple"}
+	both := []Attachment{green, purple}
+
+	tests := []struct {
+		name        string
+		attachments []Attachment
+		choice      string
+		databaseURL string
+		strict      bool
+		want        Attachment
+		wantOK      bool
+		wantErr     bool
+	}{
+		{name: "none", attachments: nil, wantOK: false},
+		{name: "only one", attachments: []Attachment{purple}, strict: true, want: purple, wantOK: true},
+		{name: "explicit color", attachments: both, choice: "purple", strict: true, want: purple, wantOK: true},
+		{name: "explicit config var", attachments: both, choice: "HEROKU_POSTGRESQL_GREEN_URL", strict: true, want: green, wantOK: true},
+		{name: "explicit DATABASE_URL", attachments: both, choice: "DATABASE", strict: true, wantOK: false},
+		{name: "unknown choice", attachments: both, choice: "RED", wantErr: true},
+		{name: "matches DATABASE_URL", attachments: both, databaseURL: "postgres://purple", strict: true, want: purple, wantOK: true},
+		{name: "ambiguous in development", attachments: both, want: green, wantOK: true},
+		{name: "ambiguous in production", attachments: both, strict: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := pickAttachment(tt.attachments, tt.choice, tt.databaseURL, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Expected %+v (%v), got %+v (%v)", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+
+	if _, _, err := pickAttachment(both, "", "", true); !errors.Is(err, ErrAmbiguousAttachment) {
+		t.Errorf("Expected ErrAmbiguousAttachment, got %v", err)
+	}
+}
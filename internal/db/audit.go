@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"saas-go-app/internal/auth"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// AuditedTables record every insert, update, and delete of their rows in
+// audit_log, with the row before and after, maintained by the record_audit
+// trigger (see migrations/0020_audit_log.up.sql)
+var AuditedTables = []string{"customers", "accounts"}
+
+// The record_audit trigger reads the actor of a change from the saas.actor
+// setting, which follows the actor carried by the context of each query (see
+// auth.WithActor). A connection keeps the setting between queries, so it is
+// only sent when the actor changes. A new connection starts without one.
+
+// setActor makes the actor carried by ctx the one record_audit sees on c
+func (c *tracedConn) setActor(ctx context.Context) error {
+	conn, ok := c.Conn.(*stdlib.Conn)
+	if !ok {
+		return nil
+	}
+	pgConn := conn.Conn().PgConn()
+	if pgConn.TxStatus() == 'E' {
+		// The transaction failed and will reject the query anyway
+		return nil
+	}
+	actor := auth.ActorFromContext(ctx)
+	data := pgConn.CustomData()
+	current, _ := data["actor"].(string)
+	if actor == current && data["actor_unknown"] == nil {
+		return nil
+	}
+	if _, err := conn.Conn().Exec(ctx, "SELECT set_config('saas.actor', $1, false)", actor); err != nil {
+		return err
+	}
+	data["actor"] = actor
+	delete(data, "actor_unknown")
+	return nil
+}
+
+// forgetActor marks the actor of c unknown, so the next query sets it again
+func (c *tracedConn) forgetActor() {
+	if conn, ok := c.Conn.(*stdlib.Conn); ok {
+		conn.Conn().PgConn().CustomData()["actor_unknown"] = true
+	}
+}
+
+// actorTx forgets the connection's actor when the transaction rolls back,
+// since that undoes any change of saas.actor made in it
+type actorTx struct {
+	driver.Tx
+	conn *tracedConn
+}
+
+func (t actorTx) Rollback() error {
+	t.conn.forgetActor()
+	return t.Tx.Rollback()
+}
+
+// setLocalActor sets the actor carried by ctx for the rest of tx, for
+// changes made through pgx directly rather than PrimaryDB. It overrides
+// whatever actor the connection was last used with.
+func setLocalActor(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, "SELECT set_config('saas.actor', $1, true)", auth.ActorFromContext(ctx))
+	return err
+}
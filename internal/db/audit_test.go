@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/auth"
+)
+
+func TestAuditLogRecordsActor(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	if err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	alice := auth.WithActor(context.Background(), "alice")
+
+	var id int
+	email := "audit-" + time.Now().Format("20060102150405.000000") + "@example.com"
+	if err := PrimaryDB.QueryRowContext(alice, "INSERT INTO customers (name, email) VALUES ('Before', $1) RETURNING id", email).Scan(&id); err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", id)
+
+	// A rolled back change of actor doesn't stick to the connection
+	err := WithTx(auth.WithActor(context.Background(), "mallory"), func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(auth.WithActor(context.Background(), "mallory"), "UPDATE customers SET name = 'Rolled back' WHERE id = $1", id); err != nil {
+			return err
+		}
+		return errors.New("roll back")
+	})
+	if err == nil {
+		t.Fatal("Expected the transaction to roll back")
+	}
+	if _, err := PrimaryDB.ExecContext(context.Background(), "UPDATE customers SET name = 'After' WHERE id = $1", id); err != nil {
+		t.Fatalf("Failed to update customer: %v", err)
+	}
+	// Updates that change nothing aren't recorded
+	if _, err := PrimaryDB.ExecContext(alice, "UPDATE customers SET name = 'After' WHERE id = $1", id); err != nil {
+		t.Fatalf("Failed to update customer: %v", err)
+	}
+
+	rows, err := PrimaryDB.Query("SELECT action, actor, before->>'name', after->>'name' FROM audit_log WHERE entity = 'customers' AND entity_id = $1 ORDER BY id", id)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var action, actor string
+		var before, after sql.NullString
+		if err := rows.Scan(&action, &actor, &before, &after); err != nil {
+			t.Fatalf("Failed to scan audit log: %v", err)
+		}
+		got = append(got, action+" by "+actor+": "+before.String+" -> "+after.String)
+	}
+	want := []string{"insert by alice:  -> Before", "update by system: Before -> After"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected audit log %q, got %q", want, got)
+	}
+}
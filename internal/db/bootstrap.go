@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"saas-go-app/internal/auth"
+)
+
+// BootstrapAdminUsername is the admin user a bootstrapped app gets
+const BootstrapAdminUsername = "admin"
+
+// DefaultAdminPassword is the password SeedData gives BootstrapAdminUsername.
+// It is deprecated: anyone who has read the README can sign in as admin
+// with it, so startup warns while it works (see posture).
+const DefaultAdminPassword = "admin123"
+
+// CreateBootstrapAdmin creates BootstrapAdminUsername as an admin with a
+// random password, instead of the admin123 SeedData would use, and returns
+// the password. It returns "" if the database already has users, so only the
+// first boot of an app gets a credential.
+func CreateBootstrapAdmin(ctx context.Context) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	password := hex.EncodeToString(b)
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	result, err := PrimaryDB.ExecContext(ctx, `
+		INSERT INTO users (username, password_hash, role)
+		SELECT $1, $2, 'admin' WHERE NOT EXISTS (SELECT 1 FROM users)
+		ON CONFLICT (username) DO NOTHING`,
+		BootstrapAdminUsername, passwordHash,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create admin user: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return "", nil
+	}
+	return password, nil
+}
+
+// DefaultAdminCredentialsInUse reports whether BootstrapAdminUsername still
+// signs in with DefaultAdminPassword
+func DefaultAdminCredentialsInUse(ctx context.Context) (bool, error) {
+	var passwordHash string
+	err := Primary(ctx).QueryRow("SELECT password_hash FROM users WHERE username = $1", BootstrapAdminUsername).Scan(&passwordHash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return auth.CheckPasswordHash(DefaultAdminPassword, passwordHash), nil
+}
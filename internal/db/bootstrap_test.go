@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCreateBootstrapAdminKeepsExistingUsers(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	ctx := context.Background()
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	username := "bootstrap-" + time.Now().Format("20060102150405.000000")
+	if _, err := PrimaryDB.ExecContext(ctx, "INSERT INTO users (username, password_hash) VALUES ($1, 'x')", username); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM users WHERE username = $1", username)
+
+	var before string
+	PrimaryDB.QueryRowContext(ctx, "SELECT COALESCE((SELECT password_hash FROM users WHERE username = $1), '')", BootstrapAdminUsername).Scan(&before)
+	password, err := CreateBootstrapAdmin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to bootstrap: %v", err)
+	}
+	if password != "" {
+		t.Errorf("Expected no credential for a database with users, got one")
+	}
+	var after string
+	PrimaryDB.QueryRowContext(ctx, "SELECT COALESCE((SELECT password_hash FROM users WHERE username = $1), '')", BootstrapAdminUsername).Scan(&after)
+	if after != before {
+		t.Errorf("Expected the existing admin password to be kept")
+	}
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"saas-go-app/internal/chaos"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultSeedBatchSize is the number of rows sent per COPY when SEED_BATCH_SIZE is unset
+const DefaultSeedBatchSize = 10000
+
+// BulkLoadStats summarizes a bulk load into one table
+type BulkLoadStats struct {
+	Table   string
+	Rows    int64
+	Batches int
+	Elapsed time.Duration
+}
+
+// RowsPerSecond is the load's throughput
+func (s BulkLoadStats) RowsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Rows) / s.Elapsed.Seconds()
+}
+
+func (s BulkLoadStats) String() string {
+	return fmt.Sprintf("%d %s in %v (%d batches, %.0f rows/s)",
+		s.Rows, s.Table, s.Elapsed.Round(time.Millisecond), s.Batches, s.RowsPerSecond())
+}
+
+// bulkLoader buffers generated rows and copies them into a table in batches
+type bulkLoader struct {
+	columns   []string
+	batchSize int
+	rows      [][]any
+	started   time.Time
+	stats     BulkLoadStats
+
+	// onFlush is called after each batch with the rows loaded so far
+	onFlush func(loaded int64)
+	// copy is CopyFrom unless the loader copies inside a transaction
+	copy copyFunc
+}
+
+// copyFunc copies rows into table, like CopyFrom
+type copyFunc func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+
+// copyIn returns a copyFunc that copies within tx
+func copyIn(tx pgx.Tx) copyFunc {
+	return func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+		if err := chaos.DB(ctx); err != nil {
+			return 0, err
+		}
+		return tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+	}
+}
+
+func newBulkLoader(table string, columns []string, batchSize int) *bulkLoader {
+	if batchSize <= 0 {
+		batchSize = DefaultSeedBatchSize
+	}
+	return &bulkLoader{
+		columns:   columns,
+		batchSize: batchSize,
+		rows:      make([][]any, 0, batchSize),
+		started:   time.Now(),
+		stats:     BulkLoadStats{Table: table},
+		copy:      CopyFrom,
+	}
+}
+
+// Add buffers a row, copying the buffer once it holds a full batch
+func (l *bulkLoader) Add(ctx context.Context, row ...any) error {
+	l.rows = append(l.rows, row)
+	if len(l.rows) < l.batchSize {
+		return nil
+	}
+	return l.Flush(ctx)
+}
+
+// Flush copies any buffered rows
+func (l *bulkLoader) Flush(ctx context.Context) error {
+	if len(l.rows) > 0 {
+		n, err := l.copy(ctx, l.stats.Table, l.columns, l.rows)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s batch: %w", l.stats.Table, err)
+		}
+		l.stats.Rows += n
+		l.stats.Batches++
+		l.rows = l.rows[:0]
+		if l.onFlush != nil {
+			l.onFlush(l.stats.Rows)
+		}
+	}
+	l.stats.Elapsed = time.Since(l.started)
+	return nil
+}
+
+// Stats reports what has been loaded so far
+func (l *bulkLoader) Stats() BulkLoadStats {
+	return l.stats
+}
+
+// ImportCustomers inserts rows of (name, email), skipping emails that already
+// exist or repeat within rows, and returns how many were inserted. Rows are
+// copied into a temporary table first so conflicts can be skipped, which COPY
+// into customers directly can't do.
+func ImportCustomers(ctx context.Context, rows [][]any) (int64, error) {
+	if err := chaos.DB(ctx); err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	err := pgx.BeginFunc(ctx, PrimaryPgx, func(tx pgx.Tx) error {
+		if err := setLocalActor(ctx, tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "CREATE TEMP TABLE customers_import (name TEXT, email TEXT) ON COMMIT DROP")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"customers_import"}, []string{"name", "email"}, pgx.CopyFromRows(rows)); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, annotate(ctx, `INSERT INTO customers (name, email)
+			SELECT DISTINCT ON (email) name, email FROM customers_import ORDER BY email
+			ON CONFLICT (email) DO NOTHING`))
+		if err != nil {
+			return err
+		}
+		inserted = tag.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import customers: %w", err)
+	}
+	return inserted, nil
+}
+
+// ImportAccounts inserts rows of (customer email, name, status) for the
+// customers with those emails and returns how many were inserted. Each account
+// gets the next reference in its customer's sequence, as if created through
+// the API. Rows whose customer doesn't exist or is soft-deleted are skipped.
+func ImportAccounts(ctx context.Context, rows [][]any) (int64, error) {
+	if err := chaos.DB(ctx); err != nil {
+		return 0, err
+	}
+
+	// Reserve each customer's sequence numbers in one statement, in the
+	// order the rows name them
+	var emails []string
+	var counts []int32
+	index := make(map[string]int)
+	for _, row := range rows {
+		email := row[0].(string)
+		i, ok := index[email]
+		if !ok {
+			i = len(emails)
+			index[email] = i
+			emails = append(emails, email)
+			counts = append(counts, 0)
+		}
+		counts[i]++
+	}
+
+	var inserted int64
+	err := pgx.BeginFunc(ctx, PrimaryPgx, func(tx pgx.Tx) error {
+		if err := setLocalActor(ctx, tx); err != nil {
+			return err
+		}
+		// The row locks serialize with NextAccountReference, so references
+		// handed out concurrently don't collide
+		reserved, err := tx.Query(ctx, annotate(ctx, `UPDATE customers c SET account_seq = c.account_seq + x.n
+			FROM unnest($1::text[], $2::int[]) AS x(email, n)
+			WHERE c.email = x.email AND c.deleted_at IS NULL
+			RETURNING c.email, c.id, c.account_seq - x.n`),
+			emails, counts,
+		)
+		if err != nil {
+			return err
+		}
+		type sequence struct{ customerID, next int }
+		sequences := make(map[string]*sequence)
+		for reserved.Next() {
+			var email string
+			var seq sequence
+			if err := reserved.Scan(&email, &seq.customerID, &seq.next); err != nil {
+				reserved.Close()
+				return err
+			}
+			sequences[email] = &seq
+		}
+		if err := reserved.Err(); err != nil {
+			return err
+		}
+
+		prefix, digits := AccountReferencePrefix(), getEnvInt("ACCOUNT_REF_SEQUENCE_DIGITS", 4)
+		accounts := make([][]any, 0, len(rows))
+		for _, row := range rows {
+			seq, ok := sequences[row[0].(string)]
+			if !ok {
+				continue
+			}
+			seq.next++
+			reference := FormatAccountReference(prefix, seq.customerID, seq.next, digits)
+			accounts = append(accounts, []any{seq.customerID, reference, row[1], row[2]})
+		}
+		inserted, err = tx.CopyFrom(ctx, pgx.Identifier{"accounts"}, []string{"customer_id", "reference", "name", "status"}, pgx.CopyFromRows(accounts))
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import accounts: %w", err)
+	}
+	return inserted, nil
+}
+
+// reserveIDs draws n values from table's id sequence so rows can be copied with
+// known ids, letting child rows reference them without a RETURNING round trip
+// per row
+func reserveIDs(ctx context.Context, table string, n int) ([]int, error) {
+	rows, err := PrimaryPgx.Query(ctx,
+		annotate(ctx, "SELECT nextval(pg_get_serial_sequence($1, 'id')) FROM generate_series(1, $2)"),
+		table, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve %s ids: %w", table, err)
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
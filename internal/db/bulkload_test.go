@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBulkLoaderBatches(t *testing.T) {
+	var batches []int
+	loader := newBulkLoader("accounts", []string{"customer_id", "name", "status"}, 3)
+	loader.copy = func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+		if table != "accounts" || len(columns) != 3 {
+			t.Errorf("Unexpected copy into %s %v", table, columns)
+		}
+		batches = append(batches, len(rows))
+		return int64(len(rows)), nil
+	}
+	var flushed []int64
+	loader.onFlush = func(loaded int64) { flushed = append(flushed, loaded) }
+
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		if err := loader.Add(ctx, i, "Pro Account", "active"); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := loader.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(batches) != 3 || batches[0] != 3 || batches[1] != 3 || batches[2] != 1 {
+		t.Errorf("Expected batches of 3, 3, and 1, got %v", batches)
+	}
+	if len(flushed) != 3 || flushed[2] != 7 {
+		t.Errorf("Expected progress after each batch ending at 7, got %v", flushed)
+	}
+	if stats := loader.Stats(); stats.Rows != 7 || stats.Batches != 3 {
+		t.Errorf("Expected 7 rows in 3 batches, got %+v", stats)
+	}
+}
+
+func TestBulkLoaderCopyError(t *testing.T) {
+	loader := newBulkLoader("customers", []string{"id", "name", "email"}, 1)
+	loader.copy = func(context.Context, string, []string, [][]any) (int64, error) {
+		return 0, errors.New("duplicate key")
+	}
+	if err := loader.Add(context.Background(), 1, "Acme", "contact@acme.com"); err == nil {
+		t.Error("Expected the copy error to be returned")
+	}
+	if stats := loader.Stats(); stats.Rows != 0 {
+		t.Errorf("Expected no rows loaded, got %d", stats.Rows)
+	}
+}
+
+func TestBulkLoadStatsRowsPerSecond(t *testing.T) {
+	stats := BulkLoadStats{Table: "customers", Rows: 1000000, Batches: 100, Elapsed: 20 * time.Second}
+	if rate := stats.RowsPerSecond(); rate != 50000 {
+		t.Errorf("Expected 50000 rows/s, got %.0f", rate)
+	}
+	if rate := (BulkLoadStats{Rows: 10}).RowsPerSecond(); rate != 0 {
+		t.Errorf("Expected 0 rows/s without elapsed time, got %.0f", rate)
+	}
+}
+
+func TestImportAccounts(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	ctx := context.Background()
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	t.Setenv("ACCOUNT_REF_SEQUENCE_DIGITS", "")
+	email := "import-" + time.Now().Format("20060102150405.000000") + "@example.com"
+	var customerID int
+	if err := PrimaryDB.QueryRowContext(ctx, "INSERT INTO customers (name, email, account_seq) VALUES ('Import', $1, 2) RETURNING id", email).Scan(&customerID); err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", customerID)
+
+	inserted, err := ImportAccounts(ctx, [][]any{
+		{email, "First", "active"},
+		{"missing-" + email, "Orphan", "active"},
+		{email, "Second", "inactive"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to import accounts: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("Expected 2 accounts for the existing customer, got %d", inserted)
+	}
+
+	rows, err := PrimaryDB.QueryContext(ctx, "SELECT reference FROM accounts WHERE customer_id = $1 ORDER BY reference", customerID)
+	if err != nil {
+		t.Fatalf("Failed to read accounts: %v", err)
+	}
+	defer rows.Close()
+	var references []string
+	for rows.Next() {
+		var reference string
+		rows.Scan(&reference)
+		references = append(references, reference)
+	}
+	prefix := AccountReferencePrefix()
+	want := []string{FormatAccountReference(prefix, customerID, 3, 4), FormatAccountReference(prefix, customerID, 4, 4)}
+	if len(references) != 2 || references[0] != want[0] || references[1] != want[1] {
+		t.Errorf("Expected references %v continuing the customer's sequence, got %v", want, references)
+	}
+}
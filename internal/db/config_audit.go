@@ -0,0 +1,26 @@
+package db
+
+import (
+	"encoding/json"
+	"log"
+
+	"saas-go-app/internal/config"
+)
+
+// RecordConfigChanges stores a config change in the config_audit table.
+// It is registered as a config.ChangeHook at startup.
+func RecordConfigChanges(changes []config.Change, source, actor string) {
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Warning: Failed to encode config changes: %v", err)
+		return
+	}
+
+	_, err = PrimaryDB.Exec(
+		"INSERT INTO config_audit (source, actor, changes) VALUES ($1, $2, $3)",
+		source, actor, payload,
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to audit config changes: %v", err)
+	}
+}
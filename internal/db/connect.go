@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnectRetry controls how startup waits for Postgres. After a dyno restart
+// or a database failover, Postgres can take a few seconds to accept
+// connections; retrying keeps the dyno from crash looping meanwhile.
+type ConnectRetry struct {
+	// Attempts is the number of pings before giving up
+	Attempts int
+	// Timeout bounds each ping
+	Timeout time.Duration
+	// BaseBackoff is the first retry delay, doubled after each attempt with jitter
+	BaseBackoff time.Duration
+	// MaxBackoff caps retry delays
+	MaxBackoff time.Duration
+}
+
+// ConnectRetryFromEnv reads DB_CONNECT_ATTEMPTS (default 6), DB_CONNECT_TIMEOUT
+// (default 5s), DB_CONNECT_BACKOFF (default 500ms), and DB_CONNECT_MAX_BACKOFF
+// (default 15s). The defaults give up after about 20s of waiting, well within
+// Heroku's 60s boot timeout.
+func ConnectRetryFromEnv() ConnectRetry {
+	retry := ConnectRetry{
+		Attempts:    getEnvInt("DB_CONNECT_ATTEMPTS", 6),
+		Timeout:     getEnvDuration("DB_CONNECT_TIMEOUT", 5*time.Second),
+		BaseBackoff: getEnvDuration("DB_CONNECT_BACKOFF", 500*time.Millisecond),
+		MaxBackoff:  getEnvDuration("DB_CONNECT_MAX_BACKOFF", 15*time.Second),
+	}
+	if retry.Attempts < 1 {
+		log.Printf("Warning: Invalid value for DB_CONNECT_ATTEMPTS (%d), using default 6", retry.Attempts)
+		retry.Attempts = 6
+	}
+	if retry.Timeout == 0 {
+		retry.Timeout = 5 * time.Second
+	}
+	if retry.MaxBackoff < retry.BaseBackoff {
+		retry.MaxBackoff = retry.BaseBackoff
+	}
+	return retry
+}
+
+// delay returns the jittered wait before the attempt after attempt (1-based):
+// between half and all of BaseBackoff doubled per attempt, capped at
+// MaxBackoff. Jitter keeps dynos that restart together from retrying in step.
+func (r ConnectRetry) delay(attempt int) time.Duration {
+	backoff := r.BaseBackoff
+	for i := 1; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// pingWithRetry pings pool until it answers, retrying per ConnectRetryFromEnv.
+// Every attempt is logged as key=value pairs. Errors that retrying can't fix,
+// such as bad credentials or an unknown database, fail immediately.
+func pingWithRetry(name string, pool *pgxpool.Pool) error {
+	retry := ConnectRetryFromEnv()
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), retry.Timeout)
+		start := time.Now()
+		err := pool.Ping(ctx)
+		cancel()
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		if err == nil {
+			log.Printf("db_connect pool=%s attempt=%d/%d status=ok duration=%s", name, attempt, retry.Attempts, elapsed)
+			return nil
+		}
+		if attempt >= retry.Attempts || !retryableConnectError(err) {
+			log.Printf("db_connect pool=%s attempt=%d/%d status=failed duration=%s error=%q", name, attempt, retry.Attempts, elapsed, err.Error())
+			return err
+		}
+
+		wait := retry.delay(attempt)
+		log.Printf("db_connect pool=%s attempt=%d/%d status=retrying duration=%s retry_in=%s error=%q", name, attempt, retry.Attempts, elapsed, wait.Round(time.Millisecond), err.Error())
+		time.Sleep(wait)
+	}
+}
+
+// retryableConnectError reports whether err may go away by itself. Invalid
+// authorization (class 28) and unknown databases (3D000) won't.
+func retryableConnectError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return !strings.HasPrefix(pgErr.Code, "28") && pgErr.Code != "3D000"
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestConnectRetryDelay(t *testing.T) {
+	retry := ConnectRetry{Attempts: 6, BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{4, 400 * time.Millisecond, 800 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if delay := retry.delay(tt.attempt); delay < tt.min || delay > tt.max {
+				t.Errorf("delay(%d) = %v, expected between %v and %v", tt.attempt, delay, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestConnectRetryFromEnv(t *testing.T) {
+	t.Setenv("DB_CONNECT_ATTEMPTS", "0")
+	t.Setenv("DB_CONNECT_TIMEOUT", "")
+	t.Setenv("DB_CONNECT_BACKOFF", "2s")
+	t.Setenv("DB_CONNECT_MAX_BACKOFF", "1s")
+
+	retry := ConnectRetryFromEnv()
+	if retry.Attempts != 6 || retry.Timeout != 5*time.Second {
+		t.Errorf("Expected default attempts and timeout, got %+v", retry)
+	}
+	if retry.MaxBackoff != 2*time.Second {
+		t.Errorf("Expected max backoff raised to the base backoff, got %v", retry.MaxBackoff)
+	}
+}
+
+func TestRetryableConnectError(t *testing.T) {
+	if !retryableConnectError(errors.New("dial tcp: connection refused")) {
+		t.Error("Expected network errors to be retried")
+	}
+	if !retryableConnectError(&pgconn.PgError{Code: "57P03"}) {
+		t.Error("Expected cannot_connect_now to be retried")
+	}
+	if retryableConnectError(&pgconn.PgError{Code: "28P01"}) {
+		t.Error("Expected invalid_password not to be retried")
+	}
+	if retryableConnectError(&pgconn.PgError{Code: "3D000"}) {
+		t.Error("Expected invalid_catalog_name not to be retried")
+	}
+}
+
+func TestPingWithRetryGivesUp(t *testing.T) {
+	t.Setenv("DB_CONNECT_ATTEMPTS", "3")
+	t.Setenv("DB_CONNECT_TIMEOUT", "1s")
+	t.Setenv("DB_CONNECT_BACKOFF", "10ms")
+	t.Setenv("DB_CONNECT_MAX_BACKOFF", "")
+
+	// Nothing listens on port 1, so every attempt is refused right away
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/app?sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to open pool: %v", err)
+	}
+	defer pool.Close()
+
+	start := time.Now()
+	if err := pingWithRetry("test", pool); err == nil {
+		t.Fatal("Expected an error when Postgres is unreachable")
+	}
+	// Two waits of 5-10ms and 10-20ms
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Expected pingWithRetry to back off between attempts, returned after %v", elapsed)
+	}
+}
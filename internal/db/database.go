@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 
-	_ "github.com/lib/pq"
+	"saas-go-app/internal/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
@@ -16,45 +17,29 @@ var (
 	
 	// AnalyticsDB is the follower pool connection for analytics
 	AnalyticsDB *sql.DB
+
+	// PrimaryPgx and AnalyticsPgx are the pgx pools behind PrimaryDB and
+	// AnalyticsDB, for pgx features database/sql lacks, such as batches
+	// (see SendBatch) and COPY
+	PrimaryPgx   *pgxpool.Pool
+	AnalyticsPgx *pgxpool.Pool
 )
 
 // InitPrimaryDB initializes the primary database connection
-// For Next Gen Postgres Advanced, checks for HEROKU_POSTGRESQL_*_URL first
-// Falls back to DATABASE_URL if not found
+// For Next Gen Postgres Advanced, uses a HEROKU_POSTGRESQL_*_URL attachment
+// first (see PRIMARY_DB_ATTACHMENT) and falls back to DATABASE_URL
 func InitPrimaryDB() error {
-	// Check for Next Gen Postgres Advanced connection string first
-	// Heroku creates config vars like HEROKU_POSTGRESQL_PURPLE_URL for NGPG databases
-	var databaseURL string
-	envVars := os.Environ()
-	for _, envVar := range envVars {
-		if len(envVar) > 20 && envVar[:20] == "HEROKU_POSTGRESQL_" {
-			// Check if it's a URL (not a color name like HEROKU_POSTGRESQL_PURPLE)
-			if len(envVar) > 24 && envVar[len(envVar)-4:] == "_URL" {
-				parts := strings.SplitN(envVar, "=", 2)
-				if len(parts) == 2 && strings.HasPrefix(parts[1], "postgres://") {
-					databaseURL = parts[1]
-					log.Printf("Using Next Gen Postgres Advanced connection: %s", parts[0])
-					break
-				}
-			}
-		}
-	}
-	
-	// Fall back to DATABASE_URL if no NGPG connection found
-	if databaseURL == "" {
-		databaseURL = os.Getenv("DATABASE_URL")
-		if databaseURL == "" {
-			return fmt.Errorf("DATABASE_URL environment variable is not set")
-		}
+	databaseURL, err := primaryDatabaseURL()
+	if err != nil {
+		return err
 	}
 
-	var err error
-	PrimaryDB, err = sql.Open("postgres", databaseURL)
+	PrimaryPgx, PrimaryDB, err = openPool("Primary database", withQueryTimeout(databaseURL))
 	if err != nil {
 		return fmt.Errorf("failed to open primary database: %w", err)
 	}
 
-	if err := PrimaryDB.Ping(); err != nil {
+	if err := pingWithRetry("primary", PrimaryPgx); err != nil {
 		return fmt.Errorf("failed to ping primary database: %w", err)
 	}
 
@@ -68,7 +53,8 @@ func InitPrimaryDB() error {
 // With Heroku Postgres Advanced (Next Generation), if ANALYTICS_DB_URL is not set,
 // the application will use PrimaryDB for analytics. If the DATABASE_URL connection
 // has automatic read routing configured, the database will automatically route
-// read queries to the follower pool.
+// read queries to the follower pool. In the app, Router sends read-only
+// queries to this connection and writes to PrimaryDB.
 func InitAnalyticsDB() error {
 	// First, check for explicit ANALYTICS_DB_URL
 	analyticsURL := os.Getenv("ANALYTICS_DB_URL")
@@ -96,17 +82,17 @@ func InitAnalyticsDB() error {
 		log.Println("read queries will be automatically routed to the follower pool.")
 		log.Println("To use explicit follower pool routing: Set ANALYTICS_DB_URL to the follower pool connection string")
 		log.Println("Get the follower URL from: Heroku Dashboard → Postgres addon → Follower Pool → Connection String")
-		AnalyticsDB = PrimaryDB
+		AnalyticsDB, AnalyticsPgx = PrimaryDB, PrimaryPgx
 		return nil
 	}
 
 	var err error
-	AnalyticsDB, err = sql.Open("postgres", analyticsURL)
+	AnalyticsPgx, AnalyticsDB, err = openPool("Analytics database", withQueryTimeout(analyticsURL))
 	if err != nil {
 		return fmt.Errorf("failed to open analytics database: %w", err)
 	}
 
-	if err := AnalyticsDB.Ping(); err != nil {
+	if err := pingWithRetry("analytics", AnalyticsPgx); err != nil {
 		return fmt.Errorf("failed to ping analytics database: %w", err)
 	}
 
@@ -114,6 +100,17 @@ func InitAnalyticsDB() error {
 	return nil
 }
 
+// AnalyticsPool returns the connection to use for read-only analytics queries.
+// It is the follower pool unless ANALYTICS_ROUTING is set to primary (a
+// hot-reloadable setting), outside read-only maintenance mode, or no
+// analytics connection has been initialized.
+func AnalyticsPool() *sql.DB {
+	if AnalyticsDB == nil || (config.Current().AnalyticsRouting == "primary" && !config.ReadOnly()) {
+		return PrimaryDB
+	}
+	return AnalyticsDB
+}
+
 // CloseDB closes all database connections
 func CloseDB() {
 	if PrimaryDB != nil {
@@ -122,50 +119,11 @@ func CloseDB() {
 	if AnalyticsDB != nil && AnalyticsDB != PrimaryDB {
 		AnalyticsDB.Close()
 	}
-}
-
-// CreateTables creates the necessary database tables
-func CreateTables() error {
-	customersTable := `
-	CREATE TABLE IF NOT EXISTS customers (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) NOT NULL UNIQUE,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	accountsTable := `
-	CREATE TABLE IF NOT EXISTS accounts (
-		id SERIAL PRIMARY KEY,
-		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
-		name VARCHAR(255) NOT NULL,
-		status VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		username VARCHAR(255) NOT NULL UNIQUE,
-		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	if _, err := PrimaryDB.Exec(customersTable); err != nil {
-		return fmt.Errorf("failed to create customers table: %w", err)
+	// Closing a *sql.DB from a pgxpool leaves the pool open
+	if PrimaryPgx != nil {
+		PrimaryPgx.Close()
 	}
-
-	if _, err := PrimaryDB.Exec(accountsTable); err != nil {
-		return fmt.Errorf("failed to create accounts table: %w", err)
+	if AnalyticsPgx != nil && AnalyticsPgx != PrimaryPgx {
+		AnalyticsPgx.Close()
 	}
-
-	if _, err := PrimaryDB.Exec(usersTable); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
-	}
-
-	log.Println("Database tables created successfully")
-	return nil
 }
-
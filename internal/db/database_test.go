@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"os"
 	"testing"
 )
@@ -28,7 +29,7 @@ func TestInitPrimaryDB(t *testing.T) {
 	CloseDB()
 }
 
-func TestCreateTables(t *testing.T) {
+func TestMigrateUp(t *testing.T) {
 	// Skip if DATABASE_URL is not set
 	if os.Getenv("DATABASE_URL") == "" {
 		t.Skip("DATABASE_URL not set, skipping database test")
@@ -40,9 +41,14 @@ func TestCreateTables(t *testing.T) {
 	}
 	defer CloseDB()
 
-	err = CreateTables()
+	err = MigrateUp(context.Background())
 	if err != nil {
-		t.Fatalf("Failed to create tables: %v", err)
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// A second run finds nothing to apply
+	if err := EnsureSchema(context.Background()); err != nil {
+		t.Fatalf("Schema is behind after migrating: %v", err)
 	}
 }
 
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UniqueViolation returns the name of the unique constraint err violated, or
+// "" if err isn't a unique violation (23505)
+func UniqueViolation(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// ReadOnlyViolation reports whether err is a write refused because the
+// database is read-only (25006), e.g. a primary being failed over
+func ReadOnlyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "25006"
+}
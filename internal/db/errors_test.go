@@ -0,0 +1,31 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestUniqueViolation(t *testing.T) {
+	err := fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "users_username_key"})
+	if got := UniqueViolation(err); got != "users_username_key" {
+		t.Errorf("Expected users_username_key, got %q", got)
+	}
+	if got := UniqueViolation(&pgconn.PgError{Code: "40001"}); got != "" {
+		t.Errorf("Expected no constraint for a serialization failure, got %q", got)
+	}
+	if got := UniqueViolation(errors.New("23505")); got != "" {
+		t.Errorf("Expected no constraint for a plain error, got %q", got)
+	}
+}
+
+func TestReadOnlyViolation(t *testing.T) {
+	if !ReadOnlyViolation(fmt.Errorf("insert: %w", &pgconn.PgError{Code: "25006"})) {
+		t.Error("Expected a read-only transaction error to be detected")
+	}
+	if ReadOnlyViolation(&pgconn.PgError{Code: "23505"}) || ReadOnlyViolation(errors.New("connection refused")) {
+		t.Error("Expected other errors not to be read-only violations")
+	}
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
+)
+
+var fanOutQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_fanout_query_duration_seconds",
+	Help:    "Time taken by each query of a fan-out, by query name.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"query"})
+
+// FanOut runs independent read-only queries, such as the widgets of a
+// dashboard, concurrently on the analytics pool (see Analytics), so a
+// response takes as long as its slowest query instead of all of them:
+//
+//	fanOut := db.NewFanOut(ctx)
+//	fanOut.Go("customers", func(h db.Handle) error {
+//		return h.QueryRow("SELECT COUNT(*) FROM customers").Scan(&customers)
+//	})
+//	fanOut.Go("accounts", ...)
+//	if err := fanOut.Wait(); err != nil { ... }
+//
+// At most FANOUT_CONCURRENCY (default 4) of a fan-out's queries run at once,
+// each within FANOUT_QUERY_TIMEOUT (default 10s). The first error cancels
+// the queries still running. Each query must read all its rows before
+// returning, and only write to its own variables.
+type FanOut struct {
+	ctx     context.Context
+	group   *errgroup.Group
+	timeout time.Duration
+}
+
+// NewFanOut returns a fan-out whose queries run with ctx
+func NewFanOut(ctx context.Context) *FanOut {
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(FanOutConcurrency())
+	return &FanOut{ctx: ctx, group: group, timeout: FanOutQueryTimeout()}
+}
+
+// Go runs query, named for errors and metrics, with a handle on the analytics
+// pool bound to a context carrying the query timeout. It waits while the
+// fan-out's queries are at its concurrency limit.
+func (f *FanOut) Go(name string, query func(Handle) error) {
+	f.group.Go(func() error {
+		ctx, cancel := context.WithTimeout(f.ctx, f.timeout)
+		defer cancel()
+
+		start := time.Now()
+		err := query(Analytics(ctx))
+		fanOutQueryDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			return &QueryError{
+				Query:    name,
+				TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded) && f.ctx.Err() == nil,
+				Err:      err,
+			}
+		}
+		return nil
+	})
+}
+
+// Wait waits for every query and returns the first error, a *QueryError
+func (f *FanOut) Wait() error {
+	return f.group.Wait()
+}
+
+// QueryError is the error of a fan-out's query
+type QueryError struct {
+	// Query is the name the query was run with
+	Query string
+	// TimedOut is set if the query ran out of FANOUT_QUERY_TIMEOUT
+	TimedOut bool
+	Err      error
+}
+
+func (e *QueryError) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("%s: timed out: %v", e.Query, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// FanOutConcurrency reads FANOUT_CONCURRENCY (default 4), the number of a
+// fan-out's queries that run at once. Every request fanning out uses that
+// many connections, so keep it well below DB_MAX_OPEN_CONNS.
+func FanOutConcurrency() int {
+	value := os.Getenv("FANOUT_CONCURRENCY")
+	if value == "" {
+		return 4
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		log.Printf("Warning: Invalid value for FANOUT_CONCURRENCY (%s), using default 4", value)
+		return 4
+	}
+	return n
+}
+
+// FanOutQueryTimeout reads FANOUT_QUERY_TIMEOUT (default 10s), how long each
+// query of a fan-out may take
+func FanOutQueryTimeout() time.Duration {
+	value := os.Getenv("FANOUT_QUERY_TIMEOUT")
+	if value == "" {
+		return 10 * time.Second
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Printf("Warning: Invalid value for FANOUT_QUERY_TIMEOUT (%s), using default 10s", value)
+		return 10 * time.Second
+	}
+	return timeout
+}
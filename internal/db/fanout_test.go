@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanOutBoundsConcurrency(t *testing.T) {
+	t.Setenv("FANOUT_CONCURRENCY", "2")
+	t.Setenv("FANOUT_QUERY_TIMEOUT", "")
+
+	var running, most atomic.Int32
+	results := make([]int, 6)
+	fanOut := NewFanOut(context.Background())
+	for i := range results {
+		fanOut.Go("widget", func(Handle) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				seen := most.Load()
+				if n <= seen || most.CompareAndSwap(seen, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			results[i] = i + 1
+			return nil
+		})
+	}
+	if err := fanOut.Wait(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := most.Load(); got != 2 {
+		t.Errorf("Expected at most 2 queries at once, got %d", got)
+	}
+	for i, result := range results {
+		if result != i+1 {
+			t.Errorf("Expected every query to run, query %d didn't", i)
+		}
+	}
+}
+
+func TestFanOutQueryTimeout(t *testing.T) {
+	t.Setenv("FANOUT_CONCURRENCY", "")
+	t.Setenv("FANOUT_QUERY_TIMEOUT", "20ms")
+
+	var cancelled atomic.Bool
+	fanOut := NewFanOut(context.Background())
+	fanOut.Go("slow widget", func(h Handle) error {
+		<-h.Context().Done()
+		return h.Context().Err()
+	})
+	fanOut.Go("waiting widget", func(h Handle) error {
+		select {
+		case <-h.Context().Done():
+			cancelled.Store(true)
+		case <-time.After(time.Second):
+		}
+		return nil
+	})
+
+	err := fanOut.Wait()
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Query != "slow widget" || !queryErr.TimedOut {
+		t.Fatalf("Expected the slow widget to time out, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the query's error to be wrapped, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the first error to cancel the other queries")
+	}
+}
+
+func TestFanOutFirstError(t *testing.T) {
+	failed := errors.New("relation does not exist")
+	fanOut := NewFanOut(context.Background())
+	fanOut.Go("ok", func(Handle) error { return nil })
+	fanOut.Go("broken", func(Handle) error { return failed })
+
+	err := fanOut.Wait()
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Query != "broken" || queryErr.TimedOut || !errors.Is(err, failed) {
+		t.Errorf("Expected the broken query's error, got %v", err)
+	}
+	if err.Error() != "broken: relation does not exist" {
+		t.Errorf("Expected the error to name the query, got %q", err.Error())
+	}
+}
+
+func TestFanOutSettingsFromEnv(t *testing.T) {
+	t.Setenv("FANOUT_CONCURRENCY", "")
+	t.Setenv("FANOUT_QUERY_TIMEOUT", "")
+	if FanOutConcurrency() != 4 || FanOutQueryTimeout() != 10*time.Second {
+		t.Errorf("Expected defaults 4 and 10s, got %d and %v", FanOutConcurrency(), FanOutQueryTimeout())
+	}
+
+	t.Setenv("FANOUT_CONCURRENCY", "8")
+	t.Setenv("FANOUT_QUERY_TIMEOUT", "2s")
+	if FanOutConcurrency() != 8 || FanOutQueryTimeout() != 2*time.Second {
+		t.Errorf("Expected 8 and 2s, got %d and %v", FanOutConcurrency(), FanOutQueryTimeout())
+	}
+
+	t.Setenv("FANOUT_CONCURRENCY", "0")
+	t.Setenv("FANOUT_QUERY_TIMEOUT", "-1s")
+	if FanOutConcurrency() != 4 || FanOutQueryTimeout() != 10*time.Second {
+		t.Errorf("Expected invalid values to fall back to defaults, got %d and %v", FanOutConcurrency(), FanOutQueryTimeout())
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Handle is a connection pool bound to a context, usually the request's, so
+// every query run through it is cancelled when the client goes away. Get one
+// with Primary or Analytics; it is cheap and meant to be created per call.
+type Handle struct {
+	ctx  context.Context
+	pool *sql.DB
+	// shadow is set on analytics handles sent to the primary by
+	// analytics_routing, whose reads may be mirrored (see shadowRead)
+	shadow bool
+}
+
+// Primary returns the primary database bound to ctx
+func Primary(ctx context.Context) Handle {
+	return Handle{ctx: ctx, pool: PrimaryDB}
+}
+
+// Analytics returns the pool for read-only analytics queries (see
+// AnalyticsPool) bound to ctx, or the primary if ctx was marked with
+// WithPrimary
+func Analytics(ctx context.Context) Handle {
+	if PinnedToPrimary(ctx) {
+		return Handle{ctx: ctx, pool: PrimaryDB}
+	}
+	pool := AnalyticsPool()
+	return Handle{ctx: ctx, pool: pool, shadow: pool == PrimaryDB}
+}
+
+// Context returns the context queries run with
+func (h Handle) Context() context.Context {
+	return h.ctx
+}
+
+// Query runs a query that returns rows
+func (h Handle) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if h.shadow {
+		shadowRead(query, args)
+	}
+	return h.pool.QueryContext(h.ctx, query, args...)
+}
+
+// QueryRow runs a query that returns at most one row
+func (h Handle) QueryRow(query string, args ...interface{}) *sql.Row {
+	if h.shadow {
+		shadowRead(query, args)
+	}
+	return h.pool.QueryRowContext(h.ctx, query, args...)
+}
+
+// Exec runs a statement that returns no rows
+func (h Handle) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return h.pool.ExecContext(h.ctx, query, args...)
+}
+
+// Begin starts a transaction that is rolled back if the context is cancelled
+// before it commits
+func (h Handle) Begin() (*sql.Tx, error) {
+	return h.pool.BeginTx(h.ctx, nil)
+}
+
+// Ping verifies a connection can be made
+func (h Handle) Ping() error {
+	return h.pool.PingContext(h.ctx)
+}
+
+// QueryTimeout reads DB_QUERY_TIMEOUT, the longest a single statement may run
+// before Postgres cancels it (default 30s, 0 disables). It is applied as the
+// statement_timeout of every connection, so a hung query frees its
+// connection instead of holding it until the pool is exhausted.
+func QueryTimeout() time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return 30 * time.Second
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		log.Printf("Warning: Invalid value for DB_QUERY_TIMEOUT (%s), using default 30s", value)
+		return 30 * time.Second
+	}
+	return timeout
+}
+
+// withQueryTimeout adds the statement_timeout from QueryTimeout to a
+// connection string
+func withQueryTimeout(databaseURL string) string {
+	timeout := QueryTimeout()
+	if timeout == 0 {
+		return databaseURL
+	}
+	return withRuntimeParam(databaseURL, "statement_timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"fmt"
+)
+
+// VersionedTables keep every version of their rows in a <table>_history table,
+// maintained by the record_history trigger (see migrations/0001_baseline.up.sql).
+// Each version stores the full row as JSONB with the period it was current, so
+// columns added later are versioned without touching the triggers.
+var VersionedTables = []string{"customers", "accounts"}
+
+// AsOf returns a subquery that can replace table in a FROM clause to read
+// rows as they existed at the timestamp bound to placeholder $param. It has
+// the same columns as table, so existing queries work unchanged.
+func AsOf(table string, param int) string {
+	return fmt.Sprintf(`(
+		SELECT r.* FROM %[1]s_history h, jsonb_populate_record(NULL::%[1]s, h.data) r
+		WHERE h.valid_from <= $%[2]d AND (h.valid_to IS NULL OR h.valid_to > $%[2]d)
+	) %[1]s`, table, param)
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAsOfReturnsPreviousVersion(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	if err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	var id int
+	email := "history-" + time.Now().Format("20060102150405.000000") + "@example.com"
+	if err := PrimaryDB.QueryRow("INSERT INTO customers (name, email) VALUES ('Before', $1) RETURNING id", email).Scan(&id); err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", id)
+
+	var before time.Time
+	if err := PrimaryDB.QueryRow("SELECT now()").Scan(&before); err != nil {
+		t.Fatalf("Failed to read time: %v", err)
+	}
+	if _, err := PrimaryDB.Exec("UPDATE customers SET name = 'After' WHERE id = $1", id); err != nil {
+		t.Fatalf("Failed to update customer: %v", err)
+	}
+
+	var name string
+	if err := PrimaryDB.QueryRow("SELECT name FROM "+AsOf("customers", 2)+" WHERE id = $1", id, before).Scan(&name); err != nil {
+		t.Fatalf("Failed to read customer as of %s: %v", before, err)
+	}
+	if name != "Before" {
+		t.Errorf("Expected name Before, got %s", name)
+	}
+}
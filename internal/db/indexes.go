@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// explainMinRows is how many rows a table needs before a sequential scan in
+// a checked plan is worth a warning; below it the planner rightly prefers one
+const explainMinRows = 10000
+
+// ExpectedIndex is an index the list endpoints rely on once tables are large.
+// Any valid, non-partial index whose leading columns are Columns satisfies it.
+type ExpectedIndex struct {
+	Table   string
+	Columns []string
+	// Query is a representative query that should use the index
+	Query string
+}
+
+// ExpectedIndexes are checked at startup (see CheckIndexes). Migrations
+// 0007-0010 create them, and customers.email is covered by its UNIQUE constraint.
+var ExpectedIndexes = []ExpectedIndex{
+	{
+		Table:   "accounts",
+		Columns: []string{"customer_id"},
+		Query:   "SELECT id FROM accounts WHERE customer_id = 1 ORDER BY created_at DESC, id DESC LIMIT 50",
+	},
+	{
+		Table:   "accounts",
+		Columns: []string{"status"},
+		Query:   "SELECT id FROM accounts WHERE status = 'active' ORDER BY created_at DESC, id DESC LIMIT 50",
+	},
+	{
+		Table:   "accounts",
+		Columns: []string{"created_at", "id"},
+		Query:   "SELECT id FROM accounts ORDER BY created_at DESC, id DESC LIMIT 50",
+	},
+	{
+		Table:   "customers",
+		Columns: []string{"email"},
+		Query:   "SELECT id FROM customers WHERE email = 'contact@example.com'",
+	},
+	{
+		Table:   "customers",
+		Columns: []string{"created_at", "id"},
+		Query:   "SELECT id FROM customers ORDER BY created_at DESC, id DESC LIMIT 50",
+	},
+}
+
+func (e ExpectedIndex) String() string {
+	return fmt.Sprintf("%s (%s)", e.Table, strings.Join(e.Columns, ", "))
+}
+
+// covers reports whether an index on columns satisfies e
+func (e ExpectedIndex) covers(columns []string) bool {
+	if len(columns) < len(e.Columns) {
+		return false
+	}
+	for i, column := range e.Columns {
+		if columns[i] != column {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexReport is the outcome of CheckIndexes
+type IndexReport struct {
+	// Missing are expected indexes with no valid index covering them
+	Missing []ExpectedIndex
+	// Invalid are indexes left unusable by a failed concurrent build
+	Invalid []string
+	// SeqScans describe checked queries planned as a sequential scan of a large table
+	SeqScans []string
+}
+
+// CheckIndexes verifies the ExpectedIndexes exist and are valid, and EXPLAINs
+// their queries to confirm the planner uses an index on tables with at least
+// explainMinRows rows
+func CheckIndexes(ctx context.Context) (IndexReport, error) {
+	var report IndexReport
+	tables := map[string]bool{}
+	for _, expected := range ExpectedIndexes {
+		tables[expected.Table] = true
+	}
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+
+	// Columns of each index in key order; expression columns come back empty,
+	// so they never match an expected column
+	rows, err := PrimaryDB.QueryContext(ctx, `
+		SELECT t.relname, ix.relname, i.indisvalid, i.indpred IS NOT NULL,
+			string_agg(COALESCE(a.attname, ''), ',' ORDER BY k.ord)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class ix ON ix.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = current_schema() AND t.relname = ANY($1)
+		GROUP BY t.relname, ix.relname, i.indisvalid, i.indpred IS NOT NULL`,
+		names,
+	)
+	if err != nil {
+		return report, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := map[string][][]string{}
+	for rows.Next() {
+		var table, name string
+		var valid, partial bool
+		var columns string
+		if err := rows.Scan(&table, &name, &valid, &partial, &columns); err != nil {
+			return report, fmt.Errorf("failed to scan index: %w", err)
+		}
+		if !valid {
+			report.Invalid = append(report.Invalid, name)
+			continue
+		}
+		if !partial {
+			indexes[table] = append(indexes[table], strings.Split(columns, ","))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	for _, expected := range ExpectedIndexes {
+		covered := false
+		for _, columns := range indexes[expected.Table] {
+			if expected.covers(columns) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			report.Missing = append(report.Missing, expected)
+		}
+	}
+
+	for _, expected := range ExpectedIndexes {
+		var estimate float64
+		if err := PrimaryDB.QueryRowContext(ctx, "SELECT reltuples FROM pg_class WHERE oid = to_regclass($1)", expected.Table).Scan(&estimate); err != nil {
+			return report, fmt.Errorf("failed to estimate %s rows: %w", expected.Table, err)
+		}
+		if estimate < explainMinRows {
+			continue
+		}
+
+		var plan string
+		if err := PrimaryDB.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+expected.Query).Scan(&plan); err != nil {
+			return report, fmt.Errorf("failed to explain %q: %w", expected.Query, err)
+		}
+		scanned, err := seqScannedTables(plan)
+		if err != nil {
+			return report, fmt.Errorf("failed to read plan of %q: %w", expected.Query, err)
+		}
+		for _, table := range scanned {
+			if table == expected.Table {
+				report.SeqScans = append(report.SeqScans, fmt.Sprintf("%s scans all ~%.0f rows of %s", expected.Query, estimate, table))
+			}
+		}
+	}
+	return report, nil
+}
+
+// planNode is the part of an EXPLAIN (FORMAT JSON) node that seqScannedTables reads
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// seqScannedTables returns the tables a JSON plan reads with a sequential scan
+func seqScannedTables(plan string) ([]string, error) {
+	var explained []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil {
+		return nil, err
+	}
+
+	var tables []string
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		if node.NodeType == "Seq Scan" {
+			tables = append(tables, node.RelationName)
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, statement := range explained {
+		walk(statement.Plan)
+	}
+	return tables, nil
+}
+
+// LogIndexReport checks the indexes and logs anything wrong. Problems only
+// slow queries down, so they are warnings rather than startup failures.
+func LogIndexReport(ctx context.Context) {
+	report, err := CheckIndexes(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to check indexes: %v", err)
+		return
+	}
+	for _, missing := range report.Missing {
+		log.Printf("Warning: Missing index on %s; list endpoints will slow down as the table grows (run `migrate up`)", missing)
+	}
+	for _, name := range report.Invalid {
+		log.Printf("Warning: Index %s is invalid, likely from an interrupted CREATE INDEX CONCURRENTLY; drop it and run `migrate up` again", name)
+	}
+	for _, scan := range report.SeqScans {
+		log.Printf("Warning: Query plan does not use an index: %s", scan)
+	}
+	if len(report.Missing) == 0 && len(report.Invalid) == 0 && len(report.SeqScans) == 0 {
+		log.Println("Database indexes are in place")
+	}
+}
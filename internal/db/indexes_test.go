@@ -0,0 +1,43 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpectedIndexCovers(t *testing.T) {
+	expected := ExpectedIndex{Table: "customers", Columns: []string{"created_at", "id"}}
+	tests := []struct {
+		columns []string
+		want    bool
+	}{
+		{[]string{"created_at", "id"}, true},
+		{[]string{"created_at", "id", "name"}, true},
+		{[]string{"created_at"}, false},
+		{[]string{"id", "created_at"}, false},
+		{[]string{""}, false},
+	}
+	for _, tt := range tests {
+		if got := expected.covers(tt.columns); got != tt.want {
+			t.Errorf("covers(%v) = %v, want %v", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestSeqScannedTables(t *testing.T) {
+	plan := `[{"Plan": {"Node Type": "Limit", "Plans": [
+		{"Node Type": "Sort", "Plans": [{"Node Type": "Seq Scan", "Relation Name": "accounts"}]},
+		{"Node Type": "Index Scan", "Relation Name": "customers", "Index Name": "customers_email_key"}
+	]}}]`
+	tables, err := seqScannedTables(plan)
+	if err != nil {
+		t.Fatalf("Failed to read plan: %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"accounts"}) {
+		t.Errorf("Expected a sequential scan of accounts only, got %v", tables)
+	}
+
+	if _, err := seqScannedTables("not json"); err == nil {
+		t.Error("Expected an error for a malformed plan")
+	}
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// IntegrityCheck is a data invariant with a query counting its violations and,
+// where a safe fix exists, a statement repairing them
+type IntegrityCheck struct {
+	Name        string
+	Description string
+	Count       string
+	Repair      string
+}
+
+// IntegrityResult is the outcome of running one IntegrityCheck
+type IntegrityResult struct {
+	Check       string `json:"check"`
+	Description string `json:"description"`
+	Violations  int    `json:"violations"`
+	Repairable  bool   `json:"repairable"`
+	Repaired    int64  `json:"repaired"`
+}
+
+// IntegrityChecks are the invariants verified by the integrity job. Foreign keys
+// cover most of these, but bulk loads and manual fixes can bypass them.
+var IntegrityChecks = []IntegrityCheck{
+	{
+		Name:        "accounts_missing_customer",
+		Description: "Accounts whose customer no longer exists",
+		Count:       "SELECT COUNT(*) FROM accounts a WHERE NOT EXISTS (SELECT 1 FROM customers c WHERE c.id = a.customer_id)",
+		Repair:      "DELETE FROM accounts a WHERE NOT EXISTS (SELECT 1 FROM customers c WHERE c.id = a.customer_id)",
+	},
+	{
+		Name:        "notes_missing_account",
+		Description: "Account notes whose account no longer exists",
+		Count:       "SELECT COUNT(*) FROM account_notes n WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.id = n.account_id)",
+		Repair:      "DELETE FROM account_notes n WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.id = n.account_id)",
+	},
+	{
+		// account_seq is a denormalized counter; if it falls behind, the next reference collides
+		Name:        "customer_account_seq_behind",
+		Description: "Customers whose account sequence is lower than the highest issued reference",
+		Count: `
+			SELECT COUNT(*) FROM customers c
+			JOIN (
+				SELECT customer_id, MAX(substring(reference from '-(\d+)-\d$')::int) AS max_seq
+				FROM accounts WHERE reference IS NOT NULL GROUP BY customer_id
+			) issued ON issued.customer_id = c.id
+			WHERE c.account_seq < issued.max_seq`,
+		Repair: `
+			UPDATE customers c SET account_seq = issued.max_seq
+			FROM (
+				SELECT customer_id, MAX(substring(reference from '-(\d+)-\d$')::int) AS max_seq
+				FROM accounts WHERE reference IS NOT NULL GROUP BY customer_id
+			) issued
+			WHERE issued.customer_id = c.id AND c.account_seq < issued.max_seq`,
+	},
+	{
+		Name:        "accounts_missing_reference",
+		Description: "Accounts without a reference (created before references or by a bulk load)",
+		Count:       "SELECT COUNT(*) FROM accounts WHERE reference IS NULL",
+	},
+	{
+		Name:        "notifications_unknown_user",
+		Description: "Notifications addressed to users that no longer exist",
+		Count:       "SELECT COUNT(*) FROM notifications n WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.username = n.username)",
+		Repair:      "DELETE FROM notifications n WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.username = n.username)",
+	},
+	{
+		// Audit rows are kept even when orphaned; they are reported, never deleted
+		Name:        "config_audit_unknown_actor",
+		Description: "Config audit rows whose actor is neither a system process nor an existing user",
+		Count:       "SELECT COUNT(*) FROM config_audit a WHERE a.actor <> 'system' AND NOT EXISTS (SELECT 1 FROM users u WHERE u.username = a.actor)",
+	},
+}
+
+// CheckIntegrity runs every integrity check. With repair set, violations of
+// repairable checks are fixed with a single statement per check.
+func CheckIntegrity(ctx context.Context, repair bool) ([]IntegrityResult, error) {
+	results := make([]IntegrityResult, 0, len(IntegrityChecks))
+	for _, check := range IntegrityChecks {
+		result := IntegrityResult{
+			Check:       check.Name,
+			Description: check.Description,
+			Repairable:  check.Repair != "",
+		}
+
+		if err := PrimaryDB.QueryRowContext(ctx, check.Count).Scan(&result.Violations); err != nil {
+			return nil, fmt.Errorf("failed to run integrity check %s: %w", check.Name, err)
+		}
+
+		if repair && result.Repairable && result.Violations > 0 {
+			repaired, err := repairIntegrity(ctx, check)
+			if err != nil {
+				return nil, fmt.Errorf("failed to repair %s: %w", check.Name, err)
+			}
+			result.Repaired = repaired
+		}
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func repairIntegrity(ctx context.Context, check IntegrityCheck) (int64, error) {
+	res, err := PrimaryDB.ExecContext(ctx, check.Repair)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
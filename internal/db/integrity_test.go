@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	if err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	results, err := CheckIntegrity(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Failed to run integrity checks: %v", err)
+	}
+
+	if len(results) != len(IntegrityChecks) {
+		t.Errorf("Expected %d results, got %d", len(IntegrityChecks), len(results))
+	}
+	for _, result := range results {
+		if result.Repaired != 0 {
+			t.Errorf("Check %s repaired rows without repair mode", result.Check)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var replicationLag = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "db_replication_lag_seconds",
+	Help: "How far the analytics follower lagged the primary when last checked.",
+})
+
+// lagCheckInterval is how long a replication lag measurement is reused, so
+// requests don't each add a query to the follower
+const lagCheckInterval = 5 * time.Second
+
+var (
+	// measureLag is ReplicationLag, replaceable in tests
+	measureLag = ReplicationLag
+
+	lagMu      sync.Mutex
+	lagChecked time.Time
+	lastLag    time.Duration
+	lastLagErr error
+)
+
+// ReplicationLag returns how far AnalyticsDB lags the primary: the time since
+// the last replayed transaction, or 0 when the follower has replayed all the
+// WAL it received (an idle primary sends no new transactions, so the replay
+// timestamp alone would keep growing). It is 0 when AnalyticsDB is the
+// primary or isn't a replica at all.
+func ReplicationLag(ctx context.Context) (time.Duration, error) {
+	if AnalyticsDB == nil || AnalyticsDB == PrimaryDB {
+		return 0, nil
+	}
+
+	var seconds float64
+	err := AnalyticsDB.QueryRowContext(ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+		END`,
+	).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// MaxReplicaLag reads DB_MAX_REPLICA_LAG, the replication lag above which
+// analytics reads fall back to the primary (default 30s, 0 disables)
+func MaxReplicaLag() time.Duration {
+	value := os.Getenv("DB_MAX_REPLICA_LAG")
+	if value == "" {
+		return 30 * time.Second
+	}
+	lag, err := time.ParseDuration(value)
+	if err != nil || lag < 0 {
+		log.Printf("Warning: Invalid value for DB_MAX_REPLICA_LAG (%s), using default 30s", value)
+		return 30 * time.Second
+	}
+	return lag
+}
+
+// ReplicaStale reports whether the follower lags the primary by more than
+// MaxReplicaLag, so reads should go to the primary instead. The lag is
+// measured at most every few seconds. A follower whose lag can't be measured
+// counts as stale, since it is likely unreachable as well.
+func ReplicaStale(ctx context.Context) bool {
+	max := MaxReplicaLag()
+	if max == 0 || AnalyticsPool() == PrimaryDB {
+		return false
+	}
+
+	lag, _, err := cachedLag(ctx)
+	return err != nil || lag > max
+}
+
+// FollowerDataAsOf returns the time the follower's data was current on the
+// primary: when the lag was last measured, less the lag. Like ReplicaStale,
+// it reuses measurements for a few seconds.
+func FollowerDataAsOf(ctx context.Context) (time.Time, error) {
+	lag, checked, err := cachedLag(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return checked.Add(-lag), nil
+}
+
+// cachedLag returns the last replication lag measurement and when it was
+// taken, measuring again if it is older than lagCheckInterval
+func cachedLag(ctx context.Context) (time.Duration, time.Time, error) {
+	lagMu.Lock()
+	defer lagMu.Unlock()
+	if time.Since(lagChecked) >= lagCheckInterval {
+		// Measure independently of the request, since the result is shared
+		checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+		lastLag, lastLagErr = measureLag(checkCtx)
+		cancel()
+		lagChecked = time.Now()
+		if lastLagErr != nil {
+			log.Printf("Failed to measure replication lag: %v", lastLagErr)
+		} else {
+			replicationLag.Set(lastLag.Seconds())
+			if max := MaxReplicaLag(); max > 0 && lastLag > max {
+				log.Printf("Replication lag %s exceeds DB_MAX_REPLICA_LAG (%s), reading from primary", lastLag.Round(time.Millisecond), max)
+			}
+		}
+	}
+	return lastLag, lagChecked, lastLagErr
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxReplicaLag(t *testing.T) {
+	t.Setenv("DB_MAX_REPLICA_LAG", "")
+	if got := MaxReplicaLag(); got != 30*time.Second {
+		t.Errorf("Expected default 30s, got %s", got)
+	}
+	t.Setenv("DB_MAX_REPLICA_LAG", "0")
+	if got := MaxReplicaLag(); got != 0 {
+		t.Errorf("Expected 0 to disable the check, got %s", got)
+	}
+	t.Setenv("DB_MAX_REPLICA_LAG", "-5s")
+	if got := MaxReplicaLag(); got != 30*time.Second {
+		t.Errorf("Expected negative lag to fall back to 30s, got %s", got)
+	}
+}
+
+func TestReplicaStale(t *testing.T) {
+	primary, analytics := PrimaryDB, AnalyticsDB
+	t.Cleanup(func() {
+		PrimaryDB, AnalyticsDB, measureLag = primary, analytics, ReplicationLag
+		lagChecked = time.Time{}
+	})
+	PrimaryDB, AnalyticsDB = new(sql.DB), new(sql.DB)
+	t.Setenv("DB_MAX_REPLICA_LAG", "10s")
+
+	var lag time.Duration
+	var lagErr error
+	calls := 0
+	measureLag = func(context.Context) (time.Duration, error) {
+		calls++
+		return lag, lagErr
+	}
+	check := func() bool {
+		lagChecked = time.Time{}
+		return ReplicaStale(context.Background())
+	}
+
+	lag = 2 * time.Second
+	if check() {
+		t.Error("Expected 2s of lag to be within the limit")
+	}
+	lag = time.Minute
+	if !check() {
+		t.Error("Expected a minute of lag to be stale")
+	}
+	lag, lagErr = 0, errors.New("connection refused")
+	if !check() {
+		t.Error("Expected an unmeasurable follower to be stale")
+	}
+
+	// Measurements are reused between checks
+	lagErr = nil
+	calls = 0
+	check()
+	ReplicaStale(context.Background())
+	if calls != 1 {
+		t.Errorf("Expected one measurement, got %d", calls)
+	}
+
+	t.Setenv("DB_MAX_REPLICA_LAG", "0")
+	lag = time.Hour
+	if check() {
+		t.Error("Expected no fallback with the check disabled")
+	}
+	t.Setenv("DB_MAX_REPLICA_LAG", "10s")
+	AnalyticsDB = PrimaryDB
+	if check() {
+		t.Error("Expected no fallback without a separate follower")
+	}
+}
+
+func TestAnalyticsWithPrimary(t *testing.T) {
+	primary, analytics := PrimaryDB, AnalyticsDB
+	t.Cleanup(func() { PrimaryDB, AnalyticsDB = primary, analytics })
+	PrimaryDB, AnalyticsDB = new(sql.DB), new(sql.DB)
+
+	if Analytics(context.Background()).pool != AnalyticsDB {
+		t.Error("Expected analytics reads on the follower by default")
+	}
+	if Analytics(WithPrimary(context.Background())).pool != PrimaryDB {
+		t.Error("Expected analytics reads on the primary with WithPrimary")
+	}
+}
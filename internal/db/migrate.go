@@ -0,0 +1,397 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema changes are versioned SQL files in migrations/, embedded in the
+// binary. Each version has an up file and optionally a down file:
+//
+//	migrations/0002_add_widgets.up.sql
+//	migrations/0002_add_widgets.down.sql
+//
+// Applied versions are recorded in schema_migrations with a checksum of the
+// up file, so editing a migration after it shipped is reported by status.
+// Each file runs in its own transaction unless its first line is
+// "-- migrate: no-transaction", for statements such as CREATE INDEX
+// CONCURRENTLY; such a file must hold a single statement, since Postgres runs
+// the statements of one query string in an implicit transaction.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key that keeps dynos starting at the
+// same time from applying migrations concurrently
+const migrationLockID = 7262251
+
+const noTransactionDirective = "-- migrate: no-transaction"
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrSchemaBehind is returned by EnsureSchema when migrations are pending and
+// DB_AUTO_MIGRATE is false
+var ErrSchemaBehind = errors.New("database schema is behind this release")
+
+// Migration is one version of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the contents of the up file
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the up file changed after it was applied
+	Modified bool `json:"modified,omitempty"`
+	// Unknown is set for applied versions this release has no file for,
+	// e.g. after rolling back to an older release
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+func (s MigrationStatus) String() string {
+	state := "pending"
+	if s.Applied {
+		state = "applied " + s.AppliedAt.Format(time.RFC3339)
+	}
+	switch {
+	case s.Unknown:
+		state += " (not in this release)"
+	case s.Modified:
+		state += " (modified since applied)"
+	}
+	return fmt.Sprintf("%04d %-40s %s", s.Version, s.Name, state)
+}
+
+// Migrations returns the embedded migrations ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		contents, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// MigrateUp applies every pending migration in order
+func MigrateUp(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	return withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, migration.Checksum(),
+			); err != nil {
+				return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+			count++
+		}
+		if count == 0 {
+			log.Println("Database schema is up to date")
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first
+func MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	return withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not in this release, revert it with the release that added it", version, applied[version].name)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down file", version, migration.Name)
+			}
+			if err := runMigration(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", version,
+			); err != nil {
+				return fmt.Errorf("failed to revert migration %04d_%s: %w", version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %04d_%s", version, migration.Name)
+		}
+		return nil
+	})
+}
+
+// MigrationStatuses returns every embedded migration and every applied
+// version, ordered by version
+func MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := PrimaryDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = record.checksum != migration.Checksum()
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		appliedAt := record.appliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: record.name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// EnsureSchema brings the schema up to date on startup, or with
+// DB_AUTO_MIGRATE=false only checks it and returns ErrSchemaBehind if any
+// migration is pending, so the process fails fast instead of serving
+// requests against tables it doesn't expect
+func EnsureSchema(ctx context.Context) error {
+	if AutoMigrate() {
+		return MigrateUp(ctx)
+	}
+
+	statuses, err := MigrationStatuses(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+		if status.Modified {
+			log.Printf("Warning: Migration %04d_%s was modified after it was applied", status.Version, status.Name)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending migrations %s (run `migrate up` or set DB_AUTO_MIGRATE=true)", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	log.Println("Database schema is up to date")
+	return nil
+}
+
+// AutoMigrate reads DB_AUTO_MIGRATE, whether startup applies pending
+// migrations (default true)
+func AutoMigrate() bool {
+	value := os.Getenv("DB_AUTO_MIGRATE")
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for DB_AUTO_MIGRATE (%s), using default true", value)
+		return true
+	}
+	return enabled
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration
+// advisory lock
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := PrimaryDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Migrations may wait for the lock or rewrite large tables, so they are
+	// exempt from DB_QUERY_TIMEOUT; RESET restores it before the connection
+	// goes back to the pool
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 0"); err != nil {
+		return fmt.Errorf("failed to disable statement timeout: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "RESET statement_timeout"); err != nil {
+			log.Printf("Warning: Failed to restore statement timeout: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("Warning: Failed to release migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedMigrations reads schema_migrations; a database that has never been
+// migrated has none
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	applied := make(map[int]appliedMigration)
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx,
+		"SELECT to_regclass(format('%I.schema_migrations', current_schema())) IS NOT NULL",
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check for schema_migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// runMigration runs a migration file and the statement that records it,
+// together in one transaction unless the file opts out
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	if strings.HasPrefix(strings.TrimSpace(script), noTransactionDirective) {
+		if _, err := conn.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
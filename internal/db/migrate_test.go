@@ -0,0 +1,59 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %04d_%s", i, i+1, migration.Version, migration.Name)
+		}
+		if migration.Down == "" {
+			t.Errorf("Expected migration %04d_%s to have a down file", migration.Version, migration.Name)
+		}
+		if strings.Contains(migration.Up, noTransactionDirective) && !strings.HasPrefix(strings.TrimSpace(migration.Up), noTransactionDirective) {
+			t.Errorf("Migration %04d_%s must put %q on its first line", migration.Version, migration.Name, noTransactionDirective)
+		}
+	}
+}
+
+func TestMigrationChecksum(t *testing.T) {
+	a := Migration{Up: "CREATE TABLE a (id INTEGER);"}
+	b := Migration{Up: "CREATE TABLE a (id BIGINT);"}
+	if a.Checksum() == b.Checksum() {
+		t.Error("Expected different up files to have different checksums")
+	}
+	if a.Checksum() != (Migration{Up: a.Up, Down: "DROP TABLE a;"}).Checksum() {
+		t.Error("Expected the checksum to ignore the down file")
+	}
+}
+
+func TestAutoMigrate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{"0", false},
+		{"not-a-bool", true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("DB_AUTO_MIGRATE", tt.value)
+		if got := AutoMigrate(); got != tt.expected {
+			t.Errorf("AutoMigrate() with DB_AUTO_MIGRATE=%q = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
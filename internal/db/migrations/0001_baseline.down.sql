@@ -0,0 +1,20 @@
+-- Drops everything the baseline creates, including all data
+DROP TABLE IF EXISTS
+	pii_access_log,
+	customers_history,
+	accounts_history,
+	contact_issues,
+	webhook_deliveries,
+	webhook_endpoints,
+	account_duplicate_candidates,
+	api_usage_rollups,
+	config_audit,
+	anomaly_events,
+	login_attempts,
+	notifications,
+	account_notes,
+	users,
+	accounts,
+	customers
+CASCADE;
+DROP FUNCTION IF EXISTS record_history();
@@ -0,0 +1,225 @@
+-- Baseline: the schema previously created by CreateTables. Every statement is
+-- idempotent so databases created before migrations existed adopt it cleanly.
+
+CREATE TABLE IF NOT EXISTS customers (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id SERIAL PRIMARY KEY,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	status VARCHAR(50) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL UNIQUE,
+	password_hash VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Roles were added after users existed; promote the seeded admin when the column is first created
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'role') THEN
+		ALTER TABLE users ADD COLUMN role VARCHAR(50) NOT NULL DEFAULT 'user';
+		UPDATE users SET role = 'admin' WHERE username = 'admin';
+	END IF;
+END $$;
+
+-- Per-customer sequence and human-friendly account references
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS account_seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS reference VARCHAR(64) UNIQUE;
+
+-- Account type selects the JSON Schema the settings document must match (see internal/accountsettings)
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS type VARCHAR(50) NOT NULL DEFAULT 'standard';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(settings) = 'object');
+
+-- Notes carry a generated tsvector so full-text search can use a GIN index
+CREATE TABLE IF NOT EXISTS account_notes (
+	id SERIAL PRIMARY KEY,
+	account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	author VARCHAR(255) NOT NULL,
+	body TEXT NOT NULL,
+	mentions TEXT[] NOT NULL DEFAULT '{}',
+	search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', body)) STORED,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_account_notes_search ON account_notes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_account_notes_account_id ON account_notes (account_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	kind VARCHAR(50) NOT NULL,
+	message TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_username ON notifications (username, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	success BOOLEAN NOT NULL,
+	ip_address VARCHAR(64),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created_at ON login_attempts (created_at);
+
+CREATE TABLE IF NOT EXISTS anomaly_events (
+	id SERIAL PRIMARY KEY,
+	metric VARCHAR(100) NOT NULL,
+	observed DOUBLE PRECISION NOT NULL,
+	baseline_mean DOUBLE PRECISION NOT NULL,
+	baseline_stddev DOUBLE PRECISION NOT NULL,
+	threshold DOUBLE PRECISION NOT NULL,
+	detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS config_audit (
+	id SERIAL PRIMARY KEY,
+	source VARCHAR(50) NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	changes JSONB NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Hourly API usage per caller, endpoint, and status, upserted in batches
+CREATE TABLE IF NOT EXISTS api_usage_rollups (
+	bucket TIMESTAMP NOT NULL,
+	username VARCHAR(255) NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	route VARCHAR(255) NOT NULL,
+	status INTEGER NOT NULL,
+	calls BIGINT NOT NULL DEFAULT 0,
+	total_latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+	max_latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+	PRIMARY KEY (bucket, username, method, route, status)
+);
+CREATE INDEX IF NOT EXISTS idx_api_usage_rollups_username ON api_usage_rollups (username, bucket);
+
+-- Trigram similarity powers fuzzy duplicate detection; not every role may create extensions
+DO $$
+BEGIN
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+	RAISE WARNING 'Failed to enable pg_trgm, duplicate detection will only match identical names: %', SQLERRM;
+END $$;
+
+CREATE TABLE IF NOT EXISTS account_duplicate_candidates (
+	customer_id INTEGER NOT NULL,
+	keep_account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	merge_account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	score DOUBLE PRECISION NOT NULL,
+	name_similarity DOUBLE PRECISION NOT NULL,
+	created_apart_seconds DOUBLE PRECISION NOT NULL,
+	detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (keep_account_id, merge_account_id)
+);
+
+-- Webhook subscriptions; an empty events array subscribes to every event type
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id SERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	events TEXT[] NOT NULL DEFAULT '{}',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Outbox of events per endpoint, drained by the webhook dispatcher
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+	event_type VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	request_id VARCHAR(255),
+	traceparent VARCHAR(255),
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries (endpoint_id, created_at DESC);
+
+-- Contact fields flagged by the normalization job, replaced on each run
+CREATE TABLE IF NOT EXISTS contact_issues (
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+	field VARCHAR(50) NOT NULL,
+	value TEXT NOT NULL,
+	issue VARCHAR(50) NOT NULL,
+	detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (customer_id, field, issue)
+);
+
+-- System versioning (see VersionedTables). record_history closes the current
+-- version of a row and opens a new one. Versions use transaction time, so all
+-- changes in one transaction share a timestamp.
+CREATE OR REPLACE FUNCTION record_history() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		EXECUTE format('UPDATE %I SET valid_to = now() WHERE id = $1 AND valid_to IS NULL', TG_TABLE_NAME || '_history')
+			USING OLD.id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		EXECUTE format('INSERT INTO %I (id, data, valid_from) VALUES ($1, $2, now())', TG_TABLE_NAME || '_history')
+			USING NEW.id, to_jsonb(NEW);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Existing rows are backfilled once, when a history table is first created,
+-- with their last update as the start of the current version
+DO $$
+DECLARE
+	versioned TEXT;
+BEGIN
+	FOREACH versioned IN ARRAY ARRAY['customers', 'accounts'] LOOP
+		-- Look only in the current schema, so migrating a scratch schema (see
+		-- MigrateInSchema) doesn't find the live history tables
+		IF to_regclass(format('%I.%I', current_schema(), versioned || '_history')) IS NULL THEN
+			EXECUTE format('CREATE TABLE %I (
+				history_id BIGSERIAL PRIMARY KEY,
+				id INTEGER NOT NULL,
+				data JSONB NOT NULL,
+				valid_from TIMESTAMPTZ NOT NULL,
+				valid_to TIMESTAMPTZ
+			)', versioned || '_history');
+			EXECUTE format('CREATE INDEX %I ON %I (id, valid_from)', 'idx_' || versioned || '_history_id', versioned || '_history');
+			EXECUTE format('CREATE INDEX %I ON %I (valid_from)', 'idx_' || versioned || '_history_valid_from', versioned || '_history');
+			EXECUTE format('INSERT INTO %I (id, data, valid_from) SELECT id, to_jsonb(t), COALESCE(updated_at, created_at, now()) FROM %I t',
+				versioned || '_history', versioned);
+		END IF;
+		IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = format('%I.%I', current_schema(), versioned)::regclass AND tgname = versioned || '_record_history') THEN
+			EXECUTE format('CREATE TRIGGER %I AFTER INSERT OR UPDATE OR DELETE ON %I FOR EACH ROW EXECUTE FUNCTION record_history()',
+				versioned || '_record_history', versioned);
+		END IF;
+	END LOOP;
+END $$;
+
+-- Who saw unmasked PII, and of which records (see internal/pii)
+CREATE TABLE IF NOT EXISTS pii_access_log (
+	id BIGSERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	role VARCHAR(50) NOT NULL,
+	resource VARCHAR(50) NOT NULL,
+	record_ids INTEGER[] NOT NULL,
+	fields TEXT[] NOT NULL,
+	route VARCHAR(255) NOT NULL,
+	request_id VARCHAR(200),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_pii_access_log_created_at ON pii_access_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_pii_access_log_record_ids ON pii_access_log USING GIN (record_ids);
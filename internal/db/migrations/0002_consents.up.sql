@@ -0,0 +1,12 @@
+-- Which user accepted which version of which policy (see internal/consent)
+CREATE TABLE consents (
+	id BIGSERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	policy VARCHAR(50) NOT NULL,
+	version VARCHAR(50) NOT NULL,
+	ip_address VARCHAR(64),
+	user_agent TEXT,
+	accepted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (username, policy, version)
+);
+CREATE INDEX idx_consents_policy_version ON consents (policy, version);
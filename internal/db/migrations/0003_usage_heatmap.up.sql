@@ -0,0 +1,14 @@
+-- API calls per user by weekday (ISO, 1 = Monday) and hour in UTC over the
+-- last 28 days, for GET /api/analytics/heatmap. Refreshed by the
+-- analytics:heatmap job; the unique index allows REFRESH ... CONCURRENTLY.
+CREATE MATERIALIZED VIEW usage_heatmap AS
+SELECT username,
+	EXTRACT(ISODOW FROM bucket)::int AS weekday,
+	EXTRACT(HOUR FROM bucket)::int AS hour,
+	SUM(calls)::bigint AS calls,
+	COALESCE(SUM(calls) FILTER (WHERE status >= 500), 0)::bigint AS errors,
+	NOW() AS refreshed_at
+FROM api_usage_rollups
+WHERE bucket >= (NOW() AT TIME ZONE 'UTC') - INTERVAL '28 days'
+GROUP BY username, weekday, hour;
+CREATE UNIQUE INDEX idx_usage_heatmap ON usage_heatmap (username, weekday, hour);
@@ -0,0 +1,10 @@
+-- Completeness metrics from the last data quality run (see internal/jobs/quality.go)
+CREATE TABLE data_quality_metrics (
+	table_name VARCHAR(63) NOT NULL,
+	metric VARCHAR(63) NOT NULL,
+	description TEXT NOT NULL,
+	failing BIGINT NOT NULL,
+	total BIGINT NOT NULL,
+	measured_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (table_name, metric)
+);
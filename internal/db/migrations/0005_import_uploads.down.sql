@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS import_upload_parts;
+DROP TABLE IF EXISTS import_uploads;
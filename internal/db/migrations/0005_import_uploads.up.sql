@@ -0,0 +1,29 @@
+-- Resumable chunked uploads of CSV files for import (see internal/imports).
+-- Each upload is an S3 multipart upload; the id doubles as the resume token.
+CREATE TABLE import_uploads (
+	id VARCHAR(64) PRIMARY KEY,
+	filename VARCHAR(255) NOT NULL,
+	size BIGINT NOT NULL,
+	chunk_size BIGINT NOT NULL,
+	object_key TEXT NOT NULL,
+	multipart_id TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'uploading',
+	job_id VARCHAR(64),
+	imported BIGINT NOT NULL DEFAULT 0,
+	skipped BIGINT NOT NULL DEFAULT 0,
+	error TEXT,
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Chunks received so far, with the checksum each was validated against
+CREATE TABLE import_upload_parts (
+	upload_id VARCHAR(64) NOT NULL REFERENCES import_uploads(id) ON DELETE CASCADE,
+	part_number INTEGER NOT NULL,
+	size BIGINT NOT NULL,
+	sha256 CHAR(64) NOT NULL,
+	etag TEXT NOT NULL,
+	uploaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (upload_id, part_number)
+);
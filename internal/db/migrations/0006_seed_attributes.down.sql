@@ -0,0 +1,4 @@
+ALTER TABLE accounts DROP COLUMN IF EXISTS mrr_cents;
+ALTER TABLE customers DROP COLUMN IF EXISTS arr_band;
+ALTER TABLE customers DROP COLUMN IF EXISTS country;
+ALTER TABLE customers DROP COLUMN IF EXISTS industry;
@@ -0,0 +1,8 @@
+-- Firmographics and revenue for analytics demos (see SeedPerformance). They
+-- are NULL for customers created through the API.
+ALTER TABLE customers ADD COLUMN industry VARCHAR(100);
+ALTER TABLE customers ADD COLUMN country CHAR(2);
+ALTER TABLE customers ADD COLUMN arr_band VARCHAR(20);
+
+-- Monthly recurring revenue in cents
+ALTER TABLE accounts ADD COLUMN mrr_cents BIGINT NOT NULL DEFAULT 0 CHECK (mrr_cents >= 0);
@@ -0,0 +1,5 @@
+-- migrate: no-transaction
+-- Accounts of one customer, newest first (the customer_id filter and grouped
+-- lists), and the ON DELETE CASCADE from customers. Built concurrently so
+-- large tables stay writable.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_accounts_customer_id ON accounts (customer_id, created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+-- migrate: no-transaction
+-- Accounts with a status, newest first (the status filter and facets)
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_accounts_status ON accounts (status, created_at DESC, id DESC);
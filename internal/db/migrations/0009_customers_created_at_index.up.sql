@@ -0,0 +1,4 @@
+-- migrate: no-transaction
+-- Keyset pagination of the customer list, newest first. customers.email
+-- needs no index of its own: its UNIQUE constraint already has one.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_customers_created_at ON customers (created_at DESC, id DESC);
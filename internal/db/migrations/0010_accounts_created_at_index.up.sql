@@ -0,0 +1,3 @@
+-- migrate: no-transaction
+-- Keyset pagination of the unfiltered account list, newest first
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_accounts_created_at ON accounts (created_at DESC, id DESC);
@@ -0,0 +1,19 @@
+-- API tokens customers use to read their own data through /api/my. Only a
+-- SHA-256 of each token is kept; prefix is its first characters, shown in
+-- lists to tell tokens apart. Tokens scoped to an account see only that account.
+CREATE TABLE customer_api_tokens (
+	id SERIAL PRIMARY KEY,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+	account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP,
+	expires_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_customer_api_tokens_customer_id ON customer_api_tokens (customer_id);
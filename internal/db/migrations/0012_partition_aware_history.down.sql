@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION record_history() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		EXECUTE format('UPDATE %I SET valid_to = now() WHERE id = $1 AND valid_to IS NULL', TG_TABLE_NAME || '_history')
+			USING OLD.id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		EXECUTE format('INSERT INTO %I (id, data, valid_from) VALUES ($1, $2, now())', TG_TABLE_NAME || '_history')
+			USING NEW.id, to_jsonb(NEW);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
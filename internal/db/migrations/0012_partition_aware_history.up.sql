@@ -0,0 +1,23 @@
+-- Let record_history serve partitioned tables: their row triggers fire with
+-- TG_TABLE_NAME set to the partition, so the versioned table is passed as the
+-- trigger argument instead. Moving rows between partitions (see
+-- EnsureAccountPartitions) sets saas.moving_rows so the move isn't recorded
+-- as a delete and a new version.
+CREATE OR REPLACE FUNCTION record_history() RETURNS trigger AS $$
+DECLARE
+	history TEXT := COALESCE(TG_ARGV[0], TG_TABLE_NAME) || '_history';
+BEGIN
+	IF current_setting('saas.moving_rows', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		EXECUTE format('UPDATE %I SET valid_to = now() WHERE id = $1 AND valid_to IS NULL', history)
+			USING OLD.id;
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		EXECUTE format('INSERT INTO %I (id, data, valid_from) VALUES ($1, $2, now())', history)
+			USING NEW.id, to_jsonb(NEW);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS organization_invites;
+DROP TABLE IF EXISTS subscriptions;
+ALTER TABLE users DROP COLUMN IF EXISTS organization_role;
+ALTER TABLE users DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS organizations;
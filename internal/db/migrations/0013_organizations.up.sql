@@ -0,0 +1,46 @@
+-- Self-service signups: an organization (workspace) with an owner, a trial
+-- subscription, and a customer record for billing. The customer points at
+-- its organization rather than the other way round, so clearing customers
+-- with TRUNCATE ... CASCADE leaves organizations and their users alone.
+CREATE TABLE organizations (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE customers ADD COLUMN organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX idx_customers_organization_id ON customers (organization_id);
+
+-- organization_role is owner or member; users created before signups have neither
+ALTER TABLE users ADD COLUMN organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN organization_role VARCHAR(20);
+CREATE INDEX idx_users_organization_id ON users (organization_id);
+
+CREATE TABLE subscriptions (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	plan VARCHAR(50) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	trial_ends_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_subscriptions_organization_id ON subscriptions (organization_id);
+
+-- Invites to join an organization. Like customer API tokens, only a SHA-256
+-- of each invite token is kept.
+CREATE TABLE organization_invites (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	invited_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_at TIMESTAMP,
+	accepted_by VARCHAR(255),
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_organization_invites_organization_id ON organization_invites (organization_id);
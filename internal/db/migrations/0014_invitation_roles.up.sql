@@ -0,0 +1,2 @@
+-- The role an invitation grants when it is accepted: owner or member
+ALTER TABLE organization_invites ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member';
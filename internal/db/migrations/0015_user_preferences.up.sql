@@ -0,0 +1,11 @@
+-- Each user's defaults for the list endpoints, applied when a request leaves
+-- out the matching query parameter. fields maps a list (customers, accounts)
+-- to the fields to return.
+CREATE TABLE user_preferences (
+	username VARCHAR(255) PRIMARY KEY,
+	page_size INTEGER,
+	sort VARCHAR(50),
+	fields JSONB NOT NULL DEFAULT '{}',
+	timezone VARCHAR(64),
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Rows that were soft-deleted become live again
+ALTER TABLE accounts DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS deleted_at;
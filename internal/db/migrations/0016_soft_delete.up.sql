@@ -0,0 +1,6 @@
+-- Deleting a customer or account through the API sets deleted_at instead of
+-- removing the row, so it can be restored. Lists and lookups skip rows with
+-- deleted_at set. A customer's accounts are deleted with it, at the same
+-- time, which is how restoring the customer finds them again.
+ALTER TABLE customers ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE accounts ADD COLUMN deleted_at TIMESTAMP;
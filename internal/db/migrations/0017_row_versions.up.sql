@@ -0,0 +1,5 @@
+-- Optimistic concurrency control: every update of a customer's or account's
+-- fields increments version, and PUT requests must name the version they
+-- read, so a stale write is refused instead of overwriting a newer one
+ALTER TABLE customers ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE accounts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_accounts_uuid;
+ALTER TABLE accounts DROP COLUMN IF EXISTS uuid;
+DROP INDEX IF EXISTS idx_customers_uuid;
+ALTER TABLE customers DROP COLUMN IF EXISTS uuid;
+DROP FUNCTION IF EXISTS uuid_generate_v7(TIMESTAMPTZ);
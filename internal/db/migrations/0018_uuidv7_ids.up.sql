@@ -0,0 +1,40 @@
+-- Customers and accounts get a UUIDv7 next to their serial id. The API
+-- accepts either in paths and returns both, so clients can move to the UUID,
+-- which can't be guessed or counted, before the integer ids are retired.
+-- UUIDv7 starts with a millisecond timestamp, so UUIDs sort by creation time
+-- like the serial ids do, and rows inserted on different databases don't
+-- collide when they are merged or replicated.
+
+-- uuid_generate_v7 returns a UUIDv7 (RFC 9562) for ts: 48 bits of Unix
+-- milliseconds, then the version and variant over random bits taken from a
+-- version 4 UUID. Postgres 18 has uuidv7() built in; this works on older
+-- versions too.
+CREATE OR REPLACE FUNCTION uuid_generate_v7(ts TIMESTAMPTZ DEFAULT clock_timestamp()) RETURNS UUID AS $$
+	SELECT encode(
+		set_bit(set_bit(
+			overlay(uuid_send(gen_random_uuid())
+				PLACING substring(int8send(floor(extract(epoch FROM ts) * 1000)::BIGINT) FROM 3)
+				FROM 1 FOR 6),
+			52, 1), 53, 1),
+		'hex')::UUID
+$$ LANGUAGE sql VOLATILE;
+
+-- Existing rows get UUIDs for their created_at, so the UUIDs sort like the
+-- rows. The backfill isn't a change to record: saas.moving_rows keeps it out
+-- of history, and the UUIDs are added to the rows' recorded versions instead.
+SET LOCAL saas.moving_rows = 'on';
+
+ALTER TABLE customers ADD COLUMN uuid UUID;
+UPDATE customers SET uuid = uuid_generate_v7(created_at AT TIME ZONE 'UTC');
+UPDATE customers_history h SET data = h.data || jsonb_build_object('uuid', c.uuid) FROM customers c WHERE c.id = h.id;
+ALTER TABLE customers ALTER COLUMN uuid SET DEFAULT uuid_generate_v7(), ALTER COLUMN uuid SET NOT NULL;
+CREATE UNIQUE INDEX idx_customers_uuid ON customers (uuid);
+
+ALTER TABLE accounts ADD COLUMN uuid UUID;
+UPDATE accounts SET uuid = uuid_generate_v7(created_at AT TIME ZONE 'UTC');
+UPDATE accounts_history h SET data = h.data || jsonb_build_object('uuid', a.uuid) FROM accounts a WHERE a.id = h.id;
+ALTER TABLE accounts ALTER COLUMN uuid SET DEFAULT uuid_generate_v7(), ALTER COLUMN uuid SET NOT NULL;
+-- A unique index on a partitioned table must include the partition key
+CREATE UNIQUE INDEX idx_accounts_uuid ON accounts (uuid, created_at);
+
+SET LOCAL saas.moving_rows = 'off';
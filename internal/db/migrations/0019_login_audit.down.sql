@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_login_attempts_username;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS country;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS device_fingerprint;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,10 @@
+-- Logins record the client they came from: its user agent, a fingerprint of
+-- the device derived from the request headers, and the country when a proxy
+-- in front of the app reports it. A successful login from a device or country
+-- the user hasn't logged in from before notifies them.
+ALTER TABLE login_attempts ADD COLUMN user_agent TEXT;
+ALTER TABLE login_attempts ADD COLUMN device_fingerprint VARCHAR(64);
+ALTER TABLE login_attempts ADD COLUMN country VARCHAR(2);
+
+-- Recent logins per user, for /me/security and the new device check
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username ON login_attempts (username, created_at DESC);
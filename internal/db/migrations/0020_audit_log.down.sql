@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS accounts_record_audit ON accounts;
+DROP TRIGGER IF EXISTS customers_record_audit ON customers;
+DROP FUNCTION IF EXISTS record_audit();
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,56 @@
+-- Every insert, update, and delete of customers and accounts is recorded in
+-- audit_log with the row before and after the change, and the actor who made
+-- it. The app passes the actor, the user behind the request, in saas.actor;
+-- changes made outside a request, e.g. by scheduled jobs, are recorded as
+-- system. Moving rows between partitions (saas.moving_rows) changes nothing
+-- and isn't recorded, and neither are updates that leave a row as it was.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	entity VARCHAR(50) NOT NULL,
+	entity_id INTEGER NOT NULL,
+	action VARCHAR(10) NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	before JSONB,
+	after JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity, entity_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, id DESC);
+
+-- Like record_history, the audited table is passed as the trigger argument,
+-- since row triggers of a partitioned table fire with TG_TABLE_NAME set to
+-- the partition
+CREATE OR REPLACE FUNCTION record_audit() RETURNS trigger AS $$
+DECLARE
+	old_row JSONB;
+	new_row JSONB;
+BEGIN
+	IF current_setting('saas.moving_rows', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		old_row := to_jsonb(OLD);
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		new_row := to_jsonb(NEW);
+	END IF;
+	IF old_row = new_row THEN
+		RETURN NULL;
+	END IF;
+	INSERT INTO audit_log (entity, entity_id, action, actor, before, after)
+	VALUES (
+		COALESCE(TG_ARGV[0], TG_TABLE_NAME),
+		(COALESCE(new_row, old_row)->>'id')::INTEGER,
+		lower(TG_OP),
+		COALESCE(NULLIF(current_setting('saas.actor', true), ''), 'system'),
+		old_row,
+		new_row
+	);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customers_record_audit AFTER INSERT OR UPDATE OR DELETE ON customers
+	FOR EACH ROW EXECUTE FUNCTION record_audit('customers');
+CREATE TRIGGER accounts_record_audit AFTER INSERT OR UPDATE OR DELETE ON accounts
+	FOR EACH ROW EXECUTE FUNCTION record_audit('accounts');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS job_workers;
+DROP TABLE IF EXISTS queued_jobs;
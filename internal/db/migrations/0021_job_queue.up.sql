@@ -0,0 +1,40 @@
+-- A job queue in Postgres, for work that must outlive the request that asked
+-- for it without depending on Redis (see internal/jobs/queue.go). Workers
+-- claim due jobs with FOR UPDATE SKIP LOCKED, so any number of them can poll
+-- at once. A failed job is retried with backoff until max_attempts, then
+-- left as dead for an admin to inspect and retry.
+CREATE TABLE IF NOT EXISTS queued_jobs (
+	id BIGSERIAL PRIMARY KEY,
+	queue VARCHAR(50) NOT NULL DEFAULT 'default',
+	type VARCHAR(100) NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	-- pending, running, done, or dead
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL DEFAULT 5,
+	run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	worker_id VARCHAR(100),
+	last_error TEXT,
+	-- The request and user that enqueued the job, restored while it runs
+	request_id VARCHAR(200),
+	traceparent VARCHAR(55),
+	actor VARCHAR(255),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_queued_jobs_due ON queued_jobs (queue, run_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_queued_jobs_running ON queued_jobs (worker_id) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_queued_jobs_finished ON queued_jobs (status, finished_at) WHERE status IN ('done', 'dead');
+
+-- Running workers and the job types they handle. A worker updates
+-- last_seen_at every heartbeat; the jobs of one that stops are handed to
+-- the others.
+CREATE TABLE IF NOT EXISTS job_workers (
+	id VARCHAR(100) PRIMARY KEY,
+	queues TEXT[] NOT NULL,
+	types TEXT[] NOT NULL,
+	concurrency INTEGER NOT NULL,
+	started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS accounts_notify_change ON accounts;
+DROP TRIGGER IF EXISTS customers_notify_change ON customers;
+DROP FUNCTION IF EXISTS notify_change();
@@ -0,0 +1,37 @@
+-- Every committed insert, update, and delete of customers and accounts sends
+-- a notification on the saas_changes channel, which the app LISTENs on (see
+-- internal/changefeed). The payload only identifies the row and the change,
+-- since notifications are limited to 8000 bytes; subscribers read the row if
+-- they need it. Like record_audit, moving rows between partitions and updates
+-- that leave a row as it was don't notify.
+CREATE OR REPLACE FUNCTION notify_change() RETURNS trigger AS $$
+DECLARE
+	row_data JSONB;
+BEGIN
+	IF current_setting('saas.moving_rows', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		row_data := to_jsonb(OLD);
+	ELSE
+		row_data := to_jsonb(NEW);
+	END IF;
+	IF TG_OP = 'UPDATE' AND to_jsonb(OLD) = row_data THEN
+		RETURN NULL;
+	END IF;
+	PERFORM pg_notify('saas_changes', jsonb_build_object(
+		'entity', COALESCE(TG_ARGV[0], TG_TABLE_NAME),
+		'id', row_data->'id',
+		'uuid', row_data->'uuid',
+		'action', lower(TG_OP),
+		'version', row_data->'version',
+		'actor', COALESCE(NULLIF(current_setting('saas.actor', true), ''), 'system')
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER customers_notify_change AFTER INSERT OR UPDATE OR DELETE ON customers
+	FOR EACH ROW EXECUTE FUNCTION notify_change('customers');
+CREATE TRIGGER accounts_notify_change AFTER INSERT OR UPDATE OR DELETE ON accounts
+	FOR EACH ROW EXECUTE FUNCTION notify_change('accounts');
@@ -0,0 +1,22 @@
+-- Refresh tokens exchange for new short-lived access tokens at
+-- POST /auth/refresh. Every exchange rotates the token: the one presented is
+-- marked used and a new one is issued in the same family, the chain of tokens
+-- descended from one login. A used token presented again means it was copied,
+-- so its whole family is revoked, signing out both the thief and the user.
+-- Only a SHA-256 of each token is stored.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	family_id UUID NOT NULL,
+	-- The token this one was rotated from
+	parent_id BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+	ip_address VARCHAR(64),
+	user_agent TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens (user_id, expires_at);
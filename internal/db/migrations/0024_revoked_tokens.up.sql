@@ -0,0 +1,10 @@
+-- JWTs revoked before they expire, such as those of users who logged out,
+-- by their jti claim. The auth middleware rejects them. A row is only needed
+-- until its token expires, and expired ones are deleted as others are added.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti VARCHAR(64) PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires ON revoked_tokens (expires_at);
@@ -0,0 +1,19 @@
+-- API keys scripts and CI use in place of a JWT, sent in X-API-Key. A key
+-- acts as the user who created it, limited to its scopes (read, write,
+-- admin). Only a SHA-256 of each key is kept; prefix is its first
+-- characters, shown in lists to tell keys apart.
+CREATE TABLE api_keys (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP,
+	last_used_ip VARCHAR(45),
+	expires_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,18 @@
+-- Monthly customer statements, compiled by the statements:generate job (see
+-- internal/jobs/statements.go). period is the first day of the month
+-- covered. The document is kept in the import bucket at object_key when one
+-- is configured, and in document otherwise; the totals are copied out so
+-- statements can be listed without reading their documents.
+CREATE TABLE IF NOT EXISTS customer_statements (
+	id SERIAL PRIMARY KEY,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+	period DATE NOT NULL,
+	account_changes INTEGER NOT NULL,
+	api_calls BIGINT NOT NULL,
+	invoice_totals JSONB NOT NULL DEFAULT '{}',
+	object_key TEXT,
+	document JSONB,
+	generated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (customer_id, period),
+	CHECK (object_key IS NOT NULL OR document IS NOT NULL)
+);
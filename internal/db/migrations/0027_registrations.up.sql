@@ -0,0 +1,21 @@
+-- Every POST /auth/register, so registrations can be counted per IP address
+-- (see internal/abuse). status is created for registrations that created
+-- their user right away, and pending for those flagged for review until an
+-- admin approves or rejects them. A pending registration holds the password
+-- hash its user is created with on approval; it is cleared once reviewed.
+CREATE TABLE IF NOT EXISTS registrations (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	email VARCHAR(255),
+	ip_address VARCHAR(64),
+	user_agent TEXT,
+	status VARCHAR(20) NOT NULL,
+	reasons TEXT[] NOT NULL DEFAULT '{}',
+	password_hash VARCHAR(255),
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	reviewed_by VARCHAR(255),
+	reviewed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_registrations_ip_address ON registrations (ip_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_registrations_pending ON registrations (created_at) WHERE status = 'pending';
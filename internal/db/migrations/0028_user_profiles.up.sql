@@ -0,0 +1,7 @@
+-- Profile fields users manage themselves through PUT /me. email starts out as
+-- the address given to POST /auth/register, if any. password_changed_at is
+-- set by POST /me/password and stays NULL for passwords set at registration.
+ALTER TABLE users ADD COLUMN display_name VARCHAR(255);
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sla_breaches;
+DROP TABLE IF EXISTS sla_rules;
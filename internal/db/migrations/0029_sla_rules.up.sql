@@ -0,0 +1,29 @@
+-- Account lifecycle SLAs: an account in from_status must move to to_status
+-- within within_days, e.g. pending accounts activate within 7 days. The
+-- scheduler records every account still in from_status past its deadline as
+-- a breach, once per time it entered the status, and resolves the breach when
+-- the account leaves it (see internal/jobs/sla.go). account_id has no foreign
+-- key, since accounts may be partitioned (see db.PartitionedSchema); breaches
+-- of deleted accounts are resolved like any other.
+CREATE TABLE IF NOT EXISTS sla_rules (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	from_status VARCHAR(50) NOT NULL,
+	to_status VARCHAR(50) NOT NULL,
+	within_days INTEGER NOT NULL CHECK (within_days > 0),
+	created_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CHECK (from_status <> to_status)
+);
+
+CREATE TABLE IF NOT EXISTS sla_breaches (
+	id SERIAL PRIMARY KEY,
+	rule_id INTEGER NOT NULL REFERENCES sla_rules(id) ON DELETE CASCADE,
+	account_id INTEGER NOT NULL,
+	entered_at TIMESTAMPTZ NOT NULL,
+	due_at TIMESTAMPTZ NOT NULL,
+	detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP,
+	UNIQUE (rule_id, account_id, entered_at)
+);
+CREATE INDEX IF NOT EXISTS idx_sla_breaches_open ON sla_breaches (rule_id) WHERE resolved_at IS NULL;
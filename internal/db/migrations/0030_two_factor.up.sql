@@ -0,0 +1,19 @@
+-- TOTP two-factor authentication (RFC 6238). totp_secret is sealed with
+-- AES-256-GCM under a key derived from TOTP_ENCRYPTION_KEY. POST /me/2fa/setup
+-- sets it, and logins only require a code once POST /me/2fa/verify has
+-- confirmed it and set totp_enabled_at. totp_last_step is the time step of
+-- the last code accepted, so no code is accepted twice.
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT;
+
+-- Single-use backup codes, for logging in without the authenticator. Only a
+-- SHA-256 of each code is stored.
+CREATE TABLE IF NOT EXISTS user_backup_codes (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash VARCHAR(64) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	used_at TIMESTAMP,
+	UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,16 @@
+-- Identities users sign in with through an OpenID Connect provider, keyed by
+-- the provider's issuer and the user's subject there. A user is provisioned
+-- on their first OIDC login, or linked to an existing user with the same
+-- verified email. email is the one the provider last reported.
+CREATE TABLE IF NOT EXISTS user_identities (
+	id BIGSERIAL PRIMARY KEY,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email VARCHAR(255),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,3 @@
+-- CIDR ranges an API key can be used from, in canonical form (see
+-- auth.NormalizeCIDRs). An empty list allows any address.
+ALTER TABLE api_keys ADD COLUMN allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+UPDATE users SET role = 'user' WHERE role = 'tenant';
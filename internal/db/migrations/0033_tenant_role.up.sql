@@ -0,0 +1,4 @@
+-- Users who signed up or joined an organization by invitation are tenants:
+-- they only see their organization's customers and accounts, and nothing once
+-- they leave it. Users with the default role, user, are staff.
+UPDATE users SET role = 'tenant' WHERE organization_id IS NOT NULL AND role = 'user';
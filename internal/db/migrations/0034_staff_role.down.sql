@@ -0,0 +1,2 @@
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+UPDATE users SET role = 'user' WHERE role = 'tenant' AND organization_id IS NULL;
@@ -0,0 +1,5 @@
+-- Staff, who see every organization's customers and accounts, are only the
+-- users given the staff or admin role by hand. Everyone else is a tenant, so
+-- the old default role, user, goes, and new users are tenants.
+UPDATE users SET role = 'tenant' WHERE role = 'user';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'tenant';
@@ -0,0 +1,15 @@
+-- Rows that only differ by organization or token are merged back together
+CREATE TEMP TABLE api_usage_rollups_merged AS
+SELECT bucket, username, method, route, status,
+	SUM(calls) AS calls, SUM(total_latency_ms) AS total_latency_ms, MAX(max_latency_ms) AS max_latency_ms
+FROM api_usage_rollups
+GROUP BY bucket, username, method, route, status;
+DELETE FROM api_usage_rollups;
+DROP INDEX IF EXISTS idx_api_usage_rollups_organization;
+ALTER TABLE api_usage_rollups DROP CONSTRAINT api_usage_rollups_pkey;
+ALTER TABLE api_usage_rollups DROP COLUMN token_id;
+ALTER TABLE api_usage_rollups DROP COLUMN organization_id;
+INSERT INTO api_usage_rollups (bucket, username, method, route, status, calls, total_latency_ms, max_latency_ms)
+SELECT bucket, username, method, route, status, calls, total_latency_ms, max_latency_ms FROM api_usage_rollups_merged;
+ALTER TABLE api_usage_rollups ADD PRIMARY KEY (bucket, username, method, route, status);
+DROP TABLE api_usage_rollups_merged;
@@ -0,0 +1,8 @@
+-- API usage is also broken down by the caller's organization and the token
+-- (API key or customer token) used, so organizations can see their own
+-- consumption. 0 stands for no organization and for JWTs.
+ALTER TABLE api_usage_rollups ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_usage_rollups ADD COLUMN token_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_usage_rollups DROP CONSTRAINT api_usage_rollups_pkey;
+ALTER TABLE api_usage_rollups ADD PRIMARY KEY (bucket, username, organization_id, token_id, method, route, status);
+CREATE INDEX IF NOT EXISTS idx_api_usage_rollups_organization ON api_usage_rollups (organization_id, bucket);
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// CreateNotifications stores a notification for each of the given usernames.
+// Usernames that don't belong to a registered user are silently ignored.
+func CreateNotifications(ctx context.Context, kind, message string, usernames ...string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	_, err := PrimaryDB.ExecContext(ctx,
+		"INSERT INTO notifications (username, kind, message) SELECT username, $1, $2 FROM users WHERE username = ANY($3)",
+		kind, message, usernames,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
+	return nil
+}
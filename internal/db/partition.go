@@ -0,0 +1,390 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// With PARTITIONED_SCHEMA=true, accounts is range-partitioned by the month of
+// created_at, for the performance showcase: list queries over recent accounts
+// only touch recent partitions, and old months can be detached or dropped
+// whole. EnsurePartitionedAccounts converts an existing table at startup.
+//
+// A partitioned table's primary key must include its partition key, so the
+// key becomes (id, created_at) and foreign keys can no longer reference
+// accounts(id) alone. The conversion replaces each ON DELETE CASCADE foreign
+// key to accounts with triggers that delete (and truncate) the dependent rows,
+// and references stay unique by construction rather than by constraint.
+// Converting back is not supported; restore the table from a backup instead.
+
+// partitionMonthsAhead is how many months of partitions are kept ready past
+// the current one
+const partitionMonthsAhead = 3
+
+// defaultAccountsPartition catches rows outside every monthly partition, e.g.
+// when partitions weren't created ahead in time. Creating the partition for
+// their month moves them out of it.
+const defaultAccountsPartition = "accounts_default"
+
+// PartitionedSchema reads PARTITIONED_SCHEMA, whether accounts is partitioned
+// by month (default false)
+func PartitionedSchema() bool {
+	value := os.Getenv("PARTITIONED_SCHEMA")
+	if value == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for PARTITIONED_SCHEMA (%s), using default false", value)
+		return false
+	}
+	return enabled
+}
+
+// accountsPartitioned reports whether accounts is a partitioned table
+func accountsPartitioned(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var partitioned bool
+	err := conn.QueryRowContext(ctx, "SELECT relkind = 'p' FROM pg_class WHERE oid = to_regclass('accounts')").Scan(&partitioned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return partitioned, err
+}
+
+// EnsurePartitionedAccounts converts accounts to a partitioned table if it
+// isn't one yet, then creates the partitions for the coming months. It holds
+// the migration lock, so dynos starting together don't race.
+func EnsurePartitionedAccounts(ctx context.Context) error {
+	return withMigrationLock(ctx, func(conn *sql.Conn) error {
+		partitioned, err := accountsPartitioned(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("failed to check whether accounts is partitioned: %w", err)
+		}
+		if !partitioned {
+			if err := partitionAccounts(ctx, conn); err != nil {
+				return fmt.Errorf("failed to partition accounts: %w", err)
+			}
+		}
+		now := time.Now().UTC()
+		return ensureAccountPartitions(ctx, conn, now, now.AddDate(0, partitionMonthsAhead, 0))
+	})
+}
+
+// MaintainAccountPartitions creates the partitions for the current and the
+// next partitionMonthsAhead months. It does nothing unless accounts is
+// partitioned.
+func MaintainAccountPartitions(ctx context.Context) error {
+	now := time.Now().UTC()
+	return EnsureAccountPartitions(ctx, now, now.AddDate(0, partitionMonthsAhead, 0))
+}
+
+// EnsureAccountPartitions creates the missing monthly partitions of accounts
+// covering from through to, moving rows of those months out of the default
+// partition. It does nothing unless accounts is partitioned.
+func EnsureAccountPartitions(ctx context.Context, from, to time.Time) error {
+	return withMigrationLock(ctx, func(conn *sql.Conn) error {
+		partitioned, err := accountsPartitioned(ctx, conn)
+		if err != nil {
+			return fmt.Errorf("failed to check whether accounts is partitioned: %w", err)
+		}
+		if !partitioned {
+			return nil
+		}
+		return ensureAccountPartitions(ctx, conn, from, to)
+	})
+}
+
+func ensureAccountPartitions(ctx context.Context, conn *sql.Conn, from, to time.Time) error {
+	rows, err := conn.QueryContext(ctx, "SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = to_regclass('accounts')")
+	if err != nil {
+		return fmt.Errorf("failed to list account partitions: %w", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan account partition: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list account partitions: %w", err)
+	}
+
+	created := 0
+	for _, month := range partitionMonths(from, to) {
+		if existing[accountPartitionName(month)] {
+			continue
+		}
+		if err := createAccountPartition(ctx, conn, month); err != nil {
+			return err
+		}
+		created++
+	}
+	if created > 0 {
+		log.Printf("Created %d account partitions", created)
+	}
+	return nil
+}
+
+// createAccountPartition creates the partition for month. Rows of that month
+// already in the default partition are moved into it first, since attaching
+// a partition fails while the default one holds rows that belong to it.
+func createAccountPartition(ctx context.Context, conn *sql.Conn, month time.Time) error {
+	name := accountPartitionName(month)
+	bounds := partitionBounds(month)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stranded bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT to_regclass($1) IS NOT NULL AND EXISTS (SELECT 1 FROM accounts WHERE created_at >= $2 AND created_at < $3 AND tableoid = to_regclass($1))",
+		defaultAccountsPartition, month, month.AddDate(0, 1, 0),
+	).Scan(&stranded)
+	if err != nil {
+		return fmt.Errorf("failed to check the default partition for %s: %w", name, err)
+	}
+
+	if !stranded {
+		if _, err := tx.ExecContext(ctx, "CREATE TABLE "+pgx.Identifier{name}.Sanitize()+" PARTITION OF accounts "+bounds); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+		return tx.Commit()
+	}
+
+	// The move deletes from the default partition and inserts into a table that
+	// isn't attached yet; saas.moving_rows keeps the row triggers from
+	// recording it as deletes
+	statements := []string{
+		"SET LOCAL saas.moving_rows = 'on'",
+		"CREATE TABLE " + pgx.Identifier{name}.Sanitize() + " (LIKE accounts INCLUDING DEFAULTS INCLUDING CONSTRAINTS)",
+		fmt.Sprintf("WITH moved AS (DELETE FROM %s WHERE created_at >= '%s' AND created_at < '%s' RETURNING *) INSERT INTO %s SELECT * FROM moved",
+			pgx.Identifier{defaultAccountsPartition}.Sanitize(), partitionTimestamp(month), partitionTimestamp(month.AddDate(0, 1, 0)), pgx.Identifier{name}.Sanitize()),
+		"ALTER TABLE accounts ATTACH PARTITION " + pgx.Identifier{name}.Sanitize() + " " + bounds,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create partition %s from the default partition: %w", name, err)
+		}
+	}
+	log.Printf("Moved rows from %s into new partition %s", defaultAccountsPartition, name)
+	return tx.Commit()
+}
+
+// accountReference is a single-column foreign key to accounts(id)
+type accountReference struct {
+	Table      string
+	Column     string
+	Constraint string
+}
+
+// partitionAccounts replaces accounts with a partitioned copy in one
+// transaction, blocking reads and writes of accounts while it runs
+func partitionAccounts(ctx context.Context, conn *sql.Conn) error {
+	log.Println("Partitioning accounts by month of created_at...")
+	started := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE accounts IN ACCESS EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	references, err := accountReferences(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, reference := range references {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE "+pgx.Identifier{reference.Table}.Sanitize()+" DROP CONSTRAINT "+pgx.Identifier{reference.Constraint}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to drop foreign key %s: %w", reference.Constraint, err)
+		}
+	}
+
+	var sequence sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT pg_get_serial_sequence('accounts', 'id')").Scan(&sequence); err != nil {
+		return fmt.Errorf("failed to find the accounts id sequence: %w", err)
+	}
+	var oldest sql.NullTime
+	if err := tx.QueryRowContext(ctx, "SELECT MIN(created_at) FROM accounts").Scan(&oldest); err != nil {
+		return fmt.Errorf("failed to find the oldest account: %w", err)
+	}
+	now := time.Now().UTC()
+	from := now
+	if oldest.Valid && oldest.Time.Before(now) {
+		from = oldest.Time
+	}
+
+	statements := []string{
+		// Rows are copied, not changed, so none of this is recorded as history
+		"SET LOCAL saas.moving_rows = 'on'",
+		"UPDATE accounts SET created_at = COALESCE(updated_at, now()) WHERE created_at IS NULL",
+		"ALTER TABLE accounts RENAME TO accounts_unpartitioned",
+		"CREATE TABLE accounts (LIKE accounts_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY RANGE (created_at)",
+		"ALTER TABLE accounts ALTER COLUMN created_at SET NOT NULL",
+		"ALTER TABLE accounts ADD CONSTRAINT accounts_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE",
+		"CREATE TABLE " + pgx.Identifier{defaultAccountsPartition}.Sanitize() + " PARTITION OF accounts DEFAULT",
+	}
+	for _, month := range partitionMonths(from, now.AddDate(0, partitionMonthsAhead, 0)) {
+		statements = append(statements, "CREATE TABLE "+pgx.Identifier{accountPartitionName(month)}.Sanitize()+" PARTITION OF accounts "+partitionBounds(month))
+	}
+	statements = append(statements, "INSERT INTO accounts SELECT * FROM accounts_unpartitioned")
+	if sequence.Valid {
+		statements = append(statements, "ALTER SEQUENCE "+sequence.String+" OWNED BY accounts.id")
+	}
+	statements = append(statements,
+		// Dropping the old table frees its index names; building indexes after
+		// the copy is also faster than maintaining them during it
+		"DROP TABLE accounts_unpartitioned",
+		"ALTER TABLE accounts ADD PRIMARY KEY (id, created_at)",
+		"CREATE INDEX idx_accounts_reference ON accounts (reference)",
+		"CREATE INDEX idx_accounts_customer_id ON accounts (customer_id, created_at DESC, id DESC)",
+		"CREATE INDEX idx_accounts_status ON accounts (status, created_at DESC, id DESC)",
+		"CREATE INDEX idx_accounts_created_at ON accounts (created_at DESC, id DESC)",
+		"CREATE UNIQUE INDEX idx_accounts_uuid ON accounts (uuid, created_at)",
+		"CREATE TRIGGER accounts_record_history AFTER INSERT OR UPDATE OR DELETE ON accounts FOR EACH ROW EXECUTE FUNCTION record_history('accounts')",
+		"CREATE TRIGGER accounts_record_audit AFTER INSERT OR UPDATE OR DELETE ON accounts FOR EACH ROW EXECUTE FUNCTION record_audit('accounts')",
+		"CREATE TRIGGER accounts_notify_change AFTER INSERT OR UPDATE OR DELETE ON accounts FOR EACH ROW EXECUTE FUNCTION notify_change('accounts')",
+	)
+	if len(references) > 0 {
+		statements = append(statements, accountDependentsSQL(references)...)
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s: %w", strings.SplitN(statement, "\n", 2)[0], err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Partitioned accounts in %v", time.Since(started).Round(time.Millisecond))
+	if len(references) > 0 {
+		log.Printf("Foreign keys to accounts from %s were replaced by triggers", strings.Join(referencingTables(references), ", "))
+	}
+	return nil
+}
+
+// accountReferences lists the foreign keys to accounts(id). Each must be a
+// single-column ON DELETE CASCADE key, the only kind the triggers replace.
+func accountReferences(ctx context.Context, tx *sql.Tx) ([]accountReference, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.relname, a.attname, con.conname, con.confdeltype, cardinality(con.conkey)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1]
+		WHERE con.contype = 'f' AND con.confrelid = to_regclass('accounts') AND con.conrelid <> con.confrelid
+		ORDER BY c.relname, con.conname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys to accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var references []accountReference
+	for rows.Next() {
+		var reference accountReference
+		var onDelete string
+		var columns int
+		if err := rows.Scan(&reference.Table, &reference.Column, &reference.Constraint, &onDelete, &columns); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		if onDelete != "c" || columns != 1 {
+			return nil, fmt.Errorf("foreign key %s on %s is not a single-column ON DELETE CASCADE key, so it can't be replaced by a trigger", reference.Constraint, reference.Table)
+		}
+		references = append(references, reference)
+	}
+	return references, rows.Err()
+}
+
+// accountDependentsSQL creates the triggers that take over from the foreign
+// keys in references: deleting an account deletes the rows referencing it, and
+// truncating accounts truncates their tables, as TRUNCATE ... CASCADE did
+func accountDependentsSQL(references []accountReference) []string {
+	var deletes strings.Builder
+	for _, reference := range references {
+		fmt.Fprintf(&deletes, "\tDELETE FROM %s WHERE %s = OLD.id;\n", pgx.Identifier{reference.Table}.Sanitize(), pgx.Identifier{reference.Column}.Sanitize())
+	}
+	var tables []string
+	for _, table := range referencingTables(references) {
+		tables = append(tables, pgx.Identifier{table}.Sanitize())
+	}
+
+	return []string{
+		`CREATE OR REPLACE FUNCTION delete_account_dependents() RETURNS trigger AS $$
+BEGIN
+	IF current_setting('saas.moving_rows', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+` + deletes.String() + `	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION truncate_account_dependents() RETURNS trigger AS $$
+BEGIN
+	TRUNCATE TABLE ` + strings.Join(tables, ", ") + `;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`,
+		"CREATE TRIGGER accounts_delete_dependents AFTER DELETE ON accounts FOR EACH ROW EXECUTE FUNCTION delete_account_dependents()",
+		"CREATE TRIGGER accounts_truncate_dependents AFTER TRUNCATE ON accounts FOR EACH STATEMENT EXECUTE FUNCTION truncate_account_dependents()",
+	}
+}
+
+// referencingTables returns the distinct tables in references, in order
+func referencingTables(references []accountReference) []string {
+	var tables []string
+	seen := map[string]bool{}
+	for _, reference := range references {
+		if !seen[reference.Table] {
+			seen[reference.Table] = true
+			tables = append(tables, reference.Table)
+		}
+	}
+	return tables
+}
+
+// partitionMonths returns the first instant (UTC) of every month from the
+// month of from through the month of to
+func partitionMonths(from, to time.Time) []time.Time {
+	from, to = from.UTC(), to.UTC()
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var months []time.Time
+	for !month.After(last) {
+		months = append(months, month)
+		month = month.AddDate(0, 1, 0)
+	}
+	return months
+}
+
+// accountPartitionName names the partition for month, e.g. accounts_y2025m01
+func accountPartitionName(month time.Time) string {
+	return fmt.Sprintf("accounts_y%04dm%02d", month.Year(), int(month.Month()))
+}
+
+// partitionBounds is the FOR VALUES clause of the partition for month
+func partitionBounds(month time.Time) string {
+	return fmt.Sprintf("FOR VALUES FROM ('%s') TO ('%s')", partitionTimestamp(month), partitionTimestamp(month.AddDate(0, 1, 0)))
+}
+
+// partitionTimestamp formats a bound for created_at, a timestamp without time
+// zone holding UTC
+func partitionTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartitionMonths(t *testing.T) {
+	from := time.Date(2024, time.November, 30, 21, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	to := time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	months := partitionMonths(from, to)
+	var names []string
+	for _, month := range months {
+		names = append(names, accountPartitionName(month))
+	}
+	// from is already in December in UTC
+	want := "accounts_y2024m12,accounts_y2025m01,accounts_y2025m02"
+	if strings.Join(names, ",") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(names, ","))
+	}
+
+	if months := partitionMonths(to, from); len(months) != 0 {
+		t.Errorf("Expected no months when to is before from, got %v", months)
+	}
+}
+
+func TestPartitionBounds(t *testing.T) {
+	bounds := partitionBounds(time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC))
+	want := "FOR VALUES FROM ('2024-12-01 00:00:00') TO ('2025-01-01 00:00:00')"
+	if bounds != want {
+		t.Errorf("Expected %s, got %s", want, bounds)
+	}
+}
+
+func TestAccountDependentsSQL(t *testing.T) {
+	statements := accountDependentsSQL([]accountReference{
+		{Table: "account_duplicate_candidates", Column: "keep_account_id", Constraint: "account_duplicate_candidates_keep_account_id_fkey"},
+		{Table: "account_duplicate_candidates", Column: "merge_account_id", Constraint: "account_duplicate_candidates_merge_account_id_fkey"},
+		{Table: "account_notes", Column: "account_id", Constraint: "account_notes_account_id_fkey"},
+	})
+	if len(statements) != 4 {
+		t.Fatalf("Expected 2 functions and 2 triggers, got %d statements", len(statements))
+	}
+
+	for _, want := range []string{
+		`DELETE FROM "account_duplicate_candidates" WHERE "keep_account_id" = OLD.id;`,
+		`DELETE FROM "account_duplicate_candidates" WHERE "merge_account_id" = OLD.id;`,
+		`DELETE FROM "account_notes" WHERE "account_id" = OLD.id;`,
+		"saas.moving_rows",
+	} {
+		if !strings.Contains(statements[0], want) {
+			t.Errorf("Expected the delete function to contain %s, got:\n%s", want, statements[0])
+		}
+	}
+	if !strings.Contains(statements[1], `TRUNCATE TABLE "account_duplicate_candidates", "account_notes";`) {
+		t.Errorf("Expected each table truncated once, got:\n%s", statements[1])
+	}
+}
+
+func TestPartitionedSchema(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "true": true, "1": true, "false": false, "maybe": false} {
+		t.Setenv("PARTITIONED_SCHEMA", value)
+		if got := PartitionedSchema(); got != want {
+			t.Errorf("PARTITIONED_SCHEMA=%q: expected %v, got %v", value, want, got)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"saas-go-app/internal/chaos"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SendBatch runs every query queued on batch against the primary in one round
+// trip, using pgx's pipeline mode. The queries run in a single implicit
+// transaction: if one fails, none of them take effect. Use Queue(...).Exec,
+// Query, or QueryRow callbacks on the batch to read results; SendBatch
+// returns the first error.
+//
+//	batch := &pgx.Batch{}
+//	batch.Queue("DELETE FROM data_quality_metrics")
+//	for _, metric := range metrics {
+//		batch.Queue("INSERT INTO data_quality_metrics ...", metric.Table, ...)
+//	}
+//	err := db.SendBatch(ctx, batch)
+func SendBatch(ctx context.Context, batch *pgx.Batch) error {
+	if err := chaos.DB(ctx); err != nil {
+		return err
+	}
+	for _, query := range batch.QueuedQueries {
+		query.SQL = annotate(ctx, query.SQL)
+	}
+	return PrimaryPgx.SendBatch(ctx, batch).Close()
+}
+
+// CopyFrom loads rows into table on the primary with COPY FROM, returning the
+// number of rows copied. It is far faster than INSERT for bulk loads but
+// fails as a whole on the first bad row. Rows of audited tables are recorded
+// as changed by the actor carried by ctx.
+func CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	if err := chaos.DB(ctx); err != nil {
+		return 0, err
+	}
+	var copied int64
+	err := pgx.BeginFunc(ctx, PrimaryPgx, func(tx pgx.Tx) error {
+		if err := setLocalActor(ctx, tx); err != nil {
+			return err
+		}
+		var err error
+		copied, err = tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	return copied, err
+}
+
+// typeMaps holds pgtype maps for Array; a map caches scan plans and isn't
+// safe for concurrent use
+var typeMaps = sync.Pool{New: func() any { return pgtype.NewMap() }}
+
+type arrayScanner struct {
+	dest any
+}
+
+func (a arrayScanner) Scan(src any) error {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	return m.SQLScanner(a.dest).Scan(src)
+}
+
+// Array scans a Postgres array column into dest, a pointer to a slice, e.g.
+// rows.Scan(&note.ID, db.Array(&note.Mentions)). Slices can be passed as
+// query arguments directly.
+func Array(dest any) sql.Scanner {
+	return arrayScanner{dest: dest}
+}
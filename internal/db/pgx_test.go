@@ -0,0 +1,21 @@
+package db
+
+import "testing"
+
+func TestArray(t *testing.T) {
+	var mentions []string
+	if err := Array(&mentions).Scan("{alice,\"bob smith\"}"); err != nil {
+		t.Fatalf("Failed to scan text array: %v", err)
+	}
+	if len(mentions) != 2 || mentions[1] != "bob smith" {
+		t.Errorf("Expected [alice, bob smith], got %q", mentions)
+	}
+
+	var ids []int64
+	if err := Array(&ids).Scan([]byte("{1,2,3}")); err != nil || len(ids) != 3 || ids[2] != 3 {
+		t.Errorf("Expected [1 2 3], got %v (%v)", ids, err)
+	}
+	if err := Array(&ids).Scan(nil); err != nil || ids != nil {
+		t.Errorf("Expected NULL to scan as nil, got %v (%v)", ids, err)
+	}
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolConfig sizes a connection pool. Without limits, a pool opens
+// connections under load until Postgres refuses them.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// PoolConfigFromEnv reads DB_MAX_OPEN_CONNS (default 20), DB_MAX_IDLE_CONNS
+// (default 2, capped at the open limit), DB_CONN_MAX_LIFETIME (default 30m),
+// and DB_CONN_MAX_IDLE_TIME (default 5m). A limit of 0 removes it. The values
+// apply to each pool, so keep DB_MAX_OPEN_CONNS times the number of pools and
+// dynos under the plan's connection limit.
+func PoolConfigFromEnv() PoolConfig {
+	config := PoolConfig{
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 20),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 2),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	}
+	if config.MaxOpenConns < 0 {
+		log.Printf("Warning: Invalid value for DB_MAX_OPEN_CONNS (%d), using default 20", config.MaxOpenConns)
+		config.MaxOpenConns = 20
+	}
+	if config.MaxIdleConns < 0 {
+		log.Printf("Warning: Invalid value for DB_MAX_IDLE_CONNS (%d), using default 2", config.MaxIdleConns)
+		config.MaxIdleConns = 2
+	}
+	if config.MaxOpenConns > 0 && config.MaxIdleConns > config.MaxOpenConns {
+		config.MaxIdleConns = config.MaxOpenConns
+	}
+	return config
+}
+
+// forever stands in for "no limit" where pgxpool has no such setting
+const forever = 100 * 365 * 24 * time.Hour
+
+// configurePool applies PoolConfigFromEnv to a pgxpool config and logs the
+// effective values. pgxpool keeps idle connections until they pass the idle
+// time, so DB_MAX_IDLE_CONNS becomes the number kept open regardless
+// (MinConns); those are opened when the pool starts.
+func configurePool(name string, config *pgxpool.Config) {
+	pool := PoolConfigFromEnv()
+	config.MaxConns = math.MaxInt32
+	if pool.MaxOpenConns > 0 {
+		config.MaxConns = int32(pool.MaxOpenConns)
+	}
+	config.MinConns = int32(pool.MaxIdleConns)
+	config.MaxConnLifetime = forever
+	if pool.ConnMaxLifetime > 0 {
+		config.MaxConnLifetime = pool.ConnMaxLifetime
+	}
+	config.MaxConnIdleTime = forever
+	if pool.ConnMaxIdleTime > 0 {
+		config.MaxConnIdleTime = pool.ConnMaxIdleTime
+	}
+	log.Printf("%s pool: max open %d, max idle %d, max lifetime %v, max idle time %v",
+		name, pool.MaxOpenConns, pool.MaxIdleConns, pool.ConnMaxLifetime, pool.ConnMaxIdleTime)
+}
+
+// openPool creates a pgxpool for databaseURL and a *sql.DB drawing its
+// connections from it. The pool doesn't connect until it is first used
+// (apart from its MinConns, which it opens in the background).
+//
+// Queries are sent as unnamed prepared statements, described and executed
+// in one round trip, as lib/pq did. Request tracing prefixes every query with
+// a unique comment, which would make a statement cache useless; the hot
+// queries are prepared explicitly instead (see Prepared).
+func openPool(name, databaseURL string) (*pgxpool.Pool, *sql.DB, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid connection string: %w", err)
+	}
+	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+	configurePool(name, config)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// database/sql must not keep idle connections itself: they would stay
+	// acquired from pgxpool and starve direct pgx users (see stdlib.OpenDBFromPool)
+	connector := &tracedConnector{Connector: stdlib.GetPoolConnector(pool)}
+	sqlDB := sql.OpenDB(connector)
+	connector.pool = sqlDB
+	sqlDB.SetMaxIdleConns(0)
+	return pool, sqlDB, nil
+}
+
+// PoolStat is a point-in-time view of a connection pool
+type PoolStat struct {
+	Pool              string        `json:"pool"`
+	MaxConns          int32         `json:"max_conns"`
+	TotalConns        int32         `json:"total_conns"`
+	AcquiredConns     int32         `json:"acquired_conns"`
+	IdleConns         int32         `json:"idle_conns"`
+	ConstructingConns int32         `json:"constructing_conns"`
+	AcquireCount      int64         `json:"acquire_count"`
+	EmptyAcquireCount int64         `json:"empty_acquire_count"`
+	AcquireWait       time.Duration `json:"acquire_wait_ns"`
+}
+
+// PoolStats returns the stats of the primary pool and, if it is a separate
+// pool, the analytics one
+func PoolStats() []PoolStat {
+	var stats []PoolStat
+	for _, pool := range []struct {
+		name string
+		pool *pgxpool.Pool
+	}{{"primary", PrimaryPgx}, {"analytics", AnalyticsPgx}} {
+		if pool.pool == nil || (pool.name == "analytics" && pool.pool == PrimaryPgx) {
+			continue
+		}
+		stat := pool.pool.Stat()
+		stats = append(stats, PoolStat{
+			Pool:              pool.name,
+			MaxConns:          stat.MaxConns(),
+			TotalConns:        stat.TotalConns(),
+			AcquiredConns:     stat.AcquiredConns(),
+			IdleConns:         stat.IdleConns(),
+			ConstructingConns: stat.ConstructingConns(),
+			AcquireCount:      stat.AcquireCount(),
+			EmptyAcquireCount: stat.EmptyAcquireCount(),
+			AcquireWait:       stat.EmptyAcquireWaitTime(),
+		})
+	}
+	return stats
+}
+
+// poolCollector exports PoolStats on /metrics at scrape time
+type poolCollector struct{}
+
+var (
+	poolConnsDesc       = prometheus.NewDesc("db_pool_conns", "Connections in the pool by state.", []string{"pool", "state"}, nil)
+	poolMaxConnsDesc    = prometheus.NewDesc("db_pool_max_conns", "Most connections the pool opens.", []string{"pool"}, nil)
+	poolAcquiresDesc    = prometheus.NewDesc("db_pool_acquires_total", "Connections acquired from the pool.", []string{"pool"}, nil)
+	poolEmptyAcqDesc    = prometheus.NewDesc("db_pool_empty_acquires_total", "Acquires that had to wait because no connection was idle.", []string{"pool"}, nil)
+	poolAcquireWaitDesc = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Time spent waiting for a connection.", []string{"pool"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(poolCollector{})
+}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyAcqDesc
+	ch <- poolAcquireWaitDesc
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stat := range PoolStats() {
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns), stat.Pool, "acquired")
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns), stat.Pool, "idle")
+		ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stat.ConstructingConns), stat.Pool, "constructing")
+		ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns), stat.Pool)
+		ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount), stat.Pool)
+		ch <- prometheus.MustNewConstMetric(poolEmptyAcqDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount), stat.Pool)
+		ch <- prometheus.MustNewConstMetric(poolAcquireWaitDesc, prometheus.CounterValue, stat.AcquireWait.Seconds(), stat.Pool)
+	}
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Printf("Warning: Invalid value for %s (%s), using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestPoolConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "")
+	t.Setenv("DB_MAX_IDLE_CONNS", "")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "")
+
+	expected := PoolConfig{MaxOpenConns: 20, MaxIdleConns: 2, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute}
+	if got := PoolConfigFromEnv(); got != expected {
+		t.Errorf("Expected defaults %+v, got %+v", expected, got)
+	}
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "5")
+	t.Setenv("DB_MAX_IDLE_CONNS", "10")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "0")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "soon")
+	expected = PoolConfig{MaxOpenConns: 5, MaxIdleConns: 5, ConnMaxLifetime: 0, ConnMaxIdleTime: 5 * time.Minute}
+	if got := PoolConfigFromEnv(); got != expected {
+		t.Errorf("Expected idle capped at open and invalid idle time ignored, %+v, got %+v", expected, got)
+	}
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "-1")
+	t.Setenv("DB_MAX_IDLE_CONNS", "0")
+	if got := PoolConfigFromEnv(); got.MaxOpenConns != 20 || got.MaxIdleConns != 0 {
+		t.Errorf("Expected default open limit and no idle connections, got %+v", got)
+	}
+}
+
+func TestConfigurePool(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "0")
+	t.Setenv("DB_MAX_IDLE_CONNS", "3")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "0")
+	t.Setenv("DB_CONN_MAX_IDLE_TIME", "1m")
+
+	config, err := pgxpool.ParseConfig("postgres://localhost/app")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	configurePool("test", config)
+	if config.MaxConns != math.MaxInt32 || config.MinConns != 3 {
+		t.Errorf("Expected no open limit and 3 connections kept open, got max %d min %d", config.MaxConns, config.MinConns)
+	}
+	if config.MaxConnLifetime != forever || config.MaxConnIdleTime != time.Minute {
+		t.Errorf("Expected no lifetime limit and a 1m idle time, got %v and %v", config.MaxConnLifetime, config.MaxConnIdleTime)
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"saas-go-app/internal/chaos"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Queries are normally sent unprepared (see openPool), so Postgres parses and
+// plans each one again. The hot CRUD queries are registered with Prepared
+// instead: the first time one runs on a connection it is prepared under a
+// name, and from then on that connection executes it by name. A registered
+// query is recognized by its text, before request tracing prefixes it, and
+// runs without the tracing comment.
+
+var preparedStatementLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_prepared_statements_total",
+	Help: "Runs of registered prepared statements, by statement and whether the connection had it prepared already (hit) or had to prepare it (miss).",
+}, []string{"statement", "result"})
+
+// maxPreparedStatements caps the registry. Lists build their query from the
+// filters in use, so each statement can have a few variants; past the cap,
+// new variants run unprepared.
+const maxPreparedStatements = 200
+
+// preparedStatement is a registered query
+type preparedStatement struct {
+	// name identifies the query's text on a connection
+	name string
+	// label is the name it was registered under, shared by its variants
+	label string
+}
+
+var (
+	preparedMu      sync.RWMutex
+	preparedQueries = map[string]preparedStatement{}
+
+	preparedEnabled     bool
+	preparedEnabledOnce sync.Once
+)
+
+// PreparedStatementsEnabled reads DB_PREPARED_STATEMENTS, whether queries
+// registered with Prepared run as prepared statements (default true). Turn it
+// off behind a pooler in transaction mode, such as PgBouncer, where a later
+// query may land on a server connection that never saw the statement.
+func PreparedStatementsEnabled() bool {
+	value := os.Getenv("DB_PREPARED_STATEMENTS")
+	if value == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for DB_PREPARED_STATEMENTS (%s), using default true", value)
+		return true
+	}
+	return enabled
+}
+
+// Prepared registers query to run as a prepared statement and returns it
+// unchanged, so it can wrap the query at the call site:
+//
+//	db.Primary(ctx).QueryRow(db.Prepared("customers.get", "SELECT ... WHERE id = $1"), id)
+//
+// label names the statement in metrics. Queries that differ in text, e.g. a
+// list with and without a filter, are prepared separately under one label.
+func Prepared(label, query string) string {
+	preparedEnabledOnce.Do(func() { preparedEnabled = PreparedStatementsEnabled() })
+	if !preparedEnabled {
+		return query
+	}
+
+	preparedMu.RLock()
+	_, ok := preparedQueries[query]
+	preparedMu.RUnlock()
+	if ok {
+		return query
+	}
+
+	preparedMu.Lock()
+	defer preparedMu.Unlock()
+	if _, ok := preparedQueries[query]; !ok && len(preparedQueries) < maxPreparedStatements {
+		preparedQueries[query] = preparedStatement{name: preparedName(label, query), label: label}
+	}
+	return query
+}
+
+// preparedName derives a statement name from label and a digest of query, so
+// variants under one label get names of their own
+func preparedName(label, query string) string {
+	digest := sha256.Sum256([]byte(query))
+	return label + "_" + hex.EncodeToString(digest[:6])
+}
+
+// lookupPrepared returns the registered statement for query, if any
+func lookupPrepared(query string) (preparedStatement, bool) {
+	preparedMu.RLock()
+	defer preparedMu.RUnlock()
+	statement, ok := preparedQueries[query]
+	return statement, ok
+}
+
+// prepare makes sure statement is prepared on conn and returns the name to
+// execute it by
+func prepare(ctx context.Context, conn *stdlib.Conn, query string, statement preparedStatement) (string, error) {
+	// Names prepared on this connection; it outlives the request, and the
+	// pool hands it to one caller at a time
+	data := conn.Conn().PgConn().CustomData()
+	prepared, _ := data["prepared_statements"].(map[string]bool)
+	if prepared == nil {
+		prepared = map[string]bool{}
+		data["prepared_statements"] = prepared
+	}
+
+	if prepared[statement.name] {
+		preparedStatementLookups.WithLabelValues(statement.label, "hit").Inc()
+		return statement.name, nil
+	}
+	preparedStatementLookups.WithLabelValues(statement.label, "miss").Inc()
+	if _, err := conn.Conn().Prepare(ctx, statement.name, query); err != nil {
+		return "", err
+	}
+	prepared[statement.name] = true
+	return statement.name, nil
+}
+
+// preparedFor returns the registered statement for query and the pgx
+// connection to prepare it on, or false if query should run as is
+func (c *tracedConn) preparedFor(query string) (*stdlib.Conn, preparedStatement, bool) {
+	conn, ok := c.Conn.(*stdlib.Conn)
+	if !ok {
+		return nil, preparedStatement{}, false
+	}
+	statement, ok := lookupPrepared(query)
+	return conn, statement, ok
+}
+
+// queryPrepared runs query by its statement name if it is registered. It
+// returns false if it isn't, and the query should run as is.
+func (c *tracedConn) queryPrepared(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, bool, error) {
+	conn, statement, ok := c.preparedFor(query)
+	if !ok {
+		return nil, false, nil
+	}
+	if err := chaos.DB(ctx); err != nil {
+		return nil, true, err
+	}
+	name, err := prepare(ctx, conn, query, statement)
+	if err != nil {
+		return nil, true, err
+	}
+	rows, err := conn.QueryContext(ctx, name, args)
+	return rows, true, err
+}
+
+// execPrepared is queryPrepared for statements that return no rows
+func (c *tracedConn) execPrepared(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, bool, error) {
+	conn, statement, ok := c.preparedFor(query)
+	if !ok {
+		return nil, false, nil
+	}
+	if err := chaos.DB(ctx); err != nil {
+		return nil, true, err
+	}
+	name, err := prepare(ctx, conn, query, statement)
+	if err != nil {
+		return nil, true, err
+	}
+	result, err := conn.ExecContext(ctx, name, args)
+	return result, true, err
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPreparedStatementsEnabled(t *testing.T) {
+	tests := map[string]bool{"": true, "true": true, "false": false, "0": false, "maybe": true}
+	for value, expected := range tests {
+		t.Setenv("DB_PREPARED_STATEMENTS", value)
+		if got := PreparedStatementsEnabled(); got != expected {
+			t.Errorf("PreparedStatementsEnabled() with DB_PREPARED_STATEMENTS=%q = %v, expected %v", value, got, expected)
+		}
+	}
+}
+
+func TestPreparedRegistersVariants(t *testing.T) {
+	list := "SELECT id FROM prepared_test ORDER BY id"
+	filtered := "SELECT id FROM prepared_test WHERE status = $1 ORDER BY id"
+	if got := Prepared("prepared_test.list", list); got != list {
+		t.Errorf("Expected the query back unchanged, got %q", got)
+	}
+	Prepared("prepared_test.list", filtered)
+	Prepared("prepared_test.list", list)
+
+	first, ok := lookupPrepared(list)
+	second, ok2 := lookupPrepared(filtered)
+	if !ok || !ok2 {
+		t.Fatal("Expected both variants to be registered")
+	}
+	if first.name == second.name || !strings.HasPrefix(first.name, "prepared_test.list_") || first.label != "prepared_test.list" {
+		t.Errorf("Expected distinct names under one label, got %+v and %+v", first, second)
+	}
+	if _, ok := lookupPrepared("SELECT 1"); ok {
+		t.Error("Expected unregistered queries not to be found")
+	}
+}
+
+func TestPreparedStatementHits(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	// A dedicated connection, so both runs use the same one
+	ctx := context.Background()
+	conn, err := PrimaryDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get a connection: %v", err)
+	}
+	defer conn.Close()
+
+	query := Prepared("prepared_test.add", "SELECT $1::int + 1")
+	hits := preparedStatementLookups.WithLabelValues("prepared_test.add", "hit")
+	before := testutil.ToFloat64(hits)
+	for i := 0; i < 2; i++ {
+		var sum int
+		if err := conn.QueryRowContext(ctx, query, 41).Scan(&sum); err != nil || sum != 42 {
+			t.Fatalf("Expected 42, got %d, %v", sum, err)
+		}
+	}
+	if got := testutil.ToFloat64(hits) - before; got != 1 {
+		t.Errorf("Expected the second run to hit the prepared statement, got %v hits", got)
+	}
+}
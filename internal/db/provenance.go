@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// QueryStats record which pools served the queries run with a context and
+// how long they took, so a response can say what the database did for it.
+// Only the time to run each statement is counted, not reading its rows.
+type QueryStats struct {
+	mu       sync.Mutex
+	primary  int
+	follower int
+	duration time.Duration
+	// parent is the QueryStats of the context this one was derived from,
+	// which counts the same queries
+	parent *QueryStats
+}
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose queries, whether run through a
+// Handle, a Router, or a pool directly, are recorded in the returned
+// QueryStats. QueryStats the context already had keep counting them too.
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	stats.parent, _ = ctx.Value(queryStatsKey{}).(*QueryStats)
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+// record counts one statement run on pool
+func (s *QueryStats) record(pool *sql.DB, elapsed time.Duration) {
+	s.mu.Lock()
+	if pool == PrimaryDB {
+		s.primary++
+	} else {
+		s.follower++
+	}
+	s.duration += elapsed
+	s.mu.Unlock()
+	if s.parent != nil {
+		s.parent.record(pool, elapsed)
+	}
+}
+
+// ServedBy is "primary" or "follower" when every query went to that pool,
+// "mixed" when both served some, and "" when there were no queries
+func (s *QueryStats) ServedBy() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.primary > 0 && s.follower > 0:
+		return "mixed"
+	case s.primary > 0:
+		return "primary"
+	case s.follower > 0:
+		return "follower"
+	}
+	return ""
+}
+
+// Queries returns the number of statements run
+func (s *QueryStats) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.primary + s.follower
+}
+
+// Duration returns the total time spent running statements
+func (s *QueryStats) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duration
+}
+
+// record adds a statement started at start to ctx's QueryStats, if any
+func (c *tracedConn) record(ctx context.Context, start time.Time) {
+	if stats, ok := ctx.Value(queryStatsKey{}).(*QueryStats); ok {
+		stats.record(c.pool, time.Since(start))
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// referencePattern matches references produced by FormatAccountReference
+var referencePattern = regexp.MustCompile(`^([A-Z0-9]+)-(\d+)-(\d+)-(\d)$`)
+
+// AccountReferencePrefix returns the configured prefix for account references.
+// Configure with ACCOUNT_REF_PREFIX (default: ACC).
+func AccountReferencePrefix() string {
+	prefix := strings.ToUpper(strings.TrimSpace(os.Getenv("ACCOUNT_REF_PREFIX")))
+	if prefix == "" {
+		return "ACC"
+	}
+	return prefix
+}
+
+// FormatAccountReference builds a human-friendly account reference of the form
+// PREFIX-CUSTOMER-SEQUENCE-CHECK, e.g. ACC-000042-0003-6. The trailing check
+// digit is a Luhn checksum over the customer and sequence digits, so typos are
+// caught before a lookup hits the database.
+func FormatAccountReference(prefix string, customerID, sequence, sequenceDigits int) string {
+	digits := fmt.Sprintf("%06d%0*d", customerID, sequenceDigits, sequence)
+	return fmt.Sprintf("%s-%s-%s-%d", prefix, digits[:len(digits)-sequenceDigits], digits[len(digits)-sequenceDigits:], luhnCheckDigit(digits))
+}
+
+// ValidAccountReference reports whether a reference is well-formed and its check digit matches
+func ValidAccountReference(reference string) bool {
+	parts := referencePattern.FindStringSubmatch(strings.ToUpper(reference))
+	if parts == nil {
+		return false
+	}
+	return fmt.Sprint(luhnCheckDigit(parts[2]+parts[3])) == parts[4]
+}
+
+// NextAccountReference atomically increments the customer's account sequence
+// within tx and returns the reference for the next account. The row lock taken
+// by the UPDATE serializes concurrent account creation for the same customer.
+// It returns sql.ErrNoRows if the customer doesn't exist or is soft-deleted.
+func NextAccountReference(ctx context.Context, tx *sql.Tx, customerID int) (string, error) {
+	var sequence int
+	err := tx.QueryRowContext(ctx,
+		"UPDATE customers SET account_seq = account_seq + 1 WHERE id = $1 AND deleted_at IS NULL RETURNING account_seq",
+		customerID,
+	).Scan(&sequence)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatAccountReference(AccountReferencePrefix(), customerID, sequence, getEnvInt("ACCOUNT_REF_SEQUENCE_DIGITS", 4)), nil
+}
+
+// luhnCheckDigit computes the Luhn check digit for a string of decimal digits
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
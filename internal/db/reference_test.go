@@ -0,0 +1,33 @@
+package db
+
+import "testing"
+
+func TestFormatAccountReference(t *testing.T) {
+	reference := FormatAccountReference("ACC", 42, 3, 4)
+	if reference != "ACC-000042-0003-6" {
+		t.Errorf("Expected ACC-000042-0003-6, got %s", reference)
+	}
+
+	if !ValidAccountReference(reference) {
+		t.Errorf("Expected %s to be valid", reference)
+	}
+}
+
+func TestValidAccountReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		valid     bool
+	}{
+		{FormatAccountReference("ACC", 1, 1, 4), true},
+		{FormatAccountReference("ORG", 123456, 99, 6), true},
+		{"ACC-000042-0003-9", false},
+		{"ACC-000042-0003", false},
+		{"not-a-reference", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidAccountReference(tt.reference); got != tt.valid {
+			t.Errorf("ValidAccountReference(%q) = %v, expected %v", tt.reference, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MigrateInSchema applies this release's migrations to a new, empty schema
+// instead of public, so the schema this release would create can be compared
+// with the live one without touching live tables. The caller drops the schema when
+// done. PrimaryDB is swapped while it runs, so it is only safe to call from
+// one-off commands such as cmd/schemacheck.
+func MigrateInSchema(ctx context.Context, schema string) error {
+	databaseURL, err := primaryDatabaseURL()
+	if err != nil {
+		return err
+	}
+
+	if _, err := PrimaryDB.ExecContext(ctx, "CREATE SCHEMA "+pgx.Identifier{schema}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+
+	scopedPgx, scoped, err := openPool("Schema "+schema, withSearchPath(withQueryTimeout(databaseURL), schema+",public"))
+	if err != nil {
+		return fmt.Errorf("failed to open connection for schema %s: %w", schema, err)
+	}
+	defer scopedPgx.Close()
+	defer scoped.Close()
+
+	live, livePgx := PrimaryDB, PrimaryPgx
+	PrimaryDB, PrimaryPgx = scoped, scopedPgx
+	defer func() { PrimaryDB, PrimaryPgx = live, livePgx }()
+
+	return MigrateUp(ctx)
+}
+
+// withSearchPath adds a search_path run-time parameter to a connection string
+func withSearchPath(databaseURL, searchPath string) string {
+	return withRuntimeParam(databaseURL, "search_path", searchPath)
+}
+
+// withRuntimeParam adds a run-time parameter, sent to Postgres when each
+// connection starts, to a connection string in either URL or key=value form
+func withRuntimeParam(databaseURL, name, value string) string {
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		if u, err := url.Parse(databaseURL); err == nil {
+			query := u.Query()
+			query.Set(name, value)
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return databaseURL + " " + name + "='" + value + "'"
+}
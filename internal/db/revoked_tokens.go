@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Revoked tokens stay revoked, so once a dyno has seen a token on the
+// denylist it remembers that until the token expires instead of asking again
+var (
+	revokedMu    sync.Mutex
+	revokedCache = make(map[string]time.Time) // jti -> expiry
+)
+
+// RevokeToken adds the JWT with ID jti to the denylist until it expires at
+// expiresAt, and deletes the entries of tokens that have expired since
+func RevokeToken(ctx context.Context, jti, username string, expiresAt time.Time) error {
+	// expires_at is a TIMESTAMP, compared against UTC timestamps
+	now := time.Now().UTC()
+	_, err := Primary(ctx).Exec(
+		"INSERT INTO revoked_tokens (jti, username, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING",
+		jti, username, expiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if _, err := Primary(ctx).Exec("DELETE FROM revoked_tokens WHERE expires_at < $1", now); err != nil {
+		return fmt.Errorf("failed to delete expired revocations: %w", err)
+	}
+	cacheRevocation(jti, expiresAt)
+	return nil
+}
+
+// TokenRevoked reports whether the JWT with ID jti is on the denylist
+func TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revokedMu.Lock()
+	_, cached := revokedCache[jti]
+	revokedMu.Unlock()
+	if cached {
+		return true, nil
+	}
+
+	var expiresAt time.Time
+	err := Primary(ctx).QueryRow("SELECT expires_at FROM revoked_tokens WHERE jti = $1", jti).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cacheRevocation(jti, expiresAt)
+	return true, nil
+}
+
+// cacheRevocation remembers a revoked token and forgets expired ones
+func cacheRevocation(jti string, expiresAt time.Time) {
+	revokedMu.Lock()
+	defer revokedMu.Unlock()
+	now := time.Now()
+	for id, expiry := range revokedCache {
+		if expiry.Before(now) {
+			delete(revokedCache, id)
+		}
+	}
+	revokedCache[jti] = expiresAt
+}
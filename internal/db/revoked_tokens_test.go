@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRevokeToken(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	ctx := context.Background()
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	jti := "test-" + time.Now().Format("150405.000000000")
+	t.Cleanup(func() {
+		Primary(ctx).Exec("DELETE FROM revoked_tokens WHERE jti = $1", jti)
+		revokedMu.Lock()
+		delete(revokedCache, jti)
+		revokedMu.Unlock()
+	})
+
+	if revoked, err := TokenRevoked(ctx, jti); err != nil || revoked {
+		t.Fatalf("Expected the token not to be revoked yet, got %v, %v", revoked, err)
+	}
+	if err := RevokeToken(ctx, jti, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+
+	// Another dyno has no cached revocation and reads it from the table
+	revokedMu.Lock()
+	delete(revokedCache, jti)
+	revokedMu.Unlock()
+	if revoked, err := TokenRevoked(ctx, jti); err != nil || !revoked {
+		t.Errorf("Expected the token to be revoked, got %v, %v", revoked, err)
+	}
+	if revoked, _ := TokenRevoked(ctx, jti); !revoked {
+		t.Error("Expected the revocation to be cached")
+	}
+}
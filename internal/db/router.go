@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var routedQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_routed_queries_total",
+	Help: "Queries sent through db.Router, by the pool that ran them (primary or follower).",
+}, []string{"target"})
+
+var (
+	// firstKeyword matches the first keyword of a statement, after any
+	// comments, whitespace, and opening parentheses
+	firstKeyword = regexp.MustCompile(`(?s)^(?:\s+|--[^\n]*\n?|/\*.*?\*/|\()*(\w+)`)
+	// writeClauses match statements or clauses that write, lock rows, or have
+	// side effects, even when they start with SELECT or WITH
+	writeClauses = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|INTO|NEXTVAL|SETVAL|SET_CONFIG|PG_NOTIFY|PG_ADVISORY_\w+)\b|\bFOR\s+(NO\s+KEY\s+)?(UPDATE|SHARE|KEY\s+SHARE)\b`)
+)
+
+type routeKey struct{}
+
+// WithPrimary marks ctx so a Router or Analytics handle sends every query
+// made with it to the primary, for callers that must read their own writes or
+// when the follower is too far behind
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, true)
+}
+
+// PinnedToPrimary reports whether ctx was marked with WithPrimary, so every
+// read made with it goes to the primary
+func PinnedToPrimary(ctx context.Context) bool {
+	return ctx.Value(routeKey{}) != nil
+}
+
+// ReadOnly reports whether query can safely run on a follower: a SELECT (or a
+// WITH query, VALUES, or TABLE) that doesn't lock rows, write, or call
+// functions with side effects. Anything it can't tell is treated as a write.
+func ReadOnly(query string) bool {
+	match := firstKeyword.FindStringSubmatch(query)
+	if match == nil {
+		return false
+	}
+	switch strings.ToUpper(match[1]) {
+	case "SELECT", "WITH", "VALUES", "TABLE":
+		return !writeClauses.MatchString(query)
+	}
+	return false
+}
+
+// Router is a Handle that picks the pool per statement: read-only queries
+// (see ReadOnly) go to the follower pool from AnalyticsPool and everything
+// else, including transactions, to the primary. Followers lag the primary
+// slightly, so code that reads right after writing should use Primary or a
+// context from WithPrimary. Get one with Routed; like Handle, it is cheap and
+// meant to be created per call.
+type Router struct {
+	ctx context.Context
+}
+
+// Routed returns a Router bound to ctx
+func Routed(ctx context.Context) Router {
+	return Router{ctx: ctx}
+}
+
+// Context returns the context queries run with
+func (r Router) Context() context.Context {
+	return r.ctx
+}
+
+// route returns the handle to run query on
+func (r Router) route(query string) Handle {
+	if PinnedToPrimary(r.ctx) || !ReadOnly(query) {
+		routedQueries.WithLabelValues("primary").Inc()
+		return Primary(r.ctx)
+	}
+	handle := Analytics(r.ctx)
+	if handle.pool == PrimaryDB {
+		routedQueries.WithLabelValues("primary").Inc()
+	} else {
+		routedQueries.WithLabelValues("follower").Inc()
+	}
+	return handle
+}
+
+// Query runs a query that returns rows on the pool chosen for it
+func (r Router) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.route(query).Query(query, args...)
+}
+
+// QueryRow runs a query that returns at most one row on the pool chosen for it
+func (r Router) QueryRow(query string, args ...interface{}) *sql.Row {
+	return r.route(query).QueryRow(query, args...)
+}
+
+// Exec runs a statement that returns no rows on the primary
+func (r Router) Exec(query string, args ...interface{}) (sql.Result, error) {
+	routedQueries.WithLabelValues("primary").Inc()
+	return Primary(r.ctx).Exec(query, args...)
+}
+
+// Begin starts a transaction on the primary
+func (r Router) Begin() (*sql.Tx, error) {
+	return Primary(r.ctx).Begin()
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT id, name FROM customers ORDER BY created_at DESC":              true,
+		"select\n\tid\nfrom customers where deleted_at is null":                true,
+		"  -- list customers\n/* multi\nline */ SELECT 1":                      true,
+		"(SELECT 1) UNION (SELECT 2)":                                          true,
+		"WITH recent AS (SELECT * FROM accounts) SELECT COUNT(*) FROM recent":  true,
+		"SELECT updated_at FROM accounts":                                      true,
+		"VALUES (1), (2)":                                                      true,
+		"SELECT id FROM customers WHERE id = $1 FOR UPDATE":                    false,
+		"SELECT id FROM customers FOR NO KEY UPDATE":                           false,
+		"select id from customers for share":                                   false,
+		"SELECT nextval('customers_id_seq')":                                   false,
+		"SELECT pg_advisory_lock(1)":                                           false,
+		"SELECT * INTO backup FROM customers":                                  false,
+		"WITH moved AS (DELETE FROM accounts RETURNING *) SELECT * FROM moved": false,
+		"INSERT INTO customers (name) VALUES ($1)":                             false,
+		"UPDATE customers SET name = $1":                                       false,
+		"DELETE FROM customers":                                                false,
+		"SET statement_timeout = 0":                                            false,
+		"":                                                                     false,
+	}
+
+	for query, want := range tests {
+		if got := ReadOnly(query); got != want {
+			t.Errorf("ReadOnly(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestWithPrimary(t *testing.T) {
+	ctx := context.Background()
+	if PinnedToPrimary(ctx) {
+		t.Error("Expected a plain context to allow follower reads")
+	}
+	if !PinnedToPrimary(WithPrimary(ctx)) {
+		t.Error("Expected WithPrimary to pin queries to the primary")
+	}
+}
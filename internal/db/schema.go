@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TableSchema describes a table of the application's schema. Partitions are
+// left out; a partitioned table stands for them, with their rows and size.
+type TableSchema struct {
+	Name        string `json:"name"`
+	Partitioned bool   `json:"partitioned"`
+	// RowEstimate is the planner's row count as of the last ANALYZE, or nil
+	// if the table has never been analyzed
+	RowEstimate *int64             `json:"row_estimate"`
+	SizeBytes   int64              `json:"size_bytes"`
+	Columns     []ColumnSchema     `json:"columns"`
+	Indexes     []IndexSchema      `json:"indexes"`
+	ForeignKeys []ForeignKeySchema `json:"foreign_keys"`
+}
+
+// ColumnSchema describes a table column
+type ColumnSchema struct {
+	Name string `json:"name"`
+	// Type is the SQL type with its modifiers, e.g. "character varying(255)"
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	// Default is the default expression, or how an identity or generated
+	// column is filled in
+	Default string `json:"default,omitempty"`
+}
+
+// IndexSchema describes an index of a table
+type IndexSchema struct {
+	Name string `json:"name"`
+	// Columns are the key columns in order, with expressions for expression
+	// indexes
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+	// Valid is false for an index left unusable by a failed concurrent build
+	Valid      bool   `json:"valid"`
+	Definition string `json:"definition"`
+}
+
+// ForeignKeySchema describes a foreign key of a table
+type ForeignKeySchema struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+}
+
+// tableFilter selects the tables of the current schema that DescribeSchema
+// reports, aliased t
+const tableFilter = `n.nspname = current_schema() AND t.relkind IN ('r', 'p') AND NOT t.relispartition`
+
+// DescribeSchema reads the tables of the primary's current schema from
+// pg_catalog, with their columns, indexes, foreign keys, and row estimates,
+// ordered by name
+func DescribeSchema(ctx context.Context) ([]TableSchema, error) {
+	// Partitioned tables have no rows or storage of their own, so the
+	// estimates and sizes of the leaves of each table's partition tree (the
+	// table itself, when it isn't partitioned) are summed
+	rows, err := PrimaryDB.QueryContext(ctx, `
+		SELECT t.relname, t.relkind = 'p', leaves.row_estimate, leaves.size_bytes
+		FROM pg_class t
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL (
+			SELECT (SUM(l.reltuples) FILTER (WHERE l.reltuples >= 0))::bigint AS row_estimate,
+				COALESCE(SUM(pg_total_relation_size(l.oid)), 0)::bigint AS size_bytes
+			FROM pg_partition_tree(t.oid) tree
+			JOIN pg_class l ON l.oid = tree.relid
+			WHERE tree.isleaf
+		) leaves
+		WHERE `+tableFilter+`
+		ORDER BY t.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []TableSchema{}
+	for rows.Next() {
+		table := TableSchema{Columns: []ColumnSchema{}, Indexes: []IndexSchema{}, ForeignKeys: []ForeignKeySchema{}}
+		if err := rows.Scan(&table.Name, &table.Partitioned, &table.RowEstimate, &table.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	byName := make(map[string]*TableSchema, len(tables))
+	for i := range tables {
+		byName[tables[i].Name] = &tables[i]
+	}
+
+	err = scanSchemaRows(ctx, "columns", `
+		SELECT t.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+			CASE
+				WHEN a.attidentity = 'a' THEN 'GENERATED ALWAYS AS IDENTITY'
+				WHEN a.attidentity = 'd' THEN 'GENERATED BY DEFAULT AS IDENTITY'
+				WHEN a.attgenerated = 's' THEN 'GENERATED ALWAYS AS (' || pg_get_expr(d.adbin, d.adrelid) || ') STORED'
+				ELSE COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+			END
+		FROM pg_attribute a
+		JOIN pg_class t ON t.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE `+tableFilter+` AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY t.relname, a.attnum`,
+		func(rows *sql.Rows) error {
+			var table string
+			var column ColumnSchema
+			if err := rows.Scan(&table, &column.Name, &column.Type, &column.Nullable, &column.Default); err != nil {
+				return err
+			}
+			if t := byName[table]; t != nil {
+				t.Columns = append(t.Columns, column)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanSchemaRows(ctx, "indexes", `
+		SELECT t.relname, ix.relname,
+			ARRAY(SELECT pg_get_indexdef(i.indexrelid, k, true) FROM generate_series(1, i.indnkeyatts) k ORDER BY k),
+			i.indisunique, i.indisprimary, i.indisvalid, pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class ix ON ix.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE `+tableFilter+`
+		ORDER BY t.relname, ix.relname`,
+		func(rows *sql.Rows) error {
+			var table string
+			var index IndexSchema
+			if err := rows.Scan(&table, &index.Name, Array(&index.Columns), &index.Unique, &index.Primary, &index.Valid, &index.Definition); err != nil {
+				return err
+			}
+			if t := byName[table]; t != nil {
+				t.Indexes = append(t.Indexes, index)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Foreign keys to a partitioned table are cloned for each of its
+	// partitions; conparentid skips the clones
+	err = scanSchemaRows(ctx, "foreign keys", `
+		SELECT t.relname, con.conname, r.relname,
+			ARRAY(SELECT a.attname::text FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+			ARRAY(SELECT a.attname::text FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.ord)
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_class r ON r.oid = con.confrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE con.contype = 'f' AND con.conparentid = 0 AND `+tableFilter+`
+		ORDER BY t.relname, con.conname`,
+		func(rows *sql.Rows) error {
+			var table string
+			var foreignKey ForeignKeySchema
+			if err := rows.Scan(&table, &foreignKey.Name, &foreignKey.ReferencedTable, Array(&foreignKey.Columns), Array(&foreignKey.ReferencedColumns)); err != nil {
+				return err
+			}
+			if t := byName[table]; t != nil {
+				t.ForeignKeys = append(t.ForeignKeys, foreignKey)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// scanSchemaRows runs query on the primary and calls scan for each row; what
+// names the rows in errors
+func scanSchemaRows(ctx context.Context, what, query string, scan func(*sql.Rows) error) error {
+	rows, err := PrimaryDB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", what, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", what, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list %s: %w", what, err)
+	}
+	return nil
+}
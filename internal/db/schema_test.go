@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestDescribeSchema(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+	if err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	tables, err := DescribeSchema(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to describe schema: %v", err)
+	}
+	var accounts *TableSchema
+	for i := range tables {
+		if tables[i].Name == "accounts" {
+			accounts = &tables[i]
+		}
+	}
+	if accounts == nil {
+		t.Fatalf("Expected the accounts table, got %d tables", len(tables))
+	}
+
+	columns := map[string]ColumnSchema{}
+	for _, column := range accounts.Columns {
+		columns[column.Name] = column
+	}
+	if id := columns["id"]; id.Type != "integer" || id.Nullable || id.Default == "" {
+		t.Errorf("Expected a non-null integer id with a default, got %+v", id)
+	}
+	if _, ok := columns["customer_id"]; !ok {
+		t.Errorf("Expected a customer_id column, got %+v", accounts.Columns)
+	}
+
+	primary := false
+	for _, index := range accounts.Indexes {
+		primary = primary || (index.Primary && len(index.Columns) > 0 && index.Columns[0] == "id")
+	}
+	if !primary {
+		t.Errorf("Expected a primary key index on id, got %+v", accounts.Indexes)
+	}
+	references := false
+	for _, foreignKey := range accounts.ForeignKeys {
+		references = references || foreignKey.ReferencedTable == "customers"
+	}
+	if !references {
+		t.Errorf("Expected a foreign key to customers, got %+v", accounts.ForeignKeys)
+	}
+}
@@ -1,127 +1,310 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"saas-go-app/internal/auth"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/errgroup"
 )
 
-// SeedData populates the database with sample customers and accounts
-func SeedData() error {
-	// Check if data already exists
-	var count int
-	err := PrimaryDB.QueryRow("SELECT COUNT(*) FROM customers").Scan(&count)
-	if err != nil {
-		return err
+// DemoCustomer is a sample customer in the demo profile
+type DemoCustomer struct {
+	Name     string
+	Email    string
+	Industry string
+	Country  string
+	ARRBand  string
+}
+
+// DemoAccount is a sample account in the demo profile
+type DemoAccount struct {
+	CustomerIndex int // Index into DemoCustomers
+	Name          string
+	Status        string
+	MRRCents      int64
+}
+
+// DemoCustomers are the sample customers created by SeedData
+var DemoCustomers = []DemoCustomer{
+	{"Acme Corporation", "contact@acme.com", "Manufacturing", "US", "250k-1M"},
+	{"TechStart Inc", "info@techstart.com", "Software", "US", "10k-50k"},
+	{"Global Solutions Ltd", "hello@globalsolutions.com", "Professional Services", "GB", "50k-250k"},
+	{"Digital Innovations", "support@digitalinnovations.com", "Media", "DE", "<10k"},
+	{"Enterprise Systems", "sales@enterprisesystems.com", "Financial Services", "CA", "50k-250k"},
+}
+
+// DemoAccounts are the sample accounts created by SeedData, linked to DemoCustomers
+var DemoAccounts = []DemoAccount{
+	// Acme Corporation (index 0)
+	{0, "Premium Account", "active", 2500000},
+	{0, "Basic Account", "active", 450000},
+	{0, "Trial Account", "inactive", 0},
+	// TechStart Inc (index 1)
+	{1, "Enterprise Account", "active", 190000},
+	{1, "Starter Account", "active", 49000},
+	// Global Solutions Ltd (index 2)
+	{2, "Corporate Account", "active", 980000},
+	{2, "Legacy Account", "inactive", 0},
+	// Digital Innovations (index 3)
+	{3, "Pro Account", "active", 59000},
+	// Enterprise Systems (index 4)
+	{4, "Business Account", "active", 750000},
+	{4, "Standard Account", "active", 320000},
+	{4, "Archive Account", "inactive", 0},
+}
+
+// ProgressFunc receives seeding progress: the phase ("customers" or
+// "accounts"), rows inserted so far in that phase, and the phase total.
+// Performance seeding loads both in chunks, so both phases advance together.
+type ProgressFunc func(phase string, done, total int64)
+
+// report calls progress if it is set
+func (progress ProgressFunc) report(phase string, done, total int) {
+	if progress != nil {
+		progress(phase, int64(done), int64(total))
 	}
-	if count > 0 {
-		log.Println("Database already contains data, skipping seed")
-		return nil
+}
+
+// SeedCounts tally what seeding did with the records of one table
+type SeedCounts struct {
+	Inserted int `json:"inserted"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+}
+
+// SeedSummary is what SeedData did with each kind of demo record
+type SeedSummary struct {
+	Users     SeedCounts `json:"users"`
+	Customers SeedCounts `json:"customers"`
+	Accounts  SeedCounts `json:"accounts"`
+}
+
+// String summarizes the counts for the log
+func (s SeedSummary) String() string {
+	format := func(c SeedCounts) string {
+		return fmt.Sprintf("%d inserted, %d skipped, %d failed", c.Inserted, c.Skipped, c.Failed)
 	}
+	return fmt.Sprintf("users: %s; customers: %s; accounts: %s", format(s.Users), format(s.Customers), format(s.Accounts))
+}
 
+// Failed returns the number of records that couldn't be seeded
+func (s SeedSummary) Failed() int {
+	return s.Users.Failed + s.Customers.Failed + s.Accounts.Failed
+}
+
+// SeedData populates the database with the demo profile, one record at a
+// time. Records that already exist are skipped: customers by email, accounts
+// by name within their customer, and the admin user when there are any users.
+// So a run after a partial one fills in what is missing. A record that fails
+// to insert is logged and counted, and seeding moves on; the accounts of a
+// customer that failed fail with it. The error is only for failures that stop
+// seeding altogether, such as cancelling ctx, which stops it between records.
+func SeedData(ctx context.Context, progress ProgressFunc) (SeedSummary, error) {
 	log.Println("Seeding database with sample data...")
 
-	// Create default test user if users table is empty
-	var userCount int
-	err = PrimaryDB.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
-	if err == nil && userCount == 0 {
-		// Create default test user: admin / admin123
-		passwordHash, err := auth.HashPassword("admin123")
-		if err == nil {
-			_, err = PrimaryDB.Exec(
-				"INSERT INTO users (username, password_hash) VALUES ($1, $2)",
-				"admin", passwordHash,
-			)
-			if err == nil {
-				log.Println("Created default test user: username='admin', password='admin123'")
-			} else {
-				log.Printf("Warning: Failed to create default user: %v", err)
-			}
-		} else {
-			log.Printf("Warning: Failed to hash password for default user: %v", err)
-		}
+	var summary SeedSummary
+	summary.Users = seedDefaultUser(ctx)
+
+	now := time.Now().UTC()
+	if err := EnsureAccountPartitions(ctx, demoAccountCreatedAt(now, 0), now); err != nil {
+		return summary, err
+	}
+	seeder := demoSeeder{customer: seedDemoCustomer, account: seedDemoAccount}
+	if err := seeder.seed(ctx, now, &summary, progress); err != nil {
+		return summary, err
+	}
+
+	if summary.Failed() > 0 {
+		log.Printf("Warning: Database seeding finished with failures (%s)", summary)
+	} else {
+		log.Printf("Database seeding completed successfully (%s)", summary)
 	}
+	return summary, backdateHistory(ctx)
+}
 
-	// Sample customers
-	customers := []struct {
-		name  string
-		email string
-	}{
-		{"Acme Corporation", "contact@acme.com"},
-		{"TechStart Inc", "info@techstart.com"},
-		{"Global Solutions Ltd", "hello@globalsolutions.com"},
-		{"Digital Innovations", "support@digitalinnovations.com"},
-		{"Enterprise Systems", "sales@enterprisesystems.com"},
+// seedDefaultUser creates the default test user, admin / admin123, if there
+// are no users yet. The well-known password is deprecated; seed --bootstrap
+// already uses a random one.
+func seedDefaultUser(ctx context.Context) SeedCounts {
+	var counts SeedCounts
+	var userCount int
+	if err := PrimaryDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&userCount); err != nil {
+		log.Printf("Warning: Failed to check for users: %v", err)
+		counts.Failed++
+		return counts
 	}
+	if userCount > 0 {
+		counts.Skipped++
+		return counts
+	}
+	passwordHash, err := auth.HashPassword(DefaultAdminPassword)
+	if err != nil {
+		log.Printf("Warning: Failed to hash password for default user: %v", err)
+		counts.Failed++
+		return counts
+	}
+	result, err := PrimaryDB.ExecContext(ctx,
+		"INSERT INTO users (username, password_hash, role) VALUES ($1, $2, 'admin') ON CONFLICT (username) DO NOTHING",
+		"admin", passwordHash,
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to create default user: %v", err)
+		counts.Failed++
+		return counts
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		counts.Skipped++
+		return counts
+	}
+	log.Println("Created default test user: username='admin', password='admin123'")
+	warnDefaultAdminDeprecated()
+	counts.Inserted++
+	return counts
+}
 
-	customerIDs := make([]int, 0, len(customers))
+// warnDefaultAdminDeprecated logs that the admin / admin123 default is on its
+// way out
+func warnDefaultAdminDeprecated() {
+	log.Println("DEPRECATED: The admin / admin123 default user is insecure and will get a random password in a future release; change it with POST /api/me/password, or set up new apps with `seed --bootstrap`")
+}
 
-	// Insert customers
-	for _, customer := range customers {
-		var id int
-		err := PrimaryDB.QueryRow(
-			"INSERT INTO customers (name, email) VALUES ($1, $2) RETURNING id",
-			customer.name, customer.email,
-		).Scan(&id)
-		if err != nil {
-			return err
+// demoSeeder inserts the demo customers and accounts through customer and
+// account, which report whether they inserted the record or found it there
+// already; tests replace them
+type demoSeeder struct {
+	customer func(ctx context.Context, customer DemoCustomer) (id int, inserted bool, err error)
+	account  func(ctx context.Context, customerID int, account DemoAccount, createdAt time.Time) (inserted bool, err error)
+}
+
+// seed adds the demo records to summary one at a time, carrying on past
+// records that fail. It returns an error only if ctx is cancelled.
+func (s demoSeeder) seed(ctx context.Context, now time.Time, summary *SeedSummary, progress ProgressFunc) error {
+	customerIDs := make([]int, len(DemoCustomers))
+	for i, customer := range DemoCustomers {
+		if ctx.Err() != nil {
+			return fmt.Errorf("seed cancelled: %w", ctx.Err())
 		}
-		customerIDs = append(customerIDs, id)
-		log.Printf("Created customer: %s (ID: %d)", customer.name, id)
-	}
-
-	// Sample accounts linked to customers
-	accounts := []struct {
-		customerIndex int // Index into customerIDs array
-		name          string
-		status        string
-	}{
-		// Acme Corporation (index 0)
-		{0, "Premium Account", "active"},
-		{0, "Basic Account", "active"},
-		{0, "Trial Account", "inactive"},
-		// TechStart Inc (index 1)
-		{1, "Enterprise Account", "active"},
-		{1, "Starter Account", "active"},
-		// Global Solutions Ltd (index 2)
-		{2, "Corporate Account", "active"},
-		{2, "Legacy Account", "inactive"},
-		// Digital Innovations (index 3)
-		{3, "Pro Account", "active"},
-		// Enterprise Systems (index 4)
-		{4, "Business Account", "active"},
-		{4, "Standard Account", "active"},
-		{4, "Archive Account", "inactive"},
-	}
-
-	// Insert accounts
-	for _, account := range accounts {
-		customerID := customerIDs[account.customerIndex]
-		var id int
-		err := PrimaryDB.QueryRow(
-			"INSERT INTO accounts (customer_id, name, status) VALUES ($1, $2, $3) RETURNING id",
-			customerID, account.name, account.status,
-		).Scan(&id)
-		if err != nil {
-			return err
+		id, inserted, err := s.customer(ctx, customer)
+		switch {
+		case err != nil:
+			log.Printf("Warning: Failed to seed customer %s: %v", customer.Email, err)
+			summary.Customers.Failed++
+		case inserted:
+			log.Printf("Created customer: %s (ID: %d)", customer.Name, id)
+			summary.Customers.Inserted++
+			customerIDs[i] = id
+		default:
+			summary.Customers.Skipped++
+			customerIDs[i] = id
 		}
-		log.Printf("Created account: %s (ID: %d) for customer ID: %d", account.name, id, customerID)
+		progress.report("customers", i+1, len(DemoCustomers))
 	}
 
-	log.Println("Database seeding completed successfully")
+	// Accounts are opened over the past demoAccountMonths, so a partitioned
+	// accounts table has rows in several partitions
+	for i, account := range DemoAccounts {
+		if ctx.Err() != nil {
+			return fmt.Errorf("seed cancelled: %w", ctx.Err())
+		}
+		customerID := customerIDs[account.CustomerIndex]
+		if customerID == 0 {
+			summary.Accounts.Failed++
+		} else if inserted, err := s.account(ctx, customerID, account, demoAccountCreatedAt(now, i)); err != nil {
+			log.Printf("Warning: Failed to seed account %s for customer ID %d: %v", account.Name, customerID, err)
+			summary.Accounts.Failed++
+		} else if inserted {
+			summary.Accounts.Inserted++
+		} else {
+			summary.Accounts.Skipped++
+		}
+		progress.report("accounts", i+1, len(DemoAccounts))
+	}
 	return nil
 }
 
+// seedDemoCustomer inserts customer unless one with its email exists, deleted
+// or not, and returns the id of the customer with that email
+func seedDemoCustomer(ctx context.Context, customer DemoCustomer) (int, bool, error) {
+	var id int
+	err := PrimaryDB.QueryRowContext(ctx,
+		"INSERT INTO customers (name, email, industry, country, arr_band) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (email) DO NOTHING RETURNING id",
+		customer.Name, customer.Email, customer.Industry, customer.Country, customer.ARRBand,
+	).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err == nil, err
+	}
+	err = PrimaryDB.QueryRowContext(ctx, "SELECT id FROM customers WHERE email = $1", customer.Email).Scan(&id)
+	return id, false, err
+}
+
+// seedDemoAccount inserts account for customerID unless the customer has an
+// account of that name, deleted or not
+func seedDemoAccount(ctx context.Context, customerID int, account DemoAccount, createdAt time.Time) (bool, error) {
+	var exists bool
+	err := PrimaryDB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM accounts WHERE customer_id = $1 AND name = $2)", customerID, account.Name,
+	).Scan(&exists)
+	if err != nil || exists {
+		return false, err
+	}
+	id, reference, err := insertAccountWithReference(ctx, customerID, account.Name, account.Status, account.MRRCents, createdAt)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("Created account: %s (ID: %d, ref: %s) for customer ID: %d", account.Name, id, reference, customerID)
+	return true, nil
+}
+
+// demoAccountMonths is how far back the demo accounts were opened
+const demoAccountMonths = 12
+
+// demoAccountCreatedAt spreads the demo accounts evenly over the past
+// demoAccountMonths, oldest first, so the last one is opened at now
+func demoAccountCreatedAt(now time.Time, i int) time.Time {
+	age := time.Duration(len(DemoAccounts)-1-i) * (demoAccountMonths * 30 * 24 * time.Hour) / time.Duration(len(DemoAccounts))
+	return now.Add(-age).Truncate(time.Second)
+}
+
+// insertAccountWithReference inserts an account together with the next reference in its customer's sequence
+func insertAccountWithReference(ctx context.Context, customerID int, name, status string, mrrCents int64, createdAt time.Time) (int, string, error) {
+	tx, err := PrimaryDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	reference, err := NextAccountReference(ctx, tx, customerID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO accounts (customer_id, reference, name, status, mrr_cents, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING id",
+		customerID, reference, name, status, mrrCents, createdAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, reference, tx.Commit()
+}
+
 // SeedDataIfEmpty seeds data only if the database is empty
-func SeedDataIfEmpty() error {
+func SeedDataIfEmpty(ctx context.Context, progress ProgressFunc) error {
 	var count int
-	err := PrimaryDB.QueryRow("SELECT COUNT(*) FROM customers").Scan(&count)
+	err := PrimaryDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM customers").Scan(&count)
 	if err != nil && err != sql.ErrNoRows {
 		return err
 	}
@@ -131,37 +314,95 @@ func SeedDataIfEmpty() error {
 	
 	// Check if we should generate performance demo data
 	if os.Getenv("SEED_PERFORMANCE_DATA") == "true" {
-		return SeedPerformanceData()
+		return SeedPerformanceData(ctx, progress)
 	}
 	
-	return SeedData()
+	_, err = SeedData(ctx, progress)
+	return err
 }
 
 // ClearAndReseed clears existing data and reseeds the database
 // This is useful for regenerating demo data
-func ClearAndReseed() error {
+func ClearAndReseed(ctx context.Context, progress ProgressFunc) error {
+	if err := ClearData(ctx); err != nil {
+		return err
+	}
+	
+	// Reseed based on environment variables
+	if os.Getenv("SEED_PERFORMANCE_DATA") == "true" {
+		return SeedPerformanceData(ctx, progress)
+	}
+	
+	_, err := SeedData(ctx, progress)
+	return err
+}
+
+// ClearData removes all customers and accounts, and their history and audit
+// log
+func ClearData(ctx context.Context) error {
 	log.Println("Clearing existing data...")
 	
 	// Clear accounts first (due to foreign key constraint)
-	_, err := PrimaryDB.Exec("TRUNCATE TABLE accounts CASCADE")
+	_, err := PrimaryDB.ExecContext(ctx, "TRUNCATE TABLE accounts CASCADE")
 	if err != nil {
 		return fmt.Errorf("failed to clear accounts: %w", err)
 	}
 	
 	// Clear customers
-	_, err = PrimaryDB.Exec("TRUNCATE TABLE customers CASCADE")
+	_, err = PrimaryDB.ExecContext(ctx, "TRUNCATE TABLE customers CASCADE")
 	if err != nil {
 		return fmt.Errorf("failed to clear customers: %w", err)
 	}
 	
-	log.Println("Data cleared successfully")
-	
-	// Reseed based on environment variables
-	if os.Getenv("SEED_PERFORMANCE_DATA") == "true" {
-		return SeedPerformanceData()
+	// TRUNCATE skips row triggers, so clear the history and audit log of the
+	// old data too
+	_, err = PrimaryDB.ExecContext(ctx, "TRUNCATE TABLE customers_history, accounts_history, audit_log")
+	if err != nil {
+		return fmt.Errorf("failed to clear history: %w", err)
 	}
 	
-	return SeedData()
+	log.Println("Data cleared successfully")
+	return nil
+}
+
+// PerformanceSeedOptions configure SeedPerformance
+type PerformanceSeedOptions struct {
+	// Customers is the number of customers to generate
+	Customers int
+	// AccountsPerCustomer is the average number of accounts per customer
+	AccountsPerCustomer int
+	// BatchSize is the number of rows sent per COPY
+	BatchSize int
+	// RandomSeed makes the generated data reproducible; 0 picks a random seed
+	RandomSeed int64
+	// Workers is the number of chunks of BatchSize customers, with their
+	// accounts, generated and committed concurrently
+	Workers int
+	// MaxRows is the most customers and accounts generated without Force;
+	// 0 disables the limit
+	MaxRows int
+	// MaxDatabaseMB is the largest the database may grow to without Force;
+	// 0 disables the limit
+	MaxDatabaseMB int
+	// Force seeds datasets beyond MaxRows and MaxDatabaseMB
+	Force bool
+}
+
+// PerformanceSeedOptionsFromEnv reads SEED_CUSTOMERS (default 1000),
+// SEED_ACCOUNTS_PER_CUSTOMER (default 5), SEED_BATCH_SIZE (default 10000),
+// SEED_RANDOM_SEED (default random), SEED_WORKERS (default 1), SEED_MAX_ROWS
+// (default 1000000), SEED_MAX_DATABASE_MB (default 1024), and SEED_FORCE
+func PerformanceSeedOptionsFromEnv() PerformanceSeedOptions {
+	return PerformanceSeedOptions{
+		Customers:           getEnvInt("SEED_CUSTOMERS", 1000),
+		AccountsPerCustomer: getEnvInt("SEED_ACCOUNTS_PER_CUSTOMER", 5),
+		BatchSize:           getEnvInt("SEED_BATCH_SIZE", DefaultSeedBatchSize),
+		RandomSeed:          getEnvInt64("SEED_RANDOM_SEED", 0),
+		Workers:             getEnvInt("SEED_WORKERS", 1),
+		MaxRows:             getEnvInt("SEED_MAX_ROWS", 1000000),
+		MaxDatabaseMB:       getEnvInt("SEED_MAX_DATABASE_MB", 1024),
+		Force:               os.Getenv("SEED_FORCE") == "true",
+	}
 }
 
 // SeedPerformanceData generates large datasets for NGPG performance demonstrations
@@ -169,42 +410,183 @@ func ClearAndReseed() error {
 // - Read scaling with follower pools
 // - Analytics query performance
 // - Automatic query routing
-func SeedPerformanceData() error {
+func SeedPerformanceData(ctx context.Context, progress ProgressFunc) error {
+	return SeedPerformance(ctx, PerformanceSeedOptionsFromEnv(), progress)
+}
+
+// SeedPerformance generates performance demo data sized by opts. Chunks are
+// committed one at a time, so cancelling ctx rolls back the chunks in flight
+// and keeps those already committed; clear the data before seeding again.
+// Chunks are paced by a Throttle, which reports to the job ctx carries.
+func SeedPerformance(ctx context.Context, opts PerformanceSeedOptions, progress ProgressFunc) error {
 	log.Println("Generating performance demo data for NGPG showcase...")
 	
-	// Get configuration from environment or use defaults
-	numCustomers := getEnvInt("SEED_CUSTOMERS", 1000)
-	numAccountsPerCustomer := getEnvInt("SEED_ACCOUNTS_PER_CUSTOMER", 5)
+	numCustomers := opts.Customers
+	numAccountsPerCustomer := opts.AccountsPerCustomer
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultSeedBatchSize
+	}
 	
 	totalAccounts := numCustomers * numAccountsPerCustomer
 	
 	log.Printf("Generating %d customers with ~%d accounts each (~%d total accounts)...", 
 		numCustomers, numAccountsPerCustomer, totalAccounts)
 	
+	// A fixed seed makes the dataset reproducible across environments; log the
+	// seed of random runs so they can be reproduced too
+	randomSeed := opts.RandomSeed
+	if randomSeed == 0 {
+		randomSeed = time.Now().UnixNano()
+	}
+	chunks, expectedAccounts := planSeedChunks(numCustomers, numAccountsPerCustomer, batchSize, randomSeed)
+
+	// Refuse datasets a small plan can't hold before writing anything
+	estimate, err := EstimatePerformanceSeed(ctx, numCustomers, expectedAccounts)
+	if err != nil {
+		return fmt.Errorf("failed to estimate seed size: %w", err)
+	}
+	if err := opts.checkLimits(estimate); err != nil {
+		return err
+	}
+	log.Printf("Estimated seed size: %s", estimate)
+
 	// Create default test user if users table is empty
 	var userCount int
-	err := PrimaryDB.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
+	err = PrimaryDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&userCount)
 	if err == nil && userCount == 0 {
-		passwordHash, err := auth.HashPassword("admin123")
+		passwordHash, err := auth.HashPassword(DefaultAdminPassword)
 		if err == nil {
-			_, err = PrimaryDB.Exec(
-				"INSERT INTO users (username, password_hash) VALUES ($1, $2)",
+			_, err = PrimaryDB.ExecContext(ctx,
+				"INSERT INTO users (username, password_hash, role) VALUES ($1, $2, 'admin')",
 				"admin", passwordHash,
 			)
 			if err == nil {
 				log.Println("Created default test user: username='admin', password='admin123'")
+				warnDefaultAdminDeprecated()
 			}
 		}
 	}
 	
-	// Company name templates for realistic data
-	companyTypes := []string{
+	log.Printf("Using random seed %d (set SEED_RANDOM_SEED to reproduce this dataset)", randomSeed)
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if maxConns := int(PrimaryPgx.Config().MaxConns); workers > maxConns {
+		log.Printf("Warning: SEED_WORKERS (%d) exceeds the pool's %d connections, using %d workers", workers, maxConns, maxConns)
+		workers = maxConns
+	}
+
+	log.Printf("Copying %d customers and %d accounts in %d chunks with %d workers...",
+		numCustomers, expectedAccounts, len(chunks), workers)
+
+	started := time.Now()
+	now := started.UTC()
+	// Signups span signupWindow, so make sure a partitioned accounts table has
+	// a partition for each of its months rather than filling the default one
+	if err := EnsureAccountPartitions(ctx, now.Add(-signupWindow), now); err != nil {
+		return err
+	}
+	customerStats := BulkLoadStats{Table: "customers"}
+	accountStats := BulkLoadStats{Table: "accounts"}
+	var mu sync.Mutex
+
+	// Each chunk is generated and committed in its own transaction by one of
+	// the workers, so chunks load concurrently and a failed chunk leaves none
+	// of its rows behind. Workers pause between chunks while the primary is
+	// under pressure.
+	throttle := NewThrottle(ctx)
+	g, loadCtx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for i := range chunks {
+		if loadCtx.Err() != nil {
+			break
+		}
+		chunk := &chunks[i]
+		g.Go(func() error {
+			if err := throttle.Wait(loadCtx); err != nil {
+				return err
+			}
+			// Customers are copied with ids drawn from their sequence up
+			// front, so accounts can reference them without reading anything back
+			ids, err := reserveIDs(loadCtx, "customers", chunk.customers)
+			if err != nil {
+				return err
+			}
+			var customers, accounts BulkLoadStats
+			err = pgx.BeginFunc(loadCtx, PrimaryPgx, func(tx pgx.Tx) error {
+				if err := setLocalActor(loadCtx, tx); err != nil {
+					return err
+				}
+				customers, accounts, err = chunk.load(loadCtx, ids, now, batchSize, copyIn(tx))
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			customerStats.Rows += customers.Rows
+			customerStats.Batches += customers.Batches
+			accountStats.Rows += accounts.Rows
+			accountStats.Batches += accounts.Batches
+			log.Printf("  Copied %d/%d customers, %d/%d accounts (%s)...",
+				customerStats.Rows, numCustomers, accountStats.Rows, expectedAccounts,
+				seedETA(customerStats.Rows+accountStats.Rows, int64(numCustomers+expectedAccounts), time.Since(started)))
+			progress.report("customers", int(customerStats.Rows), numCustomers)
+			progress.report("accounts", int(accountStats.Rows), expectedAccounts)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("seed cancelled after %d customers and %d accounts were committed: %w",
+				customerStats.Rows, accountStats.Rows, ctx.Err())
+		}
+		return err
+	}
+
+	// Chunks overlap, so every table's throughput is measured against the
+	// whole run
+	elapsed := time.Since(started)
+	customerStats.Elapsed, accountStats.Elapsed = elapsed, elapsed
+	log.Printf("Copied %s", customerStats)
+	log.Printf("Copied %s", accountStats)
+
+	total := BulkLoadStats{
+		Table:   "rows",
+		Rows:    customerStats.Rows + accountStats.Rows,
+		Batches: customerStats.Batches + accountStats.Batches,
+		Elapsed: elapsed,
+	}
+	log.Printf("Performance demo data generation completed: %s", total)
+	log.Printf("Summary: %d customers, %d accounts", customerStats.Rows, accountStats.Rows)
+
+	return backdateHistory(ctx)
+}
+
+// seedETA describes the rate rows are loaded at and the time left at that rate
+func seedETA(done, total int64, elapsed time.Duration) string {
+	if done <= 0 || elapsed <= 0 {
+		return "ETA unknown"
+	}
+	rate := float64(done) / elapsed.Seconds()
+	remaining := time.Duration(float64(total-done) / rate * float64(time.Second))
+	return fmt.Sprintf("%.0f rows/s, ETA %v", rate, remaining.Round(time.Second))
+}
+
+// Company name templates for realistic performance data
+var (
+	companyTypes = []string{
 		"Corporation", "Inc", "LLC", "Ltd", "Group", "Solutions", "Systems",
 		"Innovations", "Technologies", "Enterprises", "Partners", "Associates",
 		"Industries", "Holdings", "Ventures", "Capital", "Global", "International",
 	}
-	
-	companyNames := []string{
+
+	companyNames = []string{
 		"Acme", "TechStart", "Global", "Digital", "Enterprise", "Premier", "Elite",
 		"Advanced", "Strategic", "Dynamic", "Progressive", "Innovative", "Modern",
 		"NextGen", "Future", "Vision", "Prime", "Apex", "Summit", "Peak",
@@ -212,137 +594,189 @@ func SeedPerformanceData() error {
 		"Cyber", "Cloud", "Data", "Info", "Net", "Web", "Mobile", "Smart",
 		"Fast", "Swift", "Rapid", "Turbo", "Power", "Force", "Strong", "Mighty",
 	}
-	
-	accountTypes := []string{
+
+	accountTypes = []string{
 		"Premium", "Enterprise", "Business", "Professional", "Standard", "Basic",
 		"Starter", "Trial", "Pro", "Corporate", "Elite", "Ultimate", "Advanced",
 		"Legacy", "Archive", "Development", "Production", "Staging", "Testing",
 	}
-	
-	statuses := []string{"active", "inactive", "suspended", "pending"}
-	statusWeights := []int{70, 20, 5, 5} // 70% active, 20% inactive, etc.
-	
-	rand.Seed(time.Now().UnixNano())
-	
-	// Generate customers - insert individually to get IDs
-	customerIDs := make([]int, 0, numCustomers)
-	
-	log.Println("Creating customers...")
-	startTime := time.Now()
-	
-	for i := 0; i < numCustomers; i++ {
-		companyName := companyNames[rand.Intn(len(companyNames))]
-		companyType := companyTypes[rand.Intn(len(companyTypes))]
-		name := fmt.Sprintf("%s %s", companyName, companyType)
-		email := fmt.Sprintf("contact@%s%d.com", 
-			companyName[:min(len(companyName), 8)], 
-			i)
-		
-		var id int
-		err := PrimaryDB.QueryRow(
-			"INSERT INTO customers (name, email) VALUES ($1, $2) RETURNING id",
-			name, email,
-		).Scan(&id)
-		if err != nil {
-			return fmt.Errorf("failed to insert customer: %w", err)
+
+	statuses      = []string{"active", "inactive", "suspended", "pending"}
+	statusWeights = []int{70, 20, 5, 5} // 70% active, 20% inactive, etc.
+
+	industries = []string{
+		"Software", "Financial Services", "Healthcare", "Retail", "Manufacturing",
+		"Media", "Education", "Professional Services", "Logistics", "Energy",
+	}
+	industryWeights = []int{22, 14, 12, 11, 10, 8, 7, 7, 5, 4}
+
+	// ISO 3166-1 alpha-2 codes, weighted towards North America and Europe
+	countries      = []string{"US", "GB", "DE", "CA", "FR", "IN", "AU", "NL", "JP", "BR", "SG", "SE"}
+	countryWeights = []int{40, 10, 8, 7, 6, 5, 5, 4, 4, 4, 4, 3}
+
+	// Most customers are small, with a long tail of large contracts
+	arrBands = []arrBand{
+		{"<10k", 1000, 10000},
+		{"10k-50k", 10000, 50000},
+		{"50k-250k", 50000, 250000},
+		{"250k-1M", 250000, 1000000},
+		{"1M+", 1000000, 5000000},
+	}
+	arrBandWeights = []int{35, 30, 20, 10, 5}
+)
+
+// signupWindow is how far back seeded customers signed up
+const signupWindow = 3 * 365 * 24 * time.Hour
+
+// arrBand is a range of annual recurring revenue, in dollars
+type arrBand struct {
+	name     string
+	min, max int64
+}
+
+// randomARRBand picks a band by arrBandWeights
+func randomARRBand(rng *rand.Rand) arrBand {
+	names := make([]string, len(arrBands))
+	for i, band := range arrBands {
+		names[i] = band.name
+	}
+	name := weightedRandom(rng, names, arrBandWeights)
+	for _, band := range arrBands {
+		if band.name == name {
+			return band
 		}
-		customerIDs = append(customerIDs, id)
-		
-		if (i+1)%100 == 0 {
-			log.Printf("  Created %d/%d customers...", i+1, numCustomers)
+	}
+	return arrBands[0]
+}
+
+// randomSignup returns a signup time within signupWindow before now. Signups
+// get more frequent towards now, like a growing business.
+func randomSignup(rng *rand.Rand, now time.Time) time.Time {
+	age := time.Duration((1 - math.Sqrt(rng.Float64())) * float64(signupWindow))
+	return now.Add(-age).Truncate(time.Second)
+}
+
+// splitMRR spreads a customer's ARR, drawn log-uniformly from band, over its
+// active accounts as MRR in whole dollars. Accounts that aren't active bring
+// in nothing.
+func splitMRR(rng *rand.Rand, band arrBand, accountStatuses []string) []int64 {
+	arr := float64(band.min) * math.Pow(float64(band.max)/float64(band.min), rng.Float64())
+	weights := make([]float64, len(accountStatuses))
+	total := 0.0
+	for i, status := range accountStatuses {
+		if status == "active" {
+			weights[i] = 0.5 + rng.Float64()
+			total += weights[i]
 		}
 	}
-	
-	customerTime := time.Since(startTime)
-	log.Printf("Created %d customers in %v", len(customerIDs), customerTime)
-	
-	// Generate accounts in batches for better performance
-	log.Println("Creating accounts...")
-	accountStartTime := time.Now()
-	
-	accountCount := 0
-	accountBatch := make([]struct {
-		customerID int
-		name       string
-		status     string
-	}, 0, 500)
-	
-	insertAccountBatch := func() error {
-		if len(accountBatch) == 0 {
-			return nil
+	mrrCents := make([]int64, len(accountStatuses))
+	for i, weight := range weights {
+		if weight > 0 {
+			mrrCents[i] = int64(arr/12*weight/total) * 100
 		}
-		
-		// Build batch insert query
-		placeholders := ""
-		values := make([]interface{}, 0, len(accountBatch)*3)
-		
-		for i, acc := range accountBatch {
-			if i > 0 {
-				placeholders += ", "
+	}
+	return mrrCents
+}
+
+// seedChunk is a run of performance customers, and their accounts, that is
+// generated and committed together. Each chunk draws from its own random
+// source, so the data doesn't depend on how many workers load the chunks or
+// in which order.
+type seedChunk struct {
+	first     int   // index of the chunk's first customer
+	customers int   // number of customers in the chunk
+	accounts  []int // accounts to generate for each customer
+	rng       *rand.Rand
+}
+
+// planSeedChunks splits numCustomers into chunks of batchSize and decides each
+// customer's account count up front, so progress has an exact total. It
+// returns the chunks and the total number of accounts.
+func planSeedChunks(numCustomers, numAccountsPerCustomer, batchSize int, randomSeed int64) ([]seedChunk, int) {
+	var chunks []seedChunk
+	expectedAccounts := 0
+	for first := 0; first < numCustomers; first += batchSize {
+		chunk := seedChunk{
+			first:     first,
+			customers: min(batchSize, numCustomers-first),
+			rng:       rand.New(rand.NewSource(randomSeed + int64(len(chunks)))),
+		}
+		// Add some variation: 20% of customers have 1-2x the average
+		chunk.accounts = make([]int, chunk.customers)
+		for i := range chunk.accounts {
+			chunk.accounts[i] = numAccountsPerCustomer
+			if chunk.rng.Float32() < 0.2 {
+				chunk.accounts[i] = int(float32(numAccountsPerCustomer) * (1.0 + chunk.rng.Float32()))
 			}
-			placeholders += fmt.Sprintf("($%d, $%d, $%d)", len(values)+1, len(values)+2, len(values)+3)
-			values = append(values, acc.customerID, acc.name, acc.status)
+			expectedAccounts += chunk.accounts[i]
 		}
-		
-		query := fmt.Sprintf("INSERT INTO accounts (customer_id, name, status) VALUES %s", placeholders)
-		_, err := PrimaryDB.Exec(query, values...)
-		if err != nil {
-			return fmt.Errorf("failed to insert account batch: %w", err)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, expectedAccounts
+}
+
+// load generates the chunk's customers with the given ids, then their
+// accounts, copying both in batches with copyRows. Signup and account dates
+// are spread over the signupWindow before now.
+func (chunk *seedChunk) load(ctx context.Context, ids []int, now time.Time, batchSize int, copyRows copyFunc) (BulkLoadStats, BulkLoadStats, error) {
+	customers := newBulkLoader("customers", []string{"id", "name", "email", "industry", "country", "arr_band", "created_at", "updated_at"}, batchSize)
+	customers.copy = copyRows
+	signups := make([]time.Time, len(ids))
+	bands := make([]arrBand, len(ids))
+	for j, id := range ids {
+		companyName := companyNames[chunk.rng.Intn(len(companyNames))]
+		companyType := companyTypes[chunk.rng.Intn(len(companyTypes))]
+		name := fmt.Sprintf("%s %s", companyName, companyType)
+		email := fmt.Sprintf("contact@%s%d.com",
+			companyName[:min(len(companyName), 8)],
+			chunk.first+j)
+		industry := weightedRandom(chunk.rng, industries, industryWeights)
+		country := weightedRandom(chunk.rng, countries, countryWeights)
+		bands[j] = randomARRBand(chunk.rng)
+		signups[j] = randomSignup(chunk.rng, now)
+		if err := customers.Add(ctx, id, name, email, industry, country, bands[j].name, signups[j], signups[j]); err != nil {
+			return customers.Stats(), BulkLoadStats{}, err
 		}
-		
-		accountCount += len(accountBatch)
-		accountBatch = accountBatch[:0] // Clear batch
-		return nil
 	}
-	
-	for i := 0; i < len(customerIDs); i++ {
-		customerID := customerIDs[i]
-		accountsForCustomer := numAccountsPerCustomer
-		
-		// Add some variation: 20% of customers have 1-2x the average
-		if rand.Float32() < 0.2 {
-			accountsForCustomer = int(float32(accountsForCustomer) * (1.0 + rand.Float32()))
+	if err := customers.Flush(ctx); err != nil {
+		return customers.Stats(), BulkLoadStats{}, err
+	}
+
+	accounts := newBulkLoader("accounts", []string{"customer_id", "name", "status", "mrr_cents", "created_at", "updated_at"}, batchSize)
+	accounts.copy = copyRows
+	for j, customerID := range ids {
+		names := make([]string, chunk.accounts[j])
+		accountStatuses := make([]string, chunk.accounts[j])
+		for k := range names {
+			accountType := accountTypes[chunk.rng.Intn(len(accountTypes))]
+			names[k] = fmt.Sprintf("%s Account", accountType)
+			accountStatuses[k] = weightedRandom(chunk.rng, statuses, statusWeights)
 		}
-		
-		// Add accounts to batch
-		for j := 0; j < accountsForCustomer; j++ {
-			accountType := accountTypes[rand.Intn(len(accountTypes))]
-			accountName := fmt.Sprintf("%s Account", accountType)
-			status := weightedRandomStatus(statuses, statusWeights)
-			
-			accountBatch = append(accountBatch, struct {
-				customerID int
-				name       string
-				status     string
-			}{customerID, accountName, status})
-			
-			// Insert batch when it reaches size limit
-			if len(accountBatch) >= 500 {
-				if err := insertAccountBatch(); err != nil {
-					return err
-				}
+		mrrCents := splitMRR(chunk.rng, bands[j], accountStatuses)
+		for k := range names {
+			// Accounts are opened between the customer's signup and now
+			created := signups[j].Add(time.Duration(chunk.rng.Float64() * float64(now.Sub(signups[j])))).Truncate(time.Second)
+			if err := accounts.Add(ctx, customerID, names[k], accountStatuses[k], mrrCents[k], created, created); err != nil {
+				return customers.Stats(), accounts.Stats(), err
 			}
 		}
-		
-		// Log progress
-		if (i+1)%100 == 0 {
-			log.Printf("  Created accounts for %d/%d customers (%d total accounts)...", 
-				i+1, len(customerIDs), accountCount)
-		}
 	}
-	
-	// Insert remaining accounts
-	if err := insertAccountBatch(); err != nil {
-		return err
+	err := accounts.Flush(ctx)
+	return customers.Stats(), accounts.Stats(), err
+}
+
+// backdateHistory starts the current version of every row at its created_at,
+// so as_of reads of seeded data agree with the backdated signup dates. It is
+// meant for freshly seeded tables, where no row has changed since.
+func backdateHistory(ctx context.Context) error {
+	for _, table := range VersionedTables {
+		_, err := PrimaryDB.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s_history SET valid_from = (data->>'created_at')::timestamp AT TIME ZONE 'UTC'
+			WHERE valid_to IS NULL AND (data->>'created_at')::timestamp AT TIME ZONE 'UTC' < valid_from`, table))
+		if err != nil {
+			return fmt.Errorf("failed to backdate %s history: %w", table, err)
+		}
 	}
-	
-	accountTime := time.Since(accountStartTime)
-	totalTime := customerTime + accountTime
-	
-	log.Printf("Created %d accounts in %v", accountCount, accountTime)
-	log.Printf("Performance demo data generation completed in %v", totalTime)
-	log.Printf("Summary: %d customers, %d accounts", len(customerIDs), accountCount)
-	
 	return nil
 }
 
@@ -360,21 +794,35 @@ func getEnvInt(key string, defaultValue int) int {
 	return intValue
 }
 
-func weightedRandomStatus(statuses []string, weights []int) string {
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s (%s), using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return intValue
+}
+
+// weightedRandom picks one of values, each as likely as its weight
+func weightedRandom(rng *rand.Rand, values []string, weights []int) string {
 	totalWeight := 0
 	for _, w := range weights {
 		totalWeight += w
 	}
 	
-	r := rand.Intn(totalWeight)
+	r := rng.Intn(totalWeight)
 	cumulative := 0
 	for i, weight := range weights {
 		cumulative += weight
 		if r < cumulative {
-			return statuses[i]
+			return values[i]
 		}
 	}
-	return statuses[len(statuses)-1]
+	return values[len(values)-1]
 }
 
 func min(a, b int) int {
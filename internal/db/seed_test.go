@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestWeightedRandomStatusIsReproducible(t *testing.T) {
+	statuses := []string{"active", "inactive", "suspended", "pending"}
+	weights := []int{70, 20, 5, 5}
+
+	draw := func(seed int64) []string {
+		rng := rand.New(rand.NewSource(seed))
+		picks := make([]string, 50)
+		for i := range picks {
+			picks[i] = weightedRandom(rng, statuses, weights)
+		}
+		return picks
+	}
+
+	first, second := draw(42), draw(42)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same statuses for the same seed, differed at %d: %s vs %s", i, first[i], second[i])
+		}
+	}
+}
+
+func TestPerformanceSeedOptionsFromEnv(t *testing.T) {
+	t.Setenv("SEED_RANDOM_SEED", "1234")
+	if opts := PerformanceSeedOptionsFromEnv(); opts.RandomSeed != 1234 {
+		t.Errorf("Expected random seed 1234, got %d", opts.RandomSeed)
+	}
+	t.Setenv("SEED_RANDOM_SEED", "abc")
+	if opts := PerformanceSeedOptionsFromEnv(); opts.RandomSeed != 0 {
+		t.Errorf("Expected an invalid seed to fall back to random, got %d", opts.RandomSeed)
+	}
+}
+
+func TestPlanSeedChunks(t *testing.T) {
+	chunks, expected := planSeedChunks(25, 5, 10, 42)
+	if len(chunks) != 3 || chunks[2].first != 20 || chunks[2].customers != 5 {
+		t.Fatalf("Expected chunks of 10, 10, and 5 customers, got %+v", chunks)
+	}
+	total := 0
+	for _, chunk := range chunks {
+		for _, n := range chunk.accounts {
+			if n < 5 || n > 10 {
+				t.Errorf("Expected 5-10 accounts per customer, got %d", n)
+			}
+			total += n
+		}
+	}
+	if total != expected {
+		t.Errorf("Expected the total of %d to match the plan, got %d", expected, total)
+	}
+}
+
+func TestSeedChunkLoadIsReproducible(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	load := func() [][]any {
+		chunks, _ := planSeedChunks(20, 3, 10, 42)
+		var rows [][]any
+		copyRows := func(ctx context.Context, table string, columns []string, batch [][]any) (int64, error) {
+			rows = append(rows, batch...)
+			return int64(len(batch)), nil
+		}
+		// Load the chunks in reverse, as concurrent workers might
+		for i := len(chunks) - 1; i >= 0; i-- {
+			ids := make([]int, chunks[i].customers)
+			for j := range ids {
+				ids[j] = chunks[i].first + j + 1
+			}
+			customers, accounts, err := chunks[i].load(context.Background(), ids, now, 4, copyRows)
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if customers.Rows != 10 || customers.Batches != 3 || accounts.Rows < 30 {
+				t.Errorf("Unexpected chunk stats: %+v, %+v", customers, accounts)
+			}
+		}
+		return rows
+	}
+
+	first, second := load(), load()
+	if len(first) != len(second) {
+		t.Fatalf("Expected the same number of rows, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if fmt.Sprint(first[i]) != fmt.Sprint(second[i]) {
+			t.Fatalf("Expected the same rows for the same seed, row %d: %v vs %v", i, first[i], second[i])
+		}
+	}
+}
+
+func TestSeedCheckLimits(t *testing.T) {
+	opts := PerformanceSeedOptions{MaxRows: 1000, MaxDatabaseMB: 10}
+	small := SeedEstimate{Rows: 600, Bytes: 1 << 20, DatabaseBytes: 8 << 20}
+	if err := opts.checkLimits(small); err != nil {
+		t.Errorf("Expected a seed within the limits to pass, got %v", err)
+	}
+
+	tooManyRows := SeedEstimate{Rows: 1001, Bytes: 1 << 20}
+	tooBig := SeedEstimate{Rows: 600, Bytes: 3 << 20, DatabaseBytes: 8 << 20}
+	for _, estimate := range []SeedEstimate{tooManyRows, tooBig} {
+		if err := opts.checkLimits(estimate); !errors.Is(err, ErrSeedTooLarge) {
+			t.Errorf("Expected ErrSeedTooLarge for %s, got %v", estimate, err)
+		}
+		forced := opts
+		forced.Force = true
+		if err := forced.checkLimits(estimate); err != nil {
+			t.Errorf("Expected Force to allow %s, got %v", estimate, err)
+		}
+	}
+
+	if err := (PerformanceSeedOptions{}).checkLimits(tooBig); err != nil {
+		t.Errorf("Expected limits of 0 to be disabled, got %v", err)
+	}
+}
+
+func TestSeedETA(t *testing.T) {
+	if got := seedETA(2500, 10000, 5*time.Second); got != "500 rows/s, ETA 15s" {
+		t.Errorf("Unexpected ETA %q", got)
+	}
+	if got := seedETA(0, 10000, time.Second); got != "ETA unknown" {
+		t.Errorf("Expected no ETA before any rows are copied, got %q", got)
+	}
+}
+
+func TestSeedAttributes(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 100; i++ {
+		if signup := randomSignup(rng, now); signup.After(now) || signup.Before(now.Add(-signupWindow)) {
+			t.Fatalf("Expected a signup within the last 3 years, got %v", signup)
+		}
+	}
+
+	band := arrBands[1]
+	mrr := splitMRR(rng, band, []string{"active", "inactive", "active"})
+	if mrr[1] != 0 {
+		t.Errorf("Expected no MRR for an inactive account, got %d", mrr[1])
+	}
+	if arr := (mrr[0] + mrr[2]) * 12 / 100; arr < band.min-24 || arr > band.max {
+		t.Errorf("Expected ARR within %s, got %d", band.name, arr)
+	}
+}
+
+func TestDemoAccountCreatedAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := demoAccountCreatedAt(now, len(DemoAccounts)-1); !got.Equal(now) {
+		t.Errorf("Expected the last demo account to be opened now, got %v", got)
+	}
+	if months := len(partitionMonths(demoAccountCreatedAt(now, 0), now)); months < demoAccountMonths {
+		t.Errorf("Expected the demo accounts to span at least %d months, got %d", demoAccountMonths, months)
+	}
+	for i := 1; i < len(DemoAccounts); i++ {
+		if !demoAccountCreatedAt(now, i).After(demoAccountCreatedAt(now, i-1)) {
+			t.Fatalf("Expected demo accounts to be opened oldest first, but %d isn't after %d", i, i-1)
+		}
+	}
+}
+
+func TestDemoSeederCarriesOnPastFailures(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seeder := demoSeeder{
+		customer: func(ctx context.Context, customer DemoCustomer) (int, bool, error) {
+			switch customer.Email {
+			case DemoCustomers[0].Email:
+				// Left behind by a partial run
+				return 100, false, nil
+			case DemoCustomers[1].Email:
+				return 0, false, errors.New("connection reset")
+			}
+			return 200, true, nil
+		},
+		account: func(ctx context.Context, customerID int, account DemoAccount, createdAt time.Time) (bool, error) {
+			if customerID == 100 {
+				return account.Name != "Premium Account", nil
+			}
+			return true, nil
+		},
+	}
+
+	var summary SeedSummary
+	var done int64
+	err := seeder.seed(context.Background(), now, &summary, func(phase string, d, total int64) {
+		if phase == "accounts" {
+			done = d
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected failed records not to stop seeding, got %v", err)
+	}
+	if summary.Customers != (SeedCounts{Inserted: 3, Skipped: 1, Failed: 1}) {
+		t.Errorf("Unexpected customer counts %+v", summary.Customers)
+	}
+	// TechStart's two accounts fail with it, and Acme's Premium Account exists
+	if summary.Accounts != (SeedCounts{Inserted: 8, Skipped: 1, Failed: 2}) {
+		t.Errorf("Unexpected account counts %+v", summary.Accounts)
+	}
+	if summary.Failed() != 3 || done != int64(len(DemoAccounts)) {
+		t.Errorf("Expected 3 failures and every account reported, got %d and %d", summary.Failed(), done)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := seeder.seed(ctx, now, &SeedSummary{}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelling to stop seeding, got %v", err)
+	}
+}
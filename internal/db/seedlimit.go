@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSeedTooLarge is returned by SeedPerformance when the dataset exceeds
+// SEED_MAX_ROWS or would grow the database past SEED_MAX_DATABASE_MB, and
+// neither SEED_FORCE nor --force is set
+var ErrSeedTooLarge = errors.New("performance seed exceeds the configured size limits")
+
+// Rough on-disk size of a generated row, with its share of indexes, used
+// until the tables hold enough rows to measure it
+const (
+	customerRowBytes = 200
+	accountRowBytes  = 250
+	// minMeasuredRows is how many rows a table needs before its own average
+	// row size is used instead of the defaults above
+	minMeasuredRows = 1000
+)
+
+// SeedEstimate is the projected size of a performance seed next to the size
+// of the database it goes into
+type SeedEstimate struct {
+	// Rows is the number of customers and accounts to generate
+	Rows int64
+	// Bytes is the estimated on-disk size of those rows, with indexes
+	Bytes int64
+	// DatabaseBytes is the current size of the database
+	DatabaseBytes int64
+}
+
+func (e SeedEstimate) String() string {
+	return fmt.Sprintf("%d rows, ~%d MB on top of a %d MB database",
+		e.Rows, e.Bytes>>20, e.DatabaseBytes>>20)
+}
+
+// EstimatePerformanceSeed projects the size of customers and accounts rows
+// from the average row size of the existing tables (or defaults while they
+// are nearly empty) and reads the current database size
+func EstimatePerformanceSeed(ctx context.Context, customers, accounts int) (SeedEstimate, error) {
+	estimate := SeedEstimate{Rows: int64(customers) + int64(accounts)}
+	if err := PrimaryDB.QueryRowContext(ctx, "SELECT pg_database_size(current_database())").Scan(&estimate.DatabaseBytes); err != nil {
+		return estimate, err
+	}
+
+	rowBytes := map[string]int64{"customers": customerRowBytes, "accounts": accountRowBytes}
+	// reltuples is the planner's row estimate, so sizing doesn't scan the tables
+	rows, err := PrimaryDB.QueryContext(ctx, `
+		SELECT relname, GREATEST(reltuples, 0)::bigint, pg_total_relation_size(oid)
+		FROM pg_class
+		WHERE oid IN ('customers'::regclass, 'accounts'::regclass)`)
+	if err != nil {
+		return estimate, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		var tuples, size int64
+		if err := rows.Scan(&table, &tuples, &size); err != nil {
+			return estimate, err
+		}
+		if tuples >= minMeasuredRows {
+			rowBytes[table] = size / tuples
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return estimate, err
+	}
+
+	estimate.Bytes = int64(customers)*rowBytes["customers"] + int64(accounts)*rowBytes["accounts"]
+	return estimate, nil
+}
+
+// checkLimits returns ErrSeedTooLarge, with the limit that was hit, unless
+// the estimate fits within opts' limits or opts.Force is set. A limit of 0
+// is disabled.
+func (opts PerformanceSeedOptions) checkLimits(estimate SeedEstimate) error {
+	if opts.Force {
+		return nil
+	}
+	if opts.MaxRows > 0 && estimate.Rows > int64(opts.MaxRows) {
+		return fmt.Errorf("%w: %s is more than SEED_MAX_ROWS (%d); set SEED_FORCE=true or pass --force to seed anyway",
+			ErrSeedTooLarge, estimate, opts.MaxRows)
+	}
+	if maxBytes := int64(opts.MaxDatabaseMB) << 20; maxBytes > 0 && estimate.DatabaseBytes+estimate.Bytes > maxBytes {
+		return fmt.Errorf("%w: %s would exceed SEED_MAX_DATABASE_MB (%d); set SEED_FORCE=true or pass --force to seed anyway",
+			ErrSeedTooLarge, estimate, opts.MaxDatabaseMB)
+	}
+	return nil
+}
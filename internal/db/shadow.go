@@ -0,0 +1,160 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Shadow reads validate the follower before analytics reads are routed to
+// it. While analytics_routing is primary and shadow_read_percent is above
+// zero, that share of the read-only queries made through Analytics handles
+// (and so Router) is run again in the background, on the primary and the
+// follower at the same time, and the two results are compared. The request
+// never waits for it and never sees the follower's result.
+
+var (
+	shadowReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_shadow_reads_total",
+		Help: "Reads mirrored to the follower pool, by result: match, mismatch, error, or dropped when too many were in flight.",
+	}, []string{"result"})
+	shadowReadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_shadow_read_duration_seconds",
+		Help:    "Duration of mirrored reads, by pool (primary or follower).",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"pool"})
+)
+
+const (
+	// maxShadowReads caps the comparisons in flight, each holding a
+	// connection of both pools; reads sampled beyond it are dropped
+	maxShadowReads = 4
+	// shadowReadTimeout bounds each comparison
+	shadowReadTimeout = 10 * time.Second
+)
+
+var shadowSlots = make(chan struct{}, maxShadowReads)
+
+// sampleShadowRead reports whether to mirror a read, given the percentage of
+// reads to mirror; tests replace it
+var sampleShadowRead = func(percent float64) bool {
+	return rand.Float64()*100 < percent
+}
+
+// shadowRead mirrors query to the follower in the background if shadow reads
+// are on and it is sampled. Handles call it for reads that ran on the primary
+// only because analytics_routing is primary.
+func shadowRead(query string, args []interface{}) {
+	percent := config.Current().ShadowReadPercent
+	if percent <= 0 || AnalyticsDB == nil || AnalyticsDB == PrimaryDB || !ReadOnly(query) || !sampleShadowRead(percent) {
+		return
+	}
+	select {
+	case shadowSlots <- struct{}{}:
+	default:
+		shadowReads.WithLabelValues("dropped").Inc()
+		return
+	}
+	go func() {
+		defer func() { <-shadowSlots }()
+		// Detached from the request, which may finish first
+		ctx, cancel := context.WithTimeout(context.Background(), shadowReadTimeout)
+		defer cancel()
+		shadowReads.WithLabelValues(compareReads(ctx, PrimaryDB, AnalyticsDB, query, args)).Inc()
+	}()
+}
+
+// readDigest summarizes a query's result
+type readDigest struct {
+	rows     int
+	sum      [sha256.Size]byte
+	duration time.Duration
+	err      error
+}
+
+// digestRead runs query on pool and hashes its rows. Rows are hashed
+// independently and sorted, so results differing only in order match.
+func digestRead(ctx context.Context, pool *sql.DB, query string, args []interface{}) readDigest {
+	start := time.Now()
+	rows, err := pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return readDigest{err: err, duration: time.Since(start)}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return readDigest{err: err, duration: time.Since(start)}
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	var rowSums [][]byte
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return readDigest{err: err, duration: time.Since(start)}
+		}
+		hash := sha256.New()
+		for _, value := range values {
+			fmt.Fprintf(hash, "%T:%v\x1f", value, value)
+		}
+		rowSums = append(rowSums, hash.Sum(nil))
+	}
+	if err := rows.Err(); err != nil {
+		return readDigest{err: err, duration: time.Since(start)}
+	}
+
+	sort.Slice(rowSums, func(i, j int) bool { return bytes.Compare(rowSums[i], rowSums[j]) < 0 })
+	digest := readDigest{rows: len(rowSums), duration: time.Since(start)}
+	digest.sum = sha256.Sum256(bytes.Join(rowSums, nil))
+	return digest
+}
+
+// compareReads runs query on primary and follower concurrently and returns
+// match, mismatch, or error, logging anything but a match
+func compareReads(ctx context.Context, primary, follower *sql.DB, query string, args []interface{}) string {
+	followerDigest := make(chan readDigest, 1)
+	go func() { followerDigest <- digestRead(ctx, follower, query, args) }()
+	p := digestRead(ctx, primary, query, args)
+	f := <-followerDigest
+
+	shadowReadDuration.WithLabelValues("primary").Observe(p.duration.Seconds())
+	shadowReadDuration.WithLabelValues("follower").Observe(f.duration.Seconds())
+	switch {
+	case p.err != nil || f.err != nil:
+		log.Printf("Shadow read failed (primary: %v, follower: %v): %s", p.err, f.err, shortQuery(query))
+		return "error"
+	case p.rows != f.rows || p.sum != f.sum:
+		lag := "unknown"
+		if measured, _, err := cachedLag(ctx); err == nil {
+			lag = measured.Round(time.Millisecond).String()
+		}
+		log.Printf("Shadow read mismatch: primary returned %d rows in %s, follower %d rows in %s (replication lag %s): %s",
+			p.rows, p.duration.Round(time.Microsecond), f.rows, f.duration.Round(time.Microsecond), lag, shortQuery(query))
+		return "mismatch"
+	}
+	return "match"
+}
+
+// shortQuery collapses whitespace in query and truncates it for logging
+func shortQuery(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > 200 {
+		return query[:200] + "..."
+	}
+	return query
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/config"
+)
+
+func TestShadowReadSampling(t *testing.T) {
+	config.Load()
+	original := config.Current()
+	t.Cleanup(func() { config.Apply(original, "test", "test") })
+	primary, analytics, sample := PrimaryDB, AnalyticsDB, sampleShadowRead
+	t.Cleanup(func() { PrimaryDB, AnalyticsDB, sampleShadowRead = primary, analytics, sample })
+
+	sampled := 0
+	sampleShadowRead = func(float64) bool {
+		sampled++
+		return false
+	}
+	PrimaryDB, AnalyticsDB = new(sql.DB), new(sql.DB)
+
+	settings := original
+	settings.AnalyticsRouting = "primary"
+	settings.ShadowReadPercent = 0
+	if _, err := config.Apply(settings, "test", "tester"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	shadowRead("SELECT 1", nil)
+	if sampled != 0 {
+		t.Error("Expected no shadow reads at 0 percent")
+	}
+
+	settings.ShadowReadPercent = 10
+	if _, err := config.Apply(settings, "test", "tester"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	shadowRead("UPDATE accounts SET status = 'active'", nil)
+	if sampled != 0 {
+		t.Error("Expected writes not to be shadowed")
+	}
+	shadowRead("SELECT 1", nil)
+	if sampled != 1 {
+		t.Errorf("Expected the read to be sampled, got %d samples", sampled)
+	}
+
+	AnalyticsDB = PrimaryDB
+	shadowRead("SELECT 1", nil)
+	if sampled != 1 {
+		t.Error("Expected no shadow reads without a separate follower")
+	}
+}
+
+func TestAnalyticsShadowsOnlyRoutedReads(t *testing.T) {
+	config.Load()
+	original := config.Current()
+	t.Cleanup(func() { config.Apply(original, "test", "test") })
+	primary, analytics := PrimaryDB, AnalyticsDB
+	t.Cleanup(func() { PrimaryDB, AnalyticsDB = primary, analytics })
+	PrimaryDB, AnalyticsDB = new(sql.DB), new(sql.DB)
+
+	if Analytics(context.Background()).shadow {
+		t.Error("Expected reads on the follower not to be shadowed")
+	}
+	settings := original
+	settings.AnalyticsRouting = "primary"
+	if _, err := config.Apply(settings, "test", "tester"); err != nil {
+		t.Fatalf("Failed to apply settings: %v", err)
+	}
+	if !Analytics(context.Background()).shadow {
+		t.Error("Expected reads routed to the primary to be shadowed")
+	}
+	if Analytics(WithPrimary(context.Background())).shadow {
+		t.Error("Expected reads pinned to the primary not to be shadowed")
+	}
+}
+
+func TestShortQuery(t *testing.T) {
+	if got := shortQuery("SELECT id\n\t\tFROM accounts"); got != "SELECT id FROM accounts" {
+		t.Errorf("Expected collapsed whitespace, got %q", got)
+	}
+	if got := shortQuery("SELECT " + strings.Repeat("x", 300)); len(got) != 203 || !strings.HasSuffix(got, "...") {
+		t.Errorf("Expected the query truncated to 200 characters, got %d", len(got))
+	}
+}
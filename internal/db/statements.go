@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrStatementsUnavailable is returned by TopStatements when the
+// pg_stat_statements extension isn't installed
+var ErrStatementsUnavailable = errors.New("pg_stat_statements is not installed")
+
+// Orders of TopStatements
+const (
+	OrderByTotalTime = "total"
+	OrderByMeanTime  = "mean"
+)
+
+// StatementStat is the execution statistics pg_stat_statements keeps for
+// one normalized statement, with constants replaced by $1, $2, ...
+type StatementStat struct {
+	QueryID int64  `json:"query_id"`
+	Query   string `json:"query"`
+	Calls   int64  `json:"calls"`
+	Rows    int64  `json:"rows"`
+	// TotalTimeMs and the other times cover execution only, not planning
+	TotalTimeMs  float64 `json:"total_time_ms"`
+	MeanTimeMs   float64 `json:"mean_time_ms"`
+	MaxTimeMs    float64 `json:"max_time_ms"`
+	StddevTimeMs float64 `json:"stddev_time_ms"`
+	// TimeShare is the statement's share of the execution time of all
+	// statements on the database
+	TimeShare float64 `json:"time_share"`
+	// CacheHitRatio is the share of blocks found in shared buffers
+	CacheHitRatio float64 `json:"cache_hit_ratio"`
+}
+
+// traceComment matches the comment request tracing prefixes queries with
+// (see tracing.SQLComment). pg_stat_statements keeps the text of the first
+// call, so the comment names whichever request happened to make it.
+var traceComment = regexp.MustCompile(`^/\*[^*]*\*/\s*`)
+
+// TopStatements returns the limit statements of the primary's database that
+// took the most total or mean execution time, by order (OrderByTotalTime or
+// OrderByMeanTime), since the statistics were last reset. It returns
+// ErrStatementsUnavailable without the pg_stat_statements extension.
+func TopStatements(ctx context.Context, order string, limit int) ([]StatementStat, error) {
+	var schema string
+	err := PrimaryDB.QueryRowContext(ctx,
+		`SELECT n.nspname FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname = 'pg_stat_statements'`,
+	).Scan(&schema)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStatementsUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy := "total_exec_time"
+	if order == OrderByMeanTime {
+		orderBy = "mean_exec_time"
+	}
+	// Heroku installs extensions in the heroku_ext schema, which may not be
+	// on the search path
+	view := pgx.Identifier{schema, "pg_stat_statements"}.Sanitize()
+	rows, err := PrimaryDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(queryid, 0), COALESCE(query, ''), calls, rows, total_exec_time, mean_exec_time, max_exec_time, stddev_exec_time,
+			COALESCE(total_exec_time / NULLIF(SUM(total_exec_time) OVER (), 0), 0),
+			shared_blks_hit, shared_blks_read
+		FROM %s
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		ORDER BY %s DESC
+		LIMIT $1`, view, orderBy),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := []StatementStat{}
+	for rows.Next() {
+		var statement StatementStat
+		var blksHit, blksRead int64
+		if err := rows.Scan(&statement.QueryID, &statement.Query, &statement.Calls, &statement.Rows,
+			&statement.TotalTimeMs, &statement.MeanTimeMs, &statement.MaxTimeMs, &statement.StddevTimeMs,
+			&statement.TimeShare, &blksHit, &blksRead); err != nil {
+			return nil, err
+		}
+		statement.Query = traceComment.ReplaceAllString(statement.Query, "")
+		statement.CacheHitRatio = cacheHitRatio(blksHit, blksRead)
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestTraceComment(t *testing.T) {
+	tests := map[string]string{
+		"/*request_id='abc',traceparent='00-1-2-01'*/ SELECT * FROM customers WHERE id = $1": "SELECT * FROM customers WHERE id = $1",
+		"SELECT 1 /* trailing */": "SELECT 1 /* trailing */",
+		"SELECT 1":                "SELECT 1",
+	}
+	for query, want := range tests {
+		if got := traceComment.ReplaceAllString(query, ""); got != want {
+			t.Errorf("Stripping %q gave %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestTopStatements(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	statements, err := TopStatements(context.Background(), OrderByMeanTime, 5)
+	if errors.Is(err, ErrStatementsUnavailable) {
+		t.Skip("pg_stat_statements not installed")
+	}
+	if err != nil {
+		t.Fatalf("Failed to read pg_stat_statements: %v", err)
+	}
+	if len(statements) > 5 {
+		t.Errorf("Expected at most 5 statements, got %d", len(statements))
+	}
+	for i := 1; i < len(statements); i++ {
+		if statements[i].MeanTimeMs > statements[i-1].MeanTimeMs {
+			t.Errorf("Expected statements by mean time, got %v after %v", statements[i].MeanTimeMs, statements[i-1].MeanTimeMs)
+		}
+	}
+}
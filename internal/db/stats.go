@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// databaseStatTimeout bounds how long DatabaseStats waits for each database,
+// so an unreachable follower doesn't hold up the report
+const databaseStatTimeout = 3 * time.Second
+
+// DatabaseStat is a point-in-time view of the database behind a pool
+type DatabaseStat struct {
+	Pool string `json:"pool"`
+	// Role is primary, or follower for a database in recovery
+	Role string `json:"role,omitempty"`
+	// LatencyMs is the round trip of a trivial query on an open connection
+	LatencyMs float64         `json:"latency_ms"`
+	Counters  *PgStatDatabase `json:"pg_stat_database,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// PgStatDatabase holds the pg_stat_database counters of the connected
+// database. They count since StatsReset on that server, so a follower's
+// counters only include the reads sent to it.
+type PgStatDatabase struct {
+	Database     string     `json:"database"`
+	Backends     int64      `json:"numbackends"`
+	XactCommit   int64      `json:"xact_commit"`
+	XactRollback int64      `json:"xact_rollback"`
+	BlksRead     int64      `json:"blks_read"`
+	BlksHit      int64      `json:"blks_hit"`
+	TupReturned  int64      `json:"tup_returned"`
+	TupFetched   int64      `json:"tup_fetched"`
+	TupInserted  int64      `json:"tup_inserted"`
+	TupUpdated   int64      `json:"tup_updated"`
+	TupDeleted   int64      `json:"tup_deleted"`
+	Conflicts    int64      `json:"conflicts"`
+	Deadlocks    int64      `json:"deadlocks"`
+	StatsReset   *time.Time `json:"stats_reset,omitempty"`
+	// CacheHitRatio is the share of block reads served from shared buffers
+	CacheHitRatio float64 `json:"cache_hit_ratio"`
+}
+
+// cacheHitRatio is the share of blocks found in shared buffers, or 0 before
+// any block was read
+func cacheHitRatio(hit, read int64) float64 {
+	if hit+read == 0 {
+		return 0
+	}
+	return float64(hit) / float64(hit+read)
+}
+
+// DatabaseStats measures the primary and, if it is a separate database, the
+// analytics follower. A database that can't be reached is reported with its
+// error rather than failing the whole report.
+func DatabaseStats(ctx context.Context) []DatabaseStat {
+	var stats []DatabaseStat
+	for _, pool := range []struct {
+		name string
+		db   *sql.DB
+	}{{"primary", PrimaryDB}, {"analytics", AnalyticsDB}} {
+		if pool.db == nil || (pool.name == "analytics" && pool.db == PrimaryDB) {
+			continue
+		}
+		stats = append(stats, databaseStat(ctx, pool.name, pool.db))
+	}
+	return stats
+}
+
+func databaseStat(ctx context.Context, name string, pool *sql.DB) DatabaseStat {
+	stat := DatabaseStat{Pool: name}
+	ctx, cancel := context.WithTimeout(ctx, databaseStatTimeout)
+	defer cancel()
+
+	// Acquire the connection first, so a pool that has to dial doesn't count
+	// the handshake as latency
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	defer conn.Close()
+
+	var inRecovery bool
+	started := time.Now()
+	if err := conn.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	stat.LatencyMs = float64(time.Since(started).Microseconds()) / 1000
+	stat.Role = "primary"
+	if inRecovery {
+		stat.Role = "follower"
+	}
+
+	var counters PgStatDatabase
+	var statsReset sql.NullTime
+	err = conn.QueryRowContext(ctx, `
+		SELECT datname, numbackends, xact_commit, xact_rollback, blks_read, blks_hit,
+			tup_returned, tup_fetched, tup_inserted, tup_updated, tup_deleted,
+			conflicts, deadlocks, stats_reset
+		FROM pg_stat_database WHERE datname = current_database()`,
+	).Scan(&counters.Database, &counters.Backends, &counters.XactCommit, &counters.XactRollback,
+		&counters.BlksRead, &counters.BlksHit, &counters.TupReturned, &counters.TupFetched,
+		&counters.TupInserted, &counters.TupUpdated, &counters.TupDeleted,
+		&counters.Conflicts, &counters.Deadlocks, &statsReset)
+	if err != nil {
+		stat.Error = err.Error()
+		return stat
+	}
+	if statsReset.Valid {
+		counters.StatsReset = &statsReset.Time
+	}
+	counters.CacheHitRatio = cacheHitRatio(counters.BlksHit, counters.BlksRead)
+	stat.Counters = &counters
+	return stat
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCacheHitRatio(t *testing.T) {
+	if got := cacheHitRatio(0, 0); got != 0 {
+		t.Errorf("Expected 0 before any block was read, got %v", got)
+	}
+	if got := cacheHitRatio(99, 1); got != 0.99 {
+		t.Errorf("Expected 0.99, got %v", got)
+	}
+}
+
+func TestDatabaseStatsWithoutPools(t *testing.T) {
+	primary, analytics := PrimaryDB, AnalyticsDB
+	PrimaryDB, AnalyticsDB = nil, nil
+	t.Cleanup(func() { PrimaryDB, AnalyticsDB = primary, analytics })
+
+	if stats := DatabaseStats(context.Background()); len(stats) != 0 {
+		t.Errorf("Expected no stats without pools, got %+v", stats)
+	}
+}
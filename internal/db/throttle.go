@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/progress"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Bulk writers (performance seeding, imports) check the primary's health
+// before each batch and pause while it is under pressure, so a large load
+// doesn't starve live traffic. The pause doubles while the primary answers
+// slowly or its pool is nearly exhausted, and halves again once it recovers.
+
+// throttleStep is the first pause, and the smallest kept when recovering
+const throttleStep = 100 * time.Millisecond
+
+var throttlePaused = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "db_bulk_throttle_paused_seconds_total",
+	Help: "Time bulk seeding and imports spent paused for the primary's health.",
+})
+
+// ThrottleConfig sets when bulk writers back off
+type ThrottleConfig struct {
+	// Latency of a trivial query above which the primary is under pressure
+	Latency time.Duration
+	// PoolUtilization is the share of the primary pool's connections in use
+	// above which it is under pressure
+	PoolUtilization float64
+	// MaxDelay caps the pause before a batch; 0 disables throttling
+	MaxDelay time.Duration
+	step     time.Duration
+}
+
+// ThrottleConfigFromEnv reads BULK_THROTTLE_LATENCY (default 50ms),
+// BULK_THROTTLE_POOL_UTILIZATION (default 0.8), and BULK_THROTTLE_MAX_DELAY
+// (default 5s, 0 disables throttling)
+func ThrottleConfigFromEnv() ThrottleConfig {
+	config := ThrottleConfig{
+		Latency:         getEnvDuration("BULK_THROTTLE_LATENCY", 50*time.Millisecond),
+		PoolUtilization: 0.8,
+		MaxDelay:        getEnvDuration("BULK_THROTTLE_MAX_DELAY", 5*time.Second),
+		step:            throttleStep,
+	}
+	if value := os.Getenv("BULK_THROTTLE_POOL_UTILIZATION"); value != "" {
+		utilization, err := strconv.ParseFloat(value, 64)
+		if err != nil || utilization <= 0 || utilization > 1 {
+			log.Printf("Warning: Invalid value for BULK_THROTTLE_POOL_UTILIZATION (%s), using default 0.8", value)
+		} else {
+			config.PoolUtilization = utilization
+		}
+	}
+	return config
+}
+
+// Throttle paces the batches of one bulk job. It is safe for concurrent use
+// by the job's workers.
+type Throttle struct {
+	config ThrottleConfig
+	job    *progress.Job
+
+	mu    sync.Mutex
+	state progress.Throttle
+	delay time.Duration
+}
+
+// NewThrottle returns a throttle configured from the environment that
+// reports its state to the job ctx carries, if any
+func NewThrottle(ctx context.Context) *Throttle {
+	return newThrottle(ThrottleConfigFromEnv(), progress.FromContext(ctx))
+}
+
+func newThrottle(config ThrottleConfig, job *progress.Job) *Throttle {
+	return &Throttle{config: config, job: job}
+}
+
+// primaryHealth measures the primary's latency and pool utilization; tests
+// replace it
+var primaryHealth = func(ctx context.Context) (time.Duration, float64, error) {
+	started := time.Now()
+	var one int
+	if err := PrimaryPgx.QueryRow(ctx, "SELECT 1").Scan(&one); err != nil {
+		return 0, 0, err
+	}
+	latency := time.Since(started)
+	stat := PrimaryPgx.Stat()
+	return latency, float64(stat.AcquiredConns()) / float64(stat.MaxConns()), nil
+}
+
+// Wait checks the primary's health and pauses before the next batch for as
+// long as the throttle has backed off to. It returns early with ctx's error
+// when ctx is done. A failed health check keeps the current pause.
+func (t *Throttle) Wait(ctx context.Context) error {
+	if t.config.MaxDelay <= 0 {
+		return ctx.Err()
+	}
+
+	latency, utilization, err := primaryHealth(ctx)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	delay := t.adjust(latency, utilization, err)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	started := time.Now()
+	select {
+	case <-ctx.Done():
+		t.paused(time.Since(started))
+		return ctx.Err()
+	case <-timer.C:
+		t.paused(delay)
+		return nil
+	}
+}
+
+// adjust updates the pause from a health check and returns it
+func (t *Throttle) adjust(latency time.Duration, utilization float64, err error) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Warning: Failed to check the primary's health, keeping the bulk load's %v pause: %v", t.delay, err)
+		return t.delay
+	}
+
+	reason := ""
+	switch {
+	case latency > t.config.Latency:
+		reason = "latency"
+	case utilization > t.config.PoolUtilization:
+		reason = "pool"
+	}
+	previous := t.delay
+	if reason != "" {
+		t.delay *= 2
+		if t.delay < t.config.step {
+			t.delay = t.config.step
+		}
+		if t.delay > t.config.MaxDelay {
+			t.delay = t.config.MaxDelay
+		}
+	} else {
+		t.delay /= 2
+		if t.delay < t.config.step {
+			t.delay = 0
+		}
+	}
+	if t.delay != previous {
+		log.Printf("Bulk load throttle: pausing %v between batches (latency %v, pool %.0f%% in use)", t.delay, latency.Round(time.Millisecond), utilization*100)
+	}
+
+	t.state.DelayMs = t.delay.Milliseconds()
+	t.state.Reason = reason
+	t.state.LatencyMs = float64(latency.Microseconds()) / 1000
+	t.state.PoolUtilization = utilization
+	t.report()
+	return t.delay
+}
+
+// paused records time spent paused
+func (t *Throttle) paused(d time.Duration) {
+	throttlePaused.Add(d.Seconds())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.PausedMs += d.Milliseconds()
+	t.report()
+}
+
+// State returns the throttle's current state
+func (t *Throttle) State() progress.Throttle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// report sends the state to the job. Callers must hold t.mu.
+func (t *Throttle) report() {
+	if t.job != nil {
+		t.job.SetThrottle(t.state)
+	}
+}
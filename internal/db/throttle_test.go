@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/progress"
+)
+
+func TestThrottle(t *testing.T) {
+	previous := primaryHealth
+	t.Cleanup(func() { primaryHealth = previous })
+	latency, utilization := time.Millisecond, 0.1
+	var healthErr error
+	primaryHealth = func(ctx context.Context) (time.Duration, float64, error) {
+		return latency, utilization, healthErr
+	}
+
+	job := progress.Start("test")
+	throttle := newThrottle(ThrottleConfig{Latency: 50 * time.Millisecond, PoolUtilization: 0.8, MaxDelay: 8 * time.Millisecond, step: 2 * time.Millisecond}, job)
+	wait := func() progress.Throttle {
+		t.Helper()
+		if err := throttle.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+		return throttle.State()
+	}
+
+	if state := wait(); state.DelayMs != 0 || state.Reason != "" {
+		t.Errorf("Expected no pause while healthy, got %+v", state)
+	}
+
+	latency = 80 * time.Millisecond
+	delays := []int64{wait().DelayMs, wait().DelayMs, wait().DelayMs, wait().DelayMs}
+	if delays[0] != 2 || delays[1] != 4 || delays[2] != 8 || delays[3] != 8 {
+		t.Errorf("Expected the pause to double up to 8ms, got %v", delays)
+	}
+	if state := throttle.State(); state.Reason != "latency" || state.LatencyMs != 80 || state.PausedMs < 20 {
+		t.Errorf("Expected to be backing off from latency after 22ms paused, got %+v", state)
+	}
+
+	latency, utilization = time.Millisecond, 0.95
+	if state := wait(); state.DelayMs != 8 || state.Reason != "pool" || state.PoolUtilization != 0.95 {
+		t.Errorf("Expected to keep backing off from the pool, got %+v", state)
+	}
+
+	healthErr = errors.New("connection refused")
+	if state := wait(); state.DelayMs != 8 {
+		t.Errorf("Expected a failed check to keep the pause, got %+v", state)
+	}
+
+	healthErr, utilization = nil, 0.1
+	delays = []int64{wait().DelayMs, wait().DelayMs, wait().DelayMs}
+	if delays[0] != 4 || delays[1] != 2 || delays[2] != 0 {
+		t.Errorf("Expected the pause to halve back to none, got %v", delays)
+	}
+	if snapshot := job.Snapshot(); snapshot.Throttle == nil || snapshot.Throttle.PausedMs != throttle.State().PausedMs {
+		t.Errorf("Expected the job to show the throttle, got %+v", snapshot.Throttle)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	latency = 80 * time.Millisecond
+	if err := throttle.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context's error while pausing, got %v", err)
+	}
+}
+
+func TestThrottleDisabled(t *testing.T) {
+	previous := primaryHealth
+	t.Cleanup(func() { primaryHealth = previous })
+	primaryHealth = func(ctx context.Context) (time.Duration, float64, error) {
+		t.Error("Expected no health check while throttling is disabled")
+		return 0, 0, nil
+	}
+
+	t.Setenv("BULK_THROTTLE_MAX_DELAY", "0")
+	if err := NewThrottle(context.Background()).Wait(context.Background()); err != nil {
+		t.Errorf("Wait failed: %v", err)
+	}
+}
+
+func TestThrottleConfigFromEnv(t *testing.T) {
+	t.Setenv("BULK_THROTTLE_LATENCY", "20ms")
+	t.Setenv("BULK_THROTTLE_POOL_UTILIZATION", "0.5")
+	if config := ThrottleConfigFromEnv(); config.Latency != 20*time.Millisecond || config.PoolUtilization != 0.5 || config.MaxDelay != 5*time.Second {
+		t.Errorf("Unexpected config %+v", config)
+	}
+	t.Setenv("BULK_THROTTLE_POOL_UTILIZATION", "80")
+	if config := ThrottleConfigFromEnv(); config.PoolUtilization != 0.8 {
+		t.Errorf("Expected the default for an out of range utilization, got %v", config.PoolUtilization)
+	}
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"saas-go-app/internal/chaos"
+	"saas-go-app/internal/tracing"
+)
+
+// tracedConnector wraps the pgx connector so queries run with a traced
+// context are prefixed with a comment naming the request (see
+// tracing.SQLComment)
+type tracedConnector struct {
+	driver.Connector
+	// pool is the *sql.DB opened with the connector, which its connections
+	// record their queries against (see QueryStats)
+	pool *sql.DB
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, pool: c.pool}, nil
+}
+
+// tracedConn forwards to the pgx connection, annotating queries on the way,
+// passing on the actor for the audit log, applying any faults switched on
+// through the chaos endpoints, and recording queries in the context's
+// QueryStats. Queries registered with Prepared run as prepared statements
+// instead.
+type tracedConn struct {
+	driver.Conn
+	pool *sql.DB
+}
+
+func annotate(ctx context.Context, query string) string {
+	if comment := tracing.SQLComment(ctx); comment != "" {
+		return comment + " " + query
+	}
+	return query
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.record(ctx, time.Now())
+	if err := c.setActor(ctx); err != nil {
+		return nil, err
+	}
+	if rows, ok, err := c.queryPrepared(ctx, query, args); ok {
+		return rows, err
+	}
+	if err := chaos.DB(ctx); err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, annotate(ctx, query), args)
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.record(ctx, time.Now())
+	if err := c.setActor(ctx); err != nil {
+		return nil, err
+	}
+	if result, ok, err := c.execPrepared(ctx, query, args); ok {
+		return result, err
+	}
+	if err := chaos.DB(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, annotate(ctx, query), args)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, annotate(ctx, query))
+	}
+	return c.Conn.Prepare(annotate(ctx, query))
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return actorTx{Tx: tx, conn: c}, nil
+}
+
+// CheckNamedValue lets pgx encode arguments database/sql doesn't know, such
+// as slices for array parameters
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
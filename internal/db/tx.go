@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var txRetries = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "db_tx_retries_total",
+	Help: "Transactions run by db.WithTx that were retried after a serialization failure.",
+})
+
+// txRetry bounds how often WithTx reruns a transaction. Conflicts clear up
+// quickly, so the backoff is short and there are only a few attempts.
+var txRetry = ConnectRetry{Attempts: 5, BaseBackoff: 10 * time.Millisecond, MaxBackoff: 200 * time.Millisecond}
+
+// savepointSeq numbers savepoints so nested ones never share a name
+var savepointSeq atomic.Uint64
+
+// WithTx runs fn in a transaction on the primary. The transaction commits if
+// fn returns nil and rolls back otherwise, including when fn panics. If
+// Postgres aborts it with a serialization failure (40001), which happens when
+// concurrent REPEATABLE READ or SERIALIZABLE transactions conflict, fn is run
+// again in a new transaction, a few times with a short backoff. fn may
+// therefore run more than once and must not have side effects outside the
+// transaction. Use Savepoint inside fn for steps that may fail on their own.
+func WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions is WithTx with an isolation level or read-only transaction
+func WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, opts, fn)
+		if err == nil || !serializationFailure(err) || attempt == txRetry.Attempts {
+			return err
+		}
+		txRetries.Inc()
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(txRetry.delay(attempt)):
+		}
+	}
+}
+
+// runTx runs fn in one transaction
+func runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := PrimaryDB.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Savepoint runs fn inside a savepoint of tx. If fn returns an error (or
+// panics), only its statements are rolled back and tx stays usable, so the
+// caller can carry on or handle the error. Savepoints nest: fn may call
+// Savepoint again with the same tx.
+func Savepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) (err error) {
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// serializationFailure reports whether err is a Postgres serialization failure
+func serializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
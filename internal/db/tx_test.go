@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestSerializationFailure(t *testing.T) {
+	if !serializationFailure(fmt.Errorf("insert: %w", &pgconn.PgError{Code: "40001"})) {
+		t.Error("Expected wrapped 40001 to be a serialization failure")
+	}
+	if serializationFailure(&pgconn.PgError{Code: "23505"}) || serializationFailure(errors.New("40001")) {
+		t.Error("Expected other errors not to be serialization failures")
+	}
+}
+
+func TestWithTxRetriesAndSavepoints(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer CloseDB()
+
+	if err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	ctx := context.Background()
+
+	// A serialization failure reruns the whole transaction
+	attempts := 0
+	err := WithTx(ctx, func(tx *sql.Tx) error {
+		attempts++
+		if attempts < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success on the third attempt, got %v after %d", err, attempts)
+	}
+
+	// A failed savepoint is rolled back without aborting the transaction
+	email := "tx-" + time.Now().Format("20060102150405.000000") + "@example.com"
+	var id int
+	err = WithTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "INSERT INTO customers (name, email) VALUES ('Outer', $1) RETURNING id", email).Scan(&id); err != nil {
+			return err
+		}
+		nestedErr := Savepoint(ctx, tx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, "UPDATE customers SET name = 'Inner' WHERE id = $1", id); err != nil {
+				return err
+			}
+			return Savepoint(ctx, tx, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "INSERT INTO customers (name, email) VALUES ('Duplicate', $1)", email)
+				return err
+			})
+		})
+		if nestedErr == nil {
+			return errors.New("expected the duplicate insert to fail")
+		}
+		return Savepoint(ctx, tx, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE customers SET name = 'Committed' WHERE id = $1", id)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Expected the transaction to commit, got %v", err)
+	}
+	defer PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", id)
+
+	var name string
+	if err := PrimaryDB.QueryRow("SELECT name FROM customers WHERE id = $1", id).Scan(&name); err != nil {
+		t.Fatalf("Failed to read customer: %v", err)
+	}
+	if name != "Committed" {
+		t.Errorf("Expected name Committed, got %q", name)
+	}
+}
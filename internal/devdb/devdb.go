@@ -0,0 +1,171 @@
+// Package devdb starts a throwaway local Postgres for development so the
+// server can run with zero setup when no DATABASE_URL is configured.
+package devdb
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Wanted reports whether an embedded database should be started: no database
+// is configured, we're not running on a Heroku dyno, and DEV_EMBEDDED_DB
+// hasn't been set to false. DEV_EMBEDDED_DB=true forces it on.
+func Wanted() bool {
+	if os.Getenv("DEV_EMBEDDED_DB") == "false" {
+		return false
+	}
+	if os.Getenv("DEV_EMBEDDED_DB") == "true" {
+		return true
+	}
+	for _, envVar := range os.Environ() {
+		if strings.HasPrefix(envVar, "HEROKU_POSTGRESQL_") {
+			return false
+		}
+	}
+	return os.Getenv("DATABASE_URL") == "" && os.Getenv("DYNO") == ""
+}
+
+// Start launches an ephemeral Postgres using local binaries (initdb/pg_ctl on
+// PATH or in PG_BIN_DIR), falling back to Docker. It points DATABASE_URL at the
+// new instance and enables SEED_DATA so the demo profile is loaded. The returned
+// function stops the instance; it is also called on SIGINT/SIGTERM.
+func Start() (func(), error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a free port: %w", err)
+	}
+
+	var stop func()
+	if binDir, ok := findPostgresBinaries(); ok {
+		stop, err = startLocal(binDir, port)
+	} else if _, lookErr := exec.LookPath("docker"); lookErr == nil {
+		stop, err = startDocker(port)
+	} else {
+		return nil, fmt.Errorf("no local Postgres binaries (initdb, pg_ctl) or docker found; install Postgres or set DATABASE_URL")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	databaseURL := fmt.Sprintf("postgres://postgres@localhost:%d/postgres?sslmode=disable", port)
+	if err := waitForReady(databaseURL, 30*time.Second); err != nil {
+		stop()
+		return nil, err
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	if os.Getenv("SEED_DATA") == "" {
+		os.Setenv("SEED_DATA", "true")
+	}
+	log.Printf("Embedded development database ready at %s", databaseURL)
+
+	// router.Run never returns on Ctrl-C, so stop Postgres from a signal handler
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stop()
+		os.Exit(0)
+	}()
+
+	return stop, nil
+}
+
+func findPostgresBinaries() (string, bool) {
+	if dir := os.Getenv("PG_BIN_DIR"); dir != "" {
+		return dir, true
+	}
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", false
+	}
+	if _, err := exec.LookPath("pg_ctl"); err != nil {
+		return "", false
+	}
+	return filepath.Dir(initdb), true
+}
+
+func startLocal(binDir string, port int) (func(), error) {
+	dataDir, err := os.MkdirTemp("", "saas-go-app-devdb-")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Starting embedded Postgres from %s on port %d...", binDir, port)
+	initdb := exec.Command(filepath.Join(binDir, "initdb"), "-D", dataDir, "-U", "postgres", "--auth=trust", "--encoding=UTF8")
+	if output, err := initdb.CombinedOutput(); err != nil {
+		os.RemoveAll(dataDir)
+		return nil, fmt.Errorf("initdb failed: %w: %s", err, output)
+	}
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=localhost", port, dataDir)
+	start := exec.Command(filepath.Join(binDir, "pg_ctl"), "-D", dataDir, "-o", options, "-l", filepath.Join(dataDir, "postgres.log"), "-w", "start")
+	if output, err := start.CombinedOutput(); err != nil {
+		os.RemoveAll(dataDir)
+		return nil, fmt.Errorf("pg_ctl start failed: %w: %s", err, output)
+	}
+
+	return func() {
+		log.Println("Stopping embedded Postgres...")
+		exec.Command(filepath.Join(binDir, "pg_ctl"), "-D", dataDir, "-m", "fast", "-w", "stop").Run()
+		os.RemoveAll(dataDir)
+	}, nil
+}
+
+func startDocker(port int) (func(), error) {
+	name := fmt.Sprintf("saas-go-app-devdb-%d", port)
+	log.Printf("Starting Postgres container %s on port %d...", name, port)
+
+	run := exec.Command("docker", "run", "-d", "--rm", "--name", name,
+		"-e", "POSTGRES_HOST_AUTH_METHOD=trust",
+		"-p", fmt.Sprintf("%d:5432", port),
+		"postgres:16-alpine")
+	if output, err := run.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("docker run failed: %w: %s", err, output)
+	}
+
+	return func() {
+		log.Printf("Stopping Postgres container %s...", name)
+		exec.Command("docker", "stop", name).Run()
+	}, nil
+}
+
+func waitForReady(databaseURL string, timeout time.Duration) error {
+	conn, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := conn.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("embedded database not ready after %v: %w", timeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,255 @@
+// Package events publishes domain events to subscribed webhook endpoints.
+// Publishing writes one delivery row per matching endpoint (an outbox), and a
+// background dispatcher POSTs pending deliveries with retries, so callers never
+// wait on a subscriber and events survive restarts.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/httpclient"
+	"saas-go-app/internal/tracing"
+)
+
+// Event types
+const (
+	UserRegistered      = "user.registered"
+	UserLockedOut       = "user.locked_out"
+	UserPasswordChanged = "user.password_changed"
+	APIKeyCreated       = "api_key.created"
+	OrganizationCreated = "organization.created"
+	UserInvited         = "user.invited"
+	UserNewLogin        = "user.new_login"
+	RegistrationFlagged = "registration.flagged"
+	APIKeyIPDenied      = "api_key.ip_denied"
+)
+
+// Types lists every event type endpoints can subscribe to
+var Types = []string{UserRegistered, UserLockedOut, UserPasswordChanged, APIKeyCreated, OrganizationCreated, UserInvited, UserNewLogin, RegistrationFlagged, APIKeyIPDenied}
+
+// Deliveries are retried with exponential backoff up to this many attempts
+const maxAttempts = 8
+
+// Envelope is the JSON body POSTed to webhook endpoints
+type Envelope struct {
+	ID        int64       `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Publish queues an event for every active endpoint subscribed to its type.
+// Failures are logged rather than returned so events never fail the request
+// that caused them.
+func Publish(ctx context.Context, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to encode %s event: %v", eventType, err)
+		return
+	}
+
+	trace := tracing.FromContext(ctx)
+	_, err = db.Primary(ctx).Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload, request_id, traceparent)
+		SELECT id, $1, $2, $3, $4 FROM webhook_endpoints
+		WHERE active AND (cardinality(events) = 0 OR $1 = ANY(events))`,
+		eventType, payload, trace.RequestID, trace.Traceparent,
+	)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: Failed to queue %s event: %v", eventType, err)
+	}
+}
+
+// Sign returns the X-Webhook-Signature value for a body: sha256=<hex HMAC>.
+// Receivers verify it by computing the same HMAC with their endpoint secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a signing secret for a new endpoint
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// DispatchInterval returns how often pending deliveries are sent.
+// Configure with WEBHOOK_DISPATCH_INTERVAL (default: 5s).
+func DispatchInterval() time.Duration {
+	value := os.Getenv("WEBHOOK_DISPATCH_INTERVAL")
+	if value == "" {
+		return 5 * time.Second
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Warning: Invalid value for WEBHOOK_DISPATCH_INTERVAL (%s), using default 5s", value)
+		return 5 * time.Second
+	}
+	return interval
+}
+
+// Dispatcher delivers queued events
+type Dispatcher struct {
+	client *httpclient.Client
+}
+
+// NewDispatcher creates a dispatcher using the shared webhooks HTTP client
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{client: httpclient.New("webhooks", httpclient.Options{MaxRetries: -1})}
+}
+
+// Start delivers pending events every interval until ctx is done
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.DeliverPending(ctx); err != nil {
+					log.Printf("Warning: Webhook delivery failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+type delivery struct {
+	id          int64
+	eventType   string
+	payload     []byte
+	createdAt   time.Time
+	attempts    int
+	requestID   string
+	traceparent string
+	url         string
+	secret      string
+}
+
+// DeliverPending sends a batch of due deliveries. Rows are claimed with
+// SKIP LOCKED so several dynos can dispatch without double delivery.
+func (d *Dispatcher) DeliverPending(ctx context.Context) error {
+	tx, err := db.Primary(ctx).Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.id, d.event_type, d.payload, d.created_at, d.attempts,
+			COALESCE(d.request_id, ''), COALESCE(d.traceparent, ''), e.url, e.secret
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+		ORDER BY d.next_attempt_at
+		LIMIT 20
+		FOR UPDATE OF d SKIP LOCKED`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var batch []delivery
+	for rows.Next() {
+		var item delivery
+		if err := rows.Scan(&item.id, &item.eventType, &item.payload, &item.createdAt, &item.attempts,
+			&item.requestID, &item.traceparent, &item.url, &item.secret); err != nil {
+			rows.Close()
+			return err
+		}
+		batch = append(batch, item)
+	}
+	rows.Close()
+
+	for _, item := range batch {
+		deliveryErr := d.send(ctx, item)
+		attempts := item.attempts + 1
+
+		switch {
+		case deliveryErr == nil:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET status = 'delivered', attempts = $2, last_error = NULL, delivered_at = NOW() WHERE id = $1",
+				item.id, attempts)
+		case attempts >= maxAttempts:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET status = 'failed', attempts = $2, last_error = $3 WHERE id = $1",
+				item.id, attempts, deliveryErr.Error())
+		default:
+			// 30s, 1m, 2m, 4m, ... between attempts
+			backoff := time.Duration(30<<item.attempts) * time.Second
+			_, err = tx.ExecContext(ctx,
+				"UPDATE webhook_deliveries SET attempts = $2, last_error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 second' WHERE id = $1",
+				item.id, attempts, deliveryErr.Error(), backoff.Seconds())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update webhook delivery %d: %w", item.id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d *Dispatcher) send(ctx context.Context, item delivery) error {
+	body, err := json.Marshal(Envelope{
+		ID:        item.id,
+		Type:      item.eventType,
+		CreatedAt: item.createdAt,
+		Data:      json.RawMessage(item.payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deliveries carry the request ID of the request that raised the event
+	ctx = tracing.NewContext(ctx, tracing.Trace{RequestID: item.requestID, Traceparent: item.traceparent})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", item.eventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(item.id, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(item.secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// ValidType reports whether eventType is a known event type
+func ValidType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
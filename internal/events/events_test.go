@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendSignsBody(t *testing.T) {
+	var gotSignature, gotEvent, gotRequestID string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Webhook-Signature")
+		gotEvent = r.Header.Get("X-Webhook-Event")
+		gotRequestID = r.Header.Get("X-Request-ID")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher()
+	err := d.send(context.Background(), delivery{
+		id:        42,
+		eventType: UserRegistered,
+		payload:   []byte(`{"username":"alice"}`),
+		createdAt: time.Now(),
+		requestID: "req-123",
+		url:       server.URL,
+		secret:    "whsec_test",
+	})
+	if err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+
+	if gotSignature != Sign("whsec_test", gotBody) {
+		t.Errorf("Signature %q does not match body", gotSignature)
+	}
+	if gotEvent != UserRegistered {
+		t.Errorf("Expected event header %s, got %s", UserRegistered, gotEvent)
+	}
+	if gotRequestID != "req-123" {
+		t.Errorf("Expected originating request ID, got %q", gotRequestID)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(gotBody, &envelope); err != nil {
+		t.Fatalf("Invalid envelope: %v", err)
+	}
+	if envelope.ID != 42 || envelope.Type != UserRegistered {
+		t.Errorf("Unexpected envelope %+v", envelope)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := NewDispatcher().send(context.Background(), delivery{id: 1, eventType: UserLockedOut, payload: []byte(`{}`), url: server.URL, secret: "s"})
+	if err == nil {
+		t.Error("Expected a 400 response to fail the delivery")
+	}
+}
+
+func TestValidType(t *testing.T) {
+	if !ValidType(APIKeyCreated) {
+		t.Errorf("Expected %s to be valid", APIKeyCreated)
+	}
+	if ValidType("user.deleted") {
+		t.Error("Expected unknown event type to be invalid")
+	}
+}
@@ -0,0 +1,134 @@
+// Package forecast projects daily counts, such as signups, with a linear
+// trend plus a day-of-week seasonal component fitted by least squares.
+// Prediction intervals assume normally distributed residuals, which is rough
+// for small counts but good enough for a dashboard.
+package forecast
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// MinHistory is the fewest days Fit accepts: two full weeks, so every weekday
+// is seen at least twice
+const MinHistory = 14
+
+// ErrTooLittleHistory is returned by Fit for fewer than MinHistory days
+var ErrTooLittleHistory = errors.New("forecast: at least 14 days of history required")
+
+// Point is the count observed on one day
+type Point struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// Projection is the forecast for one day with its prediction interval
+type Projection struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+	Lower float64   `json:"lower"`
+	Upper float64   `json:"upper"`
+}
+
+// Model is a fitted trend with weekly seasonality
+type Model struct {
+	// Slope is the trend's change per day
+	Slope float64
+	// Intercept is the trend's value on the first day of history
+	Intercept float64
+	// Seasonal is the average offset from the trend per weekday, indexed by
+	// time.Weekday
+	Seasonal [7]float64
+	// Residual is the standard deviation of what trend and season don't explain
+	Residual float64
+
+	start time.Time
+	n     int
+	meanX float64
+	sxx   float64
+}
+
+// Fit fits a model to consecutive daily points, oldest first
+func Fit(history []Point) (Model, error) {
+	n := len(history)
+	if n < MinHistory {
+		return Model{}, ErrTooLittleHistory
+	}
+
+	// Linear trend by ordinary least squares over day offsets
+	var meanX, meanY float64
+	for i, point := range history {
+		meanX += float64(i)
+		meanY += point.Value
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+	var sxy, sxx float64
+	for i, point := range history {
+		dx := float64(i) - meanX
+		sxy += dx * (point.Value - meanY)
+		sxx += dx * dx
+	}
+	m := Model{start: history[0].Date, n: n, meanX: meanX, sxx: sxx}
+	m.Slope = sxy / sxx
+	m.Intercept = meanY - m.Slope*meanX
+
+	// Seasonal offsets are the mean detrended value per weekday, centered so
+	// they don't shift the trend
+	var sums, counts [7]float64
+	for i, point := range history {
+		weekday := point.Date.Weekday()
+		sums[weekday] += point.Value - m.trend(float64(i))
+		counts[weekday]++
+	}
+	var meanSeasonal float64
+	for weekday := range sums {
+		m.Seasonal[weekday] = sums[weekday] / counts[weekday]
+		meanSeasonal += m.Seasonal[weekday] / 7
+	}
+	for weekday := range m.Seasonal {
+		m.Seasonal[weekday] -= meanSeasonal
+	}
+
+	// Two trend and six free seasonal parameters were estimated
+	var sse float64
+	for i, point := range history {
+		residual := point.Value - m.predict(float64(i), point.Date)
+		sse += residual * residual
+	}
+	if dof := n - 8; dof > 0 {
+		m.Residual = math.Sqrt(sse / float64(dof))
+	}
+	return m, nil
+}
+
+func (m Model) trend(x float64) float64 {
+	return m.Intercept + m.Slope*x
+}
+
+func (m Model) predict(x float64, date time.Time) float64 {
+	return m.trend(x) + m.Seasonal[date.Weekday()]
+}
+
+// Project forecasts the days days after the last day of history. Bounds are
+// the prediction interval at z standard errors (1.96 for 95%), which widens
+// the further out it goes. Counts can't be negative, so values and bounds are
+// clamped at 0.
+func (m Model) Project(days int, z float64) []Projection {
+	projections := make([]Projection, 0, days)
+	for i := 0; i < days; i++ {
+		x := float64(m.n + i)
+		date := m.start.AddDate(0, 0, m.n+i)
+		value := m.predict(x, date)
+		dx := x - m.meanX
+		margin := z * m.Residual * math.Sqrt(1+1/float64(m.n)+dx*dx/m.sxx)
+		projections = append(projections, Projection{
+			Date:  date,
+			Value: math.Max(0, value),
+			Lower: math.Max(0, value-margin),
+			Upper: math.Max(0, value+margin),
+		})
+	}
+	return projections
+}
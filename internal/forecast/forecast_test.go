@@ -0,0 +1,98 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// series builds daily points starting on a Monday from value(day index)
+func series(days int, value func(i int, weekday time.Weekday) float64) []Point {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]Point, days)
+	for i := range points {
+		date := start.AddDate(0, 0, i)
+		points[i] = Point{Date: date, Value: value(i, date.Weekday())}
+	}
+	return points
+}
+
+func TestFitLinearTrend(t *testing.T) {
+	history := series(28, func(i int, _ time.Weekday) float64 { return 10 + 2*float64(i) })
+
+	model, err := Fit(history)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if math.Abs(model.Slope-2) > 1e-9 || math.Abs(model.Intercept-10) > 1e-9 {
+		t.Errorf("Expected slope 2 and intercept 10, got %v and %v", model.Slope, model.Intercept)
+	}
+	if model.Residual > 1e-9 {
+		t.Errorf("Expected a perfect fit, got residual %v", model.Residual)
+	}
+
+	projections := model.Project(3, 1.96)
+	if len(projections) != 3 {
+		t.Fatalf("Expected 3 projections, got %d", len(projections))
+	}
+	if math.Abs(projections[0].Value-66) > 1e-9 {
+		t.Errorf("Expected 66 on day 28, got %v", projections[0].Value)
+	}
+	if !projections[0].Date.Equal(history[27].Date.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the forecast to start the day after history, got %v", projections[0].Date)
+	}
+}
+
+func TestFitWeeklySeason(t *testing.T) {
+	// Flat 20 a day with weekend dips, plus a little noise
+	history := series(56, func(i int, weekday time.Weekday) float64 {
+		value := 20.0
+		if weekday == time.Saturday || weekday == time.Sunday {
+			value = 6
+		}
+		return value + float64(i%3-1)
+	})
+
+	model, err := Fit(history)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	if model.Seasonal[time.Sunday] >= 0 || model.Seasonal[time.Wednesday] <= 0 {
+		t.Errorf("Expected weekends below trend and weekdays above, got %v", model.Seasonal)
+	}
+
+	projections := model.Project(14, 1.96)
+	for _, projection := range projections {
+		if projection.Lower > projection.Value || projection.Upper < projection.Value {
+			t.Errorf("Expected %v within its bounds", projection)
+		}
+		if weekday := projection.Date.Weekday(); weekday == time.Saturday && projection.Value > 10 {
+			t.Errorf("Expected a Saturday dip, got %v", projection.Value)
+		}
+	}
+	first, last := projections[0], projections[len(projections)-1]
+	if last.Upper-last.Value <= first.Upper-first.Value {
+		t.Errorf("Expected the interval to widen over the horizon")
+	}
+}
+
+func TestFitClampsAtZero(t *testing.T) {
+	history := series(21, func(i int, _ time.Weekday) float64 { return math.Max(0, 30-2*float64(i)) })
+
+	model, err := Fit(history)
+	if err != nil {
+		t.Fatalf("Fit failed: %v", err)
+	}
+	for _, projection := range model.Project(30, 1.96) {
+		if projection.Value < 0 || projection.Lower < 0 {
+			t.Errorf("Expected no negative counts, got %+v", projection)
+		}
+	}
+}
+
+func TestFitTooLittleHistory(t *testing.T) {
+	if _, err := Fit(series(13, func(int, time.Weekday) float64 { return 1 })); !errors.Is(err, ErrTooLittleHistory) {
+		t.Errorf("Expected ErrTooLittleHistory, got %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// Package httpclient is the HTTP client shared by all outbound integrations
+// (webhooks, payment, CRM, exchange rates). It adds timeouts, retries with
+// backoff under a retry budget, a circuit breaker per host, trace propagation,
+// and Prometheus metrics labelled by destination.
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/chaos"
+	"saas-go-app/internal/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrCircuitOpen is returned without making a request while a host's breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "httpclient_requests_total",
+		Help: "Outbound HTTP requests by destination and result (status code, error, or circuit_open).",
+	}, []string{"destination", "result"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "httpclient_request_duration_seconds",
+		Help:    "Outbound HTTP request latency per attempt.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"destination"})
+
+	retriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "httpclient_retries_total",
+		Help: "Outbound HTTP retries by destination.",
+	}, []string{"destination"})
+
+	circuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "httpclient_circuit_open",
+		Help: "1 while the circuit breaker for a destination host is open.",
+	}, []string{"destination", "host"})
+)
+
+// Options configure a Client. Zero values fall back to the defaults below.
+type Options struct {
+	// Timeout bounds each attempt, including reading response headers (default 10s)
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt (default 2)
+	MaxRetries int
+	// BaseBackoff is the first retry delay, doubled on each retry with jitter (default 200ms)
+	BaseBackoff time.Duration
+	// MaxBackoff caps retry delays, including server-provided Retry-After (default 5s)
+	MaxBackoff time.Duration
+	// RetryRatio is the fraction of requests that may be retried, so retries
+	// can't multiply load on a struggling destination (default 0.2)
+	RetryRatio float64
+	// FailureThreshold is the number of consecutive failures that opens a host's breaker (default 5)
+	FailureThreshold int
+	// Cooldown is how long a breaker stays open before a trial request (default 30s)
+	Cooldown time.Duration
+	// Transport is the underlying transport (default http.DefaultTransport)
+	Transport http.RoundTripper
+}
+
+// Client is an http.Client wrapper for a single destination, e.g. "webhooks" or "stripe"
+type Client struct {
+	destination string
+	opts        Options
+	http        *http.Client
+	budget      *retryBudget
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New creates a client for the named destination
+func New(destination string, opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	} else if opts.MaxRetries == 0 {
+		opts.MaxRetries = 2
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 200 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Second
+	}
+	if opts.RetryRatio <= 0 {
+		opts.RetryRatio = 0.2
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+
+	return &Client{
+		destination: destination,
+		opts:        opts,
+		http: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: &tracing.Transport{Base: opts.Transport},
+		},
+		budget:   &retryBudget{ratio: opts.RetryRatio, tokens: 10, max: 10},
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Do sends the request, retrying network errors, 429s, and 5xx gateway errors.
+// Requests with a body are only retried if req.GetBody is set (as it is for
+// bodies built by http.NewRequest from bytes or strings readers). Callers own
+// closing the returned body.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	b := c.breaker(host)
+	if chaos.CircuitOpen(c.destination) || !b.allow(time.Now()) {
+		requestsTotal.WithLabelValues(c.destination, "circuit_open").Inc()
+		return nil, fmt.Errorf("%s %s: %w", c.destination, host, ErrCircuitOpen)
+	}
+
+	c.budget.deposit()
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.Body != nil && req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.http.Do(req)
+		requestDuration.WithLabelValues(c.destination).Observe(time.Since(start).Seconds())
+
+		retryable, delay := c.classify(resp, err, attempt)
+		if err != nil {
+			requestsTotal.WithLabelValues(c.destination, "error").Inc()
+		} else {
+			requestsTotal.WithLabelValues(c.destination, strconv.Itoa(resp.StatusCode)).Inc()
+		}
+
+		if retryable {
+			c.recordFailure(b, host)
+		} else {
+			b.success()
+			circuitOpen.WithLabelValues(c.destination, host).Set(0)
+		}
+
+		canRetry := retryable &&
+			attempt < c.opts.MaxRetries &&
+			(req.Body == nil || req.GetBody != nil) &&
+			b.allow(time.Now()) &&
+			c.budget.withdraw()
+		if !canRetry {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		retriesTotal.WithLabelValues(c.destination).Inc()
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// classify reports whether an attempt should be retried and how long to wait
+func (c *Client) classify(resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	backoff := c.opts.BaseBackoff << attempt
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+	if backoff > c.opts.MaxBackoff {
+		backoff = c.opts.MaxBackoff
+	}
+
+	if err != nil {
+		return true, backoff
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			backoff = time.Duration(seconds) * time.Second
+			if backoff > c.opts.MaxBackoff {
+				backoff = c.opts.MaxBackoff
+			}
+		}
+		return true, backoff
+	}
+	return false, 0
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{threshold: c.opts.FailureThreshold, cooldown: c.opts.Cooldown}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+func (c *Client) recordFailure(b *breaker, host string) {
+	if b.failure(time.Now()) {
+		circuitOpen.WithLabelValues(c.destination, host).Set(1)
+	}
+}
+
+// breaker opens after threshold consecutive failures and allows a single
+// trial request once the cooldown has passed
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+// failure records a failed attempt and reports whether the breaker is now open
+func (b *breaker) failure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+// retryBudget lets retries make up at most ratio of requests: every request
+// deposits ratio tokens and every retry withdraws one
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+	max    float64
+}
+
+func (r *retryBudget) deposit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens += r.ratio
+	if r.tokens > r.max {
+		r.tokens = r.max
+	}
+}
+
+func (r *retryBudget) withdraw() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
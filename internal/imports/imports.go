@@ -0,0 +1,349 @@
+// Package imports handles large CSV imports. Files are uploaded in chunks as
+// an S3 multipart upload that can be resumed after an interruption: each chunk
+// is validated against its SHA-256 and recorded, so a client only re-sends the
+// parts that are missing. Once every part is in, the object is assembled in S3
+// and an import job loads it into the database. Customers and accounts can
+// also be streamed as JSON Lines and loaded as they arrive (see Stream).
+package imports
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/progress"
+)
+
+// Upload statuses
+const (
+	StatusUploading = "uploading"
+	StatusImporting = "importing"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusAborted   = "aborted"
+)
+
+// Chunk limits follow S3 multipart uploads: every part but the last must be
+// at least 5 MiB, and an upload has at most 10,000 parts
+const (
+	MinChunkSize     int64 = 5 << 20
+	MaxChunkSize     int64 = 64 << 20
+	DefaultChunkSize int64 = 8 << 20
+	MaxParts               = 10000
+)
+
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrNotUploading     = errors.New("upload is no longer accepting parts")
+	ErrInvalidChunkSize = fmt.Errorf("chunk_size must be between %d and %d bytes", MinChunkSize, MaxChunkSize)
+	ErrTooLarge         = fmt.Errorf("file needs more than %d parts; use a larger chunk_size", MaxParts)
+	ErrInvalidPart      = errors.New("invalid part")
+	ErrInvalidChecksum  = errors.New("checksum must be a hex-encoded SHA-256")
+	ErrChecksumMismatch = errors.New("chunk does not match its checksum")
+	ErrIncomplete       = errors.New("upload is missing parts")
+)
+
+var (
+	storeOnce sync.Once
+	store     Store
+	storeErr  error
+)
+
+// defaultStore returns the store configured from the environment
+func defaultStore() (Store, error) {
+	storeOnce.Do(func() {
+		store, storeErr = StoreFromEnv(context.Background())
+		if storeErr != nil && !errors.Is(storeErr, ErrNotConfigured) {
+			log.Printf("Warning: Failed to configure import storage: %v", storeErr)
+		}
+	})
+	return store, storeErr
+}
+
+// Plan validates an upload of size bytes and returns its chunk size (the
+// default if chunkSize is 0) and number of parts
+func Plan(size, chunkSize int64) (int64, int, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return 0, 0, ErrInvalidChunkSize
+	}
+	parts := (size + chunkSize - 1) / chunkSize
+	if parts > MaxParts {
+		return 0, 0, ErrTooLarge
+	}
+	return chunkSize, int(parts), nil
+}
+
+// PartSize returns the size part number (1-based) must have: the chunk size,
+// or the remainder for the last part
+func PartSize(size, chunkSize int64, number int) int64 {
+	offset := int64(number-1) * chunkSize
+	return min(chunkSize, size-offset)
+}
+
+// VerifyChunk checks data against a hex SHA-256 and returns the raw sum
+func VerifyChunk(data []byte, checksum string) ([]byte, error) {
+	expected, err := decodeSum(checksum)
+	if err != nil {
+		return nil, err
+	}
+	actual := sha256.Sum256(data)
+	if !bytes.Equal(actual[:], expected) {
+		return nil, ErrChecksumMismatch
+	}
+	return expected, nil
+}
+
+// newUploadID returns a random upload ID, which is also the resume token
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "upl_" + hex.EncodeToString(b), nil
+}
+
+// Create starts an upload
+func Create(ctx context.Context, req models.CreateImportUploadRequest, username string) (*models.ImportUpload, error) {
+	chunkSize, _, err := Plan(req.Size, req.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	store, err := defaultStore()
+	if err != nil {
+		return nil, err
+	}
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	key := "imports/" + id + ".csv"
+	multipartID, err := store.Create(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Primary(ctx).Exec(
+		`INSERT INTO import_uploads (id, filename, size, chunk_size, object_key, multipart_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		id, req.Filename, req.Size, chunkSize, key, multipartID, username,
+	)
+	if err != nil {
+		_ = store.Abort(ctx, key, multipartID)
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+	return Get(ctx, id)
+}
+
+// Get returns an upload with the parts received so far and those still missing
+func Get(ctx context.Context, id string) (*models.ImportUpload, error) {
+	var upload models.ImportUpload
+	err := db.Primary(ctx).QueryRow(
+		`SELECT id, filename, size, chunk_size, object_key, multipart_id, status, job_id, imported, skipped, error, created_by, created_at, updated_at
+		FROM import_uploads WHERE id = $1`, id,
+	).Scan(&upload.ID, &upload.Filename, &upload.Size, &upload.ChunkSize, &upload.ObjectKey, &upload.MultipartID,
+		&upload.Status, &upload.JobID, &upload.Imported, &upload.Skipped, &upload.Error, &upload.CreatedBy,
+		&upload.CreatedAt, &upload.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, upload.Parts, _ = Plan(upload.Size, upload.ChunkSize)
+
+	rows, err := db.Primary(ctx).Query(
+		"SELECT part_number, size, sha256, etag, uploaded_at FROM import_upload_parts WHERE upload_id = $1 ORDER BY part_number", id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	upload.Received = []models.ImportUploadPart{}
+	for rows.Next() {
+		var part models.ImportUploadPart
+		if err := rows.Scan(&part.Number, &part.Size, &part.SHA256, &part.ETag, &part.UploadedAt); err != nil {
+			return nil, err
+		}
+		upload.Received = append(upload.Received, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	upload.Missing = missingParts(upload.Parts, upload.Received)
+	return &upload, nil
+}
+
+// missingParts returns the part numbers in 1..parts that haven't been received
+func missingParts(parts int, received []models.ImportUploadPart) []int {
+	have := make(map[int]bool, len(received))
+	for _, part := range received {
+		have[part.Number] = true
+	}
+	missing := []int{}
+	for number := 1; number <= parts; number++ {
+		if !have[number] {
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
+
+// PutPart validates a chunk against its checksum and stores it. Uploading a
+// part again replaces it, so interrupted chunks can simply be re-sent.
+func PutPart(ctx context.Context, id string, number int, data []byte, checksum string) (*models.ImportUploadPart, error) {
+	upload, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != StatusUploading {
+		return nil, ErrNotUploading
+	}
+	if number < 1 || number > upload.Parts {
+		return nil, fmt.Errorf("%w: part number must be between 1 and %d", ErrInvalidPart, upload.Parts)
+	}
+	if expected := PartSize(upload.Size, upload.ChunkSize, number); int64(len(data)) != expected {
+		return nil, fmt.Errorf("%w: part %d must be %d bytes, got %d", ErrInvalidPart, number, expected, len(data))
+	}
+	sum, err := VerifyChunk(data, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := defaultStore()
+	if err != nil {
+		return nil, err
+	}
+	etag, err := store.UploadPart(ctx, upload.ObjectKey, upload.MultipartID, number, data, sum)
+	if err != nil {
+		return nil, err
+	}
+
+	part := models.ImportUploadPart{Number: number, Size: int64(len(data)), SHA256: hex.EncodeToString(sum), ETag: etag}
+	err = db.Primary(ctx).QueryRow(
+		`INSERT INTO import_upload_parts (upload_id, part_number, size, sha256, etag) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (upload_id, part_number) DO UPDATE
+		SET size = EXCLUDED.size, sha256 = EXCLUDED.sha256, etag = EXCLUDED.etag, uploaded_at = CURRENT_TIMESTAMP
+		RETURNING uploaded_at`,
+		id, part.Number, part.Size, part.SHA256, part.ETag,
+	).Scan(&part.UploadedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record part: %w", err)
+	}
+	_, _ = db.Primary(ctx).Exec("UPDATE import_uploads SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return &part, nil
+}
+
+// Complete assembles an upload whose parts have all been received and starts
+// the job that imports it. The returned upload carries the job ID.
+func Complete(ctx context.Context, id string) (*models.ImportUpload, error) {
+	upload, err := Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != StatusUploading {
+		return nil, ErrNotUploading
+	}
+	if len(upload.Missing) > 0 {
+		return nil, fmt.Errorf("%w: %d of %d parts not uploaded, starting with part %d",
+			ErrIncomplete, len(upload.Missing), upload.Parts, upload.Missing[0])
+	}
+	store, err := defaultStore()
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim the upload so concurrent calls can't assemble or import it twice
+	job := progress.Start("import")
+	jobID := job.Snapshot().JobID
+	result, err := db.Primary(ctx).Exec(
+		"UPDATE import_uploads SET status = $2, job_id = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = $4",
+		id, StatusImporting, jobID, StatusUploading,
+	)
+	if err != nil {
+		job.Finish(err)
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		job.Finish(ErrNotUploading)
+		return nil, ErrNotUploading
+	}
+
+	parts := make([]StoredPart, len(upload.Received))
+	for i, part := range upload.Received {
+		sum, _ := hex.DecodeString(part.SHA256)
+		parts[i] = StoredPart{Number: part.Number, ETag: part.ETag, SHA256: sum}
+	}
+	if err := store.Complete(ctx, upload.ObjectKey, upload.MultipartID, parts); err != nil {
+		finish(context.WithoutCancel(ctx), id, job, 0, 0, err)
+		return nil, err
+	}
+
+	log.Printf("Upload %s assembled (%d bytes in %d parts), importing as job %s", id, upload.Size, upload.Parts, jobID)
+	go run(context.WithoutCancel(ctx), store, upload, job)
+
+	upload.Status = StatusImporting
+	upload.JobID = &jobID
+	return upload, nil
+}
+
+// Abort discards an unfinished upload and the parts stored so far
+func Abort(ctx context.Context, id string) error {
+	upload, err := Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if upload.Status != StatusUploading {
+		return ErrNotUploading
+	}
+	store, err := defaultStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Abort(ctx, upload.ObjectKey, upload.MultipartID); err != nil {
+		return err
+	}
+	_, err = db.Primary(ctx).Exec(
+		"UPDATE import_uploads SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", id, StatusAborted,
+	)
+	return err
+}
+
+// finish records the outcome of an import and completes its job
+func finish(ctx context.Context, id string, job *progress.Job, imported, skipped int64, err error) {
+	status := StatusCompleted
+	var message *string
+	if err != nil {
+		status = StatusFailed
+		text := err.Error()
+		message = &text
+		log.Printf("Import %s failed: %v", id, err)
+	} else {
+		log.Printf("Import %s completed: %d imported, %d skipped", id, imported, skipped)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, dbErr := db.Primary(ctx).Exec(
+		"UPDATE import_uploads SET status = $2, imported = $3, skipped = $4, error = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, status, imported, skipped, message,
+	)
+	if dbErr != nil {
+		log.Printf("Warning: Failed to record outcome of import %s: %v", id, dbErr)
+	}
+	job.Finish(err)
+}
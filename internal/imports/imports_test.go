@@ -0,0 +1,90 @@
+package imports
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/models"
+)
+
+func TestPlan(t *testing.T) {
+	chunk, parts, err := Plan(20<<20, 0)
+	if err != nil || chunk != DefaultChunkSize || parts != 3 {
+		t.Errorf("Expected 3 default-sized parts, got %d parts of %d (%v)", parts, chunk, err)
+	}
+	if _, _, err := Plan(1<<20, 1<<20); !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("Expected chunks below the S3 minimum to be rejected, got %v", err)
+	}
+	if _, _, err := Plan(MaxParts*MinChunkSize+1, MinChunkSize); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected more than %d parts to be rejected, got %v", MaxParts, err)
+	}
+
+	if size := PartSize(20<<20, DefaultChunkSize, 1); size != DefaultChunkSize {
+		t.Errorf("Expected a full first part, got %d", size)
+	}
+	if size := PartSize(20<<20, DefaultChunkSize, 3); size != 4<<20 {
+		t.Errorf("Expected the last part to hold the remainder, got %d", size)
+	}
+}
+
+func TestVerifyChunk(t *testing.T) {
+	data := []byte("name,email\nAcme,contact@acme.com\n")
+	sum := sha256.Sum256(data)
+
+	if _, err := VerifyChunk(data, hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("Expected a matching checksum to pass, got %v", err)
+	}
+	if _, err := VerifyChunk(append(data, 'x'), hex.EncodeToString(sum[:])); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected a corrupted chunk to be rejected, got %v", err)
+	}
+	if _, err := VerifyChunk(data, "abc"); !errors.Is(err, ErrInvalidChecksum) {
+		t.Errorf("Expected a malformed checksum to be rejected, got %v", err)
+	}
+}
+
+func TestMissingParts(t *testing.T) {
+	received := []models.ImportUploadPart{{Number: 1}, {Number: 3}}
+	missing := missingParts(4, received)
+	if len(missing) != 2 || missing[0] != 2 || missing[1] != 4 {
+		t.Errorf("Expected parts 2 and 4 to be missing, got %v", missing)
+	}
+}
+
+func TestLoadCustomers(t *testing.T) {
+	csv := "\uFEFFEmail, Name ,plan\n" +
+		"contact@acme.com,Acme,pro\n" +
+		",No Email,basic\n" +
+		"info@techstart.com,TechStart\n" +
+		"dup@acme.com,Acme Again,pro\n"
+
+	var batches [][][]any
+	insert := func(ctx context.Context, rows [][]any) (int64, error) {
+		batch := make([][]any, len(rows))
+		copy(batch, rows)
+		batches = append(batches, batch)
+		return int64(len(rows)) - 1, nil // pretend one email already exists
+	}
+	var read int64
+	imported, skipped, err := loadCustomers(context.Background(), strings.NewReader(csv), insert, func(n int64) { read = n })
+	if err != nil {
+		t.Fatalf("loadCustomers failed: %v", err)
+	}
+
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("Expected one batch of 3 valid rows, got %v", batches)
+	}
+	if row := batches[0][0]; row[0] != "Acme" || row[1] != "contact@acme.com" {
+		t.Errorf("Expected columns mapped from the header, got %v", row)
+	}
+	if imported != 2 || skipped != 2 || read != 4 {
+		t.Errorf("Expected 2 imported, 2 skipped of 4 read, got %d, %d of %d", imported, skipped, read)
+	}
+
+	if _, _, err := loadCustomers(context.Background(), strings.NewReader("id,email\n1,a@b.com\n"), insert, func(int64) {}); err == nil {
+		t.Error("Expected a header without a name column to be rejected")
+	}
+}
@@ -0,0 +1,140 @@
+package imports
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/progress"
+)
+
+// batchSize is the number of CSV rows loaded per COPY
+const batchSize = 10000
+
+// maxFieldLength matches the customers.name and customers.email columns
+const maxFieldLength = 255
+
+// run imports an assembled upload and records the outcome
+func run(ctx context.Context, store Store, upload *models.ImportUpload, job *progress.Job) {
+	imported, skipped, err := importObject(ctx, store, upload.ObjectKey, job)
+	finish(ctx, upload.ID, job, imported, skipped, err)
+}
+
+func importObject(ctx context.Context, store Store, key string, job *progress.Job) (int64, int64, error) {
+	body, err := store.Open(ctx, key)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer body.Close()
+
+	throttle := db.NewThrottle(progress.WithJob(ctx, job))
+	return loadCustomers(ctx, body, throttled(throttle, db.ImportCustomers), func(read int64) {
+		job.Update("customers", read, 0)
+	})
+}
+
+// throttled returns insert paced by throttle, so batches pause while the
+// primary is under pressure
+func throttled(throttle *db.Throttle, insert func(context.Context, [][]any) (int64, error)) func(context.Context, [][]any) (int64, error) {
+	return func(ctx context.Context, rows [][]any) (int64, error) {
+		if err := throttle.Wait(ctx); err != nil {
+			return 0, err
+		}
+		return insert(ctx, rows)
+	}
+}
+
+// loadCustomers reads customers from a CSV with a header row naming at least
+// the name and email columns (in any order, case-insensitive) and inserts them
+// in batches. Rows with a blank or over-long name or email are skipped, as are
+// emails that already exist. It returns the rows imported and skipped.
+func loadCustomers(ctx context.Context, r io.Reader, insert func(context.Context, [][]any) (int64, error), report func(read int64)) (int64, int64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, 0, errors.New("file is empty")
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	nameColumn, emailColumn, err := customerColumns(header)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var read, imported, skipped int64
+	batch := make([][]any, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, err := insert(ctx, batch)
+		if err != nil {
+			return err
+		}
+		imported += inserted
+		skipped += int64(len(batch)) - inserted
+		batch = batch[:0]
+		report(read)
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, skipped, err
+		}
+		read++
+
+		name, email := field(record, nameColumn), field(record, emailColumn)
+		if name == "" || email == "" || len(name) > maxFieldLength || len(email) > maxFieldLength {
+			skipped++
+			continue
+		}
+		batch = append(batch, []any{name, email})
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return imported, skipped, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return imported, skipped, err
+	}
+	return imported, skipped, nil
+}
+
+// customerColumns finds the name and email columns in a header row
+func customerColumns(header []string) (int, int, error) {
+	nameColumn, emailColumn := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\uFEFF"))) {
+		case "name":
+			nameColumn = i
+		case "email":
+			emailColumn = i
+		}
+	}
+	if nameColumn < 0 || emailColumn < 0 {
+		return 0, 0, errors.New("header must include name and email columns")
+	}
+	return nameColumn, emailColumn, nil
+}
+
+func field(record []string, column int) string {
+	if column >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column])
+}
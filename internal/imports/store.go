@@ -0,0 +1,195 @@
+package imports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrNotConfigured is returned when no bucket is configured for uploads
+var ErrNotConfigured = errors.New("import storage is not configured")
+
+// StoredPart identifies an uploaded part when assembling the object
+type StoredPart struct {
+	Number int
+	ETag   string
+	SHA256 []byte
+}
+
+// Store holds uploaded files as multipart objects
+type Store interface {
+	// Create starts a multipart upload to key and returns its upload ID
+	Create(ctx context.Context, key string) (string, error)
+	// UploadPart stores one part, which the store validates against sum, and returns its ETag
+	UploadPart(ctx context.Context, key, uploadID string, number int, data, sum []byte) (string, error)
+	// Complete assembles the parts into the object at key
+	Complete(ctx context.Context, key, uploadID string, parts []StoredPart) error
+	// Abort discards an unfinished upload and its parts
+	Abort(ctx context.Context, key, uploadID string) error
+	// Open reads the assembled object
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// S3Store keeps uploads in an S3 bucket. Parts carry their SHA-256 so S3
+// rejects any part corrupted between the app and the bucket.
+type S3Store struct {
+	Client *s3.Client
+	Bucket string
+}
+
+// Create implements Store
+func (s S3Store) Create(ctx context.Context, key string) (string, error) {
+	out, err := s.Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(s.Bucket),
+		Key:               aws.String(key),
+		ContentType:       aws.String("text/csv"),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+// UploadPart implements Store
+func (s S3Store) UploadPart(ctx context.Context, key, uploadID string, number int, data, sum []byte) (string, error) {
+	out, err := s.Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:         aws.String(s.Bucket),
+		Key:            aws.String(key),
+		UploadId:       aws.String(uploadID),
+		PartNumber:     aws.Int32(int32(number)),
+		Body:           bytes.NewReader(data),
+		ContentLength:  aws.Int64(int64(len(data))),
+		ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(sum)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload part %d: %w", number, err)
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+// Complete implements Store
+func (s S3Store) Complete(ctx context.Context, key, uploadID string, parts []StoredPart) error {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{
+			PartNumber:     aws.Int32(int32(part.Number)),
+			ETag:           aws.String(part.ETag),
+			ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(part.SHA256)),
+		}
+	}
+	_, err := s.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.Bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	return nil
+}
+
+// Abort implements Store
+func (s S3Store) Abort(ctx context.Context, key, uploadID string) error {
+	_, err := s.Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+	return nil
+}
+
+// Open implements Store
+func (s S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return out.Body, nil
+}
+
+// Put stores data as the object at key in a single request, for small
+// objects such as statement documents
+func (s S3Store) Put(ctx context.Context, key, contentType string, data []byte) error {
+	_, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:            aws.String(s.Bucket),
+		Key:               aws.String(key),
+		Body:              bytes.NewReader(data),
+		ContentLength:     aws.Int64(int64(len(data))),
+		ContentType:       aws.String(contentType),
+		ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return nil
+}
+
+// StoreFromEnv returns an S3 store for the bucket in IMPORT_S3_BUCKET, using the
+// standard AWS_* credentials, region, and AWS_ENDPOINT_URL (e.g. for MinIO).
+// Without it, the Heroku Bucketeer add-on's BUCKETEER_* settings are used.
+// Set IMPORT_S3_PATH_STYLE=true for stores that don't support virtual-hosted
+// bucket URLs.
+func StoreFromEnv(ctx context.Context) (Store, error) {
+	store, err := S3StoreFromEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// S3StoreFromEnv is StoreFromEnv for callers that need the S3 store itself,
+// e.g. to Put whole objects
+func S3StoreFromEnv(ctx context.Context) (S3Store, error) {
+	var opts []func(*config.LoadOptions) error
+	bucket := os.Getenv("IMPORT_S3_BUCKET")
+	if bucket == "" {
+		bucket = os.Getenv("BUCKETEER_BUCKET_NAME")
+		if bucket != "" {
+			opts = append(opts,
+				config.WithRegion(os.Getenv("BUCKETEER_AWS_REGION")),
+				config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+					os.Getenv("BUCKETEER_AWS_ACCESS_KEY_ID"), os.Getenv("BUCKETEER_AWS_SECRET_ACCESS_KEY"), "")),
+			)
+		}
+	}
+	if bucket == "" {
+		return S3Store{}, ErrNotConfigured
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return S3Store{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = os.Getenv("IMPORT_S3_PATH_STYLE") == "true"
+	})
+	return S3Store{Client: client, Bucket: bucket}, nil
+}
+
+// decodeSum parses a hex SHA-256 checksum
+func decodeSum(sum string) ([]byte, error) {
+	decoded, err := hex.DecodeString(sum)
+	if err != nil || len(decoded) != 32 {
+		return nil, ErrInvalidChecksum
+	}
+	return decoded, nil
+}
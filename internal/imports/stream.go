@@ -0,0 +1,180 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+)
+
+// Limits of JSON Lines imports
+const (
+	// MaxStreamLine is the longest line accepted; a longer one ends the import
+	MaxStreamLine = 1 << 20
+	// maxStreamErrors is the number of rejected lines reported individually
+	maxStreamErrors = 100
+	// maxStatusLength matches the accounts.status column
+	maxStatusLength = 50
+)
+
+// Record types of JSON Lines imports
+const (
+	RecordCustomer = "customer"
+	RecordAccount  = "account"
+)
+
+// ErrLineTooLong is returned by Stream for a line over MaxStreamLine bytes
+var ErrLineTooLong = fmt.Errorf("line is longer than %d bytes", MaxStreamLine)
+
+// streamInserters load batches of a JSON Lines import; tests replace them
+type streamInserters struct {
+	customers func(context.Context, [][]any) (int64, error)
+	accounts  func(context.Context, [][]any) (int64, error)
+}
+
+// Stream imports customers and accounts from JSON Lines (one
+// models.StreamImportRecord per line) as they are read from r, so an import
+// of any size never has to be staged first. Valid rows are inserted in
+// batches, each committed on its own; customers are inserted before the
+// accounts that follow them, so accounts can belong to customers earlier in
+// the stream. Invalid lines are counted and skipped. report is called after
+// every batch with the progress so far, and Stream returns the final
+// progress. Batches committed before an error are kept. Batches pause while
+// the primary is under pressure, reporting to the job ctx carries.
+func Stream(ctx context.Context, r io.Reader, report func(models.StreamImportProgress)) (models.StreamImportProgress, error) {
+	throttle := db.NewThrottle(ctx)
+	insert := streamInserters{customers: throttled(throttle, db.ImportCustomers), accounts: throttled(throttle, db.ImportAccounts)}
+	return loadStream(ctx, r, insert, report)
+}
+
+func loadStream(ctx context.Context, r io.Reader, insert streamInserters, report func(models.StreamImportProgress)) (models.StreamImportProgress, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), MaxStreamLine)
+
+	progress := models.StreamImportProgress{Event: "progress"}
+	customers := make([][]any, 0, batchSize)
+	accounts := make([][]any, 0, batchSize)
+	flushCustomers := func() error {
+		if len(customers) == 0 {
+			return nil
+		}
+		inserted, err := insert.customers(ctx, customers)
+		if err != nil {
+			return err
+		}
+		progress.Customers.Imported += inserted
+		progress.Customers.Skipped += int64(len(customers)) - inserted
+		customers = customers[:0]
+		report(progress)
+		return nil
+	}
+	flushAccounts := func() error {
+		if len(accounts) == 0 {
+			return nil
+		}
+		// Accounts may belong to customers still waiting in their batch
+		if err := flushCustomers(); err != nil {
+			return err
+		}
+		inserted, err := insert.accounts(ctx, accounts)
+		if err != nil {
+			return err
+		}
+		progress.Accounts.Imported += inserted
+		progress.Accounts.Skipped += int64(len(accounts)) - inserted
+		accounts = accounts[:0]
+		report(progress)
+		return nil
+	}
+	reject := func(err error) {
+		progress.Invalid++
+		if len(progress.Errors) < maxStreamErrors {
+			progress.Errors = append(progress.Errors, models.StreamImportLineError{Line: progress.Lines, Error: err.Error()})
+		}
+	}
+
+	for scanner.Scan() {
+		progress.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record models.StreamImportRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			reject(errors.New("invalid JSON"))
+			continue
+		}
+		row, err := streamRow(record)
+		if err != nil {
+			reject(err)
+			continue
+		}
+
+		if record.Type == RecordCustomer {
+			customers = append(customers, row)
+			if len(customers) == batchSize {
+				if err := flushCustomers(); err != nil {
+					return progress, err
+				}
+			}
+			continue
+		}
+		accounts = append(accounts, row)
+		if len(accounts) == batchSize {
+			if err := flushAccounts(); err != nil {
+				return progress, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = fmt.Errorf("line %d: %w", progress.Lines+1, ErrLineTooLong)
+		}
+		return progress, err
+	}
+	if err := flushCustomers(); err != nil {
+		return progress, err
+	}
+	if err := flushAccounts(); err != nil {
+		return progress, err
+	}
+	return progress, nil
+}
+
+// streamRow validates a record and returns its row for ImportCustomers or
+// ImportAccounts
+func streamRow(record models.StreamImportRecord) ([]any, error) {
+	name := strings.TrimSpace(record.Name)
+	if name == "" || len(name) > maxFieldLength {
+		return nil, fmt.Errorf("name must be 1-%d characters", maxFieldLength)
+	}
+	switch record.Type {
+	case RecordCustomer:
+		email := strings.TrimSpace(record.Email)
+		if email == "" || len(email) > maxFieldLength {
+			return nil, fmt.Errorf("email must be 1-%d characters", maxFieldLength)
+		}
+		return []any{name, email}, nil
+	case RecordAccount:
+		email := strings.TrimSpace(record.CustomerEmail)
+		if email == "" {
+			return nil, errors.New("customer_email is required")
+		}
+		status := strings.TrimSpace(record.Status)
+		if status == "" {
+			status = "active"
+		}
+		if len(status) > maxStatusLength {
+			return nil, fmt.Errorf("status must be at most %d characters", maxStatusLength)
+		}
+		return []any{email, name, status}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q, expected customer or account", record.Type)
+}
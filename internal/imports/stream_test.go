@@ -0,0 +1,71 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"saas-go-app/internal/models"
+)
+
+func TestLoadStream(t *testing.T) {
+	stream := `{"type": "customer", "name": "Acme", "email": "contact@acme.com"}
+{"type": "account", "name": "Acme Pro", "customer_email": "contact@acme.com"}
+
+{"type": "customer", "name": "", "email": "blank@acme.com"}
+not json
+{"type": "invoice", "name": "INV-1"}
+{"type": "account", "name": "Orphan", "customer_email": "nobody@example.com", "status": "inactive"}
+{"type": "customer", "name": "TechStart", "email": "info@techstart.com"}
+`
+	var calls []string
+	var accountRows [][]any
+	insert := streamInserters{
+		customers: func(ctx context.Context, rows [][]any) (int64, error) {
+			calls = append(calls, "customers")
+			return int64(len(rows)), nil
+		},
+		accounts: func(ctx context.Context, rows [][]any) (int64, error) {
+			calls = append(calls, "accounts")
+			accountRows = append(accountRows, rows...)
+			return int64(len(rows)) - 1, nil // pretend the orphan's customer doesn't exist
+		},
+	}
+	var reports int
+	result, err := loadStream(context.Background(), strings.NewReader(stream), insert, func(models.StreamImportProgress) { reports++ })
+	if err != nil {
+		t.Fatalf("loadStream failed: %v", err)
+	}
+
+	if strings.Join(calls, ",") != "customers,accounts" || reports != 2 {
+		t.Errorf("Expected customers flushed before accounts with a report each, got %v and %d reports", calls, reports)
+	}
+	if len(accountRows) != 2 || accountRows[0][2] != "active" || accountRows[1][2] != "inactive" {
+		t.Errorf("Expected accounts with their status, active by default, got %v", accountRows)
+	}
+	if result.Lines != 8 || result.Customers.Imported != 2 || result.Accounts.Imported != 1 || result.Accounts.Skipped != 1 {
+		t.Errorf("Expected 8 lines, 2 customers and 1 of 2 accounts imported, got %+v", result)
+	}
+	if result.Invalid != 3 || len(result.Errors) != 3 || result.Errors[0].Line != 4 || result.Errors[1].Error != "invalid JSON" {
+		t.Errorf("Expected 3 rejected lines from line 4, got %d %+v", result.Invalid, result.Errors)
+	}
+}
+
+func TestLoadStreamErrors(t *testing.T) {
+	failed := errors.New("connection reset")
+	insert := streamInserters{
+		customers: func(ctx context.Context, rows [][]any) (int64, error) { return 0, failed },
+		accounts:  func(ctx context.Context, rows [][]any) (int64, error) { return int64(len(rows)), nil },
+	}
+	stream := `{"type": "customer", "name": "Acme", "email": "contact@acme.com"}` + "\n"
+	if _, err := loadStream(context.Background(), strings.NewReader(stream), insert, func(models.StreamImportProgress) {}); !errors.Is(err, failed) {
+		t.Errorf("Expected the insert error, got %v", err)
+	}
+
+	long := `{"type": "customer", "name": "` + strings.Repeat("a", MaxStreamLine) + `"}`
+	result, err := loadStream(context.Background(), strings.NewReader(long), insert, func(models.StreamImportProgress) {})
+	if !errors.Is(err, ErrLineTooLong) || !strings.HasPrefix(err.Error(), "line 1:") || result.Lines != 0 {
+		t.Errorf("Expected line 1 to be too long, got %v", err)
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
 	"time"
 
 	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
 
 	"github.com/hibiken/asynq"
 )
@@ -18,12 +18,13 @@ const (
 
 // AggregationPayload represents the payload for aggregation jobs
 type AggregationPayload struct {
-	Date time.Time `json:"date"`
+	Date  time.Time     `json:"date"`
+	Trace tracing.Trace `json:"trace,omitempty"`
 }
 
-// NewAggregationTask creates a new aggregation task
-func NewAggregationTask(date time.Time) (*asynq.Task, error) {
-	payload, err := json.Marshal(AggregationPayload{Date: date})
+// NewAggregationTask creates a new aggregation task traced to the request in ctx
+func NewAggregationTask(ctx context.Context, date time.Time) (*asynq.Task, error) {
+	payload, err := json.Marshal(AggregationPayload{Date: date, Trace: tracing.FromContext(ctx)})
 	if err != nil {
 		return nil, err
 	}
@@ -37,35 +38,32 @@ func HandleAggregationTask(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 
-	log.Printf("Processing aggregation task for date: %s", payload.Date.Format("2006-01-02"))
+	tracing.Printf(ctx, "Processing aggregation task for date: %s", payload.Date.Format("2006-01-02"))
 
 	// Perform data aggregation
 	// This is a demo, so we'll just log some aggregated statistics
-	analyticsDB := db.AnalyticsDB
-	if analyticsDB == nil {
-		analyticsDB = db.PrimaryDB
-	}
+	analyticsDB := db.Analytics(ctx)
 
 	var totalCustomers int
 	var totalAccounts int
 	var activeAccounts int
 
-	err := analyticsDB.QueryRow("SELECT COUNT(*) FROM customers").Scan(&totalCustomers)
+	err := analyticsDB.QueryRow("SELECT COUNT(*) FROM customers WHERE deleted_at IS NULL").Scan(&totalCustomers)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error aggregating customers: %v", err)
+		tracing.Printf(ctx, "Error aggregating customers: %v", err)
 	}
 
-	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&totalAccounts)
+	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE deleted_at IS NULL").Scan(&totalAccounts)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error aggregating accounts: %v", err)
+		tracing.Printf(ctx, "Error aggregating accounts: %v", err)
 	}
 
-	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'active'").Scan(&activeAccounts)
+	err = analyticsDB.QueryRow("SELECT COUNT(*) FROM accounts WHERE status = 'active' AND deleted_at IS NULL").Scan(&activeAccounts)
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error aggregating active accounts: %v", err)
+		tracing.Printf(ctx, "Error aggregating active accounts: %v", err)
 	}
 
-	log.Printf("Aggregation results - Customers: %d, Accounts: %d, Active: %d", 
+	tracing.Printf(ctx, "Aggregation results - Customers: %d, Accounts: %d, Active: %d", 
 		totalCustomers, totalAccounts, activeAccounts)
 
 	// In a real application, you might store these aggregated results in a separate table
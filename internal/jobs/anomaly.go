@@ -0,0 +1,176 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypeDetectAnomalies = "anomaly:detect"
+)
+
+// anomalyMetrics maps each monitored metric to a query returning the event
+// timestamps it counts. Counts are bucketed per hour to build the baseline.
+var anomalyMetrics = map[string]string{
+	"writes": `
+		SELECT created_at FROM customers
+		UNION ALL SELECT created_at FROM accounts
+		UNION ALL SELECT created_at FROM account_notes`,
+	"login_failures": `SELECT created_at FROM login_attempts WHERE NOT success`,
+}
+
+// AnomalyResult describes how an observed value compares to its baseline
+type AnomalyResult struct {
+	Metric    string
+	Observed  float64
+	Mean      float64
+	StdDev    float64
+	Threshold float64
+	Anomalous bool
+}
+
+// NewAnomalyDetectionTask creates a new anomaly detection task
+func NewAnomalyDetectionTask() *asynq.Task {
+	return asynq.NewTask(TypeDetectAnomalies, nil)
+}
+
+// HandleAnomalyDetectionTask compares the last hour of write volume and login
+// failures against a rolling 7-day hourly baseline, storing an anomaly event
+// and notifying operators for every metric that exceeds the threshold.
+func HandleAnomalyDetectionTask(ctx context.Context, t *asynq.Task) error {
+	analyticsDB := db.Analytics(ctx)
+
+	sigma := getEnvFloat("ANOMALY_THRESHOLD_SIGMA", 3)
+	minEvents := getEnvFloat("ANOMALY_MIN_EVENTS", 10)
+
+	for metric, source := range anomalyMetrics {
+		var observed float64
+		err := analyticsDB.QueryRow(
+			fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS events WHERE created_at >= NOW() - INTERVAL '1 hour'", source),
+		).Scan(&observed)
+		if err != nil {
+			tracing.Printf(ctx, "Error computing current %s volume: %v", metric, err)
+			continue
+		}
+
+		rows, err := analyticsDB.Query(fmt.Sprintf(`
+			WITH hours AS (
+				SELECT generate_series(
+					date_trunc('hour', NOW()) - INTERVAL '168 hours',
+					date_trunc('hour', NOW()) - INTERVAL '1 hour',
+					INTERVAL '1 hour'
+				) AS hour
+			)
+			SELECT COUNT(events.created_at)
+			FROM hours
+			LEFT JOIN (%s) AS events ON date_trunc('hour', events.created_at) = hours.hour
+			GROUP BY hours.hour
+			ORDER BY hours.hour`, source),
+		)
+		if err != nil {
+			tracing.Printf(ctx, "Error computing %s baseline: %v", metric, err)
+			continue
+		}
+
+		var samples []float64
+		for rows.Next() {
+			var count float64
+			if err := rows.Scan(&count); err != nil {
+				break
+			}
+			samples = append(samples, count)
+		}
+		rows.Close()
+
+		result := DetectAnomaly(metric, observed, samples, sigma, minEvents)
+		if !result.Anomalous {
+			continue
+		}
+
+		tracing.Printf(ctx, "Anomaly detected - %s: observed %.0f, baseline %.2f ± %.2f (threshold %.2f)",
+			metric, result.Observed, result.Mean, result.StdDev, result.Threshold)
+
+		if err := recordAnomaly(ctx, result); err != nil {
+			tracing.Printf(ctx, "Error recording %s anomaly: %v", metric, err)
+		}
+	}
+
+	return nil
+}
+
+// DetectAnomaly flags observed values more than sigma standard deviations above
+// the mean of the baseline samples. Values below minEvents are never flagged so
+// quiet demo apps don't alert on a handful of events.
+func DetectAnomaly(metric string, observed float64, samples []float64, sigma, minEvents float64) AnomalyResult {
+	result := AnomalyResult{Metric: metric, Observed: observed}
+	if len(samples) > 0 {
+		for _, s := range samples {
+			result.Mean += s
+		}
+		result.Mean /= float64(len(samples))
+
+		for _, s := range samples {
+			result.StdDev += (s - result.Mean) * (s - result.Mean)
+		}
+		result.StdDev = math.Sqrt(result.StdDev / float64(len(samples)))
+	}
+
+	result.Threshold = math.Max(result.Mean+sigma*result.StdDev, minEvents)
+	result.Anomalous = observed > result.Threshold
+	return result
+}
+
+// recordAnomaly stores an anomaly event and notifies the configured operators
+func recordAnomaly(ctx context.Context, result AnomalyResult) error {
+	_, err := db.Primary(ctx).Exec(
+		"INSERT INTO anomaly_events (metric, observed, baseline_mean, baseline_stddev, threshold) VALUES ($1, $2, $3, $4, $5)",
+		result.Metric, result.Observed, result.Mean, result.StdDev, result.Threshold,
+	)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Anomaly in %s: %.0f events in the last hour (threshold %.0f)",
+		result.Metric, result.Observed, result.Threshold)
+	return db.CreateNotifications(ctx, "anomaly", message, notifyRecipients("ANOMALY_NOTIFY_USERS")...)
+}
+
+// notifyRecipients returns the usernames listed in the given env var
+// (comma-separated, default: admin), e.g. ANOMALY_NOTIFY_USERS
+func notifyRecipients(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return []string{"admin"}
+	}
+
+	var usernames []string
+	for _, username := range strings.Split(value, ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s (%s), using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return floatValue
+}
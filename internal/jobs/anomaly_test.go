@@ -0,0 +1,29 @@
+package jobs
+
+import "testing"
+
+func TestDetectAnomaly(t *testing.T) {
+	baseline := []float64{10, 12, 8, 11, 9, 10}
+
+	result := DetectAnomaly("writes", 11, baseline, 3, 5)
+	if result.Anomalous {
+		t.Errorf("Expected 11 to be within baseline, threshold was %.2f", result.Threshold)
+	}
+
+	result = DetectAnomaly("writes", 40, baseline, 3, 5)
+	if !result.Anomalous {
+		t.Errorf("Expected 40 to be anomalous, threshold was %.2f", result.Threshold)
+	}
+
+	if result.Mean != 10 {
+		t.Errorf("Expected mean 10, got %.2f", result.Mean)
+	}
+}
+
+func TestDetectAnomalyMinEvents(t *testing.T) {
+	// An empty baseline would flag any activity without a minimum
+	result := DetectAnomaly("login_failures", 3, []float64{0, 0, 0}, 3, 10)
+	if result.Anomalous {
+		t.Error("Expected values below the minimum event count not to be flagged")
+	}
+}
@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"time"
 
 	"github.com/hibiken/asynq"
@@ -14,18 +15,18 @@ func NewClient(redisURL string) (*asynq.Client, error) {
 	return asynq.NewClient(asynq.RedisClientOpt{Addr: redisURL}), nil
 }
 
-// EnqueueAggregationTask enqueues an aggregation task
-func EnqueueAggregationTask(client *asynq.Client, date time.Time) error {
+// EnqueueAggregationTask enqueues an aggregation task, carrying the trace from ctx
+func EnqueueAggregationTask(ctx context.Context, client *asynq.Client, date time.Time) error {
 	if client == nil {
 		return nil // Skip if Redis is not configured
 	}
 
-	task, err := NewAggregationTask(date)
+	task, err := NewAggregationTask(ctx, date)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.Enqueue(task, asynq.Queue("default"))
+	_, err = client.EnqueueContext(ctx, task, asynq.Queue("default"))
 	return err
 }
 
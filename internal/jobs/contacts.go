@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	TypeNormalizeContacts = "contacts:normalize"
+)
+
+// Contact issue kinds
+const (
+	IssueInvalidEmail   = "invalid_format"
+	IssueDuplicateEmail = "duplicate_after_normalization"
+)
+
+var contactIssues = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "contact_issues",
+	Help: "Customer contact fields flagged by the last normalization run.",
+}, []string{"issue"})
+
+// ContactNormalizationResult summarizes one normalization run
+type ContactNormalizationResult struct {
+	Checked    int `json:"checked"`
+	Normalized int `json:"normalized"`
+	Invalid    int `json:"invalid"`
+	Duplicates int `json:"duplicates"`
+}
+
+// NewContactNormalizationTask creates a new contact normalization task
+func NewContactNormalizationTask() *asynq.Task {
+	return asynq.NewTask(TypeNormalizeContacts, nil)
+}
+
+// HandleContactNormalizationTask normalizes and validates customer emails
+func HandleContactNormalizationTask(ctx context.Context, t *asynq.Task) error {
+	_, err := RunContactNormalization(ctx)
+	return err
+}
+
+// RunContactNormalization lowercases and trims customer emails, then replaces
+// the contact issue report with emails that are not deliverable addresses or
+// that would collide with another customer once normalized. Colliding emails
+// are left unchanged so the unique constraint holds; they need a manual merge.
+func RunContactNormalization(ctx context.Context) (ContactNormalizationResult, error) {
+	var result ContactNormalizationResult
+
+	rows, err := db.Primary(ctx).Query("SELECT id, email FROM customers")
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch customer emails: %w", err)
+	}
+	type contact struct {
+		id    int
+		email string
+	}
+	byNormalized := make(map[string][]contact)
+	for rows.Next() {
+		var c contact
+		if err := rows.Scan(&c.id, &c.email); err != nil {
+			rows.Close()
+			return result, err
+		}
+		normalized := NormalizeEmail(c.email)
+		byNormalized[normalized] = append(byNormalized[normalized], c)
+		result.Checked++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	var updateIDs, issueIDs []int64
+	var updateEmails, issueValues, issueKinds []string
+	for normalized, contacts := range byNormalized {
+		if len(contacts) > 1 {
+			for _, c := range contacts {
+				issueIDs, issueValues, issueKinds = append(issueIDs, int64(c.id)), append(issueValues, c.email), append(issueKinds, IssueDuplicateEmail)
+			}
+			result.Duplicates += len(contacts)
+			continue
+		}
+		c := contacts[0]
+		if c.email != normalized {
+			updateIDs, updateEmails = append(updateIDs, int64(c.id)), append(updateEmails, normalized)
+		}
+		if !ValidEmail(normalized) {
+			issueIDs, issueValues, issueKinds = append(issueIDs, int64(c.id)), append(issueValues, normalized), append(issueKinds, IssueInvalidEmail)
+			result.Invalid++
+		}
+	}
+
+	tx, err := db.Primary(ctx).Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if len(updateIDs) > 0 {
+		updated, err := tx.ExecContext(ctx, `
+			UPDATE customers c SET email = v.email, updated_at = CURRENT_TIMESTAMP, version = c.version + 1
+			FROM unnest($1::int[], $2::text[]) AS v(id, email)
+			WHERE c.id = v.id`,
+			updateIDs, updateEmails,
+		)
+		if err != nil {
+			return result, fmt.Errorf("failed to normalize customer emails: %w", err)
+		}
+		affected, _ := updated.RowsAffected()
+		result.Normalized = int(affected)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM contact_issues"); err != nil {
+		return result, fmt.Errorf("failed to clear contact issues: %w", err)
+	}
+	if len(issueIDs) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO contact_issues (customer_id, field, value, issue)
+			SELECT v.id, 'email', v.value, v.issue
+			FROM unnest($1::int[], $2::text[], $3::text[]) AS v(id, value, issue)
+			WHERE EXISTS (SELECT 1 FROM customers c WHERE c.id = v.id)
+			ON CONFLICT DO NOTHING`,
+			issueIDs, issueValues, issueKinds,
+		)
+		if err != nil {
+			return result, fmt.Errorf("failed to store contact issues: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	contactIssues.WithLabelValues(IssueInvalidEmail).Set(float64(result.Invalid))
+	contactIssues.WithLabelValues(IssueDuplicateEmail).Set(float64(result.Duplicates))
+	tracing.Printf(ctx, "Contact normalization checked %d emails: %d normalized, %d invalid, %d duplicates",
+		result.Checked, result.Normalized, result.Invalid, result.Duplicates)
+	return result, nil
+}
+
+// NormalizeEmail trims surrounding whitespace (including stray tabs and
+// newlines from CSV imports) and lowercases the address
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidEmail reports whether email has a deliverable format: a dot-atom local
+// part of at most 64 characters and a domain of hostname labels ending in an
+// alphabetic (or punycode) top-level domain, 254 characters in total
+func ValidEmail(email string) bool {
+	if len(email) > 254 || strings.Count(email, "@") != 1 {
+		return false
+	}
+	local, domain, _ := strings.Cut(email, "@")
+
+	if local == "" || len(local) > 64 || local[0] == '.' || local[len(local)-1] == '.' || strings.Contains(local, "..") {
+		return false
+	}
+	for _, r := range local {
+		if !isAlphanumeric(r) && !strings.ContainsRune("!#$%&'*+/=?^_`{|}~.-", r) {
+			return false
+		}
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !isAlphanumeric(r) && r != '-' {
+				return false
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if strings.HasPrefix(tld, "xn--") {
+		return true
+	}
+	if len(tld) < 2 {
+		return false
+	}
+	for _, r := range tld {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
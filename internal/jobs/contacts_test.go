@@ -0,0 +1,36 @@
+package jobs
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := NormalizeEmail("  Jane.Doe@Example.COM\t\n"); got != "jane.doe@example.com" {
+		t.Errorf("Expected jane.doe@example.com, got %q", got)
+	}
+}
+
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		valid bool
+	}{
+		{"jane.doe@example.com", true},
+		{"ops+alerts@mail.example.co.uk", true},
+		{"user@xn--bcher-kva.xn--p1ai", true},
+		{"jane@localhost", false},
+		{"jane@@example.com", false},
+		{"jane.@example.com", false},
+		{"ja..ne@example.com", false},
+		{"jane doe@example.com", false},
+		{"jane@-example.com", false},
+		{"jane@example.c", false},
+		{"jane@example.123", false},
+		{"@example.com", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidEmail(tt.email); got != tt.valid {
+			t.Errorf("ValidEmail(%q) = %v, expected %v", tt.email, got, tt.valid)
+		}
+	}
+}
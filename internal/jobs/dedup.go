@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypeDetectDuplicates = "dedup:accounts"
+)
+
+// normalizedAccounts strips case, punctuation, and filler words such as
+// "Inc" or "Account" so "Acme, Inc." and "ACME Account" compare equal
+const normalizedAccounts = `
+	SELECT id, customer_id, name, status, created_at,
+		regexp_replace(
+			regexp_replace(lower(name), '\m(inc|llc|ltd|co|corp|company|account|acct)\M', '', 'g'),
+			'[^a-z0-9]+', '', 'g'
+		) AS norm
+	FROM accounts
+	WHERE deleted_at IS NULL`
+
+// NewDuplicateDetectionTask creates a new duplicate account detection task
+func NewDuplicateDetectionTask() *asynq.Task {
+	return asynq.NewTask(TypeDetectDuplicates, nil)
+}
+
+// HandleDuplicateDetectionTask finds likely duplicate accounts within each
+// customer on the follower pool and replaces the stored duplicate report.
+// Tune with DEDUP_MIN_SCORE (default 0.7) and DEDUP_WINDOW (default 24h).
+func HandleDuplicateDetectionTask(ctx context.Context, t *asynq.Task) error {
+	minScore := getEnvFloat("DEDUP_MIN_SCORE", 0.7)
+	window := 24 * time.Hour
+	if value, err := time.ParseDuration(os.Getenv("DEDUP_WINDOW")); err == nil && value > 0 {
+		window = value
+	}
+
+	analyticsDB := db.Analytics(ctx)
+
+	// Without pg_trgm only identical normalized names match
+	var hasTrigram bool
+	if err := analyticsDB.QueryRow("SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").Scan(&hasTrigram); err != nil {
+		return fmt.Errorf("failed to check for pg_trgm: %w", err)
+	}
+	similarity := "CASE WHEN a.norm = b.norm THEN 1.0 ELSE 0.0 END"
+	if hasTrigram {
+		similarity = "CASE WHEN a.norm = b.norm THEN 1.0 ELSE similarity(a.norm, b.norm) END"
+	}
+
+	rows, err := analyticsDB.Query(fmt.Sprintf(`
+		WITH normalized AS (%s)
+		SELECT a.customer_id, a.id, a.status, b.id, b.status,
+			%s AS name_similarity,
+			ABS(EXTRACT(EPOCH FROM (b.created_at - a.created_at)))
+		FROM normalized a
+		JOIN normalized b ON b.customer_id = a.customer_id AND b.id > a.id
+		WHERE a.norm <> '' AND %s >= $1`, normalizedAccounts, similarity, similarity),
+		// A pair can't reach minScore if its names are less similar than this
+		math.Max(0, (minScore-0.2)/0.8),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate accounts: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		customerID, keepID, mergeID  int
+		score, nameSimilarity, apart float64
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var firstID, secondID int
+		var firstStatus, secondStatus string
+		if err := rows.Scan(&c.customerID, &firstID, &firstStatus, &secondID, &secondStatus, &c.nameSimilarity, &c.apart); err != nil {
+			return err
+		}
+
+		c.score = DuplicateScore(c.nameSimilarity, time.Duration(c.apart*float64(time.Second)), window)
+		if c.score < minScore {
+			continue
+		}
+
+		// Keep the older account unless only the newer one is still active
+		c.keepID, c.mergeID = firstID, secondID
+		if firstStatus != "active" && secondStatus == "active" {
+			c.keepID, c.mergeID = secondID, firstID
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Primary(ctx).Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM account_duplicate_candidates"); err != nil {
+		return fmt.Errorf("failed to clear duplicate report: %w", err)
+	}
+	for _, c := range candidates {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO account_duplicate_candidates (customer_id, keep_account_id, merge_account_id, score, name_similarity, created_apart_seconds)
+			 VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+			c.customerID, c.keepID, c.mergeID, c.score, c.nameSimilarity, c.apart,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store duplicate candidate: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	tracing.Printf(ctx, "Duplicate detection found %d likely duplicate accounts", len(candidates))
+	return nil
+}
+
+// DuplicateScore combines name similarity (80%) with how close together two
+// accounts were created (20%, decaying linearly to zero at window). Duplicates
+// are typically created by the same person retrying within a short time.
+func DuplicateScore(nameSimilarity float64, apart, window time.Duration) float64 {
+	timing := 0.0
+	if apart < window {
+		timing = 1 - float64(apart)/float64(window)
+	}
+	return 0.8*nameSimilarity + 0.2*timing
+}
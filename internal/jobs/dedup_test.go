@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDuplicateScore(t *testing.T) {
+	window := 24 * time.Hour
+
+	// Same name created minutes apart is a near-certain duplicate
+	if score := DuplicateScore(1, 5*time.Minute, window); score < 0.99 {
+		t.Errorf("Expected near-certain duplicate, got %.3f", score)
+	}
+
+	// Same name created long ago still scores on the name alone
+	if score := DuplicateScore(1, 30*24*time.Hour, window); score != 0.8 {
+		t.Errorf("Expected 0.8 outside the window, got %.3f", score)
+	}
+
+	// Loosely similar names created far apart fall below the default threshold
+	if score := DuplicateScore(0.5, 48*time.Hour, window); score >= 0.7 {
+		t.Errorf("Expected score below 0.7, got %.3f", score)
+	}
+}
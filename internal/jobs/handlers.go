@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+// taskHandlers are the handlers of the background tasks, by task type
+var taskHandlers = map[string]asynq.HandlerFunc{
+	TypeAggregateData:      HandleAggregationTask,
+	TypeDetectAnomalies:    HandleAnomalyDetectionTask,
+	TypeCheckIntegrity:     HandleIntegrityCheckTask,
+	TypeDetectDuplicates:   HandleDuplicateDetectionTask,
+	TypeNormalizeContacts:  HandleContactNormalizationTask,
+	TypeRefreshHeatmap:     HandleHeatmapRefreshTask,
+	TypeScoreDataQuality:   HandleDataQualityTask,
+	TypeMaintainPartitions: HandlePartitionMaintenanceTask,
+	TypeGenerateStatements: HandleStatementTask,
+	TypeEvaluateSLAs:       HandleSLAEvaluationTask,
+}
+
+// RegisterTasks registers the background task handlers on an Asynq mux
+func RegisterTasks(mux *asynq.ServeMux) {
+	for taskType, handler := range taskHandlers {
+		mux.HandleFunc(taskType, handler)
+	}
+}
+
+// RegisterQueueHandlers registers the background tasks on a worker of the
+// Postgres queue, so they can run there too, e.g. on apps without REDIS_URL.
+// A job's payload is the task's.
+func RegisterQueueHandlers(w *Worker) {
+	for taskType, handler := range taskHandlers {
+		w.Handle(taskType, taskHandler(handler))
+	}
+}
+
+// taskHandler runs an Asynq task handler for a queued job
+func taskHandler(handler asynq.HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, job QueuedJob) error {
+		err := handler(ctx, asynq.NewTask(job.Type, job.Payload))
+		if errors.Is(err, asynq.SkipRetry) {
+			return fmt.Errorf("%w: %v", SkipRetry, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"saas-go-app/internal/db"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypeRefreshHeatmap = "analytics:heatmap"
+)
+
+// NewHeatmapRefreshTask creates a new usage heatmap refresh task
+func NewHeatmapRefreshTask() *asynq.Task {
+	return asynq.NewTask(TypeRefreshHeatmap, nil)
+}
+
+// HandleHeatmapRefreshTask refreshes the usage heatmap rollup
+func HandleHeatmapRefreshTask(ctx context.Context, t *asynq.Task) error {
+	return RefreshHeatmap(ctx)
+}
+
+// RefreshHeatmap recomputes the usage_heatmap materialized view from the API
+// usage rollups. It runs on the primary, since followers are read-only, and
+// CONCURRENTLY so heatmap reads aren't blocked while it runs; followers pick
+// up the new contents through replication.
+func RefreshHeatmap(ctx context.Context) error {
+	if _, err := db.Primary(ctx).Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY usage_heatmap"); err != nil {
+		return fmt.Errorf("failed to refresh usage heatmap: %w", err)
+	}
+	return nil
+}
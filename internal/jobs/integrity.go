@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	TypeCheckIntegrity = "integrity:check"
+)
+
+var integrityViolations = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "integrity_violations",
+	Help: "Rows violating each data integrity invariant as of the last check (after any repair).",
+}, []string{"check"})
+
+// IntegrityPayload represents the payload for integrity check jobs
+type IntegrityPayload struct {
+	Repair bool          `json:"repair"`
+	Trace  tracing.Trace `json:"trace,omitempty"`
+}
+
+// NewIntegrityCheckTask creates a new integrity check task
+func NewIntegrityCheckTask(repair bool) (*asynq.Task, error) {
+	payload, err := json.Marshal(IntegrityPayload{Repair: repair})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeCheckIntegrity, payload), nil
+}
+
+// HandleIntegrityCheckTask verifies data invariants, repairing violations when
+// the task asks for it or INTEGRITY_REPAIR=true
+func HandleIntegrityCheckTask(ctx context.Context, t *asynq.Task) error {
+	var payload IntegrityPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return err
+	}
+
+	_, err := RunIntegrityChecks(ctx, payload.Repair || os.Getenv("INTEGRITY_REPAIR") == "true")
+	return err
+}
+
+// RunIntegrityChecks runs every integrity check, publishes the remaining
+// violation counts as metrics, and notifies operators about anything left unrepaired
+func RunIntegrityChecks(ctx context.Context, repair bool) ([]db.IntegrityResult, error) {
+	results, err := db.CheckIntegrity(ctx, repair)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, result := range results {
+		remaining := int64(result.Violations) - result.Repaired
+		integrityViolations.WithLabelValues(result.Check).Set(float64(remaining))
+
+		if result.Repaired > 0 {
+			tracing.Printf(ctx, "Integrity check %s: repaired %d of %d violations", result.Check, result.Repaired, result.Violations)
+		}
+		if remaining > 0 {
+			tracing.Printf(ctx, "Integrity check %s: %d violations", result.Check, remaining)
+			problems = append(problems, fmt.Sprintf("%s (%d)", result.Check, remaining))
+		}
+	}
+
+	if len(problems) > 0 {
+		message := "Integrity checks found violations: " + strings.Join(problems, ", ")
+		if err := db.CreateNotifications(ctx, "integrity", message, notifyRecipients("INTEGRITY_NOTIFY_USERS")...); err != nil {
+			tracing.Printf(ctx, "Warning: Failed to notify about integrity violations: %v", err)
+		}
+	}
+
+	return results, nil
+}
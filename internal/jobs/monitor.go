@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "jobs_queue_depth",
+		Help: "Tasks waiting to be processed, by queue.",
+	}, []string{"queue"})
+	queueOldestAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "jobs_queue_oldest_pending_age_seconds",
+		Help: "Age of the oldest task waiting to be processed, by queue.",
+	}, []string{"queue"})
+	workersAlive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "jobs_workers",
+		Help: "Job processors with a live heartbeat in Redis.",
+	})
+	workerLastSeen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "jobs_worker_last_seen_timestamp_seconds",
+		Help: "Unix time a job processor heartbeat was last seen.",
+	})
+)
+
+// QueueStats describes one queue at the last poll
+type QueueStats struct {
+	Queue string `json:"queue"`
+	// Depth is the number of tasks waiting to be processed
+	Depth            int     `json:"depth"`
+	Active           int     `json:"active"`
+	Retry            int     `json:"retry"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	Paused           bool    `json:"paused"`
+}
+
+// QueueHealth is the state of the background job queues and the thresholds
+// they are judged against
+type QueueHealth struct {
+	Status           string       `json:"status"` // ok, degraded, or unknown before the first poll
+	Reasons          []string     `json:"reasons,omitempty"`
+	Workers          int          `json:"workers"`
+	WorkerLastSeen   *time.Time   `json:"worker_last_seen,omitempty"`
+	Depth            int          `json:"depth"`
+	OldestAgeSeconds float64      `json:"oldest_age_seconds"`
+	BacklogThreshold int          `json:"backlog_threshold"`
+	MaxAgeSeconds    float64      `json:"max_age_seconds"`
+	Queues           []QueueStats `json:"queues"`
+	CheckedAt        *time.Time   `json:"checked_at,omitempty"`
+	Error            string       `json:"error,omitempty"`
+}
+
+// Monitor polls queue depth and worker heartbeats so readiness checks and
+// scrapes never hit Redis themselves
+type Monitor struct {
+	inspector        *asynq.Inspector
+	backlogThreshold int
+	maxAge           time.Duration
+
+	mu       sync.RWMutex
+	health   QueueHealth
+	lastSeen *time.Time
+}
+
+var (
+	defaultMonitorMu sync.RWMutex
+	defaultMonitor   *Monitor
+)
+
+// StartMonitor starts polling the queues in redisURL every interval and makes
+// the results available from CurrentQueueHealth
+func StartMonitor(ctx context.Context, redisURL string, interval time.Duration) *Monitor {
+	m := &Monitor{
+		inspector:        asynq.NewInspector(asynq.RedisClientOpt{Addr: redisURL}),
+		backlogThreshold: BacklogThreshold(),
+		maxAge:           MaxJobAge(),
+	}
+	m.health = QueueHealth{Status: "unknown", Queues: []QueueStats{}, BacklogThreshold: m.backlogThreshold, MaxAgeSeconds: m.maxAge.Seconds()}
+
+	defaultMonitorMu.Lock()
+	defaultMonitor = m
+	defaultMonitorMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			m.Poll()
+			select {
+			case <-ctx.Done():
+				m.inspector.Close()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return m
+}
+
+// CurrentQueueHealth returns the last polled queue health, or false if no
+// monitor is running (REDIS_URL is not set)
+func CurrentQueueHealth() (QueueHealth, bool) {
+	defaultMonitorMu.RLock()
+	m := defaultMonitor
+	defaultMonitorMu.RUnlock()
+	if m == nil {
+		return QueueHealth{}, false
+	}
+	return m.Health(), true
+}
+
+// Health returns the result of the last poll
+func (m *Monitor) Health() QueueHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	health := m.health
+	health.Queues = append([]QueueStats{}, m.health.Queues...)
+	health.Reasons = append([]string(nil), m.health.Reasons...)
+	return health
+}
+
+// Poll reads queue stats and worker heartbeats from Redis and updates the gauges
+func (m *Monitor) Poll() {
+	now := time.Now().UTC()
+	health := QueueHealth{
+		Status:           "ok",
+		Queues:           []QueueStats{},
+		BacklogThreshold: m.backlogThreshold,
+		MaxAgeSeconds:    m.maxAge.Seconds(),
+		CheckedAt:        &now,
+	}
+
+	if err := m.collect(&health); err != nil {
+		log.Printf("Warning: Failed to poll job queues: %v", err)
+		health.Status = "degraded"
+		health.Error = err.Error()
+		health.Reasons = []string{"queue stats unavailable"}
+	}
+
+	m.mu.Lock()
+	if health.Workers > 0 {
+		m.lastSeen = &now
+		workerLastSeen.Set(float64(now.Unix()))
+	}
+	health.WorkerLastSeen = m.lastSeen
+	m.health = health
+	m.mu.Unlock()
+}
+
+func (m *Monitor) collect(health *QueueHealth) error {
+	servers, err := m.inspector.Servers()
+	if err != nil {
+		return fmt.Errorf("failed to list workers: %w", err)
+	}
+	health.Workers = len(servers)
+	workersAlive.Set(float64(len(servers)))
+
+	queues, err := m.inspector.Queues()
+	if err != nil {
+		return fmt.Errorf("failed to list queues: %w", err)
+	}
+	sort.Strings(queues)
+	for _, queue := range queues {
+		info, err := m.inspector.GetQueueInfo(queue)
+		if err != nil {
+			return fmt.Errorf("failed to read queue %s: %w", queue, err)
+		}
+		stats := QueueStats{
+			Queue:            queue,
+			Depth:            info.Pending,
+			Active:           info.Active,
+			Retry:            info.Retry,
+			OldestAgeSeconds: info.Latency.Seconds(),
+			Paused:           info.Paused,
+		}
+		health.Queues = append(health.Queues, stats)
+		health.Depth += stats.Depth
+		if stats.OldestAgeSeconds > health.OldestAgeSeconds {
+			health.OldestAgeSeconds = stats.OldestAgeSeconds
+		}
+		queueDepth.WithLabelValues(queue).Set(float64(stats.Depth))
+		queueOldestAge.WithLabelValues(queue).Set(stats.OldestAgeSeconds)
+	}
+
+	health.Reasons = evaluate(*health)
+	if len(health.Reasons) > 0 {
+		health.Status = "degraded"
+	}
+	return nil
+}
+
+// evaluate returns why the queues count as degraded, if they do
+func evaluate(health QueueHealth) []string {
+	var reasons []string
+	if health.BacklogThreshold > 0 && health.Depth > health.BacklogThreshold {
+		reasons = append(reasons, fmt.Sprintf("backlog of %d tasks exceeds %d", health.Depth, health.BacklogThreshold))
+	}
+	if health.MaxAgeSeconds > 0 && health.OldestAgeSeconds > health.MaxAgeSeconds {
+		reasons = append(reasons, fmt.Sprintf("oldest task has waited %.0fs, more than %.0fs", health.OldestAgeSeconds, health.MaxAgeSeconds))
+	}
+	if health.Workers == 0 && health.Depth > 0 {
+		reasons = append(reasons, "tasks are waiting but no worker heartbeat was found")
+	}
+	return reasons
+}
+
+// BacklogThreshold reads QUEUE_BACKLOG_THRESHOLD, the number of waiting tasks
+// above which readiness is degraded (default 1000, 0 disables)
+func BacklogThreshold() int {
+	return int(getEnvFloat("QUEUE_BACKLOG_THRESHOLD", 1000))
+}
+
+// MaxJobAge reads QUEUE_MAX_JOB_AGE, how long the oldest task may wait before
+// readiness is degraded (default 10m, 0 disables)
+func MaxJobAge() time.Duration {
+	value := os.Getenv("QUEUE_MAX_JOB_AGE")
+	if value == "" {
+		return 10 * time.Minute
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		log.Printf("Warning: Invalid value for QUEUE_MAX_JOB_AGE (%s), using default 10m", value)
+		return 10 * time.Minute
+	}
+	return age
+}
+
+// MonitorInterval reads QUEUE_MONITOR_INTERVAL (default 15s)
+func MonitorInterval() time.Duration {
+	value := os.Getenv("QUEUE_MONITOR_INTERVAL")
+	if value == "" {
+		return 15 * time.Second
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Warning: Invalid value for QUEUE_MONITOR_INTERVAL (%s), using default 15s", value)
+		return 15 * time.Second
+	}
+	return interval
+}
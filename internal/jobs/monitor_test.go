@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateQueueHealth(t *testing.T) {
+	healthy := QueueHealth{Workers: 1, Depth: 10, OldestAgeSeconds: 5, BacklogThreshold: 1000, MaxAgeSeconds: 600}
+	if reasons := evaluate(healthy); len(reasons) != 0 {
+		t.Errorf("Expected a healthy queue, got %v", reasons)
+	}
+
+	backlogged := healthy
+	backlogged.Depth = 1500
+	backlogged.OldestAgeSeconds = 900
+	if reasons := evaluate(backlogged); len(reasons) != 2 {
+		t.Errorf("Expected backlog and age reasons, got %v", reasons)
+	}
+
+	noWorkers := healthy
+	noWorkers.Workers = 0
+	if reasons := evaluate(noWorkers); len(reasons) != 1 {
+		t.Errorf("Expected waiting tasks without workers to be degraded, got %v", reasons)
+	}
+
+	disabled := QueueHealth{Workers: 1, Depth: 5000, OldestAgeSeconds: 5000}
+	if reasons := evaluate(disabled); len(reasons) != 0 {
+		t.Errorf("Expected zero thresholds to disable the checks, got %v", reasons)
+	}
+}
+
+func TestMaxJobAgeFromEnv(t *testing.T) {
+	t.Setenv("QUEUE_MAX_JOB_AGE", "2m")
+	if age := MaxJobAge(); age != 2*time.Minute {
+		t.Errorf("Expected 2m, got %v", age)
+	}
+	t.Setenv("QUEUE_MAX_JOB_AGE", "soon")
+	if age := MaxJobAge(); age != 10*time.Minute {
+		t.Errorf("Expected default 10m for an invalid value, got %v", age)
+	}
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+
+	"saas-go-app/internal/db"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypeMaintainPartitions = "partitions:accounts"
+)
+
+// NewPartitionMaintenanceTask creates a new account partition maintenance task
+func NewPartitionMaintenanceTask() *asynq.Task {
+	return asynq.NewTask(TypeMaintainPartitions, nil)
+}
+
+// HandlePartitionMaintenanceTask creates the upcoming monthly partitions of a
+// partitioned accounts table, so new accounts never land in the default one
+func HandlePartitionMaintenanceTask(ctx context.Context, t *asynq.Task) error {
+	return db.MaintainAccountPartitions(ctx)
+}
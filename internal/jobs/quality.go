@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	TypeScoreDataQuality = "quality:score"
+)
+
+var dataQualityFailing = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "data_quality_failing_ratio",
+	Help: "Share of rows failing each data quality check in the last run.",
+}, []string{"table", "metric"})
+
+// DataQualityCheck is one completeness check: rows of Table matching
+// Condition (with the table aliased as t) fail it
+type DataQualityCheck struct {
+	Table       string
+	Metric      string
+	Description string
+	Condition   string
+}
+
+// defaultAccountName matches placeholder names such as "Account", "New
+// Account", "Premium Account", or "Account 2" once lowercased and trimmed.
+// It is used both in Go and as a Postgres regular expression, so it sticks to
+// syntax the two share.
+var defaultAccountName = regexp.MustCompile(`^((([a-z]+) )?account( ?#?[0-9]+)?|untitled|default|new|test)?$`)
+
+// IsDefaultAccountName reports whether an account name looks like a placeholder
+func IsDefaultAccountName(name string) bool {
+	return defaultAccountName.MatchString(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// DataQualityChecks returns the checks a run measures. Rows count as stale
+// when they haven't been updated for DATA_QUALITY_STALE_AFTER (default 8760h,
+// one year).
+func DataQualityChecks() []DataQualityCheck {
+	staleAfter := 365 * 24 * time.Hour
+	if value, err := time.ParseDuration(os.Getenv("DATA_QUALITY_STALE_AFTER")); err == nil && value > 0 {
+		staleAfter = value
+	}
+	stale := fmt.Sprintf("t.updated_at < NOW() - INTERVAL '%d seconds'", int64(staleAfter.Seconds()))
+
+	return []DataQualityCheck{
+		{"customers", "missing_email", "Customers without an email address", "trim(t.email) = ''"},
+		{"customers", "invalid_email", "Customers whose email was flagged by contact normalization", "EXISTS (SELECT 1 FROM contact_issues i WHERE i.customer_id = t.id AND i.issue = 'invalid_format')"},
+		{"customers", "without_accounts", "Customers with no accounts", "NOT EXISTS (SELECT 1 FROM accounts a WHERE a.customer_id = t.id)"},
+		{"customers", "stale", "Customers not updated within DATA_QUALITY_STALE_AFTER", stale},
+		{"accounts", "default_name", "Accounts still named like a placeholder, e.g. \"Premium Account\"", "lower(trim(t.name)) ~ '" + defaultAccountName.String() + "'"},
+		{"accounts", "missing_reference", "Accounts without a reference", "t.reference IS NULL"},
+		{"accounts", "stale", "Accounts not updated within DATA_QUALITY_STALE_AFTER", stale},
+	}
+}
+
+// NewDataQualityTask creates a new data quality scoring task
+func NewDataQualityTask() *asynq.Task {
+	return asynq.NewTask(TypeScoreDataQuality, nil)
+}
+
+// HandleDataQualityTask measures data quality and stores the results
+func HandleDataQualityTask(ctx context.Context, t *asynq.Task) error {
+	_, err := RunDataQuality(ctx)
+	return err
+}
+
+// RunDataQuality counts the rows failing each check on the follower pool and
+// replaces the stored metrics on the primary in a single batch
+func RunDataQuality(ctx context.Context) ([]models.DataQualityMetric, error) {
+	analyticsDB := db.Analytics(ctx)
+	measuredAt := time.Now().UTC()
+
+	var metrics []models.DataQualityMetric
+	for _, check := range DataQualityChecks() {
+		metric := models.DataQualityMetric{Table: check.Table, Metric: check.Metric, Description: check.Description, MeasuredAt: measuredAt}
+		err := analyticsDB.QueryRow(
+			"SELECT COUNT(*) FILTER (WHERE "+check.Condition+"), COUNT(*) FROM "+check.Table+" t",
+		).Scan(&metric.Failing, &metric.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure %s.%s: %w", check.Table, check.Metric, err)
+		}
+		metrics = append(metrics, metric)
+	}
+
+	// Replace the stored metrics in one round trip; the batch is atomic
+	batch := &pgx.Batch{}
+	batch.Queue("DELETE FROM data_quality_metrics")
+	for _, metric := range metrics {
+		batch.Queue(
+			`INSERT INTO data_quality_metrics (table_name, metric, description, failing, total, measured_at)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			metric.Table, metric.Metric, metric.Description, metric.Failing, metric.Total, metric.MeasuredAt,
+		)
+	}
+	if err := db.SendBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to store data quality metrics: %w", err)
+	}
+
+	dataQualityFailing.Reset()
+	for _, metric := range metrics {
+		dataQualityFailing.WithLabelValues(metric.Table, metric.Metric).Set(metric.Ratio())
+	}
+	tracing.Printf(ctx, "Data quality run measured %d checks", len(metrics))
+	return metrics, nil
+}
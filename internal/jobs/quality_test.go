@@ -0,0 +1,16 @@
+package jobs
+
+import "testing"
+
+func TestIsDefaultAccountName(t *testing.T) {
+	for _, name := range []string{"Account", "Premium Account", " new account ", "Account #2", "Untitled", ""} {
+		if !IsDefaultAccountName(name) {
+			t.Errorf("Expected %q to be a default name", name)
+		}
+	}
+	for _, name := range []string{"Acme Payroll", "Account Receivables Team", "Marketing Budget"} {
+		if IsDefaultAccountName(name) {
+			t.Errorf("Expected %q not to be a default name", name)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+)
+
+// Besides the Redis-backed tasks, jobs can be queued in Postgres
+// (queued_jobs). Enqueueing can then share a transaction with the change the
+// job belongs to, and jobs run without REDIS_URL. Enqueue adds a job, and a
+// Worker (see worker.go and cmd/worker) runs it with the request ID and actor
+// of the request that queued it.
+
+// DefaultQueue is the queue jobs go to unless EnqueueOptions name another
+const DefaultQueue = "default"
+
+// defaultMaxAttempts is how often a job runs before it is dead, unless
+// EnqueueOptions set another limit
+const defaultMaxAttempts = 5
+
+// Statuses of queued jobs
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
+
+// ErrJobNotFound is returned for a job that doesn't exist or isn't in the
+// state an operation needs
+var ErrJobNotFound = errors.New("job not found")
+
+// QueuedJob is a job in the Postgres queue
+type QueuedJob struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue"`
+	Type  string `json:"type"`
+	// Payload is the JSON the job was queued with
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	// RunAt is when the job is due, or due again after a failed attempt
+	RunAt      time.Time  `json:"run_at"`
+	WorkerID   string     `json:"worker_id,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	trace tracing.Trace
+	actor string
+}
+
+// Decode unmarshals the job's payload into v
+func (j QueuedJob) Decode(v interface{}) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// EnqueueOptions adjust how a job is queued; the zero value queues it on
+// DefaultQueue to run now, up to 5 times
+type EnqueueOptions struct {
+	Queue       string
+	MaxAttempts int
+	RunAt       time.Time
+}
+
+const enqueueSQL = `INSERT INTO queued_jobs (queue, type, payload, max_attempts, run_at, request_id, traceparent, actor)
+	VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))
+	RETURNING id`
+
+// Enqueue queues a job of jobType with payload encoded as JSON and returns
+// its id. The job carries the trace and actor of ctx.
+func Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (int64, error) {
+	args, err := enqueueArgs(ctx, jobType, payload, opts)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = db.Primary(ctx).QueryRow(enqueueSQL, args...).Scan(&id)
+	return id, err
+}
+
+// EnqueueTx is Enqueue in tx, so the job is only queued if tx commits
+func EnqueueTx(ctx context.Context, tx *sql.Tx, jobType string, payload interface{}, opts EnqueueOptions) (int64, error) {
+	args, err := enqueueArgs(ctx, jobType, payload, opts)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRowContext(ctx, enqueueSQL, args...).Scan(&id)
+	return id, err
+}
+
+// enqueueArgs returns the arguments of enqueueSQL
+func enqueueArgs(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) ([]interface{}, error) {
+	if payload == nil {
+		payload = struct{}{}
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Queue == "" {
+		opts.Queue = DefaultQueue
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	var runAt *time.Time
+	if !opts.RunAt.IsZero() {
+		runAt = &opts.RunAt
+	}
+	trace := tracing.FromContext(ctx)
+	return []interface{}{opts.Queue, jobType, encoded, opts.MaxAttempts, runAt, trace.RequestID, trace.Traceparent, auth.ActorFromContext(ctx)}, nil
+}
+
+const queuedJobColumns = `id, queue, type, payload, status, attempts, max_attempts, run_at, COALESCE(worker_id, ''),
+	COALESCE(last_error, ''), created_at, finished_at, COALESCE(request_id, ''), COALESCE(traceparent, ''), COALESCE(actor, '')`
+
+func scanQueuedJob(row interface{ Scan(...interface{}) error }) (QueuedJob, error) {
+	var job QueuedJob
+	var finishedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.Queue, &job.Type, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &job.WorkerID,
+		&job.LastError, &job.CreatedAt, &finishedAt, &job.trace.RequestID, &job.trace.Traceparent, &job.actor)
+	if err == sql.ErrNoRows {
+		return job, ErrJobNotFound
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, err
+}
+
+// ListQueuedJobs returns up to limit jobs, newest first, with status and of
+// jobType unless they are ""
+func ListQueuedJobs(ctx context.Context, status, jobType string, limit int) ([]QueuedJob, error) {
+	rows, err := db.Primary(ctx).Query(`
+		SELECT `+queuedJobColumns+` FROM queued_jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR type = $2)
+		ORDER BY id DESC LIMIT $3`,
+		status, jobType, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []QueuedJob{}
+	for rows.Next() {
+		job, err := scanQueuedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// RetryJob queues a dead job again to run now, with its attempts reset. It
+// returns ErrJobNotFound if there is no dead job with id.
+func RetryJob(ctx context.Context, id int64) (QueuedJob, error) {
+	return scanQueuedJob(db.Primary(ctx).QueryRow(`
+		UPDATE queued_jobs SET status = 'pending', attempts = 0, run_at = CURRENT_TIMESTAMP, worker_id = NULL, finished_at = NULL
+		WHERE id = $1 AND status = 'dead'
+		RETURNING `+queuedJobColumns,
+		id,
+	))
+}
+
+// WorkerTypes returns the job types handled by workers with a recent
+// heartbeat, so jobs no worker would run can be refused
+func WorkerTypes(ctx context.Context) ([]string, error) {
+	rows, err := db.Primary(ctx).Query(
+		"SELECT DISTINCT unnest(types) FROM job_workers WHERE last_seen_at > CURRENT_TIMESTAMP - $1 * INTERVAL '1 second' ORDER BY 1",
+		workerStaleAfter.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := []string{}
+	for rows.Next() {
+		var jobType string
+		if err := rows.Scan(&jobType); err != nil {
+			return nil, err
+		}
+		types = append(types, jobType)
+	}
+	return types, rows.Err()
+}
@@ -0,0 +1,118 @@
+package jobs
+
+import (
+	"log"
+	"os"
+
+	"saas-go-app/internal/db"
+
+	"github.com/hibiken/asynq"
+)
+
+// NewScheduler creates an Asynq scheduler with all periodic tasks registered
+func NewScheduler(redisURL string) (*asynq.Scheduler, error) {
+	scheduler := asynq.NewScheduler(asynq.RedisClientOpt{Addr: redisURL}, nil)
+
+	// Anomaly detection runs every 15 minutes unless ANOMALY_DETECTION_SCHEDULE overrides it
+	spec := os.Getenv("ANOMALY_DETECTION_SCHEDULE")
+	if spec == "" {
+		spec = "@every 15m"
+	}
+	if _, err := scheduler.Register(spec, NewAnomalyDetectionTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled anomaly detection: %s", spec)
+
+	// Integrity checks report hourly; set INTEGRITY_REPAIR=true to also repair
+	spec = os.Getenv("INTEGRITY_CHECK_SCHEDULE")
+	if spec == "" {
+		spec = "@every 1h"
+	}
+	integrityTask, err := NewIntegrityCheckTask(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := scheduler.Register(spec, integrityTask, asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled integrity checks: %s", spec)
+
+	// Duplicate account detection refreshes the dedup report every 6 hours
+	spec = os.Getenv("DEDUP_SCHEDULE")
+	if spec == "" {
+		spec = "@every 6h"
+	}
+	if _, err := scheduler.Register(spec, NewDuplicateDetectionTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled duplicate account detection: %s", spec)
+
+	// Contact normalization cleans up emails daily, e.g. after CSV imports
+	spec = os.Getenv("CONTACT_NORMALIZE_SCHEDULE")
+	if spec == "" {
+		spec = "@every 24h"
+	}
+	if _, err := scheduler.Register(spec, NewContactNormalizationTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled contact normalization: %s", spec)
+
+	// The usage heatmap rollup is refreshed hourly, matching the usage buckets
+	spec = os.Getenv("HEATMAP_REFRESH_SCHEDULE")
+	if spec == "" {
+		spec = "@every 1h"
+	}
+	if _, err := scheduler.Register(spec, NewHeatmapRefreshTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled usage heatmap refresh: %s", spec)
+
+	// Data quality metrics are re-measured every 6 hours
+	spec = os.Getenv("DATA_QUALITY_SCHEDULE")
+	if spec == "" {
+		spec = "@every 6h"
+	}
+	if _, err := scheduler.Register(spec, NewDataQualityTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled data quality scoring: %s", spec)
+
+	// Last month's statements are generated daily; customers that already
+	// have theirs are skipped, so only the first run of a month does much
+	spec = os.Getenv("STATEMENT_SCHEDULE")
+	if spec == "" {
+		spec = "@every 24h"
+	}
+	statementTask, err := NewStatementTask("")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := scheduler.Register(spec, statementTask, asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled statement generation: %s", spec)
+
+	// Account lifecycle SLAs are checked hourly unless SLA_SCHEDULE overrides it
+	spec = os.Getenv("SLA_SCHEDULE")
+	if spec == "" {
+		spec = "@every 1h"
+	}
+	if _, err := scheduler.Register(spec, NewSLAEvaluationTask(), asynq.Queue("low")); err != nil {
+		return nil, err
+	}
+	log.Printf("Scheduled SLA evaluation: %s", spec)
+
+	// Partitioned accounts get their upcoming monthly partitions created daily
+	if db.PartitionedSchema() {
+		spec = os.Getenv("PARTITION_MAINTENANCE_SCHEDULE")
+		if spec == "" {
+			spec = "@every 24h"
+		}
+		if _, err := scheduler.Register(spec, NewPartitionMaintenanceTask(), asynq.Queue("low")); err != nil {
+			return nil, err
+		}
+		log.Printf("Scheduled account partition maintenance: %s", spec)
+	}
+
+	return scheduler, nil
+}
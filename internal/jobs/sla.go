@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	TypeEvaluateSLAs = "sla:evaluate"
+)
+
+// maxOpenBreaches caps the open breaches listed per rule in SLACompliance
+const maxOpenBreaches = 20
+
+var slaBreachedAccounts = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sla_breached_accounts",
+	Help: "Accounts past the deadline of each SLA rule in the last evaluation, by rule ID.",
+}, []string{"rule"})
+
+// slaEnteredAt is when the account aliased as a entered its current status:
+// when its last version in another status ended, or when its first version
+// started if it never had another status (see db.VersionedTables)
+const slaEnteredAt = `COALESCE(
+	(SELECT MAX(h.valid_to) FROM accounts_history h WHERE h.id = a.id AND h.data->>'status' <> a.status),
+	(SELECT MIN(h.valid_from) FROM accounts_history h WHERE h.id = a.id),
+	a.created_at)`
+
+// NewSLAEvaluationTask creates a new SLA evaluation task
+func NewSLAEvaluationTask() *asynq.Task {
+	return asynq.NewTask(TypeEvaluateSLAs, nil)
+}
+
+// HandleSLAEvaluationTask records SLA breaches and notifies operators of new ones
+func HandleSLAEvaluationTask(ctx context.Context, t *asynq.Task) error {
+	_, err := EvaluateSLAs(ctx)
+	return err
+}
+
+// SLARules returns the defined SLA rules by ID
+func SLARules(handle db.Handle) ([]models.SLARule, error) {
+	rows, err := handle.Query("SELECT id, name, from_status, to_status, within_days, created_by, created_at FROM sla_rules ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SLA rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.SLARule{}
+	for rows.Next() {
+		var rule models.SLARule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.FromStatus, &rule.ToStatus, &rule.WithinDays, &rule.CreatedBy, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan SLA rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// EvaluateSLAs finds the accounts past the deadline of each SLA rule on the
+// follower pool, records a breach for each on the primary, and resolves the
+// breaches of accounts that left the status. Operators listed in
+// SLA_NOTIFY_USERS (default admin) get one notification per rule with new
+// breaches. It returns how many breaches are new.
+func EvaluateSLAs(ctx context.Context) (int, error) {
+	rules, err := SLARules(db.Primary(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	slaBreachedAccounts.Reset()
+	total := 0
+	for _, rule := range rules {
+		accountIDs, enteredAts, err := breachingAccounts(ctx, rule)
+		if err != nil {
+			return total, err
+		}
+		slaBreachedAccounts.WithLabelValues(strconv.Itoa(rule.ID)).Set(float64(len(accountIDs)))
+
+		added, err := recordSLABreaches(ctx, rule, accountIDs, enteredAts)
+		if err != nil {
+			return total, err
+		}
+		total += added
+		if added == 0 {
+			continue
+		}
+
+		tracing.Printf(ctx, "SLA %d (%s): %d new breaches", rule.ID, rule.Name, added)
+		if err := db.CreateNotifications(ctx, "sla", SLABreachMessage(rule, added), notifyRecipients("SLA_NOTIFY_USERS")...); err != nil {
+			tracing.Printf(ctx, "Error notifying SLA %d breaches: %v", rule.ID, err)
+		}
+	}
+	return total, nil
+}
+
+// breachingAccounts returns the accounts in rule's from_status past its
+// deadline, with when each entered the status
+func breachingAccounts(ctx context.Context, rule models.SLARule) ([]int64, []time.Time, error) {
+	rows, err := db.Analytics(ctx).Query(`
+		SELECT a.id, entered.at
+		FROM accounts a CROSS JOIN LATERAL (SELECT `+slaEnteredAt+` AS at) entered
+		WHERE a.deleted_at IS NULL AND a.status = $1 AND entered.at < NOW() - make_interval(days => $2)`,
+		rule.FromStatus, rule.WithinDays,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find accounts breaching SLA %d: %w", rule.ID, err)
+	}
+	defer rows.Close()
+
+	accountIDs, enteredAts := []int64{}, []time.Time{}
+	for rows.Next() {
+		var accountID int64
+		var enteredAt time.Time
+		if err := rows.Scan(&accountID, &enteredAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan SLA breach: %w", err)
+		}
+		accountIDs = append(accountIDs, accountID)
+		enteredAts = append(enteredAts, enteredAt)
+	}
+	return accountIDs, enteredAts, rows.Err()
+}
+
+// recordSLABreaches stores the breaches not recorded yet, and resolves the
+// open breaches of rule that are no longer among them, returning how many are
+// new
+func recordSLABreaches(ctx context.Context, rule models.SLARule, accountIDs []int64, enteredAts []time.Time) (int, error) {
+	var added int64
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO sla_breaches (rule_id, account_id, entered_at, due_at)
+			SELECT $1, c.account_id, c.entered_at, c.entered_at + make_interval(days => $2)
+			FROM unnest($3::int[], $4::timestamptz[]) AS c(account_id, entered_at)
+			ON CONFLICT (rule_id, account_id, entered_at) DO NOTHING`,
+			rule.ID, rule.WithinDays, accountIDs, enteredAts,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record SLA %d breaches: %w", rule.ID, err)
+		}
+		added, _ = result.RowsAffected()
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE sla_breaches b SET resolved_at = CURRENT_TIMESTAMP
+			WHERE b.rule_id = $1 AND b.resolved_at IS NULL AND NOT EXISTS (
+				SELECT 1 FROM unnest($2::int[], $3::timestamptz[]) AS c(account_id, entered_at)
+				WHERE c.account_id = b.account_id AND c.entered_at = b.entered_at
+			)`,
+			rule.ID, accountIDs, enteredAts,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to resolve SLA %d breaches: %w", rule.ID, err)
+		}
+		return nil
+	})
+	return int(added), err
+}
+
+// SLABreachMessage is the notification for count new breaches of rule
+func SLABreachMessage(rule models.SLARule, count int) string {
+	accounts := "accounts are"
+	if count == 1 {
+		accounts = "account is"
+	}
+	return fmt.Sprintf("SLA %q breached: %d %s still %s after %d days instead of %s",
+		rule.Name, count, accounts, rule.FromStatus, rule.WithinDays, rule.ToStatus)
+}
+
+// SLACompliance measures rule on the follower pool: the accounts in its
+// from_status within and past the deadline now, the accounts that moved on to
+// to_status since a time and how many did in time, and the oldest open
+// breaches recorded by EvaluateSLAs
+func SLACompliance(ctx context.Context, rule models.SLARule, since time.Time) (models.SLACompliance, error) {
+	analyticsDB := db.Analytics(ctx)
+	compliance := models.SLACompliance{Rule: rule, OpenBreaches: []models.SLABreach{}}
+
+	err := analyticsDB.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE entered.at >= NOW() - make_interval(days => $2)),
+			COUNT(*) FILTER (WHERE entered.at < NOW() - make_interval(days => $2))
+		FROM accounts a CROSS JOIN LATERAL (SELECT `+slaEnteredAt+` AS at) entered
+		WHERE a.deleted_at IS NULL AND a.status = $1`,
+		rule.FromStatus, rule.WithinDays,
+	).Scan(&compliance.InProgress, &compliance.Breached)
+	if err != nil {
+		return compliance, fmt.Errorf("failed to count accounts under SLA %d: %w", rule.ID, err)
+	}
+
+	// Status changes are the versions whose status differs from the previous
+	// version's; a transition runs from one change to the next
+	err = analyticsDB.QueryRow(`
+		WITH versions AS (
+			SELECT id, data->>'status' AS status, valid_from,
+				LAG(data->>'status') OVER (PARTITION BY id ORDER BY valid_from) AS previous_status
+			FROM accounts_history
+			WHERE id IN (SELECT id FROM accounts_history WHERE valid_from >= $4)
+		), changes AS (
+			SELECT id, status, valid_from,
+				LAG(status) OVER (PARTITION BY id ORDER BY valid_from) AS from_status,
+				LAG(valid_from) OVER (PARTITION BY id ORDER BY valid_from) AS entered_at
+			FROM versions
+			WHERE previous_status IS DISTINCT FROM status
+		)
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE valid_from <= entered_at + make_interval(days => $3))
+		FROM changes
+		WHERE from_status = $1 AND status = $2 AND valid_from >= $4`,
+		rule.FromStatus, rule.ToStatus, rule.WithinDays, since.UTC(),
+	).Scan(&compliance.Completed, &compliance.CompletedOnTime)
+	if err != nil {
+		return compliance, fmt.Errorf("failed to count transitions under SLA %d: %w", rule.ID, err)
+	}
+	compliance.Compliance = ComplianceRate(compliance.CompletedOnTime, compliance.Completed, compliance.Breached)
+
+	rows, err := analyticsDB.Query(`
+		SELECT id, rule_id, account_id, entered_at, due_at, detected_at
+		FROM sla_breaches
+		WHERE rule_id = $1 AND resolved_at IS NULL
+		ORDER BY entered_at, id
+		LIMIT $2`,
+		rule.ID, maxOpenBreaches,
+	)
+	if err != nil {
+		return compliance, fmt.Errorf("failed to fetch SLA %d breaches: %w", rule.ID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var breach models.SLABreach
+		if err := rows.Scan(&breach.ID, &breach.RuleID, &breach.AccountID, &breach.EnteredAt, &breach.DueAt, &breach.DetectedAt); err != nil {
+			return compliance, fmt.Errorf("failed to scan SLA breach: %w", err)
+		}
+		compliance.OpenBreaches = append(compliance.OpenBreaches, breach)
+	}
+	return compliance, rows.Err()
+}
+
+// ComplianceRate returns the percentage, to one decimal, of the accounts
+// that completed or breached an SLA that completed it in time, or nil if
+// there are none
+func ComplianceRate(onTime, completed, breached int) *float64 {
+	if completed+breached == 0 {
+		return nil
+	}
+	rate := math.Round(1000*float64(onTime)/float64(completed+breached)) / 10
+	return &rate
+}
@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+)
+
+func TestSLABreachMessage(t *testing.T) {
+	rule := models.SLARule{Name: "Activation", FromStatus: "pending", ToStatus: "active", WithinDays: 7}
+	if got, expected := SLABreachMessage(rule, 1), `SLA "Activation" breached: 1 account is still pending after 7 days instead of active`; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got, expected := SLABreachMessage(rule, 3), `SLA "Activation" breached: 3 accounts are still pending after 7 days instead of active`; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestComplianceRate(t *testing.T) {
+	if rate := ComplianceRate(0, 0, 0); rate != nil {
+		t.Errorf("Expected no rate without accounts, got %v", *rate)
+	}
+	// Breached accounts count against the rate until they complete
+	if rate := ComplianceRate(2, 2, 1); rate == nil || *rate != 66.7 {
+		t.Errorf("Expected 66.7, got %v", rate)
+	}
+	if rate := ComplianceRate(4, 4, 0); rate == nil || *rate != 100 {
+		t.Errorf("Expected 100, got %v", rate)
+	}
+}
+
+func TestEvaluateSLAs(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	if err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	var customerID, accountID, ruleID int
+	err := db.PrimaryDB.QueryRow(
+		"INSERT INTO customers (name, email) VALUES ('SLA Test', $1) RETURNING id",
+		fmt.Sprintf("sla-%d@example.com", time.Now().UnixNano()),
+	).Scan(&customerID)
+	if err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	defer db.PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", customerID)
+
+	// A status only the test uses, so accounts of other tests don't count
+	status := fmt.Sprintf("sla-test-%d", customerID)
+	err = db.PrimaryDB.QueryRow(
+		"INSERT INTO accounts (customer_id, name, status) VALUES ($1, 'SLA Account', $2) RETURNING id",
+		customerID, status,
+	).Scan(&accountID)
+	if err != nil {
+		t.Fatalf("Failed to insert account: %v", err)
+	}
+	enteredAt := time.Now().Add(-10 * 24 * time.Hour)
+	if _, err := db.PrimaryDB.Exec("UPDATE accounts_history SET valid_from = $2 WHERE id = $1", accountID, enteredAt); err != nil {
+		t.Fatalf("Failed to backdate account history: %v", err)
+	}
+
+	err = db.PrimaryDB.QueryRow(
+		"INSERT INTO sla_rules (name, from_status, to_status, within_days, created_by) VALUES ('SLA Test', $1, 'active', 7, 'test') RETURNING id",
+		status,
+	).Scan(&ruleID)
+	if err != nil {
+		t.Fatalf("Failed to insert SLA rule: %v", err)
+	}
+	defer db.PrimaryDB.Exec("DELETE FROM sla_rules WHERE id = $1", ruleID)
+
+	openBreaches := func() int {
+		var count int
+		if err := db.PrimaryDB.QueryRow("SELECT COUNT(*) FROM sla_breaches WHERE rule_id = $1 AND resolved_at IS NULL", ruleID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count breaches: %v", err)
+		}
+		return count
+	}
+
+	if _, err := EvaluateSLAs(ctx); err != nil {
+		t.Fatalf("Failed to evaluate SLAs: %v", err)
+	}
+	if count := openBreaches(); count != 1 {
+		t.Fatalf("Expected 1 open breach, got %d", count)
+	}
+	// A second run doesn't record the breach again
+	if _, err := EvaluateSLAs(ctx); err != nil {
+		t.Fatalf("Failed to evaluate SLAs again: %v", err)
+	}
+	if count := openBreaches(); count != 1 {
+		t.Errorf("Expected the breach to be recorded once, got %d", count)
+	}
+
+	rule := models.SLARule{ID: ruleID, FromStatus: status, ToStatus: "active", WithinDays: 7}
+	compliance, err := SLACompliance(ctx, rule, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("Failed to measure compliance: %v", err)
+	}
+	if compliance.Breached != 1 || compliance.Completed != 0 || len(compliance.OpenBreaches) != 1 {
+		t.Errorf("Expected 1 breached account, got %+v", compliance)
+	}
+
+	// Activating the account resolves the breach and completes the SLA late
+	if _, err := db.PrimaryDB.Exec("UPDATE accounts SET status = 'active' WHERE id = $1", accountID); err != nil {
+		t.Fatalf("Failed to activate account: %v", err)
+	}
+	if _, err := EvaluateSLAs(ctx); err != nil {
+		t.Fatalf("Failed to evaluate SLAs after activation: %v", err)
+	}
+	if count := openBreaches(); count != 0 {
+		t.Errorf("Expected the breach to be resolved, got %d open", count)
+	}
+	compliance, err = SLACompliance(ctx, rule, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("Failed to measure compliance after activation: %v", err)
+	}
+	if compliance.Completed != 1 || compliance.CompletedOnTime != 0 || compliance.Compliance == nil || *compliance.Compliance != 0 {
+		t.Errorf("Expected 1 late completion, got %+v", compliance)
+	}
+}
@@ -0,0 +1,368 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/imports"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+)
+
+const (
+	TypeGenerateStatements = "statements:generate"
+)
+
+// StatementPeriodLayout formats statement periods, e.g. "2026-09"
+const StatementPeriodLayout = "2006-01"
+
+var (
+	// ErrInvalidPeriod is returned for periods that aren't a month that has
+	// already ended
+	ErrInvalidPeriod = errors.New("period must be a past month formatted as YYYY-MM")
+	// ErrStatementNotFound is returned when a customer has no statement for a period
+	ErrStatementNotFound = errors.New("statement not found")
+)
+
+// StatementPayload represents the payload for statement generation jobs
+type StatementPayload struct {
+	// Period is the month to generate, e.g. "2026-09"; the previous month
+	// if empty
+	Period string `json:"period,omitempty"`
+}
+
+// NewStatementTask creates a new statement generation task for period, or
+// for the previous month if period is empty
+func NewStatementTask(period string) (*asynq.Task, error) {
+	payload, err := json.Marshal(StatementPayload{Period: period})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeGenerateStatements, payload), nil
+}
+
+// HandleStatementTask generates the statements of a month that customers
+// don't have yet
+func HandleStatementTask(ctx context.Context, t *asynq.Task) error {
+	var payload StatementPayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+		}
+	}
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	if payload.Period != "" {
+		var err error
+		if start, err = ParseStatementPeriod(payload.Period); err != nil {
+			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+		}
+	}
+
+	_, err := GenerateStatements(ctx, start)
+	return err
+}
+
+// ParseStatementPeriod parses a period formatted as YYYY-MM, returning the
+// start of the month in UTC. Months that haven't ended yet are rejected,
+// since statements aren't regenerated once stored.
+func ParseStatementPeriod(period string) (time.Time, error) {
+	start, err := time.Parse(StatementPeriodLayout, period)
+	if err != nil || !start.AddDate(0, 1, 0).Before(time.Now()) {
+		return time.Time{}, ErrInvalidPeriod
+	}
+	return start, nil
+}
+
+// StatementStore keeps statement documents as objects
+type StatementStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+var (
+	statementStoreOnce sync.Once
+	statementStore     StatementStore
+)
+
+// defaultStatementStore returns the import bucket, or nil if none is
+// configured, in which case documents are kept in the database
+func defaultStatementStore() StatementStore {
+	statementStoreOnce.Do(func() {
+		store, err := imports.S3StoreFromEnv(context.Background())
+		if err != nil {
+			if !errors.Is(err, imports.ErrNotConfigured) {
+				log.Printf("Warning: Failed to configure statement storage, keeping statements in the database: %v", err)
+			}
+			return
+		}
+		statementStore = store
+	})
+	return statementStore
+}
+
+// openStatementStore returns the store statement documents go to; tests
+// replace it
+var openStatementStore = defaultStatementStore
+
+// GenerateStatements compiles and stores the statements for the month
+// starting at start of every customer that existed during it and doesn't
+// have one yet, returning how many were generated. A customer whose
+// statement fails doesn't stop the others; the errors are returned together
+// so the task is retried, which skips the statements already stored.
+func GenerateStatements(ctx context.Context, start time.Time) (int, error) {
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	rows, err := db.Primary(ctx).Query(`
+		SELECT c.id FROM customers c
+		WHERE c.created_at < $2 AND (c.deleted_at IS NULL OR c.deleted_at >= $1)
+			AND NOT EXISTS (SELECT 1 FROM customer_statements s WHERE s.customer_id = c.id AND s.period = $1)
+		ORDER BY c.id`,
+		start, end,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list customers without statements: %w", err)
+	}
+	var customerIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		customerIDs = append(customerIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list customers without statements: %w", err)
+	}
+
+	generated := 0
+	var errs []error
+	for _, customerID := range customerIDs {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		document, err := CompileStatement(ctx, customerID, start)
+		if err == nil {
+			err = storeStatement(ctx, document)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("customer %d: %w", customerID, err))
+			continue
+		}
+		generated++
+	}
+
+	tracing.Printf(ctx, "Generated %d statements for %s", generated, start.Format(StatementPeriodLayout))
+	return generated, errors.Join(errs...)
+}
+
+// CompileStatement reads a customer's account changes, API usage, and
+// invoices for the month starting at start from the analytics DB
+func CompileStatement(ctx context.Context, customerID int, start time.Time) (models.StatementDocument, error) {
+	start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	document := models.StatementDocument{
+		Customer:       models.StatementCustomer{ID: customerID},
+		Period:         start.Format(StatementPeriodLayout),
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		GeneratedAt:    time.Now().UTC(),
+		AccountChanges: []models.StatementAccountChange{},
+		Usage:          models.StatementUsage{Endpoints: []models.StatementUsageEndpoint{}},
+		Invoices:       []models.StatementInvoice{},
+	}
+	analyticsDB := db.Analytics(ctx)
+
+	err := analyticsDB.QueryRow("SELECT name, email FROM customers WHERE id = $1", customerID).
+		Scan(&document.Customer.Name, &document.Customer.Email)
+	if err != nil {
+		return document, fmt.Errorf("failed to fetch customer: %w", err)
+	}
+
+	// Every version of the customer's accounts that started during the
+	// period is a creation or an update, and every version that ended
+	// without a successor is a deletion (see db.VersionedTables)
+	rows, err := analyticsDB.Query(`
+		SELECT h.id, CASE WHEN EXISTS (SELECT 1 FROM accounts_history p WHERE p.id = h.id AND p.valid_from < h.valid_from)
+				THEN 'updated' ELSE 'created' END,
+			h.valid_from, h.data->>'name', h.data->>'status', COALESCE((h.data->>'mrr_cents')::bigint, 0)
+		FROM accounts_history h
+		WHERE (h.data->>'customer_id')::int = $1 AND h.valid_from >= $2 AND h.valid_from < $3
+		UNION ALL
+		SELECT h.id, 'deleted', h.valid_to, h.data->>'name', h.data->>'status', COALESCE((h.data->>'mrr_cents')::bigint, 0)
+		FROM accounts_history h
+		WHERE (h.data->>'customer_id')::int = $1 AND h.valid_to >= $2 AND h.valid_to < $3
+			AND NOT EXISTS (SELECT 1 FROM accounts_history n WHERE n.id = h.id AND n.valid_from = h.valid_to)
+		ORDER BY 3, 1`,
+		customerID, start, end,
+	)
+	if err != nil {
+		return document, fmt.Errorf("failed to fetch account changes: %w", err)
+	}
+	for rows.Next() {
+		var change models.StatementAccountChange
+		if err := rows.Scan(&change.AccountID, &change.Change, &change.At, &change.Name, &change.Status, &change.MRRCents); err != nil {
+			rows.Close()
+			return document, fmt.Errorf("failed to scan account change: %w", err)
+		}
+		change.At = change.At.UTC()
+		document.AccountChanges = append(document.AccountChanges, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return document, fmt.Errorf("failed to fetch account changes: %w", err)
+	}
+
+	// Customer token calls are tracked as customer:<id> (see
+	// api.CustomerPrincipal)
+	rows, err = analyticsDB.Query(`
+		SELECT method, route, SUM(calls), COALESCE(SUM(calls) FILTER (WHERE status >= 500), 0)
+		FROM api_usage_rollups
+		WHERE username = $1 AND bucket >= $2 AND bucket < $3
+		GROUP BY method, route
+		ORDER BY SUM(calls) DESC, method, route`,
+		"customer:"+strconv.Itoa(customerID), start, end,
+	)
+	if err != nil {
+		return document, fmt.Errorf("failed to fetch API usage: %w", err)
+	}
+	for rows.Next() {
+		var endpoint models.StatementUsageEndpoint
+		if err := rows.Scan(&endpoint.Method, &endpoint.Route, &endpoint.Calls, &endpoint.Errors); err != nil {
+			rows.Close()
+			return document, fmt.Errorf("failed to scan API usage: %w", err)
+		}
+		document.Usage.Calls += endpoint.Calls
+		document.Usage.Errors += endpoint.Errors
+		document.Usage.Endpoints = append(document.Usage.Endpoints, endpoint)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return document, fmt.Errorf("failed to fetch API usage: %w", err)
+	}
+
+	// There is no billing system behind the app, so each account active at
+	// the end of the period is invoiced its MRR, in the currency and on the
+	// invoice day from its settings
+	rows, err = analyticsDB.Query(`
+		SELECT accounts.id, accounts.name, accounts.mrr_cents,
+			COALESCE(accounts.settings->>'currency', 'USD'), COALESCE((accounts.settings->>'invoice_day')::int, 1)
+		FROM `+db.AsOf("accounts", 2)+`
+		WHERE accounts.customer_id = $1 AND accounts.status = 'active' AND accounts.deleted_at IS NULL AND accounts.mrr_cents > 0
+		ORDER BY accounts.id`,
+		customerID, end.Add(-time.Microsecond),
+	)
+	if err != nil {
+		return document, fmt.Errorf("failed to fetch invoices: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var invoice models.StatementInvoice
+		var invoiceDay int
+		if err := rows.Scan(&invoice.AccountID, &invoice.AccountName, &invoice.AmountCents, &invoice.Currency, &invoiceDay); err != nil {
+			return document, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoice.IssuedOn = start.AddDate(0, 0, invoiceDay-1).Format("2006-01-02")
+		document.Invoices = append(document.Invoices, invoice)
+	}
+	if err := rows.Err(); err != nil {
+		return document, fmt.Errorf("failed to fetch invoices: %w", err)
+	}
+	return document, nil
+}
+
+// statementObjectKey is where a statement document is kept in the bucket
+func statementObjectKey(customerID int, period string) string {
+	return fmt.Sprintf("statements/%d/%s.json", customerID, period)
+}
+
+// storeStatement saves document in the statement store, or in the database
+// without one, and records the statement. A statement that already exists
+// is left alone.
+func storeStatement(ctx context.Context, document models.StatementDocument) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to encode statement: %w", err)
+	}
+	totals := map[string]int64{}
+	for _, invoice := range document.Invoices {
+		totals[invoice.Currency] += invoice.AmountCents
+	}
+	totalsJSON, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("failed to encode invoice totals: %w", err)
+	}
+
+	var objectKey, inline interface{}
+	if store := openStatementStore(); store != nil {
+		key := statementObjectKey(document.Customer.ID, document.Period)
+		if err := store.Put(ctx, key, "application/json", data); err != nil {
+			return err
+		}
+		objectKey = key
+	} else {
+		inline = string(data)
+	}
+
+	_, err = db.Primary(ctx).Exec(
+		`INSERT INTO customer_statements (customer_id, period, account_changes, api_calls, invoice_totals, object_key, document, generated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (customer_id, period) DO NOTHING`,
+		document.Customer.ID, document.PeriodStart, len(document.AccountChanges), document.Usage.Calls,
+		string(totalsJSON), objectKey, inline, document.GeneratedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record statement: %w", err)
+	}
+	return nil
+}
+
+// StatementDocument returns the stored document of a customer's statement
+// for the month starting at start, as JSON
+func StatementDocument(ctx context.Context, customerID int, start time.Time) ([]byte, error) {
+	var objectKey sql.NullString
+	var inline []byte
+	err := db.Primary(ctx).QueryRow(
+		"SELECT object_key, document FROM customer_statements WHERE customer_id = $1 AND period = $2",
+		customerID, start,
+	).Scan(&objectKey, &inline)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStatementNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch statement: %w", err)
+	}
+	if !objectKey.Valid {
+		return inline, nil
+	}
+
+	store := openStatementStore()
+	if store == nil {
+		return nil, fmt.Errorf("statement is stored at %s, but statement storage is not configured", objectKey.String)
+	}
+	body, err := store.Open(ctx, objectKey.String)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+)
+
+func TestParseStatementPeriod(t *testing.T) {
+	start, err := ParseStatementPeriod("2026-02")
+	if err != nil || !start.Equal(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February 2026, got %v, %v", start, err)
+	}
+
+	current := time.Now().UTC().Format(StatementPeriodLayout)
+	for _, period := range []string{current, "2026-13", "2026-2", "02-2026", ""} {
+		if _, err := ParseStatementPeriod(period); !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("Expected %q to be rejected, got %v", period, err)
+		}
+	}
+}
+
+func TestCompileAndStoreStatement(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	if err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// Keep documents in the database
+	openStatementStore = func() StatementStore { return nil }
+	t.Cleanup(func() { openStatementStore = defaultStatementStore })
+
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	var customerID int
+	err := db.PrimaryDB.QueryRow(
+		"INSERT INTO customers (name, email, created_at) VALUES ('Statement Test', $1, $2) RETURNING id",
+		fmt.Sprintf("statement-%d@example.com", time.Now().UnixNano()), start.AddDate(0, -1, 0),
+	).Scan(&customerID)
+	if err != nil {
+		t.Fatalf("Failed to insert customer: %v", err)
+	}
+	principal := fmt.Sprintf("customer:%d", customerID)
+	defer db.PrimaryDB.Exec("DELETE FROM customers WHERE id = $1", customerID)
+	defer db.PrimaryDB.Exec("DELETE FROM api_usage_rollups WHERE username = $1", principal)
+
+	// An account created before the period and upgraded during it, and one
+	// created and deleted during it, recorded straight into the history
+	accountID := -customerID
+	version := func(id int, status string, mrrCents int64, from time.Time, to *time.Time) {
+		data, _ := json.Marshal(map[string]interface{}{
+			"id": id, "customer_id": customerID, "name": "Statement Account", "status": status,
+			"mrr_cents": mrrCents, "settings": map[string]interface{}{"currency": "EUR", "invoice_day": 5},
+		})
+		if _, err := db.PrimaryDB.Exec("INSERT INTO accounts_history (id, data, valid_from, valid_to) VALUES ($1, $2, $3, $4)", id, string(data), from, to); err != nil {
+			t.Fatalf("Failed to insert account history: %v", err)
+		}
+	}
+	upgradedAt := start.AddDate(0, 0, 10)
+	deletedAt := start.AddDate(0, 0, 20)
+	version(accountID, "active", 1000, start.AddDate(0, -1, 0), &upgradedAt)
+	version(accountID, "active", 2500, upgradedAt, nil)
+	version(accountID-1, "pending", 0, start.AddDate(0, 0, 15), &deletedAt)
+	defer db.PrimaryDB.Exec("DELETE FROM accounts_history WHERE id IN ($1, $2)", accountID, accountID-1)
+
+	_, err = db.PrimaryDB.Exec(
+		`INSERT INTO api_usage_rollups (bucket, username, method, route, status, calls, total_latency_ms, max_latency_ms)
+		 VALUES ($1, $2, 'GET', '/api/my/accounts', 200, 40, 400, 20), ($1, $2, 'GET', '/api/my/accounts', 503, 2, 10, 5),
+			($3, $2, 'GET', '/api/my/usage', 200, 7, 70, 10)`,
+		start.AddDate(0, 0, 3), principal, start.AddDate(0, 1, 0),
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert API usage: %v", err)
+	}
+
+	document, err := CompileStatement(ctx, customerID, start)
+	if err != nil {
+		t.Fatalf("Failed to compile statement: %v", err)
+	}
+	if document.Period != "2025-03" || document.Customer.Name != "Statement Test" {
+		t.Errorf("Expected the 2025-03 statement of the customer, got %+v", document)
+	}
+
+	var changes []string
+	for _, change := range document.AccountChanges {
+		changes = append(changes, fmt.Sprintf("%d %s", change.AccountID, change.Change))
+	}
+	expected := []string{fmt.Sprintf("%d updated", accountID), fmt.Sprintf("%d created", accountID-1), fmt.Sprintf("%d deleted", accountID-1)}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("Expected account changes %v, got %v", expected, changes)
+	}
+
+	// The call in the next month isn't counted
+	if document.Usage.Calls != 42 || document.Usage.Errors != 2 || len(document.Usage.Endpoints) != 1 {
+		t.Errorf("Expected 42 calls with 2 errors to one endpoint, got %+v", document.Usage)
+	}
+
+	expectedInvoice := models.StatementInvoice{AccountID: accountID, AccountName: "Statement Account", IssuedOn: "2025-03-05", Currency: "EUR", AmountCents: 2500}
+	if len(document.Invoices) != 1 || document.Invoices[0] != expectedInvoice {
+		t.Errorf("Expected an invoice %+v, got %+v", expectedInvoice, document.Invoices)
+	}
+
+	if err := storeStatement(ctx, document); err != nil {
+		t.Fatalf("Failed to store statement: %v", err)
+	}
+	// A second run leaves the stored statement alone
+	later := document
+	later.Usage.Calls = 0
+	if err := storeStatement(ctx, later); err != nil {
+		t.Fatalf("Failed to store statement again: %v", err)
+	}
+
+	data, err := StatementDocument(ctx, customerID, start)
+	if err != nil {
+		t.Fatalf("Failed to read statement: %v", err)
+	}
+	var stored models.StatementDocument
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Failed to decode statement: %v", err)
+	}
+	if stored.Usage.Calls != 42 || len(stored.AccountChanges) != 3 {
+		t.Errorf("Expected the first statement to be kept, got %+v", stored)
+	}
+
+	if _, err := StatementDocument(ctx, customerID, start.AddDate(0, 1, 0)); !errors.Is(err, ErrStatementNotFound) {
+		t.Errorf("Expected ErrStatementNotFound for another month, got %v", err)
+	}
+}
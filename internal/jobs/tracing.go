@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+
+	"saas-go-app/internal/tracing"
+
+	"github.com/hibiken/asynq"
+)
+
+// tracedPayload is embedded in task payloads so the request that enqueued a
+// task can be followed into the worker
+type tracedPayload struct {
+	Trace tracing.Trace `json:"trace,omitempty"`
+}
+
+// TracingMiddleware restores the trace stored in a task's payload onto the
+// handler context. Tasks without one (e.g. scheduled tasks) get the task ID
+// as their request ID so their log lines and queries can still be grouped.
+func TracingMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var payload tracedPayload
+		if len(t.Payload()) > 0 {
+			_ = json.Unmarshal(t.Payload(), &payload)
+		}
+		if payload.Trace.RequestID == "" {
+			if taskID, ok := asynq.GetTaskID(ctx); ok {
+				payload.Trace.RequestID = "task-" + taskID
+			} else {
+				payload.Trace.RequestID = tracing.NewRequestID()
+			}
+		}
+
+		ctx = tracing.NewContext(ctx, payload.Trace)
+		tracing.Printf(ctx, "Processing %s task", t.Type())
+		return next.ProcessTask(ctx, t)
+	})
+}
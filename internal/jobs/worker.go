@@ -0,0 +1,429 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var queuedJobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "queued_jobs_processed_total",
+	Help: "Attempts at Postgres-queued jobs, by type and outcome (done, retry, or dead).",
+}, []string{"type", "outcome"})
+
+const (
+	// heartbeatInterval is how often a worker reports it is alive and hands
+	// back the jobs of workers that stopped
+	heartbeatInterval = 15 * time.Second
+	// workerStaleAfter is how long a worker may miss heartbeats before its
+	// running jobs are given to other workers
+	workerStaleAfter = time.Minute
+	// maxRetryBackoff caps the wait between attempts
+	maxRetryBackoff = time.Hour
+)
+
+// SkipRetry, wrapped in a handler's error, marks the job dead right away,
+// for failures another attempt won't fix
+var SkipRetry = errors.New("skip retry")
+
+// HandlerFunc runs a job. An error retries it with backoff until its
+// attempts run out. ctx is cancelled if the worker is stopped before the job
+// finishes.
+type HandlerFunc func(ctx context.Context, job QueuedJob) error
+
+// WorkerOptions configure a Worker
+type WorkerOptions struct {
+	// Queues are the queues the worker takes jobs from
+	Queues []string
+	// Concurrency is the number of jobs run at once
+	Concurrency int
+	// PollInterval is how often the worker looks for due jobs while it has
+	// a free slot
+	PollInterval time.Duration
+	// ShutdownTimeout is how long running jobs get to finish once the
+	// worker is stopped, before they are cancelled
+	ShutdownTimeout time.Duration
+	// Retention is how long finished jobs are kept (0 keeps them)
+	Retention time.Duration
+}
+
+// WorkerOptionsFromEnv reads JOB_QUEUES (comma-separated, default
+// "default"), JOB_WORKER_CONCURRENCY (default 5), JOB_POLL_INTERVAL (default
+// 1s), JOB_SHUTDOWN_TIMEOUT (default 25s, within the 30s Heroku gives a dyno
+// to stop), and JOB_RETENTION (default 168h)
+func WorkerOptionsFromEnv() WorkerOptions {
+	opts := WorkerOptions{
+		Queues:          []string{DefaultQueue},
+		Concurrency:     5,
+		PollInterval:    time.Second,
+		ShutdownTimeout: 25 * time.Second,
+		Retention:       7 * 24 * time.Hour,
+	}
+	if value := os.Getenv("JOB_QUEUES"); value != "" {
+		var queues []string
+		for _, queue := range strings.Split(value, ",") {
+			if queue = strings.TrimSpace(queue); queue != "" {
+				queues = append(queues, queue)
+			}
+		}
+		if len(queues) > 0 {
+			opts.Queues = queues
+		}
+	}
+	if value := os.Getenv("JOB_WORKER_CONCURRENCY"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			log.Printf("Warning: Invalid value for JOB_WORKER_CONCURRENCY (%s), using default 5", value)
+		} else {
+			opts.Concurrency = n
+		}
+	}
+	opts.PollInterval = durationFromEnv("JOB_POLL_INTERVAL", opts.PollInterval, false)
+	opts.ShutdownTimeout = durationFromEnv("JOB_SHUTDOWN_TIMEOUT", opts.ShutdownTimeout, true)
+	opts.Retention = durationFromEnv("JOB_RETENTION", opts.Retention, true)
+	return opts
+}
+
+// durationFromEnv reads a duration from key, falling back to defaultValue if
+// it is unset or invalid. Zero is only valid with allowZero.
+func durationFromEnv(key string, defaultValue time.Duration, allowZero bool) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 || (d == 0 && !allowZero) {
+		log.Printf("Warning: Invalid value for %s (%s), using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// Worker runs jobs from the Postgres queue. It registers itself in
+// job_workers, with the job types it handles, and sends a heartbeat while it
+// runs; jobs claimed by a worker whose heartbeat stops go back to the queue.
+// Any number of workers can run at once.
+type Worker struct {
+	id       string
+	opts     WorkerOptions
+	handlers map[string]HandlerFunc
+}
+
+// NewWorker returns a worker with no job types; register them with Handle
+func NewWorker(opts WorkerOptions) *Worker {
+	if len(opts.Queues) == 0 {
+		opts.Queues = []string{DefaultQueue}
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &Worker{id: workerID(), opts: opts, handlers: map[string]HandlerFunc{}}
+}
+
+// workerID names a worker after its dyno (or host) plus a random suffix, since
+// a restarted dyno keeps its name
+func workerID() string {
+	name := os.Getenv("DYNO")
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	return name + "-" + tracing.NewRequestID()[:8]
+}
+
+// ID returns the worker's id in job_workers and queued_jobs.worker_id
+func (w *Worker) ID() string {
+	return w.id
+}
+
+// Handle registers handler for jobs of jobType. Register every type before
+// calling Run.
+func (w *Worker) Handle(jobType string, handler HandlerFunc) {
+	w.handlers[jobType] = handler
+}
+
+// types returns the registered job types, sorted
+func (w *Worker) types() []string {
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Run registers the worker and runs jobs until ctx is done. It then stops
+// taking jobs, gives the running ones ShutdownTimeout to finish, cancels
+// those still running, and unregisters.
+func (w *Worker) Run(ctx context.Context) error {
+	if len(w.handlers) == 0 {
+		return errors.New("no job types registered")
+	}
+	if err := w.register(ctx); err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+	log.Printf("Job worker %s running %s from queues %s, %d at a time",
+		w.id, strings.Join(w.types(), ", "), strings.Join(w.opts.Queues, ", "), w.opts.Concurrency)
+
+	// Jobs get a context of their own, so stopping the worker doesn't cut
+	// them off before the shutdown timeout
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+	slots := make(chan struct{}, w.opts.Concurrency)
+	var running sync.WaitGroup
+
+	poll := time.NewTicker(w.opts.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-heartbeat.C:
+			if err := w.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Warning: Job worker %s heartbeat failed: %v", w.id, err)
+			}
+		case <-poll.C:
+			free := cap(slots) - len(slots)
+			if free == 0 {
+				continue
+			}
+			jobs, err := w.claim(ctx, free)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Warning: Job worker %s failed to claim jobs: %v", w.id, err)
+				}
+				continue
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				running.Add(1)
+				go func(job QueuedJob) {
+					defer func() {
+						<-slots
+						running.Done()
+					}()
+					w.run(jobCtx, job)
+				}(job)
+			}
+		}
+	}
+
+	log.Printf("Job worker %s stopping, waiting for %d running jobs", w.id, len(slots))
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(w.opts.ShutdownTimeout):
+		log.Printf("Job worker %s cancelling jobs still running after %v", w.id, w.opts.ShutdownTimeout)
+		cancelJobs()
+		<-done
+	}
+	return w.unregister(context.WithoutCancel(ctx))
+}
+
+// register adds the worker to job_workers
+func (w *Worker) register(ctx context.Context) error {
+	_, err := db.Primary(ctx).Exec(`
+		INSERT INTO job_workers (id, queues, types, concurrency) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET queues = EXCLUDED.queues, types = EXCLUDED.types,
+			concurrency = EXCLUDED.concurrency, last_seen_at = CURRENT_TIMESTAMP`,
+		w.id, w.opts.Queues, w.types(), w.opts.Concurrency,
+	)
+	return err
+}
+
+// unregister removes the worker from job_workers. Its jobs have finished or
+// been cancelled, and put back in the queue, by now.
+func (w *Worker) unregister(ctx context.Context) error {
+	_, err := db.Primary(ctx).Exec("DELETE FROM job_workers WHERE id = $1", w.id)
+	return err
+}
+
+// heartbeat marks the worker alive, hands the jobs of stale workers back to
+// the queue, and removes finished jobs past the retention period
+func (w *Worker) heartbeat(ctx context.Context) error {
+	result, err := db.Primary(ctx).Exec("UPDATE job_workers SET last_seen_at = CURRENT_TIMESTAMP WHERE id = $1", w.id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Another worker took this one for stale and removed it
+		if err := w.register(ctx); err != nil {
+			return err
+		}
+	}
+
+	// A job whose worker died counts as a failed attempt
+	result, err = db.Primary(ctx).Exec(`
+		UPDATE queued_jobs j SET
+			status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+			finished_at = CASE WHEN attempts >= max_attempts THEN CURRENT_TIMESTAMP END,
+			run_at = CURRENT_TIMESTAMP,
+			last_error = 'worker ' || COALESCE(worker_id, '') || ' stopped while running the job'
+		WHERE status = 'running' AND NOT EXISTS (
+			SELECT 1 FROM job_workers w WHERE w.id = j.worker_id AND w.last_seen_at > CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'
+		)`,
+		workerStaleAfter.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to recover jobs of stopped workers: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Job worker %s recovered %d jobs of stopped workers", w.id, n)
+	}
+	if _, err := db.Primary(ctx).Exec(
+		"DELETE FROM job_workers WHERE last_seen_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'",
+		workerStaleAfter.Seconds(),
+	); err != nil {
+		return err
+	}
+
+	if w.opts.Retention > 0 {
+		if _, err := db.Primary(ctx).Exec(
+			"DELETE FROM queued_jobs WHERE status = 'done' AND finished_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'",
+			w.opts.Retention.Seconds(),
+		); err != nil {
+			return fmt.Errorf("failed to remove finished jobs: %w", err)
+		}
+	}
+	return nil
+}
+
+// claim takes up to n due jobs of the registered types from the worker's
+// queues, oldest due first. SKIP LOCKED passes over jobs another worker is
+// claiming at the same time.
+func (w *Worker) claim(ctx context.Context, n int) ([]QueuedJob, error) {
+	rows, err := db.Primary(ctx).Query(`
+		UPDATE queued_jobs SET status = 'running', attempts = attempts + 1, worker_id = $1, started_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM queued_jobs
+			WHERE status = 'pending' AND run_at <= CURRENT_TIMESTAMP AND queue = ANY($2) AND type = ANY($3)
+			ORDER BY run_at, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+queuedJobColumns,
+		w.id, w.opts.Queues, w.types(), n,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []QueuedJob
+	for rows.Next() {
+		job, err := scanQueuedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// run runs job with the trace and actor it was queued with and records the
+// outcome
+func (w *Worker) run(ctx context.Context, job QueuedJob) {
+	trace := job.trace
+	if trace.RequestID == "" {
+		trace.RequestID = fmt.Sprintf("job-%d", job.ID)
+	}
+	ctx = tracing.NewContext(ctx, trace)
+	if job.actor != "" {
+		ctx = auth.WithActor(ctx, job.actor)
+	}
+	tracing.Printf(ctx, "Running %s job %d (attempt %d of %d)", job.Type, job.ID, job.Attempts, job.MaxAttempts)
+
+	started := time.Now()
+	err := runHandler(ctx, w.handlers[job.Type], job)
+	outcome := jobOutcome(job, err)
+	if err != nil {
+		tracing.Printf(ctx, "Warning: %s job %d failed after %v (%s): %v", job.Type, job.ID, time.Since(started).Round(time.Millisecond), outcome, err)
+	}
+	queuedJobsProcessed.WithLabelValues(job.Type, outcome).Inc()
+
+	// The outcome is recorded even if the job was cancelled; the worker only
+	// updates jobs it still holds
+	if err := w.finish(context.WithoutCancel(ctx), job, outcome, err); err != nil {
+		tracing.Printf(ctx, "Warning: Failed to record the outcome of %s job %d: %v", job.Type, job.ID, err)
+	}
+}
+
+// runHandler calls handler, turning a panic into an error
+func runHandler(ctx context.Context, handler HandlerFunc, job QueuedJob) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// jobOutcome returns what becomes of job after an attempt that returned err:
+// done, retry, or dead
+func jobOutcome(job QueuedJob, err error) string {
+	switch {
+	case err == nil:
+		return JobDone
+	case errors.Is(err, SkipRetry) || job.Attempts >= job.MaxAttempts:
+		return JobDead
+	default:
+		return "retry"
+	}
+}
+
+// retryBackoff returns the wait before the attempt after the given number
+// of attempts: 10s, 20s, 40s, ..., up to an hour
+func retryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 20 {
+		return maxRetryBackoff
+	}
+	backoff := 10 * time.Second << (attempts - 1)
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// finish records the outcome of an attempt at job
+func (w *Worker) finish(ctx context.Context, job QueuedJob, outcome string, jobErr error) error {
+	var err error
+	switch outcome {
+	case JobDone:
+		_, err = db.Primary(ctx).Exec(
+			"UPDATE queued_jobs SET status = 'done', last_error = NULL, finished_at = CURRENT_TIMESTAMP WHERE id = $1 AND worker_id = $2 AND status = 'running'",
+			job.ID, w.id)
+	case JobDead:
+		_, err = db.Primary(ctx).Exec(
+			"UPDATE queued_jobs SET status = 'dead', last_error = $3, finished_at = CURRENT_TIMESTAMP WHERE id = $1 AND worker_id = $2 AND status = 'running'",
+			job.ID, w.id, jobErr.Error())
+	default:
+		_, err = db.Primary(ctx).Exec(
+			"UPDATE queued_jobs SET status = 'pending', last_error = $3, run_at = CURRENT_TIMESTAMP + $4 * INTERVAL '1 second' WHERE id = $1 AND worker_id = $2 AND status = 'running'",
+			job.ID, w.id, jobErr.Error(), retryBackoff(job.Attempts).Seconds())
+	}
+	return err
+}
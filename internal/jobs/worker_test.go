@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/db"
+)
+
+func TestJobOutcome(t *testing.T) {
+	job := QueuedJob{Attempts: 2, MaxAttempts: 3}
+	failed := errors.New("timeout")
+	tests := []struct {
+		name string
+		job  QueuedJob
+		err  error
+		want string
+	}{
+		{"success", job, nil, JobDone},
+		{"failure with attempts left", job, failed, "retry"},
+		{"failure on the last attempt", QueuedJob{Attempts: 3, MaxAttempts: 3}, failed, JobDead},
+		{"skip retry", job, fmt.Errorf("bad payload: %w", SkipRetry), JobDead},
+	}
+	for _, tt := range tests {
+		if got := jobOutcome(tt.job, tt.err); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  10 * time.Second,
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		10: time.Hour,
+		64: time.Hour,
+	} {
+		if got := retryBackoff(attempts); got != want {
+			t.Errorf("Expected %v after %d attempts, got %v", want, attempts, got)
+		}
+	}
+}
+
+func TestWorkerOptionsFromEnv(t *testing.T) {
+	t.Setenv("JOB_QUEUES", " webhooks, ,default")
+	t.Setenv("JOB_WORKER_CONCURRENCY", "0")
+	t.Setenv("JOB_POLL_INTERVAL", "0s")
+	t.Setenv("JOB_SHUTDOWN_TIMEOUT", "10s")
+	t.Setenv("JOB_RETENTION", "0")
+	opts := WorkerOptionsFromEnv()
+	if len(opts.Queues) != 2 || opts.Queues[0] != "webhooks" || opts.Queues[1] != "default" {
+		t.Errorf("Expected queues webhooks and default, got %v", opts.Queues)
+	}
+	if opts.Concurrency != 5 || opts.PollInterval != time.Second {
+		t.Errorf("Expected defaults for invalid values, got concurrency %d and poll interval %v", opts.Concurrency, opts.PollInterval)
+	}
+	if opts.ShutdownTimeout != 10*time.Second || opts.Retention != 0 {
+		t.Errorf("Expected shutdown timeout 10s and retention 0, got %v and %v", opts.ShutdownTimeout, opts.Retention)
+	}
+}
+
+func TestWorkerRetriesAndDeadLetters(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer db.CloseDB()
+
+	ctx := context.Background()
+	if err := db.MigrateUp(ctx); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	queue := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	w := NewWorker(WorkerOptions{Queues: []string{queue}, Concurrency: 1})
+	w.Handle("test:fail", func(ctx context.Context, job QueuedJob) error {
+		return errors.New("always fails")
+	})
+	id, err := Enqueue(ctx, "test:fail", map[string]int{"n": 1}, EnqueueOptions{Queue: queue, MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	defer db.Primary(ctx).Exec("DELETE FROM queued_jobs WHERE queue = $1", queue)
+
+	runDue := func() []QueuedJob {
+		t.Helper()
+		claimed, err := w.claim(ctx, 10)
+		if err != nil {
+			t.Fatalf("Failed to claim: %v", err)
+		}
+		for _, job := range claimed {
+			w.run(ctx, job)
+		}
+		return claimed
+	}
+	if claimed := runDue(); len(claimed) != 1 || claimed[0].ID != id || claimed[0].Attempts != 1 {
+		t.Fatalf("Expected to claim job %d on its first attempt, got %+v", id, claimed)
+	}
+	// The retry waits for its backoff
+	if claimed := runDue(); len(claimed) != 0 {
+		t.Fatalf("Expected no due jobs during the backoff, got %+v", claimed)
+	}
+	if _, err := db.Primary(ctx).Exec("UPDATE queued_jobs SET run_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		t.Fatalf("Failed to skip the backoff: %v", err)
+	}
+	runDue()
+
+	dead, err := ListQueuedJobs(ctx, JobDead, "test:fail", 100)
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(dead) == 0 || dead[0].ID != id || dead[0].Attempts != 2 || dead[0].LastError != "always fails" {
+		t.Fatalf("Expected job %d to be dead after 2 attempts, got %+v", id, dead)
+	}
+
+	job, err := RetryJob(ctx, id)
+	if err != nil || job.Status != JobPending || job.Attempts != 0 {
+		t.Fatalf("Expected the dead job to be pending again, got %+v (%v)", job, err)
+	}
+	if _, err := RetryJob(ctx, id); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound retrying a pending job, got %v", err)
+	}
+}
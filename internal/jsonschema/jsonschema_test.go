@@ -0,0 +1,60 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+		"count": {"type": "integer", "minimum": 1, "maximum": 10},
+		"mode": {"enum": ["fast", "slow"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}}
+	}
+}`
+
+func decode(t *testing.T, document string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(document), &value); err != nil {
+		t.Fatalf("Invalid test document: %v", err)
+	}
+	return value
+}
+
+func TestValidateAcceptsMatchingDocument(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if errs := schema.Validate(decode(t, `{"name": "abc", "count": 3, "mode": "fast", "tags": ["a"]}`)); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	errs := schema.Validate(decode(t, `{"count": 2.5, "mode": "medium", "tags": ["a", 1, "c"], "extra": true}`))
+	expected := []string{"count", "extra", "mode", "name", "tags", "tags[1]"}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for i, path := range expected {
+		if errs[i].Path != path {
+			t.Errorf("Expected error %d at %s, got %s", i, path, errs[i].Path)
+		}
+	}
+}
+
+func TestParseRejectsInvalidPattern(t *testing.T) {
+	if _, err := Parse([]byte(`{"properties": {"a": {"pattern": "("}}}`)); err == nil {
+		t.Error("Expected invalid pattern to be rejected")
+	}
+}
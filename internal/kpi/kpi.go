@@ -0,0 +1,119 @@
+// Package kpi exports business KPIs as Prometheus gauges next to the system
+// metrics on /metrics. The gauges are refreshed periodically from the follower
+// pool, so scrapes never run queries themselves.
+package kpi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"saas-go-app/internal/db"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	customersTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "business_customers",
+		Help: "Number of customers.",
+	})
+	accountsByStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "business_accounts",
+		Help: "Number of accounts by status.",
+	}, []string{"status"})
+	customersCreatedToday = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "business_customers_created_today",
+		Help: "Customers created since midnight (database time).",
+	})
+	signupsToday = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "business_signups_today",
+		Help: "Users registered since midnight (database time).",
+	})
+	lastRefresh = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "business_metrics_last_refresh_timestamp_seconds",
+		Help: "Unix time of the last successful business metrics refresh.",
+	})
+)
+
+// Refresh recomputes every business gauge with two aggregate queries
+func Refresh(ctx context.Context) error {
+	analyticsDB := db.Analytics(ctx)
+
+	var customers, customersToday, signups int64
+	err := analyticsDB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM customers WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM customers WHERE created_at >= CURRENT_DATE AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM users WHERE created_at >= CURRENT_DATE)`,
+	).Scan(&customers, &customersToday, &signups)
+	if err != nil {
+		return fmt.Errorf("failed to count customers and signups: %w", err)
+	}
+
+	rows, err := analyticsDB.Query("SELECT status, COUNT(*) FROM accounts WHERE deleted_at IS NULL GROUP BY status")
+	if err != nil {
+		return fmt.Errorf("failed to count accounts: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]int64)
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return err
+		}
+		statuses[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	customersTotal.Set(float64(customers))
+	customersCreatedToday.Set(float64(customersToday))
+	signupsToday.Set(float64(signups))
+	// Reset so statuses that no longer exist stop being reported
+	accountsByStatus.Reset()
+	for status, count := range statuses {
+		accountsByStatus.WithLabelValues(status).Set(float64(count))
+	}
+	lastRefresh.SetToCurrentTime()
+	return nil
+}
+
+// Start refreshes the gauges immediately and then every interval until ctx is done
+func Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := Refresh(ctx); err != nil {
+				log.Printf("Warning: Failed to refresh business metrics: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RefreshInterval returns how often business metrics are refreshed.
+// Configure with BUSINESS_METRICS_INTERVAL (default: 60s).
+func RefreshInterval() time.Duration {
+	value := os.Getenv("BUSINESS_METRICS_INTERVAL")
+	if value == "" {
+		return time.Minute
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Warning: Invalid value for BUSINESS_METRICS_INTERVAL (%s), using default 60s", value)
+		return time.Minute
+	}
+	return interval
+}
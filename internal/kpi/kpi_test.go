@@ -0,0 +1,42 @@
+package kpi
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"saas-go-app/internal/db"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRefresh(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	defer db.CloseDB()
+
+	if err := db.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	if err := Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh business metrics: %v", err)
+	}
+
+	if testutil.ToFloat64(lastRefresh) == 0 {
+		t.Error("Expected last refresh timestamp to be set")
+	}
+}
+
+func TestRefreshInterval(t *testing.T) {
+	t.Setenv("BUSINESS_METRICS_INTERVAL", "not-a-duration")
+	if got := RefreshInterval(); got.Seconds() != 60 {
+		t.Errorf("Expected default of 60s for invalid value, got %s", got)
+	}
+}
@@ -0,0 +1,842 @@
+package mock
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/accountsettings"
+	"saas-go-app/internal/api"
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/consent"
+	"saas-go-app/internal/forecast"
+	"saas-go-app/internal/jobs"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers /health and the /api routes backed by an in-memory
+// store. Request and response shapes match the Postgres-backed handlers. JWTs
+// are checked against the store's revocation list.
+func RegisterRoutes(router *gin.Engine) {
+	h := &handlers{store: NewStore()}
+	auth.CheckRevocations(h.store.TokenRevoked)
+
+	router.GET("/health", h.health)
+	router.GET("/health/ready", h.ready)
+
+	apiRoutes := router.Group("/api")
+	apiRoutes.Use(api.ResponseStyle())
+	{
+		apiRoutes.POST("/auth/login", h.login)
+		apiRoutes.POST("/auth/refresh", h.refresh)
+		apiRoutes.POST("/auth/revoke", h.revoke)
+		apiRoutes.POST("/auth/logout", auth.AuthMiddleware(), h.logout)
+		apiRoutes.POST("/auth/register", h.register)
+	}
+
+	protectedRoutes := apiRoutes.Group("")
+	protectedRoutes.Use(auth.AuthMiddleware())
+	{
+		customers := protectedRoutes.Group("/customers")
+		{
+			customers.GET("", h.getCustomers)
+			customers.GET("/:id", h.getCustomer)
+			customers.GET("/:id/diff", h.getCustomerDiff)
+			customers.POST("", h.createCustomer)
+			customers.PUT("/:id", h.updateCustomer)
+			customers.DELETE("/:id", h.deleteCustomer)
+			customers.POST("/:id/restore", h.restoreCustomer)
+		}
+
+		accounts := protectedRoutes.Group("/accounts")
+		{
+			accounts.GET("", h.getAccounts)
+			accounts.GET("/:id", h.getAccount)
+			accounts.GET("/by-reference/:reference", h.getAccountByReference)
+			accounts.POST("", h.createAccount)
+			accounts.PUT("/:id", h.updateAccount)
+			accounts.DELETE("/:id", h.deleteAccount)
+			accounts.POST("/:id/restore", h.restoreAccount)
+			accounts.GET("/:id/notes", h.getAccountNotes)
+			accounts.POST("/:id/notes", h.createAccountNote)
+			accounts.GET("/:id/settings", h.getAccountSettings)
+			accounts.PATCH("/:id/settings", h.patchAccountSettings)
+		}
+
+		protectedRoutes.GET("/account-types", api.GetAccountTypes)
+		protectedRoutes.GET("/account-types/:type/schema", api.GetAccountTypeSchema)
+
+		protectedRoutes.GET("/search/notes", h.searchNotes)
+		protectedRoutes.GET("/notifications", h.getNotifications)
+		protectedRoutes.GET("/consents", h.getConsents)
+		protectedRoutes.POST("/consents", h.acceptConsent)
+
+		analytics := protectedRoutes.Group("/analytics")
+		{
+			analytics.GET("", h.getAnalytics)
+			analytics.GET("/customers/:customer_id", h.getCustomerAnalytics)
+			analytics.GET("/anomalies", h.getAnomalies)
+			analytics.GET("/api-usage", h.getAPIUsage)
+			analytics.GET("/duplicates", h.getDuplicateAccounts)
+			analytics.GET("/heatmap", h.getUsageHeatmap)
+			analytics.GET("/forecast", h.getForecast)
+			analytics.GET("/data-quality", h.getDataQuality)
+		}
+	}
+}
+
+type handlers struct {
+	store *Store
+}
+
+func (h *handlers) health(c *gin.Context) {
+	c.JSON(http.StatusOK, api.HealthResponse{Status: "healthy", Database: "mock", AnalyticsDB: "mock"})
+}
+
+func (h *handlers) ready(c *gin.Context) {
+	c.JSON(http.StatusOK, api.ReadinessResponse{Status: "ready", Database: "mock", Jobs: "disabled"})
+}
+
+func (h *handlers) login(c *gin.Context) {
+	var req api.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.store.CheckUser(req.Username, req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	h.issueTokens(c, req.Username, "")
+}
+
+// issueTokens responds with a JWT for username and refresh, or a refresh token
+// starting a new family when refresh is empty
+func (h *handlers) issueTokens(c *gin.Context, username, refresh string) {
+	id, role, ok := h.store.User(username)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	token, err := auth.GenerateToken(id, username, role)
+	if err == nil && refresh == "" {
+		if refresh, err = auth.NewRefreshToken(); err == nil {
+			h.store.IssueRefreshToken(username, refresh)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.LoginResponse{Token: token, RefreshToken: refresh, ExpiresIn: int(auth.AccessTokenTTL().Seconds())})
+}
+
+func (h *handlers) refresh(c *gin.Context) {
+	var req api.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	next, err := auth.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	username, ok := h.store.RotateRefreshToken(req.RefreshToken, next)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, revoked, or expired refresh token"})
+		return
+	}
+	h.issueTokens(c, username, next)
+}
+
+func (h *handlers) revoke(c *gin.Context) {
+	var req api.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.store.RevokeRefreshToken(req.RefreshToken)
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh token revoked"})
+}
+
+func (h *handlers) logout(c *gin.Context) {
+	var req api.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := c.MustGet("token_claims").(*auth.Claims)
+	if claims.ID != "" {
+		h.store.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+	}
+	if req.RefreshToken != "" {
+		h.store.RevokeRefreshToken(req.RefreshToken)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+func (h *handlers) register(c *gin.Context) {
+	var req api.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if !h.store.CreateUser(req.Username, passwordHash) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+}
+
+func (h *handlers) getCustomers(c *gin.Context) {
+	includeDeleted, ok := h.adminFlag(c, "include_deleted", "Only admins can list deleted records")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.store.Customers(includeDeleted))
+}
+
+func (h *handlers) getCustomer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	customer, ok := h.store.Customer(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// getCustomerDiff reports no changes; the mock store keeps no history
+func (h *handlers) getCustomerDiff(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	if _, ok := h.store.Customer(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	from, _ := time.Parse(time.RFC3339, c.Query("from"))
+	c.JSON(http.StatusOK, models.CustomerDiff{
+		CustomerID: id,
+		From:       from,
+		To:         time.Now().UTC(),
+		Changes:    []models.FieldChange{},
+		Accounts:   models.AccountsDiff{Added: []models.Account{}, Removed: []models.Account{}, Changed: []models.AccountChange{}},
+	})
+}
+
+func (h *handlers) createCustomer(c *gin.Context) {
+	var req models.CreateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.store.CreateCustomer(req.Name, req.Email))
+}
+
+func (h *handlers) updateCustomer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	var req models.UpdateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, ok := h.store.UpdateCustomer(id, req.Name, req.Email)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *handlers) deleteCustomer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	hard, ok := h.adminFlag(c, "hard", "Only admins can delete records permanently")
+	if !ok {
+		return
+	}
+
+	if !h.store.DeleteCustomer(id, hard) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	message := "Customer deleted successfully"
+	if hard {
+		message = "Customer permanently deleted"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (h *handlers) restoreCustomer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	customer, ok := h.store.RestoreCustomer(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *handlers) getAccounts(c *gin.Context) {
+	customerID := 0
+	if value := c.Query("customer_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer_id"})
+			return
+		}
+		customerID = id
+	}
+	status, accountType := c.Query("status"), c.Query("type")
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "customer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by, expected customer"})
+		return
+	}
+	includeDeleted, ok := h.adminFlag(c, "include_deleted", "Only admins can list deleted records")
+	if !ok {
+		return
+	}
+
+	accounts := []models.Account{}
+	for _, account := range h.store.Accounts(includeDeleted) {
+		if (status != "" && account.Status != status) ||
+			(accountType != "" && account.Type != accountType) ||
+			(customerID != 0 && account.CustomerID != customerID) {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	value, ok := c.GetQuery("facets")
+	if groupBy != "" {
+		if ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "facets can't be combined with group_by"})
+			return
+		}
+		byCustomer := map[int][]models.Account{}
+		for _, account := range accounts {
+			byCustomer[account.CustomerID] = append(byCustomer[account.CustomerID], account)
+		}
+		customers := []models.CustomerAccounts{}
+		for _, customer := range h.store.Customers(includeDeleted) {
+			if customerAccounts := byCustomer[customer.ID]; len(customerAccounts) > 0 {
+				customers = append(customers, models.CustomerAccounts{Customer: customer, Accounts: customerAccounts})
+			}
+		}
+		c.JSON(http.StatusOK, customers)
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, accounts)
+		return
+	}
+	facets := models.Facets{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := repository.AccountFacets[name]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown facet " + name + ", expected one of: customer_id, status, type"})
+			return
+		}
+		counts := map[string]int64{}
+		for _, account := range accounts {
+			switch name {
+			case "status":
+				counts[account.Status]++
+			case "type":
+				counts[account.Type]++
+			case "customer_id":
+				counts[strconv.Itoa(account.CustomerID)]++
+			}
+		}
+		facets[name] = counts
+	}
+	c.JSON(http.StatusOK, models.AccountList{Accounts: accounts, Facets: facets})
+}
+
+func (h *handlers) getAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	account, ok := h.store.Account(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *handlers) getAccountByReference(c *gin.Context) {
+	account, ok := h.store.AccountByReference(strings.ToUpper(c.Param("reference")))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *handlers) createAccount(c *gin.Context) {
+	var req models.CreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = accountsettings.DefaultType
+	}
+	if !accountsettings.ValidType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account type, expected one of: " + strings.Join(accountsettings.Types(), ", ")})
+		return
+	}
+
+	account, ok := h.store.CreateAccount(req.CustomerID, req.Type, req.Name, req.Status)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *handlers) updateAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req models.UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	account, ok := h.store.UpdateAccount(id, req.Name, req.Status)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *handlers) getAccountSettings(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	settings, ok := h.store.AccountSettings(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *handlers) patchAccountSettings(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Settings patch must be a JSON object"})
+		return
+	}
+
+	settings, errs, ok := h.store.PatchAccountSettings(id, patch)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if len(errs) > 0 {
+		account, _ := h.store.Account(id)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Settings do not match the schema for " + account.Type + " accounts",
+			"details": errs,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *handlers) deleteAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	hard, ok := h.adminFlag(c, "hard", "Only admins can delete records permanently")
+	if !ok {
+		return
+	}
+
+	if !h.store.DeleteAccount(id, hard) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	message := "Account deleted successfully"
+	if hard {
+		message = "Account permanently deleted"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (h *handlers) restoreAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	account, err := h.store.RestoreAccount(id)
+	if errors.Is(err, repository.ErrCustomerNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The account's customer is deleted, restore the customer instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// adminFlag reads the boolean query parameter name, which only admins may set
+// to true, like the real handlers' include_deleted and hard. It writes a 400
+// or 403 response and returns false if the caller can't have it.
+func (h *handlers) adminFlag(c *gin.Context, name, forbidden string) (bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return false, true
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ", expected true or false"})
+		return false, false
+	}
+	if _, role, _ := h.store.User(c.GetString("username")); flag && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": forbidden})
+		return false, false
+	}
+	return flag, true
+}
+
+func (h *handlers) getAccountNotes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.store.Notes(id))
+}
+
+func (h *handlers) createAccountNote(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req models.CreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := h.store.Account(id); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.store.AddNote(id, c.GetString("username"), req.Body, api.ParseMentions(req.Body)))
+}
+
+func (h *handlers) searchNotes(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.store.SearchNotes(query, limit))
+}
+
+func (h *handlers) getNotifications(c *gin.Context) {
+	c.JSON(http.StatusOK, h.store.Notifications(c.GetString("username")))
+}
+
+func (h *handlers) getConsents(c *gin.Context) {
+	consents := h.store.Consents(c.GetString("username"))
+	accepted := make([]consent.Policy, 0, len(consents))
+	for _, record := range consents {
+		accepted = append(accepted, consent.Policy{Policy: record.Policy, Version: record.Version})
+	}
+	c.JSON(http.StatusOK, models.ConsentStatus{
+		Pending:  policyVersions(consent.Pending(accepted)),
+		Required: policyVersions(consent.Required()),
+		Consents: consents,
+	})
+}
+
+func (h *handlers) acceptConsent(c *gin.Context) {
+	var req models.AcceptConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	current, ok := consent.Current(req.Policy)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+	if current.Version != req.Version {
+		c.JSON(http.StatusConflict, gin.H{"error": "Version " + req.Version + " is not the current version of " + req.Policy + " (" + current.Version + ")"})
+		return
+	}
+
+	record, created := h.store.AcceptConsent(models.Consent{
+		Username:  c.GetString("username"),
+		Policy:    current.Policy,
+		Version:   current.Version,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if created {
+		c.JSON(http.StatusCreated, record)
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+func policyVersions(policies []consent.Policy) []models.PolicyVersion {
+	versions := make([]models.PolicyVersion, 0, len(policies))
+	for _, policy := range policies {
+		versions = append(versions, models.PolicyVersion{Policy: policy.Policy, Version: policy.Version})
+	}
+	return versions
+}
+
+func (h *handlers) getAnalytics(c *gin.Context) {
+	customers := h.store.Customers(false)
+	accounts := h.store.Accounts(false)
+
+	response := api.AnalyticsResponse{
+		TotalCustomers: len(customers),
+		TotalAccounts:  len(accounts),
+	}
+	customersWithAccounts := make(map[int]bool)
+	for _, account := range accounts {
+		customersWithAccounts[account.CustomerID] = true
+		switch account.Status {
+		case "active":
+			response.ActiveAccounts++
+		case "inactive":
+			response.InactiveAccounts++
+		}
+	}
+	if len(customersWithAccounts) > 0 {
+		response.AvgAccountsPerCustomer = float64(len(accounts)) / float64(len(customersWithAccounts))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handlers) getCustomerAnalytics(c *gin.Context) {
+	customerID := c.Param("customer_id")
+
+	var accountCount, activeCount int
+	for _, account := range h.store.Accounts(false) {
+		if strconv.Itoa(account.CustomerID) != customerID {
+			continue
+		}
+		accountCount++
+		if account.Status == "active" {
+			activeCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customer_id":       customerID,
+		"total_accounts":    accountCount,
+		"active_accounts":   activeCount,
+		"inactive_accounts": accountCount - activeCount,
+	})
+}
+
+func (h *handlers) getAnomalies(c *gin.Context) {
+	api.RespondRows(c, []models.AnomalyEvent{})
+}
+
+func (h *handlers) getAPIUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, api.APIUsageResponse{
+		Username:  c.DefaultQuery("username", c.GetString("username")),
+		Since:     time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Hour),
+		Endpoints: []api.APIUsageEndpoint{},
+	})
+}
+
+func (h *handlers) getDuplicateAccounts(c *gin.Context) {
+	api.RespondRows(c, []models.DuplicateCandidate{})
+}
+
+func (h *handlers) getForecast(c *gin.Context) {
+	metric, historyDays, days, ok := api.ParseForecastParams(c)
+	if !ok {
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start := today.AddDate(0, 0, -historyDays)
+	history := make([]forecast.Point, historyDays)
+	for i := range history {
+		history[i].Date = start.AddDate(0, 0, i)
+	}
+	count := func(createdAt time.Time) {
+		if i := int(createdAt.UTC().Sub(start).Hours() / 24); !createdAt.Before(start) && i < historyDays {
+			history[i].Value++
+		}
+	}
+	if metric == "customers" {
+		for _, customer := range h.store.Customers(false) {
+			count(customer.CreatedAt)
+		}
+	} else {
+		for _, account := range h.store.Accounts(false) {
+			count(account.CreatedAt)
+		}
+	}
+
+	response, err := api.BuildForecast(metric, history, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fit forecast"})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handlers) getUsageHeatmap(c *gin.Context) {
+	grid := func() [][]int64 {
+		rows := make([][]int64, 7)
+		for i := range rows {
+			rows[i] = make([]int64, 24)
+		}
+		return rows
+	}
+	c.JSON(http.StatusOK, api.UsageHeatmapResponse{
+		Username: c.DefaultQuery("username", c.GetString("username")),
+		Calls:    grid(),
+		Errors:   grid(),
+	})
+}
+
+// getDataQuality measures the in-memory store on every request. Checks that
+// depend on Postgres-only state (contact issues, staleness) are left out.
+func (h *handlers) getDataQuality(c *gin.Context) {
+	customers := h.store.Customers(false)
+	accounts := h.store.Accounts(false)
+	now := time.Now().UTC()
+	metric := func(table, name, description string, total int, failing func(i int) bool) models.DataQualityMetric {
+		m := models.DataQualityMetric{Table: table, Metric: name, Description: description, Total: int64(total), MeasuredAt: now}
+		for i := 0; i < total; i++ {
+			if failing(i) {
+				m.Failing++
+			}
+		}
+		return m
+	}
+
+	withAccounts := map[int]bool{}
+	for _, account := range accounts {
+		withAccounts[account.CustomerID] = true
+	}
+	c.JSON(http.StatusOK, api.BuildDataQuality([]models.DataQualityMetric{
+		metric("accounts", "default_name", "Accounts still named like a placeholder, e.g. \"Premium Account\"", len(accounts), func(i int) bool {
+			return jobs.IsDefaultAccountName(accounts[i].Name)
+		}),
+		metric("accounts", "missing_reference", "Accounts without a reference", len(accounts), func(i int) bool {
+			return accounts[i].Reference == ""
+		}),
+		metric("customers", "missing_email", "Customers without an email address", len(customers), func(i int) bool {
+			return strings.TrimSpace(customers[i].Email) == ""
+		}),
+		metric("customers", "without_accounts", "Customers with no accounts", len(customers), func(i int) bool {
+			return !withAccounts[customers[i].ID]
+		}),
+	}))
+}
@@ -0,0 +1,357 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"saas-go-app/internal/api"
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMockLoginAndListAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+
+	body, _ := json.Marshal(map[string]string{"username": "admin", "password": "admin123"})
+	req, _ := http.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil || login.Token == "" {
+		t.Fatalf("Expected a token, got %s", w.Body.String())
+	}
+	if claims, err := auth.ValidateToken(login.Token); err != nil || claims.UserID == 0 || claims.Role != "admin" {
+		t.Errorf("Expected a JWT with the admin's ID and role, got %+v, %v", claims, err)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/accounts", nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var accounts []models.Account
+	if err := json.Unmarshal(w.Body.Bytes(), &accounts); err != nil {
+		t.Fatalf("Failed to decode accounts: %v", err)
+	}
+	if len(accounts) != 11 {
+		t.Errorf("Expected 11 demo accounts, got %d", len(accounts))
+	}
+	if accounts[0].Reference == "" {
+		t.Error("Expected demo accounts to have references")
+	}
+}
+
+func TestMockPatchAccountSettingsValidates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	req, _ := http.NewRequest("GET", "/api/accounts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var accounts []models.Account
+	if err := json.Unmarshal(w.Body.Bytes(), &accounts); err != nil || len(accounts) == 0 {
+		t.Fatalf("Expected demo accounts, got %s", w.Body.String())
+	}
+	path := fmt.Sprintf("/api/accounts/%d/settings", accounts[0].ID)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := patch(`{"seats": 10}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected enterprise-only setting to be rejected for a standard account, got %d", w.Code)
+	}
+
+	w = patch(`{"currency": "EUR", "notifications": {"digest": "weekly"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var settings models.AccountSettings
+	if err := json.Unmarshal(w.Body.Bytes(), &settings); err != nil {
+		t.Fatalf("Failed to decode settings: %v", err)
+	}
+	if settings.Type != "standard" || settings.Settings["currency"] != "EUR" {
+		t.Errorf("Expected merged standard settings, got %+v", settings)
+	}
+}
+
+func TestMockForecast(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/analytics/forecast?metric=customers&history=28&days=7")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		History  []json.RawMessage `json:"history"`
+		Forecast []json.RawMessage `json:"forecast"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode forecast: %v", err)
+	}
+	if len(response.History) != 28 || len(response.Forecast) != 7 {
+		t.Errorf("Expected 28 days of history and 7 of forecast, got %d and %d", len(response.History), len(response.Forecast))
+	}
+
+	for _, query := range []string{"metric=notes", "history=7", "days=0"} {
+		if w := get("/api/analytics/forecast?" + query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+}
+
+func TestMockDataQuality(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	req, _ := http.NewRequest("GET", "/api/analytics/data-quality", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Score  float64 `json:"score"`
+		Tables []struct {
+			Table   string            `json:"table"`
+			Metrics []json.RawMessage `json:"metrics"`
+		} `json:"tables"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode data quality report: %v", err)
+	}
+	if len(response.Tables) != 2 || len(response.Tables[0].Metrics) != 2 {
+		t.Errorf("Expected two tables with two metrics each, got %s", w.Body.String())
+	}
+	if response.Score <= 0 || response.Score > 100 {
+		t.Errorf("Expected a score between 0 and 100, got %.1f", response.Score)
+	}
+}
+
+func TestMockAccountFacets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	req, _ := http.NewRequest("GET", "/api/accounts?status=active&facets=status,customer_id", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list models.AccountList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode account list: %v", err)
+	}
+	if len(list.Accounts) != 8 || list.Facets["status"]["active"] != 8 {
+		t.Errorf("Expected the 8 active demo accounts with matching facet counts, got %d and %v", len(list.Accounts), list.Facets)
+	}
+	if len(list.Facets["customer_id"]) != 5 {
+		t.Errorf("Expected active accounts across 5 customers, got %v", list.Facets["customer_id"])
+	}
+}
+
+func TestMockAccountsByCustomer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	req, _ := http.NewRequest("GET", "/api/accounts?group_by=customer&status=inactive", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var customers []models.CustomerAccounts
+	if err := json.Unmarshal(w.Body.Bytes(), &customers); err != nil {
+		t.Fatalf("Failed to decode customers: %v", err)
+	}
+	if len(customers) != 3 {
+		t.Fatalf("Expected the 3 demo customers with inactive accounts, got %d", len(customers))
+	}
+	for _, customer := range customers {
+		for _, account := range customer.Accounts {
+			if account.CustomerID != customer.ID || account.Status != "inactive" {
+				t.Errorf("Expected inactive accounts of customer %d, got %+v", customer.ID, account)
+			}
+		}
+	}
+}
+
+func TestMockRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	post := func(path string, payload map[string]string) (int, api.LoginResponse) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response api.LoginResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	_, login := post("/api/auth/login", map[string]string{"username": "admin", "password": "admin123"})
+	if login.RefreshToken == "" {
+		t.Fatal("Expected a refresh token with the login")
+	}
+	code, refreshed := post("/api/auth/refresh", map[string]string{"refresh_token": login.RefreshToken})
+	if code != http.StatusOK || refreshed.Token == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Fatalf("Expected a new token and refresh token, got %d %+v", code, refreshed)
+	}
+
+	// Reusing the first token revokes the family, including its successor
+	if code, _ := post("/api/auth/refresh", map[string]string{"refresh_token": login.RefreshToken}); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a reused token, got %d", http.StatusUnauthorized, code)
+	}
+	if code, _ := post("/api/auth/refresh", map[string]string{"refresh_token": refreshed.RefreshToken}); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d after the family was revoked, got %d", http.StatusUnauthorized, code)
+	}
+}
+
+func TestMockLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+	t.Cleanup(func() { auth.CheckRevocations(nil) })
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+	request := func(path, body string) int {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("/api/auth/logout", ""); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := request("/api/auth/logout", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for the revoked token, got %d", http.StatusUnauthorized, code)
+	}
+}
+
+func TestMockSoftDeleteAndRestore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = auth.InitJWT()
+
+	router := gin.New()
+	RegisterRoutes(router)
+	token, _ := auth.GenerateToken(1, "admin", "admin")
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	countAccounts := func(query string) int {
+		var accounts []models.Account
+		if err := json.Unmarshal(request("GET", "/api/accounts"+query).Body.Bytes(), &accounts); err != nil {
+			t.Fatalf("Failed to decode accounts: %v", err)
+		}
+		return len(accounts)
+	}
+
+	var customers []models.Customer
+	if err := json.Unmarshal(request("GET", "/api/customers").Body.Bytes(), &customers); err != nil || len(customers) == 0 {
+		t.Fatal("Expected demo customers")
+	}
+	customer := customers[len(customers)-1]
+	path := fmt.Sprintf("/api/customers/%d", customer.ID)
+	before := countAccounts("")
+
+	if w := request("DELETE", path); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d deleting, got %d", http.StatusOK, w.Code)
+	}
+	if w := request("GET", path); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a deleted customer, got %d", http.StatusNotFound, w.Code)
+	}
+	after := countAccounts("")
+	if after >= before {
+		t.Errorf("Expected the customer's accounts to be deleted with it, got %d of %d", after, before)
+	}
+	if all := countAccounts("?include_deleted=true"); all != before {
+		t.Errorf("Expected include_deleted to list all %d accounts, got %d", before, all)
+	}
+
+	if w := request("POST", path+"/restore"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d restoring, got %d", http.StatusOK, w.Code)
+	}
+	if w := request("POST", path+"/restore"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d restoring a live customer, got %d", http.StatusNotFound, w.Code)
+	}
+	if restored := countAccounts(""); restored != before {
+		t.Errorf("Expected the customer's accounts to be restored, got %d of %d", restored, before)
+	}
+
+	token, _ = auth.GenerateToken(2, "viewer", "user")
+	if w := request("GET", "/api/customers?include_deleted=true"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for include_deleted without admin, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,491 @@
+// Package mock serves the API from an in-memory store so the frontend can be
+// developed without Postgres or Redis. Enable it with APP_MODE=mock.
+package mock
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/accountsettings"
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/jsonschema"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/repository"
+)
+
+// Store holds all mock data. It is safe for concurrent use.
+type Store struct {
+	mu            sync.RWMutex
+	customers     map[int]*models.Customer
+	accounts      map[int]*models.Account
+	accountSeq    map[int]int
+	settings      map[int]map[string]interface{}
+	users         map[string]*user
+	refreshTokens map[string]*refreshToken
+	revoked       map[int]bool         // revoked refresh token families
+	revokedTokens map[string]time.Time // revoked JWT IDs, until they expire
+	notes         []models.Note
+	notifications []models.Notification
+	consents      []models.Consent
+	nextID        int
+}
+
+// NewStore creates a store seeded with the demo profile and the default admin user
+func NewStore() *Store {
+	s := &Store{
+		customers:     make(map[int]*models.Customer),
+		accounts:      make(map[int]*models.Account),
+		accountSeq:    make(map[int]int),
+		settings:      make(map[int]map[string]interface{}),
+		users:         make(map[string]*user),
+		refreshTokens: make(map[string]*refreshToken),
+		revoked:       make(map[int]bool),
+		revokedTokens: make(map[string]time.Time),
+	}
+
+	if hash, err := auth.HashPassword("admin123"); err == nil {
+		s.users["admin"] = &user{id: s.id(), role: "admin", passwordHash: hash}
+	}
+
+	customerIDs := make([]int, 0, len(db.DemoCustomers))
+	for _, customer := range db.DemoCustomers {
+		created := s.CreateCustomer(customer.Name, customer.Email)
+		s.customers[created.ID].Industry = customer.Industry
+		s.customers[created.ID].Country = customer.Country
+		s.customers[created.ID].ARRBand = customer.ARRBand
+		customerIDs = append(customerIDs, created.ID)
+	}
+	for _, account := range db.DemoAccounts {
+		created, _ := s.CreateAccount(customerIDs[account.CustomerIndex], accountsettings.DefaultType, account.Name, account.Status)
+		s.accounts[created.ID].MRRCents = account.MRRCents
+	}
+
+	return s
+}
+
+func (s *Store) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+// user is a registered user
+type user struct {
+	id           int
+	role         string
+	passwordHash string
+}
+
+// CheckUser verifies a username/password pair
+func (s *Store) CheckUser(username, password string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	return ok && auth.CheckPasswordHash(password, u.passwordHash)
+}
+
+// User returns the ID and role of a user. ok is false for unknown users.
+func (s *Store) User(username string) (id int, role string, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	if !ok {
+		return 0, "", false
+	}
+	return u.id, u.role, true
+}
+
+// CreateUser registers a user, returning false if the username is taken
+func (s *Store) CreateUser(username, passwordHash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[username]; exists {
+		return false
+	}
+	s.users[username] = &user{id: s.id(), role: models.RoleTenant, passwordHash: passwordHash}
+	return true
+}
+
+// refreshToken is a stored refresh token. Mock tokens don't expire.
+type refreshToken struct {
+	username string
+	family   int
+	used     bool
+}
+
+// IssueRefreshToken stores token for username, starting a new family
+func (s *Store) IssueRefreshToken(username, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshTokens[token] = &refreshToken{username: username, family: s.id()}
+}
+
+// RotateRefreshToken uses up token and stores next in its family, returning
+// the user it belongs to. A token used before revokes its family. ok is false
+// for unknown, revoked, and reused tokens.
+func (s *Store) RotateRefreshToken(token, next string) (username string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, exists := s.refreshTokens[token]
+	if !exists || s.revoked[current.family] {
+		return "", false
+	}
+	if current.used {
+		s.revoked[current.family] = true
+		return "", false
+	}
+	current.used = true
+	s.refreshTokens[next] = &refreshToken{username: current.username, family: current.family}
+	return current.username, true
+}
+
+// RevokeRefreshToken revokes the family of token
+func (s *Store) RevokeRefreshToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, exists := s.refreshTokens[token]; exists {
+		s.revoked[current.family] = true
+	}
+}
+
+// RevokeToken revokes the JWT with ID jti until it expires
+func (s *Store) RevokeToken(jti string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokedTokens[jti] = expiresAt
+}
+
+// TokenRevoked reports whether the JWT with ID jti was revoked
+func (s *Store) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	expiresAt, ok := s.revokedTokens[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// Customers returns all customers, newest first. Soft-deleted customers are
+// left out unless includeDeleted is set.
+func (s *Store) Customers(includeDeleted bool) []models.Customer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	customers := make([]models.Customer, 0, len(s.customers))
+	for _, customer := range s.customers {
+		if customer.DeletedAt == nil || includeDeleted {
+			customers = append(customers, *customer)
+		}
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID > customers[j].ID })
+	return customers
+}
+
+// Customer returns a single customer that isn't soft-deleted
+func (s *Store) Customer(id int) (models.Customer, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	customer, ok := s.customers[id]
+	if !ok || customer.DeletedAt != nil {
+		return models.Customer{}, false
+	}
+	return *customer, true
+}
+
+// CreateCustomer adds a customer
+func (s *Store) CreateCustomer(name, email string) models.Customer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	customer := &models.Customer{ID: s.id(), Name: name, Email: email, CreatedAt: now, UpdatedAt: now}
+	s.customers[customer.ID] = customer
+	return *customer
+}
+
+// UpdateCustomer updates a customer
+func (s *Store) UpdateCustomer(id int, name, email string) (models.Customer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	customer, ok := s.customers[id]
+	if !ok || customer.DeletedAt != nil {
+		return models.Customer{}, false
+	}
+	customer.Name, customer.Email, customer.UpdatedAt = name, email, time.Now()
+	return *customer, true
+}
+
+// DeleteCustomer soft-deletes a customer and its live accounts, which get the
+// customer's deleted_at so RestoreCustomer can tell them apart. With hard, it
+// removes the customer and all its accounts, whether or not it was
+// soft-deleted.
+func (s *Store) DeleteCustomer(id int, hard bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	customer, ok := s.customers[id]
+	if !ok || (customer.DeletedAt != nil && !hard) {
+		return false
+	}
+	if hard {
+		delete(s.customers, id)
+		for accountID, account := range s.accounts {
+			if account.CustomerID == id {
+				delete(s.accounts, accountID)
+				delete(s.settings, accountID)
+			}
+		}
+		return true
+	}
+	now := time.Now()
+	customer.DeletedAt = &now
+	for _, account := range s.accounts {
+		if account.CustomerID == id && account.DeletedAt == nil {
+			account.DeletedAt = &now
+		}
+	}
+	return true
+}
+
+// RestoreCustomer brings back a soft-deleted customer and the accounts deleted
+// with it
+func (s *Store) RestoreCustomer(id int) (models.Customer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	customer, ok := s.customers[id]
+	if !ok || customer.DeletedAt == nil {
+		return models.Customer{}, false
+	}
+	for _, account := range s.accounts {
+		if account.CustomerID == id && account.DeletedAt != nil && account.DeletedAt.Equal(*customer.DeletedAt) {
+			account.DeletedAt = nil
+		}
+	}
+	customer.DeletedAt = nil
+	return *customer, true
+}
+
+// Accounts returns all accounts, newest first. Soft-deleted accounts are left
+// out unless includeDeleted is set.
+func (s *Store) Accounts(includeDeleted bool) []models.Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	accounts := make([]models.Account, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		if account.DeletedAt == nil || includeDeleted {
+			accounts = append(accounts, *account)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID > accounts[j].ID })
+	return accounts
+}
+
+// Account returns a single account that isn't soft-deleted
+func (s *Store) Account(id int) (models.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	account, ok := s.accounts[id]
+	if !ok || account.DeletedAt != nil {
+		return models.Account{}, false
+	}
+	return *account, true
+}
+
+// AccountByReference returns the account with the given reference
+func (s *Store) AccountByReference(reference string) (models.Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, account := range s.accounts {
+		if account.Reference == reference && account.DeletedAt == nil {
+			return *account, true
+		}
+	}
+	return models.Account{}, false
+}
+
+// CreateAccount adds an account, returning false if the customer doesn't exist
+func (s *Store) CreateAccount(customerID int, accountType, name, status string) (models.Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if customer, ok := s.customers[customerID]; !ok || customer.DeletedAt != nil {
+		return models.Account{}, false
+	}
+	s.accountSeq[customerID]++
+	now := time.Now()
+	account := &models.Account{
+		ID:         s.id(),
+		CustomerID: customerID,
+		Reference:  db.FormatAccountReference(db.AccountReferencePrefix(), customerID, s.accountSeq[customerID], 4),
+		Type:       accountType,
+		Name:       name,
+		Status:     status,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	s.accounts[account.ID] = account
+	s.settings[account.ID] = map[string]interface{}{}
+	return *account, true
+}
+
+// AccountSettings returns an account's settings document
+func (s *Store) AccountSettings(id int) (models.AccountSettings, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	account, ok := s.accounts[id]
+	if !ok || account.DeletedAt != nil {
+		return models.AccountSettings{}, false
+	}
+	return models.AccountSettings{AccountID: id, Type: account.Type, Settings: s.settings[id], UpdatedAt: account.UpdatedAt}, true
+}
+
+// PatchAccountSettings merges patch into an account's settings if the result
+// matches the account type's schema, otherwise it returns the violations
+func (s *Store) PatchAccountSettings(id int, patch map[string]interface{}) (models.AccountSettings, []jsonschema.Error, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok || account.DeletedAt != nil {
+		return models.AccountSettings{}, nil, false
+	}
+	merged := accountsettings.Merge(s.settings[id], patch)
+	if errs := accountsettings.Validate(account.Type, merged); len(errs) > 0 {
+		return models.AccountSettings{}, errs, true
+	}
+	s.settings[id] = merged
+	account.UpdatedAt = time.Now()
+	return models.AccountSettings{AccountID: id, Type: account.Type, Settings: merged, UpdatedAt: account.UpdatedAt}, nil, true
+}
+
+// UpdateAccount updates an account
+func (s *Store) UpdateAccount(id int, name, status string) (models.Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok || account.DeletedAt != nil {
+		return models.Account{}, false
+	}
+	account.Name, account.Status, account.UpdatedAt = name, status, time.Now()
+	return *account, true
+}
+
+// DeleteAccount soft-deletes an account, or removes it with hard
+func (s *Store) DeleteAccount(id int, hard bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok || (account.DeletedAt != nil && !hard) {
+		return false
+	}
+	if hard {
+		delete(s.accounts, id)
+		delete(s.settings, id)
+		return true
+	}
+	now := time.Now()
+	account.DeletedAt = &now
+	return true
+}
+
+// RestoreAccount brings back a soft-deleted account. It returns
+// repository.ErrNotFound if the account isn't deleted, and
+// repository.ErrCustomerNotFound if its customer is.
+func (s *Store) RestoreAccount(id int) (models.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.accounts[id]
+	if !ok || account.DeletedAt == nil {
+		return models.Account{}, repository.ErrNotFound
+	}
+	if customer := s.customers[account.CustomerID]; customer == nil || customer.DeletedAt != nil {
+		return models.Account{}, repository.ErrCustomerNotFound
+	}
+	account.DeletedAt = nil
+	return *account, nil
+}
+
+// AddNote attaches a note to an account and notifies mentioned users
+func (s *Store) AddNote(accountID int, author, body string, mentions []string) models.Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	note := models.Note{ID: s.id(), AccountID: accountID, Author: author, Body: body, Mentions: mentions, CreatedAt: time.Now()}
+	s.notes = append(s.notes, note)
+	for _, username := range mentions {
+		if _, ok := s.users[username]; ok {
+			s.notifications = append(s.notifications, models.Notification{
+				ID:        s.id(),
+				Username:  username,
+				Kind:      "mention",
+				Message:   author + " mentioned you in a note",
+				CreatedAt: note.CreatedAt,
+			})
+		}
+	}
+	return note
+}
+
+// Notes returns the notes for an account, newest first
+func (s *Store) Notes(accountID int) []models.Note {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	notes := []models.Note{}
+	for i := len(s.notes) - 1; i >= 0; i-- {
+		if s.notes[i].AccountID == accountID {
+			notes = append(notes, s.notes[i])
+		}
+	}
+	return notes
+}
+
+// SearchNotes does a case-insensitive substring match over note bodies
+func (s *Store) SearchNotes(query string, limit int) []models.NoteSearchResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := []models.NoteSearchResult{}
+	query = strings.ToLower(query)
+	for i := len(s.notes) - 1; i >= 0 && len(results) < limit; i-- {
+		if strings.Contains(strings.ToLower(s.notes[i].Body), query) {
+			results = append(results, models.NoteSearchResult{Note: s.notes[i], Rank: 1, Headline: s.notes[i].Body})
+		}
+	}
+	return results
+}
+
+// Notifications returns the notifications for a user, newest first
+func (s *Store) Notifications(username string) []models.Notification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	notifications := []models.Notification{}
+	for i := len(s.notifications) - 1; i >= 0; i-- {
+		if s.notifications[i].Username == username {
+			notifications = append(notifications, s.notifications[i])
+		}
+	}
+	return notifications
+}
+
+// Consents returns a user's consents, newest first
+func (s *Store) Consents(username string) []models.Consent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	consents := []models.Consent{}
+	for i := len(s.consents) - 1; i >= 0; i-- {
+		if s.consents[i].Username == username {
+			consents = append(consents, s.consents[i])
+		}
+	}
+	return consents
+}
+
+// AcceptConsent records a consent, returning the existing one and false if
+// the user already accepted that version
+func (s *Store) AcceptConsent(consent models.Consent) (models.Consent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.consents {
+		if existing.Username == consent.Username && existing.Policy == consent.Policy && existing.Version == consent.Version {
+			return existing, false
+		}
+	}
+	consent.ID = int64(s.id())
+	consent.AcceptedAt = time.Now()
+	s.consents = append(s.consents, consent)
+	return consent, true
+}
@@ -1,15 +1,36 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Account represents an account in the system
 type Account struct {
 	ID         int       `json:"id" db:"id"`
+	// UUID is the account's UUIDv7, accepted in paths in place of ID
+	UUID       string    `json:"uuid" db:"uuid"`
 	CustomerID int       `json:"customer_id" db:"customer_id"`
+	Reference  string    `json:"reference" db:"reference"`
+	Type       string    `json:"type" db:"type"`
 	Name       string    `json:"name" db:"name"`
 	Status     string    `json:"status" db:"status"`
+	// MRRCents is the monthly recurring revenue in cents
+	MRRCents   int64     `json:"mrr_cents" db:"mrr_cents"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	// Version increases with every update; send it back to update the
+	// account (see UpdateAccountRequest)
+	Version    int       `json:"version" db:"version"`
+	// DeletedAt is set on soft-deleted accounts, which only admins can list
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// CustomerAccounts represents a customer with its accounts nested, returned by
+// GET /accounts?group_by=customer
+type CustomerAccounts struct {
+	Customer
+	Accounts []Account `json:"accounts"`
 }
 
 // CreateAccountRequest represents the request payload for creating an account
@@ -17,11 +38,29 @@ type CreateAccountRequest struct {
 	CustomerID int    `json:"customer_id" binding:"required"`
 	Name       string `json:"name" binding:"required"`
 	Status     string `json:"status" binding:"required"`
+	// Type selects the settings schema (default "standard")
+	Type string `json:"type"`
 }
 
 // UpdateAccountRequest represents the request payload for updating an account
 type UpdateAccountRequest struct {
 	Name   string `json:"name" binding:"required"`
 	Status string `json:"status" binding:"required"`
+	// Version is the version the update is based on, required unless the
+	// If-Match header gives it
+	Version int `json:"version"`
 }
 
+// AccountSettings represents an account's settings document
+type AccountSettings struct {
+	AccountID int                    `json:"account_id"`
+	Type      string                 `json:"type"`
+	Settings  map[string]interface{} `json:"settings"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// AccountType represents an account type and the JSON Schema its settings must match
+type AccountType struct {
+	Type   string          `json:"type"`
+	Schema json.RawMessage `json:"schema" swaggertype:"object"`
+}
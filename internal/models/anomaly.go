@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AnomalyEvent represents a metric that exceeded its rolling baseline
+type AnomalyEvent struct {
+	ID             int       `json:"id" db:"id"`
+	Metric         string    `json:"metric" db:"metric"`
+	Observed       float64   `json:"observed" db:"observed"`
+	BaselineMean   float64   `json:"baseline_mean" db:"baseline_mean"`
+	BaselineStdDev float64   `json:"baseline_stddev" db:"baseline_stddev"`
+	Threshold      float64   `json:"threshold" db:"threshold"`
+	DetectedAt     time.Time `json:"detected_at" db:"detected_at"`
+}
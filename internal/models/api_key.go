@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// APIKey represents an API key a user's scripts and CI jobs authenticate with
+type APIKey struct {
+	ID     int      `json:"id" db:"id"`
+	Name   string   `json:"name" db:"name"`
+	Prefix string   `json:"prefix" db:"prefix"`
+	Scopes []string `json:"scopes" db:"scopes"`
+	// AllowedCIDRs restrict where the key can be used from; empty allows
+	// any address
+	AllowedCIDRs []string   `json:"allowed_cidrs" db:"allowed_cidrs"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at" db:"last_used_at"`
+	LastUsedIP   *string    `json:"last_used_ip" db:"last_used_ip"`
+	ExpiresAt    *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at" db:"revoked_at"`
+	// Key is only returned when the key is created
+	Key string `json:"key,omitempty" db:"-"`
+}
+
+// CreateAPIKeyRequest represents the request payload for minting an API key
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Scopes default to read
+	Scopes []string `json:"scopes"`
+	// AllowedCIDRs are CIDR ranges or IP addresses the key can be used
+	// from; empty allows any address
+	AllowedCIDRs []string   `json:"allowed_cidrs"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// UpdateAPIKeyCIDRsRequest represents the request payload for changing where
+// an API key can be used from
+type UpdateAPIKeyCIDRsRequest struct {
+	// AllowedCIDRs replace the key's ranges; empty allows any address
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+}
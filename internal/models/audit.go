@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AuditEntry is an insert, update, or delete of a customer or account, as
+// recorded in audit_log
+type AuditEntry struct {
+	ID int64 `json:"id"`
+	// Entity is the table of the row, customers or accounts
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entity_id"`
+	// Action is insert, update, or delete
+	Action string `json:"action"`
+	// Actor is the user whose request made the change, or system
+	Actor string `json:"actor"`
+	// Before is the row before the change, null for inserts
+	Before map[string]interface{} `json:"before"`
+	// After is the row after the change, null for deletes
+	After     map[string]interface{} `json:"after"`
+	CreatedAt time.Time              `json:"created_at"`
+}
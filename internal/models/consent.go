@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Consent records that a user accepted one version of a policy
+type Consent struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Policy     string    `json:"policy"`
+	Version    string    `json:"version"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// ConsentStatus is the caller's standing against the current policies
+type ConsentStatus struct {
+	// Pending lists the current policy versions the caller still has to accept
+	Pending  []PolicyVersion `json:"pending"`
+	Required []PolicyVersion `json:"required"`
+	Consents []Consent       `json:"consents"`
+}
+
+// PolicyVersion identifies one version of a policy document
+type PolicyVersion struct {
+	Policy  string `json:"policy"`
+	Version string `json:"version"`
+}
+
+// AcceptConsentRequest represents the request to accept a policy
+type AcceptConsentRequest struct {
+	Policy  string `json:"policy" binding:"required"`
+	Version string `json:"version" binding:"required"`
+}
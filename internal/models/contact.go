@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ContactIssue represents a customer contact field that failed validation
+type ContactIssue struct {
+	CustomerID int       `json:"customer_id" db:"customer_id"`
+	Field      string    `json:"field" db:"field"`
+	Value      string    `json:"value" db:"value"`
+	Issue      string    `json:"issue" db:"issue"`
+	DetectedAt time.Time `json:"detected_at" db:"detected_at"`
+}
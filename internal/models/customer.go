@@ -5,10 +5,22 @@ import "time"
 // Customer represents a customer in the system
 type Customer struct {
 	ID        int       `json:"id" db:"id"`
+	// UUID is the customer's UUIDv7, accepted in paths in place of ID
+	UUID      string    `json:"uuid" db:"uuid"`
 	Name      string    `json:"name" db:"name"`
 	Email     string    `json:"email" db:"email"`
+	// Industry, Country (ISO 3166-1 alpha-2), and ARRBand are set on seeded
+	// demo customers
+	Industry  string    `json:"industry,omitempty" db:"industry"`
+	Country   string    `json:"country,omitempty" db:"country"`
+	ARRBand   string    `json:"arr_band,omitempty" db:"arr_band"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Version increases with every update; send it back to update the
+	// customer (see UpdateCustomerRequest)
+	Version int `json:"version" db:"version"`
+	// DeletedAt is set on soft-deleted customers, which only admins can list
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 // CreateCustomerRequest represents the request payload for creating a customer
@@ -21,5 +33,8 @@ type CreateCustomerRequest struct {
 type UpdateCustomerRequest struct {
 	Name  string `json:"name" binding:"required"`
 	Email string `json:"email" binding:"required,email"`
+	// Version is the version the update is based on, required unless the
+	// If-Match header gives it
+	Version int `json:"version"`
 }
 
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// CustomerToken represents an API token a customer uses to read its own data
+type CustomerToken struct {
+	ID         int `json:"id" db:"id"`
+	CustomerID int `json:"customer_id" db:"customer_id"`
+	// AccountID limits the token to one of the customer's accounts
+	AccountID  *int       `json:"account_id" db:"account_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedBy  string     `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	// Token is only returned when the token is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// Active reports whether the token can still be used at now
+func (t CustomerToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// HasScope reports whether the token was granted scope
+func (t CustomerToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCustomerTokenRequest represents the request payload for issuing a customer token
+type CreateCustomerTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+	// AccountID limits the token to one of the customer's accounts
+	AccountID *int `json:"account_id"`
+	// Scopes default to every scope: accounts:read, usage:read, and statements:read
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DataQualityMetric represents how many rows of a table fail one completeness check
+type DataQualityMetric struct {
+	Table       string    `json:"table" db:"table_name"`
+	Metric      string    `json:"metric" db:"metric"`
+	Description string    `json:"description" db:"description"`
+	Failing     int64     `json:"failing" db:"failing"`
+	Total       int64     `json:"total" db:"total"`
+	MeasuredAt  time.Time `json:"measured_at" db:"measured_at"`
+}
+
+// Ratio returns the share of rows failing the check, 0 for an empty table
+func (m DataQualityMetric) Ratio() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Failing) / float64(m.Total)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DuplicateCandidate represents two accounts of the same customer that are
+// likely duplicates, with a suggestion of which one to keep
+type DuplicateCandidate struct {
+	CustomerID          int       `json:"customer_id" db:"customer_id"`
+	KeepAccountID       int       `json:"keep_account_id" db:"keep_account_id"`
+	KeepName            string    `json:"keep_name" db:"keep_name"`
+	MergeAccountID      int       `json:"merge_account_id" db:"merge_account_id"`
+	MergeName           string    `json:"merge_name" db:"merge_name"`
+	Score               float64   `json:"score" db:"score"`
+	NameSimilarity      float64   `json:"name_similarity" db:"name_similarity"`
+	CreatedApartSeconds float64   `json:"created_apart_seconds" db:"created_apart_seconds"`
+	DetectedAt          time.Time `json:"detected_at" db:"detected_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// Facets counts the records matching a list's filter by field, then by value,
+// e.g. {"status": {"active": 42, "inactive": 7}}
+type Facets map[string]map[string]int64
+
+// AccountList represents a page of accounts with facet counts, returned when facets are requested
+type AccountList struct {
+	Accounts []Account `json:"accounts"`
+	Facets   Facets    `json:"facets"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// FieldChange represents one field whose value differs between two points in time
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// AccountChange represents the field changes to an account that existed at both points in time
+type AccountChange struct {
+	AccountID int           `json:"account_id"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// AccountsDiff represents how a customer's accounts changed between two points in time
+type AccountsDiff struct {
+	Added   []Account       `json:"added"`
+	Removed []Account       `json:"removed"`
+	Changed []AccountChange `json:"changed"`
+}
+
+// CustomerDiff represents what changed for a customer and its accounts in a time window
+type CustomerDiff struct {
+	CustomerID int           `json:"customer_id"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Changes    []FieldChange `json:"changes"`
+	Accounts   AccountsDiff  `json:"accounts"`
+}
+
+// TableDiff summarizes how the rows of a versioned table changed between two
+// points in time. Fields counts changed rows per field; the ID lists are
+// samples capped by the request.
+type TableDiff struct {
+	Table      string         `json:"table"`
+	Added      int            `json:"added"`
+	Removed    int            `json:"removed"`
+	Changed    int            `json:"changed"`
+	Fields     map[string]int `json:"fields"`
+	AddedIDs   []int          `json:"added_ids"`
+	RemovedIDs []int          `json:"removed_ids"`
+	ChangedIDs []int          `json:"changed_ids"`
+}
+
+// SnapshotDiff represents the per-table changes between two points in time
+type SnapshotDiff struct {
+	From   time.Time   `json:"from"`
+	To     time.Time   `json:"to"`
+	Tables []TableDiff `json:"tables"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// ImportUpload represents a resumable, chunked upload of a CSV file to be imported.
+// The ID is the resume token: clients use it to upload missing parts after an
+// interruption.
+type ImportUpload struct {
+	ID        string    `json:"id" db:"id"`
+	Filename  string    `json:"filename" db:"filename"`
+	Size      int64     `json:"size" db:"size"`
+	ChunkSize int64     `json:"chunk_size" db:"chunk_size"`
+	Parts     int       `json:"parts"`
+	Status    string    `json:"status" db:"status"`
+	JobID     *string   `json:"job_id" db:"job_id"`
+	Imported  int64     `json:"imported" db:"imported"`
+	Skipped   int64     `json:"skipped" db:"skipped"`
+	Error     *string   `json:"error" db:"error"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Received lists the parts stored so far; Missing the part numbers still to upload
+	Received []ImportUploadPart `json:"received_parts"`
+	Missing  []int              `json:"missing_parts"`
+
+	ObjectKey   string `json:"-" db:"object_key"`
+	MultipartID string `json:"-" db:"multipart_id"`
+}
+
+// ImportUploadPart represents one chunk of an upload, validated against its checksum
+type ImportUploadPart struct {
+	Number     int       `json:"number" db:"part_number"`
+	Size       int64     `json:"size" db:"size"`
+	SHA256     string    `json:"sha256" db:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at" db:"uploaded_at"`
+
+	ETag string `json:"-" db:"etag"`
+}
+
+// CreateImportUploadRequest represents the request payload for starting an upload
+type CreateImportUploadRequest struct {
+	Filename  string `json:"filename" binding:"required,max=255"`
+	Size      int64  `json:"size" binding:"required,gt=0"`
+	ChunkSize int64  `json:"chunk_size"`
+}
+
+// StreamImportRecord is one line of a JSON Lines import: a customer, or an
+// account of a customer identified by email
+type StreamImportRecord struct {
+	// Type is customer or account
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Email is the customer's email
+	Email string `json:"email,omitempty"`
+	// CustomerEmail is the email of the account's customer
+	CustomerEmail string `json:"customer_email,omitempty"`
+	// Status is the account's status (default active)
+	Status string `json:"status,omitempty"`
+}
+
+// StreamImportCounts counts the valid rows of one type: imported, or skipped
+// because the email exists already (customers) or the customer doesn't
+// (accounts)
+type StreamImportCounts struct {
+	Imported int64 `json:"imported"`
+	Skipped  int64 `json:"skipped"`
+}
+
+// StreamImportLineError is a line of a JSON Lines import that was rejected
+type StreamImportLineError struct {
+	Line  int64  `json:"line"`
+	Error string `json:"error"`
+}
+
+// StreamImportProgress is one line of the response to a JSON Lines import,
+// sent after every batch and once more at the end
+type StreamImportProgress struct {
+	// Event is progress, then completed or failed
+	Event string `json:"event"`
+	JobID string `json:"job_id"`
+	// Lines is the number of lines read so far
+	Lines     int64              `json:"lines"`
+	Customers StreamImportCounts `json:"customers"`
+	Accounts  StreamImportCounts `json:"accounts"`
+	// Invalid is the number of lines rejected; Errors lists the first 100
+	Invalid int64                   `json:"invalid"`
+	Errors  []StreamImportLineError `json:"errors,omitempty"`
+	Error   string                  `json:"error,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LoginRecord is a successful login, as listed by GET /me/security
+type LoginRecord struct {
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	// DeviceFingerprint identifies the device the login came from, derived
+	// from its headers
+	DeviceFingerprint string `json:"device_fingerprint"`
+	// Country is the ISO 3166-1 alpha-2 country reported by the proxy in
+	// front of the app, if it reports one
+	Country   string    `json:"country,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityOverview is the caller's recent login activity
+type SecurityOverview struct {
+	// RecentLogins are the latest successful logins, newest first
+	RecentLogins []LoginRecord `json:"recent_logins"`
+	// FailedLogins counts failed logins in the past 30 days
+	FailedLogins int `json:"failed_logins_30d"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Note represents a free-text note attached to an account
+type Note struct {
+	ID        int       `json:"id" db:"id"`
+	AccountID int       `json:"account_id" db:"account_id"`
+	Author    string    `json:"author" db:"author"`
+	Body      string    `json:"body" db:"body"`
+	Mentions  []string  `json:"mentions" db:"mentions"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateNoteRequest represents the request payload for creating a note
+type CreateNoteRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// NoteSearchResult represents a single full-text search hit
+type NoteSearchResult struct {
+	Note
+	Rank     float64 `json:"rank"`
+	Headline string  `json:"headline"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Notification represents an in-app notification for a user
+type Notification struct {
+	ID        int       `json:"id" db:"id"`
+	Username  string    `json:"username" db:"username"`
+	Kind      string    `json:"kind" db:"kind"`
+	Message   string    `json:"message" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import "time"
+
+// Organization represents a workspace created by a self-service signup
+type Organization struct {
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// CustomerID is the customer record the organization is billed as
+	CustomerID   *int                 `json:"customer_id" db:"customer_id"`
+	Subscription *Subscription        `json:"subscription,omitempty" db:"-"`
+	Members      []OrganizationMember `json:"members,omitempty" db:"-"`
+	CreatedAt    time.Time            `json:"created_at" db:"created_at"`
+}
+
+// OrganizationMember is a user belonging to an organization
+type OrganizationMember struct {
+	Username string `json:"username" db:"username"`
+	// Role is owner or member
+	Role      string    `json:"role" db:"organization_role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Subscription represents an organization's plan
+type Subscription struct {
+	ID   int    `json:"id" db:"id"`
+	Plan string `json:"plan" db:"plan"`
+	// Status is trialing for new signups
+	Status      string     `json:"status" db:"status"`
+	TrialEndsAt *time.Time `json:"trial_ends_at" db:"trial_ends_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Organization roles
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleMember = "member"
+)
+
+// users.role values that decide whose customers and accounts a user sees.
+// Tenants only see their organization's, and nothing outside one; every new
+// user is a tenant, however they sign up. Staff see every organization's;
+// the role is only granted by hand, in the database. Admins are staff too.
+const (
+	RoleTenant = "tenant"
+	RoleStaff  = "staff"
+	RoleAdmin  = "admin"
+)
+
+// IsStaffRole reports whether users with role see every organization's data.
+// Any other role, including unknown ones, is treated as a tenant.
+func IsStaffRole(role string) bool {
+	return role == RoleStaff || role == RoleAdmin
+}
+
+// ValidOrganizationRole reports whether role can be granted in an organization
+func ValidOrganizationRole(role string) bool {
+	return role == OrganizationRoleOwner || role == OrganizationRoleMember
+}
+
+// Invitation represents an invitation to join an organization
+type Invitation struct {
+	ID    int    `json:"id" db:"id"`
+	Email string `json:"email" db:"email"`
+	// Role is granted when the invitation is accepted
+	Role       string     `json:"role" db:"role"`
+	InvitedBy  string     `json:"invited_by" db:"invited_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at" db:"accepted_at"`
+	AcceptedBy *string    `json:"accepted_by" db:"accepted_by"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	// Token is only returned when the invitation is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// Pending reports whether the invitation can still be accepted at now
+func (i Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// SignupRequest represents the request payload for a self-service signup
+type SignupRequest struct {
+	OrganizationName string `json:"organization_name" binding:"required"`
+	// Email is the billing contact of the organization's customer record
+	Email    string `json:"email" binding:"required,email"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+	// Website is a honeypot: signup forms hide it, so only bots fill it in
+	Website string `json:"website"`
+}
+
+// CreateInvitationRequest represents the request payload for inviting a teammate
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	// Role defaults to member
+	Role string `json:"role"`
+}
+
+// AcceptInvitationRequest represents the request payload for joining an
+// organization. An existing user without an organization joins by giving
+// their own credentials; otherwise a user is created with them.
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+	// TwoFactorCode is required when an existing user with two-factor
+	// authentication joins
+	TwoFactorCode string `json:"two_factor_code"`
+}
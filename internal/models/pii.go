@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PIIAccess represents a response that showed PII fields unmasked
+type PIIAccess struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Resource  string    `json:"resource"`
+	RecordIDs []int64   `json:"record_ids"`
+	Fields    []string  `json:"fields"`
+	Route     string    `json:"route"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
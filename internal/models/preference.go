@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Preferences are a user's defaults for the list endpoints, each applied when
+// a request leaves out the matching query parameter
+type Preferences struct {
+	// PageSize is the default limit
+	PageSize int `json:"page_size,omitempty"`
+	// Sort is the default sort: -created_at (newest first) or created_at
+	Sort string `json:"sort,omitempty"`
+	// Fields are the default fields per list, e.g. {"customers": ["name", "email"]}
+	Fields map[string][]string `json:"fields,omitempty"`
+	// Timezone is the IANA time zone timestamps are returned in
+	Timezone  string     `json:"timezone,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdatePreferencesRequest represents the request payload for replacing the
+// caller's preferences. Omitted preferences are cleared.
+type UpdatePreferencesRequest struct {
+	PageSize int                 `json:"page_size"`
+	Sort     string              `json:"sort"`
+	Fields   map[string][]string `json:"fields"`
+	Timezone string              `json:"timezone"`
+}
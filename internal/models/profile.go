@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Profile is a user's own account, as returned by GET /me
+type Profile struct {
+	ID          int     `json:"id" db:"id"`
+	Username    string  `json:"username" db:"username"`
+	DisplayName *string `json:"display_name" db:"display_name"`
+	Email       *string `json:"email" db:"email"`
+	Role        string  `json:"role" db:"role"`
+	// OrganizationID and OrganizationRole are set for users who signed up or
+	// were invited to an organization
+	OrganizationID   *int       `json:"organization_id" db:"organization_id"`
+	OrganizationRole *string    `json:"organization_role" db:"organization_role"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at" db:"updated_at"`
+	// PasswordChangedAt is when POST /me/password last changed the password
+	PasswordChangedAt *time.Time `json:"password_changed_at" db:"password_changed_at"`
+	// TwoFactorEnabledAt is when POST /me/2fa/verify enabled two-factor
+	// authentication, or null if it is off
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at" db:"totp_enabled_at"`
+}
+
+// UpdateProfileRequest represents the request payload for replacing the
+// caller's profile. Omitted fields are cleared.
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name" binding:"max=255"`
+	Email       string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// ChangePasswordRequest represents the request payload for changing the
+// caller's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Registration represents a self-registration through POST /auth/register.
+// Status is created, pending (flagged and held for review), approved, or
+// rejected.
+type Registration struct {
+	ID        int     `json:"id" db:"id"`
+	Username  string  `json:"username" db:"username"`
+	Email     *string `json:"email" db:"email"`
+	IPAddress *string `json:"ip_address" db:"ip_address"`
+	UserAgent *string `json:"user_agent" db:"user_agent"`
+	Status    string  `json:"status" db:"status"`
+	// Reasons are why the registration was flagged, e.g. honeypot,
+	// disposable_email, or ip_velocity
+	Reasons    []string   `json:"reasons" db:"reasons"`
+	UserID     *int       `json:"user_id" db:"user_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReviewedBy *string    `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt *time.Time `json:"reviewed_at" db:"reviewed_at"`
+}
+
+// Registration statuses
+const (
+	RegistrationCreated  = "created"
+	RegistrationPending  = "pending"
+	RegistrationApproved = "approved"
+	RegistrationRejected = "rejected"
+)
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// SLARule is an account lifecycle SLA: accounts in FromStatus must move to
+// ToStatus within WithinDays, e.g. pending accounts activate within 7 days
+type SLARule struct {
+	ID         int       `json:"id" db:"id"`
+	Name       string    `json:"name" db:"name"`
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	WithinDays int       `json:"within_days" db:"within_days"`
+	CreatedBy  string    `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateSLARuleRequest represents the request payload for defining an SLA rule
+type CreateSLARuleRequest struct {
+	Name       string `json:"name" binding:"required,max=255"`
+	FromStatus string `json:"from_status" binding:"required,max=50"`
+	ToStatus   string `json:"to_status" binding:"required,max=50"`
+	WithinDays int    `json:"within_days" binding:"required,min=1,max=365"`
+}
+
+// SLABreach represents an account still in its rule's FromStatus past the
+// deadline. It is resolved once the account leaves the status.
+type SLABreach struct {
+	ID         int        `json:"id" db:"id"`
+	RuleID     int        `json:"rule_id" db:"rule_id"`
+	AccountID  int        `json:"account_id" db:"account_id"`
+	EnteredAt  time.Time  `json:"entered_at" db:"entered_at"`
+	DueAt      time.Time  `json:"due_at" db:"due_at"`
+	DetectedAt time.Time  `json:"detected_at" db:"detected_at"`
+	ResolvedAt *time.Time `json:"resolved_at" db:"resolved_at"`
+}
+
+// SLACompliance summarizes how accounts fare against one SLA rule
+type SLACompliance struct {
+	Rule SLARule `json:"rule"`
+	// InProgress is how many accounts are in the rule's from_status and
+	// still within the deadline
+	InProgress int `json:"in_progress"`
+	// Breached is how many accounts are in from_status past the deadline
+	Breached int `json:"breached"`
+	// Completed is how many accounts moved from from_status to to_status
+	// within the reporting window, and CompletedOnTime how many of them
+	// within the deadline
+	Completed       int `json:"completed"`
+	CompletedOnTime int `json:"completed_on_time"`
+	// Compliance is the percentage of completed and breached accounts that
+	// met the deadline, or null when there are none
+	Compliance *float64 `json:"compliance"`
+	// OpenBreaches are the oldest unresolved breaches recorded by the
+	// scheduler, at most 20
+	OpenBreaches []SLABreach `json:"open_breaches"`
+}
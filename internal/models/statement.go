@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Statement represents a customer's monthly statement, without its document
+type Statement struct {
+	ID         int `json:"id" db:"id"`
+	CustomerID int `json:"customer_id" db:"customer_id"`
+	// Period is the month covered, e.g. "2026-09"
+	Period         string `json:"period" db:"period"`
+	AccountChanges int    `json:"account_changes" db:"account_changes"`
+	APICalls       int64  `json:"api_calls" db:"api_calls"`
+	// InvoiceTotals sums the statement's invoices per currency, in cents
+	InvoiceTotals map[string]int64 `json:"invoice_totals" db:"invoice_totals"`
+	GeneratedAt   time.Time        `json:"generated_at" db:"generated_at"`
+}
+
+// StatementDocument represents the contents of a monthly statement. Times
+// are UTC; the period runs from PeriodStart up to, but not including,
+// PeriodEnd.
+type StatementDocument struct {
+	Customer       StatementCustomer        `json:"customer"`
+	Period         string                   `json:"period"`
+	PeriodStart    time.Time                `json:"period_start"`
+	PeriodEnd      time.Time                `json:"period_end"`
+	GeneratedAt    time.Time                `json:"generated_at"`
+	AccountChanges []StatementAccountChange `json:"account_changes"`
+	Usage          StatementUsage           `json:"usage"`
+	Invoices       []StatementInvoice       `json:"invoices"`
+}
+
+// StatementCustomer represents the customer a statement is for
+type StatementCustomer struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// StatementAccountChange represents a version of one of the customer's
+// accounts recorded during the period, with the account as it was after the
+// change (before it, for deletions)
+type StatementAccountChange struct {
+	AccountID int `json:"account_id"`
+	// Change is created, updated, or deleted
+	Change   string    `json:"change"`
+	At       time.Time `json:"at"`
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	MRRCents int64     `json:"mrr_cents"`
+}
+
+// StatementUsage represents the API calls made with the customer's tokens
+// during the period
+type StatementUsage struct {
+	Calls     int64                    `json:"calls"`
+	Errors    int64                    `json:"errors"`
+	Endpoints []StatementUsageEndpoint `json:"endpoints"`
+}
+
+// StatementUsageEndpoint represents the calls to one endpoint
+type StatementUsageEndpoint struct {
+	Method string `json:"method"`
+	Route  string `json:"route"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+}
+
+// StatementInvoice represents the month's charge for an account: its MRR at
+// the end of the period, issued on the account's invoice_day
+type StatementInvoice struct {
+	AccountID   int    `json:"account_id"`
+	AccountName string `json:"account_name"`
+	// IssuedOn is the date the invoice is issued, e.g. "2026-09-01"
+	IssuedOn    string `json:"issued_on"`
+	Currency    string `json:"currency"`
+	AmountCents int64  `json:"amount_cents"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// TwoFactorSetup is a new TOTP secret to enroll in an authenticator app,
+// returned by POST /me/2fa/setup
+type TwoFactorSetup struct {
+	// Secret is the base32 secret, for apps that can't scan OTPAuthURL
+	Secret string `json:"secret"`
+	// OTPAuthURL is the otpauth:// URL to show as a QR code
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorCodeRequest represents a TOTP code confirming enrollment
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorBackupCodes are the single-use codes for logging in without the
+// authenticator, shown only once
+type TwoFactorBackupCodes struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+// DisableTwoFactorRequest represents the request payload for turning off
+// two-factor authentication. Code is a TOTP or backup code.
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
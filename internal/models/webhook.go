@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// WebhookEndpoint represents a URL subscribed to lifecycle events
+type WebhookEndpoint struct {
+	ID        int       `json:"id" db:"id"`
+	URL       string    `json:"url" db:"url"`
+	Events    []string  `json:"events" db:"events"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Secret is only returned when the endpoint is created
+	Secret string `json:"secret,omitempty" db:"secret"`
+}
+
+// CreateWebhookEndpointRequest represents the request payload for subscribing an endpoint
+type CreateWebhookEndpointRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events"`
+}
+
+// WebhookDelivery represents one attempt-tracked delivery of an event to an endpoint
+type WebhookDelivery struct {
+	ID          int64      `json:"id" db:"id"`
+	EndpointID  int        `json:"endpoint_id" db:"endpoint_id"`
+	EventType   string     `json:"event_type" db:"event_type"`
+	Status      string     `json:"status" db:"status"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	LastError   *string    `json:"last_error" db:"last_error"`
+	NextAttempt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	DeliveredAt *time.Time `json:"delivered_at" db:"delivered_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
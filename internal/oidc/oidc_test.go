@@ -0,0 +1,293 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeProvider is an OpenID Connect provider signing with key
+type fakeProvider struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	jwksCalls int
+	// idToken is what the token endpoint returns
+	idToken string
+	// issuer overrides the issuer the discovery document reports
+	issuer string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key, kid: "key-1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := p.issuer
+		if issuer == "" {
+			issuer = p.server.URL
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.jwksCalls++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.idToken, "token_type": "Bearer"})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) config() Config {
+	return Config{Issuer: p.server.URL, ClientID: "client-1", ClientSecret: "secret", Scopes: []string{"openid"}}
+}
+
+// sign returns an ID token with claims on top of valid defaults
+func (p *fakeProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	all := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "client-1",
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": "nonce-1",
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(all, name)
+		} else {
+			all[name] = value
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, all)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestDiscoverAndAuthCodeURL(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider, err := Discover(context.Background(), fake.config())
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifier == challenge || len(challenge) != 43 {
+		t.Errorf("Expected a 43 character S256 challenge, got %q", challenge)
+	}
+	authURL, err := url.Parse(provider.AuthCodeURL("https://app.example.com/callback", "state-1", "nonce-1", challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"redirect_uri":          "https://app.example.com/callback",
+		"scope":                 "openid",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        challenge,
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("Expected %s %q, got %q", name, want, got)
+		}
+	}
+	if !strings.HasPrefix(authURL.String(), fake.server.URL+"/authorize?") {
+		t.Errorf("Expected the provider's authorization endpoint, got %s", authURL)
+	}
+}
+
+func TestDiscoverRejectsOtherIssuer(t *testing.T) {
+	fake := newFakeProvider(t)
+	fake.issuer = "https://evil.example.com"
+	if _, err := Discover(context.Background(), fake.config()); err == nil {
+		t.Error("Expected discovery to fail for another issuer")
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider, err := Discover(context.Background(), fake.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.idToken = fake.sign(t, jwt.MapClaims{"email": "alice@example.com", "email_verified": true})
+
+	raw, err := provider.Exchange(context.Background(), "good-code", "verifier", "https://app.example.com/callback")
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	claims, err := provider.Verify(context.Background(), raw, "nonce-1")
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if claims.String("sub") != "user-1" || claims.String("email") != "alice@example.com" || !claims.EmailVerified() {
+		t.Errorf("Unexpected claims %v", claims)
+	}
+
+	if _, err := provider.Exchange(context.Background(), "bad-code", "verifier", "https://app.example.com/callback"); err == nil {
+		t.Error("Expected a rejected code to fail")
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider, err := Discover(context.Background(), fake.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": fake.server.URL, "aud": "client-1", "sub": "user-1", "nonce": "nonce-1", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = fake.kid
+	forgedToken, err := forged.SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{
+		"wrong nonce":    fake.sign(t, jwt.MapClaims{"nonce": "nonce-2"}),
+		"no nonce":       fake.sign(t, jwt.MapClaims{"nonce": nil}),
+		"wrong audience": fake.sign(t, jwt.MapClaims{"aud": "client-2"}),
+		"wrong issuer":   fake.sign(t, jwt.MapClaims{"iss": "https://evil.example.com"}),
+		"expired":        fake.sign(t, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}),
+		"no expiry":      fake.sign(t, jwt.MapClaims{"exp": nil}),
+		"forged":         forgedToken,
+		"unsigned":       unsignedToken(t),
+	} {
+		if _, err := provider.Verify(context.Background(), token, "nonce-1"); err == nil {
+			t.Errorf("Expected %s token to be rejected", name)
+		}
+	}
+}
+
+func unsignedToken(t *testing.T) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "user-1", "nonce": "nonce-1"})
+	signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyRefetchesRotatedKeys(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider, err := Discover(context.Background(), fake.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Verify(context.Background(), fake.sign(t, nil), "nonce-1"); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	// The provider rotates its key; an unknown kid refetches the key set,
+	// but at most once per keysRefreshInterval
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.key, fake.kid = key, "key-2"
+	if _, err := provider.Verify(context.Background(), fake.sign(t, nil), "nonce-1"); err == nil {
+		t.Error("Expected the key set not to be refetched within the refresh interval")
+	}
+	provider.keysFetched = time.Now().Add(-keysRefreshInterval)
+	if _, err := provider.Verify(context.Background(), fake.sign(t, nil), "nonce-1"); err != nil {
+		t.Errorf("Expected the rotated key to be fetched, got %v", err)
+	}
+	if fake.jwksCalls != 2 {
+		t.Errorf("Expected 2 key set fetches, got %d", fake.jwksCalls)
+	}
+}
+
+func TestClaimsStrings(t *testing.T) {
+	claims := Claims{
+		"groups":         []interface{}{"staff", "admins", 3},
+		"role":           "admin",
+		"realm_access":   map[string]interface{}{"roles": []interface{}{"offline_access", "platform-admin"}},
+		"email_verified": "true",
+	}
+	tests := map[string][]string{
+		"groups":             {"staff", "admins"},
+		"role":               {"admin"},
+		"realm_access.roles": {"offline_access", "platform-admin"},
+		"realm_access.other": nil,
+		"missing":            nil,
+		"role.nested":        nil,
+	}
+	for name, want := range tests {
+		got := claims.Strings(name)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("Strings(%q) = %v, expected %v", name, got, want)
+		}
+	}
+	if !claims.EmailVerified() {
+		t.Error("Expected a string email_verified of true to count")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "")
+	t.Setenv("OIDC_CLIENT_ID", "client-1")
+	if _, err := ConfigFromEnv(); err != ErrNotConfigured {
+		t.Errorf("Expected ErrNotConfigured without an issuer, got %v", err)
+	}
+
+	t.Setenv("OIDC_ISSUER", "https://accounts.example.com/")
+	t.Setenv("OIDC_SCOPES", "")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Issuer != "https://accounts.example.com" || strings.Join(cfg.Scopes, " ") != "openid email profile" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
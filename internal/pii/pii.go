@@ -0,0 +1,107 @@
+// Package pii decides which personal fields a caller may see in full.
+// Callers whose role is not in PII_UNMASKED_ROLES (default "admin") get the
+// fields listed in PII_MASKED_FIELDS (default "email,phone") partially
+// redacted, e.g. "j***@example.com" or "*******4567".
+package pii
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Policy is the masking applied to one caller's responses
+type Policy struct {
+	fields   map[string]bool
+	unmasked bool
+}
+
+// PolicyFor returns the policy for a role; an unknown or empty role is masked
+func PolicyFor(role string) Policy {
+	policy := Policy{fields: listEnv("PII_MASKED_FIELDS", "email,phone")}
+	policy.unmasked = role != "" && listEnv("PII_UNMASKED_ROLES", "admin")[role]
+	return policy
+}
+
+// Masks reports whether field is redacted for this caller
+func (p Policy) Masks(field string) bool {
+	return p.fields[field] && !p.unmasked
+}
+
+// Reveals reports whether field is a PII field this caller sees in full. Such
+// access is audited.
+func (p Policy) Reveals(field string) bool {
+	return p.fields[field] && p.unmasked
+}
+
+// Apply returns value, redacted if field is masked for this caller
+func (p Policy) Apply(field, value string) string {
+	if !p.Masks(field) || value == "" {
+		return value
+	}
+	switch field {
+	case "email":
+		return MaskEmail(value)
+	case "phone":
+		return MaskPhone(value)
+	}
+	return MaskString(value)
+}
+
+// MaskEmail keeps the first character of the local part and the domain,
+// e.g. "jane.doe@example.com" becomes "j***@example.com"
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return MaskString(email)
+	}
+	local := []rune(email[:at])
+	return string(local[0]) + "***" + email[at:]
+}
+
+// MaskPhone keeps the last four digits and the formatting, e.g.
+// "+1 555-123-4567" becomes "+* ***-***-4567"
+func MaskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	var masked strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			seen++
+			if seen <= digits-4 {
+				r = '*'
+			}
+		}
+		masked.WriteRune(r)
+	}
+	return masked.String()
+}
+
+// MaskString keeps only the first character
+func MaskString(value string) string {
+	runes := []rune(value)
+	if len(runes) == 0 {
+		return value
+	}
+	return string(runes[0]) + "***"
+}
+
+func listEnv(key, defaultValue string) map[string]bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+	set := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			set[item] = true
+		}
+	}
+	return set
+}
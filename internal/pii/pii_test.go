@@ -0,0 +1,54 @@
+package pii
+
+import "testing"
+
+func TestMaskEmail(t *testing.T) {
+	cases := map[string]string{
+		"jane.doe@example.com": "j***@example.com",
+		"é@example.com":        "é***@example.com",
+		"not-an-email":         "n***",
+		"@example.com":         "@***",
+	}
+	for input, expected := range cases {
+		if got := MaskEmail(input); got != expected {
+			t.Errorf("MaskEmail(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	if got := MaskPhone("+1 555-123-4567"); got != "+* ***-***-4567" {
+		t.Errorf("Unexpected masked phone %q", got)
+	}
+	if got := MaskPhone("123"); got != "123" {
+		t.Errorf("Expected short numbers to be left as is, got %q", got)
+	}
+}
+
+func TestPolicyFor(t *testing.T) {
+	t.Setenv("PII_MASKED_FIELDS", "email")
+	t.Setenv("PII_UNMASKED_ROLES", "admin,support")
+
+	user := PolicyFor("user")
+	if got := user.Apply("email", "jane@example.com"); got != "j***@example.com" {
+		t.Errorf("Expected email to be masked for users, got %q", got)
+	}
+	if got := user.Apply("phone", "555-123-4567"); got != "555-123-4567" {
+		t.Errorf("Expected unlisted fields to be left as is, got %q", got)
+	}
+	if user.Reveals("email") {
+		t.Error("Expected masked access not to count as revealing")
+	}
+
+	support := PolicyFor("support")
+	if got := support.Apply("email", "jane@example.com"); got != "jane@example.com" {
+		t.Errorf("Expected email to be unmasked for support, got %q", got)
+	}
+	if !support.Reveals("email") {
+		t.Error("Expected unmasked access to be reported for auditing")
+	}
+
+	if !PolicyFor("").Masks("email") {
+		t.Error("Expected an unknown role to be masked")
+	}
+}
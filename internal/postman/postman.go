@@ -0,0 +1,292 @@
+// Package postman converts the generated Swagger (OpenAPI 2.0) spec into a
+// Postman v2.1 collection. Insomnia imports the same format.
+package postman
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+const schemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Collection is a Postman v2.1 collection
+type Collection struct {
+	Info     Info       `json:"info"`
+	Auth     *Auth      `json:"auth,omitempty"`
+	Variable []Variable `json:"variable"`
+	Item     []Item     `json:"item"`
+}
+
+// Info describes the collection
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// Auth configures request authentication
+type Auth struct {
+	Type   string     `json:"type"`
+	Bearer []Variable `json:"bearer,omitempty"`
+}
+
+// Variable is a key/value pair used for collection and path variables
+type Variable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// Item is either a folder (with nested items) or a request
+type Item struct {
+	Name    string   `json:"name"`
+	Item    []Item   `json:"item,omitempty"`
+	Request *Request `json:"request,omitempty"`
+	Event   []Event  `json:"event,omitempty"`
+}
+
+// Request is a single API call
+type Request struct {
+	Method      string     `json:"method"`
+	Description string     `json:"description,omitempty"`
+	Header      []Variable `json:"header"`
+	URL         URL        `json:"url"`
+	Body        *Body      `json:"body,omitempty"`
+	Auth        *Auth      `json:"auth,omitempty"`
+}
+
+// URL is a request URL broken into Postman's parts
+type URL struct {
+	Raw      string     `json:"raw"`
+	Host     []string   `json:"host"`
+	Path     []string   `json:"path"`
+	Query    []Variable `json:"query,omitempty"`
+	Variable []Variable `json:"variable,omitempty"`
+}
+
+// Body is a raw JSON request body
+type Body struct {
+	Mode    string      `json:"mode"`
+	Raw     string      `json:"raw"`
+	Options interface{} `json:"options,omitempty"`
+}
+
+// Event is a pre-request or test script
+type Event struct {
+	Listen string `json:"listen"`
+	Script Script `json:"script"`
+}
+
+// Script holds the lines of a Postman script
+type Script struct {
+	Type string   `json:"type"`
+	Exec []string `json:"exec"`
+}
+
+type swaggerSpec struct {
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"info"`
+	BasePath    string                          `json:"basePath"`
+	Paths       map[string]map[string]swaggerOp `json:"paths"`
+	Definitions map[string]swaggerSchema        `json:"definitions"`
+}
+
+type swaggerOp struct {
+	Summary     string                `json:"summary"`
+	Description string                `json:"description"`
+	Tags        []string              `json:"tags"`
+	Security    []map[string][]string `json:"security"`
+	Parameters  []swaggerParam        `json:"parameters"`
+}
+
+type swaggerParam struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required"`
+	Type        string         `json:"type"`
+	Schema      *swaggerSchema `json:"schema"`
+}
+
+type swaggerSchema struct {
+	Ref        string                   `json:"$ref"`
+	Type       string                   `json:"type"`
+	Properties map[string]swaggerSchema `json:"properties"`
+	Items      *swaggerSchema           `json:"items"`
+}
+
+// FromSwagger builds a collection from a Swagger 2.0 JSON document. baseURL
+// should include the API base path (e.g. https://app.herokuapp.com/api);
+// when empty, the spec's basePath on localhost:8080 is used.
+func FromSwagger(spec []byte, baseURL string) (*Collection, error) {
+	var doc swaggerSpec
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+
+	if baseURL == "" {
+		baseURL = "http://localhost:8080" + doc.BasePath
+	}
+
+	collection := &Collection{
+		Info: Info{
+			Name:        doc.Info.Title,
+			Description: doc.Info.Description,
+			Schema:      schemaURL,
+		},
+		Auth: &Auth{
+			Type:   "bearer",
+			Bearer: []Variable{{Key: "token", Value: "{{token}}", Type: "string"}},
+		},
+		Variable: []Variable{
+			{Key: "baseUrl", Value: strings.TrimRight(baseURL, "/")},
+			{Key: "token", Value: "", Description: "Set automatically by the Login request"},
+		},
+	}
+
+	// Group requests into one folder per tag, sorted for stable output
+	folders := make(map[string][]Item)
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		methods := make([]string, 0, len(doc.Paths[path]))
+		for method := range doc.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			op := doc.Paths[path][method]
+			tag := "default"
+			if len(op.Tags) > 0 {
+				tag = op.Tags[0]
+			}
+			folders[tag] = append(folders[tag], buildItem(doc, path, method, op))
+		}
+	}
+
+	tags := make([]string, 0, len(folders))
+	for tag := range folders {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		collection.Item = append(collection.Item, Item{Name: tag, Item: folders[tag]})
+	}
+
+	return collection, nil
+}
+
+func buildItem(doc swaggerSpec, path, method string, op swaggerOp) Item {
+	name := op.Summary
+	if name == "" {
+		name = strings.ToUpper(method) + " " + path
+	}
+
+	request := &Request{
+		Method:      strings.ToUpper(method),
+		Description: op.Description,
+		Header:      []Variable{{Key: "Content-Type", Value: "application/json"}},
+		URL:         URL{Host: []string{"{{baseUrl}}"}},
+	}
+
+	// Swagger {param} segments become Postman :param variables
+	rawPath := path
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			variable := strings.Trim(segment, "{}")
+			rawPath = strings.Replace(rawPath, segment, ":"+variable, 1)
+			segment = ":" + variable
+		}
+		request.URL.Path = append(request.URL.Path, segment)
+	}
+
+	var query []string
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			request.URL.Variable = append(request.URL.Variable, Variable{Key: param.Name, Value: "", Description: param.Description})
+		case "query":
+			request.URL.Query = append(request.URL.Query, Variable{Key: param.Name, Value: "", Description: param.Description, Disabled: !param.Required})
+			if param.Required {
+				query = append(query, param.Name+"=")
+			}
+		case "body":
+			if param.Schema != nil {
+				example, _ := json.MarshalIndent(exampleFor(doc, *param.Schema, 0), "", "  ")
+				request.Body = &Body{
+					Mode:    "raw",
+					Raw:     string(example),
+					Options: map[string]interface{}{"raw": map[string]string{"language": "json"}},
+				}
+			}
+		}
+	}
+
+	request.URL.Raw = "{{baseUrl}}" + rawPath
+	if len(query) > 0 {
+		request.URL.Raw += "?" + strings.Join(query, "&")
+	}
+
+	if len(op.Security) == 0 {
+		request.Auth = &Auth{Type: "noauth"}
+	}
+
+	item := Item{Name: name, Request: request}
+
+	// Store the token from a successful login so every other request is authorized
+	if strings.HasSuffix(path, "/auth/login") {
+		item.Event = []Event{{
+			Listen: "test",
+			Script: Script{
+				Type: "text/javascript",
+				Exec: []string{
+					"if (pm.response.code === 200) {",
+					"    pm.collectionVariables.set('token', pm.response.json().token);",
+					"}",
+				},
+			},
+		}}
+	}
+
+	return item
+}
+
+// exampleFor builds a placeholder value for a schema, following $refs
+func exampleFor(doc swaggerSpec, schema swaggerSchema, depth int) interface{} {
+	if depth > 5 {
+		return nil
+	}
+	if schema.Ref != "" {
+		return exampleFor(doc, doc.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")], depth+1)
+	}
+
+	switch schema.Type {
+	case "string":
+		return ""
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{exampleFor(doc, *schema.Items, depth+1)}
+	}
+
+	example := make(map[string]interface{})
+	for name, property := range schema.Properties {
+		example[name] = exampleFor(doc, property, depth+1)
+	}
+	return example
+}
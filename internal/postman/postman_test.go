@@ -0,0 +1,62 @@
+package postman
+
+import "testing"
+
+const testSpec = `{
+	"info": {"title": "Test API", "description": "Test"},
+	"basePath": "/api",
+	"paths": {
+		"/auth/login": {
+			"post": {
+				"summary": "Login user",
+				"tags": ["auth"],
+				"parameters": [{"name": "credentials", "in": "body", "required": true, "schema": {"$ref": "#/definitions/LoginRequest"}}]
+			}
+		},
+		"/accounts/{id}": {
+			"get": {
+				"summary": "Get account by ID",
+				"tags": ["accounts"],
+				"security": [{"BearerAuth": []}],
+				"parameters": [{"name": "id", "in": "path", "required": true, "type": "integer"}]
+			}
+		}
+	},
+	"definitions": {
+		"LoginRequest": {"type": "object", "properties": {"username": {"type": "string"}, "password": {"type": "string"}}}
+	}
+}`
+
+func TestFromSwagger(t *testing.T) {
+	collection, err := FromSwagger([]byte(testSpec), "")
+	if err != nil {
+		t.Fatalf("Failed to convert spec: %v", err)
+	}
+
+	if collection.Variable[0].Value != "http://localhost:8080/api" {
+		t.Errorf("Expected default base URL, got %s", collection.Variable[0].Value)
+	}
+
+	if len(collection.Item) != 2 || collection.Item[0].Name != "accounts" || collection.Item[1].Name != "auth" {
+		t.Fatalf("Expected accounts and auth folders, got %+v", collection.Item)
+	}
+
+	get := collection.Item[0].Item[0].Request
+	if get.URL.Raw != "{{baseUrl}}/accounts/:id" {
+		t.Errorf("Expected path variable in URL, got %s", get.URL.Raw)
+	}
+	if get.Auth != nil {
+		t.Error("Expected secured request to inherit collection auth")
+	}
+
+	login := collection.Item[1].Item[0]
+	if login.Request.Auth == nil || login.Request.Auth.Type != "noauth" {
+		t.Error("Expected login request to disable auth")
+	}
+	if login.Request.Body == nil || login.Request.Body.Raw == "" {
+		t.Error("Expected login request to have an example body")
+	}
+	if len(login.Event) == 0 {
+		t.Error("Expected login request to capture the token")
+	}
+}
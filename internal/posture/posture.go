@@ -0,0 +1,249 @@
+// Package posture assesses how safely the app is deployed: whether the admin
+// user still has the well-known seed password, whether JWTs are signed with a
+// configured key, how strong JWT_SECRET is, and whether rate limits and login
+// lockouts protect the API. Demo apps often run with the insecure defaults;
+// the assessment makes that visible at startup and at
+// GET /api/admin/security-posture.
+package posture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"saas-go-app/internal/auth"
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/secrets"
+)
+
+// Severities of a check, from the most serious. A failing check puts the
+// deployment at its severity's risk.
+const (
+	Critical = "critical"
+	High     = "high"
+	Medium   = "medium"
+	Low      = "low"
+)
+
+// Statuses of a check
+const (
+	Pass = "pass"
+	Fail = "fail"
+	// Unknown means the check couldn't run, e.g. the database was down
+	Unknown = "unknown"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted, in bytes: the keys
+// derived from it are 256 bits
+const minJWTSecretLength = 32
+
+// weakJWTSecrets are placeholder secrets from the docs and common defaults
+var weakJWTSecrets = []string{
+	"your-secret-key",
+	"your-secret-key-change-in-production",
+	"your-production-secret-key",
+	"secret",
+	"changeme",
+	"change-me",
+	"jwt-secret",
+	"development",
+}
+
+// Finding is the outcome of one check
+type Finding struct {
+	// Check identifies the check, e.g. default_admin_credentials
+	Check string `json:"check"`
+	// Status is pass, fail, or unknown
+	Status string `json:"status"`
+	// Severity is critical, high, medium, or low: how exposed the deployment
+	// is while the check fails
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%-8s %s: %s", strings.ToUpper(f.Severity), f.Check, f.Message)
+}
+
+// Report is a full assessment
+type Report struct {
+	// Risk is the severity of the most serious failing check, or none
+	Risk       string    `json:"risk"`
+	Failed     int       `json:"failed"`
+	AssessedAt time.Time `json:"assessed_at"`
+	Findings   []Finding `json:"findings"`
+}
+
+// defaultAdminCredentialsInUse reports whether the admin user still has the
+// seed password; tests replace it
+var defaultAdminCredentialsInUse = db.DefaultAdminCredentialsInUse
+
+// signingKeyGenerated reports whether JWTs are signed with a key generated at
+// startup; tests replace it
+var signingKeyGenerated = auth.SigningKeyGenerated
+
+// jwtSecret reads JWT_SECRET; tests replace it
+var jwtSecret = func() (string, error) {
+	return secrets.Get("JWT_SECRET")
+}
+
+// checks run in order, most severe first
+var checks = []func(context.Context) Finding{
+	checkDefaultAdmin,
+	checkSigningKey,
+	checkJWTSecret,
+	checkRateLimit,
+	checkLoginLockout,
+}
+
+// Assess runs every check
+func Assess(ctx context.Context) Report {
+	report := Report{Risk: "none", AssessedAt: time.Now().UTC(), Findings: make([]Finding, 0, len(checks))}
+	for _, check := range checks {
+		finding := check(ctx)
+		if finding.Status == Fail {
+			report.Failed++
+			if report.Risk == "none" || rank(finding.Severity) < rank(report.Risk) {
+				report.Risk = finding.Severity
+			}
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+	return report
+}
+
+// rank orders severities, most serious first
+func rank(severity string) int {
+	switch severity {
+	case Critical:
+		return 0
+	case High:
+		return 1
+	case Medium:
+		return 2
+	}
+	return 3
+}
+
+// LogReport assesses the deployment and logs a warning for each failing
+// check, so insecure defaults show in the startup logs
+func LogReport(ctx context.Context) {
+	report := Assess(ctx)
+	for _, finding := range report.Findings {
+		switch finding.Status {
+		case Fail:
+			log.Printf("Warning: Security posture: %s (%s)", finding, finding.Remediation)
+		case Unknown:
+			log.Printf("Warning: Security posture: could not check %s: %s", finding.Check, finding.Message)
+		}
+	}
+	if report.Failed == 0 {
+		log.Printf("Security posture: all %d checks passed", len(report.Findings))
+		return
+	}
+	log.Printf("Security posture: %d of %d checks failed, risk %s; see GET /api/admin/security-posture", report.Failed, len(report.Findings), report.Risk)
+}
+
+func checkDefaultAdmin(ctx context.Context) Finding {
+	finding := Finding{Check: "default_admin_credentials", Severity: Critical}
+	inUse, err := defaultAdminCredentialsInUse(ctx)
+	switch {
+	case err != nil:
+		finding.Status = Unknown
+		finding.Message = fmt.Sprintf("Failed to read the admin user: %v", err)
+	case inUse:
+		finding.Status = Fail
+		finding.Message = fmt.Sprintf("The %s user still has the deprecated seed password %s", db.BootstrapAdminUsername, db.DefaultAdminPassword)
+		finding.Remediation = "Sign in as admin and change the password with POST /api/me/password"
+	default:
+		finding.Status = Pass
+		finding.Message = "The admin user does not use the seed password"
+	}
+	return finding
+}
+
+func checkSigningKey(ctx context.Context) Finding {
+	finding := Finding{Check: "jwt_signing_key", Severity: High}
+	if !signingKeyGenerated() {
+		finding.Status = Pass
+		finding.Message = "JWTs are signed with the RS256 key from JWT_PRIVATE_KEY"
+		return finding
+	}
+	finding.Status = Fail
+	finding.Message = "JWT_PRIVATE_KEY is not set; a key is generated at startup, so tokens don't survive restarts or work across dynos"
+	finding.Remediation = "Set JWT_PRIVATE_KEY to a PEM RSA key of at least 2048 bits, e.g. from `openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:2048`"
+	return finding
+}
+
+func checkJWTSecret(ctx context.Context) Finding {
+	finding := Finding{Check: "jwt_secret", Severity: Critical}
+	secret, err := jwtSecret()
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		finding.Status = Unknown
+		finding.Message = fmt.Sprintf("Failed to read JWT_SECRET: %v", err)
+		return finding
+	}
+
+	finding.Remediation = "Set JWT_SECRET to a random value of at least 32 bytes, e.g. `openssl rand -base64 48`"
+	switch {
+	case secret == "":
+		// Each dyno generates its own cursor key, and two-factor
+		// authentication is unavailable
+		finding.Status = Fail
+		finding.Severity = High
+		finding.Message = "JWT_SECRET is not set; the keys derived from it are generated at startup, so pagination cursors don't survive restarts or work across dynos"
+	case isWeakJWTSecret(secret):
+		finding.Status = Fail
+		finding.Message = "JWT_SECRET is a well-known placeholder, so anyone can forge pagination cursors and decrypt TOTP secrets derived from it"
+	case len(secret) < minJWTSecretLength:
+		finding.Status = Fail
+		finding.Severity = High
+		finding.Message = fmt.Sprintf("JWT_SECRET is %d bytes; at least %d are needed to resist brute force", len(secret), minJWTSecretLength)
+	default:
+		finding.Status = Pass
+		finding.Message = "JWT_SECRET is set and long enough"
+		finding.Remediation = ""
+	}
+	return finding
+}
+
+func isWeakJWTSecret(secret string) bool {
+	secret = strings.ToLower(strings.TrimSpace(secret))
+	for _, weak := range weakJWTSecrets {
+		if secret == weak {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRateLimit(ctx context.Context) Finding {
+	finding := Finding{Check: "rate_limit", Severity: Medium}
+	if limit := config.Current().RateLimitPerMinute; limit > 0 {
+		finding.Status = Pass
+		finding.Message = fmt.Sprintf("Each client IP is limited to %d requests per minute", limit)
+		return finding
+	}
+	finding.Status = Fail
+	finding.Message = "Rate limiting is disabled, so clients can make unlimited requests"
+	finding.Remediation = "Set RATE_LIMIT_PER_MINUTE, or rate_limit_per_minute with PUT /api/admin/config"
+	return finding
+}
+
+func checkLoginLockout(ctx context.Context) Finding {
+	finding := Finding{Check: "login_lockout", Severity: Medium}
+	if threshold := auth.LoginLockoutThreshold(); threshold > 0 {
+		finding.Status = Pass
+		finding.Message = fmt.Sprintf("Accounts lock after %d failed logins", threshold)
+		return finding
+	}
+	finding.Status = Fail
+	finding.Message = "Login lockout is disabled, so passwords can be guessed without limit"
+	finding.Remediation = "Set LOGIN_LOCKOUT_THRESHOLD above 0 (default 5)"
+	return finding
+}
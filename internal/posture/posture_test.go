@@ -0,0 +1,132 @@
+package posture
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"saas-go-app/internal/config"
+)
+
+// findingsByCheck assesses and indexes the findings by check
+func findingsByCheck(t *testing.T) (Report, map[string]Finding) {
+	t.Helper()
+	report := Assess(context.Background())
+	findings := map[string]Finding{}
+	for _, finding := range report.Findings {
+		findings[finding.Check] = finding
+	}
+	if len(findings) != len(checks) {
+		t.Fatalf("Expected %d findings, got %d", len(checks), len(findings))
+	}
+	return report, findings
+}
+
+// useJWTSecret makes the checks see secret as JWT_SECRET
+func useJWTSecret(t *testing.T, secret string) {
+	t.Helper()
+	original := jwtSecret
+	t.Cleanup(func() { jwtSecret = original })
+	jwtSecret = func() (string, error) { return secret, nil }
+}
+
+// useSigningKey makes the checks see a generated or configured signing key
+func useSigningKey(t *testing.T, generated bool) {
+	t.Helper()
+	original := signingKeyGenerated
+	t.Cleanup(func() { signingKeyGenerated = original })
+	signingKeyGenerated = func() bool { return generated }
+}
+
+func TestAssessInsecureDefaults(t *testing.T) {
+	original := defaultAdminCredentialsInUse
+	t.Cleanup(func() { defaultAdminCredentialsInUse = original })
+	defaultAdminCredentialsInUse = func(ctx context.Context) (bool, error) { return true, nil }
+	useSigningKey(t, true)
+
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+	settings := config.Current()
+	settings.RateLimitPerMinute = 0
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatal(err)
+	}
+	useJWTSecret(t, "your-secret-key-change-in-production")
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "0")
+
+	report, findings := findingsByCheck(t)
+	for check, want := range map[string]string{
+		"default_admin_credentials": Critical,
+		"jwt_signing_key":           High,
+		"jwt_secret":                Critical,
+		"rate_limit":                Medium,
+		"login_lockout":             Medium,
+	} {
+		finding := findings[check]
+		if finding.Status != Fail || finding.Severity != want || finding.Remediation == "" {
+			t.Errorf("Expected %s to fail with severity %s and a remediation, got %+v", check, want, finding)
+		}
+	}
+	if report.Risk != Critical || report.Failed != 5 {
+		t.Errorf("Expected risk critical with 5 failures, got %s with %d", report.Risk, report.Failed)
+	}
+}
+
+func TestAssessHardenedDeployment(t *testing.T) {
+	original := defaultAdminCredentialsInUse
+	t.Cleanup(func() { defaultAdminCredentialsInUse = original })
+	defaultAdminCredentialsInUse = func(ctx context.Context) (bool, error) { return false, nil }
+	useSigningKey(t, false)
+
+	previous := config.Current()
+	t.Cleanup(func() { config.Apply(previous, "test", "test") })
+	settings := config.Current()
+	settings.RateLimitPerMinute = 100
+	if _, err := config.Apply(settings, "test", "test"); err != nil {
+		t.Fatal(err)
+	}
+	useJWTSecret(t, "k3P9vXq2LmZr7TbW1sYd8NfHc4GjUe6A0oRi5")
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "")
+
+	report, findings := findingsByCheck(t)
+	for check, finding := range findings {
+		if finding.Status != Pass {
+			t.Errorf("Expected %s to pass, got %+v", check, finding)
+		}
+	}
+	if report.Risk != "none" || report.Failed != 0 {
+		t.Errorf("Expected no risk, got %s with %d failures", report.Risk, report.Failed)
+	}
+}
+
+func TestCheckJWTSecret(t *testing.T) {
+	tests := []struct {
+		secret   string
+		status   string
+		severity string
+	}{
+		{"", Fail, High},
+		{"changeme", Fail, Critical},
+		{" Your-Secret-Key ", Fail, Critical},
+		{"short-but-random-8f2a", Fail, High},
+		{"k3P9vXq2LmZr7TbW1sYd8NfHc4GjUe6A0oRi5", Pass, Critical},
+	}
+	for _, tt := range tests {
+		useJWTSecret(t, tt.secret)
+		finding := checkJWTSecret(context.Background())
+		if finding.Status != tt.status || finding.Severity != tt.severity {
+			t.Errorf("Expected %s/%s for %q, got %s/%s", tt.status, tt.severity, tt.secret, finding.Status, finding.Severity)
+		}
+	}
+}
+
+func TestCheckDefaultAdminUnknown(t *testing.T) {
+	original := defaultAdminCredentialsInUse
+	t.Cleanup(func() { defaultAdminCredentialsInUse = original })
+	defaultAdminCredentialsInUse = func(ctx context.Context) (bool, error) { return false, errors.New("connection refused") }
+
+	report := Assess(context.Background())
+	if finding := report.Findings[0]; finding.Check != "default_admin_credentials" || finding.Status != Unknown {
+		t.Errorf("Expected an unknown default_admin_credentials finding, got %+v", finding)
+	}
+}
@@ -0,0 +1,326 @@
+// Package progress tracks long-running jobs (seeding, imports) in memory so
+// their progress can be polled or streamed to the admin UI by job ID.
+package progress
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	// ErrNotCancellable is returned by Cancel for jobs that did not set up a context
+	ErrNotCancellable = errors.New("job cannot be cancelled")
+	// ErrFinished is returned by Cancel for jobs that have already finished
+	ErrFinished = errors.New("job has already finished")
+)
+
+// Finished jobs are kept for this long so late subscribers still see the outcome
+const retention = time.Hour
+
+// Snapshot is the state of a job at one point in time
+type Snapshot struct {
+	JobID      string   `json:"job_id"`
+	Kind       string   `json:"kind"`
+	Status     string   `json:"status"`
+	Phase      string   `json:"phase"`
+	Done       int64    `json:"done"`
+	Total      int64    `json:"total"`
+	RatePerSec float64  `json:"rate_per_sec"`
+	ETASeconds *float64 `json:"eta_seconds"`
+	Error      string   `json:"error,omitempty"`
+	// Throttle is set once the job has checked the database's health
+	Throttle  *Throttle `json:"throttle,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Throttle is how much a bulk job is slowing down to spare the database
+type Throttle struct {
+	// DelayMs is the pause before each batch, 0 at full speed
+	DelayMs int64 `json:"delay_ms"`
+	// Reason is what the job is backing off from: latency or pool
+	Reason string `json:"reason,omitempty"`
+	// LatencyMs and PoolUtilization are the latest health check of the primary
+	LatencyMs       float64 `json:"latency_ms"`
+	PoolUtilization float64 `json:"pool_utilization"`
+	// PausedMs is the time spent paused so far
+	PausedMs int64 `json:"paused_ms"`
+}
+
+// Finished reports whether the job has completed, failed, or been cancelled
+func (s Snapshot) Finished() bool {
+	return s.Status != StatusRunning
+}
+
+// Job reports progress for one running job
+type Job struct {
+	mu           sync.Mutex
+	snapshot     Snapshot
+	phaseStarted time.Time
+	subscribers  map[chan Snapshot]struct{}
+	cancel       context.CancelFunc
+	cancelled    bool
+}
+
+// Update records progress within a phase (e.g. "customers", "accounts").
+// Rate and ETA are computed from the start of the current phase.
+func (j *Job) Update(phase string, done, total int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	if phase != j.snapshot.Phase {
+		j.snapshot.Phase = phase
+		j.phaseStarted = now
+	}
+	j.snapshot.Done = done
+	j.snapshot.Total = total
+	j.snapshot.UpdatedAt = now
+	j.snapshot.RatePerSec, j.snapshot.ETASeconds = estimate(done, total, now.Sub(j.phaseStarted))
+	j.publish()
+}
+
+// SetThrottle records how much the job is slowing down
+func (j *Job) SetThrottle(throttle Throttle) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.snapshot.Throttle = &throttle
+	j.snapshot.UpdatedAt = time.Now()
+	j.publish()
+}
+
+// Finish marks the job completed, or failed if err is not nil. A job that
+// was cancelled and stopped with the context's error is marked cancelled.
+func (j *Job) Finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.snapshot.Status = StatusCompleted
+	if err != nil {
+		j.snapshot.Status = StatusFailed
+		if j.cancelled && errors.Is(err, context.Canceled) {
+			j.snapshot.Status = StatusCancelled
+		}
+		j.snapshot.Error = err.Error()
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.snapshot.ETASeconds = nil
+	j.snapshot.UpdatedAt = time.Now()
+	j.publish()
+
+	for ch := range j.subscribers {
+		close(ch)
+	}
+	j.subscribers = nil
+}
+
+// Context returns a context derived from parent that is cancelled by Cancel
+// and carries the job (see FromContext). Jobs that don't call it can't be
+// cancelled.
+func (j *Job) Context(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(WithJob(parent, j))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.cancel = cancel
+	return ctx
+}
+
+// Cancel asks the job to stop by cancelling its context. The job stays
+// running until the work returns and calls Finish.
+func (j *Job) Cancel() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.snapshot.Finished() {
+		return ErrFinished
+	}
+	if j.cancel == nil {
+		return ErrNotCancellable
+	}
+	j.cancelled = true
+	j.cancel()
+	return nil
+}
+
+// Snapshot returns the job's current state
+func (j *Job) Snapshot() Snapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.snapshot
+}
+
+// Subscribe returns a channel that receives the current state immediately and
+// then every update. Slow subscribers only get the latest state. The channel is
+// closed when the job finishes; call cancel to unsubscribe earlier.
+func (j *Job) Subscribe() (<-chan Snapshot, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ch := make(chan Snapshot, 1)
+	ch <- j.snapshot
+	if j.snapshot.Finished() {
+		close(ch)
+		return ch, func() {}
+	}
+
+	j.subscribers[ch] = struct{}{}
+	return ch, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Wait blocks until the job finishes or ctx is done, and returns the job's
+// state at that point
+func (j *Job) Wait(ctx context.Context) Snapshot {
+	updates, cancel := j.Subscribe()
+	defer cancel()
+
+	var snapshot Snapshot
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return j.Snapshot()
+			}
+			snapshot = update
+		case <-ctx.Done():
+			return j.Snapshot()
+		}
+		if snapshot.Finished() {
+			return snapshot
+		}
+	}
+}
+
+// publish sends the current state to every subscriber, replacing any state
+// they have not read yet. Callers must hold j.mu.
+func (j *Job) publish() {
+	for ch := range j.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- j.snapshot
+	}
+}
+
+// estimate returns the processing rate and, when a total is known and
+// progress has been made, the seconds remaining at that rate
+func estimate(done, total int64, elapsed time.Duration) (float64, *float64) {
+	if done <= 0 || elapsed <= 0 {
+		return 0, nil
+	}
+	rate := float64(done) / elapsed.Seconds()
+	if total <= 0 || done > total {
+		return rate, nil
+	}
+	eta := float64(total-done) / rate
+	return rate, &eta
+}
+
+type jobKey struct{}
+
+// WithJob returns a context carrying job, so code running on its behalf can
+// report to it
+func WithJob(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+// FromContext returns the job ctx carries, or nil
+func FromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobKey{}).(*Job)
+	return job
+}
+
+var (
+	mu   sync.Mutex
+	jobs = make(map[string]*Job)
+)
+
+// Start registers a new running job of the given kind
+func Start(kind string) *Job {
+	now := time.Now()
+	job := &Job{
+		snapshot: Snapshot{
+			JobID:     newID(kind),
+			Kind:      kind,
+			Status:    StatusRunning,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		phaseStarted: now,
+		subscribers:  make(map[chan Snapshot]struct{}),
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	prune(now)
+	jobs[job.snapshot.JobID] = job
+	return job
+}
+
+func newID(kind string) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%x", kind, time.Now().UnixNano())
+	}
+	return kind + "-" + hex.EncodeToString(b)
+}
+
+// Get returns a job by ID
+func Get(id string) (*Job, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	job, ok := jobs[id]
+	return job, ok
+}
+
+// List returns the state of every tracked job, most recent first
+func List() []Snapshot {
+	mu.Lock()
+	defer mu.Unlock()
+	prune(time.Now())
+
+	snapshots := make([]Snapshot, 0, len(jobs))
+	for _, job := range jobs {
+		snapshots = append(snapshots, job.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].StartedAt.After(snapshots[j].StartedAt) })
+	return snapshots
+}
+
+// prune forgets jobs that finished more than retention ago. Callers must hold mu.
+func prune(now time.Time) {
+	for id, job := range jobs {
+		snapshot := job.Snapshot()
+		if snapshot.Finished() && now.Sub(snapshot.UpdatedAt) > retention {
+			delete(jobs, id)
+		}
+	}
+}
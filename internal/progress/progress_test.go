@@ -0,0 +1,126 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEstimate(t *testing.T) {
+	rate, eta := estimate(250, 1000, 5*time.Second)
+	if rate != 50 {
+		t.Errorf("Expected 50 rows/sec, got %v", rate)
+	}
+	if eta == nil || *eta != 15 {
+		t.Errorf("Expected ETA of 15s, got %v", eta)
+	}
+
+	if _, eta := estimate(0, 1000, time.Second); eta != nil {
+		t.Error("Expected no ETA before any progress")
+	}
+	if _, eta := estimate(10, 0, time.Second); eta != nil {
+		t.Error("Expected no ETA without a total")
+	}
+}
+
+func TestSubscribeReceivesUpdatesUntilFinished(t *testing.T) {
+	job := Start("test")
+	updates, cancel := job.Subscribe()
+	defer cancel()
+
+	if first := <-updates; first.Status != StatusRunning {
+		t.Fatalf("Expected initial running state, got %s", first.Status)
+	}
+
+	job.Update("customers", 10, 100)
+	if update := <-updates; update.Phase != "customers" || update.Done != 10 {
+		t.Errorf("Unexpected update %+v", update)
+	}
+
+	job.Finish(errors.New("boom"))
+	final := <-updates
+	if final.Status != StatusFailed || final.Error != "boom" {
+		t.Errorf("Expected failed state, got %+v", final)
+	}
+	if _, open := <-updates; open {
+		t.Error("Expected channel to close when the job finishes")
+	}
+
+	if _, ok := Get(final.JobID); !ok {
+		t.Error("Expected finished job to still be retrievable")
+	}
+}
+
+func TestSlowSubscriberGetsLatestState(t *testing.T) {
+	job := Start("test")
+	updates, cancel := job.Subscribe()
+	defer cancel()
+
+	job.Update("accounts", 1, 3)
+	job.Update("accounts", 2, 3)
+	job.Update("accounts", 3, 3)
+
+	if latest := <-updates; latest.Done != 3 {
+		t.Errorf("Expected latest state with 3 done, got %d", latest.Done)
+	}
+}
+
+func TestWait(t *testing.T) {
+	job := Start("test")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	job.Update("accounts", 1, 3)
+	if snapshot := job.Wait(ctx); snapshot.Finished() || snapshot.Done != 1 {
+		t.Errorf("Expected the running state once the wait times out, got %+v", snapshot)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		job.Update("accounts", 3, 3)
+		job.Finish(nil)
+	}()
+	if snapshot := job.Wait(context.Background()); snapshot.Status != StatusCompleted || snapshot.Done != 3 {
+		t.Errorf("Expected the completed state, got %+v", snapshot)
+	}
+	if snapshot := job.Wait(context.Background()); snapshot.Status != StatusCompleted {
+		t.Errorf("Expected a finished job to return at once, got %+v", snapshot)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	job := Start("test")
+	if err := job.Cancel(); !errors.Is(err, ErrNotCancellable) {
+		t.Errorf("Expected ErrNotCancellable without a context, got %v", err)
+	}
+
+	ctx := job.Context(context.Background())
+	if FromContext(ctx) != job {
+		t.Error("Expected the context to carry the job")
+	}
+	if err := job.Cancel(); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	<-ctx.Done()
+	if status := job.Snapshot().Status; status != StatusRunning {
+		t.Errorf("Expected the job to run until it finishes, got %s", status)
+	}
+
+	job.Finish(fmt.Errorf("seed stopped: %w", ctx.Err()))
+	if snapshot := job.Snapshot(); snapshot.Status != StatusCancelled || !snapshot.Finished() {
+		t.Errorf("Expected cancelled state, got %+v", snapshot)
+	}
+	if err := job.Cancel(); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished after the job finished, got %v", err)
+	}
+}
+
+func TestContextErrorWithoutCancelFails(t *testing.T) {
+	job := Start("test")
+	job.Context(context.Background())
+	job.Finish(context.Canceled)
+	if status := job.Snapshot().Status; status != StatusFailed {
+		t.Errorf("Expected a job that wasn't cancelled to fail, got %s", status)
+	}
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/sqlbuilder"
+)
+
+// AccountRepository reads and writes accounts
+type AccountRepository interface {
+	// List returns accounts newest first, or oldest first with opts.Ascending
+	List(ctx context.Context, opts ListOptions) ([]models.Account, error)
+	// Get returns a live account, as it was at asOf when it is not nil
+	Get(ctx context.Context, id int, asOf *time.Time) (models.Account, error)
+	// GetByReference returns a live account by its reference, as it was at
+	// asOf when it is not nil
+	GetByReference(ctx context.Context, reference string, asOf *time.Time) (models.Account, error)
+	// Create inserts an account with the next reference in its customer's
+	// sequence, returning ErrCustomerNotFound for unknown or deleted customers
+	Create(ctx context.Context, req models.CreateAccountRequest) (models.Account, error)
+	// Update replaces an account's name and status. With req.Version set, it
+	// returns the current account and ErrVersionConflict if that isn't the
+	// account's version.
+	Update(ctx context.Context, id int, req models.UpdateAccountRequest) (models.Account, error)
+	// Delete soft-deletes an account
+	Delete(ctx context.Context, id int) error
+	// Restore brings back a soft-deleted account, returning ErrNotFound if
+	// there is no such deleted account and ErrCustomerNotFound if its
+	// customer is deleted
+	Restore(ctx context.Context, id int) (models.Account, error)
+	// Purge removes an account, deleted or not
+	Purge(ctx context.Context, id int) error
+	// Facets counts the accounts matching opts.Filter (at opts.AsOf) by each
+	// of the named AccountFacets
+	Facets(ctx context.Context, opts ListOptions, names []string) (models.Facets, error)
+	// ListByCustomer returns the customers with accounts matching opts.Filter,
+	// newest first (or oldest first with opts.Ascending), each with those
+	// accounts newest first. opts.After and opts.Limit page through customers.
+	ListByCustomer(ctx context.Context, opts ListOptions) ([]models.CustomerAccounts, error)
+	// IDByUUID returns the id of the account with uuid, deleted or not
+	IDByUUID(ctx context.Context, uuid string) (int, error)
+}
+
+// AccountFacets are the account fields lists can be filtered on and counted
+// by, mapped to their SQL expressions
+var AccountFacets = map[string]string{
+	"status":      "status",
+	"type":        "COALESCE(type, 'standard')",
+	"customer_id": "customer_id",
+}
+
+// PostgresAccounts is the AccountRepository backed by the primary database
+type PostgresAccounts struct{}
+
+const accountColumns = "id, COALESCE(uuid::text, ''), customer_id, COALESCE(reference, ''), COALESCE(type, 'standard'), name, status, COALESCE(mrr_cents, 0), created_at, updated_at, deleted_at, COALESCE(version, 1)"
+
+func scanAccount(row interface{ Scan(...interface{}) error }) (models.Account, error) {
+	var account models.Account
+	var deletedAt sql.NullTime
+	err := row.Scan(&account.ID, &account.UUID, &account.CustomerID, &account.Reference, &account.Type, &account.Name, &account.Status, &account.MRRCents, &account.CreatedAt, &account.UpdatedAt, &deletedAt, &account.Version)
+	if err == sql.ErrNoRows {
+		return account, ErrNotFound
+	}
+	account.DeletedAt = nullTime(deletedAt)
+	return account, err
+}
+
+// List returns accounts newest first, or oldest first with opts.Ascending
+func (PostgresAccounts) List(ctx context.Context, opts ListOptions) ([]models.Account, error) {
+	args := sqlbuilder.NewArgs()
+	source := versionedSource("accounts", opts.AsOf, args)
+	where := opts.where(AccountFacets, args)
+	scopeAccounts(ctx, where, args)
+	limit := args.Limit(opts.Limit)
+	rows, err := db.Routed(ctx).Query(
+		db.Prepared("accounts.list", "SELECT "+accountColumns+" FROM "+source+where.String()+opts.orderBy("")+limit),
+		args.Values()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// Get returns a live account, as it was at asOf when it is not nil
+func (PostgresAccounts) Get(ctx context.Context, id int, asOf *time.Time) (models.Account, error) {
+	args := sqlbuilder.NewArgs()
+	where := args.Where().Equal("id", id).And(notDeleted)
+	scopeAccounts(ctx, where, args)
+	source := versionedSource("accounts", asOf, args)
+	return scanAccount(db.Routed(ctx).QueryRow(
+		db.Prepared("accounts.get", "SELECT "+accountColumns+" FROM "+source+where.String()),
+		args.Values()...,
+	))
+}
+
+// GetByReference returns a live account by its reference, as it was at asOf
+// when it is not nil
+func (PostgresAccounts) GetByReference(ctx context.Context, reference string, asOf *time.Time) (models.Account, error) {
+	args := sqlbuilder.NewArgs()
+	where := args.Where().Equal("reference", reference).And(notDeleted)
+	scopeAccounts(ctx, where, args)
+	source := versionedSource("accounts", asOf, args)
+	return scanAccount(db.Routed(ctx).QueryRow(
+		db.Prepared("accounts.get_by_reference", "SELECT "+accountColumns+" FROM "+source+where.String()),
+		args.Values()...,
+	))
+}
+
+// Create inserts an account with the next reference in its customer's
+// sequence. The customer must be in the organization ctx is scoped to, if any.
+func (PostgresAccounts) Create(ctx context.Context, req models.CreateAccountRequest) (models.Account, error) {
+	var account models.Account
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		if id := OrganizationFromContext(ctx); id != 0 {
+			var inOrganization bool
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND organization_id = $2)", req.CustomerID, id).Scan(&inOrganization)
+			if err != nil {
+				return err
+			}
+			if !inOrganization {
+				return ErrCustomerNotFound
+			}
+		}
+
+		// Reserve the next reference in the customer's sequence
+		reference, err := db.NextAccountReference(ctx, tx, req.CustomerID)
+		if err == sql.ErrNoRows {
+			return ErrCustomerNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		account, err = scanAccount(tx.QueryRowContext(ctx,
+			db.Prepared("accounts.insert", "INSERT INTO accounts (customer_id, reference, type, name, status) VALUES ($1, $2, $3, $4, $5) RETURNING "+accountColumns),
+			req.CustomerID, reference, req.Type, req.Name, req.Status,
+		))
+		return err
+	})
+	return account, err
+}
+
+// Update replaces a live account's name and status, if it is still at
+// req.Version when that is set
+func (PostgresAccounts) Update(ctx context.Context, id int, req models.UpdateAccountRequest) (models.Account, error) {
+	account, err := scanAccount(db.Primary(ctx).QueryRow(
+		db.Prepared("accounts.update", `UPDATE accounts SET name = $1, status = $2, updated_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = $3 AND `+notDeleted+` AND ($4 = 0 OR version = $4) AND `+accountInOrganization(5)+` RETURNING `+accountColumns),
+		req.Name, req.Status, id, req.Version, OrganizationFromContext(ctx),
+	))
+	if err == ErrNotFound && req.Version != 0 {
+		// Tell a stale version from a missing account
+		current, err := scanAccount(db.Primary(ctx).QueryRow(
+			"SELECT "+accountColumns+" FROM accounts WHERE id = $1 AND "+notDeleted+" AND "+accountInOrganization(2),
+			id, OrganizationFromContext(ctx),
+		))
+		if err != nil {
+			return current, err
+		}
+		return current, ErrVersionConflict
+	}
+	return account, err
+}
+
+// Delete soft-deletes an account
+func (PostgresAccounts) Delete(ctx context.Context, id int) error {
+	result, err := db.Primary(ctx).Exec(
+		db.Prepared("accounts.soft_delete", "UPDATE accounts SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND "+notDeleted+" AND "+accountInOrganization(2)),
+		id, OrganizationFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore brings back a soft-deleted account of a live customer
+func (PostgresAccounts) Restore(ctx context.Context, id int) (models.Account, error) {
+	var account models.Account
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Locking the customer too keeps it from being deleted meanwhile
+		var customerDeleted bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT c.deleted_at IS NOT NULL FROM accounts a JOIN customers c ON c.id = a.customer_id
+			WHERE a.id = $1 AND a.deleted_at IS NOT NULL AND ($2 = 0 OR c.organization_id = $2) FOR UPDATE`,
+			id, OrganizationFromContext(ctx),
+		).Scan(&customerDeleted)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if customerDeleted {
+			return ErrCustomerNotFound
+		}
+		account, err = scanAccount(tx.QueryRowContext(ctx,
+			"UPDATE accounts SET deleted_at = NULL WHERE id = $1 RETURNING "+accountColumns,
+			id,
+		))
+		return err
+	})
+	return account, err
+}
+
+// Purge removes an account, deleted or not
+func (PostgresAccounts) Purge(ctx context.Context, id int) error {
+	return deleteByID(ctx, "accounts", id, accountInOrganization(2))
+}
+
+// IDByUUID returns the id of the account with uuid, deleted or not
+func (PostgresAccounts) IDByUUID(ctx context.Context, uuid string) (int, error) {
+	return idByUUID(ctx, "accounts", uuid)
+}
+
+// Facets counts the accounts matching opts.Filter by each named facet
+func (PostgresAccounts) Facets(ctx context.Context, opts ListOptions, names []string) (models.Facets, error) {
+	return countFacets(ctx, "accounts", AccountFacets, opts, names)
+}
+
+// groupedAccountColumns are the account columns named after their JSON fields,
+// so rows can be aggregated with json_agg and decoded into models.Account.
+// Timestamps are stored without a zone in UTC; JSON needs the offset.
+const groupedAccountColumns = `id, COALESCE(uuid::text, '') AS uuid, customer_id, COALESCE(reference, '') AS reference, COALESCE(type, 'standard') AS type,
+	name, status, COALESCE(mrr_cents, 0) AS mrr_cents, created_at AT TIME ZONE 'UTC' AS created_at, updated_at AT TIME ZONE 'UTC' AS updated_at,
+	deleted_at AT TIME ZONE 'UTC' AS deleted_at, COALESCE(version, 1) AS version`
+
+// ListByCustomer returns customers with their matching accounts nested, built
+// in one query with json_agg rather than joined by the caller
+func (PostgresAccounts) ListByCustomer(ctx context.Context, opts ListOptions) ([]models.CustomerAccounts, error) {
+	// The filter applies to accounts, the keyset position and limit to customers
+	args := sqlbuilder.NewArgs()
+	accountSource := versionedSource("accounts", opts.AsOf, args)
+	accountWhere := ListOptions{Filter: opts.Filter, IncludeDeleted: opts.IncludeDeleted}.where(AccountFacets, args)
+	customerSource := versionedSource("customers", opts.AsOf, args)
+	customerWhere := ListOptions{After: opts.After, Ascending: opts.Ascending, IncludeDeleted: opts.IncludeDeleted}.where(nil, args)
+	scopeCustomers(ctx, customerWhere)
+	limit := args.Limit(opts.Limit)
+
+	rows, err := db.Routed(ctx).Query(`
+		SELECT c.id, c.uuid, c.name, c.email, c.industry, c.country, c.arr_band, c.created_at, c.updated_at, c.deleted_at, c.version,
+			json_agg(a ORDER BY a.created_at DESC, a.id DESC)
+		FROM (SELECT `+customerColumns+` FROM `+customerSource+customerWhere.String()+`) c
+		JOIN (SELECT `+groupedAccountColumns+` FROM `+accountSource+accountWhere.String()+`) a ON a.customer_id = c.id
+		GROUP BY c.id, c.uuid, c.name, c.email, c.industry, c.country, c.arr_band, c.created_at, c.updated_at, c.deleted_at, c.version
+		`+opts.orderBy("c.")+limit,
+		args.Values()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.CustomerAccounts
+	for rows.Next() {
+		var customer models.CustomerAccounts
+		var deletedAt sql.NullTime
+		var accounts []byte
+		if err := rows.Scan(&customer.ID, &customer.UUID, &customer.Name, &customer.Email, &customer.Industry, &customer.Country, &customer.ARRBand, &customer.CreatedAt, &customer.UpdatedAt, &deletedAt, &customer.Version, &accounts); err != nil {
+			return nil, err
+		}
+		customer.DeletedAt = nullTime(deletedAt)
+		if err := json.Unmarshal(accounts, &customer.Accounts); err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
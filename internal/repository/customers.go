@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/sqlbuilder"
+)
+
+// CustomerRepository reads and writes customers
+type CustomerRepository interface {
+	// List returns customers newest first, or oldest first with opts.Ascending
+	List(ctx context.Context, opts ListOptions) ([]models.Customer, error)
+	// Get returns a live customer, as it was at asOf when it is not nil
+	Get(ctx context.Context, id int, asOf *time.Time) (models.Customer, error)
+	Create(ctx context.Context, req models.CreateCustomerRequest) (models.Customer, error)
+	// Update replaces a customer's name and email. With req.Version set, it
+	// returns the current customer and ErrVersionConflict if that isn't the
+	// customer's version.
+	Update(ctx context.Context, id int, req models.UpdateCustomerRequest) (models.Customer, error)
+	// Delete soft-deletes a customer and its live accounts
+	Delete(ctx context.Context, id int) error
+	// Restore brings back a soft-deleted customer and the accounts deleted
+	// with it, returning ErrNotFound if there is no such deleted customer
+	Restore(ctx context.Context, id int) (models.Customer, error)
+	// Purge removes a customer, deleted or not, and by cascade its accounts
+	Purge(ctx context.Context, id int) error
+	// IDByUUID returns the id of the customer with uuid, deleted or not
+	IDByUUID(ctx context.Context, uuid string) (int, error)
+}
+
+// PostgresCustomers is the CustomerRepository backed by the primary database
+type PostgresCustomers struct{}
+
+// customerColumns are read from history as well as the live table; history
+// recorded before row versions has no version, which reads as 1, and that of
+// customers purged before UUIDs has no uuid
+const customerColumns = "id, COALESCE(uuid::text, '') AS uuid, name, email, COALESCE(industry, '') AS industry, COALESCE(country, '') AS country, COALESCE(arr_band, '') AS arr_band, created_at, updated_at, deleted_at, COALESCE(version, 1) AS version"
+
+func scanCustomer(row interface{ Scan(...interface{}) error }) (models.Customer, error) {
+	var customer models.Customer
+	var deletedAt sql.NullTime
+	err := row.Scan(&customer.ID, &customer.UUID, &customer.Name, &customer.Email, &customer.Industry, &customer.Country, &customer.ARRBand, &customer.CreatedAt, &customer.UpdatedAt, &deletedAt, &customer.Version)
+	if err == sql.ErrNoRows {
+		return customer, ErrNotFound
+	}
+	customer.DeletedAt = nullTime(deletedAt)
+	return customer, err
+}
+
+// List returns customers newest first, or oldest first with opts.Ascending
+func (PostgresCustomers) List(ctx context.Context, opts ListOptions) ([]models.Customer, error) {
+	args := sqlbuilder.NewArgs()
+	source := versionedSource("customers", opts.AsOf, args)
+	where := opts.where(nil, args)
+	scopeCustomers(ctx, where)
+	limit := args.Limit(opts.Limit)
+	rows, err := db.Routed(ctx).Query(
+		db.Prepared("customers.list", "SELECT "+customerColumns+" FROM "+source+where.String()+opts.orderBy("")+limit),
+		args.Values()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.Customer
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
+
+// Get returns a live customer, as it was at asOf when it is not nil
+func (PostgresCustomers) Get(ctx context.Context, id int, asOf *time.Time) (models.Customer, error) {
+	args := sqlbuilder.NewArgs()
+	where := args.Where().Equal("id", id).And(notDeleted)
+	scopeCustomers(ctx, where)
+	source := versionedSource("customers", asOf, args)
+	return scanCustomer(db.Routed(ctx).QueryRow(
+		db.Prepared("customers.get", "SELECT "+customerColumns+" FROM "+source+where.String()),
+		args.Values()...,
+	))
+}
+
+// Create inserts a customer, in the organization ctx is scoped to if any
+func (PostgresCustomers) Create(ctx context.Context, req models.CreateCustomerRequest) (models.Customer, error) {
+	return scanCustomer(db.Primary(ctx).QueryRow(
+		db.Prepared("customers.insert", "INSERT INTO customers (name, email, organization_id) VALUES ($1, $2, NULLIF($3, 0)) RETURNING "+customerColumns),
+		req.Name, req.Email, OrganizationFromContext(ctx),
+	))
+}
+
+// Update replaces a live customer's name and email, if it is still at
+// req.Version when that is set
+func (PostgresCustomers) Update(ctx context.Context, id int, req models.UpdateCustomerRequest) (models.Customer, error) {
+	customer, err := scanCustomer(db.Primary(ctx).QueryRow(
+		db.Prepared("customers.update", `UPDATE customers SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = $3 AND `+notDeleted+` AND ($4 = 0 OR version = $4) AND ($5 = 0 OR organization_id = $5) RETURNING `+customerColumns),
+		req.Name, req.Email, id, req.Version, OrganizationFromContext(ctx),
+	))
+	if err == ErrNotFound && req.Version != 0 {
+		// Tell a stale version from a missing customer
+		current, err := scanCustomer(db.Primary(ctx).QueryRow(
+			"SELECT "+customerColumns+" FROM customers WHERE id = $1 AND "+notDeleted+" AND ($2 = 0 OR organization_id = $2)",
+			id, OrganizationFromContext(ctx),
+		))
+		if err != nil {
+			return current, err
+		}
+		return current, ErrVersionConflict
+	}
+	return customer, err
+}
+
+// Delete soft-deletes a customer and its live accounts. The accounts get the
+// customer's deleted_at, which is how Restore tells them from accounts that
+// were deleted on their own.
+func (PostgresCustomers) Delete(ctx context.Context, id int) error {
+	return db.WithTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			db.Prepared("customers.soft_delete", "UPDATE customers SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND "+notDeleted+" AND ($2 = 0 OR organization_id = $2)"),
+			id, OrganizationFromContext(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return ErrNotFound
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE accounts a SET deleted_at = c.deleted_at FROM customers c
+			WHERE c.id = $1 AND a.customer_id = c.id AND a.deleted_at IS NULL`,
+			id,
+		)
+		return err
+	})
+}
+
+// Restore brings back a soft-deleted customer and the accounts deleted with it
+func (PostgresCustomers) Restore(ctx context.Context, id int) (models.Customer, error) {
+	var customer models.Customer
+	err := db.WithTx(ctx, func(tx *sql.Tx) error {
+		// Accounts first, while the customer's deleted_at still tells which
+		// went with it
+		_, err := tx.ExecContext(ctx, `
+			UPDATE accounts a SET deleted_at = NULL FROM customers c
+			WHERE c.id = $1 AND a.customer_id = c.id AND a.deleted_at = c.deleted_at AND ($2 = 0 OR c.organization_id = $2)`,
+			id, OrganizationFromContext(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		customer, err = scanCustomer(tx.QueryRowContext(ctx,
+			"UPDATE customers SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = 0 OR organization_id = $2) RETURNING "+customerColumns,
+			id, OrganizationFromContext(ctx),
+		))
+		return err
+	})
+	return customer, err
+}
+
+// Purge removes a customer, deleted or not, and by cascade its accounts
+func (PostgresCustomers) Purge(ctx context.Context, id int) error {
+	return deleteByID(ctx, "customers", id, "($2 = 0 OR organization_id = $2)")
+}
+
+// IDByUUID returns the id of the customer with uuid, deleted or not
+func (PostgresCustomers) IDByUUID(ctx context.Context, uuid string) (int, error) {
+	return idByUUID(ctx, "customers", uuid)
+}
+
+// idByUUID returns the id of the row of table with uuid, or ErrNotFound
+func idByUUID(ctx context.Context, table, uuid string) (int, error) {
+	var id int
+	err := db.Routed(ctx).QueryRow(db.Prepared(table+".id_by_uuid", "SELECT id FROM "+table+" WHERE uuid = $1"), uuid).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// deleteByID deletes the row with id from table that meets inOrganization,
+// the condition on the organization ctx is scoped to being $2, returning
+// ErrNotFound if there was none
+func deleteByID(ctx context.Context, table string, id int, inOrganization string) error {
+	result, err := db.Primary(ctx).Exec(
+		db.Prepared(table+".delete", "DELETE FROM "+table+" WHERE id = $1 AND "+inOrganization),
+		id, OrganizationFromContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// nullTime returns the time of t, or nil if it is NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
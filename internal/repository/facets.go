@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/sqlbuilder"
+)
+
+// countFacets counts the rows of table matching opts.Filter by each named
+// field in fields, in a single GROUPING SETS query. Each row of the result
+// belongs to one grouping set; GROUPING() has a bit set for every field that
+// isn't part of the row's set, which tells which facet the row counts.
+func countFacets(ctx context.Context, table string, fields map[string]string, opts ListOptions, names []string) (models.Facets, error) {
+	facets := make(models.Facets, len(names))
+	if len(names) == 0 {
+		return facets, nil
+	}
+
+	exprs := make([]string, len(names))
+	values := make([]string, len(names))
+	sets := make([]string, len(names))
+	for i, name := range names {
+		expr, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown facet %q", name)
+		}
+		exprs[i] = expr
+		values[i] = "(" + expr + ")::text"
+		sets[i] = "(" + expr + ")"
+		facets[name] = map[string]int64{}
+	}
+
+	args := sqlbuilder.NewArgs()
+	source := versionedSource(table, opts.AsOf, args)
+	where := opts.filter(fields, args)
+	if table == "accounts" {
+		scopeAccounts(ctx, where, args)
+	}
+
+	rows, err := db.Routed(ctx).Query(
+		"SELECT GROUPING("+strings.Join(exprs, ", ")+"), "+strings.Join(values, ", ")+", COUNT(*) FROM "+source+where.String()+
+			" GROUP BY GROUPING SETS ("+strings.Join(sets, ", ")+")",
+		args.Values()...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grouping int64
+		var count int64
+		row := make([]sql.NullString, len(names))
+		dest := make([]interface{}, 0, len(names)+2)
+		dest = append(dest, &grouping)
+		for i := range row {
+			dest = append(dest, &row[i])
+		}
+		dest = append(dest, &count)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		i := facetIndex(grouping, len(names))
+		if i < 0 {
+			continue
+		}
+		value := row[i].String
+		if !row[i].Valid {
+			value = "null"
+		}
+		facets[names[i]][value] = count
+	}
+	return facets, rows.Err()
+}
+
+// facetIndex returns which of n fields a GROUPING() result's row was grouped
+// by: the one whose bit is clear, counting from the most significant of n bits.
+// It returns -1 if the row isn't grouped by exactly one field.
+func facetIndex(grouping int64, n int) int {
+	index := -1
+	for i := 0; i < n; i++ {
+		if grouping&(1<<(n-1-i)) == 0 {
+			if index >= 0 {
+				return -1
+			}
+			index = i
+		}
+	}
+	return index
+}
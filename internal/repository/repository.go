@@ -0,0 +1,102 @@
+// Package repository is the data access layer for customers and accounts.
+// Handlers depend on the CustomerRepository and AccountRepository interfaces
+// rather than on the database, so they can be tested with in-memory fakes.
+package repository
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/sqlbuilder"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist (or did
+	// not exist at the requested point in time)
+	ErrNotFound = errors.New("not found")
+	// ErrCustomerNotFound is returned when creating or restoring an account
+	// for a customer that does not exist or is deleted
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrVersionConflict is returned when an update names a version the
+	// record has moved on from
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// Position is the keyset position a list resumes after, in the (created_at,
+// id) order lists are returned in
+type Position struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// ListOptions narrow a list. The zero value lists every live record, i.e.
+// every record that isn't soft-deleted.
+type ListOptions struct {
+	// AsOf lists the records as they were at that time
+	AsOf *time.Time
+	// After skips records up to and including this position
+	After *Position
+	// Limit caps the number of records returned; 0 means no limit
+	Limit int
+	// Ascending lists records oldest first instead of newest first
+	Ascending bool
+	// Filter keeps records whose field equals the value, e.g. {"status":
+	// "active"}. Fields the repository can't filter on are ignored.
+	Filter map[string]interface{}
+	// IncludeDeleted lists soft-deleted records too
+	IncludeDeleted bool
+}
+
+// where returns the WHERE conditions for opts, with their values bound to
+// args: live records unless opts.IncludeDeleted, the filter, and the keyset
+// position. fields maps the filterable field names to their SQL expressions.
+func (opts ListOptions) where(fields map[string]string, args *sqlbuilder.Args) *sqlbuilder.Where {
+	where := opts.filter(fields, args)
+	if opts.After != nil {
+		where.After([]string{"created_at", "id"}, !opts.Ascending, opts.After.CreatedAt, opts.After.ID)
+	}
+	return where
+}
+
+// orderBy returns the ORDER BY clause for opts, on the created_at and id
+// columns qualified by prefix
+func (opts ListOptions) orderBy(prefix string) string {
+	return sqlbuilder.OrderBy(!opts.Ascending, prefix+"created_at", prefix+"id")
+}
+
+// filter returns the condition that skips soft-deleted records, unless
+// opts.IncludeDeleted, and a condition per filtered field, in field order so
+// queries are stable, with the values bound to args
+func (opts ListOptions) filter(fields map[string]string, args *sqlbuilder.Args) *sqlbuilder.Where {
+	names := make([]string, 0, len(opts.Filter))
+	for name := range opts.Filter {
+		if _, ok := fields[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	where := args.Where()
+	if !opts.IncludeDeleted {
+		where.And(notDeleted)
+	}
+	for _, name := range names {
+		where.Equal(fields[name], opts.Filter[name])
+	}
+	return where
+}
+
+// notDeleted is the condition that skips soft-deleted rows
+const notDeleted = "deleted_at IS NULL"
+
+// versionedSource returns what to select from for table: the live table, or
+// its rows as of asOf with the timestamp bound to args
+func versionedSource(table string, asOf *time.Time, args *sqlbuilder.Args) string {
+	if asOf == nil {
+		return table
+	}
+	args.Add(*asOf)
+	return db.AsOf(table, args.Len())
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/db"
+	"saas-go-app/internal/models"
+	"saas-go-app/internal/sqlbuilder"
+)
+
+func setupTestDB(t *testing.T) {
+	// Skip if DATABASE_URL is not set
+	if os.Getenv("DATABASE_URL") == "" {
+		t.Skip("DATABASE_URL not set, skipping database test")
+	}
+
+	if err := db.InitPrimaryDB(); err != nil {
+		t.Fatalf("Failed to initialize primary database: %v", err)
+	}
+	t.Cleanup(db.CloseDB)
+	if err := db.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+}
+
+func TestCustomersAndAccounts(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	customers, accounts := PostgresCustomers{}, PostgresAccounts{}
+
+	customer, err := customers.Create(ctx, models.CreateCustomerRequest{Name: "Repository Test", Email: "repository-test@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create customer: %v", err)
+	}
+	defer customers.Purge(ctx, customer.ID)
+
+	if id, err := customers.IDByUUID(ctx, customer.UUID); err != nil || id != customer.ID {
+		t.Errorf("Expected the customer's UUID to resolve to %d, got %d, %v", customer.ID, id, err)
+	}
+	if _, err := customers.IDByUUID(ctx, "00000000-0000-7000-8000-000000000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown UUID, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := accounts.Create(ctx, models.CreateAccountRequest{CustomerID: customer.ID, Name: "Account", Status: "active", Type: "standard"}); err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	first, err := accounts.List(ctx, ListOptions{Limit: 2})
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Expected a page of 2 accounts, got %d, %v", len(first), err)
+	}
+	last := first[len(first)-1]
+	second, err := accounts.List(ctx, ListOptions{After: &Position{CreatedAt: last.CreatedAt, ID: last.ID}, Limit: 2})
+	if err != nil || len(second) == 0 {
+		t.Fatalf("Expected a second page, got %d, %v", len(second), err)
+	}
+	if second[0].ID == first[0].ID || second[0].ID == last.ID {
+		t.Errorf("Expected the second page to start after account %d, got %d", last.ID, second[0].ID)
+	}
+
+	account, err := accounts.GetByReference(ctx, first[0].Reference, nil)
+	if err != nil || account.ID != first[0].ID {
+		t.Errorf("Expected account %d by reference, got %d, %v", first[0].ID, account.ID, err)
+	}
+
+	facets, err := accounts.Facets(ctx, ListOptions{Filter: map[string]interface{}{"customer_id": customer.ID}}, []string{"status", "type"})
+	if err != nil {
+		t.Fatalf("Failed to count facets: %v", err)
+	}
+	if facets["status"]["active"] != 3 || facets["type"]["standard"] != 3 {
+		t.Errorf("Expected 3 active standard accounts for the customer, got %v", facets)
+	}
+
+	grouped, err := accounts.ListByCustomer(ctx, ListOptions{Filter: map[string]interface{}{"customer_id": customer.ID}})
+	if err != nil {
+		t.Fatalf("Failed to list accounts by customer: %v", err)
+	}
+	if len(grouped) != 1 || grouped[0].ID != customer.ID || len(grouped[0].Accounts) != 3 {
+		t.Errorf("Expected customer %d with its 3 accounts nested, got %+v", customer.ID, grouped)
+	} else if grouped[0].Accounts[0].CreatedAt.IsZero() || grouped[0].Accounts[0].Reference == "" {
+		t.Errorf("Expected nested accounts decoded in full, got %+v", grouped[0].Accounts[0])
+	}
+
+	if _, err := accounts.Create(ctx, models.CreateAccountRequest{CustomerID: -1, Name: "Orphan", Status: "active", Type: "standard"}); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Expected ErrCustomerNotFound, got %v", err)
+	}
+
+	updated, err := customers.Update(ctx, customer.ID, models.UpdateCustomerRequest{Name: "Renamed", Email: customer.Email, Version: customer.Version})
+	if err != nil || updated.Version != customer.Version+1 {
+		t.Fatalf("Expected the update to bump the version, got %+v, %v", updated, err)
+	}
+	current, err := customers.Update(ctx, customer.ID, models.UpdateCustomerRequest{Name: "Stale", Email: customer.Email, Version: customer.Version})
+	if !errors.Is(err, ErrVersionConflict) || current.Name != "Renamed" {
+		t.Errorf("Expected ErrVersionConflict with the current customer, got %+v, %v", current, err)
+	}
+
+	// Deleted on its own, the newest account stays deleted when the customer
+	// is restored
+	byCustomer := map[string]interface{}{"customer_id": customer.ID}
+	owned, err := accounts.List(ctx, ListOptions{Filter: byCustomer})
+	if err != nil || len(owned) != 3 {
+		t.Fatalf("Expected the customer's 3 accounts, got %d, %v", len(owned), err)
+	}
+	if err := accounts.Delete(ctx, owned[0].ID); err != nil {
+		t.Fatalf("Failed to delete account: %v", err)
+	}
+	if err := customers.Delete(ctx, customer.ID); err != nil {
+		t.Fatalf("Failed to delete customer: %v", err)
+	}
+	if _, err := customers.Get(ctx, customer.ID, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := customers.Delete(ctx, customer.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if live, err := accounts.List(ctx, ListOptions{Filter: byCustomer}); err != nil || len(live) != 0 {
+		t.Errorf("Expected the customer's accounts deleted with it, got %d, %v", len(live), err)
+	}
+	if all, err := accounts.List(ctx, ListOptions{Filter: byCustomer, IncludeDeleted: true}); err != nil || len(all) != 3 || all[0].DeletedAt == nil {
+		t.Errorf("Expected 3 deleted accounts with include deleted, got %d, %v", len(all), err)
+	}
+	if _, err := accounts.Restore(ctx, owned[1].ID); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Expected ErrCustomerNotFound restoring an account of a deleted customer, got %v", err)
+	}
+
+	restored, err := customers.Restore(ctx, customer.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("Failed to restore customer: %+v, %v", restored, err)
+	}
+	if live, err := accounts.List(ctx, ListOptions{Filter: byCustomer}); err != nil || len(live) != 2 {
+		t.Errorf("Expected the 2 accounts deleted with the customer restored, got %d, %v", len(live), err)
+	}
+	if _, err := customers.Restore(ctx, customer.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound restoring a live customer, got %v", err)
+	}
+	if account, err := accounts.Restore(ctx, owned[0].ID); err != nil || account.DeletedAt != nil {
+		t.Errorf("Failed to restore account: %+v, %v", account, err)
+	}
+
+	if err := customers.Purge(ctx, customer.ID); err != nil {
+		t.Fatalf("Failed to purge customer: %v", err)
+	}
+	if err := customers.Purge(ctx, customer.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound purging twice, got %v", err)
+	}
+}
+
+func TestFacetIndex(t *testing.T) {
+	// GROUPING(status, type, customer_id) for the sets (status), (type), (customer_id)
+	tests := map[int64]int{0b011: 0, 0b101: 1, 0b110: 2, 0b111: -1, 0b001: -1}
+	for grouping, want := range tests {
+		if got := facetIndex(grouping, 3); got != want {
+			t.Errorf("facetIndex(%03b) = %d, want %d", grouping, got, want)
+		}
+	}
+}
+
+func TestListOptionsFilter(t *testing.T) {
+	opts := ListOptions{Filter: map[string]interface{}{"type": "standard", "status": "active", "name": "ignored"}, Limit: 10}
+	args := sqlbuilder.NewArgs()
+	where := opts.where(AccountFacets, args).String()
+	limit := args.Limit(opts.Limit)
+	if where != " WHERE deleted_at IS NULL AND status = $1 AND COALESCE(type, 'standard') = $2" || limit != " LIMIT $3" {
+		t.Errorf("Unexpected clause %q %q", where, limit)
+	}
+	if values := args.Values(); len(values) != 3 || values[0] != "active" || values[1] != "standard" || values[2] != 10 {
+		t.Errorf("Unexpected args %v", values)
+	}
+}
+
+func TestListOptionsAscending(t *testing.T) {
+	opts := ListOptions{After: &Position{ID: 7}, Ascending: true}
+	args := sqlbuilder.NewArgs()
+	if where := opts.where(nil, args).String(); where != " WHERE deleted_at IS NULL AND (created_at, id) > ($1, $2)" || args.Len() != 2 {
+		t.Errorf("Unexpected clause %q %v", where, args.Values())
+	}
+	if order := opts.orderBy("c."); order != " ORDER BY c.created_at ASC, c.id ASC" {
+		t.Errorf("Unexpected order %q", order)
+	}
+	if order := (ListOptions{}).orderBy(""); order != " ORDER BY created_at DESC, id DESC" {
+		t.Errorf("Unexpected order %q", order)
+	}
+}
+
+func TestListOptionsIncludeDeleted(t *testing.T) {
+	if where := (ListOptions{}).where(nil, sqlbuilder.NewArgs()).String(); where != " WHERE deleted_at IS NULL" {
+		t.Errorf("Expected deleted records skipped, got %q", where)
+	}
+	if where := (ListOptions{IncludeDeleted: true}).where(nil, sqlbuilder.NewArgs()).String(); where != "" {
+		t.Errorf("Expected no condition, got %q", where)
+	}
+}
+
+func TestListOptionsAsOf(t *testing.T) {
+	asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := ListOptions{AsOf: &asOf, Filter: map[string]interface{}{"status": "active"}, After: &Position{CreatedAt: asOf, ID: 7}}
+	args := sqlbuilder.NewArgs()
+	source := versionedSource("accounts", opts.AsOf, args)
+	where := opts.where(AccountFacets, args).String()
+
+	// The snapshot takes $1, so the filter and keyset are numbered after it
+	if !strings.Contains(source, "h.valid_from <= $1") {
+		t.Errorf("Expected the snapshot bound to $1, got %s", source)
+	}
+	if where != " WHERE deleted_at IS NULL AND status = $2 AND (created_at, id) < ($3, $4)" {
+		t.Errorf("Unexpected clause %q", where)
+	}
+	if values := args.Values(); len(values) != 4 || values[0] != asOf || values[1] != "active" || values[3] != 7 {
+		t.Errorf("Unexpected args %v", values)
+	}
+	if source := versionedSource("accounts", nil, args); source != "accounts" || args.Len() != 4 {
+		t.Errorf("Expected the live table and no argument, got %s, %d", source, args.Len())
+	}
+}
+
+func TestOrganizationScope(t *testing.T) {
+	ctx := context.Background()
+	if OrganizationFromContext(ctx) != 0 || OrganizationFromContext(WithOrganization(ctx, 5)) != 5 {
+		t.Error("Expected only scoped contexts to carry an organization")
+	}
+
+	args := sqlbuilder.NewArgs()
+	where := (ListOptions{}).where(AccountFacets, args)
+	scopeAccounts(ctx, where, args)
+	if where.String() != " WHERE deleted_at IS NULL" {
+		t.Errorf("Expected staff to see every account, got %q", where.String())
+	}
+	scopeAccounts(WithOrganization(ctx, 5), where, args)
+	if where.String() != " WHERE deleted_at IS NULL AND customer_id IN (SELECT id FROM customers WHERE organization_id = $1)" || args.Values()[0] != 5 {
+		t.Errorf("Unexpected clause %q %v", where.String(), args.Values())
+	}
+
+	where = sqlbuilder.NewArgs().Where()
+	scopeCustomers(WithOrganization(ctx, 5), where)
+	if where.String() != " WHERE organization_id = $1" {
+		t.Errorf("Unexpected clause %q", where.String())
+	}
+}
+
+func TestOrganizationScopedCustomers(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	customers, accounts := PostgresCustomers{}, PostgresAccounts{}
+
+	var orgID int
+	if err := db.PrimaryDB.QueryRow("INSERT INTO organizations (name) VALUES ('Scope Test') RETURNING id").Scan(&orgID); err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	defer db.PrimaryDB.Exec("DELETE FROM organizations WHERE id = $1", orgID)
+	scoped := WithOrganization(ctx, orgID)
+
+	own, err := customers.Create(scoped, models.CreateCustomerRequest{Name: "Scope Test", Email: "scope-own@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create customer: %v", err)
+	}
+	defer customers.Purge(ctx, own.ID)
+	other, err := customers.Create(ctx, models.CreateCustomerRequest{Name: "Scope Test Other", Email: "scope-other@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create customer: %v", err)
+	}
+	defer customers.Purge(ctx, other.ID)
+	otherAccount, err := accounts.Create(ctx, models.CreateAccountRequest{CustomerID: other.ID, Name: "Other", Status: "active", Type: "standard"})
+	if err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	listed, err := customers.List(scoped, ListOptions{})
+	if err != nil || len(listed) != 1 || listed[0].ID != own.ID {
+		t.Errorf("Expected only the organization's customer, got %+v, %v", listed, err)
+	}
+	if _, err := customers.Get(scoped, other.ID, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another organization's customer not to be found, got %v", err)
+	}
+	if _, err := customers.Update(scoped, other.ID, models.UpdateCustomerRequest{Name: "Taken over", Email: "x@example.com"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another organization's customer not to be updated, got %v", err)
+	}
+	if err := customers.Delete(scoped, other.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another organization's customer not to be deleted, got %v", err)
+	}
+	if _, err := accounts.Get(scoped, otherAccount.ID, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another organization's account not to be found, got %v", err)
+	}
+	if _, err := accounts.Create(scoped, models.CreateAccountRequest{CustomerID: other.ID, Name: "Sneaky", Status: "active", Type: "standard"}); !errors.Is(err, ErrCustomerNotFound) {
+		t.Errorf("Expected accounts not to be added to another organization's customer, got %v", err)
+	}
+	if err := accounts.Purge(scoped, otherAccount.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another organization's account not to be purged, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"saas-go-app/internal/sqlbuilder"
+)
+
+type organizationKey struct{}
+
+// WithOrganization returns a copy of ctx that scopes the repositories to the
+// customers of organization id and their accounts, for requests made by its
+// members. Records of other organizations read as not found.
+func WithOrganization(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, organizationKey{}, id)
+}
+
+// OrganizationFromContext returns the organization ctx is scoped to, or 0 for
+// staff, who see every customer
+func OrganizationFromContext(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	id, _ := ctx.Value(organizationKey{}).(int)
+	return id
+}
+
+// scopeCustomers keeps the customers of the organization ctx is scoped to
+func scopeCustomers(ctx context.Context, where *sqlbuilder.Where) {
+	if id := OrganizationFromContext(ctx); id != 0 {
+		where.Equal("organization_id", id)
+	}
+}
+
+// scopeAccounts keeps the accounts of the organization ctx is scoped to
+func scopeAccounts(ctx context.Context, where *sqlbuilder.Where, args *sqlbuilder.Args) {
+	if id := OrganizationFromContext(ctx); id != 0 {
+		where.And("customer_id IN (SELECT id FROM customers WHERE organization_id = " + args.Add(id) + ")")
+	}
+}
+
+// accountInOrganization is the condition on an account belonging to the
+// organization bound to $n, or to any organization when that is 0
+func accountInOrganization(n int) string {
+	return fmt.Sprintf("($%[1]d = 0 OR customer_id IN (SELECT id FROM customers WHERE organization_id = $%[1]d))", n)
+}
@@ -0,0 +1,103 @@
+// Package scenario runs the NGPG showcase demos against a running app over its
+// HTTP API: it seeds data, generates read load, changes routing, and imports
+// files, then summarizes what happened. cmd/saasctl is its command line.
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client calls the app's API as an admin
+type Client struct {
+	// BaseURL is the API base including /api, e.g. http://localhost:8080/api
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient returns a client for baseURL that sends token
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Login signs in with a username and password and keeps the token
+func (c *Client) Login(ctx context.Context, username, password string) error {
+	var response struct {
+		Token string `json:"token"`
+	}
+	err := c.Do(ctx, http.MethodPost, "/auth/login", map[string]string{"username": username, "password": password}, &response)
+	if err != nil {
+		return fmt.Errorf("failed to log in as %s: %w", username, err)
+	}
+	c.Token = response.Token
+	return nil
+}
+
+// Do sends a JSON request and decodes a JSON response into out, if it is not
+// nil. Responses of 400 and above are returned as errors with their message.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// newRequest builds an authenticated request for path
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// send sends req and decodes the response into out
+func (c *Client) send(req *http.Request, out interface{}) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		if failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %d %s", req.Method, req.URL.Path, resp.StatusCode, failure.Error)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,87 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"saas-go-app/internal/imports"
+	"saas-go-app/internal/models"
+)
+
+// chunkChecksumHeader carries each part's hex SHA-256 (see api.ChunkChecksumHeader)
+const chunkChecksumHeader = "X-Chunk-SHA256"
+
+// customersCSV generates rows customers with emails unique to run, so
+// repeated runs import new customers instead of skipping them
+func customersCSV(rows int, run string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("name,email\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&buf, "Scenario Customer %d,scenario-%s-%d@example.com\n", i+1, run, i+1)
+	}
+	return buf.Bytes()
+}
+
+// bulkImport uploads a generated CSV of rows customers in parts, completes the
+// upload, and waits for the import to finish
+func (c *Client) bulkImport(ctx context.Context, rows int, pollInterval time.Duration) (models.ImportUpload, error) {
+	run := strconv.FormatInt(time.Now().Unix(), 36)
+	data := customersCSV(rows, run)
+
+	var upload models.ImportUpload
+	err := c.Do(ctx, http.MethodPost, "/admin/imports", models.CreateImportUploadRequest{
+		Filename:  "scenario-" + run + ".csv",
+		Size:      int64(len(data)),
+		ChunkSize: imports.DefaultChunkSize,
+	}, &upload)
+	if err != nil {
+		return upload, fmt.Errorf("failed to start upload: %w", err)
+	}
+	log.Printf("Uploading %d customers (%d bytes) in %d parts (upload %s)...", rows, len(data), upload.Parts, upload.ID)
+
+	for part := 1; part <= upload.Parts; part++ {
+		start := int64(part-1) * upload.ChunkSize
+		chunk := data[start:min(start+upload.ChunkSize, int64(len(data)))]
+		sum := sha256.Sum256(chunk)
+		req, err := c.newRequest(ctx, http.MethodPut, fmt.Sprintf("/admin/imports/%s/parts/%d", upload.ID, part), bytes.NewReader(chunk))
+		if err != nil {
+			return upload, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set(chunkChecksumHeader, hex.EncodeToString(sum[:]))
+		if err := c.send(req, nil); err != nil {
+			return upload, fmt.Errorf("failed to upload part %d: %w", part, err)
+		}
+	}
+
+	if err := c.Do(ctx, http.MethodPost, "/admin/imports/"+upload.ID+"/complete", nil, &upload); err != nil {
+		return upload, fmt.Errorf("failed to complete upload: %w", err)
+	}
+	log.Printf("Importing (upload %s)...", upload.ID)
+	for upload.Status == imports.StatusImporting {
+		select {
+		case <-ctx.Done():
+			return upload, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+		if err := c.Do(ctx, http.MethodGet, "/admin/imports/"+upload.ID, nil, &upload); err != nil {
+			return upload, fmt.Errorf("failed to check import: %w", err)
+		}
+		log.Printf("  %d imported, %d skipped", upload.Imported, upload.Skipped)
+	}
+	if upload.Status != imports.StatusCompleted {
+		message := upload.Status
+		if upload.Error != nil {
+			message += ": " + *upload.Error
+		}
+		return upload, fmt.Errorf("import %s ended as %s", upload.ID, message)
+	}
+	return upload, nil
+}
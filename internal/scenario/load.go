@@ -0,0 +1,162 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultReadPaths are the read-only endpoints load is generated against:
+// list pages and the analytics that the follower pool serves
+var DefaultReadPaths = []string{
+	"/customers?limit=50",
+	"/accounts?limit=50",
+	"/accounts?status=active&limit=50&facets=status,type",
+	"/analytics",
+}
+
+// Sample is the outcome of one request
+type Sample struct {
+	At      time.Time
+	Latency time.Duration
+	Failed  bool
+}
+
+// LoadOptions configure Load
+type LoadOptions struct {
+	// Paths are requested in turn by each worker
+	Paths []string
+	// Concurrency is the number of workers sending requests back to back
+	Concurrency int
+}
+
+// Load sends GET requests for opts.Paths from opts.Concurrency workers until
+// ctx is done, and returns every request's outcome in the order they finished.
+// Failed requests, including 4xx and 5xx responses, are samples too, so
+// errors show up in the summary instead of stopping the run.
+func (c *Client) Load(ctx context.Context, opts LoadOptions) []Sample {
+	paths := opts.Paths
+	if len(paths) == 0 {
+		paths = DefaultReadPaths
+	}
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var samples []Sample
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; ctx.Err() == nil; i++ {
+				sample, ok := c.get(ctx, paths[i%len(paths)])
+				if !ok {
+					return
+				}
+				mu.Lock()
+				samples = append(samples, sample)
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+	return samples
+}
+
+// get times one request. It returns false if the request was cut short
+// because ctx is done, so it shouldn't count.
+func (c *Client) get(ctx context.Context, path string) (Sample, bool) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return Sample{At: time.Now(), Failed: true}, true
+	}
+	started := time.Now()
+	resp, err := c.HTTP.Do(req)
+	if err == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if ctx.Err() != nil {
+		return Sample{}, false
+	}
+	finished := time.Now()
+	failed := err != nil || resp.StatusCode >= http.StatusBadRequest
+	return Sample{At: finished, Latency: finished.Sub(started), Failed: failed}, true
+}
+
+// LoadStats summarize the samples of one phase of a scenario
+type LoadStats struct {
+	Phase    string
+	Requests int
+	Errors   int
+	Elapsed  time.Duration
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+}
+
+// RequestsPerSecond is the phase's throughput
+func (s LoadStats) RequestsPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Elapsed.Seconds()
+}
+
+// ErrorRate is the fraction of requests that failed
+func (s LoadStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+func (s LoadStats) String() string {
+	return fmt.Sprintf("%s: %d requests (%.1f/s), %d errors, p50 %v, p95 %v, p99 %v",
+		s.Phase, s.Requests, s.RequestsPerSecond(), s.Errors,
+		s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.P99.Round(time.Millisecond))
+}
+
+// Summarize computes the stats of the samples that finished in [from, to)
+func Summarize(phase string, samples []Sample, from, to time.Time) LoadStats {
+	stats := LoadStats{Phase: phase, Elapsed: to.Sub(from)}
+	var latencies []time.Duration
+	for _, sample := range samples {
+		if sample.At.Before(from) || !sample.At.Before(to) {
+			continue
+		}
+		stats.Requests++
+		if sample.Failed {
+			stats.Errors++
+		}
+		latencies = append(latencies, sample.Latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.P50 = percentile(latencies, 0.50)
+	stats.P95 = percentile(latencies, 0.95)
+	stats.P99 = percentile(latencies, 0.99)
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,323 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"saas-go-app/internal/config"
+	"saas-go-app/internal/progress"
+)
+
+// Options configure a scenario run
+type Options struct {
+	// Duration is how long each load phase runs (default 30s)
+	Duration time.Duration
+	// Concurrency is the number of load workers (default 10)
+	Concurrency int
+	// Paths are the endpoints to load (default DefaultReadPaths)
+	Paths []string
+	// Seed clears and reseeds the database before read-scaling
+	Seed bool
+	// Rows is the number of customers bulk-import uploads (default 100000)
+	Rows int
+	// PollInterval is how often seed and import jobs are checked (default 2s)
+	PollInterval time.Duration
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Duration <= 0 {
+		opts.Duration = 30 * time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 10
+	}
+	if opts.Rows <= 0 {
+		opts.Rows = 100000
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	return opts
+}
+
+func (opts Options) load() LoadOptions {
+	return LoadOptions{Paths: opts.Paths, Concurrency: opts.Concurrency}
+}
+
+// Report is the outcome of a scenario: load stats per phase and notes on
+// what the scenario did and found
+type Report struct {
+	Scenario string
+	Phases   []LoadStats
+	Notes    []string
+}
+
+// Write prints the report as a table followed by its notes
+func (r Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "Scenario: %s\n\n", r.Scenario)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "PHASE\tREQUESTS\tREQ/S\tERRORS\tP50\tP95\tP99\t")
+	for _, phase := range r.Phases {
+		fmt.Fprintf(table, "%s\t%d\t%.1f\t%d (%.1f%%)\t%v\t%v\t%v\t\n",
+			phase.Phase, phase.Requests, phase.RequestsPerSecond(), phase.Errors, phase.ErrorRate()*100,
+			phase.P50.Round(time.Millisecond), phase.P95.Round(time.Millisecond), phase.P99.Round(time.Millisecond))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, note := range r.Notes {
+		if _, err := fmt.Fprintf(w, "- %s\n", note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scenario is a repeatable demo
+type Scenario struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, c *Client, opts Options) (Report, error)
+}
+
+// Scenarios are the demos saasctl can run
+var Scenarios = []Scenario{
+	{
+		Name:        "read-scaling",
+		Description: "Load the read endpoints with analytics routed to the primary, then to the follower pool, and compare",
+		Run:         runReadScaling,
+	},
+	{
+		Name:        "failover",
+		Description: "Keep read load running while analytics fail over from the follower pool to the primary and back",
+		Run:         runFailover,
+	},
+	{
+		Name:        "bulk-import",
+		Description: "Upload a generated customers CSV through the import API while reads continue, and compare read latency",
+		Run:         runBulkImport,
+	},
+}
+
+// Find returns the scenario with the given name
+func Find(name string) (Scenario, bool) {
+	for _, s := range Scenarios {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
+
+// Names lists the scenario names
+func Names() []string {
+	names := make([]string, len(Scenarios))
+	for i, s := range Scenarios {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Run runs the named scenario
+func Run(ctx context.Context, name string, c *Client, opts Options) (Report, error) {
+	s, ok := Find(name)
+	if !ok {
+		return Report{}, fmt.Errorf("unknown scenario %q (expected %s)", name, strings.Join(Names(), ", "))
+	}
+	return s.Run(ctx, c, opts.withDefaults())
+}
+
+// runReadScaling compares read load with analytics on the primary and on the follower pool
+func runReadScaling(ctx context.Context, c *Client, opts Options) (Report, error) {
+	report := Report{Scenario: "read-scaling"}
+	if opts.Seed {
+		note, err := c.reseed(ctx, opts.PollInterval)
+		if err != nil {
+			return report, err
+		}
+		report.Notes = append(report.Notes, note)
+	}
+
+	restore, err := c.routeAnalytics(ctx, "primary")
+	if err != nil {
+		return report, err
+	}
+	defer restore()
+
+	for _, routing := range []string{"primary", "follower"} {
+		if routing != "primary" {
+			if _, err := c.routeAnalytics(ctx, routing); err != nil {
+				return report, err
+			}
+		}
+		log.Printf("Loading reads for %v with analytics on the %s...", opts.Duration, routing)
+		started := time.Now()
+		loadCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+		samples := c.Load(loadCtx, opts.load())
+		cancel()
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		report.Phases = append(report.Phases, Summarize(routing, samples, started, time.Now()))
+	}
+
+	primary, follower := report.Phases[0], report.Phases[1]
+	if primary.RequestsPerSecond() > 0 && follower.P95 > 0 {
+		report.Notes = append(report.Notes, fmt.Sprintf("The follower pool served %.2fx the requests per second of the primary, with a p95 of %v vs %v",
+			follower.RequestsPerSecond()/primary.RequestsPerSecond(), follower.P95.Round(time.Millisecond), primary.P95.Round(time.Millisecond)))
+	}
+	return report, nil
+}
+
+// runFailover keeps read load running through a follower outage, simulated by
+// routing analytics to the primary for the middle phase
+func runFailover(ctx context.Context, c *Client, opts Options) (Report, error) {
+	report := Report{Scenario: "failover"}
+	restore, err := c.routeAnalytics(ctx, "follower")
+	if err != nil {
+		return report, err
+	}
+	defer restore()
+
+	loadCtx, stop := context.WithCancel(ctx)
+	done := make(chan []Sample, 1)
+	go func() { done <- c.Load(loadCtx, opts.load()) }()
+
+	phases := []struct{ name, routing string }{
+		{"baseline", "follower"},
+		{"failover", "primary"},
+		{"recovered", "follower"},
+	}
+	boundaries := []time.Time{time.Now()}
+	for i, phase := range phases {
+		if i > 0 {
+			if _, err := c.routeAnalytics(ctx, phase.routing); err != nil {
+				stop()
+				<-done
+				return report, err
+			}
+		}
+		log.Printf("Phase %s: analytics on the %s for %v...", phase.name, phase.routing, opts.Duration)
+		select {
+		case <-ctx.Done():
+			stop()
+			<-done
+			return report, ctx.Err()
+		case <-time.After(opts.Duration):
+		}
+		boundaries = append(boundaries, time.Now())
+	}
+	stop()
+	samples := <-done
+
+	for i, phase := range phases {
+		report.Phases = append(report.Phases, Summarize(phase.name, samples, boundaries[i], boundaries[i+1]))
+	}
+	failed := 0
+	for _, phase := range report.Phases {
+		failed += phase.Errors
+	}
+	report.Notes = append(report.Notes, fmt.Sprintf("%d requests failed across the failover and recovery", failed))
+	return report, nil
+}
+
+// runBulkImport measures read latency before and during a bulk import
+func runBulkImport(ctx context.Context, c *Client, opts Options) (Report, error) {
+	report := Report{Scenario: "bulk-import"}
+
+	log.Printf("Loading reads for %v before the import...", opts.Duration)
+	started := time.Now()
+	loadCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+	samples := c.Load(loadCtx, opts.load())
+	cancel()
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+	report.Phases = append(report.Phases, Summarize("before import", samples, started, time.Now()))
+
+	loadCtx, stop := context.WithCancel(ctx)
+	done := make(chan []Sample, 1)
+	started = time.Now()
+	go func() { done <- c.Load(loadCtx, opts.load()) }()
+
+	upload, err := c.bulkImport(ctx, opts.Rows, opts.PollInterval)
+	finished := time.Now()
+	stop()
+	samples = <-done
+	if err != nil {
+		return report, err
+	}
+	report.Phases = append(report.Phases, Summarize("during import", samples, started, finished))
+	elapsed := finished.Sub(started)
+	report.Notes = append(report.Notes, fmt.Sprintf("Imported %d customers (%d skipped) in %v, %.0f rows/s, including the upload",
+		upload.Imported, upload.Skipped, elapsed.Round(time.Second), float64(upload.Imported+upload.Skipped)/elapsed.Seconds()))
+	return report, nil
+}
+
+// routeAnalytics sets the analytics_routing runtime setting and returns a
+// function that puts back the settings from before
+func (c *Client) routeAnalytics(ctx context.Context, routing string) (func(), error) {
+	var current struct {
+		Settings config.Settings `json:"settings"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/admin/config", nil, &current); err != nil {
+		return nil, fmt.Errorf("failed to read runtime config: %w", err)
+	}
+	settings := current.Settings
+	settings.AnalyticsRouting = routing
+	if err := c.Do(ctx, http.MethodPut, "/admin/config", settings, nil); err != nil {
+		return nil, fmt.Errorf("failed to route analytics to the %s: %w", routing, err)
+	}
+	return func() {
+		// Restore even when the run was interrupted
+		if err := c.Do(context.Background(), http.MethodPut, "/admin/config", current.Settings, nil); err != nil {
+			log.Printf("Warning: Failed to restore analytics_routing to %s: %v", current.Settings.AnalyticsRouting, err)
+		}
+	}, nil
+}
+
+// reseed clears and reseeds the database through the admin API and waits for
+// the seed job to finish
+func (c *Client) reseed(ctx context.Context, pollInterval time.Duration) (string, error) {
+	var accepted struct {
+		JobID string `json:"job_id"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/admin/seed", nil, &accepted); err != nil {
+		return "", fmt.Errorf("failed to start reseed: %w", err)
+	}
+	log.Printf("Reseeding (job %s)...", accepted.JobID)
+
+	started := time.Now()
+	var job progress.Snapshot
+	for {
+		if err := c.Do(ctx, http.MethodGet, "/admin/jobs/"+accepted.JobID, nil, &job); err != nil {
+			return "", fmt.Errorf("failed to check seed job: %w", err)
+		}
+		if job.Finished() {
+			break
+		}
+		log.Printf("  %s: %d/%d", job.Phase, job.Done, job.Total)
+		select {
+		case <-ctx.Done():
+			// Don't leave the seed running on the app after an interrupt
+			if err := c.Do(context.Background(), http.MethodPost, "/admin/jobs/"+accepted.JobID+"/cancel", nil, nil); err != nil {
+				log.Printf("Warning: Failed to cancel seed job %s: %v", accepted.JobID, err)
+			}
+			return "", ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+	if job.Status == progress.StatusFailed {
+		return "", fmt.Errorf("seed job %s failed: %s", job.JobID, job.Error)
+	}
+	return fmt.Sprintf("Reseeded in %v (job %s)", time.Since(started).Round(time.Second), job.JobID), nil
+}
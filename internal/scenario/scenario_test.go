@@ -0,0 +1,107 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"saas-go-app/internal/config"
+)
+
+// fakeApp serves login, runtime config, and read endpoints, recording the
+// analytics routing each read was served with
+type fakeApp struct {
+	mu       sync.Mutex
+	settings config.Settings
+	reads    map[string]int
+}
+
+func (f *fakeApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/api/auth/login":
+		json.NewEncoder(w).Encode(map[string]string{"token": "test-token"})
+	case r.Header.Get("Authorization") != "Bearer test-token":
+		w.WriteHeader(http.StatusUnauthorized)
+	case r.URL.Path == "/api/admin/config" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"settings": f.settings})
+	case r.URL.Path == "/api/admin/config" && r.Method == http.MethodPut:
+		json.NewDecoder(r.Body).Decode(&f.settings)
+		json.NewEncoder(w).Encode(map[string]interface{}{"settings": f.settings})
+	default:
+		f.reads[f.settings.AnalyticsRouting]++
+		w.Write([]byte("[]"))
+	}
+}
+
+func TestRunFailover(t *testing.T) {
+	app := &fakeApp{settings: config.Settings{LogLevel: "debug", AnalyticsRouting: "primary"}, reads: map[string]int{}}
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/", "")
+	if err := client.Login(context.Background(), "admin", "admin123"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	report, err := Run(context.Background(), "failover", client, Options{Duration: 50 * time.Millisecond, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Scenario failed: %v", err)
+	}
+
+	if len(report.Phases) != 3 || report.Phases[1].Phase != "failover" {
+		t.Fatalf("Expected baseline, failover, and recovered phases, got %+v", report.Phases)
+	}
+	for _, phase := range report.Phases {
+		if phase.Requests == 0 || phase.Errors != 0 {
+			t.Errorf("Expected successful requests in every phase, got %s", phase)
+		}
+	}
+	if app.reads["follower"] == 0 || app.reads["primary"] == 0 {
+		t.Errorf("Expected reads with analytics on both pools, got %v", app.reads)
+	}
+	if app.settings.AnalyticsRouting != "primary" || app.settings.LogLevel != "debug" {
+		t.Errorf("Expected the original settings to be restored, got %+v", app.settings)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil || !strings.Contains(out.String(), "recovered") {
+		t.Errorf("Expected a report with every phase, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestRunUnknownScenario(t *testing.T) {
+	if _, err := Run(context.Background(), "chaos-monkey", NewClient("http://localhost", ""), Options{}); err == nil {
+		t.Error("Expected an error for an unknown scenario")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Now()
+	var samples []Sample
+	for i := 1; i <= 100; i++ {
+		samples = append(samples, Sample{At: start.Add(time.Duration(i) * time.Millisecond), Latency: time.Duration(i) * time.Millisecond, Failed: i%50 == 0})
+	}
+	samples = append(samples, Sample{At: start.Add(time.Second), Latency: time.Hour})
+
+	stats := Summarize("test", samples, start, start.Add(time.Second))
+	if stats.Requests != 100 || stats.Errors != 2 {
+		t.Errorf("Expected 100 requests with 2 errors in the window, got %s", stats)
+	}
+	if stats.P50 != 50*time.Millisecond || stats.P95 != 95*time.Millisecond || stats.P99 != 99*time.Millisecond {
+		t.Errorf("Unexpected percentiles: %s", stats)
+	}
+}
+
+func TestCustomersCSV(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(customersCSV(3, "abc"))), "\n")
+	if len(lines) != 4 || lines[0] != "name,email" || lines[3] != "Scenario Customer 3,scenario-abc-3@example.com" {
+		t.Errorf("Unexpected CSV: %q", lines)
+	}
+}
@@ -0,0 +1,352 @@
+// Package schemacheck compares the live schema with the schema a new release
+// creates and reports changes that would break the release still serving
+// traffic during a blue/green (pipeline promotion) deploy.
+package schemacheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severities of a finding. Breaking findings fail the check.
+const (
+	Breaking = "breaking"
+	Warning  = "warning"
+	Info     = "info"
+)
+
+// Column describes a table column as reported by information_schema
+type Column struct {
+	Name       string
+	DataType   string
+	MaxLength  *int
+	Precision  *int
+	Scale      *int
+	Nullable   bool
+	HasDefault bool
+}
+
+// Type renders the column type, e.g. "character varying(255)"
+func (c Column) Type() string {
+	switch {
+	case c.MaxLength != nil:
+		return fmt.Sprintf("%s(%d)", c.DataType, *c.MaxLength)
+	case c.DataType == "numeric" && c.Precision != nil && c.Scale != nil:
+		return fmt.Sprintf("numeric(%d,%d)", *c.Precision, *c.Scale)
+	}
+	return c.DataType
+}
+
+// Schema maps table names to their columns by name
+type Schema map[string]map[string]Column
+
+// Finding is one difference between the live and release schemas
+type Finding struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	target := f.Table
+	if f.Column != "" {
+		target += "." + f.Column
+	}
+	return fmt.Sprintf("%-8s %s: %s", strings.ToUpper(f.Severity), target, f.Message)
+}
+
+// HasBreaking reports whether any finding is breaking
+func HasBreaking(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the base tables and columns of a schema. Partitions are left
+// out; their columns are those of the partitioned table.
+func Load(ctx context.Context, conn *sql.DB, schema string) (Schema, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type, c.character_maximum_length,
+			c.numeric_precision, c.numeric_scale, c.is_nullable = 'YES',
+			c.column_default IS NOT NULL OR c.is_identity = 'YES' OR c.is_generated = 'ALWAYS'
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = $1 AND t.table_type = 'BASE TABLE'
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_class pc JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+				WHERE pn.nspname = c.table_schema AND pc.relname = c.table_name AND pc.relispartition
+			)`,
+		schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of schema %s: %w", schema, err)
+	}
+	defer rows.Close()
+
+	tables := make(Schema)
+	for rows.Next() {
+		var table string
+		var column Column
+		if err := rows.Scan(&table, &column.Name, &column.DataType, &column.MaxLength,
+			&column.Precision, &column.Scale, &column.Nullable, &column.HasDefault); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		if tables[table] == nil {
+			tables[table] = make(map[string]Column)
+		}
+		tables[table][column.Name] = column
+	}
+	return tables, rows.Err()
+}
+
+// ReferenceFunc lists the objects (views, foreign keys) that depend on a live
+// column, or on any column of the table when column is empty
+type ReferenceFunc func(table, column string) ([]string, error)
+
+// References returns a ReferenceFunc that looks dependents up in the catalog
+func References(ctx context.Context, conn *sql.DB, schema string) ReferenceFunc {
+	return func(table, column string) ([]string, error) {
+		rows, err := conn.QueryContext(ctx, `
+			SELECT 'view ' || v.relname
+			FROM pg_attribute a
+			JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.refobjid = a.attrelid AND d.refobjsubid = a.attnum
+			JOIN pg_rewrite r ON r.oid = d.objid
+			JOIN pg_class v ON v.oid = r.ev_class AND v.oid <> a.attrelid
+			WHERE a.attrelid = to_regclass($1) AND ($2 = '' OR a.attname = $2)
+			UNION
+			SELECT 'foreign key ' || con.conname
+			FROM pg_attribute a
+			JOIN pg_constraint con ON con.contype = 'f' AND con.conrelid <> a.attrelid
+				AND con.confrelid = a.attrelid AND a.attnum = ANY(con.confkey)
+			WHERE a.attrelid = to_regclass($1) AND ($2 = '' OR a.attname = $2)
+			ORDER BY 1`,
+			schema+"."+table, column,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up references to %s: %w", table, err)
+		}
+		defer rows.Close()
+
+		var references []string
+		for rows.Next() {
+			var reference string
+			if err := rows.Scan(&reference); err != nil {
+				return nil, err
+			}
+			references = append(references, reference)
+		}
+		return references, rows.Err()
+	}
+}
+
+// Compare reports the differences between the live schema, which the running
+// release uses, and the schema the new release creates. While both releases
+// run against the same database, the running release keeps reading and
+// writing the live columns, and the new release's writes must still satisfy
+// the live constraints.
+func Compare(live, release Schema, references ReferenceFunc) ([]Finding, error) {
+	var findings []Finding
+
+	for table, liveColumns := range live {
+		releaseColumns, ok := release[table]
+		if !ok {
+			refs, err := references(table, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(refs) > 0 {
+				findings = append(findings, Finding{Breaking, "dropped_table_referenced", table, "",
+					"table is no longer created by the release but is still referenced by " + strings.Join(refs, ", ")})
+			} else {
+				findings = append(findings, Finding{Warning, "dropped_table", table, "",
+					"table is no longer created by the release; only drop it once no running release uses it"})
+			}
+			continue
+		}
+
+		for name, liveColumn := range liveColumns {
+			releaseColumn, ok := releaseColumns[name]
+			if !ok {
+				finding, err := droppedColumn(table, liveColumn, references)
+				if err != nil {
+					return nil, err
+				}
+				findings = append(findings, finding)
+				continue
+			}
+			findings = append(findings, changedColumn(table, liveColumn, releaseColumn)...)
+		}
+
+		for name, releaseColumn := range releaseColumns {
+			if _, ok := liveColumns[name]; ok {
+				continue
+			}
+			if !releaseColumn.Nullable && !releaseColumn.HasDefault {
+				findings = append(findings, Finding{Breaking, "required_column_added", table, name,
+					"new NOT NULL column without a default; inserts from the running release will fail. Add a default or make it nullable first"})
+			} else {
+				findings = append(findings, Finding{Info, "column_added", table, name, "new column " + releaseColumn.Type()})
+			}
+		}
+	}
+
+	for table := range release {
+		if _, ok := live[table]; !ok {
+			findings = append(findings, Finding{Info, "table_added", table, "", "new table"})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if rank(a.Severity) != rank(b.Severity) {
+			return rank(a.Severity) < rank(b.Severity)
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Column < b.Column
+	})
+	return findings, nil
+}
+
+func droppedColumn(table string, column Column, references ReferenceFunc) (Finding, error) {
+	refs, err := references(table, column.Name)
+	if err != nil {
+		return Finding{}, err
+	}
+	switch {
+	case len(refs) > 0:
+		return Finding{Breaking, "dropped_column_referenced", table, column.Name,
+			"column is no longer created by the release but is still referenced by " + strings.Join(refs, ", ")}, nil
+	case !column.Nullable && !column.HasDefault:
+		return Finding{Breaking, "dropped_column_required", table, column.Name,
+			"column is no longer created by the release but is NOT NULL without a default, so the release's inserts will fail. Make it nullable or give it a default first"}, nil
+	}
+	return Finding{Warning, "dropped_column", table, column.Name,
+		"column is no longer created by the release; only drop it once no running release reads it"}, nil
+}
+
+func changedColumn(table string, live, release Column) []Finding {
+	var findings []Finding
+
+	switch compareTypes(live, release) {
+	case narrowed:
+		findings = append(findings, Finding{Breaking, "type_narrowed", table, live.Name,
+			fmt.Sprintf("type narrowed from %s to %s; values written by the running release may not fit", live.Type(), release.Type())})
+	case changed:
+		findings = append(findings, Finding{Breaking, "type_changed", table, live.Name,
+			fmt.Sprintf("type changed from %s to %s", live.Type(), release.Type())})
+	case widened:
+		findings = append(findings, Finding{Info, "type_widened", table, live.Name,
+			fmt.Sprintf("type widened from %s to %s", live.Type(), release.Type())})
+	}
+
+	if live.Nullable && !release.Nullable {
+		findings = append(findings, Finding{Breaking, "not_null_added", table, live.Name,
+			"column becomes NOT NULL; the running release may still write NULLs"})
+	}
+	if live.HasDefault && !release.HasDefault && !release.Nullable {
+		findings = append(findings, Finding{Breaking, "default_removed", table, live.Name,
+			"default removed from a NOT NULL column; inserts from the running release that omit it will fail"})
+	}
+	return findings
+}
+
+type typeChange int
+
+const (
+	same typeChange = iota
+	widened
+	narrowed
+	changed
+)
+
+// ladders orders types from narrowest to widest within a family
+var ladders = [][]string{
+	{"smallint", "integer", "bigint"},
+	{"real", "double precision"},
+	{"character", "character varying", "text"},
+}
+
+func compareTypes(live, release Column) typeChange {
+	if live.DataType == release.DataType {
+		if live.DataType == "numeric" {
+			return compareLimits(
+				compareLimit(live.Precision, release.Precision),
+				compareLimit(live.Scale, release.Scale),
+			)
+		}
+		return compareLimit(live.MaxLength, release.MaxLength)
+	}
+
+	for _, ladder := range ladders {
+		from, to := indexOf(ladder, live.DataType), indexOf(ladder, release.DataType)
+		if from < 0 || to < 0 {
+			continue
+		}
+		// Moving to a narrower type, or to a length limit from an unbounded
+		// type, can reject values the running release writes
+		if to < from {
+			return narrowed
+		}
+		if release.MaxLength != nil && (live.MaxLength == nil || *release.MaxLength < *live.MaxLength) {
+			return narrowed
+		}
+		return widened
+	}
+	return changed
+}
+
+// compareLimit compares optional length or precision limits; nil is unbounded
+func compareLimit(live, release *int) typeChange {
+	switch {
+	case live == nil && release == nil:
+		return same
+	case release == nil:
+		return widened
+	case live == nil:
+		return narrowed
+	case *release < *live:
+		return narrowed
+	case *release > *live:
+		return widened
+	}
+	return same
+}
+
+func compareLimits(changes ...typeChange) typeChange {
+	result := same
+	for _, change := range changes {
+		if change > result {
+			result = change
+		}
+	}
+	return result
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func rank(severity string) int {
+	switch severity {
+	case Breaking:
+		return 0
+	case Warning:
+		return 1
+	}
+	return 2
+}
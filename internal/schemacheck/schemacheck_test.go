@@ -0,0 +1,104 @@
+package schemacheck
+
+import "testing"
+
+func length(n int) *int { return &n }
+
+func noReferences(table, column string) ([]string, error) { return nil, nil }
+
+func kinds(findings []Finding) map[string]string {
+	result := make(map[string]string)
+	for _, finding := range findings {
+		result[finding.Table+"."+finding.Column] = finding.Kind
+	}
+	return result
+}
+
+func TestCompareReportsBreakingChanges(t *testing.T) {
+	live := Schema{"accounts": {
+		"id":     {Name: "id", DataType: "integer", HasDefault: true},
+		"status": {Name: "status", DataType: "character varying", MaxLength: length(50)},
+		"notes":  {Name: "notes", DataType: "text", Nullable: true},
+		"legacy": {Name: "legacy", DataType: "text"},
+		"unused": {Name: "unused", DataType: "text", Nullable: true},
+	}}
+	release := Schema{"accounts": {
+		"id":     {Name: "id", DataType: "bigint", HasDefault: true},
+		"status": {Name: "status", DataType: "character varying", MaxLength: length(20)},
+		"notes":  {Name: "notes", DataType: "text"},
+		"tier":   {Name: "tier", DataType: "text"},
+		"region": {Name: "region", DataType: "text", Nullable: true},
+	}}
+
+	findings, err := Compare(live, release, noReferences)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"accounts.id":     "type_widened",
+		"accounts.status": "type_narrowed",
+		"accounts.notes":  "not_null_added",
+		"accounts.legacy": "dropped_column_required",
+		"accounts.unused": "dropped_column",
+		"accounts.tier":   "required_column_added",
+		"accounts.region": "column_added",
+	}
+	got := kinds(findings)
+	for target, kind := range expected {
+		if got[target] != kind {
+			t.Errorf("Expected %s to be %s, got %q", target, kind, got[target])
+		}
+	}
+	if !HasBreaking(findings) {
+		t.Error("Expected breaking findings")
+	}
+	if findings[0].Severity != Breaking {
+		t.Errorf("Expected breaking findings first, got %v", findings[0])
+	}
+}
+
+func TestCompareFlagsReferencedDrops(t *testing.T) {
+	live := Schema{
+		"customers": {"email": {Name: "email", DataType: "text", Nullable: true}},
+		"invoices":  {"id": {Name: "id", DataType: "integer"}},
+	}
+	release := Schema{"customers": {}}
+	references := func(table, column string) ([]string, error) {
+		if table == "customers" && column == "email" {
+			return []string{"view customer_emails"}, nil
+		}
+		return nil, nil
+	}
+
+	findings, err := Compare(live, release, references)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	got := kinds(findings)
+	if got["customers.email"] != "dropped_column_referenced" {
+		t.Errorf("Expected referenced drop, got %q", got["customers.email"])
+	}
+	if got["invoices."] != "dropped_table" {
+		t.Errorf("Expected dropped table warning, got %q", got["invoices."])
+	}
+}
+
+func TestCompareTypes(t *testing.T) {
+	cases := []struct {
+		live, release Column
+		expected      typeChange
+	}{
+		{Column{DataType: "text"}, Column{DataType: "character varying", MaxLength: length(255)}, narrowed},
+		{Column{DataType: "character varying", MaxLength: length(64)}, Column{DataType: "text"}, widened},
+		{Column{DataType: "bigint"}, Column{DataType: "integer"}, narrowed},
+		{Column{DataType: "numeric", Precision: length(10), Scale: length(2)}, Column{DataType: "numeric", Precision: length(12), Scale: length(1)}, narrowed},
+		{Column{DataType: "integer"}, Column{DataType: "text"}, changed},
+		{Column{DataType: "jsonb"}, Column{DataType: "jsonb"}, same},
+	}
+	for _, tc := range cases {
+		if got := compareTypes(tc.live, tc.release); got != tc.expected {
+			t.Errorf("%s -> %s: expected %d, got %d", tc.live.Type(), tc.release.Type(), tc.expected, got)
+		}
+	}
+}
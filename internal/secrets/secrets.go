@@ -0,0 +1,307 @@
+// Package secrets resolves secrets such as JWT_SECRET and API keys through a
+// configurable provider (environment, mounted files, or Vault) instead of
+// reading them straight from the environment. Values are cached and can be
+// refreshed periodically; consumers register hooks to pick up rotations.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"saas-go-app/internal/httpclient"
+)
+
+// ErrNotFound is returned when no provider has the requested secret
+var ErrNotFound = errors.New("secret not found")
+
+// Provider looks up a secret by name, e.g. "JWT_SECRET"
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// RotationHook is called with the new value after a cached secret changes
+type RotationHook func(name, value string)
+
+// EnvProvider reads secrets from environment variables
+type EnvProvider struct{}
+
+// Get implements Provider
+func (EnvProvider) Get(ctx context.Context, name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+// FileProvider reads secrets from files named after the secret in Dir, as
+// mounted by Docker or Kubernetes secrets (e.g. /run/secrets/JWT_SECRET)
+type FileProvider struct {
+	Dir string
+}
+
+// Get implements Provider
+func (p FileProvider) Get(ctx context.Context, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// VaultProvider reads secrets from a HashiCorp Vault KV v2 secret, where each
+// key of the secret at Path is one named secret
+type VaultProvider struct {
+	Addr   string
+	Token  string
+	Path   string // e.g. secret/data/saas-go-app
+	Client *httpclient.Client
+}
+
+// Get implements Provider
+func (p VaultProvider) Get(ctx context.Context, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(p.Addr, "/")+"/v1/"+strings.TrimLeft(p.Path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	value, ok := body.Data.Data[name]
+	if !ok || value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Chain tries each provider in order, returning the first secret found
+type Chain []Provider
+
+// Get implements Provider
+func (c Chain) Get(ctx context.Context, name string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.Get(ctx, name)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	return "", ErrNotFound
+}
+
+type cached struct {
+	value   string
+	fetched time.Time
+}
+
+// Store caches secrets from a provider and notifies hooks when they rotate
+type Store struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cached
+	hooks map[string][]RotationHook
+}
+
+// NewStore creates a store caching values for ttl (0 caches until Refresh)
+func NewStore(provider Provider, ttl time.Duration) *Store {
+	return &Store{
+		provider: provider,
+		ttl:      ttl,
+		cache:    make(map[string]cached),
+		hooks:    make(map[string][]RotationHook),
+	}
+}
+
+// Get returns a secret, using the cached value while it is fresh
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && (s.ttl == 0 || time.Since(entry.fetched) < s.ttl) {
+		return entry.value, nil
+	}
+	return s.fetch(ctx, name)
+}
+
+// OnRotate registers a hook called when the named secret's value changes
+func (s *Store) OnRotate(name string, hook RotationHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[name] = append(s.hooks[name], hook)
+}
+
+// Forget drops cached secrets, so the next Get reads them from the provider
+// without firing hooks
+func (s *Store) Forget(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		delete(s.cache, name)
+	}
+}
+
+// Refresh re-reads every cached secret, firing hooks for any that changed
+func (s *Store) Refresh(ctx context.Context) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.cache))
+	for name := range s.cache {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		if _, err := s.fetch(ctx, name); err != nil {
+			log.Printf("Warning: Failed to refresh secret %s: %v", name, err)
+		}
+	}
+}
+
+// WatchRotation refreshes cached secrets every interval until ctx is done
+func (s *Store) WatchRotation(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Store) fetch(ctx context.Context, name string) (string, error) {
+	value, err := s.provider.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	previous, existed := s.cache[name]
+	s.cache[name] = cached{value: value, fetched: time.Now()}
+	hooks := append([]RotationHook(nil), s.hooks[name]...)
+	s.mu.Unlock()
+
+	if existed && previous.value != value {
+		log.Printf("Secret %s rotated", name)
+		for _, hook := range hooks {
+			hook(name, value)
+		}
+	}
+	return value, nil
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore = NewStore(EnvProvider{}, 0)
+)
+
+// Init configures the default store from the environment:
+//
+//	SECRETS_PROVIDER          comma-separated providers tried in order: env, file, vault (default: env)
+//	SECRETS_DIR               directory for the file provider (default: /run/secrets)
+//	VAULT_ADDR, VAULT_TOKEN   Vault server and token for the vault provider
+//	VAULT_SECRET_PATH         KV v2 path holding the secrets (default: secret/data/saas-go-app)
+//	SECRETS_REFRESH_INTERVAL  how often cached secrets are re-read to pick up rotations, e.g. 5m (default: off)
+func Init() error {
+	names := os.Getenv("SECRETS_PROVIDER")
+	if names == "" {
+		names = "env"
+	}
+
+	var chain Chain
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "env":
+			chain = append(chain, EnvProvider{})
+		case "file":
+			dir := os.Getenv("SECRETS_DIR")
+			if dir == "" {
+				dir = "/run/secrets"
+			}
+			chain = append(chain, FileProvider{Dir: dir})
+		case "vault":
+			addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+			if addr == "" || token == "" {
+				return fmt.Errorf("vault secrets provider requires VAULT_ADDR and VAULT_TOKEN")
+			}
+			path := os.Getenv("VAULT_SECRET_PATH")
+			if path == "" {
+				path = "secret/data/saas-go-app"
+			}
+			chain = append(chain, VaultProvider{Addr: addr, Token: token, Path: path, Client: httpclient.New("vault", httpclient.Options{})})
+		default:
+			return fmt.Errorf("unknown secrets provider %q (expected env, file, or vault)", name)
+		}
+	}
+
+	store := NewStore(chain, 0)
+	if value := os.Getenv("SECRETS_REFRESH_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid SECRETS_REFRESH_INTERVAL %q", value)
+		}
+		store.WatchRotation(context.Background(), interval)
+	}
+
+	defaultMu.Lock()
+	defaultStore = store
+	defaultMu.Unlock()
+	log.Printf("Secrets provider: %s", names)
+	return nil
+}
+
+// Default returns the store configured by Init
+func Default() *Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
+
+// Get reads a secret from the default store
+func Get(name string) (string, error) {
+	return Default().Get(context.Background(), name)
+}
+
+// OnRotate registers a rotation hook on the default store
+func OnRotate(name string, hook RotationHook) {
+	Default().OnRotate(name, hook)
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChainFallsThroughMissingSecrets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "API_KEY"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("API_KEY", "")
+
+	chain := Chain{EnvProvider{}, FileProvider{Dir: dir}}
+	value, err := chain.Get(context.Background(), "API_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "from-file" {
+		t.Errorf("Get() = %q, want from-file", value)
+	}
+
+	if _, err := chain.Get(context.Background(), "MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(MISSING) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreRefreshFiresRotationHooks(t *testing.T) {
+	t.Setenv("ROTATING_SECRET", "v1")
+	store := NewStore(EnvProvider{}, 0)
+
+	var rotated string
+	store.OnRotate("ROTATING_SECRET", func(name, value string) {
+		rotated = value
+	})
+
+	if value, _ := store.Get(context.Background(), "ROTATING_SECRET"); value != "v1" {
+		t.Fatalf("Get() = %q, want v1", value)
+	}
+
+	// Cached until refreshed
+	t.Setenv("ROTATING_SECRET", "v2")
+	if value, _ := store.Get(context.Background(), "ROTATING_SECRET"); value != "v1" {
+		t.Errorf("Get() before refresh = %q, want cached v1", value)
+	}
+
+	store.Refresh(context.Background())
+	if rotated != "v2" {
+		t.Errorf("rotation hook got %q, want v2", rotated)
+	}
+	if value, _ := store.Get(context.Background(), "ROTATING_SECRET"); value != "v2" {
+		t.Errorf("Get() after refresh = %q, want v2", value)
+	}
+}
@@ -0,0 +1,117 @@
+// Package sqlbuilder assembles the dynamic parts of SQL statements: numbered
+// placeholders, WHERE conditions, keyset positions, ORDER BY, and LIMIT.
+// Values are always bound as arguments, never spliced into the text, and the
+// builder keeps placeholder numbers in step with the arguments, which is what
+// goes wrong first when clauses are concatenated by hand.
+//
+// Column names and expressions passed to the builder are SQL, so they must
+// come from the code (e.g. a map of filterable fields), never from a request.
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Args are the arguments of a statement, in placeholder order
+type Args struct {
+	values []interface{}
+}
+
+// NewArgs returns Args starting with values, bound to $1, $2, ...
+func NewArgs(values ...interface{}) *Args {
+	return &Args{values: values}
+}
+
+// Add binds value and returns its placeholder
+func (a *Args) Add(value interface{}) string {
+	a.values = append(a.values, value)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+// Len returns the number of arguments, which is also the number of the last
+// placeholder
+func (a *Args) Len() int {
+	return len(a.values)
+}
+
+// Values returns the arguments to run the statement with
+func (a *Args) Values() []interface{} {
+	return a.values
+}
+
+// Limit returns a LIMIT clause binding n, or "" if n isn't positive
+func (a *Args) Limit(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return " LIMIT " + a.Add(n)
+}
+
+// Where returns an empty condition list whose values are bound to a
+func (a *Args) Where() *Where {
+	return &Where{args: a}
+}
+
+// Where is a list of conditions joined with AND
+type Where struct {
+	args       *Args
+	conditions []string
+}
+
+// And adds a condition that takes no arguments, e.g. "deleted_at IS NULL"
+func (w *Where) And(condition string) *Where {
+	w.conditions = append(w.conditions, condition)
+	return w
+}
+
+// Equal adds expr = value
+func (w *Where) Equal(expr string, value interface{}) *Where {
+	return w.And(expr + " = " + w.args.Add(value))
+}
+
+// After adds the keyset condition for rows that come after values in an
+// order by columns, all descending or all ascending, e.g.
+// (created_at, id) < ($1, $2) for descending.
+func (w *Where) After(columns []string, descending bool, values ...interface{}) *Where {
+	if len(columns) != len(values) {
+		panic(fmt.Sprintf("sqlbuilder: %d keyset columns but %d values", len(columns), len(values)))
+	}
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = w.args.Add(value)
+	}
+	operator := ">"
+	if descending {
+		operator = "<"
+	}
+	return w.And(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", ")))
+}
+
+// Conditions returns the conditions added so far
+func (w *Where) Conditions() []string {
+	return w.conditions
+}
+
+// String returns the WHERE clause with a leading space, or "" if there are
+// no conditions
+func (w *Where) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// OrderBy returns an ORDER BY clause with a leading space, sorting by columns
+// in one direction
+func OrderBy(descending bool, columns ...string) string {
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	terms := make([]string, len(columns))
+	for i, column := range columns {
+		terms[i] = column + " " + direction
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
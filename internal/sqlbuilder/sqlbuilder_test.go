@@ -0,0 +1,79 @@
+package sqlbuilder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgsNumbering(t *testing.T) {
+	args := NewArgs("preset")
+	if placeholder := args.Add(42); placeholder != "$2" {
+		t.Errorf("Expected $2 after a preset value, got %s", placeholder)
+	}
+	if placeholder := args.Add("x"); placeholder != "$3" {
+		t.Errorf("Expected $3, got %s", placeholder)
+	}
+	if args.Len() != 3 || !reflect.DeepEqual(args.Values(), []interface{}{"preset", 42, "x"}) {
+		t.Errorf("Unexpected args %v", args.Values())
+	}
+}
+
+func TestLimit(t *testing.T) {
+	args := NewArgs()
+	if limit := args.Limit(0); limit != "" || args.Len() != 0 {
+		t.Errorf("Expected no limit and no argument, got %q %v", limit, args.Values())
+	}
+	if limit := args.Limit(25); limit != " LIMIT $1" || !reflect.DeepEqual(args.Values(), []interface{}{25}) {
+		t.Errorf("Unexpected limit %q %v", limit, args.Values())
+	}
+}
+
+func TestWhere(t *testing.T) {
+	args := NewArgs()
+	if where := args.Where().String(); where != "" {
+		t.Errorf("Expected an empty WHERE, got %q", where)
+	}
+
+	where := args.Where().
+		And("deleted_at IS NULL").
+		Equal("status", "active").
+		Equal("COALESCE(type, 'standard')", "standard")
+	expected := " WHERE deleted_at IS NULL AND status = $1 AND COALESCE(type, 'standard') = $2"
+	if where.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, where.String())
+	}
+	if len(where.Conditions()) != 3 || !reflect.DeepEqual(args.Values(), []interface{}{"active", "standard"}) {
+		t.Errorf("Unexpected conditions %v and args %v", where.Conditions(), args.Values())
+	}
+}
+
+func TestWhereAfter(t *testing.T) {
+	args := NewArgs("snapshot")
+	where := args.Where().After([]string{"created_at", "id"}, true, "2024-03-01", 7)
+	if where.String() != " WHERE (created_at, id) < ($2, $3)" {
+		t.Errorf("Unexpected descending keyset %q", where.String())
+	}
+	where = NewArgs().Where().After([]string{"c.created_at", "c.id"}, false, "2024-03-01", 7)
+	if where.String() != " WHERE (c.created_at, c.id) > ($1, $2)" {
+		t.Errorf("Unexpected ascending keyset %q", where.String())
+	}
+	if !reflect.DeepEqual(args.Values(), []interface{}{"snapshot", "2024-03-01", 7}) {
+		t.Errorf("Unexpected args %v", args.Values())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for mismatched columns and values")
+		}
+	}()
+	NewArgs().Where().After([]string{"created_at", "id"}, true, 7)
+}
+
+func TestOrderBy(t *testing.T) {
+	if order := OrderBy(true, "created_at", "id"); order != " ORDER BY created_at DESC, id DESC" {
+		t.Errorf("Unexpected order %q", order)
+	}
+	if order := OrderBy(false, "c.created_at", "c.id"); order != " ORDER BY c.created_at ASC, c.id ASC" {
+		t.Errorf("Unexpected order %q", order)
+	}
+}
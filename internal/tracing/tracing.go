@@ -0,0 +1,177 @@
+// Package tracing carries the Heroku router's X-Request-ID and any W3C
+// traceparent header through a request, so one request can be followed across
+// the router, app logs, SQL (via query comments), background jobs, and
+// outbound HTTP calls.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderRequestID is set by the Heroku router on every request
+	HeaderRequestID = "X-Request-ID"
+	// HeaderTraceparent is the W3C Trace Context header
+	HeaderTraceparent = "traceparent"
+
+	// ContextKey is the gin context key holding the request ID
+	ContextKey = "request_id"
+)
+
+var (
+	// Heroku accepts 20-200 characters of [A-Za-z0-9+/=-]; allow the same charset plus a few common separators
+	requestIDPattern   = regexp.MustCompile(`^[A-Za-z0-9+/=_.:-]{1,200}$`)
+	traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// Trace identifies the request a piece of work belongs to
+type Trace struct {
+	RequestID   string `json:"request_id,omitempty"`
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+type traceKey struct{}
+
+// NewContext returns a copy of ctx carrying the trace
+func NewContext(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// FromContext returns the trace carried by ctx, if any
+func FromContext(ctx context.Context) Trace {
+	if ctx == nil {
+		return Trace{}
+	}
+	trace, _ := ctx.Value(traceKey{}).(Trace)
+	return trace
+}
+
+// FromHeaders reads a trace from incoming headers, dropping malformed values
+func FromHeaders(header http.Header) Trace {
+	trace := Trace{
+		RequestID:   strings.TrimSpace(header.Get(HeaderRequestID)),
+		Traceparent: strings.ToLower(strings.TrimSpace(header.Get(HeaderTraceparent))),
+	}
+	if !requestIDPattern.MatchString(trace.RequestID) {
+		trace.RequestID = ""
+	}
+	if !traceparentPattern.MatchString(trace.Traceparent) {
+		trace.Traceparent = ""
+	}
+	return trace
+}
+
+// Inject copies the trace onto outgoing headers
+func (t Trace) Inject(header http.Header) {
+	if t.RequestID != "" {
+		header.Set(HeaderRequestID, t.RequestID)
+	}
+	if t.Traceparent != "" {
+		header.Set(HeaderTraceparent, t.Traceparent)
+	}
+}
+
+// NewRequestID generates a random request ID for work that didn't arrive
+// through the router, e.g. local requests or scheduled jobs
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Middleware honors the router's X-Request-ID (generating one when absent) and
+// any traceparent header, stores them on the request context, and echoes the
+// request ID back in the response.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trace := FromHeaders(c.Request.Header)
+		if trace.RequestID == "" {
+			trace.RequestID = NewRequestID()
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), trace))
+		c.Set(ContextKey, trace.RequestID)
+		c.Header(HeaderRequestID, trace.RequestID)
+		c.Next()
+	}
+}
+
+// LogFormatter is gin's default access log line with the request ID appended
+func LogFormatter(param gin.LogFormatterParams) string {
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	requestID, _ := param.Keys[ContextKey].(string)
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v request_id=%s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		requestID,
+		param.ErrorMessage,
+	)
+}
+
+// Printf logs a message tagged with the request ID carried by ctx
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	if requestID := FromContext(ctx).RequestID; requestID != "" {
+		format = "[request_id=" + requestID + "] " + format
+	}
+	log.Printf(format, args...)
+}
+
+// SQLComment renders the trace as a sqlcommenter-style comment, e.g.
+// /*request_id='abc',traceparent='00-...'*/, or "" when ctx carries no trace.
+// Comments show up in pg_stat_activity and slow query logs.
+func SQLComment(ctx context.Context) string {
+	trace := FromContext(ctx)
+	var pairs []string
+	if trace.RequestID != "" {
+		pairs = append(pairs, "request_id='"+url.QueryEscape(trace.RequestID)+"'")
+	}
+	if trace.Traceparent != "" {
+		pairs = append(pairs, "traceparent='"+url.QueryEscape(trace.Traceparent)+"'")
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+// Transport propagates the trace on outbound HTTP requests. Wrap the transport
+// of any client that calls other services, including webhook deliveries.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	trace := FromContext(req.Context())
+	if trace == (Trace{}) {
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	trace.Inject(req.Header)
+	return base.RoundTrip(req)
+}
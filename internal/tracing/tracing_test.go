@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFromHeadersDropsMalformedValues(t *testing.T) {
+	header := http.Header{}
+	header.Set(HeaderRequestID, "abc 123; DROP TABLE users")
+	header.Set(HeaderTraceparent, "00-not-a-trace")
+
+	if trace := FromHeaders(header); trace != (Trace{}) {
+		t.Errorf("FromHeaders() = %+v, want empty trace", trace)
+	}
+}
+
+func TestSQLComment(t *testing.T) {
+	ctx := NewContext(context.Background(), Trace{
+		RequestID:   "f1b6c5e2-0c4d-4a52-9a2f-1d0e1c7b7f3a",
+		Traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+
+	want := "/*request_id='f1b6c5e2-0c4d-4a52-9a2f-1d0e1c7b7f3a',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/"
+	if got := SQLComment(ctx); got != want {
+		t.Errorf("SQLComment() = %q, want %q", got, want)
+	}
+	if got := SQLComment(context.Background()); got != "" {
+		t.Errorf("SQLComment() without trace = %q, want empty", got)
+	}
+}
+
+func TestMiddlewareHonorsRouterRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+
+	var seen Trace
+	router.GET("/", func(c *gin.Context) {
+		seen = FromContext(c.Request.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderRequestID, "router-id-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if seen.RequestID != "router-id-123" {
+		t.Errorf("request context ID = %q, want router-id-123", seen.RequestID)
+	}
+	if got := w.Header().Get(HeaderRequestID); got != "router-id-123" {
+		t.Errorf("response %s = %q, want router-id-123", HeaderRequestID, got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Header().Get(HeaderRequestID) == "" {
+		t.Error("expected a generated request ID when the header is missing")
+	}
+}
+
+func TestTransportInjectsTrace(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer server.Close()
+
+	ctx := NewContext(context.Background(), Trace{RequestID: "outbound-1"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	client := &http.Client{Transport: &Transport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Get(HeaderRequestID) != "outbound-1" {
+		t.Errorf("outbound %s = %q, want outbound-1", HeaderRequestID, got.Get(HeaderRequestID))
+	}
+}
@@ -0,0 +1,176 @@
+// Package usage records API calls per caller, token, and endpoint into hourly
+// rollups. Calls are aggregated in memory and flushed in batches so tracking
+// adds no database round trip to the request path; the caller's organization
+// is looked up as each batch is flushed.
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key identifies one rollup row
+type Key struct {
+	Bucket   time.Time
+	Username string
+	// TokenID is the API key, or for customer token callers the customer
+	// token, the call was made with; 0 for JWTs
+	TokenID int
+	Method  string
+	Route    string
+	Status   int
+}
+
+// Counts are the aggregated measurements for a Key
+type Counts struct {
+	Calls          int64
+	TotalLatencyMs float64
+	MaxLatencyMs   float64
+}
+
+// Recorder buffers usage until the next flush
+type Recorder struct {
+	mu      sync.Mutex
+	pending map[Key]*Counts
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{pending: make(map[Key]*Counts)}
+}
+
+// Record adds one call to the current hour's rollup
+func (r *Recorder) Record(username string, tokenID int, method, route string, status int, latency time.Duration) {
+	key := Key{
+		Bucket:   time.Now().UTC().Truncate(time.Hour),
+		Username: username,
+		TokenID:  tokenID,
+		Method:   method,
+		Route:    route,
+		Status:   status,
+	}
+	ms := float64(latency) / float64(time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts, ok := r.pending[key]
+	if !ok {
+		counts = &Counts{}
+		r.pending[key] = counts
+	}
+	counts.Calls++
+	counts.TotalLatencyMs += ms
+	if ms > counts.MaxLatencyMs {
+		counts.MaxLatencyMs = ms
+	}
+}
+
+// drain returns and clears the buffered rollups
+func (r *Recorder) drain() map[Key]*Counts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending := r.pending
+	r.pending = make(map[Key]*Counts)
+	return pending
+}
+
+// Flush writes buffered rollups with a single upsert. Each row gets the
+// organization of its caller: the user's, or for customer token callers
+// (customer:<id>) the customer's, or 0 if none. On failure the batch is
+// merged back so it is retried on the next flush.
+func (r *Recorder) Flush(ctx context.Context, db *sql.DB) error {
+	pending := r.drain()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(pending))
+	args := make([]interface{}, 0, len(pending)*9)
+	for key, counts := range pending {
+		n := len(args)
+		placeholders = append(placeholders, fmt.Sprintf(
+			"($%d::timestamp, $%d::text, $%d::integer, $%d::text, $%d::text, $%d::integer, $%d::bigint, $%d::double precision, $%d::double precision)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9,
+		))
+		args = append(args, key.Bucket, key.Username, key.TokenID, key.Method, key.Route, key.Status, counts.Calls, counts.TotalLatencyMs, counts.MaxLatencyMs)
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO api_usage_rollups (bucket, username, organization_id, token_id, method, route, status, calls, total_latency_ms, max_latency_ms)
+		SELECT v.bucket, v.username, COALESCE(u.organization_id, c.organization_id, 0), v.token_id,
+			v.method, v.route, v.status, v.calls, v.total_latency_ms, v.max_latency_ms
+		FROM (VALUES `+strings.Join(placeholders, ", ")+`)
+			AS v (bucket, username, token_id, method, route, status, calls, total_latency_ms, max_latency_ms)
+		LEFT JOIN users u ON u.username = v.username
+		LEFT JOIN customers c ON c.id = CASE WHEN v.username ~ '^customer:[0-9]+$' THEN substring(v.username FROM 10)::integer END
+		ON CONFLICT (bucket, username, organization_id, token_id, method, route, status) DO UPDATE SET
+			calls = api_usage_rollups.calls + EXCLUDED.calls,
+			total_latency_ms = api_usage_rollups.total_latency_ms + EXCLUDED.total_latency_ms,
+			max_latency_ms = GREATEST(api_usage_rollups.max_latency_ms, EXCLUDED.max_latency_ms)`,
+		args...,
+	)
+	if err != nil {
+		r.merge(pending)
+		return fmt.Errorf("failed to flush api usage: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) merge(batch map[Key]*Counts) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, counts := range batch {
+		existing, ok := r.pending[key]
+		if !ok {
+			r.pending[key] = counts
+			continue
+		}
+		existing.Calls += counts.Calls
+		existing.TotalLatencyMs += counts.TotalLatencyMs
+		if counts.MaxLatencyMs > existing.MaxLatencyMs {
+			existing.MaxLatencyMs = counts.MaxLatencyMs
+		}
+	}
+}
+
+// Start flushes to db every interval until ctx is done
+func (r *Recorder) Start(ctx context.Context, db *sql.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Flush(ctx, db); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// FlushInterval returns how often usage is flushed.
+// Configure with API_USAGE_FLUSH_INTERVAL (default: 10s).
+func FlushInterval() time.Duration {
+	value := os.Getenv("API_USAGE_FLUSH_INTERVAL")
+	if value == "" {
+		return 10 * time.Second
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Warning: Invalid value for API_USAGE_FLUSH_INTERVAL (%s), using default 10s", value)
+		return 10 * time.Second
+	}
+	return interval
+}
+
+// Default is the recorder used by the API usage middleware
+var Default = NewRecorder()
@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordAggregatesPerKey(t *testing.T) {
+	r := NewRecorder()
+	r.Record("alice", 0, "GET", "/api/customers", 200, 10*time.Millisecond)
+	r.Record("alice", 0, "GET", "/api/customers", 200, 30*time.Millisecond)
+	r.Record("alice", 0, "GET", "/api/customers", 500, 5*time.Millisecond)
+	r.Record("bob", 0, "GET", "/api/customers", 200, time.Millisecond)
+	r.Record("alice", 4, "GET", "/api/customers", 200, time.Millisecond)
+
+	pending := r.drain()
+	if len(pending) != 4 {
+		t.Fatalf("Expected 4 rollup rows, got %d", len(pending))
+	}
+
+	for key, counts := range pending {
+		if key.Username != "alice" || key.TokenID != 0 || key.Status != 200 {
+			continue
+		}
+		if counts.Calls != 2 {
+			t.Errorf("Expected 2 calls, got %d", counts.Calls)
+		}
+		if counts.TotalLatencyMs != 40 || counts.MaxLatencyMs != 30 {
+			t.Errorf("Expected total 40ms and max 30ms, got %.0f and %.0f", counts.TotalLatencyMs, counts.MaxLatencyMs)
+		}
+	}
+
+	if len(r.drain()) != 0 {
+		t.Error("Expected drain to clear pending rollups")
+	}
+}
+
+func TestMergeKeepsFailedBatch(t *testing.T) {
+	r := NewRecorder()
+	r.Record("alice", 0, "GET", "/api/accounts", 200, 20*time.Millisecond)
+	batch := r.drain()
+
+	r.Record("alice", 0, "GET", "/api/accounts", 200, 50*time.Millisecond)
+	r.merge(batch)
+
+	for _, counts := range r.drain() {
+		if counts.Calls != 2 || counts.MaxLatencyMs != 50 {
+			t.Errorf("Expected merged 2 calls with max 50ms, got %d and %.0f", counts.Calls, counts.MaxLatencyMs)
+		}
+	}
+}
@@ -21,12 +21,44 @@ apiClient.interceptors.request.use(
   }
 )
 
-// Handle 401 errors (unauthorized)
+// Exchanges the refresh token for a new token. Concurrent 401s share one
+// refresh, since presenting a refresh token twice signs the session out.
+let refreshing = null
+const refreshToken = () => {
+  if (!refreshing) {
+    const token = localStorage.getItem('refresh_token')
+    refreshing = (token ? axios.post('/api/auth/refresh', { refresh_token: token }) : Promise.reject(new Error('no refresh token')))
+      .then((response) => {
+        localStorage.setItem('token', response.data.token)
+        localStorage.setItem('refresh_token', response.data.refresh_token)
+      })
+      .finally(() => {
+        refreshing = null
+      })
+  }
+  return refreshing
+}
+
+// Handle 401 errors (unauthorized): refresh the token once and retry, or
+// send the user to the login page
 apiClient.interceptors.response.use(
   (response) => response,
-  (error) => {
-    if (error.response?.status === 401) {
+  async (error) => {
+    const config = error.config
+    if (error.response?.status === 401 && !config._retried && !config.url.startsWith('/auth/')) {
+      config._retried = true
+      try {
+        await refreshToken()
+        return apiClient(config)
+      } catch {
+        // Fall through to the login page
+      }
+    }
+    // Failed logins stay on the login page, which asks for a two-factor code
+    // when the response says one is required
+    if (error.response?.status === 401 && config.url !== '/auth/login') {
       localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
       window.location.href = '/login'
     }
     return Promise.reject(error)